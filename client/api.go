@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/scrape"
+)
+
+// ParseResult is a summary of completed parse job returned by Parse.
+type ParseResult struct {
+	Status    string `json:"Status"`
+	TaskID    string `json:"Task ID"`
+	RequestID string `json:"Request ID"`
+	//ResultsID identifies parse results. It is the same for all runs of a payload.
+	ResultsID string `json:"Results ID"`
+	//OutputFile is the file results are encoded to in the requested format.
+	OutputFile string            `json:"Output file"`
+	Report     *scrape.JobReport `json:"Report"`
+}
+
+// Parse processes payload p and returns the summary of the job. Records are available with Results
+// and StreamResults once Parse returns. Empty result is returned if nothing has been parsed.
+func (c *Client) Parse(ctx context.Context, p scrape.Payload) (*ParseResult, error) {
+	return c.parse(ctx, "/parse", p)
+}
+
+// ParsePayload processes payload saved to the registry.
+func (c *Client) ParsePayload(ctx context.Context, req scrape.PayloadRequest) (*ParseResult, error) {
+	return c.parse(ctx, payloadPath(req.Name)+"/parse", req)
+}
+
+func (c *Client) parse(ctx context.Context, path string, body interface{}) (*ParseResult, error) {
+	data, err := c.do(ctx, "POST", path, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	res := &ParseResult{}
+	if len(data) == 0 {
+		return res, nil
+	}
+	return res, decode(path, data, res)
+}
+
+// Results returns a page of parse results.
+func (c *Client) Results(ctx context.Context, req scrape.ResultsRequest) (*scrape.ResultsPage, error) {
+	q := url.Values{}
+	if req.Offset != 0 {
+		q.Set("offset", strconv.Itoa(req.Offset))
+	}
+	if req.Limit != 0 {
+		q.Set("limit", strconv.Itoa(req.Limit))
+	}
+	page := &scrape.ResultsPage{}
+	return page, c.getJSON(ctx, "GET", resultsPath(req.ID), q, nil, page)
+}
+
+// StreamResults passes all records of results id to fn requesting them by pages of pageSize records.
+// Streaming stops at the first error returned by fn. Service default page size is used if pageSize is 0.
+func (c *Client) StreamResults(ctx context.Context, id string, pageSize int, fn func(record map[string]interface{}) error) error {
+	offset := 0
+	for {
+		page, err := c.Results(ctx, scrape.ResultsRequest{ID: id, Offset: offset, Limit: pageSize})
+		if err != nil {
+			return err
+		}
+		for _, record := range page.Results {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		offset += len(page.Results)
+		if len(page.Results) == 0 || offset >= page.Total {
+			return nil
+		}
+	}
+}
+
+// Report returns the report of the latest job run with results id.
+func (c *Client) Report(ctx context.Context, id string) (*scrape.JobReport, error) {
+	report := &scrape.JobReport{}
+	return report, c.getJSON(ctx, "GET", resultsPath(id)+"/report", nil, nil, report)
+}
+
+// WaitReport polls the report of results id every interval until a job finished after since is reported
+// or ctx is done. It is used to wait for payloads run by the scheduler or by other clients.
+func (c *Client) WaitReport(ctx context.Context, id string, since time.Time, interval time.Duration) (*scrape.JobReport, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := c.Report(ctx, id)
+		switch e := err.(type) {
+		case nil:
+			if report.Finished.After(since) {
+				return report, nil
+			}
+		case errs.Error:
+			//no job has completed yet
+			if e.Status() != http.StatusNotFound {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreatePayload saves a new named payload to the registry.
+func (c *Client) CreatePayload(ctx context.Context, p scrape.Payload) (*scrape.PayloadInfo, error) {
+	info := &scrape.PayloadInfo{}
+	return info, c.getJSON(ctx, "POST", "/payloads", nil, p, info)
+}
+
+// UpdatePayload saves a new version of the payload to the registry.
+func (c *Client) UpdatePayload(ctx context.Context, p scrape.Payload) (*scrape.PayloadInfo, error) {
+	info := &scrape.PayloadInfo{}
+	return info, c.getJSON(ctx, "PUT", payloadPath(p.Name), nil, p, info)
+}
+
+// GetPayload returns specified version of the payload saved to the registry.
+func (c *Client) GetPayload(ctx context.Context, req scrape.PayloadRequest) (*scrape.Payload, error) {
+	data, err := c.do(ctx, "GET", payloadPath(req.Name), versionQuery(req.Version), nil)
+	if err != nil {
+		return nil, err
+	}
+	p, err := scrape.UnmarshalPayload(data, false)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPayloads returns all payloads saved to the registry.
+func (c *Client) ListPayloads(ctx context.Context) ([]scrape.PayloadInfo, error) {
+	payloads := []scrape.PayloadInfo{}
+	return payloads, c.getJSON(ctx, "GET", "/payloads", nil, nil, &payloads)
+}
+
+// PayloadVersions returns version history of the payload.
+func (c *Client) PayloadVersions(ctx context.Context, name string) ([]scrape.PayloadVersion, error) {
+	versions := []scrape.PayloadVersion{}
+	return versions, c.getJSON(ctx, "GET", payloadPath(name)+"/versions", nil, nil, &versions)
+}
+
+// DeletePayload removes the payload along with all its versions from the registry.
+func (c *Client) DeletePayload(ctx context.Context, name string) error {
+	_, err := c.do(ctx, "DELETE", payloadPath(name), nil, nil)
+	return err
+}
+
+// NewSession issues a user token valid for ttl. Cookies and localStorage of web sites are kept between
+// requests passing the token as Request.UserToken, f.e. to stay signed in. Tokens issued with 0 ttl don't expire.
+func (c *Client) NewSession(ctx context.Context, ttl time.Duration) (*fetch.UserToken, error) {
+	token := &fetch.UserToken{}
+	req := fetch.UserTokenRequest{TTL: int64(ttl / time.Second)}
+	return token, c.getJSON(ctx, "POST", "/tokens", nil, req, token)
+}
+
+// Purge deletes stored data of the job, tenant or URL of req. The client token should have admin role,
+// req.Token is not sent.
+func (c *Client) Purge(ctx context.Context, req scrape.PurgeRequest) (*scrape.PurgeResult, error) {
+	res := &scrape.PurgeResult{}
+	return res, c.getJSON(ctx, "POST", "/purge", nil, req, res)
+}
+
+func resultsPath(id string) string {
+	return "/results/" + url.PathEscape(id)
+}
+
+func payloadPath(name string) string {
+	return "/payloads/" + url.PathEscape(name)
+}
+
+func versionQuery(version int) url.Values {
+	if version == 0 {
+		return nil
+	}
+	return url.Values{"version": {strconv.Itoa(version)}}
+}
@@ -0,0 +1,241 @@
+// Dataflow kit - client
+//
+// Copyright © 2017-2018 Slotix s.r.o. <dm@slotix.sk>
+//
+//
+// All rights reserved. Use of this source code is governed
+// by the BSD 3-Clause License license.
+
+// Package client of the Dataflow kit is a Go client of Parse service HTTP API.
+//
+// Client sends payloads for parsing, polls job reports, streams parse results page by page,
+// manages saved payloads and issues user tokens keeping sessions of web sites between requests:
+//
+//	c, err := client.New("127.0.0.1:8001", client.WithToken(apiKey))
+//	if err != nil {
+//		return err
+//	}
+//	res, err := c.Parse(ctx, payload)
+//	if err != nil {
+//		return err
+//	}
+//	err = c.StreamResults(ctx, res.ResultsID, 100, func(record map[string]interface{}) error {
+//		fmt.Println(record)
+//		return nil
+//	})
+//
+// Requests rejected because of service overload are retried with exponential backoff. Reading requests
+// are retried on network failures as well. Errors returned by the service are errs.Response holding
+// the error code, errs.FetchError for pages which can't be fetched or errs.StatusError for responses
+// of proxies in front of the service. All of them implement errs.Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// Default retry settings of Client.
+const (
+	DefaultRetries = 3
+	DefaultBackoff = 500 * time.Millisecond
+)
+
+// maxBackoff limits the delay between retries.
+const maxBackoff = 30 * time.Second
+
+// Client is a client of Parse service. It is safe for concurrent use.
+type Client struct {
+	base    *url.URL
+	http    *http.Client
+	token   string
+	retries int
+	backoff time.Duration
+}
+
+// Option configures Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with c, f.e. to set transport or timeout. http.DefaultClient is used by default.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.http = c
+	}
+}
+
+// WithToken authenticates requests with API key or JWT passed as Bearer token.
+func WithToken(token string) Option {
+	return func(cl *Client) {
+		cl.token = token
+	}
+}
+
+// WithRetries sets the number of times failed requests are retried and the delay before the first retry.
+// The delay doubles with every retry. Requests are not retried if retries is 0.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(cl *Client) {
+		cl.retries = retries
+		cl.backoff = backoff
+	}
+}
+
+// New returns Client of Parse service living at addr, f.e. "127.0.0.1:8001" or "https://dfk.example.com".
+func New(addr string, opts ...Option) (*Client, error) {
+	if !strings.HasPrefix(addr, "http") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = ""
+	c := &Client{
+		base:    u,
+		http:    http.DefaultClient,
+		retries: DefaultRetries,
+		backoff: DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// retryable reports whether the request of method failed with status code or err may be retried.
+// Requests changing data are retried only if the service rejected them without processing.
+func retryable(method string, code int, err error) bool {
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		return true
+	}
+	if method == "POST" {
+		return false
+	}
+	return err != nil || code == http.StatusBadGateway || code == http.StatusGatewayTimeout
+}
+
+// do sends the request to path of the service and returns the body of successful response.
+// body is JSON encoded unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}) ([]byte, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	//path elements are escaped already
+	u := c.base.String() + path
+	if q := query.Encode(); q != "" {
+		u += "?" + q
+	}
+	//the same correlation ID is sent with all retries
+	requestID := utils.RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = utils.NewRequestID()
+	}
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, code, retryAfter, err := c.send(ctx, method, u, requestID, data)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= c.retries || !retryable(method, code, err) {
+			return nil, err
+		}
+		wait := delay
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		if delay *= 2; delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+}
+
+// send makes a single attempt of the request. It returns response body, status code
+// and the delay requested by Retry-After header.
+func (c *Client) send(ctx context.Context, method, u, requestID string, data []byte) ([]byte, int, time.Duration, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	req = req.WithContext(ctx)
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set(utils.RequestIDHeader, requestID)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, 0, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return content, resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(s) * time.Second
+	}
+	if err := errs.DecodeResponse(resp.StatusCode, content); err != nil {
+		return nil, resp.StatusCode, retryAfter, err
+	}
+	//responses of proxies in front of the service
+	msg := strings.TrimSpace(string(content))
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return nil, resp.StatusCode, retryAfter, errs.StatusError{
+		Code: resp.StatusCode,
+		Err:  errors.New(msg),
+	}
+}
+
+// getJSON sends the request and decodes JSON encoded response to v.
+func (c *Client) getJSON(ctx context.Context, method, path string, query url.Values, body, v interface{}) error {
+	data, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return decode(path, data, v)
+}
+
+// decode decodes JSON encoded response of path to v.
+func decode(path string, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Cannot decode response of %s. %s", path, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/scrape"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Retries(t *testing.T) {
+	var calls int32
+	requestIDs := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		requestIDs[r.Header.Get(utils.RequestIDHeader)] = true
+		switch r.URL.Path {
+		case "/results/busy/report":
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(scrape.JobReport{ResultsID: "busy", Status: "Completed"})
+		case "/parse":
+			atomic.AddInt32(&calls, 1)
+			errs.NewResponse(errors.New("Internal error"), "Internal error", "").Write(w)
+		case "/payloads":
+			http.Error(w, "Bad gateway", http.StatusBadGateway)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL, WithToken("secret"), WithRetries(3, time.Millisecond))
+	assert.NoError(t, err)
+	report, err := c.Report(context.Background(), "busy")
+	assert.NoError(t, err)
+	assert.Equal(t, "Completed", report.Status)
+	assert.Equal(t, int32(3), calls)
+	assert.Len(t, requestIDs, 1)
+
+	//processed requests are not retried
+	calls = 0
+	_, err = c.Parse(context.Background(), scrape.Payload{Name: "test"})
+	assert.Equal(t, int32(1), calls)
+	e, ok := err.(errs.Response)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, e.Status())
+	assert.Equal(t, errs.CodeInternal, e.Code)
+	assert.Equal(t, "Internal error", e.Error())
+
+	//responses of proxies
+	noRetries, err := New(ts.URL, WithToken("secret"), WithRetries(0, 0))
+	assert.NoError(t, err)
+	_, err = noRetries.ListPayloads(context.Background())
+	statusErr, ok := err.(errs.StatusError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, statusErr.Status())
+
+	_, err = c.Report(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestClient_StreamResults(t *testing.T) {
+	total := 5
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/results/a%2Fb", r.URL.EscapedPath())
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page := scrape.ResultsPage{ID: "a/b", Offset: offset, Limit: limit, Total: total}
+		for i := offset; i < offset+limit && i < total; i++ {
+			page.Results = append(page.Results, map[string]interface{}{"n": fmt.Sprint(i)})
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL)
+	assert.NoError(t, err)
+	records := []string{}
+	err = c.StreamResults(context.Background(), "a/b", 2, func(record map[string]interface{}) error {
+		records = append(records, record["n"].(string))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, records)
+
+	stop := fmt.Errorf("stop")
+	err = c.StreamResults(context.Background(), "a/b", 2, func(record map[string]interface{}) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
+
+func TestClient_WaitReport(t *testing.T) {
+	since := time.Now()
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			http.NotFound(w, r)
+		case 2:
+			json.NewEncoder(w).Encode(scrape.JobReport{Finished: since.Add(-time.Hour)})
+		default:
+			json.NewEncoder(w).Encode(scrape.JobReport{Finished: since.Add(time.Second), Status: "Completed"})
+		}
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL)
+	assert.NoError(t, err)
+	report, err := c.WaitReport(context.Background(), "id", since, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "Completed", report.Status)
+	assert.Equal(t, int32(3), calls)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitReport(ctx, "id", since.Add(time.Hour), time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bench flag vars
+var (
+	benchURLs        []string
+	benchURLsFile    string
+	benchRequests    int
+	benchConcurrency int
+)
+
+func init() {
+	benchCmd.Flags().StringSliceVarP(&benchURLs, "URLS", "", nil, "Comma separated list of URLs to be fetched")
+	benchCmd.Flags().StringVarP(&benchURLsFile, "URLS_FILE", "", "", "File containing URLs to be fetched, one per line")
+	benchCmd.Flags().IntVarP(&benchRequests, "REQUESTS", "n", 100, "Total number of requests")
+	benchCmd.Flags().IntVarP(&benchConcurrency, "CONCURRENCY", "c", 10, "Number of requests sent concurrently")
+	RootCmd.AddCommand(benchCmd)
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark fetcher",
+	Long: `Bench fires specified number of requests at a URL list through a chosen fetcher type.
+It reports throughput, latency percentiles, error breakdown and Chrome pool saturation.
+Use it for capacity planning before large jobs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		urls, err := loadBenchURLs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(urls) == 0 {
+			fmt.Fprintln(os.Stderr, "error: no URLs specified")
+			os.Exit(1)
+		}
+		svc, err := fetch.NewHTTPClient(viper.GetString("DFK_FETCH"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		res := runBench(svc, urls, fetcher, benchRequests, benchConcurrency)
+		res.print(os.Stdout)
+	},
+}
+
+// loadBenchURLs combines URLs passed with URLS and URLS_FILE flags.
+func loadBenchURLs() ([]string, error) {
+	urls := append([]string{}, benchURLs...)
+	if benchURLsFile == "" {
+		return urls, nil
+	}
+	f, err := os.Open(benchURLsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if u := strings.TrimSpace(scanner.Text()); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// benchResults keeps statistics collected during benchmark.
+type benchResults struct {
+	requests  int
+	took      time.Duration
+	latencies []time.Duration
+	errors    map[string]int
+	//Chrome pool saturation sampled from Fetch service during benchmark
+	poolSampled     bool
+	maxActiveChrome int64
+	maxChrome       int
+	maxQueued       int64
+	rejected        int64
+}
+
+// runBench sends n requests for urls (round robin) through svc using c concurrent workers.
+func runBench(svc fetch.Service, urls []string, fetcherType string, n, c int) *benchResults {
+	res := &benchResults{
+		requests: n,
+		errors:   make(map[string]int),
+	}
+	if c < 1 {
+		c = 1
+	}
+	var mx sync.Mutex
+	jobs := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < c; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				begin := time.Now()
+				content, err := svc.Fetch(fetch.Request{
+					Type: strings.ToLower(fetcherType),
+					URL:  u,
+				})
+				if err == nil {
+					_, err = io.Copy(ioutil.Discard, content)
+					content.Close()
+				}
+				latency := time.Since(begin)
+				mx.Lock()
+				res.latencies = append(res.latencies, latency)
+				if err != nil {
+					res.errors[strings.TrimSpace(err.Error())]++
+				}
+				mx.Unlock()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go res.samplePool(done, sampled)
+
+	begin := time.Now()
+	for i := 0; i < n; i++ {
+		jobs <- urls[i%len(urls)]
+	}
+	close(jobs)
+	wg.Wait()
+	res.took = time.Since(begin)
+	close(done)
+	<-sampled
+	return res
+}
+
+// samplePool polls Fetch service worker pool counters until done is closed.
+func (res *benchResults) samplePool(done, sampled chan struct{}) {
+	defer close(sampled)
+	var startRejected int64
+	if stats, err := fetch.GetPoolStats(viper.GetString("DFK_FETCH")); err == nil {
+		startRejected = stats.Rejected
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		stats, err := fetch.GetPoolStats(viper.GetString("DFK_FETCH"))
+		if err == nil {
+			res.poolSampled = true
+			res.maxChrome = stats.MaxChrome
+			if stats.ActiveChrome > res.maxActiveChrome {
+				res.maxActiveChrome = stats.ActiveChrome
+			}
+			if stats.Queued > res.maxQueued {
+				res.maxQueued = stats.Queued
+			}
+			res.rejected = stats.Rejected - startRejected
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// percentile returns p-th percentile of sorted latencies linearly interpolated between the closest ranks.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	if rank <= 0 {
+		return sorted[0]
+	}
+	if rank >= float64(len(sorted)-1) {
+		return sorted[len(sorted)-1]
+	}
+	lo := int(rank)
+	frac := rank - float64(lo)
+	return sorted[lo] + time.Duration(frac*float64(sorted[lo+1]-sorted[lo]))
+}
+
+func (res *benchResults) print(w io.Writer) {
+	sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+	failed := 0
+	for _, count := range res.errors {
+		failed += count
+	}
+	fmt.Fprintf(w, "Requests:     %d (%d failed)\n", res.requests, failed)
+	fmt.Fprintf(w, "Took:         %s\n", res.took)
+	if res.took > 0 {
+		fmt.Fprintf(w, "Throughput:   %.2f req/s\n", float64(res.requests)/res.took.Seconds())
+	}
+	fmt.Fprintln(w, "Latency:")
+	for _, p := range []float64{50, 90, 95, 99} {
+		fmt.Fprintf(w, "  p%-3.0f        %s\n", p, percentile(res.latencies, p))
+	}
+	fmt.Fprintf(w, "  max         %s\n", percentile(res.latencies, 100))
+	if failed > 0 {
+		fmt.Fprintln(w, "Errors:")
+		for e, count := range res.errors {
+			fmt.Fprintf(w, "  %6d  %s\n", count, e)
+		}
+	}
+	if res.poolSampled {
+		fmt.Fprintln(w, "Fetch pool:")
+		if res.maxChrome > 0 {
+			fmt.Fprintf(w, "  Chrome sessions peak  %d/%d (%.0f%%)\n", res.maxActiveChrome, res.maxChrome,
+				float64(res.maxActiveChrome)*100/float64(res.maxChrome))
+		} else {
+			fmt.Fprintf(w, "  Chrome sessions peak  %d (unlimited)\n", res.maxActiveChrome)
+		}
+		fmt.Fprintf(w, "  Queued peak           %d\n", res.maxQueued)
+		fmt.Fprintf(w, "  Rejected              %d\n", res.rejected)
+	}
+}
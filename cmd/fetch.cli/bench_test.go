@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{nil, 50, 0},
+		{[]time.Duration{}, 99, 0},
+		{[]time.Duration{7 * ms}, 0, 7 * ms},
+		{[]time.Duration{7 * ms}, 50, 7 * ms},
+		{[]time.Duration{7 * ms}, 100, 7 * ms},
+		{[]time.Duration{10 * ms, 20 * ms}, 50, 15 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, 0, 10 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, 50, 25 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, 90, 37 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, 100, 40 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms}, 50, 20 * ms},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, percentile(tt.sorted, tt.p), fmt.Sprintf("p%v of %v", tt.p, tt.sorted))
+	}
+}
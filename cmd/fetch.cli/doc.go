@@ -22,6 +22,15 @@
 //		./fetch.cli -u http://example.com -t base
 //		./fetch.cli -u http://example.com -t chrome
 //
+// Benchmarking fetcher
+//		./fetch.cli bench --URLS http://example.com,http://example.org -n 500 -c 20 -t chrome
+//		./fetch.cli bench --URLS_FILE urls.txt -n 1000 -c 50
+// Bench reports throughput, latency percentiles, error breakdown and Chrome pool saturation of Fetch service.
+//		URLS: comma separated list of URLs to be fetched
+//		URLS_FILE: file containing URLs to be fetched, one per line
+//		REQUESTS: total number of requests (defaults to 100)
+//		CONCURRENCY: number of requests sent concurrently (defaults to 10)
+//
 // Flags and configuration settings
 //		DFK_FETCH: HTTP listen address of Fetch service (defaults to "127.0.0.1:8000")
 //		FETCHER_TYPE: DFK Fetcher type: chrome, base (defaults to base)
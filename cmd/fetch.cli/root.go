@@ -142,8 +142,8 @@ func Execute(version string) {
 
 func init() {
 	//flags and configuration settings. They are global for the application.
-	RootCmd.Flags().StringVarP(&DFKFetch, "DFK_FETCH", "f", "127.0.0.1:8000", "DFK Fetch service address")
-	RootCmd.Flags().StringVarP(&fetcher, "FETCHER_TYPE", "t", "base", "DFK Fetcher type: chrome, base")
+	RootCmd.PersistentFlags().StringVarP(&DFKFetch, "DFK_FETCH", "f", "127.0.0.1:8000", "DFK Fetch service address")
+	RootCmd.PersistentFlags().StringVarP(&fetcher, "FETCHER_TYPE", "t", "base", "DFK Fetcher type: chrome, base")
 	RootCmd.Flags().StringVarP(&URL, "URL", "u", "", "URL to be fetched")
 	RootCmd.Flags().StringVarP(&Params, "FORMDATA", "", "", "Params is a string value for passing formdata parameters.")
 	RootCmd.Flags().StringVarP(&Cookies, "COOKIES", "", "", "Cookies contain cookies to be added to request  before sending it to browser.")
@@ -153,9 +153,9 @@ func init() {
 	if os.Getenv("DFK_FETCH") != "" {
 		viper.BindEnv("DFK_FETCH")
 	} else {
-		viper.BindPFlag("DFK_FETCH", RootCmd.Flags().Lookup("DFK_FETCH"))
+		viper.BindPFlag("DFK_FETCH", RootCmd.PersistentFlags().Lookup("DFK_FETCH"))
 	}
-	viper.BindPFlag("FETCHER_TYPE", RootCmd.Flags().Lookup("FETCHER_TYPE"))
+	viper.BindPFlag("FETCHER_TYPE", RootCmd.PersistentFlags().Lookup("FETCHER_TYPE"))
 	viper.BindPFlag("URL", RootCmd.Flags().Lookup("URL"))
 	viper.BindPFlag("PARAMS", RootCmd.Flags().Lookup("PARAMS"))
 	viper.BindPFlag("COOKIES", RootCmd.Flags().Lookup("COOKIES"))
@@ -0,0 +1,71 @@
+// Form filling helpers used by fillForm request option.
+// Values are assigned with native setters so frameworks tracking input values notice the change.
+
+function formElement(selector) {
+  let elem = document.querySelector(selector);
+  if (elem === null) {
+    throw new Error('Element ' + selector + ' not found');
+  }
+  return elem;
+}
+
+function nativeSetValue(elem, value) {
+  let proto = Object.getPrototypeOf(elem);
+  let desc = Object.getOwnPropertyDescriptor(proto, 'value');
+  if (desc && desc.set) {
+    desc.set.call(elem, value);
+  } else {
+    elem.value = value;
+  }
+}
+
+function fireEvents(elem, ...types) {
+  for (let type of types) {
+    elem.dispatchEvent(new Event(type, { bubbles: true }));
+  }
+}
+
+// setField sets value of select, checkbox and radio elements.
+// Text fields are cleared and true is returned so the value is typed in afterwards.
+function setField(selector, value) {
+  let elem = formElement(selector);
+  elem.scrollIntoView({ block: 'center' });
+  let type = (elem.type || '').toLowerCase();
+  if (elem.tagName === 'SELECT') {
+    let option = Array.from(elem.options).find(o => o.value === value || o.text.trim() === value);
+    if (option === undefined) {
+      throw new Error('Option ' + value + ' not found in ' + selector);
+    }
+    elem.focus();
+    nativeSetValue(elem, option.value);
+    fireEvents(elem, 'input', 'change');
+    elem.blur();
+    return false;
+  }
+  if (type === 'checkbox' || type === 'radio') {
+    let checked = !['', 'false', 'off', '0'].includes(value.toLowerCase());
+    if (elem.checked !== checked) {
+      elem.click();
+    }
+    return false;
+  }
+  elem.focus();
+  nativeSetValue(elem, '');
+  fireEvents(elem, 'input');
+  return true;
+}
+
+// commitField fires change event of typed in field and moves focus out of it.
+function commitField(selector) {
+  let elem = formElement(selector);
+  fireEvents(elem, 'change');
+  elem.blur();
+}
+
+function focusField(selector) {
+  formElement(selector).focus();
+}
+
+function submitForm(selector) {
+  formElement(selector).click();
+}
@@ -0,0 +1,20 @@
+// Frame helpers used by frames request option.
+// Content of frames read by Fetch service is copied to the main document as <frame-document> elements.
+
+function frameDocument(html, name, url) {
+  let doc = document.createElement('frame-document');
+  doc.setAttribute('data-frame-name', name);
+  doc.setAttribute('data-frame-url', url);
+  doc.innerHTML = html;
+  return doc;
+}
+
+// appendFrameDocument appends frame content to the end of the main document body.
+function appendFrameDocument(html, name, url) {
+  (document.body || document.documentElement).appendChild(frameDocument(html, name, url));
+}
+
+// insertFrameDocument is called on iframe element. It inserts frame content right after it.
+function insertFrameDocument(html, name, url) {
+  this.after(frameDocument(html, name, url));
+}
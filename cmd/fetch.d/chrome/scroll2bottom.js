@@ -1,29 +1,31 @@
-function sleep(ms) {
-  return new Promise(resolve => setTimeout(resolve, ms));
+function documentHeight() {
+  return window.document.body.scrollHeight;
 }
 
-async function ScrollDown(pageCount, buttonCSSSelector = '') {
-  let docHeight = 0;
-  let delay = 500;
-  currentPageNum = 1;
-  while (docHeight < window.document.body.scrollHeight && currentPageNum < pageCount) {
-    // We have to reGet more button element reference every scroll 'cause
-    // element changes its location and old reference is not valid any more
-    moreButton = (buttonCSSSelector == '')? null: document.querySelector(buttonCSSSelector);
-    docHeight = window.document.body.scrollHeight;
-    if (moreButton == null) {
-      window.scrollTo(0, docHeight);
-    } else {
-      moreButton.click();
-    }
-    await sleep(delay);
-    if (docHeight == window.document.body.scrollHeight) {
-      delay += 500;
-      await sleep(3000);
-    } else {
-      currentPageNum++;
-    }
+// scrollStep loads the next page of infinite scroll or "Load more" paginator.
+// It returns document height before the step. The next page is loaded once network is idle.
+function scrollStep(buttonCSSSelector = '') {
+  let docHeight = documentHeight();
+  // We have to reGet more button element reference every step 'cause
+  // element changes its location and old reference is not valid any more
+  let moreButton = (buttonCSSSelector == '') ? null : findElement(buttonCSSSelector);
+  if (moreButton == null) {
+    window.scrollTo(0, docHeight);
+  } else {
+    moreButton.click();
+  }
+  return docHeight;
+}
+
+// findElement returns the first element matching selector. Detected "Load more" buttons are selected
+// by their text like 'button:contains("Load more")', which is not supported by querySelector.
+function findElement(selector) {
+  let m = selector.match(/^(.*):contains\((".*")\)$/);
+  if (m == null) {
+    return document.querySelector(selector);
   }
+  let text = JSON.parse(m[2]);
+  return Array.from(document.querySelectorAll(m[1] || '*')).find(e => e.textContent.includes(text)) || null;
 }
 
 function clickElement(selector) {
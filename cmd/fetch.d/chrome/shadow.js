@@ -0,0 +1,38 @@
+// Shadow DOM helpers used to return content of open shadow roots along with the page.
+// Shadow roots are inlined into a copy of the document as <shadow-root> first children of their hosts.
+// The copy is made in a separate document so custom elements are not constructed again.
+
+function inlineShadowRoots(doc, orig, copy) {
+  let found = false;
+  for (let i = 0; i < orig.children.length; i++) {
+    found = inlineShadowRoots(doc, orig.children[i], copy.children[i]) || found;
+  }
+  if (orig.shadowRoot) {
+    found = true;
+    let root = doc.createElement('shadow-root');
+    for (let child of orig.shadowRoot.childNodes) {
+      let c = doc.importNode(child, true);
+      if (child.nodeType === Node.ELEMENT_NODE) {
+        inlineShadowRoots(doc, child, c);
+      }
+      root.appendChild(c);
+    }
+    copy.insertBefore(root, copy.firstChild);
+  }
+  return found;
+}
+
+// flattenShadowRoots returns HTML of the document, or of its body if bodyOnly is true,
+// with open shadow roots inlined. Empty string is returned if there are no shadow roots.
+function flattenShadowRoots(bodyOnly = false) {
+  let doc = document.implementation.createHTMLDocument('');
+  let copy = doc.importNode(document.documentElement, true);
+  if (!inlineShadowRoots(doc, document.documentElement, copy)) {
+    return '';
+  }
+  if (bodyOnly) {
+    let body = copy.querySelector('body');
+    return body ? body.innerHTML : '';
+  }
+  return '<!DOCTYPE html>' + copy.outerHTML;
+}
@@ -0,0 +1,25 @@
+// Dataflow kit - main
+//
+// Copyright © 2017-2018 Slotix s.r.o. <dm@slotix.sk>
+//
+//
+// All rights reserved. Use of this source code is governed
+// by the BSD 3-Clause License license.
+
+// Parser CLI of the Dataflow kit sends payloads from files to Parse service endpoint and prints parse results info.
+//
+// Payloads may be written either in JSON or in YAML. Files with .yaml and .yml extensions are decoded as YAML.
+// Comments, multi-line selectors and anchors make complex payloads more maintainable in YAML.
+//
+// Examples
+//		./parse.cli --PAYLOAD examples/books.json
+//		./parse.cli -f examples/books.yaml
+//		./parse.cli -f examples/books.yaml -p 127.0.0.1:8001
+//
+// Flags and configuration settings
+//		DFK_PARSE: HTTP listen address of Parse service (defaults to "127.0.0.1:8001")
+//		PAYLOAD: payload file
+//
+package main
+
+// EOF
@@ -0,0 +1,118 @@
+// Copyright © 2018 Slotix s.r.o. <dm@slotix.sk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/healthcheck"
+	"github.com/slotix/dataflowkit/parse"
+	"github.com/slotix/dataflowkit/scrape"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//flag vars
+var (
+	DFKParse string
+	payload  string //payload file
+)
+
+// RootCmd represents the base command when called without any subcommands
+var RootCmd = &cobra.Command{
+	Use:   "dataflowkit",
+	Short: "DataFlow Kit parser CLI",
+	Long:  `Dataflow Kit Parser CLI sends payload from specified file to Parse service`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if payload == "" {
+			fmt.Fprintf(os.Stderr, "error: %v\n", errs.StatusError{Code: 400, Err: errors.New("no payload file specified")})
+			os.Exit(1)
+		}
+		p, err := loadPayload(payload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		status := healthcheck.CheckServices(healthcheck.ParseConn{
+			//Check if DFK Parse service is alive
+			Host: viper.GetString("DFK_PARSE"),
+		})
+		for k, v := range status {
+			if v != "Ok" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", k, v)
+				os.Exit(1)
+			}
+		}
+		svc, err := parse.NewHTTPClient(viper.GetString("DFK_PARSE"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		r, err := svc.Parse(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+	},
+}
+
+// loadPayload reads payload from file. Files with .yaml and .yml extensions are decoded as YAML, others as JSON.
+func loadPayload(path string) (scrape.Payload, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return scrape.Payload{}, err
+	}
+	return scrape.UnmarshalPayload(data, scrape.IsYAML(path))
+}
+
+// Execute adds all child commands to the root command sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute(version string) {
+	VERSION = version
+
+	if err := RootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
+
+func init() {
+	//flags and configuration settings. They are global for the application.
+	RootCmd.PersistentFlags().StringVarP(&DFKParse, "DFK_PARSE", "p", "127.0.0.1:8001", "DFK Parse service address")
+	RootCmd.Flags().StringVarP(&payload, "PAYLOAD", "f", "", "Payload file. JSON and YAML (.yaml, .yml) payloads are supported")
+
+	//Environment variable takes precedence over flag value
+	if os.Getenv("DFK_PARSE") != "" {
+		viper.BindEnv("DFK_PARSE")
+	} else {
+		viper.BindPFlag("DFK_PARSE", RootCmd.PersistentFlags().Lookup("DFK_PARSE"))
+	}
+}
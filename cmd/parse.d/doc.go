@@ -73,6 +73,51 @@ Request parameters are passed to Fetch Endpoint for downloading html pages.
 url holds the URL address of the web page to be downloaded. URL is required. All other fields are optional.
 userToken identifies every unique user making requests. Cookies are stored as key/value for each unique user to handle multiple requests to a domain.
 type specifies fetcher type which may be "base" or "chrome" value.
+headers and cookies are sent along with the request, f.e. {"headers": {"Accept-Language": "de"}, "cookies": {"region": "eu"}}.
+cookies may also be a list of cookies scoped to a domain and path, f.e. a consent cookie of all subdomains of a site:
+{"cookies": [{"name": "consent", "value": "yes", "domain": "example.com", "path": "/"}]}.
+autoConsent makes Chrome fetcher dismiss the banner of a known consent manager (OneTrust, Cookiebot, ...) before extraction.
+method may be GET, HEAD, POST, PUT, PATCH, DELETE or OPTIONS. formData is sent URL encoded, body is sent as is,
+f.e. JSON document of API request. Requests carrying either of them are sent with POST method if method is omitted.
+Content type of body is taken from Content-Type header. JSON body is sent as application/json by default.
+  "request": {"url": "https://api.example.com/items/42", "method": "PATCH", "body": "{\"stock\": 0}"}
+
+Seed URLs
+
+A list of "urls" turns the payload into a batch one. Every seed URL is fetched with request settings (fetcher type,
+cookies, form data) and processed with the same fields after request url, which may be omitted then.
+Seeds are scraped in turn along with their paginated pages. Up to PREFETCH_PAGES next seeds are fetched
+while the current one is extracted, and so are details pages of a block. Every record gets "source_url" field holding its seed URL.
+A failed seed doesn't stop the job. "URLs" of Parse response lists every seed with its status
+("OK", "Failed" or "Skipped" if the job has stopped before or the seed is excluded by "exclude" and "include"), the number of extracted records and an error if any.
+  "urls": ["http://books.toscrape.com/catalogue/category/books/poetry_23/index.html",
+    "http://books.toscrape.com/catalogue/category/books/travel_2/index.html"]
+A seed written as an object overrides "method", "formData", "body", "headers", "cookies" or fetcher "type" of the request
+for its URL, so a few POST searches may run among GET details pages. Headers and cookies are added to request ones.
+  "urls": ["http://example.com/item/1",
+    {"url": "http://example.com/search", "formData": "q=shoes", "cookies": {"region": "eu"}, "type": "chrome"}]
+
+URL lists
+
+"urlList" reads seed URLs from a file uploaded along with the payload, an S3 object or a URL returning a list.
+"format" is "txt" (a URL per line), "csv" (a header row followed by rows) or "json" (an array of URLs or objects).
+It is detected by file extension, Content-Type of the response or content if omitted.
+URLs are taken from "urlColumn" ("url" column or the first column by default). "vars" map variable names
+to columns holding per-URL values, f.e. product IDs. Values are substituted for {{variables}} in the URL
+and are added to every record extracted from it.
+  "urlList": {"location": "s3://my-bucket/products.csv", "urlColumn": "link", "vars": {"sku": "SKU"}}
+Upload a file with multipart form request holding the payload in "payload" field and the list in "urls" field:
+  curl -XPOST 127.0.0.1:8001/parse -F payload=@payload.json -F urls=@products.csv
+S3 objects are read with S3_ACCESS_KEY and S3_SECRET_KEY credentials from S3_ENDPOINT or AWS S3.
+
+Link checking
+
+Payload of "linkcheck" type sends HEAD request to request url and every seed URL instead of extracting fields,
+so large URL sets may be audited without downloading page bodies. Fields and paginator are not required.
+A record is stored for every URL with "url", "status", "final_url" (the URL redirects end at), "content_type",
+"content_length", "last_modified" and "error" of failed requests. Broken URLs don't stop the job.
+  {"type": "linkcheck", "urlList": {"location": "https://example.com/urls.txt"}, "format": "csv"}
+HEAD requests are sent by base fetcher only and are accepted in link check payloads only.
 
 Fields
 
@@ -84,6 +129,48 @@ Field name is required, and is used to aggregate results.
 Selector represents a CSS selector for data extraction within the given block. Pass in "." to use the root block's selector.
 
 Extractor contains the logic on how to extract some results from the selector that is provided to this Field.
+Every field chooses its own output: "text" type returns plain text, "html" returns inner HTML and "outerHtml"
+returns HTML of selected elements themselves. Text extractor param "ownText" excludes text of descendant elements.
+  {"name": "price", "selector": ".price", "extractor": {"types": ["text"], "params": {"ownText": true}}},
+  {"name": "description", "selector": ".description", "extractor": {"types": ["html"]}}
+"href", "src" and "srcset" extractors return absolute URLs resolved against the href of <base> element
+of the page or the page URL if there is none.
+"image" extractor returns image URLs of img elements preferring srcset candidates and lazy-load attributes
+data-src, data-lazy and data-original over src, which is often a placeholder. The largest srcset candidate
+is chosen unless "width" param sets the target width in pixels.
+  {"name": "photo", "selector": ".product img", "extractor": {"types": ["image"], "params": {"width": 800}}},
+"email" and "phone" extractors scan text and mailto:/tel: links of matched elements for email addresses and
+phone numbers. Use "body" selector to scan the whole page. Phone numbers are normalized to E.164 format,
+f.e. "+15550100199". "country" param sets ISO country code of numbers written without calling code.
+Numbers in text are taken if they start with "+" or are written as groups of digits, so dates and IDs are skipped.
+  {"name": "phones", "selector": "body", "extractor": {"types": ["phone"], "params": {"country": "US"}}},
+"count" extractor returns the number of matches of the selector, f.e. review count. Set "includeIfEmpty" param
+to get 0 instead of omitting the field. "exists" extractor returns true or false depending on whether
+the selector matches, f.e. presence of a sale badge.
+  {"name": "reviews", "selector": ".review", "extractor": {"types": ["count"], "params": {"includeIfEmpty": true}}},
+  {"name": "onSale", "selector": ".badge-sale", "extractor": {"types": ["exists"]}}
+Field "index" picks matches of the selector within a block by their position starting from 0 instead of all of them.
+It may be a single match "1", the last match "-1" or a range "start:end" with exclusive end, f.e. "0:3" or "2:".
+  {"name": "salePrice", "selector": ".price", "index": "1", "extractor": {"types": ["text"]}}
+Field "traverse" moves from elements matched by the selector to related ones, so values are located relative to
+stable landmarks like labels. Steps are "parent", "closest(selector)", "next", "prev", "children", "find(selector)"
+and "filter(selector)". "next(selector)" and "prev(selector)" move to the nearest sibling matching selector.
+  {"name": "sku", "selector": "th:contains('SKU')", "traverse": ["next(td)"], "extractor": {"types": ["text"]}}
+Selectors pick elements by their text with ":contains(text)" and ":containsOwn(text)" predicates matching text
+of the element with or without its descendants case-insensitively. ":matches(regexp)" and ":matchesOwn(regexp)" match it
+against a regular expression, ":has(selector)" picks elements containing matching ones. Predicates are used in field,
+traverse and paginator selectors as well as in selectors of blocks derived from fields.
+  {"name": "specs", "selector": "div:has(h3:contains('Specifications')) li", "extractor": {"types": ["text"]}}
+Field "textMatch" is a regular expression the trimmed text of elements matched by the selector must match.
+Other elements are skipped before traversal steps and index are applied.
+  {"name": "weight", "selector": "th", "textMatch": "^Weight(, kg)?$", "traverse": ["next(td)"], "extractor": {"types": ["text"]}}
+Fields matching nothing or extracting empty values are left out of records by default. Payload "onMissing" sets
+a policy for them: "empty" sets an empty string, "null" sets null, "drop" drops the record and "fail" stops the job
+with an error naming the field and the page. Field "onMissing" overrides the payload policy, field "default" sets
+the value of the field instead and implies "default" policy. Missing value policies turn off streaming extraction.
+  "onMissing": "null",
+  "fields": [{"name": "price", "selector": ".price", "onMissing": "fail", "extractor": {"types": ["text"]}},
+    {"name": "stock", "selector": ".stock", "default": "unknown", "extractor": {"types": ["text"]}}]
 
 Paginator
 
@@ -95,14 +182,526 @@ There are three paginator types.
 "Infinite scroll" automatically loads content while user scrolls page down.
 "Load more Button" looks like "Next link" but loads content on its click.
 
-Type represents paginator type. The following are available: "next", "more", "infinite"
+Type represents paginator type. The following are available: "next", "number", "more", "infinite"
 Selector represents corresponding CSS selector for the "Next" link or "Load more" Button paginator types page along with
 Attr belong exclusively to "Next" link paginator to define HTML element attribute for the next page.
+"Number" paginator type follows the link next to the current page of numbered pagination widget specified by Selector.
+
+If "autoPaginate" is true and Paginator is omitted, pagination is detected on the first page.
+rel=next links, "next" links, numbered pagination widgets and "Load more" buttons are followed up to MAX_PAGES pages.
+
+
+Intercepted responses
+
+Data loaded by the page asynchronously may be extracted from JSON responses of XHR and fetch requests directly.
+Request "intercept" lists URL patterns of such requests. Chrome fetcher inserts their responses into the page
+as <script type="application/json" data-intercepted="request URL"> elements, so they are selected like other
+elements and parsed with "json" extractor. Its "path" param is a dot separated list of keys and array indexes
+where "*" takes all elements.
+  "request": {"type": "chrome", "url": "https://example.com/shop", "intercept": ["https://example.com/api/products*"]},
+  "fields": [{"name": "price", "selector": "script[data-intercepted*='/api/products']",
+    "extractor": {"types": ["json"], "params": {"path": "items.*.price"}}}]
+
+Frames
+
+Request "frames" makes fetcher capture content of frames, f.e. embedded widgets or comments.
+Base fetcher fetches frames from the host of the page only, Chrome fetcher captures all rendered frames.
+Content of every frame is returned as <frame-document data-frame-name="frame name" data-frame-url="frame URL">
+element. "inline" inserts it right after its iframe element, "documents" appends all frames to the end of page body.
+  "request": {"type": "chrome", "url": "https://example.com", "frames": "documents"},
+  "fields": [{"name": "review", "selector": "frame-document[data-frame-url*='reviews'] .review", "extractor": {"types": ["text"]}}]
+
+Shadow DOM
+
+Chrome fetcher inlines open shadow roots into the returned page as <shadow-root> first children of their hosts
+if request "pierceShadow" is true. ">>>" combinator in field, paginator and watch selectors descends from a host
+element into its shadow tree. Payloads using it turn "pierceShadow" on, pages of other payloads are not changed.
+  "fields": [{"name": "price", "selector": "product-card >>> .price", "extractor": {"types": ["text"]}}]
+
+Navigation steps
+
+Stateful flows like accepting cookies, searching and opening a result are performed in one Chrome session with
+request "steps". Every step performs one of "navigate" (URL, may be relative), "click" (selector) or "fillForm"
+and may wait for "networkidle". Fields are extracted from snapshots of pages taken after steps marked "extract".
+Every snapshot is wrapped into <div data-step="step name" data-step-url="page URL">, so "[data-step=result]"
+selects fields of a particular step.
+  "request": {"type": "chrome", "url": "https://example.com", "steps": [{"click": "#accept-cookies"},
+    {"fillForm": {"fields": [{"selector": "#q", "value": "phone"}]}, "waitUntil": "networkidle"},
+    {"click": ".result a", "name": "result", "extract": true}]}
+
+Recorded sessions
+
+"recording" is a user flow recorded by Chrome DevTools Recorder and exported as JSON. It is replayed by Chrome
+fetcher as request steps, so a complex flow may be captured once in a browser. Request URL is taken from the first
+navigate step if it is omitted. navigate, click and doubleClick steps are replayed with the first CSS selector of
+the target, change steps are collected into "fillForm" submitted by the following click or Enter key.
+Other steps like setViewport, scroll or waitForElement are skipped.
+  "recording": {"title": "Search", "steps": [{"type": "navigate", "url": "https://example.com"},
+    {"type": "change", "value": "phone", "selectors": [["aria/Search"], ["#q"]]}, {"type": "keyDown", "key": "Enter"}]}
+
+Splash requests
+
+Request "pause" is the time in milliseconds Chrome fetcher waits after the page is loaded and steps are performed,
+up to 30 seconds. "block" lists URL patterns of resources Chrome doesn't load, "*" matches any characters.
+  "request": {"type": "chrome", "url": "https://example.com", "pause": 1500, "block": ["*.woff2", "*://ads.example.com/*"]}
+Requests of legacy Splash payloads are accepted, so they are migrated without rewriting. "wait" seconds become
+"pause", "images": 0 blocks images and "filters" names Adblock Plus filter files <name>.txt in SPLASH_FILTERS
+directory whose URL blocking rules are converted to "block" patterns. "http_method" sets the request method.
+"lua_source" script is not run in the browser. Its main function is called once with splash object recording
+splash:go, splash:wait, splash:png, splash:set_user_agent, splash:set_custom_headers and splash.images_enabled,
+which are converted to the request URL, navigation steps, pause, screenshot, headers and blocked images.
+Scripts calling other splash methods are rejected and should be rewritten with steps.
+The script is run in the sandbox of payload scripts, see Scripts, when the request is received.
+  "request": {"url": "https://example.com", "wait": 0.5, "images": 0,
+    "lua_source": "function main(splash, args) assert(splash:go(args.url)) splash:wait(args.wait) return splash:html() end"}
+
+Robots directives
+
+If "robotsMeta" is true, pages marked noindex by meta robots tag or X-Robots-Tag header are not extracted
+and links marked nofollow by rel attribute or by page level nofollow directive are not followed to paginated
+and details pages. It is on by default in polite mode enabled with POLITE.
+  "robotsMeta": true
+
+URL patterns
+
+"include" and "exclude" lists gate seed URLs and links to paginated and details pages. A URL is fetched and its page
+is extracted if it matches one of "include" patterns (if any) and none of "exclude" patterns.
+Patterns enclosed in slashes are regular expressions searched anywhere in URL. Other patterns are globs
+matching the whole URL where "*" matches any sequence of characters and "?" matches a single character.
+A page redirected to a URL which doesn't pass the patterns is not extracted. Payload with excluded request URL is rejected
+unless it is a batch one, where excluded seeds are skipped.
+  "include": ["https://example.com/catalog*"],
+  "exclude": ["*calendar*", "/[?&](sessionid|sort)=/"]
+
+Login
+
+"login" signs in to a web site with Chrome fetcher before pages are fetched. The form on the login page is filled in
+and submitted (see "fillForm" of Fetch service) and the page loaded afterwards must contain "success" element.
+Login is performed once per request "userToken". Cookies and localStorage of the session are kept for the token
+by Fetch service and reused by all subsequent fetches of the payload, including details pages.
+If a fetched page contains "loggedOut" element, login is repeated and the page is fetched again.
+  "request": {"url": "https://example.com/orders", "userToken": "shop-user"},
+  "login": {
+    "url": "https://example.com/login",
+    "form": {"fields": [{"selector": "#email", "value": "user@example.com"}, {"selector": "#password", "value": "secret"}],
+             "submit": "button[type=submit]"},
+    "success": "a.logout",
+    "loggedOut": "form#login"
+  }
+
+Job limits
+
+"limits" stop the job once the number of fetched pages (including paginated and details pages),
+total size of fetched pages in bytes or duration in seconds reaches them. Pages being processed are completed,
+no more pages are fetched and results extracted so far are returned with "Status": "Limit reached: max pages".
+JOB_MAX_PAGES, JOB_MAX_BYTES and JOB_MAX_DURATION set ceilings which can't be exceeded by payload limits.
+  "limits": {"maxPages": 500, "maxBytes": 104857600, "maxDuration": 600}
+
+Referer
+
+Requests of paginated and details pages are sent with Referer header set to the URL of the page the link
+has been found on, as many sites serve different content to requests without Referer or block them.
+Like browsers do, only the origin is sent to other sites and nothing is sent from HTTPS pages to HTTP ones.
+"noReferer": true turns it off.
+
+Request delay
+
+"delay" spaces out consecutive requests to the same host by a random time between "min" and "max" milliseconds
+in addition to robots.txt Crawl-delay, which is honored unless IGNORE_ROBOTS_TXT is set. Unlike fixed intervals random ones don't make crawl traffic periodic,
+which is tracked by some sites to ban crawlers. It replaces FETCH_DELAY and RANDOMIZE_FETCH_DELAY settings
+for the payload. IGNORE_FETCH_DELAY disables it.
+  "delay": {"min": 1000, "max": 4000}
+
+Sitemaps
+
+URLs listed in Sitemap directives of robots.txt of the visited hosts are returned in "Sitemaps" of Parse response,
+so they may be used as seeds of the next payload.
+  "Sitemaps": ["https://example.com/sitemap.xml"]
+
+Near duplicates
+
+If "skipNearDuplicates" is true, simhash fingerprint of visible text of every fetched page is computed.
+Pages nearly identical to already extracted ones, f.e. print views or URLs differing in tracking parameters only,
+are not extracted. The number of skipped pages is returned in "Near duplicates" of Parse response.
+
+Unchanged pages
+
+If "skipUnchanged" is true, initial and paginated pages are compared with the previous run of the payload.
+Base fetcher requests them with If-None-Match and If-Modified-Since built from ETag and Last-Modified
+of the previous response. Pages answered with 304 Not Modified or having the same content hash as before
+are not extracted, records extracted from them by the previous run are kept. Details pages of unchanged pages
+are not fetched. Unchanged pages are counted in "unchanged" of the job report and in "Unchanged pages"
+of Parse response. Seeds of batch payloads get "Unchanged" status. Page states are not stored by jobs stopped by a limit.
+  "skipUnchanged": true
 
+Fetch metrics
+
+"Fetches" of Parse response summarizes fetched pages per fetcher type: the number of pages and failures,
+average latency in milliseconds, total size and the number of pages within every latency (seconds) and size (bytes) bucket.
+  "Fetches": {"chrome": {"count": 12, "errors": 0, "avgLatency": 2310, "latency": {"2.5": 9, "5": 3}, "bytes": 2104331, "size": {"250000": 12}}}
+The same is exported by GET /metrics as dfk_parse_service_fetch_latency_seconds and dfk_parse_service_fetch_response_bytes
+Prometheus histograms labeled with "fetcher", so SLOs can be monitored and the cost of Chrome and Base fetchers compared.
+"network" of Base fetcher summary tells slow network path from slow target: the share of pages received over
+reused kept-alive connections and average DNS lookup, TCP connect and TLS handshake times of new connections
+along with average time to the first byte of response (TTFB), all in milliseconds. High connection setup times
+point to the network, TTFB much longer than connection setup points to the target.
+  "network": {"traced": 40, "reused": 36, "reuseRate": 0.9, "avgDns": 12, "avgConnect": 35, "avgTls": 70, "avgTtfb": 640}
+
+If "fetchMetadata" is true, every record gets fields describing the fetch of the page it is extracted from:
+"fetched_at" (RFC 3339 UTC), "fetch_ms", "response_bytes", "cache_hit" (the page is read from a source archive
+or served from fixtures) and "fetcher" type. Fields with the same names extracted from the page are kept.
+Records of unchanged pages (see Unchanged pages) keep metadata of the fetch they have been extracted from.
+  "fetchMetadata": true
 
 Format
 
 The following Output formats are available: CSV, JSON, XML
+
+Language
+
+If "detectLanguage" is true, every record gets "language" field containing ISO 639-1 code
+of the language detected over extracted text, f.e. "en", "de" or "ja". Records with too short texts
+or texts in unknown languages are left without it.
+
+Scripts
+
+Transformations too complex for declarative filters may be written in Lua.
+Field extractor's "script" receives extracted value as "value" global and returns a new value. Nil excludes it.
+Payload's "script" receives every record as "record" table and returns a new table. Nil or false drops the record.
+Scripts run in a sandbox with base, table, string and math libraries only. Every invocation is limited
+by SCRIPT_TIMEOUT, SCRIPT_MAX_STACK and SCRIPT_MAX_MEMORY. Scripts holding more than SCRIPT_MAX_MEMORY bytes
+in strings and tables are aborted. Memory is measured while the script runs, so it may be exceeded briefly.
+Strings built by string.rep, string.gsub, string.format, table.concat and concatenation may not be longer
+than 1 MB or the longest string passed to the script.
+  "extractor":{"types":["text"], "script":"return tonumber((value:gsub('[^%d.]', '')))"}
+  "script":"if record.Price_text > 100 then return nil end record.Currency = 'USD' return record"
+
+Custom extractors and formats
+
+Custom extractor types and output formats are registered by name with scrape.RegisterExtractor and
+scrape.RegisterEncoder and referenced from payload's extractor "types" and "format" like built-in ones.
+Applications embedding scrape package call them directly. Parse service loads Go plugins from PLUGINS_DIR
+which call them from their init functions.
+A new extractor is created by the registered factory for every field of the type. Extractors implementing
+scrape.ConfigurableExtractor get params and filters of the field.
+  // go build -buildmode=plugin -o plugins/upper.so upper.go
+  func init() {
+      scrape.RegisterExtractor("upper", func() extract.Extractor { return &upperText{} })
+  }
+
+Enrichment
+
+"enrich" sends every extracted record to external HTTP endpoint and merges returned fields back in,
+f.e. geocoding, categorization or sentiment. Records are posted one by one as JSON objects or in batches
+as JSON arrays if "batchSize" is greater than 1. Failed requests leave records as they are.
+"concurrency" and "timeout" are capped by ENRICH_CONCURRENCY and ENRICH_TIMEOUT.
+  "enrich":{"url":"http://127.0.0.1:9000/sentiment", "batchSize":50, "concurrency":4, "timeout":10,
+    "headers":{"Authorization":"Bearer <token>"}}
+
+YAML payloads
+
+Payloads may be sent in YAML instead of JSON with Content-Type header set to application/x-yaml.
+Comments, multi-line selectors and anchors make complex payloads more maintainable.
+  curl -XPOST 127.0.0.1:8001/parse -H "Content-Type: application/x-yaml" --data-binary @examples/books.yaml
+
+Request ID
+
+Every request gets a correlation ID taken from X-Request-ID header or generated if the header is missing or is not
+up to 64 letters, digits, ".", "_" and "-".
+It is returned in X-Request-ID response header, in "Request ID" of Parse response and in error messages.
+The ID is passed to Fetch service with every fetch request caused by the parse request and is added to log lines
+of both services, so all the steps of processing a single failed URL may be found by the ID.
+
+Errors
+
+Failed requests get JSON error response with a stable error code clients may branch on:
+  {"code":"fetch_failed","message":"...","retryable":false,"details":{...},"upstreamStatus":403,"requestId":"...","status":502}
+Codes are bad_request, unauthorized, forbidden, not_found, conflict, too_large, unsupported_media_type,
+rate_limited, canceled, timeout, fetch_failed, no_blocks, unavailable and internal.
+Details of fetch_failed describe the response of the web site, details of no_blocks list unmatched selectors.
+Failed fetches are returned with 502 Bad Gateway status, the status of the web site is kept in "upstreamStatus".
+
+Rate limits and quotas
+
+If RATE_LIMIT or QUOTA is set, requests are counted for every API key or JWT subject authenticated
+by access control (see Access control). Requests of anonymous clients, including ones sending tokens
+which are not API keys or valid JWTs, are counted by client IP address. Every response contains current usage so clients
+may throttle themselves:
+  X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset
+  X-Quota-Limit, X-Quota-Used, X-Quota-Remaining, X-Quota-Reset
+Reset headers contain Unix time when the window or quota period is over. Requests exceeding the limit
+are rejected with 429 Too Many Requests and Retry-After header.
+
+Links
+
+Links endpoint returns all links found on a web page without a Payload. It may be used for link audits and for seeding crawls.
+Request body has the same structure as Payload's Request.
+  curl -XPOST  127.0.0.1:8001/links -d '{"url":"https://example.com", "type":"base"}'
+Every link in the response contains raw href, absolute url, anchor text, rel attribute, nofollow flag
+and internal flag which is true for links pointing to the same host as requested page.
+
+Selector suggestions
+
+Suggest endpoint takes a web page and one or two example values a user wants to extract.
+It returns up to 10 candidate CSS selectors matching all examples along with extractor type, the number of
+matching elements and sample values. Suggestions are ranked by stability, the number of matches and specificity.
+Selectors containing generated class names or ids are considered less stable.
+  curl -XPOST 127.0.0.1:8001/suggest -d '{"request":{"url":"http://books.toscrape.com"},
+    "examples":["A Light in the Attic", "Tipping the Velvet"]}'
+
+Auto extraction
+
+Auto endpoint detects the dominant repeating structure on a web page (list or grid items) without a Payload.
+It returns item selector, records with best-guess fields (title, link, image and price-like text) and
+a Payload built from detected selectors which may be used as a zero-config starting point.
+Detected pagination is offered as Payload's paginator.
+  curl -XPOST 127.0.0.1:8001/auto -d '{"url":"http://books.toscrape.com"}'
+
+Results
+
+Parse response contains "Results ID". Large result sets may be retrieved page by page
+instead of downloading the whole output file.
+  curl 127.0.0.1:8001/results/<Results ID>?offset=0&limit=100
+The response contains total count of results along with requested results.
+Limit defaults to 100 and cannot exceed 1000.
+
+Source archives
+
+Pages may be extracted from previously captured responses instead of the network. Put WARC (.warc, .warc.gz)
+or HAR (.har) file to SOURCE_DIR of Parse service and specify its name as payload "source". Payload request URL,
+paginated and details pages are looked up in the archive by URL. Pages missing in the archive are reported as not found.
+HAR files may be exported from browser developer tools to extract data from captured browsing sessions.
+  {"name": "archived books", "source": "books.warc.gz", "request": {"url": "http://books.toscrape.com/"},
+    "fields": [{"name": "Title", "selector": "h3 a", "extractor": {"types": ["text"]}}]}
+
+Page snapshots
+
+Set payload "snapshots" option to store HTML of every fetched page exactly as it was fetched or rendered by Chrome.
+Snapshots of SNAPSHOT_RUNS latest runs of the payload are kept for the Results ID, so pages may be extracted
+again later or kept as evidence of data extracted by every run.
+  curl 127.0.0.1:8001/results/<Results ID>/snapshots
+returns the list of stored pages with the run which took them, their URLs, fetch time and size.
+  curl '127.0.0.1:8001/results/<Results ID>/snapshots?run=<run>'
+lists pages stored by the run only.
+  curl '127.0.0.1:8001/results/<Results ID>/snapshot?url=http%3A%2F%2Fbooks.toscrape.com%2F'
+returns HTML of the latest snapshot of the page. Add "run" parameter to get the page as it was fetched by the run.
+A payload, f.e. with updated fields, may be processed over the latest snapshots without fetching pages again:
+  curl -XPOST 127.0.0.1:8001/results/<Results ID>/reparse -d @payload.json
+Pages missing in snapshots, f.e. details pages of new fields, are reported as not found.
+Pages marked noarchive or nosnippet by meta robots tag or X-Robots-Tag header are extracted but not stored
+unless IGNORE_NOARCHIVE is set.
+
+WARC archive
+
+Set payload "warc" option to write request and resource records of every fetched page to a gzipped WARC file
+in RESULTS_DIR along with output file. Parse response contains its path as "WARC file". The file may be
+processed by standard web archive tools. Fetch service passes content of successful responses only without
+their status and headers, so pages are archived as resource records with Content-Type detected from content.
+Pages marked noarchive are not written to WARC file unless IGNORE_NOARCHIVE is set.
+
+Query
+
+Results of the latest QUERY_STORE_RUNS runs of every payload are kept and may be queried with field filters,
+sorting and projection. F.e. all products cheaper than 10 across the last 5 runs sorted by price:
+  curl -XPOST 127.0.0.1:8001/query -d '{"id":"<Results ID>", "runs":5,
+    "filters":[{"field":"Price_text", "op":"<", "value":10}],
+    "sort":[{"field":"Price_text"}], "fields":["Title_text", "Price_text"]}'
+Filter operators: "=", "!=", "<", "<=", ">", ">=", "contains".
+Values are compared as numbers if both of them are numeric.
+Every returned record contains "_run" and "_time" fields identifying the run it was extracted by.
+
+Payload registry
+
+Named payloads may be saved to the registry and reused by name instead of being sent with every request.
+Every update creates a new version of a payload. All versions are kept until the payload is deleted.
+  POST   /payloads                   creates a new payload. Payload name is required.
+  GET    /payloads                   lists saved payloads
+  GET    /payloads/{name}            returns the latest version or the one specified with ?version=N
+  PUT    /payloads/{name}            saves a new version of the payload
+  DELETE /payloads/{name}            removes the payload with all its versions
+  GET    /payloads/{name}/versions   returns version history
+  POST   /payloads/{name}/parse      runs the latest version or the one specified with ?version=N
+
+A payload may extend a saved one with "extends": "name" or "extends": "name@version".
+Base payload settings, request settings and fields are inherited. Non-empty settings of extending payload
+override inherited ones. Fields with the same names replace inherited fields, other fields are appended.
+Base payloads may extend other payloads too. Inheritance is resolved by Parse service.
+  {"name":"books-site-a", "extends":"books-base", "request":{"url":"https://site-a.example"}}
+
+Scheduled runs
+
+If SCHEDULER is true, saved payloads with "schedule" are run periodically, one at a time. Results of every run
+are compared with the previous run (see QUERY_STORE_RUNS). Change statistics are kept for every seed URL,
+so they are shared by payloads scheduled over the same page and survive payload changes. Records of batch
+payloads are attributed to seed URLs by "source_url". With "adaptive" schedule the interval of a URL is halved
+after a run with changed results of the URL and increased by half after a run without changes within RECRAWL_MIN_INTERVAL
+and RECRAWL_MAX_INTERVAL, so fast-changing pages are visited often and static ones rarely. The payload runs
+at the shortest interval of its URLs.
+Current interval, next run time and change statistics of URLs are returned as "recrawl" by GET /payloads.
+  "schedule": {"interval": 3600, "adaptive": true}
+When several replicas share the storage, the one holding the scheduler lease for LEADER_LEASE seconds checks
+watches and dispatches due payloads. The leader renews the lease while it runs, and another replica takes over
+when it stops. Dispatched payloads are run by any replica with SCHEDULER, every run by the replica holding
+its run lease, so a run is not repeated and a run left by a stopped replica is taken over when the lease
+expires. All replicas keep serving parse requests. Leases are granted by MongoDB, Cassandra or by Diskv shared
+by replicas on the same host.
+
+With "prewarm" schedule, seed pages and their first level of details and next pages are fetched and cached
+within "prewarm" seconds before the run, during PREWARM_HOURS only. The run takes cached pages instead of
+fetching them, so it finishes quickly within tight delivery windows. Cached pages are taken once and expire
+an interval after the run. Payloads with login or source archive are not prewarmed.
+  "schedule": {"interval": 86400, "prewarm": 14400}
+
+Alerts
+
+"alerts" of a scheduled payload send a summary with a link to results to Slack incoming webhook,
+generic webhook (JSON with added and removed records) or email (see SMTP_HOST) when a run detects changes
+matching the rule: "any" change of results, change of "field" values or "threshold" crossing, i.e. more
+records having numeric field satisfying "op" and "value" than in the previous run.
+  "alerts": [
+    {"rule": "threshold", "field": "price", "op": "<", "value": 100, "slack": "https://hooks.slack.com/services/..."},
+    {"rule": "field", "field": "stock", "webhook": "https://example.com/hook", "email": ["ops@example.com"]}
+  ]
+
+"canary" of a scheduled payload lists invariants every run is expected to hold: "minRecords" count, known
+"records" which must appear (a record matches if it has all listed values) and "numeric" fields which must parse
+as numbers. Fields are named by record keys as they appear in results. A run violating them is marked suspect:
+the status of its job report becomes "Suspect", violations are listed in "suspect" of the report and of "recrawl"
+state, and a summary is sent to channels of all alerts instead of change notifications. Failed runs, f.e. failed
+by a fill rate gate, are suspect as well, with the error listed in "suspect" of "recrawl" state. Suspect runs don't
+count as changes of adaptive schedule.
+  "canary": {"minRecords": 20, "records": [{"title_text": "Main Street Lamp"}], "numeric": ["price_text"]}
+
+Job report
+
+A report of every completed job is returned in "Report" of Parse response and is kept with results until
+the next run. GET /results/{id}/report returns the latest one. It counts pages processed OK and failed ones
+by reason ("HTTP 404", "no blocks", "near duplicate", "binary content", ...), emitted records, the share of records filling every field
+and breaks duration down into login, scrape, enrich and encode stages along with fetch, extraction and storage time
+summed up over workers. Fields filled in less than REPORT_LOW_FILL_RATE of records are listed in "lowFill".
+"fillGates" set minimum fill rates of payload fields ("*" for every field). Fields below them are listed
+in "fillViolations". Gates with "fail" action fail the job with 422 status once results are stored, so selectors
+broken by a site redesign don't go unnoticed. "warn" gates (default) only report and log violations.
+  "fillGates": [{"field": "price", "minRate": 0.9, "action": "fail"}, {"field": "*", "minRate": 0.5}]
+Pages rendered by Chrome which logged console errors, threw uncaught exceptions or had failed requests (blocked scripts,
+404 assets, XHR denied by the site, ...) are listed in "diagnostics" (up to 20 pages). "failedRequests" counts failed
+requests by reason and resource type, f.e. "HTTP 403 (Document)" or "blocked: csp (Script)", so a site blocking the job
+may be told from a wrong selector. Diagnostics of the page are added to "no blocks" errors as well.
+"report" sends it to Slack, a webhook (JSON report) or email once the job completes.
+  "report": {"webhook": "https://example.com/jobs", "email": ["ops@example.com"]}
+
+Watches
+
+A watch tracks a single value such as price or stock status without a full payload. If SCHEDULER is true,
+the page is fetched every "interval" seconds and the text (or "attr" attribute) of the first element
+matching "selector" is appended to the time series of the watch. Failed checks are kept with an error.
+  curl -XPOST 127.0.0.1:8001/watches -d '{"name":"phone-price", "request":{"url":"https://example.com/phone"},
+   "selector":".price", "interval":3600}'
+GET /watches lists watches with their latest values, GET /watches/{name}?limit=100 returns the latest
+values of the watch, PUT /watches/{name} replaces it and DELETE /watches/{name} removes it with its values.
+
+Variables
+
+Payload request URL, form data, body, login form and const field values may contain {{variables}} substituted with "vars"
+at submission time, so one payload can serve many searches or regions.
+  {"name":"search", "request":{"url":"https://example.com/search?q={{query}}&zip={{zip}}"},
+   "vars":{"query":"laptops", "zip":"10001"}, ...}
+Variables of saved payloads serve as defaults. They may be overridden when a saved payload is run:
+  curl -XPOST 127.0.0.1:8001/payloads/search/parse -d '{"vars":{"query":"tablets"}}'
+Parse fails if a variable is undefined. Values are escaped for the URL part they are in: query escaped after "?",
+so they can't add query parameters, and path escaped before it. Form data values are query escaped.
+
+Credentials
+
+Logins, passwords and session headers of a site may be kept in the credentials vault instead of payload files.
+Credentials are encrypted with CREDENTIALS_KEY before they are stored. "domain" restricts them to payloads
+fetching pages of the domain and its subdomains.
+  curl -XPOST 127.0.0.1:8001/credentials -d '{"name":"shopX", "domain":"shopx.com", "username":"bot@example.com",
+   "password":"secret", "headers":{"X-Api-Key":"k1"}, "cookies":{"region":"eu"}}'
+A payload refers to them by name. Their headers, cookies and "formData" are added to the request unless the payload
+sets them, username and password are available as {{credentials.username}} and {{credentials.password}} variables
+in login form fields, form data, body and headers. They are refused in URLs and const field values, which end up
+in results. Credentials are attached to every fetched page of the domain only: paginated and details pages
+of other hosts are requested without them. Credentials belong to the tenant of the client which saved them (see Access control) and only payloads
+of the tenant may refer to them. Credentials saved without a tenant are used by scheduled runs.
+  {"name":"orders", "credentials":"shopX", "request":{"url":"https://shopx.com/orders", "userToken":"u1"},
+   "login":{"url":"https://shopx.com/login", "form":{"fields":[{"selector":"#email", "value":"{{credentials.username}}"},
+   {"selector":"#password", "value":"{{credentials.password}}"}]}, "success":".logout"}, ...}
+GET /credentials lists names, domains and header names of credentials of the tenant. Secrets are never returned.
+PUT /credentials/{name} replaces credentials and DELETE /credentials/{name} removes them.
+
+Cache admin
+
+GET /cache lists stored items (cached pages, cookies, intermediate results, reports, snapshots, ...) with their
+sizes in bytes and ages in seconds. DELETE /cache deletes matching items and returns their number. Items are
+filtered by query parameters: "type" (Cache, Cookies, Intermediate or Binary), key "prefix", "domain" of the host
+in the key, f.e. "example.com" matches "www.example.com" but not "myexample.com", and "olderThan" seconds. "limit" caps the number of listed items. DELETE requires at least one filter.
+They require admin role (see Access control).
+Listing is supported by Diskv and MongoDB storage. Diskv keeps all types together, so "type" is ignored
+by GET and refused by DELETE there.
+  curl -H 'X-API-Token: secret' '127.0.0.1:8001/cache?domain=example.com&limit=100'
+  curl -XDELETE -H 'X-API-Token: secret' '127.0.0.1:8001/cache?prefix=snapshot-&olderThan=604800'
+
+User tokens
+
+"userToken" of a request keeps cookies, localStorage and login sessions of a user between requests.
+If USER_TOKEN_SECRET is set, only tokens issued by POST /tokens are accepted, so a client can't guess the token
+of another one and take over its sessions. Tokens are signed with USER_TOKEN_SECRET and their claims are encrypted
+if USER_TOKEN_KEY is set. "ttl" sets the number of seconds the token is valid for. Issued tokens don't expire by default.
+  curl -XPOST 127.0.0.1:8001/tokens -d '{"ttl": 86400}'
+  {"userToken":"s1.eyJpZCI6...","expires":"2019-01-02T15:04:05Z"}
+Cookies and sessions kept for free-form tokens are not available once USER_TOKEN_SECRET is set.
+
+Access control
+
+API clients are identified by API keys listed in API_KEYS_FILE or by JWTs signed with JWT_SECRET (HS256)
+sent in X-API-Token or "Authorization: Bearer" header. ADMIN_TOKEN is an API key with admin role.
+  [{"key": "k1", "role": "user", "tenant": "acme"}, {"key": "k2", "role": "operator"}]
+JWT claims are "sub", "role", "tenant" and optional "exp". Roles are:
+  user      runs parse jobs and reads results of jobs run by its tenant
+  operator  reads results of all tenants, changes payloads and watches saved to the registry (schedules)
+            and manages the credentials vault
+  admin     lists and purges stored items with /cache and purges data with /purge
+Every role is granted access of lower ones. Results are owned by tenants which ran parse jobs producing them.
+Results of jobs run without a tenant are available to everyone. If neither API_KEYS_FILE nor JWT_SECRET is set,
+anonymous clients are granted user role, so changing the registry and the vault requires ADMIN_TOKEN.
+Set ANONYMOUS_OPERATOR to grant them operator role on trusted networks.
+
+Data retention
+
+Results of parse jobs (output and WARC files, stored records, runs, reports and page snapshots) are purged
+RETENTION_RESULTS seconds after the job finished. Snapshots alone may be purged earlier with RETENTION_SNAPSHOTS.
+Purges and the reaper record deleted data in the audit log kept as Binary "audit" item. Its entries are deleted
+RETENTION_AUDIT seconds after the deletion. RETENTION_FILE overrides the settings for tenants:
+  {"acme": {"results": 2592000, "snapshots": 604800, "audit": 31536000}, "globex": {"results": 86400}}
+Results shared by several tenants are kept as long as the longest policy of their owners requires.
+Audit entries of purges by tenant follow the policy of the tenant, others follow the global one.
+The reaper checks retention every RETENTION_INTERVAL seconds. Jobs are aged by their reports, so it requires
+Diskv or MongoDB storage. Jobs with unreadable reports are skipped and logged. 0 keeps data forever.
+With LEADER_LEASE, only the replica elected leader runs the reaper. Access logs written to the service output
+are retained by the log collector of the deployment.
+POST /purge deletes data at once and requires admin role. "id" purges all data of a job, "tenant" purges jobs
+owned by the tenant only and removes the tenant from owners of shared ones, "url" purges snapshots of a page
+taken by the job "id" or by all jobs.
+  curl -XPOST -H 'X-API-Token: secret' 127.0.0.1:8001/purge -d '{"tenant": "acme"}'
+  {"jobs":["2735176284"],"released":["1801438233"],"snapshots":12}
+
+Configuration profiles
+
+Settings may be kept in named profiles instead of flags. A profile is a YAML, JSON or TOML file in PROFILES_DIR
+named after the profile, f.e. profiles/prod.yaml. Settings may be grouped into sections which are flattened:
+  proxy:
+    PROXY: http://proxy.internal:3128
+  limits:
+    MAX_PAGES: 50
+    JOB_MAX_DURATION: 600
+  storage:
+    STORAGE_TYPE: MongoDB
+    MONGO: mongo.internal:27017
+DFK_PROFILE selects comma separated profiles applied in order, so customer overlays go after the environment:
+  parse.d --DFK_PROFILE prod,acme
+Profiles override flag defaults. Flags passed on the command line and environment variables override profiles.
+Fetch service reads the same profiles, so CHROME and PROXY of an environment may be kept together with Parse settings.
 */
 //
 // Flags and configuration settings
@@ -112,6 +711,10 @@ The following Output formats are available: CSV, JSON, XML
 //
 //    DFK_FETCH: HTTP listen address of Fetch service (defaults to "127.0.0.1:8000")
 //
+//    DFK_PROFILE: Comma separated configuration profiles. Later profiles override earlier ones. (defaults to "")
+//
+//    PROFILES_DIR: Directory of configuration profiles (defaults to "profiles")
+//
 //Storage settings
 //
 //    STORAGE_TYPE: Storage backend for intermediary data passed to Dataflow
@@ -123,6 +726,7 @@ The following Output formats are available: CSV, JSON, XML
 //
 //    DISKV_BASE_DIR: diskv base directory for storing parsed results (defaults to "diskv").
 //    RESULTS_DIR: Directory for storing results (defaults to "results").
+//    SOURCE_DIR: Directory of WARC and HAR files used as payload source (defaults to "sources").
 //    Find more information about Diskv storage at https://github.com/peterbourgon/diskv
 //    CASSANDRA: Cassandra host address (defaults to "127.0.0.1")
 //
@@ -141,10 +745,34 @@ The following Output formats are available: CSV, JSON, XML
 //    crawler being blocked. This way a random delay ranging from 0.5 * FetchDelay
 //    to 1.5 * FetchDelay seconds is used between consecutive requests to the same
 //    domain. If FetchDelay is zero this option has no effect. (defaults to true)
+//    Payloads with "delay" are not delayed by FETCH_DELAY and RANDOMIZE_FETCH_DELAY.
 //
 //    IGNORE_FETCH_DELAY: Ignores fetchDelay setting intended for debug purpose.
 //    Please set it to false in Production
 //
+//    PREFETCH_PAGES: The number of seed and details pages of a job fetched ahead
+//    while earlier pages are extracted. Set it to 0 to fetch pages one by one. (defaults to 4)
+//
+//    MAX_PENDING_PAGES: The number of fetched pages of a job held in memory until their blocks
+//    are extracted and stored. If extraction or results storage can't keep up, fetching of
+//    the next pages waits for them instead of buffering pages. Set it to 0 for no limit. (defaults to 10)
+//
+//    AUTO_PAGINATE: Pagination is detected on the first page if payload has no paginator.
+//    rel=next links, "next" links, numbered pagination widgets and "Load more" buttons
+//    are followed up to MAX_PAGES pages. Payload's "autoPaginate" overrides it. (defaults to false)
+//
+//    POLITE: Polite mode. Pages marked noindex by meta robots tag or X-Robots-Tag header
+//    are not extracted and nofollow links are not followed. Payload's "robotsMeta" overrides it. (defaults to false)
+//
+//    IGNORE_NOARCHIVE: Pages marked noarchive or nosnippet by meta robots tag or X-Robots-Tag header
+//    are stored as snapshots and written to WARC files. They are extracted but not stored by default. (defaults to false)
+//
+//    IGNORE_ROBOTS_TXT: robots.txt of web sites is not complied with. Pages disallowed by it are fetched,
+//    its Crawl-delay is not honored and its Sitemap URLs are not returned. (defaults to false)
+//
+//    SPLASH_FILTERS: Directory of Adblock Plus filter files <name>.txt referred by "filters" argument
+//    of legacy Splash requests in payloads. (defaults to "")
+//
 //Output settings
 //    FORMAT: Format represents output format (CSV, JSON, XML)(defaults to "json")
 //
@@ -153,6 +781,129 @@ The following Output formats are available: CSV, JSON, XML
 //    Paginated results are applicable for JSON and XML output formats.
 //    Combined list of results is always returned for CSV format. (defaults to false)
 //
+//    STREAM_EXTRACTION: Flat payloads are processed with streaming HTML tokenizer
+//    instead of building a full DOM. It cuts memory consumption on huge listing pages.
+//    A payload is flat if it has no paginator and details, and its selectors consist of
+//    type, #id and .class selectors combined with descendant combinators only.
+//    Every block of a page should contain single match of every field. (defaults to false)
+//
+//    DOC_CACHE_SIZE: The number of parsed documents kept in LRU cache keyed by content hash.
+//    Identical pages, f.e. the same details page referred from several blocks, are parsed only once.
+//    Set it to 0 to disable caching. (defaults to 100)
+//
+//    QUERY_STORE_RUNS: The number of the latest runs of every payload kept for querying
+//    with Query endpoint. Set it to 0 to disable storing of runs. (defaults to 30)
+//
+//    SNAPSHOT_RUNS: The number of the latest runs of every payload which page snapshots are kept.
+//    Set it to 0 to keep snapshots of all runs until they are purged by retention policy. (defaults to 3)
+//
+//    ENRICH_CONCURRENCY: The number of simultaneous requests to enrichment endpoint
+//    if payload doesn't specify it. Payloads can't exceed it. (defaults to 4)
+//
+//    ENRICH_TIMEOUT: Timeout of a single request to enrichment endpoint in seconds
+//    if payload doesn't specify it. Payloads can't exceed it. (defaults to 10)
+//
+//    PLUGINS_DIR: Directory containing Go plugins (*.so) with custom extractors
+//    and output formats. Plugins are loaded at startup. (defaults to "")
+//
+//    SCRIPT_TIMEOUT: Maximum execution time of a single field or record script
+//    invocation in milliseconds. (defaults to 100)
+//
+//    SCRIPT_MAX_STACK: Maximum size of Lua interpreter stack of a single script
+//    invocation. It bounds recursion depth, not heap memory of strings and tables. (defaults to 65536)
+//
+//    SCRIPT_MAX_MEMORY: Maximum number of bytes held by strings and tables of a single script
+//    invocation. (defaults to 67108864)
+//
+//    RATE_LIMIT: The number of requests allowed for every API client within RATE_LIMIT_WINDOW.
+//    Set it to 0 to disable rate limiting. (defaults to 0)
+//
+//    RATE_LIMIT_WINDOW: Rate limit window in seconds. (defaults to 60)
+//
+//    QUOTA: The number of requests allowed for every API client within QUOTA_PERIOD.
+//    Set it to 0 to disable quotas. (defaults to 0)
+//
+//    QUOTA_PERIOD: Quota period in seconds. (defaults to 86400)
+//
+//    ADMIN_TOKEN: API key granted admin role. It is required by admin endpoints such as /cache. (defaults to "")
+//
+//    API_KEYS_FILE: JSON file listing API keys with their roles and tenants. Anonymous clients
+//    are granted user role if neither API_KEYS_FILE nor JWT_SECRET is set. (defaults to "")
+//
+//    JWT_SECRET: Secret HS256 JWTs carrying role and tenant claims are verified with. (defaults to "")
+//
+//    ANONYMOUS_OPERATOR: Anonymous clients are granted operator role instead of user role
+//    if neither API_KEYS_FILE nor JWT_SECRET is set. (defaults to false)
+//
+//    RETENTION_RESULTS: The number of seconds results of parse jobs are kept after completion.
+//    Set it to 0 to keep results forever. (defaults to 0)
+//
+//    RETENTION_SNAPSHOTS: The number of seconds page snapshots are kept after completion of parse job.
+//    Snapshots are kept as long as results if 0. (defaults to 0)
+//
+//    RETENTION_AUDIT: The number of seconds entries of the audit log of deleted data are kept.
+//    Set it to 0 to keep the log forever. (defaults to 0)
+//
+//    RETENTION_FILE: JSON file mapping tenants to their retention policies. (defaults to "")
+//
+//    RETENTION_INTERVAL: Interval between purges of expired data in seconds. Set it to 0 to turn off the reaper. (defaults to 3600)
+//
+//    SCHEDULER: Saved payloads with a schedule are run and watches are checked periodically. (defaults to false)
+//
+//    LEADER_LEASE: TTL in seconds of the lease held by the replica elected to dispatch scheduled payloads, check watches
+//    and purge expired data.
+//    Set it to 0 to turn off leader election. (defaults to 90)
+//
+//    RECRAWL_MIN_INTERVAL: The minimum interval between runs of scheduled payloads
+//    with adaptive schedule in seconds. (defaults to 300)
+//
+//    RECRAWL_MAX_INTERVAL: The maximum interval between runs of scheduled payloads
+//    with adaptive schedule in seconds. (defaults to 604800)
+//
+//    PREWARM_HOURS: Off-peak hours of local time, f.e. "1-6" or "22-5", pages of scheduled runs
+//    with "prewarm" schedule are fetched and cached within. Any time if empty. (defaults to "")
+//
+//    PREWARM_MAX_PAGES: The maximum number of pages prewarmed for a scheduled run. (defaults to 100)
+//
+//    WATCH_MAX_POINTS: The maximum number of values kept for every watch. The oldest values
+//    are removed first. Set it to 0 for no limit. (defaults to 10000)
+//
+//    ALERT_BASE_URL: Public URL of Parse service used for links to results in alerts.
+//    http://DFK_PARSE is used if empty. (defaults to "")
+//
+//    REPORT_LOW_FILL_RATE: Fields filled in less than this share of records are listed
+//    in "lowFill" of job reports. (defaults to 0.5)
+//
+//    SMTP_HOST: SMTP server address (host:port) used to send email alerts. (defaults to "")
+//
+//    SMTP_USER, SMTP_PASSWORD: SMTP credentials. Authentication is skipped if SMTP_USER
+//    is empty. (defaults to "")
+//
+//    SMTP_FROM: Sender address of email alerts. (defaults to "dataflowkit@localhost")
+//
+//    S3_ENDPOINT: S3 compatible endpoint used to read URL lists, f.e. https://ams3.digitaloceanspaces.com.
+//    AWS S3 is used if empty. (defaults to "")
+//
+//    S3_REGION: S3 region. (defaults to "us-east-1")
+//
+//    S3_ACCESS_KEY, S3_SECRET_KEY: S3 credentials. Anonymous requests are sent if they are empty. (defaults to "")
+//
+//    USER_TOKEN_SECRET: Secret user tokens issued by POST /tokens are signed with. It must be the same
+//    for Fetch and Parse services. Free-form user tokens are accepted if empty. (defaults to "")
+//
+//    USER_TOKEN_KEY: Key claims of issued user tokens are encrypted with. Tokens are only signed if empty. (defaults to "")
+//
+//    CREDENTIALS_KEY: Key credentials of the vault are encrypted with. The vault is disabled if empty. (defaults to "")
+//
+//    JOB_MAX_PAGES: The maximum number of pages fetched by a single parse job.
+//    Set it to 0 for no limit. (defaults to 0)
+//
+//    JOB_MAX_BYTES: The maximum total size in bytes of pages fetched by a single parse job.
+//    Set it to 0 for no limit. (defaults to 0)
+//
+//    JOB_MAX_DURATION: The maximum duration of a single parse job in seconds.
+//    Set it to 0 for no limit. (defaults to 0)
+//
 package main
 
 // EOF
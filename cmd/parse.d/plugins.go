@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	"github.com/slotix/dataflowkit/scrape"
+)
+
+// loadPlugins opens Go plugins (*.so files) found in dir.
+// Plugins register custom extractors and encoders with scrape.RegisterExtractor
+// and scrape.RegisterEncoder from their init functions.
+func loadPlugins(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, err := plugin.Open(f); err != nil {
+			return fmt.Errorf("Cannot load plugin %s. %s", f, err.Error())
+		}
+		fmt.Printf("Plugin %s loaded\n", filepath.Base(f))
+	}
+	if e := scrape.Extractors(); len(e) > 0 {
+		fmt.Printf("Custom extractors: %s\n", strings.Join(e, ", "))
+	}
+	if e := scrape.Encoders(); len(e) > 0 {
+		fmt.Printf("Custom formats: %s\n", strings.Join(e, ", "))
+	}
+	return nil
+}
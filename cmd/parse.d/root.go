@@ -25,9 +25,12 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
+	"github.com/slotix/dataflowkit/config"
 	"github.com/slotix/dataflowkit/healthcheck"
 	"github.com/slotix/dataflowkit/parse"
+	"github.com/slotix/dataflowkit/scrape"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -36,25 +39,82 @@ import (
 var (
 	DFKParse           string
 	DFKFetch           string //DFKFetch service address.
+	profile            string //Comma separated configuration profiles
+	profilesDir        string
 	storageType        string
 	skipStorageMW      bool
 	storageItemExpires int64 //how long in seconds object stay in a cache before expiration.
 	diskvBaseDir       string
 	resultsDir         string
+	sourceDir          string
 
 	cassandraHost string
 	mongoHost     string
 
 	maxPages            int
 	paginateResults     bool
+	autoPaginate        bool
+	polite              bool
+	ignoreNoarchive     bool
+	ignoreRobotsTxt     bool
+	splashFilters       string
+	jobMaxPages         int
+	jobMaxBytes         int64
+	jobMaxDuration      int
+	scheduler           bool
+	leaderLease         int
+	recrawlMinInterval  int
+	recrawlMaxInterval  int
+	prewarmHours        string
+	prewarmMaxPages     int
+	watchMaxPoints      int
+	alertBaseURL        string
+	reportLowFillRate   float64
+	smtpHost            string
+	smtpUser            string
+	smtpPassword        string
+	smtpFrom            string
+	s3Endpoint          string
+	s3Region            string
+	s3AccessKey         string
+	s3SecretKey         string
+	userTokenSecret     string
+	userTokenKey        string
+	credentialsKey      string
+	streamExtraction    bool
+	docCacheSize        int
+	queryStoreRuns      int
+	snapshotRuns        int
+	enrichConcurrency   int
+	enrichTimeout       int
+	pluginsDir          string
+	scriptTimeout       int
+	scriptMaxStack      int
+	scriptMaxMemory     int
+	rateLimit           int
+	rateLimitWindow     int
+	quota               int
+	quotaPeriod         int
+	adminToken          string
+	apiKeysFile         string
+	jwtSecret           string
+	anonymousOperator   bool
+	retentionResults    int64
+	retentionSnapshots  int64
+	retentionAudit      int64
+	retentionFile       string
+	retentionInterval   int
 	fetchDelay          int
 	randomizeFetchDelay bool
 	ignoreFetchDelay    bool
 
 	fetchChannelSize int
+	prefetchPages    int
+	maxPendingPages  int
 	blockChannelSize int
 	fetchWorkerNum   int
 	blockWorkerNum   int
+	extractWorkerNum int
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -71,15 +131,15 @@ var RootCmd = &cobra.Command{
 				Host: viper.GetString("DFK_FETCH"),
 			},
 		}
-		sType := strings.ToLower(storageType)
+		sType := strings.ToLower(viper.GetString("STORAGE_TYPE"))
 		if sType == "cassandra" {
 			services = append(services, healthcheck.CassandraConn{
-				Host: cassandraHost,
+				Host: viper.GetString("CASSANDRA"),
 			})
 		}
 		if sType == "mongodb" {
 			services = append(services, healthcheck.MongoConn{
-				Host: mongoHost,
+				Host: viper.GetString("MONGO"),
 			})
 		}
 
@@ -93,14 +153,58 @@ var RootCmd = &cobra.Command{
 			}
 		}
 		if allAlive {
+			if dir := viper.GetString("PLUGINS_DIR"); dir != "" {
+				if err := loadPlugins(dir); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
 			if skipStorageMW {
 				fmt.Printf("Storage %s\n", "None")
 			} else {
-				fmt.Printf("Storage %s\n", storageType)
+				fmt.Printf("Storage %s\n", viper.GetString("STORAGE_TYPE"))
+			}
+			var apiKeys []parse.APIKey
+			if file := viper.GetString("API_KEYS_FILE"); file != "" {
+				keys, err := parse.LoadAPIKeys(file)
+				if err != nil {
+					fmt.Println(err)
+					return
+				}
+				apiKeys = keys
+			}
+			retention := scrape.RetentionPolicies{
+				Global: scrape.RetentionPolicy{
+					Results:   viper.GetInt64("RETENTION_RESULTS"),
+					Snapshots: viper.GetInt64("RETENTION_SNAPSHOTS"),
+					Audit:     viper.GetInt64("RETENTION_AUDIT"),
+				},
+			}
+			if file := viper.GetString("RETENTION_FILE"); file != "" {
+				tenants, err := scrape.LoadTenantRetention(file)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				retention.Tenants = tenants
 			}
 			parseServer := viper.GetString("DFK_PARSE")
 			serverCfg := parse.Config{
-				Host: parseServer, //"localhost:5000",
+				Host:            parseServer, //"localhost:5000",
+				RateLimit:       viper.GetInt("RATE_LIMIT"),
+				RateLimitWindow: time.Duration(viper.GetInt("RATE_LIMIT_WINDOW")) * time.Second,
+				Quota:           viper.GetInt("QUOTA"),
+				QuotaPeriod:     time.Duration(viper.GetInt("QUOTA_PERIOD")) * time.Second,
+				Scheduler:       viper.GetBool("SCHEDULER"),
+				LeaderLease:     time.Duration(viper.GetInt("LEADER_LEASE")) * time.Second,
+				AdminToken:      viper.GetString("ADMIN_TOKEN"),
+				APIKeys:         apiKeys,
+				JWTSecret:       viper.GetString("JWT_SECRET"),
+
+				AnonymousOperator: viper.GetBool("ANONYMOUS_OPERATOR"),
+
+				Retention:         retention,
+				RetentionInterval: time.Duration(viper.GetInt("RETENTION_INTERVAL")) * time.Second,
 			}
 			htmlServer := parse.Start(serverCfg)
 			defer htmlServer.Stop()
@@ -128,25 +232,82 @@ func Execute(version string) {
 func init() {
 	//flags and configuration settings. They are global for the application.
 
+	RootCmd.Flags().StringVarP(&profile, "DFK_PROFILE", "", "", "Comma separated configuration profiles, f.e. \"prod,acme\". Later profiles override earlier ones.")
+	RootCmd.Flags().StringVarP(&profilesDir, "PROFILES_DIR", "", "profiles", "Directory of configuration profiles")
 	RootCmd.Flags().StringVarP(&DFKParse, "DFK_PARSE", "p", "127.0.0.1:8001", "HTTP listen address")
 	RootCmd.Flags().StringVarP(&DFKFetch, "DFK_FETCH", "f", "127.0.0.1:8000", "DFK Fetch service address")
 	RootCmd.Flags().StringVarP(&storageType, "STORAGE_TYPE", "", "Diskv", "Storage backend for intermediary data passed to html parser. Types: Diskv, MongoDB, Cassandra")
 	RootCmd.Flags().StringVarP(&resultsDir, "RESULTS_DIR", "", "results", "Directory for storing results")
+	RootCmd.Flags().StringVarP(&sourceDir, "SOURCE_DIR", "", "sources", "Directory of WARC and HAR files used as payload source")
 	RootCmd.Flags().Int64VarP(&storageItemExpires, "ITEM_EXPIRE_IN", "", 86400, "Default value for item expiration in seconds")
 	RootCmd.Flags().StringVarP(&diskvBaseDir, "DISKV_BASE_DIR", "", "diskv", "diskv base directory for storing fetch results")
 	RootCmd.Flags().StringVarP(&cassandraHost, "CASSANDRA", "c", "127.0.0.1", "Cassandra host address")
 	RootCmd.Flags().StringVarP(&mongoHost, "MONGO", "", "127.0.0.1", "MongoDB host address")
 
 	RootCmd.Flags().IntVarP(&maxPages, "MAX_PAGES", "", 10, "The maximum number of pages to scrape")
+	RootCmd.Flags().BoolVarP(&streamExtraction, "STREAM_EXTRACTION", "", false, "Flat payloads without paginator and details are processed with streaming HTML tokenizer instead of building DOM. It reduces memory consumption on huge pages.")
+	RootCmd.Flags().IntVarP(&docCacheSize, "DOC_CACHE_SIZE", "", 100, "The number of parsed documents cached to avoid re-parsing of identical pages. Set it to 0 to disable caching.")
+	RootCmd.Flags().IntVarP(&queryStoreRuns, "QUERY_STORE_RUNS", "", 30, "The number of the latest runs of every payload kept for querying. Set it to 0 to disable storing of runs.")
+	RootCmd.Flags().IntVarP(&snapshotRuns, "SNAPSHOT_RUNS", "", 3, "The number of the latest runs of every payload which page snapshots are kept. Set it to 0 to keep snapshots of all runs until they are purged by retention policy.")
+	RootCmd.Flags().IntVarP(&enrichConcurrency, "ENRICH_CONCURRENCY", "", 4, "The number of simultaneous requests to enrichment endpoint. It is also the maximum allowed for payloads.")
+	RootCmd.Flags().IntVarP(&enrichTimeout, "ENRICH_TIMEOUT", "", 10, "Timeout of a single request to enrichment endpoint in seconds. It is also the maximum allowed for payloads.")
+	RootCmd.Flags().StringVarP(&pluginsDir, "PLUGINS_DIR", "", "", "Directory containing Go plugins (*.so) with custom extractors and output formats.")
+	RootCmd.Flags().IntVarP(&scriptTimeout, "SCRIPT_TIMEOUT", "", 100, "Maximum execution time of a single field or record script invocation in milliseconds.")
+	RootCmd.Flags().IntVarP(&scriptMaxStack, "SCRIPT_MAX_STACK", "", 65536, "Maximum size of Lua interpreter stack of a single script invocation. It bounds recursion depth, not heap memory of strings and tables.")
+	RootCmd.Flags().IntVarP(&scriptMaxMemory, "SCRIPT_MAX_MEMORY", "", 64<<20, "Maximum number of bytes held by strings and tables of a single script invocation.")
+	RootCmd.Flags().IntVarP(&rateLimit, "RATE_LIMIT", "", 0, "The number of requests allowed for every API client within RATE_LIMIT_WINDOW. Set it to 0 to disable rate limiting.")
+	RootCmd.Flags().IntVarP(&rateLimitWindow, "RATE_LIMIT_WINDOW", "", 60, "Rate limit window in seconds.")
+	RootCmd.Flags().IntVarP(&quota, "QUOTA", "", 0, "The number of requests allowed for every API client within QUOTA_PERIOD. Set it to 0 to disable quotas.")
+	RootCmd.Flags().IntVarP(&quotaPeriod, "QUOTA_PERIOD", "", 86400, "Quota period in seconds.")
+	RootCmd.Flags().StringVarP(&adminToken, "ADMIN_TOKEN", "", "", "API key granted admin role. It is required by admin endpoints such as /cache.")
+	RootCmd.Flags().StringVarP(&apiKeysFile, "API_KEYS_FILE", "", "", "JSON file listing API keys with their roles and tenants. Anonymous clients are granted user role if neither API_KEYS_FILE nor JWT_SECRET is set.")
+	RootCmd.Flags().StringVarP(&jwtSecret, "JWT_SECRET", "", "", "Secret HS256 JWTs carrying role and tenant claims are verified with.")
+	RootCmd.Flags().BoolVarP(&anonymousOperator, "ANONYMOUS_OPERATOR", "", false, "Anonymous clients are granted operator role instead of user role if neither API_KEYS_FILE nor JWT_SECRET is set.")
+	RootCmd.Flags().Int64VarP(&retentionResults, "RETENTION_RESULTS", "", 0, "The number of seconds results of parse jobs are kept after completion. Set it to 0 to keep results forever.")
+	RootCmd.Flags().Int64VarP(&retentionSnapshots, "RETENTION_SNAPSHOTS", "", 0, "The number of seconds page snapshots are kept after completion of parse job. Snapshots are kept as long as results if 0.")
+	RootCmd.Flags().Int64VarP(&retentionAudit, "RETENTION_AUDIT", "", 0, "The number of seconds entries of the audit log of deleted data are kept. Set it to 0 to keep the log forever.")
+	RootCmd.Flags().StringVarP(&retentionFile, "RETENTION_FILE", "", "", "JSON file mapping tenants to their retention policies overriding RETENTION_RESULTS, RETENTION_SNAPSHOTS and RETENTION_AUDIT.")
+	RootCmd.Flags().IntVarP(&retentionInterval, "RETENTION_INTERVAL", "", 3600, "Interval between purges of expired data in seconds. Set it to 0 to turn off the reaper.")
+	RootCmd.Flags().IntVarP(&jobMaxPages, "JOB_MAX_PAGES", "", 0, "The maximum number of pages fetched by a single parse job. Set it to 0 for no limit.")
+	RootCmd.Flags().Int64VarP(&jobMaxBytes, "JOB_MAX_BYTES", "", 0, "The maximum total size in bytes of pages fetched by a single parse job. Set it to 0 for no limit.")
+	RootCmd.Flags().IntVarP(&jobMaxDuration, "JOB_MAX_DURATION", "", 0, "The maximum duration of a single parse job in seconds. Set it to 0 for no limit.")
+	RootCmd.Flags().BoolVarP(&scheduler, "SCHEDULER", "", false, "Payloads saved to the registry with a schedule are run and watches are checked periodically.")
+	RootCmd.Flags().IntVarP(&leaderLease, "LEADER_LEASE", "", 90, "TTL in seconds of the lease held by the replica elected to dispatch scheduled payloads, check watches and purge expired data. Set it to 0 to turn off leader election.")
+	RootCmd.Flags().IntVarP(&recrawlMinInterval, "RECRAWL_MIN_INTERVAL", "", 300, "The minimum interval between runs of scheduled payloads with adaptive schedule in seconds.")
+	RootCmd.Flags().IntVarP(&watchMaxPoints, "WATCH_MAX_POINTS", "", 10000, "The maximum number of values kept for every watch. The oldest values are removed first. Set it to 0 for no limit.")
+	RootCmd.Flags().IntVarP(&recrawlMaxInterval, "RECRAWL_MAX_INTERVAL", "", 604800, "The maximum interval between runs of scheduled payloads with adaptive schedule in seconds.")
+	RootCmd.Flags().StringVarP(&prewarmHours, "PREWARM_HOURS", "", "", "Off-peak hours of local time pages of scheduled runs are prewarmed within, f.e. \"1-6\". Any time if empty.")
+	RootCmd.Flags().IntVarP(&prewarmMaxPages, "PREWARM_MAX_PAGES", "", 100, "The maximum number of pages prewarmed for a scheduled run.")
+	RootCmd.Flags().StringVarP(&alertBaseURL, "ALERT_BASE_URL", "", "", "Public URL of Parse service used for links to results in alerts. http://DFK_PARSE is used if empty.")
+	RootCmd.Flags().Float64VarP(&reportLowFillRate, "REPORT_LOW_FILL_RATE", "", 0.5, "Fields filled in less than this share of records are listed as low fill ones in job reports.")
+	RootCmd.Flags().StringVarP(&smtpHost, "SMTP_HOST", "", "", "SMTP server address (host:port) used to send email alerts.")
+	RootCmd.Flags().StringVarP(&smtpUser, "SMTP_USER", "", "", "SMTP user name. Authentication is skipped if empty.")
+	RootCmd.Flags().StringVarP(&smtpPassword, "SMTP_PASSWORD", "", "", "SMTP password.")
+	RootCmd.Flags().StringVarP(&smtpFrom, "SMTP_FROM", "", "dataflowkit@localhost", "Sender address of email alerts.")
+	RootCmd.Flags().StringVarP(&s3Endpoint, "S3_ENDPOINT", "", "", "S3 compatible endpoint used to read URL lists, f.e. https://ams3.digitaloceanspaces.com. AWS S3 is used if empty.")
+	RootCmd.Flags().StringVarP(&s3Region, "S3_REGION", "", "us-east-1", "S3 region.")
+	RootCmd.Flags().StringVarP(&s3AccessKey, "S3_ACCESS_KEY", "", "", "S3 access key. Anonymous requests are sent if empty.")
+	RootCmd.Flags().StringVarP(&s3SecretKey, "S3_SECRET_KEY", "", "", "S3 secret key.")
+	RootCmd.Flags().StringVarP(&userTokenSecret, "USER_TOKEN_SECRET", "", "", "Secret user tokens issued by Parse service are signed with. Free-form user tokens are accepted if empty.")
+	RootCmd.Flags().StringVarP(&userTokenKey, "USER_TOKEN_KEY", "", "", "Key claims of issued user tokens are encrypted with. Tokens are only signed if empty.")
+	RootCmd.Flags().StringVarP(&credentialsKey, "CREDENTIALS_KEY", "", "", "Key credentials of the vault are encrypted with. The vault is disabled if empty.")
 	RootCmd.Flags().BoolVarP(&paginateResults, "PAGINATE_RESULTS", "", false, "Paginated results are returned. Single list of combined results from every block on all pages is returned by default.")
+	RootCmd.Flags().BoolVarP(&autoPaginate, "AUTO_PAGINATE", "", false, "Pagination is detected and followed up to MAX_PAGES pages if payload has no paginator.")
+	RootCmd.Flags().StringVarP(&splashFilters, "SPLASH_FILTERS", "", "", "Directory of Adblock Plus filter files <name>.txt referred by filters argument of legacy Splash requests in payloads")
+	RootCmd.Flags().BoolVarP(&ignoreNoarchive, "IGNORE_NOARCHIVE", "", false, "Pages marked noarchive or nosnippet by meta robots tag or X-Robots-Tag header are stored as snapshots and written to WARC files.")
+	RootCmd.Flags().BoolVarP(&ignoreRobotsTxt, "IGNORE_ROBOTS_TXT", "", false, "robots.txt of web sites is not complied with. Pages disallowed by it are fetched and its Crawl-delay is not honored.")
+	RootCmd.Flags().BoolVarP(&polite, "POLITE", "", false, "Polite mode. Pages marked noindex by meta robots tag or X-Robots-Tag header are not extracted and nofollow links are not followed unless payload's robotsMeta is false.")
 	RootCmd.Flags().IntVarP(&fetchDelay, "FETCH_DELAY", "", 500, "Specifies sleep time in milliseconds for multiple requests for the same domain.")
 	RootCmd.Flags().BoolVarP(&randomizeFetchDelay, "RANDOMIZE_FETCH_DELAY", "", true, "RandomizeFetchDelay setting decreases the chance of a crawler being blocked. This way a random delay ranging from 0.5 * FetchDelay to 1.5 * FetchDelay seconds is used between consecutive requests to the same domain. If FetchDelay is zero this option has no effect.")
 	RootCmd.Flags().BoolVarP(&ignoreFetchDelay, "IGNORE_FETCH_DELAY", "", false, "Ignores fetchDelay setting intended for debug purpose. Please set it to false in Production")
 
 	RootCmd.Flags().IntVar(&fetchChannelSize, "FETCH_CHANNEL_SIZE", 60, "The size of fetcher pool")
+	RootCmd.Flags().IntVar(&prefetchPages, "PREFETCH_PAGES", 4, "The number of seed and details pages of a job fetched ahead while earlier pages are extracted. Pages are fetched one by one if 0")
+	RootCmd.Flags().IntVar(&maxPendingPages, "MAX_PENDING_PAGES", 10, "The number of fetched pages of a job held in memory until their blocks are extracted and stored. Fetching of the next pages waits for them. Unlimited if 0")
 	RootCmd.Flags().IntVar(&fetchWorkerNum, "FETCH_WORKER_NUM", 60, "The number of fetcher workers")
 	RootCmd.Flags().IntVar(&blockChannelSize, "BLOCK_CHANNEL_SIZE", 50, "The size of block pool")
 	RootCmd.Flags().IntVar(&blockWorkerNum, "BLOCK_WORKER_NUM", 50, "The number of block workers")
+	RootCmd.Flags().IntVar(&extractWorkerNum, "EXTRACT_WORKER_NUM", 0, "The number of concurrent extractions. It is bounded by GOMAXPROCS. GOMAXPROCS is used if 0")
 
 	//viper.AutomaticEnv() // read in environment variables that match
 
@@ -177,7 +338,20 @@ func init() {
 		viper.BindPFlag("MONGO", RootCmd.Flags().Lookup("MONGO"))
 	}
 
+	if os.Getenv("DFK_PROFILE") != "" {
+		viper.Set("DFK_PROFILE", os.Getenv("DFK_PROFILE"))
+	} else {
+		viper.BindPFlag("DFK_PROFILE", RootCmd.Flags().Lookup("DFK_PROFILE"))
+	}
+	if os.Getenv("PROFILES_DIR") != "" {
+		viper.Set("PROFILES_DIR", os.Getenv("PROFILES_DIR"))
+	} else {
+		viper.BindPFlag("PROFILES_DIR", RootCmd.Flags().Lookup("PROFILES_DIR"))
+	}
+	cobra.OnInitialize(loadProfiles)
+
 	viper.BindPFlag("RESULTS_DIR", RootCmd.Flags().Lookup("RESULTS_DIR"))
+	viper.BindPFlag("SOURCE_DIR", RootCmd.Flags().Lookup("SOURCE_DIR"))
 	viper.BindPFlag("STORAGE_TYPE", RootCmd.Flags().Lookup("STORAGE_TYPE"))
 	viper.BindPFlag("ITEM_EXPIRE_IN", RootCmd.Flags().Lookup("ITEM_EXPIRE_IN"))
 	viper.BindPFlag("DISKV_BASE_DIR", RootCmd.Flags().Lookup("DISKV_BASE_DIR"))
@@ -185,13 +359,79 @@ func init() {
 	viper.BindPFlag("MONGO", RootCmd.Flags().Lookup("MONGO"))
 
 	viper.BindPFlag("MAX_PAGES", RootCmd.Flags().Lookup("MAX_PAGES"))
+	viper.BindPFlag("STREAM_EXTRACTION", RootCmd.Flags().Lookup("STREAM_EXTRACTION"))
+	viper.BindPFlag("DOC_CACHE_SIZE", RootCmd.Flags().Lookup("DOC_CACHE_SIZE"))
+	viper.BindPFlag("QUERY_STORE_RUNS", RootCmd.Flags().Lookup("QUERY_STORE_RUNS"))
+	viper.BindPFlag("SNAPSHOT_RUNS", RootCmd.Flags().Lookup("SNAPSHOT_RUNS"))
+	viper.BindPFlag("ENRICH_CONCURRENCY", RootCmd.Flags().Lookup("ENRICH_CONCURRENCY"))
+	viper.BindPFlag("ENRICH_TIMEOUT", RootCmd.Flags().Lookup("ENRICH_TIMEOUT"))
+	viper.BindPFlag("PLUGINS_DIR", RootCmd.Flags().Lookup("PLUGINS_DIR"))
+	viper.BindPFlag("SCRIPT_TIMEOUT", RootCmd.Flags().Lookup("SCRIPT_TIMEOUT"))
+	viper.BindPFlag("SCRIPT_MAX_STACK", RootCmd.Flags().Lookup("SCRIPT_MAX_STACK"))
+	viper.BindPFlag("SCRIPT_MAX_MEMORY", RootCmd.Flags().Lookup("SCRIPT_MAX_MEMORY"))
+	viper.BindPFlag("RATE_LIMIT", RootCmd.Flags().Lookup("RATE_LIMIT"))
+	viper.BindPFlag("RATE_LIMIT_WINDOW", RootCmd.Flags().Lookup("RATE_LIMIT_WINDOW"))
+	viper.BindPFlag("QUOTA", RootCmd.Flags().Lookup("QUOTA"))
+	viper.BindPFlag("QUOTA_PERIOD", RootCmd.Flags().Lookup("QUOTA_PERIOD"))
+	viper.BindPFlag("ADMIN_TOKEN", RootCmd.Flags().Lookup("ADMIN_TOKEN"))
+	viper.BindPFlag("API_KEYS_FILE", RootCmd.Flags().Lookup("API_KEYS_FILE"))
+	viper.BindPFlag("JWT_SECRET", RootCmd.Flags().Lookup("JWT_SECRET"))
+	viper.BindPFlag("ANONYMOUS_OPERATOR", RootCmd.Flags().Lookup("ANONYMOUS_OPERATOR"))
+	viper.BindPFlag("RETENTION_RESULTS", RootCmd.Flags().Lookup("RETENTION_RESULTS"))
+	viper.BindPFlag("RETENTION_SNAPSHOTS", RootCmd.Flags().Lookup("RETENTION_SNAPSHOTS"))
+	viper.BindPFlag("RETENTION_AUDIT", RootCmd.Flags().Lookup("RETENTION_AUDIT"))
+	viper.BindPFlag("RETENTION_FILE", RootCmd.Flags().Lookup("RETENTION_FILE"))
+	viper.BindPFlag("RETENTION_INTERVAL", RootCmd.Flags().Lookup("RETENTION_INTERVAL"))
+	viper.BindPFlag("JOB_MAX_PAGES", RootCmd.Flags().Lookup("JOB_MAX_PAGES"))
+	viper.BindPFlag("JOB_MAX_BYTES", RootCmd.Flags().Lookup("JOB_MAX_BYTES"))
+	viper.BindPFlag("JOB_MAX_DURATION", RootCmd.Flags().Lookup("JOB_MAX_DURATION"))
+	viper.BindPFlag("SCHEDULER", RootCmd.Flags().Lookup("SCHEDULER"))
+	viper.BindPFlag("LEADER_LEASE", RootCmd.Flags().Lookup("LEADER_LEASE"))
+	viper.BindPFlag("RECRAWL_MIN_INTERVAL", RootCmd.Flags().Lookup("RECRAWL_MIN_INTERVAL"))
+	viper.BindPFlag("RECRAWL_MAX_INTERVAL", RootCmd.Flags().Lookup("RECRAWL_MAX_INTERVAL"))
+	viper.BindPFlag("PREWARM_HOURS", RootCmd.Flags().Lookup("PREWARM_HOURS"))
+	viper.BindPFlag("PREWARM_MAX_PAGES", RootCmd.Flags().Lookup("PREWARM_MAX_PAGES"))
+	viper.BindPFlag("WATCH_MAX_POINTS", RootCmd.Flags().Lookup("WATCH_MAX_POINTS"))
+	viper.BindPFlag("ALERT_BASE_URL", RootCmd.Flags().Lookup("ALERT_BASE_URL"))
+	viper.BindPFlag("REPORT_LOW_FILL_RATE", RootCmd.Flags().Lookup("REPORT_LOW_FILL_RATE"))
+	viper.BindPFlag("SMTP_HOST", RootCmd.Flags().Lookup("SMTP_HOST"))
+	viper.BindPFlag("SMTP_USER", RootCmd.Flags().Lookup("SMTP_USER"))
+	viper.BindPFlag("SMTP_PASSWORD", RootCmd.Flags().Lookup("SMTP_PASSWORD"))
+	viper.BindPFlag("SMTP_FROM", RootCmd.Flags().Lookup("SMTP_FROM"))
+	viper.BindPFlag("S3_ENDPOINT", RootCmd.Flags().Lookup("S3_ENDPOINT"))
+	viper.BindPFlag("S3_REGION", RootCmd.Flags().Lookup("S3_REGION"))
+	viper.BindPFlag("S3_ACCESS_KEY", RootCmd.Flags().Lookup("S3_ACCESS_KEY"))
+	viper.BindPFlag("S3_SECRET_KEY", RootCmd.Flags().Lookup("S3_SECRET_KEY"))
+	viper.BindPFlag("USER_TOKEN_SECRET", RootCmd.Flags().Lookup("USER_TOKEN_SECRET"))
+	viper.BindPFlag("USER_TOKEN_KEY", RootCmd.Flags().Lookup("USER_TOKEN_KEY"))
+	viper.BindPFlag("CREDENTIALS_KEY", RootCmd.Flags().Lookup("CREDENTIALS_KEY"))
 	viper.BindPFlag("PAGINATE_RESULTS", RootCmd.Flags().Lookup("PAGINATE_RESULTS"))
+	viper.BindPFlag("AUTO_PAGINATE", RootCmd.Flags().Lookup("AUTO_PAGINATE"))
+	viper.BindPFlag("POLITE", RootCmd.Flags().Lookup("POLITE"))
+	viper.BindPFlag("IGNORE_NOARCHIVE", RootCmd.Flags().Lookup("IGNORE_NOARCHIVE"))
+	viper.BindPFlag("IGNORE_ROBOTS_TXT", RootCmd.Flags().Lookup("IGNORE_ROBOTS_TXT"))
+	viper.BindPFlag("SPLASH_FILTERS", RootCmd.Flags().Lookup("SPLASH_FILTERS"))
 	viper.BindPFlag("FETCH_DELAY", RootCmd.Flags().Lookup("FETCH_DELAY"))
 	viper.BindPFlag("RANDOMIZE_FETCH_DELAY", RootCmd.Flags().Lookup("RANDOMIZE_FETCH_DELAY"))
 	viper.BindPFlag("IGNORE_FETCH_DELAY", RootCmd.Flags().Lookup("IGNORE_FETCH_DELAY"))
 
 	viper.BindPFlag("FETCH_CHANNEL_SIZE", RootCmd.Flags().Lookup("FETCH_CHANNEL_SIZE"))
+	viper.BindPFlag("PREFETCH_PAGES", RootCmd.Flags().Lookup("PREFETCH_PAGES"))
+	viper.BindPFlag("MAX_PENDING_PAGES", RootCmd.Flags().Lookup("MAX_PENDING_PAGES"))
 	viper.BindPFlag("FETCH_WORKER_NUM", RootCmd.Flags().Lookup("FETCH_WORKER_NUM"))
 	viper.BindPFlag("BLOCK_CHANNEL_SIZE", RootCmd.Flags().Lookup("BLOCK_CHANNEL_SIZE"))
 	viper.BindPFlag("BLOCK_WORKER_NUM", RootCmd.Flags().Lookup("BLOCK_WORKER_NUM"))
+	viper.BindPFlag("EXTRACT_WORKER_NUM", RootCmd.Flags().Lookup("EXTRACT_WORKER_NUM"))
+}
+
+// loadProfiles applies configuration profiles selected with DFK_PROFILE on top of flag defaults.
+func loadProfiles() {
+	files, err := config.Load(viper.GetString("PROFILES_DIR"), viper.GetString("DFK_PROFILE"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(files) > 0 {
+		fmt.Printf("Profiles %s\n", strings.Join(files, ", "))
+	}
 }
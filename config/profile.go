@@ -0,0 +1,105 @@
+// Dataflow kit - config
+//
+// Copyright © 2017-2018 Slotix s.r.o. <dm@slotix.sk>
+//
+//
+// All rights reserved. Use of this source code is governed
+// by the BSD 3-Clause License license.
+
+// Package config of the Dataflow kit loads named configuration profiles.
+//
+// A profile is a YAML, JSON or TOML file named after the profile in profiles directory,
+// f.e. profiles/prod.yaml. Its keys are names of settings. Settings may be grouped into sections
+// like proxy, limits, storage or chrome which are flattened when the profile is loaded:
+//
+//	storage:
+//	  STORAGE_TYPE: MongoDB
+//	  MONGO: mongo.internal:27017
+//	limits:
+//	  MAX_PAGES: 50
+//
+// Several profiles separated by commas are layered, so "prod,acme" applies customer overlay acme on top of prod.
+// Profiles override defaults of flags. Flags passed on the command line and environment variables override profiles.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// extensions are file types profiles are looked up with, in order.
+var extensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// Names splits comma separated list of profiles. Empty names are skipped.
+func Names(profiles string) []string {
+	names := []string{}
+	for _, name := range strings.Split(profiles, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Load reads profiles from dir and merges their settings into viper, later profiles overriding earlier ones.
+// It returns the files settings were read from.
+func Load(dir, profiles string) ([]string, error) {
+	files := []string{}
+	//profiles are layered before merging as viper skips values of other types than merged ones,
+	//f.e. numbers read from YAML and JSON
+	layered := map[string]interface{}{}
+	for _, name := range Names(profiles) {
+		file, err := find(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		settings, err := read(file)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range settings {
+			layered[k] = v
+		}
+		files = append(files, file)
+	}
+	if err := viper.MergeConfigMap(layered); err != nil {
+		return nil, fmt.Errorf("Cannot apply profiles %s. %s", profiles, err.Error())
+	}
+	return files, nil
+}
+
+// find returns the file of profile name in dir.
+func find(dir, name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	for _, ext := range extensions {
+		file := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("profile %s not found in %s", name, dir)
+}
+
+// read returns settings of profile file with sections flattened. Setting names are upper-cased.
+func read(file string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Cannot read profile %s. %s", file, err.Error())
+	}
+	settings := map[string]interface{}{}
+	for _, key := range v.AllKeys() {
+		name := strings.ToUpper(key[strings.LastIndex(key, ".")+1:])
+		if _, ok := settings[name]; ok {
+			return nil, fmt.Errorf("Cannot read profile %s. %s is set more than once", file, name)
+		}
+		settings[name] = v.Get(key)
+	}
+	return settings, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeProfile(t *testing.T, dir, file, content string) {
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer viper.Reset()
+
+	writeProfile(t, dir, "prod.yaml", `
+PROXY: http://proxy:3128
+storage:
+  STORAGE_TYPE: MongoDB
+  MONGO: mongo:27017
+limits:
+  MAX_PAGES: 50
+  FETCH_DELAY: 1000
+`)
+	writeProfile(t, dir, "acme.json", `{"limits": {"MAX_PAGES": 500}, "chrome": {"CHROME": "http://chrome-acme:9222"}}`)
+	writeProfile(t, dir, "dup.yaml", `
+MAX_PAGES: 1
+limits:
+  MAX_PAGES: 2
+`)
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("MAX_PAGES", 10, "")
+	flags.Int("FETCH_DELAY", 500, "")
+	flags.String("STORAGE_TYPE", "Diskv", "")
+	for _, name := range []string{"MAX_PAGES", "FETCH_DELAY", "STORAGE_TYPE"} {
+		viper.BindPFlag(name, flags.Lookup(name))
+	}
+	assert.NoError(t, flags.Parse([]string{"--FETCH_DELAY=200"}))
+
+	files, err := Load(dir, " prod, acme ,")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "prod.yaml"), filepath.Join(dir, "acme.json")}, files)
+	assert.Equal(t, "http://proxy:3128", viper.GetString("PROXY"))
+	assert.Equal(t, "MongoDB", viper.GetString("STORAGE_TYPE"))
+	assert.Equal(t, "mongo:27017", viper.GetString("MONGO"))
+	assert.Equal(t, 500, viper.GetInt("MAX_PAGES"))
+	assert.Equal(t, "http://chrome-acme:9222", viper.GetString("CHROME"))
+	//flags passed on the command line take precedence
+	assert.Equal(t, 200, viper.GetInt("FETCH_DELAY"))
+
+	_, err = Load(dir, "staging")
+	assert.Error(t, err)
+	_, err = Load(dir, "../prod")
+	assert.Error(t, err)
+	_, err = Load(dir, "dup")
+	assert.Error(t, err)
+
+	files, err = Load(dir, "")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -10,6 +10,9 @@ package errs
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
 )
 
 // Error represents a handler error. It provides methods for a HTTP status
@@ -41,6 +44,7 @@ const (
 	ErrEmptyResults             = "empty results"
 	ErrNoCommonAncestor         = "no common ancestor for selectors found"
 	ErrNoPartOrSelectorProvided = "no selector/name provided for %s"
+	ErrNoURL                    = "no URL provided"
 )
 
 //BadPayload error is returned if Payload is invalid 400
@@ -80,12 +84,101 @@ func (c Cancel) Error() string {
 	return "Operation canceled."
 }
 
+// BodySnippetSize is the maximum number of bytes of response body kept by errors.
+const BodySnippetSize = 512
+
+// Snippet returns the beginning of body up to BodySnippetSize bytes. Incomplete UTF-8 sequence is cut off.
+func Snippet(body []byte) string {
+	if len(body) <= BodySnippetSize {
+		return string(body)
+	}
+	body = body[:BodySnippetSize]
+	for i := 0; i < utf8.UTFMax && len(body) > 0; i++ {
+		r, size := utf8.DecodeLastRune(body)
+		if r != utf8.RuneError || size > 1 {
+			break
+		}
+		body = body[:len(body)-1]
+	}
+	return string(body) + "..."
+}
+
+// FetchError is returned if a page can't be fetched. It describes the failed response,
+// so the cause may be found out without fetching the page again.
+type FetchError struct {
+	URL string `json:"url"`
+	//FinalURL is the URL of the response after redirects. It is empty if the request was not redirected.
+	FinalURL string `json:"finalUrl,omitempty"`
+	Code     int    `json:"status"`
+	//Reason is HTTP status line or the error of the browser, f.e. "net::ERR_NAME_NOT_RESOLVED"
+	Reason string `json:"reason"`
+	//Body is the beginning of the response body
+	Body string `json:"body,omitempty"`
+}
+
+func (e FetchError) Error() string {
+	msg := fmt.Sprintf("Cannot fetch %s: %s", e.URL, e.Reason)
+	if e.FinalURL != "" && e.FinalURL != e.URL {
+		msg += ". Final URL: " + e.FinalURL
+	}
+	if e.Body != "" {
+		msg += fmt.Sprintf(". Body: %q", e.Body)
+	}
+	return msg
+}
+
+// Status returns HTTP status code of the response.
+func (e FetchError) Status() int {
+	return e.Code
+}
+
+// Temporary reports whether the page may be fetched if it is requested again later.
+// It is true for request timeouts, rate limits and server errors.
+func (e FetchError) Temporary() bool {
+	switch {
+	case e.Code == http.StatusRequestTimeout, e.Code == http.StatusTooEarly, e.Code == http.StatusTooManyRequests:
+		return true
+	case e.Code == http.StatusNotImplemented, e.Code == http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return e.Code >= 500 && e.Code < 600
+}
+
+// Gone reports whether the page has been removed permanently or is blocked for legal reasons.
+// Unlike 404 Not Found, 410 Gone and 451 Unavailable For Legal Reasons mean the URL should not be requested again.
+func (e FetchError) Gone() bool {
+	return e.Code == http.StatusGone || e.Code == http.StatusUnavailableForLegalReasons
+}
+
+// NoBlocksToParse is returned if payload selectors match nothing on the page.
 type NoBlocksToParse struct {
 	URL string
+	//Fields lists payload fields with their selectors, f.e. "price (.price)"
+	Fields []string
+	//Body is the beginning of the page HTML
+	Body string
+	//Console lists console errors and uncaught exceptions of the page rendered by Chrome.
+	//A script failure often explains missing content.
+	Console []string
+	//FailedRequests lists failed requests of the page rendered by Chrome, f.e. blocked scripts or XHR denied by the site.
+	FailedRequests []string
 }
 
 func (e *NoBlocksToParse) Error() string {
-	return fmt.Sprintf("No blocks found for current page. URL: %s", e.URL)
+	msg := fmt.Sprintf("No blocks found for current page. URL: %s", e.URL)
+	if len(e.Fields) > 0 {
+		msg += ". Fields matching nothing: " + strings.Join(e.Fields, ", ")
+	}
+	if len(e.Console) > 0 {
+		msg += ". Console errors: " + strings.Join(e.Console, "; ")
+	}
+	if len(e.FailedRequests) > 0 {
+		msg += ". Failed requests: " + strings.Join(e.FailedRequests, "; ")
+	}
+	if e.Body != "" {
+		msg += fmt.Sprintf(". Body: %q", e.Body)
+	}
+	return msg
 }
 
 type OK struct {
@@ -0,0 +1,30 @@
+package errs
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchError_Status(t *testing.T) {
+	for _, tc := range []struct {
+		code      int
+		temporary bool
+		gone      bool
+	}{
+		{http.StatusNotFound, false, false},
+		{http.StatusRequestTimeout, true, false},
+		{http.StatusGone, false, true},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusUnavailableForLegalReasons, false, true},
+		{http.StatusInternalServerError, true, false},
+		{http.StatusNotImplemented, false, false},
+		{599, true, false},
+	} {
+		e := FetchError{URL: "http://example.com", Code: tc.code}
+		assert.Equal(t, tc.code, e.Status())
+		assert.Equal(t, tc.temporary, e.Temporary(), tc.code)
+		assert.Equal(t, tc.gone, e.Gone(), tc.code)
+	}
+}
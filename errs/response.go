@@ -0,0 +1,160 @@
+package errs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Codes of error responses. They don't change between releases, so clients may branch on them
+// instead of parsing error messages.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeTooLarge     = "too_large"
+	CodeUnsupported  = "unsupported_media_type"
+	CodeRateLimited  = "rate_limited"
+	CodeCanceled     = "canceled"
+	CodeTimeout      = "timeout"
+	CodeFetchFailed  = "fetch_failed"
+	CodeNoBlocks     = "no_blocks"
+	CodeUnavailable  = "unavailable"
+	CodeInternal     = "internal"
+)
+
+// Response is JSON encoded body of error responses of Dataflow kit services.
+type Response struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	//Retryable is set if the request may succeed when it is sent again later
+	Retryable bool `json:"retryable"`
+	//Details are data specific to the error, f.e. FetchError describing the response of failed fetch
+	Details interface{} `json:"details,omitempty"`
+	//UpstreamStatus is the status code of the web site response of failed fetch
+	UpstreamStatus int    `json:"upstreamStatus,omitempty"`
+	RequestID      string `json:"requestId,omitempty"`
+	//HTTPStatus is the status code of the response
+	HTTPStatus int `json:"status"`
+}
+
+// Error returns the message of the response.
+func (r Response) Error() string {
+	return r.Message
+}
+
+// Status returns HTTP status code of the response.
+func (r Response) Status() int {
+	return r.HTTPStatus
+}
+
+// NewResponse returns Response describing err. msg is the message returned to the client.
+func NewResponse(err error, msg, requestID string) Response {
+	r := Response{
+		Code:       CodeInternal,
+		Message:    msg,
+		RequestID:  requestID,
+		HTTPStatus: http.StatusInternalServerError,
+	}
+	switch e := err.(type) {
+	case Response:
+		r.Code, r.Retryable, r.Details, r.HTTPStatus = e.Code, e.Retryable, e.Details, e.HTTPStatus
+		r.UpstreamStatus = e.UpstreamStatus
+	case FetchError:
+		//the web site failed, not the service, so statuses of the site, f.e. 404 or 401, are not passed on
+		//as they would be taken for statuses of the service. The status of the page is kept in the body
+		r.Code, r.Details, r.UpstreamStatus = CodeFetchFailed, e, e.Code
+		r.HTTPStatus = http.StatusBadGateway
+		r.Retryable = e.Temporary()
+	case *NoBlocksToParse:
+		r.Code, r.Details = CodeNoBlocks, e
+	case Cancel, *Cancel:
+		r.Code = CodeCanceled
+	case Error:
+		r.HTTPStatus = e.Status()
+		r.Code = statusCode(e.Status())
+		r.Retryable = retryableStatus(e.Status())
+	default:
+		if err == context.DeadlineExceeded {
+			r.Code, r.Retryable = CodeTimeout, true
+		}
+	}
+	return r
+}
+
+// Write writes r to w.
+func (r Response) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(r.HTTPStatus)
+	json.NewEncoder(w).Encode(r)
+}
+
+// WriteError writes error response with status and code to w. It is used by HTTP middlewares
+// rejecting requests before they reach service endpoints.
+func WriteError(w http.ResponseWriter, status int, code, msg string) {
+	Response{
+		Code:       code,
+		Message:    msg,
+		Retryable:  retryableStatus(status),
+		HTTPStatus: status,
+	}.Write(w)
+}
+
+// statusCode returns the code of responses with HTTP status.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupported
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return CodeTimeout
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
+
+// retryableStatus reports whether requests failed with HTTP status may succeed later.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// DecodeResponse returns error described by JSON encoded error response data with HTTP status.
+// FetchError is returned for failed fetches. Nil is returned if data is not an error response.
+func DecodeResponse(status int, data []byte) error {
+	var r Response
+	if err := json.Unmarshal(data, &r); err != nil || r.Code == "" {
+		return nil
+	}
+	r.HTTPStatus = status
+	if r.Code == CodeFetchFailed {
+		var e FetchError
+		details, _ := json.Marshal(r.Details)
+		if err := json.Unmarshal(details, &e); err == nil && e.Code != 0 {
+			return e
+		}
+	}
+	return r
+}
@@ -0,0 +1,58 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResponse(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{BadPayload{ErrText: "no URL"}, http.StatusBadRequest, CodeBadRequest, false},
+		{StatusError{Code: http.StatusNotFound, Err: errors.New("not found")}, http.StatusNotFound, CodeNotFound, false},
+		{StatusError{Code: http.StatusServiceUnavailable, Err: errors.New("busy")}, http.StatusServiceUnavailable, CodeUnavailable, true},
+		{FetchError{URL: "http://example.com", Code: http.StatusTooManyRequests}, http.StatusBadGateway, CodeFetchFailed, true},
+		{FetchError{URL: "http://example.com", Code: http.StatusNotFound}, http.StatusBadGateway, CodeFetchFailed, false},
+		{FetchError{URL: "http://example.com", Reason: "net::ERR_NAME_NOT_RESOLVED"}, http.StatusBadGateway, CodeFetchFailed, false},
+		{FetchError{URL: "http://example.com", Code: http.StatusNotModified}, http.StatusBadGateway, CodeFetchFailed, false},
+		{&NoBlocksToParse{URL: "http://example.com"}, http.StatusInternalServerError, CodeNoBlocks, false},
+		{Cancel{}, http.StatusInternalServerError, CodeCanceled, false},
+		{context.DeadlineExceeded, http.StatusInternalServerError, CodeTimeout, true},
+		{errors.New("failed"), http.StatusInternalServerError, CodeInternal, false},
+	} {
+		r := NewResponse(tc.err, tc.err.Error(), "id")
+		assert.Equal(t, tc.status, r.Status(), tc.err.Error())
+		assert.Equal(t, tc.code, r.Code, tc.err.Error())
+		assert.Equal(t, tc.retryable, r.Retryable, tc.err.Error())
+		assert.Equal(t, "id", r.RequestID)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	fetchErr := FetchError{URL: "http://example.com", Code: http.StatusForbidden, Reason: "403 Forbidden", Body: "denied"}
+	w := httptest.NewRecorder()
+	NewResponse(fetchErr, fetchErr.Error(), "").Write(w)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), `"upstreamStatus":403`)
+	assert.Equal(t, fetchErr, DecodeResponse(w.Code, w.Body.Bytes()))
+
+	w = httptest.NewRecorder()
+	WriteError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+	err := DecodeResponse(w.Code, w.Body.Bytes())
+	r, ok := err.(Response)
+	assert.True(t, ok)
+	assert.Equal(t, CodeRateLimited, r.Code)
+	assert.True(t, r.Retryable)
+	assert.Equal(t, http.StatusTooManyRequests, r.Status())
+
+	assert.Nil(t, DecodeResponse(http.StatusBadGateway, []byte("Bad gateway")))
+}
@@ -0,0 +1,199 @@
+package extract
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	// phoneRe matches text looking like a phone number, f.e. "+1 (555) 010-0199" or "030 1234567".
+	phoneRe = regexp.MustCompile(`\+?\(?\b\d[\d \t().\-/]{5,}\d\b`)
+	// dateRe matches dates phoneRe matches too, f.e. "2019-01-31" or "31.01.2019 12", the latter cut off at time separator.
+	dateRe = regexp.MustCompile(`^(?:\d{4}[\-./]\d{1,2}[\-./]\d{1,2}|\d{1,2}[\-./]\d{1,2}[\-./]\d{4})(?:\s|$)`)
+)
+
+// callingCodes maps ISO 3166-1 alpha-2 country codes to country calling codes.
+var callingCodes = map[string]string{
+	"AR": "54", "AT": "43", "AU": "61", "BE": "32", "BR": "55", "CA": "1", "CH": "41", "CN": "86",
+	"CZ": "420", "DE": "49", "DK": "45", "ES": "34", "FI": "358", "FR": "33", "GB": "44", "GR": "30",
+	"HU": "36", "IE": "353", "IL": "972", "IN": "91", "IT": "39", "JP": "81", "KR": "82", "MX": "52",
+	"NL": "31", "NO": "47", "NZ": "64", "PL": "48", "PT": "351", "RO": "40", "RU": "7", "SE": "46",
+	"SG": "65", "TR": "90", "UA": "380", "US": "1", "ZA": "27",
+}
+
+// trunkPrefixes are national dialling prefixes differing from "0" which is used by most countries.
+// Italian numbers keep their leading 0 after the calling code.
+var trunkPrefixes = map[string]string{"CA": "1", "IT": "", "RU": "8", "US": "1"}
+
+// CallingCode returns the calling code of the country specified by ISO 3166-1 alpha-2 code, f.e. "44" for "GB".
+func CallingCode(country string) (string, bool) {
+	code, ok := callingCodes[strings.ToUpper(country)]
+	return code, ok
+}
+
+// Email extracts email addresses found in text and mailto: links of the selection and its descendants.
+// Addresses are lower-cased and returned once in the order of appearance.
+type Email struct {
+	// By default, if there is only a single address found, Email will return
+	// the address itself (as opposed to an array containing the single address).
+	// Set AlwaysReturnList to true to disable this behaviour.
+	AlwaysReturnList bool
+}
+
+// Extract returns email addresses of specified selection.
+func (e Email) Extract(sel *goquery.Selection) (interface{}, error) {
+	found := newContactList()
+	sel.Each(func(i int, s *goquery.Selection) {
+		for _, href := range linkTargets(s, "mailto:") {
+			if addr, err := url.PathUnescape(strings.SplitN(href, "?", 2)[0]); err == nil {
+				for _, a := range strings.Split(addr, ",") {
+					found.add(strings.ToLower(strings.TrimSpace(a)))
+				}
+			}
+		}
+		for _, addr := range emailRe.FindAllString(s.Text(), -1) {
+			found.add(strings.ToLower(addr))
+		}
+	})
+	return found.result(e.AlwaysReturnList), nil
+}
+
+var _ Extractor = Email{}
+
+// Phone extracts phone numbers found in text and tel: links of the selection and its descendants.
+// Numbers in text are taken only if they are written with a leading "+" or as groups of digits.
+// Numbers are normalized to E.164 format, f.e. "+442071234567", and returned once in the order of appearance.
+type Phone struct {
+	// Country is ISO 3166-1 alpha-2 code of the country national numbers written without
+	// calling code belong to, f.e. "US". National numbers are returned as digits only if Country is empty.
+	Country string
+	// By default, if there is only a single number found, Phone will return
+	// the number itself (as opposed to an array containing the single number).
+	// Set AlwaysReturnList to true to disable this behaviour.
+	AlwaysReturnList bool
+}
+
+// Extract returns phone numbers of specified selection.
+func (e Phone) Extract(sel *goquery.Selection) (interface{}, error) {
+	found := newContactList()
+	sel.Each(func(i int, s *goquery.Selection) {
+		for _, href := range linkTargets(s, "tel:") {
+			if number, err := url.PathUnescape(href); err == nil {
+				found.add(normalizePhone(number, e.Country))
+			}
+		}
+		for _, number := range phoneRe.FindAllString(s.Text(), -1) {
+			if phoneLike(number) {
+				found.add(normalizePhone(number, e.Country))
+			}
+		}
+	})
+	return found.result(e.AlwaysReturnList), nil
+}
+
+var _ Extractor = Phone{}
+
+// phoneLike reports whether number found in text is written like a phone number, i.e. with a leading "+"
+// or as groups of digits, f.e. "(555) 010-0199" or "030 1234567". Runs of digits, f.e. order IDs,
+// decimals and dates are not.
+func phoneLike(number string) bool {
+	if dateRe.MatchString(number) {
+		return false
+	}
+	if strings.HasPrefix(number, "+") {
+		return true
+	}
+	groups := strings.FieldsFunc(number, func(r rune) bool { return r < '0' || r > '9' })
+	if len(groups) == 2 && strings.Count(number, ".")+strings.Count(number, ",") == 1 {
+		return false
+	}
+	return len(groups) > 1
+}
+
+// normalizePhone converts phone number written in international or national format to E.164.
+// Empty string is returned if number has too few or too many digits.
+func normalizePhone(number, country string) string {
+	number = strings.TrimSpace(number)
+	//extension, f.e. "555-0199;ext=12"
+	if i := strings.IndexAny(number, ";,"); i >= 0 {
+		number = number[:i]
+	}
+	var digits strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(d, "00"):
+		d = d[2:]
+	default:
+		code, ok := CallingCode(country)
+		if !ok {
+			if len(d) < 7 || len(d) > 15 {
+				return ""
+			}
+			return d
+		}
+		trunk, ok := trunkPrefixes[strings.ToUpper(country)]
+		if !ok {
+			trunk = "0"
+		}
+		//the trunk prefix of North American numbers is a part of calling code
+		if trunk != "" && strings.HasPrefix(d, trunk) && !(trunk == code && len(d) <= 10) {
+			d = d[len(trunk):]
+		}
+		d = code + d
+	}
+	//E.164 numbers have up to 15 digits. Shorter ones than 8 digits are not real numbers in practice.
+	if len(d) < 8 || len(d) > 15 {
+		return ""
+	}
+	return "+" + d
+}
+
+// linkTargets returns href values of s and its descendant links with scheme prefix stripped.
+func linkTargets(s *goquery.Selection, scheme string) []string {
+	targets := []string{}
+	s.Find("a[href]").AddSelection(s.Filter("a[href]")).Each(func(i int, a *goquery.Selection) {
+		href := strings.TrimSpace(a.AttrOr("href", ""))
+		if strings.HasPrefix(strings.ToLower(href), scheme) {
+			targets = append(targets, href[len(scheme):])
+		}
+	})
+	return targets
+}
+
+// contactList collects unique non-empty values in the order they are added.
+type contactList struct {
+	seen   map[string]bool
+	values []string
+}
+
+func newContactList() *contactList {
+	return &contactList{seen: make(map[string]bool)}
+}
+
+func (l *contactList) add(v string) {
+	if v == "" || l.seen[v] {
+		return
+	}
+	l.seen[v] = true
+	l.values = append(l.values, v)
+}
+
+func (l *contactList) result(alwaysReturnList bool) interface{} {
+	if len(l.values) == 0 {
+		return nil
+	}
+	if len(l.values) == 1 && !alwaysReturnList {
+		return l.values[0]
+	}
+	return l.values
+}
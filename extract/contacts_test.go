@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	sel := selFrom(`
+	<div class="contacts">
+		<p>Write to Sales@Example.com or support@example.co.uk.</p>
+		<a href="mailto:info@example.com?subject=Hello">Email us</a>
+		<a href="mailto:sales@example.com">sales@example.com</a>
+	</div>
+	<p class="none">No addresses here, just @mentions.</p>
+	`)
+	ret, err := Email{}.Extract(sel.Find(".contacts"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"info@example.com", "sales@example.com", "support@example.co.uk"}, ret)
+
+	ret, err = Email{}.Extract(sel.Find(".contacts a").Last())
+	assert.NoError(t, err)
+	assert.Equal(t, "sales@example.com", ret)
+
+	ret, err = Email{AlwaysReturnList: true}.Extract(sel.Find(".contacts a").Last())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sales@example.com"}, ret)
+
+	ret, err = Email{}.Extract(sel.Find(".none"))
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+}
+
+func TestNormalizePhone(t *testing.T) {
+	assert.Equal(t, "+442071234567", normalizePhone("+44 20 7123 4567", ""))
+	assert.Equal(t, "+442071234567", normalizePhone("020 7123 4567", "GB"))
+	assert.Equal(t, "+442071234567", normalizePhone("0044 20 7123 4567", "US"))
+	assert.Equal(t, "+15550100199", normalizePhone("(555) 010-0199", "us"))
+	assert.Equal(t, "+15550100199", normalizePhone("1-555-010-0199", "US"))
+	assert.Equal(t, "+390612345678", normalizePhone("06 1234 5678", "IT"))
+	assert.Equal(t, "+74951234567", normalizePhone("8 495 123-45-67", "RU"))
+	assert.Equal(t, "+15550100199", normalizePhone("+1-555-010-0199;ext=12", ""))
+	assert.Equal(t, "5550100199", normalizePhone("555.010.0199", ""))
+	assert.Equal(t, "", normalizePhone("12345", "US"))
+	assert.Equal(t, "", normalizePhone("+1234567890123456", ""))
+}
+
+func TestPhone(t *testing.T) {
+	sel := selFrom(`
+	<div class="contacts">
+		<p>Call us: (555) 010-0199 or +44 20 7123 4567. Open since 2019-01-31, order #12345.</p>
+		<a href="tel:+1-555-010-0199">Call</a>
+		<a href="tel:555-010-0188">Call</a>
+	</div>
+	<p class="none">Price: 199.99</p>
+	`)
+	ret, err := Phone{Country: "US"}.Extract(sel.Find(".contacts"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+15550100199", "+15550100188", "+442071234567"}, ret)
+
+	ret, err = Phone{}.Extract(sel.Find(".contacts a").Last())
+	assert.NoError(t, err)
+	assert.Equal(t, "5550100188", ret)
+
+	ret, err = Phone{Country: "US"}.Extract(sel.Find(".none"))
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+
+	//dates and IDs are not phone numbers
+	for _, text := range []string{
+		"Updated 2019-01-31 12:30:00",
+		"Published 31.01.2019 09:15",
+		"Order 1234567890 shipped",
+		"Invoice no. 20190131123456",
+		"Distance 12345.678 km",
+	} {
+		ret, err = Phone{Country: "US"}.Extract(selFrom("<p>" + text + "</p>"))
+		assert.NoError(t, err)
+		assert.Nil(t, ret, text)
+	}
+	ret, err = Phone{}.Extract(selFrom("<p>Call +4930123456789 or 030 1234567</p>"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+4930123456789", "0301234567"}, ret)
+
+	_, ok := CallingCode("gb")
+	assert.True(t, ok)
+	_, ok = CallingCode("XX")
+	assert.False(t, ok)
+}
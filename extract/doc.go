@@ -11,6 +11,7 @@
 // Extractor types
 //
 // - Text Extractor returns the combined text contents of the given selection.
+// If "ownText" param is true, text of descendant elements is excluded.
 //
 // - HTML Extractor returns the HTML from inside each part of the
 // given selection, as a string.
@@ -43,6 +44,16 @@
 //
 // The return type of the extractor is a list of string matches (i.e. []string).
 //
+// - Count returns the number of parts in the selection. Nothing is returned for empty selection
+// unless "includeIfEmpty" param is true.
+//
+// - Exists returns true if the selection is not empty and false otherwise.
+//
+// - JSON parses text of each part in the selection as JSON and extracts values
+// found by a dot separated path, f.e. "items.*.price".
+//
+// The return type of the extractor is a list of values unless a single value is found.
+//
 //Filters
 //
 //Filters are used to manipulate text data when extracting.
@@ -57,6 +68,16 @@
 //
 //- trim returns a copy of the Extractor's text/ Attr, with all leading and trailing white space removed
 //
+//- decodeEntities decodes HTML entities left in the text, f.e. double escaped "&amp;amp;" or entities in attributes
+//
+//- nbsp converts non-breaking spaces to regular spaces
+//
+//- collapseWhitespace replaces every sequence of white space including line breaks with a single space
+//
+//- preserveNewlines collapses white space within lines, keeps line breaks and removes empty lines
+//
+//Filters are applied in the order they are listed, f.e. ["nbsp", "collapseWhitespace"].
+//
 //Filters are available for Text, Link and Image extractor types.
 //
 //Image alt attribute, Link Text and Text are influenced by specified filters.
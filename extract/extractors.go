@@ -6,9 +6,9 @@ package extract
 import (
 	"bytes"
 	"errors"
+	"net/url"
 	"regexp"
-
-	"github.com/slotix/dataflowkit/utils"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 
@@ -48,12 +48,19 @@ type Text struct {
 	// should be included to the results, as opposed to omitting the
 	// empty string.
 	IncludeIfEmpty bool
+	//OwnText excludes text of descendant elements. Only text nodes which are direct children
+	//of selected elements are extracted, f.e. price without currency wrapped into a span.
+	OwnText bool
 	//Filters are used to manipulate Text data when extracting.
 	//Currently the following filters are available:
 	//upperCase makes all of the letters in the selected text  uppercase.
 	//lowerCase makes all of the letters in the selected text lowercase.
 	//capitalize capitalizes the first letter of each word in the selected text
 	//trim returns a copy of the text, with all leading and trailing white space removed
+	//decodeEntities decodes HTML entities left in the text, f.e. double escaped ones
+	//nbsp converts non-breaking spaces to regular spaces
+	//collapseWhitespace replaces every sequence of white space including line breaks with a single space
+	//preserveNewlines collapses white space within lines, keeps line breaks and removes empty lines
 	Filters []string
 }
 
@@ -61,8 +68,12 @@ type Text struct {
 func (e Text) Extract(sel *goquery.Selection) (interface{}, error) {
 	results := []string{}
 	sel.Each(func(i int, s *goquery.Selection) {
+		text := s.Text()
+		if e.OwnText {
+			text = ownText(s)
+		}
 		//filtering text data.
-		filtered := filterText(s.Text(), e.Filters)
+		filtered := filterText(text, e.Filters)
 		results = append(results, filtered)
 	})
 
@@ -79,6 +90,20 @@ func (e Text) Extract(sel *goquery.Selection) (interface{}, error) {
 
 var _ Extractor = Text{}
 
+// ownText returns text of text nodes which are direct children of the first element in selection.
+func ownText(s *goquery.Selection) string {
+	if len(s.Nodes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for c := s.Nodes[0].FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
 // Html extracts and returns the HTML from inside each part of the
 // given selection, as a string.
 //
@@ -87,30 +112,30 @@ var _ Extractor = Text{}
 // then the output will be: "<b>ONE</b><i>TWO</i>".
 //
 // The return type is a string of all the inner HTML joined together.
-//type Html struct{}
+type Html struct{}
 
 // Extract returns HTML from specified selection.
-// func (e Html) Extract(sel *goquery.Selection) (interface{}, error) {
-// 	var ret, h string
-// 	var err error
+func (e Html) Extract(sel *goquery.Selection) (interface{}, error) {
+	var ret, h string
+	var err error
 
-// 	sel.EachWithBreak(func(i int, s *goquery.Selection) bool {
-// 		h, err = s.Html()
-// 		if err != nil {
-// 			return false
-// 		}
+	sel.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		h, err = s.Html()
+		if err != nil {
+			return false
+		}
 
-// 		ret += h
-// 		return true
-// 	})
+		ret += h
+		return true
+	})
 
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return ret, nil
-// }
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
 
-// var _ Extractor = Html{}
+var _ Extractor = Html{}
 
 // OuterHtml extracts and returns the HTML of each part of the
 // given selection, as a string.
@@ -249,6 +274,7 @@ type Attr struct {
 	// The HTML attribute to extract from each part.
 	Attr string
 	//BaseURL specifies the base URL to use for all relative URLs contained within a document.
+	//It should be the document base URL honoring <base> element. See DocumentBaseURL.
 	BaseURL string
 	// By default, if there is only a single attribute extracted, AttrExtractor
 	// will return the match itself (as opposed to an array containing the single
@@ -271,7 +297,7 @@ type Attr struct {
 }
 
 // Extract returns Attr value from specified selection.
-//Absolute URL will be returned for href and src attributes if relative URLs provided
+//Absolute URLs will be returned for href, src and srcset attributes if relative URLs provided
 func (e Attr) Extract(sel *goquery.Selection) (interface{}, error) {
 	if len(e.Attr) == 0 {
 		return nil, errors.New("no attribute provided")
@@ -279,14 +305,8 @@ func (e Attr) Extract(sel *goquery.Selection) (interface{}, error) {
 	results := []string{}
 	sel.Each(func(i int, s *goquery.Selection) {
 		if val, found := s.Attr(e.Attr); found {
-			if e.Attr == "href" || e.Attr == "src" {
-				var err error
-				//transform relative url to absolute url
-				val, err = utils.RelUrl(e.BaseURL, val)
-				if err != nil {
-					logger.Error(err.Error())
-				}
-			}
+			//transform relative url to absolute url
+			val = absoluteAttr(e.Attr, e.BaseURL, val)
 			filtered := filterText(val, e.Filters)
 			results = append(results, filtered)
 		}
@@ -324,3 +344,83 @@ func (e Count) Extract(sel *goquery.Selection) (interface{}, error) {
 }
 
 var _ Extractor = Count{}
+
+// Exists reports whether any part is matched. Unlike other extractors it always returns a value,
+// so absence of an element, f.e. a badge or a sale label, is recorded as false.
+type Exists struct{}
+
+// Extract returns true if selection is not empty.
+func (e Exists) Extract(sel *goquery.Selection) (interface{}, error) {
+	return sel.Length() > 0, nil
+}
+
+var _ Extractor = Exists{}
+
+// Link represents a hyperlink found on a page.
+type Link struct {
+	//Href is a raw value of href attribute
+	Href string `json:"href"`
+	//URL is an absolute URL resolved against BaseURL
+	URL string `json:"url"`
+	//Text is an anchor text
+	Text string `json:"text"`
+	//Rel is a value of rel attribute
+	Rel string `json:"rel,omitempty"`
+	//Nofollow is true if rel attribute contains "nofollow"
+	Nofollow bool `json:"nofollow"`
+	//Internal is true if link points to the same host as BaseURL
+	Internal bool `json:"internal"`
+}
+
+// Links extracts all <a href> elements found in the selection and its descendants.
+// It is used for link audits and for seeding crawls and doesn't require a Payload.
+// The return type of the extractor is a list of links (i.e. []Link).
+type Links struct {
+	//BaseURL specifies the base URL to use for all relative URLs contained within a document.
+	BaseURL string
+	// If no links are found, then return the empty list from Extract, instead of 'nil'.
+	IncludeIfEmpty bool
+}
+
+// Extract returns Links found in specified selection.
+// Links with empty href and javascript: links are skipped.
+func (e Links) Extract(sel *goquery.Selection) (interface{}, error) {
+	baseURL, err := url.Parse(e.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	results := []Link{}
+	anchors := sel.Filter("a[href]").AddSelection(sel.Find("a[href]"))
+	anchors.Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return
+		}
+		u, err := url.Parse(href)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		u = baseURL.ResolveReference(u)
+		link := Link{
+			Href: href,
+			URL:  u.String(),
+			Text: strings.Join(strings.Fields(s.Text()), " "),
+			Rel:  s.AttrOr("rel", ""),
+		}
+		for _, rel := range strings.Fields(strings.ToLower(link.Rel)) {
+			if rel == "nofollow" {
+				link.Nofollow = true
+			}
+		}
+		link.Internal = (u.Scheme == "http" || u.Scheme == "https") &&
+			strings.EqualFold(u.Hostname(), baseURL.Hostname())
+		results = append(results, link)
+	})
+	if len(results) == 0 && !e.IncludeIfEmpty {
+		return nil, nil
+	}
+	return results, nil
+}
+
+var _ Extractor = Links{}
@@ -38,21 +38,32 @@ func TestText(t *testing.T) {
 	assert.Equal(t, ret, []string{"First", "Second", "Third"})
 }
 
-// func TestHtml(t *testing.T) {
-// 	sel := selFrom(
-// 		`<div class="one">` +
-// 			`<div class="two">Bar</div>` +
-// 			`<div class="two"><i>Baz</i></div>` +
-// 			`<div class="three">Asdf</div>` +
-// 			`</div>`)
-// 	ret, err := Html{}.Extract(sel.Find(".one"))
-// 	assert.NoError(t, err)
-// 	assert.Equal(t, ret, `<div class="two">Bar</div><div class="two"><i>Baz</i></div><div class="three">Asdf</div>`)
-
-// 	ret, err = Html{}.Extract(sel.Find(".two"))
-// 	assert.NoError(t, err)
-// 	assert.Equal(t, ret, `Bar<i>Baz</i>`)
-// }
+func TestTextOwnText(t *testing.T) {
+	sel := selFrom(`<p class="price">19.99 <span>USD</span></p><p class="price"><b>Sale</b> 9.99</p>`)
+	ret, err := Text{OwnText: true, Filters: []string{"trim"}}.Extract(sel.Find(".price"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"19.99", "9.99"}, ret)
+
+	ret, err = Text{Filters: []string{"trim"}}.Extract(sel.Find(".price"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"19.99 USD", "Sale 9.99"}, ret)
+}
+
+func TestHtml(t *testing.T) {
+	sel := selFrom(
+		`<div class="one">` +
+			`<div class="two">Bar</div>` +
+			`<div class="two"><i>Baz</i></div>` +
+			`<div class="three">Asdf</div>` +
+			`</div>`)
+	ret, err := Html{}.Extract(sel.Find(".one"))
+	assert.NoError(t, err)
+	assert.Equal(t, ret, `<div class="two">Bar</div><div class="two"><i>Baz</i></div><div class="three">Asdf</div>`)
+
+	ret, err = Html{}.Extract(sel.Find(".two"))
+	assert.NoError(t, err)
+	assert.Equal(t, ret, `Bar<i>Baz</i>`)
+}
 
 func TestOuterHtml(t *testing.T) {
 	// Simple version
@@ -219,6 +230,22 @@ func TestCount(t *testing.T) {
 	assert.Nil(t, ret)
 }
 
+func TestExists(t *testing.T) {
+	sel := selFrom(`<div class="badge">New</div>`)
+
+	ret, err := Exists{}.Extract(sel.Find(".badge"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+
+	ret, err = Exists{}.Extract(sel.Find(".sale"))
+	assert.NoError(t, err)
+	assert.Equal(t, false, ret)
+
+	ret, err = Count{IncludeIfEmpty: true}.Extract(sel.Find(".sale"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ret)
+}
+
 func TestExtract(t *testing.T) {
 	sel := selFrom(`
 		<div>One</div>
@@ -229,3 +256,35 @@ func TestExtract(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, ret, "1")
 }
+
+func TestLinks(t *testing.T) {
+	sel := selFrom(`
+	<a href="/about">About
+		us</a>
+	<a href="http://www.yahoo.com" rel="external nofollow">yahoo</a>
+	<a href="javascript:void(0)">js</a>
+	<a href="">empty</a>
+	<a name="anchor">no href</a>
+	`)
+	ret, err := Links{BaseURL: "http://www.google.com/search"}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{
+		{
+			Href:     "/about",
+			URL:      "http://www.google.com/about",
+			Text:     "About us",
+			Internal: true,
+		},
+		{
+			Href:     "http://www.yahoo.com",
+			URL:      "http://www.yahoo.com",
+			Text:     "yahoo",
+			Rel:      "external nofollow",
+			Nofollow: true,
+		},
+	}, ret)
+
+	ret, err = Links{}.Extract(selFrom(`<p>no links</p>`))
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+}
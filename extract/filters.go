@@ -1,9 +1,13 @@
 package extract
 
 import (
+	"html"
 	"strings"
 )
 
+// nbspReplacer converts non-breaking spaces to regular ones.
+var nbspReplacer = strings.NewReplacer("\u00a0", " ", "\u2007", " ", "\u202f", " ")
+
 func filterText(data string, filters []string) string {
 	for _, filter := range filters {
 		switch strings.ToLower(filter) {
@@ -15,6 +19,14 @@ func filterText(data string, filters []string) string {
 			data = strings.ToUpper(data)
 		case "capitalize":
 			data = strings.Title(data)
+		case "decodeentities":
+			data = html.UnescapeString(data)
+		case "nbsp":
+			data = nbspReplacer.Replace(data)
+		case "collapsewhitespace":
+			data = strings.Join(strings.Fields(data), " ")
+		case "preservenewlines":
+			data = collapseLines(data)
 		}
 	}
 	return data
@@ -26,3 +38,15 @@ func filterTextMW(data string, filters ...func(data string) string) string {
 	}
 	return data
 }
+
+// collapseLines collapses whitespace within every line of data and trims lines.
+// Line breaks are kept while empty lines are removed.
+func collapseLines(data string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
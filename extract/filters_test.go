@@ -38,6 +38,34 @@ func Test_filterText(t *testing.T) {
 			},
 			want: "This Is The Test",
 		},
+		{name: "decodeEntities",
+			args: args{
+				data:    "Fish &amp; Chips &lt;b&gt;",
+				filters: []string{"decodeEntities"},
+			},
+			want: "Fish & Chips <b>",
+		},
+		{name: "nbsp + trim",
+			args: args{
+				data:    "\u00a010\u00a0000\u202fkm\u00a0",
+				filters: []string{"nbsp", "trim"},
+			},
+			want: "10 000 km",
+		},
+		{name: "collapseWhitespace",
+			args: args{
+				data:    "\n\t First  line\n\n  second\u00a0\u00a0line \t",
+				filters: []string{"collapseWhitespace"},
+			},
+			want: "First line second line",
+		},
+		{name: "preserveNewlines",
+			args: args{
+				data:    "\r\n\t First  line\r\n\n \n  second \t line \n",
+				filters: []string{"preserveNewlines"},
+			},
+			want: "First line\nsecond line",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
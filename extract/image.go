@@ -0,0 +1,159 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// lazySrcAttrs are attributes lazy-loading scripts keep real image URLs in until images are scrolled into view.
+var lazySrcAttrs = []string{"data-src", "data-lazy", "data-original", "data-lazy-src"}
+
+// lazySrcsetAttrs are attributes lazy-loading scripts keep real srcset values in.
+var lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset"}
+
+// Image extracts image URLs from img elements of the selection. Plain src is frequently a placeholder
+// on pages loading images lazily, so the URL is taken from the first available of
+// srcset candidates, lazy-load attributes (data-src, data-lazy, data-original) and src.
+// Placeholders inlined as data URIs are skipped.
+type Image struct {
+	// Width is the target image width in pixels. The smallest srcset candidate not narrower than Width is chosen.
+	// The largest candidate is chosen if Width is 0 or all candidates are narrower.
+	Width int
+	//BaseURL specifies the base URL to use for all relative URLs contained within a document.
+	//It should be the document base URL honoring <base> element. See DocumentBaseURL.
+	BaseURL string
+	// By default, if there is only a single image extracted, Image
+	// will return the URL itself (as opposed to an array containing the single URL).
+	// Set AlwaysReturnList to true to disable this behaviour.
+	AlwaysReturnList bool
+	// If no images are found, then return the empty list from Extract, instead of 'nil'.
+	IncludeIfEmpty bool
+}
+
+// Extract returns absolute image URLs of specified selection.
+func (e Image) Extract(sel *goquery.Selection) (interface{}, error) {
+	results := []string{}
+	sel.Each(func(i int, s *goquery.Selection) {
+		if u := e.imageURL(s); u != "" {
+			results = append(results, absoluteURL(e.BaseURL, u))
+		}
+	})
+	if len(results) == 0 && !e.IncludeIfEmpty {
+		return nil, nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0], nil
+	}
+	return results, nil
+}
+
+var _ Extractor = Image{}
+
+// imageURL returns the URL of image element s as it is written in the document.
+func (e Image) imageURL(s *goquery.Selection) string {
+	for _, attr := range append(lazySrcsetAttrs, "srcset") {
+		if val, ok := s.Attr(attr); ok {
+			if u := pickCandidate(parseSrcset(val), e.Width); u != "" {
+				return u
+			}
+		}
+	}
+	for _, attr := range append(lazySrcAttrs, "src") {
+		if val, ok := s.Attr(attr); ok {
+			if val = strings.TrimSpace(val); val != "" && !isDataURI(val) {
+				return val
+			}
+		}
+	}
+	return ""
+}
+
+// srcsetCandidate is an image candidate of srcset attribute.
+type srcsetCandidate struct {
+	URL string
+	// Width is set by "w" descriptor.
+	Width int
+	// Density is set by "x" descriptor. It is 1 if candidate has no descriptor.
+	Density float64
+}
+
+// parseSrcset returns image candidates of srcset attribute value, f.e. "a.jpg 480w, b.jpg 800w".
+// URLs may contain commas, f.e. data URIs, so a URL lasts up to the whitespace.
+// Candidates with data URIs and malformed descriptors are skipped.
+func parseSrcset(val string) []srcsetCandidate {
+	candidates := []srcsetCandidate{}
+	for {
+		val = strings.TrimLeft(val, " \t\n\r\f,")
+		if val == "" {
+			return candidates
+		}
+		end := strings.IndexAny(val, " \t\n\r\f")
+		if end < 0 {
+			end = len(val)
+		}
+		u, descriptors := val[:end], ""
+		val = val[end:]
+		if strings.HasSuffix(u, ",") {
+			u = strings.TrimRight(u, ",")
+		} else if end = strings.Index(val, ","); end >= 0 {
+			descriptors, val = val[:end], val[end:]
+		} else {
+			descriptors, val = val, ""
+		}
+		if isDataURI(u) {
+			continue
+		}
+		candidate := srcsetCandidate{URL: u, Density: 1}
+		if fields := strings.Fields(descriptors); len(fields) > 0 {
+			d := strings.ToLower(fields[0])
+			switch {
+			case strings.HasSuffix(d, "w"):
+				w, err := strconv.Atoi(strings.TrimSuffix(d, "w"))
+				if err != nil {
+					continue
+				}
+				candidate.Width = w
+			case strings.HasSuffix(d, "x"):
+				x, err := strconv.ParseFloat(strings.TrimSuffix(d, "x"), 64)
+				if err != nil {
+					continue
+				}
+				candidate.Density = x
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+}
+
+// pickCandidate returns the URL of the smallest candidate not narrower than width or the largest one.
+// Candidates with width descriptors are preferred. Density descriptors are compared otherwise.
+func pickCandidate(candidates []srcsetCandidate, width int) string {
+	var best *srcsetCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		switch {
+		case best == nil:
+			best = c
+		case c.Width > 0 && best.Width == 0:
+			best = c
+		case c.Width > 0:
+			if width > 0 && c.Width >= width && (best.Width < width || c.Width < best.Width) ||
+				(width == 0 || best.Width < width) && c.Width > best.Width {
+				best = c
+			}
+		case best.Width == 0 && c.Density > best.Density:
+			best = c
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.URL
+}
+
+// isDataURI reports whether u is inlined data, f.e. a transparent 1x1 gif placeholder.
+func isDataURI(u string) bool {
+	return strings.HasPrefix(strings.ToLower(u), "data:")
+}
@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickCandidate(t *testing.T) {
+	candidates := parseSrcset("s.jpg 320w, m.jpg 640w,l.jpg 1280w")
+	assert.Equal(t, "l.jpg", pickCandidate(candidates, 0))
+	assert.Equal(t, "m.jpg", pickCandidate(candidates, 500))
+	assert.Equal(t, "m.jpg", pickCandidate(candidates, 640))
+	assert.Equal(t, "l.jpg", pickCandidate(candidates, 2000))
+
+	assert.Equal(t, "b.jpg", pickCandidate(parseSrcset("a.jpg, b.jpg 2x, c.jpg 1.5x"), 0))
+	assert.Equal(t, "b.jpg", pickCandidate(parseSrcset("a.jpg 2x, b.jpg 100w"), 0))
+	assert.Equal(t, "", pickCandidate(parseSrcset("data:image/gif;base64,R0lGOD 1x"), 0))
+	assert.Equal(t, "", pickCandidate(parseSrcset(""), 0))
+}
+
+func TestImage(t *testing.T) {
+	sel := selFrom(`
+	<img class="plain" src="plain.jpg">
+	<img class="lazy" src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-src="/img/lazy.jpg">
+	<img class="original" src="placeholder.gif" data-original="original.jpg">
+	<img class="srcset" src="small.jpg" srcset="small.jpg 320w, large.jpg 1280w">
+	<img class="lazyset" src="placeholder.gif" srcset="data:image/gif;base64,R0lGOD 1x" data-srcset="a.jpg 1x, b.jpg 2x">
+	<img class="empty" src="">
+	`)
+	base := "http://example.com/shop/"
+
+	ret, err := Image{BaseURL: base}.Extract(sel.Find(".plain"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/plain.jpg", ret)
+
+	ret, err = Image{BaseURL: base}.Extract(sel.Find(".lazy"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/img/lazy.jpg", ret)
+
+	ret, err = Image{BaseURL: base}.Extract(sel.Find(".original"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/original.jpg", ret)
+
+	ret, err = Image{BaseURL: base}.Extract(sel.Find(".srcset"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/large.jpg", ret)
+
+	ret, err = Image{BaseURL: base, Width: 300}.Extract(sel.Find(".srcset"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/small.jpg", ret)
+
+	ret, err = Image{BaseURL: base}.Extract(sel.Find(".lazyset"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/b.jpg", ret)
+
+	ret, err = Image{}.Extract(sel.Find(".empty"))
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+
+	ret, err = Image{IncludeIfEmpty: true}.Extract(sel.Find(".empty"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, ret)
+
+	ret, err = Image{}.Extract(sel.Find(".plain, .srcset"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"plain.jpg", "large.jpg"}, ret)
+}
@@ -0,0 +1,92 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// JSON parses text of each part in the selection as JSON and extracts values found by Path.
+// It is used with JSON script elements, f.e. XHR responses intercepted by Chrome fetcher or JSON-LD data.
+// The return type of the extractor is a list of values unless a single value is found.
+type JSON struct {
+	// Path is a dot separated list of object keys and array indexes, f.e. "data.items.0.price".
+	// "*" takes all elements of an array or all values of an object ordered by key, f.e. "data.items.*.price".
+	// The whole document is returned if Path is empty.
+	Path string
+	// By default, if there is only a single value found, JSON will return
+	// the value itself (as opposed to an array containing the single value).
+	// Set AlwaysReturnList to true to disable this behaviour.
+	AlwaysReturnList bool
+}
+
+// Extract returns values found by Path in JSON documents of specified selection.
+func (e JSON) Extract(sel *goquery.Selection) (interface{}, error) {
+	var steps []string
+	if e.Path != "" {
+		steps = strings.Split(e.Path, ".")
+	}
+	results := []interface{}{}
+	var err error
+	sel.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var doc interface{}
+		if err = json.Unmarshal([]byte(s.Text()), &doc); err != nil {
+			err = fmt.Errorf("Failed to parse JSON. %s", err.Error())
+			return false
+		}
+		results = append(results, jsonPath(doc, steps)...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0], nil
+	}
+	return results, nil
+}
+
+var _ Extractor = JSON{}
+
+// jsonPath returns values of decoded JSON document v found by path steps.
+func jsonPath(v interface{}, steps []string) []interface{} {
+	if len(steps) == 0 {
+		if v == nil {
+			return nil
+		}
+		return []interface{}{v}
+	}
+	step, rest := steps[0], steps[1:]
+	results := []interface{}{}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if step == "*" {
+			keys := make([]string, 0, len(node))
+			for k := range node {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				results = append(results, jsonPath(node[k], rest)...)
+			}
+		} else if child, ok := node[step]; ok {
+			results = append(results, jsonPath(child, rest)...)
+		}
+	case []interface{}:
+		if step == "*" {
+			for _, child := range node {
+				results = append(results, jsonPath(child, rest)...)
+			}
+		} else if i, err := strconv.Atoi(step); err == nil && i >= 0 && i < len(node) {
+			results = append(results, jsonPath(node[i], rest)...)
+		}
+	}
+	return results
+}
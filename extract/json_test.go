@@ -0,0 +1,39 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+<script type="application/json" data-intercepted="http://example.com/api/products?page=1">
+{"items":[{"name":"Phone","price":100},{"name":"Tablet","price":250}],"total":2}
+</script>
+<script type="application/json" id="broken">{"items":</script>
+</body></html>`))
+	assert.NoError(t, err)
+	sel := doc.Find("script[data-intercepted*='/api/products']")
+
+	val, err := JSON{Path: "items.*.price"}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{100.0, 250.0}, val)
+
+	val, err = JSON{Path: "items.1.name"}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "Tablet", val)
+
+	val, err = JSON{Path: "total", AlwaysReturnList: true}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2.0}, val)
+
+	val, err = JSON{Path: "items.5.name"}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	_, err = JSON{}.Extract(doc.Find("#broken"))
+	assert.Error(t, err)
+}
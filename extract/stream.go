@@ -0,0 +1,258 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/slotix/dataflowkit/utils"
+	"golang.org/x/net/html"
+)
+
+// StreamPart describes a value extracted by StreamExtractor.
+type StreamPart struct {
+	//Name is used as a key of extracted value in results
+	Name string
+	//Selector is a CSS selector. Only type, #id and .class selectors combined with descendant combinators are supported.
+	Selector string
+	//Attr is an HTML attribute to extract. Text content of element is extracted if Attr is empty.
+	Attr string
+	//Filters are applied to extracted value like in Text and Attr extractors.
+	Filters []string
+}
+
+// StreamExtractor extracts values from HTML document using streaming x/net/html tokenizer
+// instead of building a full goquery DOM. It consumes a fraction of memory needed for DOM on huge pages.
+//
+// StreamExtractor is intended for flat listing pages where every block contains single match of every part.
+// i-th match of every part is put to i-th result.
+// As tokenizer doesn't build a document tree, implicitly closed elements are handled on a best effort basis.
+type StreamExtractor struct {
+	//BaseURL specifies the base URL to use for all relative URLs in href and src attributes.
+	BaseURL   string
+	parts     []StreamPart
+	selectors [][]compound
+}
+
+// compound is a sequence of simple selectors without combinators, like div#main.item
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// element is an open element tracked by StreamExtractor
+type element struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+var (
+	compoundRe = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[#.][a-zA-Z0-9_-]+)*)$`)
+	idClassRe  = regexp.MustCompile(`[#.][a-zA-Z0-9_-]+`)
+)
+
+// voidElements never have end tags.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// parseStreamSelector compiles selector into compounds. An error is returned for unsupported selectors.
+func parseStreamSelector(selector string) ([]compound, error) {
+	fields := strings.Fields(selector)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	compounds := []compound{}
+	for _, f := range fields {
+		m := compoundRe.FindStringSubmatch(f)
+		if m == nil {
+			return nil, fmt.Errorf("selector %q is not supported by stream extractor", selector)
+		}
+		c := compound{tag: strings.ToLower(m[1])}
+		if c.tag == "*" {
+			c.tag = ""
+		}
+		for _, s := range idClassRe.FindAllString(m[2], -1) {
+			if s[0] == '#' {
+				c.id = s[1:]
+			} else {
+				c.classes = append(c.classes, s[1:])
+			}
+		}
+		compounds = append(compounds, c)
+	}
+	return compounds, nil
+}
+
+// IsStreamable returns true if selector is supported by StreamExtractor.
+func IsStreamable(selector string) bool {
+	_, err := parseStreamSelector(selector)
+	return err == nil
+}
+
+// NewStreamExtractor creates StreamExtractor for specified parts.
+func NewStreamExtractor(baseURL string, parts []StreamPart) (*StreamExtractor, error) {
+	e := &StreamExtractor{
+		BaseURL: baseURL,
+		parts:   parts,
+	}
+	for _, p := range parts {
+		sel, err := parseStreamSelector(p.Selector)
+		if err != nil {
+			return nil, err
+		}
+		e.selectors = append(e.selectors, sel)
+	}
+	return e, nil
+}
+
+func (c compound) matches(el element) bool {
+	if c.tag != "" && c.tag != el.tag {
+		return false
+	}
+	if c.id != "" && c.id != el.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !utils.ArrayContains(el.classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches checks if the top element of stack matches selector.
+func matches(sel []compound, stack []element) bool {
+	if len(stack) == 0 || !sel[len(sel)-1].matches(stack[len(stack)-1]) {
+		return false
+	}
+	i := len(sel) - 2
+	for j := len(stack) - 2; i >= 0 && j >= 0; j-- {
+		if sel[i].matches(stack[j]) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+// capture collects text of matched element until it is closed
+type capture struct {
+	part  int
+	depth int
+	text  strings.Builder
+}
+
+// Extract reads HTML document from r and returns extracted values.
+func (e *StreamExtractor) Extract(r io.Reader) ([]map[string]interface{}, error) {
+	values := make([][]string, len(e.parts))
+	//base is changed by <base href> element
+	base, baseFound := e.BaseURL, false
+	stack := []element{}
+	captures := []*capture{}
+
+	//finish completes captures of elements which are not in the stack anymore
+	finish := func() {
+		active := captures[:0]
+		for _, c := range captures {
+			if c.depth > len(stack) {
+				values[c.part] = append(values[c.part], filterText(c.text.String(), e.parts[c.part].Filters))
+			} else {
+				active = append(active, c)
+			}
+		}
+		captures = active
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			stack = stack[:0]
+			finish()
+			return e.results(values), nil
+		case html.TextToken:
+			if len(captures) > 0 {
+				text := string(z.Text())
+				for _, c := range captures {
+					c.text.WriteString(text)
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			el := element{tag: t.Data}
+			attrs := map[string]string{}
+			for _, a := range t.Attr {
+				attrs[a.Key] = a.Val
+				switch a.Key {
+				case "id":
+					el.id = a.Val
+				case "class":
+					el.classes = strings.Fields(a.Val)
+				}
+			}
+			if href, ok := attrs["href"]; ok && el.tag == "base" && !baseFound {
+				base, baseFound = absoluteURL(e.BaseURL, href), true
+			}
+			stack = append(stack, el)
+			for i, sel := range e.selectors {
+				if !matches(sel, stack) {
+					continue
+				}
+				part := e.parts[i]
+				if part.Attr == "" {
+					captures = append(captures, &capture{part: i, depth: len(stack)})
+					continue
+				}
+				val, ok := attrs[part.Attr]
+				if !ok {
+					continue
+				}
+				val = absoluteAttr(part.Attr, base, val)
+				values[i] = append(values[i], filterText(val, part.Filters))
+			}
+			if tt == html.SelfClosingTagToken || voidElements[el.tag] {
+				stack = stack[:len(stack)-1]
+				finish()
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			//close the nearest open element with the same name along with implicitly closed ones
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					finish()
+					break
+				}
+			}
+		}
+	}
+}
+
+// results combines i-th values of every part into i-th result.
+func (e *StreamExtractor) results(values [][]string) []map[string]interface{} {
+	rows := 0
+	for _, v := range values {
+		if len(v) > rows {
+			rows = len(v)
+		}
+	}
+	results := make([]map[string]interface{}, rows)
+	for i := range results {
+		results[i] = make(map[string]interface{})
+		for p, v := range values {
+			if i < len(v) {
+				results[i][e.parts[p].Name] = v[i]
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStreamable(t *testing.T) {
+	assert.True(t, IsStreamable("div"))
+	assert.True(t, IsStreamable("#cards .card a.title"))
+	assert.True(t, IsStreamable("*"))
+	assert.False(t, IsStreamable("ul > li"))
+	assert.False(t, IsStreamable("a:first-child"))
+	assert.False(t, IsStreamable("a[href]"))
+	assert.False(t, IsStreamable("h1, h2"))
+	assert.False(t, IsStreamable(""))
+}
+
+func TestStreamExtractor(t *testing.T) {
+	page := `<html><body>
+	<h1 class="title">Listing</h1>
+	<div id="cards">
+		<div class="card">
+			<a class="title" href="/p/1"> First &amp; best </a>
+			<img src="1.png" alt="one">
+			<p>Price: <b>10</b>
+		</div>
+		<div class="card">
+			<a class="title" href="http://example.org/p/2">Second</a>
+			<img src="2.png"/>
+			<p>Price: <b>20</b></p>
+		</div>
+	</div>
+	<script>var a = "<div class='card'>";</script>
+	</body></html>`
+	e, err := NewStreamExtractor("http://example.com/list", []StreamPart{
+		{Name: "Name_text", Selector: "#cards a.title", Filters: []string{"trim"}},
+		{Name: "Name_href", Selector: "#cards a.title", Attr: "href"},
+		{Name: "Image_alt", Selector: ".card img", Attr: "alt"},
+		{Name: "Price_text", Selector: ".card p", Filters: []string{"trim"}},
+	})
+	assert.NoError(t, err)
+	results, err := e.Extract(strings.NewReader(page))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"Name_text":  "First & best",
+			"Name_href":  "http://example.com/p/1",
+			"Image_alt":  "one",
+			"Price_text": "Price: 10",
+		},
+		{
+			"Name_text":  "Second",
+			"Name_href":  "http://example.org/p/2",
+			"Price_text": "Price: 20",
+		},
+	}, results)
+
+	_, err = NewStreamExtractor("", []StreamPart{{Name: "invalid", Selector: "ul > li"}})
+	assert.Error(t, err)
+
+	//relative URLs are resolved against <base href>
+	e, err = NewStreamExtractor("http://example.com/list", []StreamPart{
+		{Name: "Name_href", Selector: "a", Attr: "href"},
+	})
+	assert.NoError(t, err)
+	results, err = e.Extract(strings.NewReader(`<html><head><base href="/shop/"></head><body><a href="p/1">1</a></body></html>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"Name_href": "http://example.com/shop/p/1"}}, results)
+}
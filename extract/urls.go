@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// isURLAttr reports whether values of attr are URLs resolved to absolute ones.
+func isURLAttr(attr string) bool {
+	switch attr {
+	case "href", "src", "srcset":
+		return true
+	}
+	return false
+}
+
+// DocumentBaseURL returns the URL relative URLs of the document loaded from pageURL are resolved against.
+// It is the href of the first <base> element if there is one and pageURL otherwise.
+func DocumentBaseURL(doc *goquery.Selection, pageURL string) string {
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return pageURL
+	}
+	return absoluteURL(pageURL, strings.TrimSpace(href))
+}
+
+// absoluteURL resolves val against base. val is returned as is if it can't be resolved.
+func absoluteURL(base, val string) string {
+	if base == "" {
+		return val
+	}
+	abs, err := utils.RelUrl(base, strings.TrimSpace(val))
+	if err != nil {
+		logger.Error(err.Error())
+		return val
+	}
+	return abs
+}
+
+// absoluteSrcset resolves URLs of every image candidate of srcset attribute value against base,
+// f.e. "img/a.jpg 1x, img/b.jpg 2x".
+func absoluteSrcset(base, val string) string {
+	candidates := strings.Split(val, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = absoluteURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// absoluteAttr resolves value of URL attributes against base.
+func absoluteAttr(attr, base, val string) string {
+	switch {
+	case attr == "srcset":
+		return absoluteSrcset(base, val)
+	case isURLAttr(attr):
+		return absoluteURL(base, val)
+	}
+	return val
+}
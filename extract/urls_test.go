@@ -0,0 +1,33 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentBaseURL(t *testing.T) {
+	sel := selFrom(`<html><head><base href="/shop/"></head><body><a href="item/1">1</a></body></html>`)
+	assert.Equal(t, "http://example.com/shop/", DocumentBaseURL(sel, "http://example.com/catalog/page.html"))
+
+	sel = selFrom(`<html><head><base target="_blank"></head><body></body></html>`)
+	assert.Equal(t, "http://example.com/catalog/page.html", DocumentBaseURL(sel, "http://example.com/catalog/page.html"))
+}
+
+func TestAbsoluteAttr(t *testing.T) {
+	base := "http://example.com/shop/"
+	assert.Equal(t, "http://example.com/shop/item/1", absoluteAttr("href", base, "item/1"))
+	assert.Equal(t, "http://example.com/img/a.png", absoluteAttr("src", base, " /img/a.png "))
+	assert.Equal(t, "http://example.com/shop/a.jpg 1x, http://cdn.example.com/b.jpg 2x",
+		absoluteAttr("srcset", base, "a.jpg 1x,http://cdn.example.com/b.jpg   2x"))
+	assert.Equal(t, "item/1", absoluteAttr("title", base, "item/1"))
+	assert.Equal(t, "item/1", absoluteAttr("href", "", "item/1"))
+
+	sel := selFrom(`<img src="a.jpg" srcset="a.jpg 1x, b.jpg 2x"><a href="../about">About</a>`)
+	ret, err := Attr{Attr: "srcset", BaseURL: base}.Extract(sel.Find("img"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/shop/a.jpg 1x, http://example.com/shop/b.jpg 2x", ret)
+	ret, err = Attr{Attr: "href", BaseURL: base}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/about", ret)
+}
@@ -4,9 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
-
-	"github.com/spf13/viper"
 )
 
 // [{"navigate":{"url":"http://url.com"}},
@@ -37,7 +34,7 @@ type ClickAction struct {
 }
 
 func (a *ClickAction) Execute(ctx context.Context, f *ChromeFetcher) error {
-	path := filepath.Join(viper.GetString("CHROME_SCRIPTS"), "scroll2bottom.js")
+	path := f.opts.scriptPath("scroll2bottom.js")
 	return f.RunJSFromFile(ctx, path, fmt.Sprintf(`clickElement("%s");`, a.Element))
 }
 
@@ -46,7 +43,25 @@ type PaginateAction struct {
 	Element string `json:"element"`
 }
 
+// Execute scrolls the page down or clicks "Load more" element until MaxPage pages are loaded.
+// Every step waits for network idle. Pagination stops once a step loads nothing.
 func (pa *PaginateAction) Execute(ctx context.Context, f *ChromeFetcher) error {
-	path := filepath.Join(viper.GetString("CHROME_SCRIPTS"), "scroll2bottom.js")
-	return f.RunJSFromFile(ctx, path, fmt.Sprintf(`ScrollDown(%d, "%s");`, pa.MaxPage, pa.Element))
+	script, err := f.opts.script("scroll2bottom.js")
+	if err != nil {
+		return err
+	}
+	for page := 1; page < pa.MaxPage; page++ {
+		var before, after int
+		if err := f.evaluate(ctx, script, "scrollStep", &before, pa.Element); err != nil {
+			return err
+		}
+		f.waitNetworkIdle(ctx)
+		if err := f.evaluate(ctx, script, "documentHeight", &after); err != nil {
+			return err
+		}
+		if after <= before {
+			break
+		}
+	}
+	return nil
 }
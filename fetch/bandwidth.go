@@ -0,0 +1,237 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// bandwidthBurst is the time of transfer at full rate allowed to be consumed at once after an idle period.
+const bandwidthBurst = time.Second
+
+// bandwidthChunk is the maximum number of bytes read at once from throttled content.
+const bandwidthChunk = 16 << 10
+
+// idleJobTimeout is the time after which counters of a job without fetches are dropped.
+const idleJobTimeout = 10 * time.Minute
+
+// maxTrackedJobs bounds memory used by Bandwidth. Further jobs are not capped separately until idle ones are dropped.
+const maxTrackedJobs = 10000
+
+// rateLimiter paces transfer of bytes to rate bytes per second.
+type rateLimiter struct {
+	mx   sync.Mutex
+	rate int64
+	//next is the time the transfer at rate catches up with bytes accounted so far
+	next time.Time
+}
+
+// reserve accounts n bytes transferred at now and returns the time to wait to keep the rate.
+func (l *rateLimiter) reserve(n int, now time.Time) time.Duration {
+	if l == nil || l.rate <= 0 {
+		return 0
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if earliest := now.Add(-bandwidthBurst); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	if wait := l.next.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// JobBandwidth contains bandwidth counters of a job. Jobs are identified by JobHeader of their fetches.
+type JobBandwidth struct {
+	Job string `json:"job"`
+	//Bytes is the number of bytes fetched by the job
+	Bytes int64 `json:"bytes"`
+	//Throttled is the total time in milliseconds fetches of the job were slowed down
+	Throttled int64 `json:"throttled"`
+
+	limiter  *rateLimiter
+	lastUsed time.Time
+}
+
+// BandwidthStats contains bandwidth counters of Fetch service.
+type BandwidthStats struct {
+	//MaxRate is the limit of bytes per second fetched by all jobs. Zero means no limit.
+	MaxRate int64 `json:"maxRate"`
+	//MaxJobRate is the limit of bytes per second fetched by a single job. Zero means no limit.
+	MaxJobRate int64 `json:"maxJobRate"`
+	//Bytes is the total number of fetched bytes
+	Bytes int64 `json:"bytes"`
+	//Throttled is the total time in milliseconds fetches were slowed down
+	Throttled int64 `json:"throttled"`
+	//Jobs are recently active jobs sorted by fetched bytes
+	Jobs []JobBandwidth `json:"jobs"`
+}
+
+// Bandwidth accounts bytes fetched by Fetch service and throttles fetches to keep global and per-job rates,
+// so a single heavy job can't saturate the egress link or a metered proxy plan.
+type Bandwidth struct {
+	mx     sync.Mutex
+	global *rateLimiter
+	stats  BandwidthStats
+	jobs   map[string]*JobBandwidth
+	//swept is the time idle jobs were dropped last
+	swept time.Time
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewBandwidth creates Bandwidth limiting the rate of all fetches to maxRate and the rate of fetches of a job
+// to maxJobRate bytes per second. Zero value disables corresponding limit.
+func NewBandwidth(maxRate, maxJobRate int64) *Bandwidth {
+	return &Bandwidth{
+		global: &rateLimiter{rate: maxRate},
+		stats:  BandwidthStats{MaxRate: maxRate, MaxJobRate: maxJobRate},
+		jobs:   make(map[string]*JobBandwidth),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// Stats returns a snapshot of bandwidth counters.
+func (b *Bandwidth) Stats() BandwidthStats {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	stats := b.stats
+	stats.Jobs = make([]JobBandwidth, 0, len(b.jobs))
+	for _, j := range b.jobs {
+		stats.Jobs = append(stats.Jobs, JobBandwidth{Job: j.Job, Bytes: j.Bytes, Throttled: j.Throttled})
+	}
+	sort.Slice(stats.Jobs, func(i, j int) bool {
+		if stats.Jobs[i].Bytes != stats.Jobs[j].Bytes {
+			return stats.Jobs[i].Bytes > stats.Jobs[j].Bytes
+		}
+		return stats.Jobs[i].Job < stats.Jobs[j].Job
+	})
+	return stats
+}
+
+// job returns counters of job id. Counters of jobs idle for idleJobTimeout are dropped. Caller should hold the lock.
+func (b *Bandwidth) job(id string, now time.Time) *JobBandwidth {
+	if id == "" {
+		return nil
+	}
+	if j, ok := b.jobs[id]; ok {
+		j.lastUsed = now
+		return j
+	}
+	if now.Sub(b.swept) > idleJobTimeout || len(b.jobs) >= maxTrackedJobs {
+		for k, j := range b.jobs {
+			if now.Sub(j.lastUsed) > idleJobTimeout {
+				delete(b.jobs, k)
+			}
+		}
+		b.swept = now
+		if len(b.jobs) >= maxTrackedJobs {
+			return nil
+		}
+	}
+	j := &JobBandwidth{Job: id, limiter: &rateLimiter{rate: b.stats.MaxJobRate}, lastUsed: now}
+	b.jobs[id] = j
+	return j
+}
+
+// transferred accounts n bytes fetched for job id and blocks while global or job rate is exceeded.
+func (b *Bandwidth) transferred(id string, n int) {
+	if n <= 0 {
+		return
+	}
+	now := b.now()
+	b.mx.Lock()
+	j := b.job(id, now)
+	b.stats.Bytes += int64(n)
+	if j != nil {
+		j.Bytes += int64(n)
+	}
+	b.mx.Unlock()
+	wait := b.global.reserve(n, now)
+	if j != nil {
+		if jobWait := j.limiter.reserve(n, now); jobWait > wait {
+			wait = jobWait
+		}
+	}
+	if wait <= 0 {
+		return
+	}
+	b.mx.Lock()
+	b.stats.Throttled += int64(wait / time.Millisecond)
+	if j != nil {
+		j.Throttled += int64(wait / time.Millisecond)
+	}
+	b.mx.Unlock()
+	b.sleep(wait)
+}
+
+// JobHeader is HTTP header carrying the job fetches of Parse service belong to, f.e. ID of the parse task.
+// Jobs of fetches sent without it are identified by the client address.
+const JobHeader = "X-Job-ID"
+
+// WithBandwidth makes fetchers account and throttle fetched bytes with b.
+func WithBandwidth(b *Bandwidth) Option {
+	return func(o *options) error {
+		o.bandwidth = b
+		return nil
+	}
+}
+
+// transport returns RoundTripper throttling response bodies of job received through next.
+// Bodies are throttled as they are read from the connection, so content saved by the fetcher
+// is paced along with content returned to the client.
+func (b *Bandwidth) transport(next http.RoundTripper, job string) http.RoundTripper {
+	if b == nil {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return throttledTransport{next: next, bandwidth: b, job: job}
+}
+
+type throttledTransport struct {
+	next      http.RoundTripper
+	bandwidth *Bandwidth
+	job       string
+}
+
+func (t throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &throttledReader{ReadCloser: resp.Body, transferred: func(n int) {
+		t.bandwidth.transferred(t.job, n)
+	}}
+	return resp, nil
+}
+
+// account reports n bytes loaded by Chrome for job. Chrome downloads on its own, so the fetch
+// is held back until the rate is kept.
+func (b *Bandwidth) account(job string, n int64) {
+	if b == nil {
+		return
+	}
+	b.transferred(job, int(n))
+}
+
+// throttledReader reports bytes read from content in small chunks so they are paced evenly.
+type throttledReader struct {
+	io.ReadCloser
+	transferred func(n int)
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.transferred(n)
+	return n, err
+}
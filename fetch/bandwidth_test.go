@@ -0,0 +1,87 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contentTransport struct {
+	content string
+}
+
+func (t contentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(t.content)), Request: req}, nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := &rateLimiter{rate: 1000}
+	//burst after idle period
+	assert.Equal(t, time.Duration(0), l.reserve(1000, now))
+	assert.Equal(t, 500*time.Millisecond, l.reserve(500, now))
+	assert.Equal(t, 1500*time.Millisecond, l.reserve(1000, now))
+	assert.Equal(t, 500*time.Millisecond, l.reserve(0, now.Add(time.Second)))
+	var off *rateLimiter
+	assert.Equal(t, time.Duration(0), off.reserve(1000, now))
+	assert.Equal(t, time.Duration(0), (&rateLimiter{}).reserve(1000, now))
+}
+
+func TestBandwidth(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	slept := time.Duration(0)
+	b := NewBandwidth(0, 10000)
+	b.now = func() time.Time { return now }
+	b.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	fetch := func(job string) {
+		client := &http.Client{Transport: b.transport(contentTransport{content: strings.Repeat("a", 30000)}, job)}
+		resp, err := client.Get("http://example.com")
+		if assert.NoError(t, err) {
+			data, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Len(t, data, 30000)
+			resp.Body.Close()
+		}
+	}
+
+	fetch("job1")
+	//a second of burst is allowed
+	assert.Equal(t, 2*time.Second, slept)
+
+	//other jobs are capped separately
+	slept = 0
+	fetch("job2")
+	fetch("")
+	assert.Equal(t, 2*time.Second, slept)
+
+	//bytes loaded by Chrome are accounted at once
+	slept = 0
+	b.account("job2", 10000)
+	assert.Equal(t, time.Second, slept)
+
+	stats := b.Stats()
+	assert.Equal(t, int64(100000), stats.Bytes)
+	assert.Equal(t, int64(10000), stats.MaxJobRate)
+	if assert.Len(t, stats.Jobs, 2) {
+		assert.Equal(t, "job2", stats.Jobs[0].Job)
+		assert.Equal(t, int64(40000), stats.Jobs[0].Bytes)
+	}
+
+	//idle jobs are dropped
+	now = now.Add(idleJobTimeout + time.Minute)
+	b.transferred("job3", 1)
+	assert.Len(t, b.Stats().Jobs, 1)
+
+	//fetches are not throttled without bandwidth
+	var off *Bandwidth
+	tr := contentTransport{}
+	assert.Equal(t, tr, off.transport(tr, "job1"))
+	off.account("job1", 1000)
+}
@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+)
+
+// defaultMaxBinarySize is used if MAX_BINARY_SIZE is not set.
+const defaultMaxBinarySize = 10 << 20
+
+// SniffLen is the number of leading bytes of content inspected by SniffBinary.
+const SniffLen = 512
+
+// BinaryHeader carries content type of a resource saved to the storage. It is passed along with
+// JSON encoded BinaryInfo returned instead of the resource, so it is not taken for a document.
+const BinaryHeader = "X-Fetch-Binary"
+
+// BinaryInfo describes a non-HTML resource (PDF, CSV, image, etc.) downloaded by fetcher.
+// Resource content is saved to the storage backend under StorageKey
+// and BinaryInfo is returned to the caller instead of the content itself.
+type BinaryInfo struct {
+	//URL of downloaded resource
+	URL string `json:"url"`
+	//ContentType is a value of Content-Type header returned by the server
+	//or the type detected from content if the header doesn't match it
+	ContentType string `json:"contentType"`
+	//DeclaredType is a value of Content-Type header of the resource which has been served as a document
+	DeclaredType string `json:"declaredType,omitempty"`
+	//Filename is a name of the file downloaded by Chrome fetcher
+	Filename string `json:"filename,omitempty"`
+	//Size of resource in bytes
+	Size int64 `json:"size"`
+	//Hash is a hex encoded SHA-256 sum of resource content
+	Hash string `json:"hash"`
+	//StorageKey is a key of storage.BINARY record holding resource content
+	StorageKey string `json:"storageKey"`
+}
+
+// isBinaryContent returns true if content of specified type should not be passed to HTML parser.
+// Missing or unparsable Content-Type is treated as a document.
+func isBinaryContent(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json") {
+		return false
+	}
+	switch mediaType {
+	case "text/html", "text/plain", "text/xml",
+		"application/xml", "application/json":
+		return false
+	}
+	return true
+}
+
+// SniffBinary detects the type of content from its first SniffLen bytes and reports whether it is not a text document,
+// f.e. PDF, image or archive served as text/html. Text in any encoding is treated as a document.
+func SniffBinary(head []byte) (string, bool) {
+	contentType := http.DetectContentType(head)
+	return contentType, isBinaryContent(contentType)
+}
+
+// sniffBinary detects binary content of response declared as a document. Sniffed bytes are kept in response body.
+func sniffBinary(resp *http.Response) (string, bool) {
+	body := bufio.NewReaderSize(resp.Body, SniffLen)
+	head, _ := body.Peek(SniffLen)
+	resp.Body = sniffedBody{Reader: body, Closer: resp.Body}
+	return SniffBinary(head)
+}
+
+// sniffedBody is response body read through sniffing buffer.
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errTooLarge is returned for resources exceeding binary size limit.
+func errTooLarge(url string, maxSize int64) error {
+	return errs.StatusError{
+		Code: http.StatusRequestEntityTooLarge,
+		Err:  fmt.Errorf("%s exceeds binary size limit of %d bytes", url, maxSize),
+	}
+}
+
+// storeBinary reads binary resource from response body, writes it to the storage
+// and returns JSON encoded BinaryInfo. Resources declared as documents are stored with sniffed content type.
+func (o *options) storeBinary(req Request, resp *http.Response, sniffed string) (io.ReadCloser, error) {
+	maxSize := o.maxBinarySize
+	if resp.ContentLength > maxSize {
+		return nil, errTooLarge(req.getURL(), maxSize)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, errTooLarge(req.getURL(), maxSize)
+	}
+	info := BinaryInfo{
+		URL:         req.getURL(),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if sniffed != "" {
+		info.DeclaredType, info.ContentType = info.ContentType, sniffed
+	}
+	return o.saveBinary(info, content)
+}
+
+// saveBinary writes content described by info to the storage and returns JSON encoded BinaryInfo.
+func (o *options) saveBinary(info BinaryInfo, content []byte) (io.ReadCloser, error) {
+	sum := sha256.Sum256(content)
+	info.Size = int64(len(content))
+	info.Hash = hex.EncodeToString(sum[:])
+	info.StorageKey = info.Hash
+
+	s, err := o.store()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	err = s.Write(storage.Record{
+		Type:    storage.BINARY,
+		Key:     info.StorageKey,
+		Value:   content,
+		ExpTime: 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return withHeader(ioutil.NopCloser(bytes.NewReader(data)), http.Header{BinaryHeader: {contentType}}), nil
+}
@@ -0,0 +1,109 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBinaryContent(t *testing.T) {
+	assert.False(t, isBinaryContent(""))
+	assert.False(t, isBinaryContent("text/html; charset=utf-8"))
+	assert.False(t, isBinaryContent("text/plain"))
+	assert.False(t, isBinaryContent("application/xhtml+xml"))
+	assert.False(t, isBinaryContent("application/json"))
+	assert.True(t, isBinaryContent("application/pdf"))
+	assert.True(t, isBinaryContent("text/csv"))
+	assert.True(t, isBinaryContent("image/png"))
+	assert.True(t, isBinaryContent("application/octet-stream"))
+}
+
+func TestSniffBinary(t *testing.T) {
+	for _, doc := range []string{"", "<!DOCTYPE html><p>text</p>", `{"a": 1}`, "<?xml version=\"1.0\"?><a/>", "\xef\xbb\xbfплоский текст"} {
+		_, binary := SniffBinary([]byte(doc))
+		assert.False(t, binary, doc)
+	}
+	for _, data := range []string{"%PDF-1.4", "\x89PNG\r\n\x1a\n", "GIF89a", "PK\x03\x04", "\x1f\x8b\x08", "\x00\x01\x02\x03"} {
+		_, binary := SniffBinary([]byte(data))
+		assert.True(t, binary, data)
+	}
+}
+
+func TestBaseFetcher_FetchBinary(t *testing.T) {
+	pdf := []byte("%PDF-1.4 test content")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf)
+	}))
+	defer ts.Close()
+
+	viper.Set("PROXY", "")
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/doc.pdf"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+
+	info := BinaryInfo{}
+	err = json.Unmarshal(data, &info)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", info.ContentType)
+	assert.Equal(t, int64(len(pdf)), info.Size)
+	assert.NotEmpty(t, info.StorageKey)
+
+	rec := storage.Record{Type: storage.BINARY, Key: info.StorageKey}
+	stored, err := st.Read(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, pdf, stored)
+	st.Delete(rec)
+
+	//resource exceeds size limit
+	viper.Set("MAX_BINARY_SIZE", 4)
+	defer viper.Set("MAX_BINARY_SIZE", 0)
+	fetcher, err = newFetcher(Base)
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/doc.pdf"})
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_FetchMislabeledBinary(t *testing.T) {
+	pdf := []byte("%PDF-1.4 " + strings.Repeat("x", 1000))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/page" {
+			w.Write([]byte("<html><body>" + strings.Repeat("text ", 200) + "</body></html>"))
+			return
+		}
+		w.Write(pdf)
+	}))
+	defer ts.Close()
+
+	viper.Set("PROXY", "")
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/doc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", ResponseHeader(content).Get(BinaryHeader))
+	info := BinaryInfo{}
+	assert.NoError(t, json.NewDecoder(content).Decode(&info))
+	assert.Equal(t, "application/pdf", info.ContentType)
+	assert.Equal(t, "text/html; charset=utf-8", info.DeclaredType)
+	assert.Equal(t, int64(len(pdf)), info.Size)
+	st.Delete(storage.Record{Type: storage.BINARY, Key: info.StorageKey})
+
+	//documents are passed as is
+	content, err = fetcher.Fetch(Request{URL: ts.URL + "/page"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Len(t, data, 1026)
+	assert.Empty(t, ResponseHeader(content).Get(BinaryHeader))
+}
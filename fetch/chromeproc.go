@@ -0,0 +1,335 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"go.uber.org/zap"
+)
+
+// ChromeLocal is the CHROME setting value making Fetch service launch and supervise local Headless Chrome
+// instead of connecting to a separate Chrome deployment.
+const ChromeLocal = "local"
+
+// chromeExecutables are looked up in PATH if Chrome executable is not set.
+var chromeExecutables = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "headless_shell", "chrome"}
+
+const (
+	//chromeStartTimeout is the maximum time to wait for DevTools endpoint of launched Chrome.
+	chromeStartTimeout = 20 * time.Second
+	//chromeRestartDelay is the delay before the first restart of crashed Chrome. It doubles
+	//with every crash up to chromeMaxRestartDelay and is reset once Chrome runs for chromeStableRun.
+	chromeRestartDelay    = time.Second
+	chromeMaxRestartDelay = 30 * time.Second
+	chromeStableRun       = time.Minute
+)
+
+// ChromeProcess configures local Headless Chrome launched by ChromeSupervisor.
+type ChromeProcess struct {
+	//Path is Chrome executable. Well known executable names are looked up in PATH if empty.
+	Path string
+	//Port is the remote debugging port Chrome listens on 127.0.0.1.
+	Port int
+	//UserDataDir is Chrome profile directory. A new temporary directory is used for every launch if empty.
+	UserDataDir string
+	//Flags are added to Chrome command line, f.e. "--proxy-server=http://proxy:3128".
+	Flags []string
+	//TargetTTL is the time after which tabs left open are closed. Tabs of fetches are closed when fetches complete,
+	//so only tabs of aborted fetches live that long. Zero disables the cleanup.
+	TargetTTL time.Duration
+}
+
+// ChromeSupervisor launches local Headless Chrome, restarts it if it crashes and closes tabs left open.
+type ChromeSupervisor struct {
+	cfg  ChromeProcess
+	path string
+
+	mx  sync.Mutex
+	cmd *exec.Cmd
+	//exited receives the result of waiting for cmd
+	exited  chan error
+	tempDir string
+	stopped bool
+
+	stop chan struct{}
+	done chan struct{}
+
+	//seen holds the time page targets were first listed by sweep
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+// NewChromeSupervisor returns supervisor of Chrome process configured with cfg.
+func NewChromeSupervisor(cfg ChromeProcess) *ChromeSupervisor {
+	if cfg.Port == 0 {
+		cfg.Port = 9222
+	}
+	return &ChromeSupervisor{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Address returns the address of DevTools endpoint of supervised Chrome to be used as CHROME setting.
+func (s *ChromeSupervisor) Address() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", s.cfg.Port)
+}
+
+// Start launches Chrome and waits until its DevTools endpoint responds.
+// Chrome is then monitored in background until Stop is called.
+func (s *ChromeSupervisor) Start() error {
+	path, err := findChrome(s.cfg.Path)
+	if err != nil {
+		return err
+	}
+	s.path = path
+	if err := s.launch(); err != nil {
+		return err
+	}
+	go s.run()
+	return nil
+}
+
+// Stop kills Chrome and removes its temporary profile directory.
+func (s *ChromeSupervisor) Stop() {
+	s.mx.Lock()
+	if s.stopped {
+		s.mx.Unlock()
+		return
+	}
+	s.stopped = true
+	close(s.stop)
+	if s.cmd != nil {
+		killProcessGroup(s.cmd)
+	}
+	s.mx.Unlock()
+	<-s.done
+	s.removeTempDir()
+}
+
+// findChrome returns the path of Chrome executable.
+func findChrome(path string) (string, error) {
+	if path != "" {
+		return exec.LookPath(path)
+	}
+	for _, name := range chromeExecutables {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("Chrome executable not found. Set CHROME_PATH")
+}
+
+// args returns Chrome command line flags.
+func (s *ChromeSupervisor) args(dataDir string) []string {
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--disable-dev-shm-usage",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--remote-debugging-address=127.0.0.1",
+		fmt.Sprintf("--remote-debugging-port=%d", s.cfg.Port),
+		"--user-data-dir=" + dataDir,
+	}
+	//Chrome refuses to run as root with sandbox, which is the usual case in containers
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, s.cfg.Flags...)
+	return append(args, "about:blank")
+}
+
+// launch starts Chrome process and waits for its DevTools endpoint. Chrome runs in a process group of its own,
+// so its renderer and GPU processes are killed along with it.
+func (s *ChromeSupervisor) launch() error {
+	//Chrome failing to listen on a busy port keeps running, so the endpoint of another process would be taken for its own
+	if chromeResponds(s.Address()) {
+		return fmt.Errorf("Chrome DevTools port %d is already in use", s.cfg.Port)
+	}
+	s.mx.Lock()
+	if s.stopped {
+		s.mx.Unlock()
+		return errors.New("Chrome supervisor is stopped")
+	}
+	dataDir := s.cfg.UserDataDir
+	if dataDir == "" {
+		s.removeTempDirLocked()
+		dir, err := ioutil.TempDir("", "dfk-chrome")
+		if err != nil {
+			s.mx.Unlock()
+			return err
+		}
+		s.tempDir, dataDir = dir, dir
+	}
+	cmd := exec.Command(s.path, s.args(dataDir)...)
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		s.mx.Unlock()
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	s.cmd, s.exited = cmd, exited
+	s.mx.Unlock()
+
+	if err := waitChrome(s.Address(), chromeStartTimeout, exited); err != nil {
+		killProcessGroup(cmd)
+		return err
+	}
+	logger.Info("Chrome started", zap.String("address", s.Address()), zap.Int("pid", cmd.Process.Pid))
+	return nil
+}
+
+// waitChrome polls DevTools endpoint at address until it responds or timeout expires.
+// It fails as soon as Chrome process exits. The result of waiting for the process is put back to exited then.
+func waitChrome(address string, timeout time.Duration, exited chan error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if chromeResponds(address) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Chrome DevTools endpoint %s is not available after %s", address, timeout)
+		}
+		select {
+		case err := <-exited:
+			exited <- err
+			return fmt.Errorf("Chrome exited before DevTools endpoint %s became available. %v", address, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// chromeResponds reports whether DevTools endpoint at address responds.
+func chromeResponds(address string) bool {
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(address + "/json/version")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// run waits for Chrome process to exit and restarts it until the supervisor is stopped.
+// Tabs left open are closed every half of TargetTTL meanwhile.
+func (s *ChromeSupervisor) run() {
+	defer close(s.done)
+	if s.cfg.TargetTTL > 0 {
+		go s.cleanup()
+	}
+	delay := chromeRestartDelay
+	for {
+		s.mx.Lock()
+		exited := s.exited
+		s.mx.Unlock()
+		started := s.now()
+		err := <-exited
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if s.now().Sub(started) > chromeStableRun {
+			delay = chromeRestartDelay
+		}
+		logger.Warn("Chrome exited", zap.Error(err), zap.Duration("restart", delay))
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > chromeMaxRestartDelay {
+				delay = chromeMaxRestartDelay
+			}
+			if err := s.launch(); err != nil {
+				logger.Error("Failed to restart Chrome. " + err.Error())
+				continue
+			}
+			break
+		}
+	}
+}
+
+// cleanup sweeps tabs left open until the supervisor is stopped.
+func (s *ChromeSupervisor) cleanup() {
+	ticker := time.NewTicker(s.cfg.TargetTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.sweep(); err != nil {
+				logger.Warn("Failed to close Chrome tabs left open. " + err.Error())
+			}
+		}
+	}
+}
+
+// sweep closes page targets listed for longer than TargetTTL.
+func (s *ChromeSupervisor) sweep() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	devt := devtool.New(s.Address())
+	targets, err := devt.List(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	listed := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if t.Type != devtool.Page {
+			continue
+		}
+		listed[t.ID] = true
+		first, ok := s.seen[t.ID]
+		if !ok {
+			s.seen[t.ID] = now
+			continue
+		}
+		if now.Sub(first) < s.cfg.TargetTTL {
+			continue
+		}
+		if err := devt.Close(ctx, t); err != nil {
+			return err
+		}
+		delete(s.seen, t.ID)
+		logger.Info("Closed Chrome tab left open", zap.String("url", t.URL))
+	}
+	for id := range s.seen {
+		if !listed[id] {
+			delete(s.seen, id)
+		}
+	}
+	return nil
+}
+
+// removeTempDir removes temporary profile directory of the last launch.
+func (s *ChromeSupervisor) removeTempDir() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.removeTempDirLocked()
+}
+
+func (s *ChromeSupervisor) removeTempDirLocked() {
+	if s.tempDir != "" {
+		os.RemoveAll(s.tempDir)
+		s.tempDir = ""
+	}
+}
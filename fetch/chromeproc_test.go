@@ -0,0 +1,93 @@
+package fetch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChromeSupervisor_args(t *testing.T) {
+	s := NewChromeSupervisor(ChromeProcess{Flags: []string{"--proxy-server=http://proxy:3128"}})
+	assert.Equal(t, "http://127.0.0.1:9222", s.Address())
+	args := s.args("/tmp/profile")
+	assert.Contains(t, args, "--headless")
+	assert.Contains(t, args, "--remote-debugging-port=9222")
+	assert.Contains(t, args, "--user-data-dir=/tmp/profile")
+	assert.Equal(t, "--proxy-server=http://proxy:3128", args[len(args)-2])
+	assert.Equal(t, "about:blank", args[len(args)-1])
+
+	_, err := findChrome("/nonexistent/chrome")
+	assert.Error(t, err)
+}
+
+func TestChromeSupervisor_sweep(t *testing.T) {
+	targets := map[string]string{"1": "page", "2": "page", "3": "service_worker"}
+	var closed []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/json/close/") {
+			id := strings.TrimPrefix(r.URL.Path, "/json/close/")
+			closed = append(closed, id)
+			delete(targets, id)
+			w.Write([]byte("Target is closing"))
+			return
+		}
+		list := []map[string]string{}
+		for id, typ := range targets {
+			list = append(list, map[string]string{"id": id, "type": typ, "url": "http://example.com/" + id})
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewChromeSupervisor(ChromeProcess{Port: port, TargetTTL: 10 * time.Minute})
+	s.now = func() time.Time { return now }
+
+	assert.NoError(t, s.sweep())
+	assert.Empty(t, closed, "new tabs are kept")
+
+	now = now.Add(5 * time.Minute)
+	targets["4"] = "page"
+	assert.NoError(t, s.sweep())
+	assert.Empty(t, closed)
+
+	now = now.Add(5 * time.Minute)
+	delete(targets, "2")
+	assert.NoError(t, s.sweep())
+	assert.Equal(t, []string{"1"}, closed, "only pages open longer than TTL are closed")
+	assert.Len(t, s.seen, 1, "closed targets are forgotten")
+}
+
+func TestChromeSupervisor_Start(t *testing.T) {
+	//Chrome exiting at once fails the start instead of waiting for the endpoint
+	s := NewChromeSupervisor(ChromeProcess{Path: "false", Port: 1})
+	begin := time.Now()
+	err := s.Start()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "exited")
+	}
+	assert.True(t, time.Since(begin) < chromeStartTimeout)
+	s.removeTempDir()
+
+	//endpoint of another process is not taken for the endpoint of launched Chrome
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Browser": "HeadlessChrome"}`))
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s = NewChromeSupervisor(ChromeProcess{Path: "true", Port: port})
+	err = s.Start()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "already in use")
+	}
+}
@@ -0,0 +1,11 @@
+package fetch
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the process of cmd. Child processes are left to exit once their parent is gone.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// consentWait is the maximum time Chrome fetcher waits for a consent banner to show up.
+// Consent managers are usually loaded asynchronously after the page.
+const consentWait = 2 * time.Second
+
+// consentSettle is the time given to the page to reveal its content after a banner is dismissed.
+const consentSettle = 500 * time.Millisecond
+
+// consentManager describes the banner of a consent management platform.
+type consentManager struct {
+	Name string `json:"name"`
+	//Reject are selectors of buttons rejecting optional cookies. They are preferred to Accept ones.
+	Reject []string `json:"reject,omitempty"`
+	//Accept are selectors of buttons accepting cookies.
+	Accept []string `json:"accept,omitempty"`
+	//Banner is a selector of the banner container. It is removed from the page if no button can be clicked,
+	//f.e. if the banner is rendered in a frame.
+	Banner string `json:"banner"`
+}
+
+// consentManagers are common consent management platforms dismissed by Chrome fetcher for requests with AutoConsent.
+var consentManagers = []consentManager{
+	{
+		Name:   "OneTrust",
+		Reject: []string{"#onetrust-reject-all-handler"},
+		Accept: []string{"#onetrust-accept-btn-handler"},
+		Banner: "#onetrust-consent-sdk",
+	},
+	{
+		Name:   "Cookiebot",
+		Reject: []string{"#CybotCookiebotDialogBodyButtonDecline"},
+		Accept: []string{"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", "#CybotCookiebotDialogBodyButtonAccept"},
+		Banner: "#CybotCookiebotDialog",
+	},
+	{
+		Name:   "Quantcast Choice",
+		Reject: []string{`.qc-cmp2-summary-buttons button[mode="secondary"]`},
+		Accept: []string{`.qc-cmp2-summary-buttons button[mode="primary"]`},
+		Banner: ".qc-cmp2-container",
+	},
+	{
+		Name:   "Didomi",
+		Reject: []string{"#didomi-notice-disagree-button"},
+		Accept: []string{"#didomi-notice-agree-button"},
+		Banner: "#didomi-host",
+	},
+	{
+		Name:   "TrustArc",
+		Reject: []string{"#truste-consent-required"},
+		Accept: []string{"#truste-consent-button"},
+		Banner: "#truste-consent-track, #truste-consent-content, .truste_overlay, .truste_box_overlay",
+	},
+	{
+		Name:   "Usercentrics",
+		Banner: "#usercentrics-root, #usercentrics-cmp-ui",
+	},
+	{
+		Name:   "Sourcepoint",
+		Banner: `[id^="sp_message_container"]`,
+	},
+	{
+		Name:   "Osano",
+		Reject: []string{".osano-cm-denyAll", ".osano-cm-deny"},
+		Accept: []string{".osano-cm-accept-all", ".osano-cm-accept"},
+		Banner: ".osano-cm-window",
+	},
+	{
+		Name:   "Cookie Consent",
+		Reject: []string{".cc-window .cc-deny"},
+		Accept: []string{".cc-window .cc-allow", ".cc-window .cc-dismiss"},
+		Banner: ".cc-window",
+	},
+	{
+		Name:   "Complianz",
+		Reject: []string{".cmplz-btn.cmplz-deny"},
+		Accept: []string{".cmplz-btn.cmplz-accept"},
+		Banner: "#cmplz-cookiebanner-container, .cmplz-cookiebanner",
+	},
+	{
+		Name:   "CookieYes",
+		Reject: []string{".cky-btn-reject"},
+		Accept: []string{".cky-btn-accept"},
+		Banner: ".cky-consent-container, .cky-overlay",
+	},
+	{
+		Name:   "iubenda",
+		Reject: []string{".iubenda-cs-reject-btn"},
+		Accept: []string{".iubenda-cs-accept-btn"},
+		Banner: "#iubenda-cs-banner",
+	},
+	{
+		Name:   "Borlabs Cookie",
+		Reject: []string{"#BorlabsCookieBox ._brlbs-refuse-btn a", "#BorlabsCookieBox ._brlbs-refuse"},
+		Accept: []string{"#BorlabsCookieBox ._brlbs-btn-accept-all", "#BorlabsCookieBox ._brlbs-accept a"},
+		Banner: "#BorlabsCookieBox",
+	},
+	{
+		Name:   "Termly",
+		Reject: []string{`[data-tid="banner-decline"]`},
+		Accept: []string{`[data-tid="banner-accept"]`},
+		Banner: "#termly-code-snippet-support",
+	},
+}
+
+// dismissConsent clicks away the banner of a known consent manager on page u loaded into Chrome.
+// It waits up to consentWait for a banner to show up.
+func (f *ChromeFetcher) dismissConsent(ctx context.Context, u string) error {
+	var name string
+	if err := f.evaluate(ctx, []byte(consentScript), "dismissConsent", &name, consentManagers, consentWait/time.Millisecond); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	logger.Info("Consent banner dismissed", zap.String("manager", name), zap.String("url", u))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(consentSettle):
+	}
+	return nil
+}
+
+// consentScript dismisses the banner of the first consent manager found on the page. A visible reject
+// or accept button is clicked, otherwise the banner is removed. Scrolling locked by the banner is restored.
+const consentScript = `async function dismissConsent(managers, wait) {
+  const visible = (elem) => elem !== null && elem.getClientRects().length > 0;
+  const unlock = () => {
+    for (const elem of [document.documentElement, document.body]) {
+      if (elem && getComputedStyle(elem).overflow === 'hidden') {
+        elem.style.setProperty('overflow', 'auto', 'important');
+      }
+    }
+  };
+  const dismiss = () => {
+    for (const m of managers) {
+      const banner = document.querySelector(m.banner);
+      for (const selector of [...(m.reject || []), ...(m.accept || [])]) {
+        const button = document.querySelector(selector);
+        if (visible(button)) {
+          button.click();
+          unlock();
+          return m.name;
+        }
+      }
+      if (visible(banner)) {
+        banner.remove();
+        unlock();
+        return m.name;
+      }
+    }
+    return '';
+  };
+  const deadline = Date.now() + wait;
+  for (;;) {
+    const name = dismiss();
+    if (name !== '' || Date.now() >= deadline) {
+      return name;
+    }
+    await new Promise((resolve) => setTimeout(resolve, 250));
+  }
+}`
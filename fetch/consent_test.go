@@ -0,0 +1,29 @@
+package fetch
+
+import (
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsentManagers(t *testing.T) {
+	names := map[string]bool{}
+	for _, m := range consentManagers {
+		assert.NotEmpty(t, m.Name)
+		assert.False(t, names[m.Name], "duplicate %s", m.Name)
+		names[m.Name] = true
+		for _, selector := range append(append([]string{m.Banner}, m.Reject...), m.Accept...) {
+			_, err := cascadia.Compile(selector)
+			assert.NoError(t, err, "%s: %s", m.Name, selector)
+		}
+	}
+}
+
+func TestAutoConsentRequiresChrome(t *testing.T) {
+	consent := true
+	_, err := FetchService{}.Fetch(Request{URL: "http://example.com", AutoConsent: &consent})
+	assert.Error(t, err)
+	assert.NotEqual(t, fixtureName(Request{URL: "http://example.com", Type: "chrome"}),
+		fixtureName(Request{URL: "http://example.com", Type: "chrome", AutoConsent: &consent}))
+}
@@ -0,0 +1,164 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+// ConsoleHeader carries console messages and uncaught exceptions of the page rendered by Chrome fetcher.
+// Its value is base64 encoded JSON array of ConsoleMessage.
+const ConsoleHeader = "X-Fetch-Console"
+
+const (
+	// maxConsoleMessages is the number of console messages passed along with the page. Later ones are counted only.
+	maxConsoleMessages = 50
+	// maxConsoleText is the maximum length of message text.
+	maxConsoleText = 300
+	// ConsoleException is the level of uncaught exceptions.
+	ConsoleException = "exception"
+)
+
+// ConsoleMessage is a message logged to browser console by the page or an uncaught exception thrown by its script.
+type ConsoleMessage struct {
+	// Level is console API call type, f.e. "log", "warning" or "error", or "exception" for uncaught exceptions.
+	Level string `json:"level"`
+	Text  string `json:"text"`
+	// URL and Line locate the script logging the message if known.
+	URL  string `json:"url,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (m ConsoleMessage) String() string {
+	s := m.Level + ": " + m.Text
+	if m.URL != "" {
+		s += fmt.Sprintf(" (%s:%d)", m.URL, m.Line)
+	}
+	return s
+}
+
+// Error reports whether the message is a console error or an uncaught exception.
+func (m ConsoleMessage) Error() bool {
+	return m.Level == "error" || m.Level == "assert" || m.Level == ConsoleException
+}
+
+// consoleLog collects console messages of the page loaded into Chrome.
+type consoleLog struct {
+	mx       sync.Mutex
+	messages []ConsoleMessage
+	dropped  int
+}
+
+func (l *consoleLog) add(m ConsoleMessage) {
+	if r := []rune(m.Text); len(r) > maxConsoleText {
+		m.Text = string(r[:maxConsoleText]) + "..."
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if len(l.messages) >= maxConsoleMessages {
+		l.dropped++
+		return
+	}
+	l.messages = append(l.messages, m)
+}
+
+// header returns collected messages encoded as ConsoleHeader. The number of dropped messages is added as the last one.
+func (l *consoleLog) header() http.Header {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if len(l.messages) == 0 {
+		return nil
+	}
+	messages := l.messages
+	if l.dropped > 0 {
+		messages = append(messages, ConsoleMessage{Level: "info", Text: strconv.Itoa(l.dropped) + " more messages are dropped"})
+	}
+	return encodeHeader(ConsoleHeader, messages)
+}
+
+// captureConsole collects console API calls and uncaught exceptions of the page loaded into Chrome until ctx is done.
+func (f *ChromeFetcher) captureConsole(ctx context.Context) (*consoleLog, error) {
+	called, err := f.cdpClient.Runtime.ConsoleAPICalled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	thrown, err := f.cdpClient.Runtime.ExceptionThrown(ctx)
+	if err != nil {
+		called.Close()
+		return nil, err
+	}
+	l := &consoleLog{}
+	go func() {
+		defer called.Close()
+		for {
+			ev, err := called.Recv()
+			if err != nil {
+				return
+			}
+			m := ConsoleMessage{Level: string(ev.Type), Text: consoleArgs(ev.Args)}
+			if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+				frame := ev.StackTrace.CallFrames[0]
+				m.URL, m.Line = frame.URL, frame.LineNumber+1
+			}
+			l.add(m)
+		}
+	}()
+	go func() {
+		defer thrown.Close()
+		for {
+			ev, err := thrown.Recv()
+			if err != nil {
+				return
+			}
+			d := ev.ExceptionDetails
+			m := ConsoleMessage{Level: ConsoleException, Text: d.Text, Line: d.LineNumber + 1}
+			if d.Exception != nil && d.Exception.Description != nil {
+				m.Text += " " + *d.Exception.Description
+			}
+			if d.URL != nil {
+				m.URL = *d.URL
+			}
+			l.add(m)
+		}
+	}()
+	return l, nil
+}
+
+// consoleArgs joins arguments of console API call as they are printed by browser console.
+func consoleArgs(args []runtime.RemoteObject) string {
+	text := ""
+	for i, arg := range args {
+		if i > 0 {
+			text += " "
+		}
+		switch {
+		case len(arg.Value) > 0:
+			var s string
+			if err := json.Unmarshal(arg.Value, &s); err == nil {
+				text += s
+			} else {
+				text += string(arg.Value)
+			}
+		case arg.Description != nil:
+			text += *arg.Description
+		default:
+			text += string(arg.Type)
+		}
+	}
+	return text
+}
+
+// ConsoleMessages returns console messages passed along with the page rendered by Chrome fetcher.
+func ConsoleMessages(rc io.ReadCloser) []ConsoleMessage {
+	messages := []ConsoleMessage{}
+	if !decodeHeader(rc, ConsoleHeader, &messages) {
+		return nil
+	}
+	return messages
+}
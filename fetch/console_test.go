@@ -0,0 +1,47 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleLog(t *testing.T) {
+	l := &consoleLog{}
+	assert.Nil(t, l.header())
+	l.add(ConsoleMessage{Level: "log", Text: "app started"})
+	l.add(ConsoleMessage{Level: ConsoleException, Text: "Uncaught ReferenceError: jQuery is not defined", URL: "http://example.com/app.js", Line: 3})
+	l.add(ConsoleMessage{Level: "warning", Text: strings.Repeat("x", maxConsoleText+10)})
+
+	rc := withHeader(ioutil.NopCloser(strings.NewReader("<html></html>")), l.header())
+	messages := ConsoleMessages(rc)
+	assert.Len(t, messages, 3)
+	assert.False(t, messages[0].Error())
+	assert.True(t, messages[1].Error())
+	assert.Equal(t, "exception: Uncaught ReferenceError: jQuery is not defined (http://example.com/app.js:3)", messages[1].String())
+	assert.Len(t, messages[2].Text, maxConsoleText+len("..."))
+
+	for i := 0; i < maxConsoleMessages; i++ {
+		l.add(ConsoleMessage{Level: "log", Text: "tick"})
+	}
+	messages = ConsoleMessages(withHeader(ioutil.NopCloser(strings.NewReader("")), l.header()))
+	assert.Len(t, messages, maxConsoleMessages+1)
+	assert.Equal(t, "3 more messages are dropped", messages[maxConsoleMessages].Text)
+
+	assert.Nil(t, ConsoleMessages(ioutil.NopCloser(strings.NewReader(""))))
+}
+
+func TestConsoleArgs(t *testing.T) {
+	description := "Error: failed"
+	args := []runtime.RemoteObject{
+		{Type: "string", Value: json.RawMessage(`"price"`)},
+		{Type: "number", Value: json.RawMessage(`42`)},
+		{Type: "object", Description: &description},
+		{Type: "undefined"},
+	}
+	assert.Equal(t, "price 42 Error: failed undefined", consoleArgs(args))
+}
@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// Cookie is injected into a single request. It may be scoped to a domain and path,
+// f.e. a consent or region cookie set for all subdomains of a site. No session is required for it.
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain of the cookie. The cookie is sent to the domain and its subdomains.
+	// If it is empty, the cookie is sent to the host of request URL only.
+	Domain string `json:"domain,omitempty"`
+	// Path of the cookie. The cookie is sent to URLs under the path. Defaults to "/".
+	Path string `json:"path,omitempty"`
+}
+
+// Cookies are sent with a request along with cookies stored for its UserToken.
+// They may be written as a list of cookies or as an object mapping names to values, f.e. {"region": "eu"}.
+type Cookies []Cookie
+
+// UnmarshalJSON decodes cookies written as a list or as an object mapping names to values.
+// Cookies of an object are sorted by name.
+func (c *Cookies) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		values := map[string]string{}
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return err
+		}
+		cookies := make(Cookies, 0, len(values))
+		for name, value := range values {
+			cookies = append(cookies, Cookie{Name: name, Value: value})
+		}
+		sort.Slice(cookies, func(i, j int) bool { return cookies[i].Name < cookies[j].Name })
+		*c = cookies
+		return nil
+	}
+	return json.Unmarshal(data, (*[]Cookie)(c))
+}
+
+// Merge returns cookies replaced and extended by override.
+// A cookie is replaced by the cookie of override with the same name, domain and path.
+func (c Cookies) Merge(override Cookies) Cookies {
+	if len(override) == 0 {
+		return c
+	}
+	merged := make(Cookies, 0, len(c)+len(override))
+	for _, cookie := range c {
+		if !override.has(cookie) {
+			merged = append(merged, cookie)
+		}
+	}
+	return append(merged, override...)
+}
+
+// has reports whether there is a cookie with the same name, domain and path as cookie.
+func (c Cookies) has(cookie Cookie) bool {
+	for _, other := range c {
+		if other.Name == cookie.Name && strings.EqualFold(other.Domain, cookie.Domain) && other.Path == cookie.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCookies checks names and values of cookies.
+func validateCookies(cookies Cookies) error {
+	for _, c := range cookies {
+		if c.Name == "" {
+			return errs.BadPayload{ErrText: "cookie requires name"}
+		}
+		if (&http.Cookie{Name: c.Name, Value: c.Value}).String() == "" || strings.ContainsAny(c.Domain, "/:") {
+			return errs.BadPayload{ErrText: fmt.Sprintf("invalid cookie %q", c.Name)}
+		}
+		if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+			return errs.BadPayload{ErrText: fmt.Sprintf("cookie %q path should start with /", c.Name)}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the cookie should be sent with request to u.
+func (c Cookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if domain := strings.ToLower(strings.TrimPrefix(c.Domain, ".")); domain != "" && host != domain &&
+		!strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	if c.Path == "" || c.Path == "/" {
+		return true
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	return p == c.Path || strings.HasPrefix(p, strings.TrimSuffix(c.Path, "/")+"/")
+}
+
+// setCookieArgs returns arguments setting the cookie in the browser before navigation to pageURL.
+func (c Cookie) setCookieArgs(pageURL string) *network.SetCookieArgs {
+	args := network.NewSetCookieArgs(c.Name, c.Value)
+	if c.Domain != "" {
+		domain := c.Domain
+		args.Domain = &domain
+	} else {
+		args.URL = &pageURL
+	}
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	args.Path = &path
+	return args
+}
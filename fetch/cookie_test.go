@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookie_matches(t *testing.T) {
+	u, _ := url.Parse("https://www.example.com/shop/item?id=1")
+	assert.True(t, Cookie{Name: "a"}.matches(u))
+	assert.True(t, Cookie{Name: "a", Domain: "example.com"}.matches(u))
+	assert.True(t, Cookie{Name: "a", Domain: ".Example.com"}.matches(u))
+	assert.True(t, Cookie{Name: "a", Domain: "www.example.com", Path: "/shop"}.matches(u))
+	assert.True(t, Cookie{Name: "a", Path: "/shop/"}.matches(u))
+	assert.False(t, Cookie{Name: "a", Domain: "shop.example.com"}.matches(u))
+	assert.False(t, Cookie{Name: "a", Domain: "ample.com"}.matches(u))
+	assert.False(t, Cookie{Name: "a", Path: "/sh"}.matches(u))
+	assert.False(t, Cookie{Name: "a", Path: "/cart"}.matches(u))
+}
+
+func TestValidateCookies(t *testing.T) {
+	assert.NoError(t, validateCookies(nil))
+	assert.NoError(t, validateCookies([]Cookie{{Name: "consent", Value: "yes", Domain: "example.com", Path: "/"}}))
+	for _, c := range []Cookie{
+		{Value: "yes"},
+		{Name: "bad name", Value: "yes"},
+		{Name: "a", Domain: "http://example.com"},
+		{Name: "a", Path: "shop"},
+	} {
+		assert.Error(t, validateCookies([]Cookie{c}), c.Name)
+	}
+}
+
+func TestCookies_UnmarshalJSON(t *testing.T) {
+	var req Request
+	assert.NoError(t, json.Unmarshal([]byte(`{"cookies": {"sid": "42", "region": "eu"}}`), &req))
+	assert.Equal(t, Cookies{{Name: "region", Value: "eu"}, {Name: "sid", Value: "42"}}, req.Cookies)
+	assert.NoError(t, json.Unmarshal([]byte(`{"cookies": [{"name": "consent", "value": "yes", "domain": "example.com"}]}`), &req))
+	assert.Equal(t, Cookies{{Name: "consent", Value: "yes", Domain: "example.com"}}, req.Cookies)
+	assert.Error(t, json.Unmarshal([]byte(`{"cookies": {"sid": 42}}`), &req))
+}
+
+func TestCookies_Merge(t *testing.T) {
+	base := Cookies{{Name: "region", Value: "eu"}, {Name: "sid", Value: "1", Domain: "example.com"}}
+	merged := base.Merge(Cookies{{Name: "region", Value: "us"}, {Name: "sid", Value: "2"}})
+	assert.Equal(t, Cookies{{Name: "sid", Value: "1", Domain: "example.com"}, {Name: "region", Value: "us"}, {Name: "sid", Value: "2"}}, merged)
+	assert.Equal(t, base, base.Merge(nil))
+}
+
+func TestBaseFetcher_Cookies(t *testing.T) {
+	viper.Set("PROXY", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range r.Cookies() {
+			w.Write([]byte(c.Name + "=" + c.Value + ";"))
+		}
+	}))
+	defer ts.Close()
+	req := Request{
+		URL: ts.URL + "/shop/list",
+		Cookies: Cookies{
+			{Name: "consent", Value: "yes", Domain: "127.0.0.1"},
+			{Name: "region", Value: "eu", Path: "/shop"},
+			{Name: "cart", Value: "1", Path: "/cart"},
+			{Name: "other", Value: "1", Domain: "example.com"},
+		},
+	}
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(req)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "consent=yes;region=eu;", string(data))
+
+	plain := Request{URL: req.URL}
+	assert.NotEqual(t, fixtureName(plain), fixtureName(req))
+}
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/slotix/dataflowkit/errs"
+	"go.uber.org/zap"
+)
+
+// Dialog actions
+const (
+	DialogAccept  = "accept"
+	DialogDismiss = "dismiss"
+)
+
+// Dialogs defines how Chrome fetcher answers alert, confirm, prompt and beforeunload dialogs
+// opened by the page. Unanswered dialogs block the page until fetch timeout.
+type Dialogs struct {
+	// Action is either "accept" or "dismiss". Defaults to CHROME_DIALOG_ACTION.
+	Action string `json:"action,omitempty"`
+	// PromptText is entered into prompt dialogs before they are accepted.
+	// Default prompt value is used if it is empty. Defaults to CHROME_DIALOG_PROMPT_TEXT.
+	PromptText string `json:"promptText,omitempty"`
+}
+
+// validate checks dialog action.
+func (d *Dialogs) validate() error {
+	if d == nil {
+		return nil
+	}
+	switch strings.ToLower(d.Action) {
+	case "", DialogAccept, DialogDismiss:
+		return nil
+	}
+	return errs.BadPayload{ErrText: "unknown dialogs action " + d.Action}
+}
+
+// key returns a string identifying dialog settings in fixture names.
+func (d *Dialogs) key() string {
+	if d == nil {
+		return ""
+	}
+	return strings.ToLower(d.Action) + " " + d.PromptText
+}
+
+// dialogAnswer returns whether the dialog of type typ is accepted and the text entered into prompt.
+// Request settings d take precedence over fetcher settings. beforeunload dialogs are always accepted
+// so the page can be left.
+func (o *options) dialogAnswer(d *Dialogs, typ, defaultPrompt string) (bool, string) {
+	action := o.dialogAction
+	text := o.dialogPrompt
+	if d != nil {
+		if d.Action != "" {
+			action = d.Action
+		}
+		if d.PromptText != "" {
+			text = d.PromptText
+		}
+	}
+	if typ == string(page.DialogTypeBeforeunload) {
+		return true, ""
+	}
+	accept := strings.ToLower(action) != DialogDismiss
+	if typ != string(page.DialogTypePrompt) || !accept {
+		return accept, ""
+	}
+	if text == "" {
+		text = defaultPrompt
+	}
+	return accept, text
+}
+
+// handleDialogs answers JavaScript dialogs opened by the page loaded into Chrome until ctx is done.
+func (f *ChromeFetcher) handleDialogs(ctx context.Context, d *Dialogs) error {
+	opening, err := f.cdpClient.Page.JavascriptDialogOpening(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer opening.Close()
+		for {
+			ev, err := opening.Recv()
+			if err != nil {
+				return
+			}
+			defaultPrompt := ""
+			if ev.DefaultPrompt != nil {
+				defaultPrompt = *ev.DefaultPrompt
+			}
+			accept, text := f.opts.dialogAnswer(d, string(ev.Type), defaultPrompt)
+			args := page.NewHandleJavaScriptDialogArgs(accept)
+			if text != "" {
+				args.SetPromptText(text)
+			}
+			logger.Info("JavaScript dialog",
+				zap.String("Type", string(ev.Type)),
+				zap.String("Message", ev.Message),
+				zap.Bool("Accepted", accept))
+			if err := f.cdpClient.Page.HandleJavaScriptDialog(ctx, args); err != nil {
+				logger.Warn("Failed to handle JavaScript dialog. " + err.Error())
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialogAnswer(t *testing.T) {
+	o, err := newOptions([]Option{WithDialogs(DialogAccept, "")})
+	assert.NoError(t, err)
+
+	accept, text := o.dialogAnswer(nil, "confirm", "")
+	assert.True(t, accept)
+	assert.Equal(t, "", text)
+
+	accept, text = o.dialogAnswer(nil, "prompt", "default")
+	assert.True(t, accept)
+	assert.Equal(t, "default", text)
+
+	accept, text = o.dialogAnswer(&Dialogs{PromptText: "42"}, "prompt", "default")
+	assert.True(t, accept)
+	assert.Equal(t, "42", text)
+
+	accept, text = o.dialogAnswer(&Dialogs{Action: "Dismiss", PromptText: "42"}, "prompt", "default")
+	assert.False(t, accept)
+	assert.Equal(t, "", text)
+
+	//beforeunload dialogs never keep the page
+	accept, _ = o.dialogAnswer(&Dialogs{Action: DialogDismiss}, "beforeunload", "")
+	assert.True(t, accept)
+
+	o, err = newOptions([]Option{WithDialogs("Dismiss", "")})
+	assert.NoError(t, err)
+	accept, _ = o.dialogAnswer(nil, "alert", "")
+	assert.False(t, accept)
+	accept, _ = o.dialogAnswer(&Dialogs{Action: DialogAccept}, "confirm", "")
+	assert.True(t, accept)
+}
+
+func TestDialogsValidate(t *testing.T) {
+	assert.NoError(t, (*Dialogs)(nil).validate())
+	assert.NoError(t, (&Dialogs{Action: "accept"}).validate())
+	assert.NoError(t, (&Dialogs{PromptText: "text"}).validate())
+	assert.Error(t, (&Dialogs{Action: "ignore"}).validate())
+	_, err := newOptions([]Option{WithDialogs("ignore", "")})
+	assert.Error(t, err)
+
+	viper.Set("CHROME_DIALOG_ACTION", "ignore")
+	assert.Error(t, CheckSettings())
+	viper.Set("CHROME_DIALOG_ACTION", DialogDismiss)
+	assert.NoError(t, CheckSettings())
+	viper.Set("CHROME_DIALOG_ACTION", "")
+
+	req := Request{Type: "chrome", URL: "http://example.com"}
+	withDialogs := req
+	withDialogs.Dialogs = &Dialogs{Action: DialogDismiss}
+	assert.NotEqual(t, fixtureName(req), fixtureName(withDialogs))
+}
@@ -17,8 +17,25 @@
 //
 // Chrome Fetcher connects to Headless Chrome which renders JavaScript pages.
 //
+// Go programs may use fetchers without the service. NewBaseFetcher and NewChromeFetcher are configured
+// with options like WithProxy, WithTimeout or WithChrome instead of the service settings:
+//
+//	fetcher, err := fetch.NewChromeFetcher(fetch.WithChrome("http://127.0.0.1:9222"), fetch.WithTimeout(30*time.Second))
+//
+// Fetchers are safe for concurrent use. Every Fetch call gets a cookie jar of its own (Chrome fetcher opens a new tab),
+// so cookies set by a site during one request are never sent with another one. Cookies and localStorage are kept
+// between requests by Fetch service only, per user token.
+//
+// ChromeSupervisor launches local Headless Chrome for single-node setups, restarts it if it crashes
+// and closes tabs left open by aborted fetches. Fetch service uses it if CHROME setting is "local".
+//
 // RobotsTxtMiddleware checks if scraping of specified resource is allowed by robots.txt
 //
+// Applications embedding fetch service add their own middlewares (authentication, quotas, caching)
+// with RegisterMiddleware before calling Start, f.e. from init functions. They wrap built-in middlewares,
+// so requests rejected by them are still logged. Middlewares are applied in the order of registration,
+// so the last registered is called first.
+//
 package fetch
 
 // EOF
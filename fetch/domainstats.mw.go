@@ -0,0 +1,216 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// maxTrackedDomains bounds memory used by DomainStats. Fetches of further domains are counted under otherDomains.
+const maxTrackedDomains = 10000
+
+const otherDomains = "*"
+
+// blockScanSize is the size of the page beginning scanned for signs of a block page.
+const blockScanSize = 64 << 10
+
+// blockMarkers are lowercase snippets of captcha, bot-check and access-denied pages served with 200 status.
+var blockMarkers = [][]byte{
+	[]byte("g-recaptcha"),
+	[]byte("h-captcha"),
+	[]byte("cf-browser-verification"),
+	[]byte("cf-challenge"),
+	[]byte("px-captcha"),
+	[]byte("distil_r_captcha"),
+	[]byte("are you a robot"),
+	[]byte("unusual traffic from your computer"),
+	[]byte("<title>access denied</title>"),
+	[]byte("request unsuccessful. incapsula"),
+}
+
+// DomainCounters contains fetch results of a domain.
+type DomainCounters struct {
+	Domain string `json:"domain"`
+	//Fetched is the number of pages fetched successfully
+	Fetched int64 `json:"fetched"`
+	//Forbidden, NotFound, TooManyRequests and ServerErrors count 403, 404, 429 and 5xx responses
+	Forbidden       int64 `json:"forbidden"`
+	NotFound        int64 `json:"notFound"`
+	TooManyRequests int64 `json:"tooManyRequests"`
+	ServerErrors    int64 `json:"serverErrors"`
+	//Gone counts pages removed permanently or blocked for legal reasons (410 and 451 responses)
+	Gone int64 `json:"gone"`
+	//OtherErrors counts other failures, f.e. network errors or timeouts
+	OtherErrors int64 `json:"otherErrors"`
+	//Blocked is the number of fetched pages recognized as captcha or bot-check pages
+	Blocked int64 `json:"blocked"`
+	//AvgLatency is the average time in milliseconds to get a response
+	AvgLatency float64 `json:"avgLatency"`
+
+	totalLatency time.Duration
+	requests     int64
+}
+
+// DomainStats keeps per-domain fetch counters so degrading targets and abusive patterns can be spotted.
+type DomainStats struct {
+	mx      sync.Mutex
+	domains map[string]*DomainCounters
+}
+
+// NewDomainStats creates empty DomainStats.
+func NewDomainStats() *DomainStats {
+	return &DomainStats{domains: make(map[string]*DomainCounters)}
+}
+
+// counters returns counters of domain. Caller should hold the lock.
+func (s *DomainStats) counters(domain string) *DomainCounters {
+	c, ok := s.domains[domain]
+	if !ok {
+		if len(s.domains) >= maxTrackedDomains {
+			domain = otherDomains
+			if c, ok = s.domains[domain]; ok {
+				return c
+			}
+		}
+		c = &DomainCounters{Domain: domain}
+		s.domains[domain] = c
+	}
+	return c
+}
+
+// record counts the result of a fetch of domain which took latency.
+func (s *DomainStats) record(domain string, latency time.Duration, err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	c := s.counters(domain)
+	c.requests++
+	c.totalLatency += latency
+	if err == nil {
+		c.Fetched++
+		return
+	}
+	status := 0
+	if e, ok := err.(errs.Error); ok {
+		status = e.Status()
+	}
+	switch {
+	case status == http.StatusForbidden:
+		c.Forbidden++
+	case status == http.StatusNotFound:
+		c.NotFound++
+	case status == http.StatusGone, status == http.StatusUnavailableForLegalReasons:
+		c.Gone++
+	case status == http.StatusTooManyRequests:
+		c.TooManyRequests++
+	case status >= 500:
+		c.ServerErrors++
+	default:
+		c.OtherErrors++
+	}
+}
+
+// blocked counts a fetched page of domain recognized as a block page.
+func (s *DomainStats) blocked(domain string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.counters(domain).Blocked++
+}
+
+// Stats returns a snapshot of counters of all domains sorted by domain.
+func (s *DomainStats) Stats() []DomainCounters {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	stats := make([]DomainCounters, 0, len(s.domains))
+	for _, c := range s.domains {
+		snapshot := *c
+		if c.requests > 0 {
+			snapshot.AvgLatency = float64(c.totalLatency/time.Millisecond) / float64(c.requests)
+		}
+		stats = append(stats, snapshot)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Domain < stats[j].Domain
+	})
+	return stats
+}
+
+// isBlockPage reports whether the beginning of a page looks like a captcha or bot-check page.
+func isBlockPage(head []byte) bool {
+	head = bytes.ToLower(head)
+	for _, m := range blockMarkers {
+		if bytes.Contains(head, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// DomainStatsMiddleware counts results of Fetch service requests per domain in stats.
+func DomainStatsMiddleware(stats *DomainStats) ServiceMiddleware {
+	return func(next Service) Service {
+		return domainStatsMiddleware{next, stats}
+	}
+}
+
+type domainStatsMiddleware struct {
+	Service
+	stats *DomainStats
+}
+
+// Fetch counts the response of req. The beginning of fetched content is checked for block page signs
+// as it is read, so content is still streamed.
+func (mw domainStatsMiddleware) Fetch(req Request) (io.ReadCloser, error) {
+	domain, err := req.Host()
+	if err != nil || domain == "" {
+		return mw.Service.Fetch(req)
+	}
+	begin := time.Now()
+	content, err := mw.Service.Fetch(req)
+	mw.stats.record(domain, time.Since(begin), err)
+	if err != nil {
+		return nil, err
+	}
+	return &blockCheckReader{ReadCloser: content, check: func(head []byte) {
+		if isBlockPage(head) {
+			mw.stats.blocked(domain)
+		}
+	}}, nil
+}
+
+// blockCheckReader keeps the beginning of content being read and passes it to check once
+// blockScanSize bytes are read or content is closed.
+type blockCheckReader struct {
+	io.ReadCloser
+	head  []byte
+	check func(head []byte)
+	once  sync.Once
+}
+
+func (r *blockCheckReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if len(r.head) < blockScanSize {
+		rest := blockScanSize - len(r.head)
+		if rest > n {
+			rest = n
+		}
+		r.head = append(r.head, p[:rest]...)
+		if len(r.head) == blockScanSize {
+			r.once.Do(func() { r.check(r.head) })
+		}
+	}
+	return n, err
+}
+
+func (r *blockCheckReader) responseHeader() http.Header {
+	return ResponseHeader(r.ReadCloser)
+}
+
+func (r *blockCheckReader) Close() error {
+	r.once.Do(func() { r.check(r.head) })
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+type pagesService map[string]string
+
+func (s pagesService) Fetch(req Request) (io.ReadCloser, error) {
+	switch page := s[req.URL]; page {
+	case "403":
+		return nil, errs.StatusError{Code: 403, Err: errors.New("Forbidden")}
+	case "410":
+		return nil, errs.FetchError{URL: req.URL, Code: 410, Reason: "410 Gone"}
+	case "503":
+		return nil, errs.StatusError{Code: 503, Err: errors.New("Service Unavailable")}
+	case "":
+		return nil, errors.New("connection refused")
+	default:
+		return ioutil.NopCloser(strings.NewReader(page)), nil
+	}
+}
+
+func TestDomainStatsMiddleware(t *testing.T) {
+	stats := NewDomainStats()
+	svc := DomainStatsMiddleware(stats)(pagesService{
+		"http://example.com/":        "<html><body>Hello</body></html>",
+		"http://example.com/captcha": `<html><body><div class="g-recaptcha"></div></body></html>`,
+		"http://example.com/admin":   "403",
+		"http://example.com/old":     "410",
+		"http://example.org/":        "503",
+	})
+	for _, url := range []string{"http://example.com/", "http://example.com/captcha", "http://example.com/admin",
+		"http://example.com/old", "http://example.org/", "http://example.org/missing"} {
+		content, err := svc.Fetch(Request{URL: url})
+		if err == nil {
+			_, err = ioutil.ReadAll(content)
+			assert.NoError(t, err)
+			content.Close()
+		}
+	}
+	s := stats.Stats()
+	assert.Equal(t, 2, len(s))
+	assert.Equal(t, "example.com", s[0].Domain)
+	assert.Equal(t, int64(2), s[0].Fetched)
+	assert.Equal(t, int64(1), s[0].Blocked)
+	assert.Equal(t, int64(1), s[0].Forbidden)
+	assert.Equal(t, int64(1), s[0].Gone)
+	assert.Equal(t, int64(0), s[0].NotFound)
+	assert.Equal(t, "example.org", s[1].Domain)
+	assert.Equal(t, int64(1), s[1].ServerErrors)
+	assert.Equal(t, int64(1), s[1].OtherErrors)
+	assert.Equal(t, int64(0), s[1].Fetched)
+}
+
+func TestIsBlockPage(t *testing.T) {
+	assert.True(t, isBlockPage([]byte("<HTML><TITLE>Access Denied</TITLE></HTML>")))
+	assert.False(t, isBlockPage([]byte("<html><title>Shop</title></html>")))
+}
@@ -0,0 +1,271 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/target"
+	"github.com/mafredri/cdp/rpcc"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// defaultDownloadTimeout is used if CHROME_DOWNLOAD_TIMEOUT is not set.
+const defaultDownloadTimeout = 60 * time.Second
+
+// partialDownloadExt is an extension of files being downloaded by Chrome.
+const partialDownloadExt = ".crdownload"
+
+// downloadPath returns an absolute path of the directory Chrome saves downloaded files to.
+// Chrome and Fetch service should share it.
+func (o *options) downloadPath() (string, error) {
+	dir := o.downloadDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Abs(dir)
+}
+
+// isolatedTarget opens a tab in a browser context of its own. Download behavior and download progress events
+// of Chrome are scoped to the browser context, so concurrent fetches don't share the download directory
+// and don't count or cancel each other's downloads. dispose removes the browser context along with the tab.
+func (f *ChromeFetcher) isolatedTarget(ctx context.Context, devt *devtool.DevTools) (*devtool.Target, func(), error) {
+	v, err := devt.Version(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := rpcc.DialContext(ctx, v.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	browser := cdp.NewClient(conn)
+	bc, err := browser.Target.CreateBrowserContext(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	dispose := func() {
+		disposeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := browser.Target.DisposeBrowserContext(disposeCtx, target.NewDisposeBrowserContextArgs(bc.BrowserContextID)); err != nil {
+			logger.Warn("Failed to dispose browser context. " + err.Error())
+		}
+		conn.Close()
+	}
+	t, err := browser.Target.CreateTarget(ctx, target.NewCreateTargetArgs("about:blank").SetBrowserContextID(bc.BrowserContextID))
+	if err != nil {
+		dispose()
+		return nil, nil, err
+	}
+	targets, err := devt.List(ctx)
+	if err != nil {
+		dispose()
+		return nil, nil, err
+	}
+	for _, pt := range targets {
+		if pt.ID == string(t.TargetID) {
+			f.browserContext = bc.BrowserContextID
+			return pt, dispose, nil
+		}
+	}
+	dispose()
+	return nil, nil, fmt.Errorf("target %s is not found", t.TargetID)
+}
+
+// download clicks the element started downloading a file, waits for the download to complete
+// and saves the file to the storage. It returns JSON encoded BinaryInfo.
+func (f *ChromeFetcher) download(ctx context.Context, req Request) (io.ReadCloser, error) {
+	base, err := f.opts.downloadPath()
+	if err != nil {
+		return nil, err
+	}
+	//every fetch downloads to a directory of its own
+	dir, err := ioutil.TempDir(base, "download")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	maxSize := f.opts.maxBinarySize
+	watch, err := f.watchDownloads(ctx, dir, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer watch.close()
+	if err := f.evaluate(ctx, []byte(clickScript), "click", nil, req.Download); err != nil {
+		return nil, errs.StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+	path, err := waitDownload(ctx, dir, f.opts.downloadTimeout, maxSize, watch.exceeded)
+	if err == errDownloadTooLarge {
+		watch.cancel()
+		return nil, errTooLarge(req.getURL(), maxSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	//Chrome downloads files outside of network events of the tab
+	f.opts.bandwidth.account(req.Job, int64(len(content)))
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	return f.opts.saveBinary(BinaryInfo{
+		URL:         req.getURL(),
+		ContentType: contentType,
+		Filename:    filepath.Base(path),
+	}, content)
+}
+
+// errDownloadTooLarge is returned by waitDownload if a file exceeds the size limit.
+var errDownloadTooLarge = errors.New("download exceeds size limit")
+
+// downloadProgress is Browser.downloadProgress event of Chrome.
+type downloadProgress struct {
+	GUID          string  `json:"guid"`
+	TotalBytes    float64 `json:"totalBytes"`
+	ReceivedBytes float64 `json:"receivedBytes"`
+	//State is one of "inProgress", "completed" or "canceled"
+	State string `json:"state"`
+}
+
+// downloadWatch follows progress of downloads of the tab and cancels them as soon as they exceed maxSize,
+// so a large file doesn't fill the disk before the download completes.
+type downloadWatch struct {
+	conn    *rpcc.Conn
+	stream  rpcc.Stream
+	maxSize int64
+	//exceeded is closed when a download exceeds maxSize
+	exceeded chan struct{}
+	once     sync.Once
+	mx       sync.Mutex
+	//inProgress keeps GUIDs of downloads in progress
+	inProgress map[string]bool
+}
+
+// watchDownloads makes Chrome save files downloaded by the tab to dir and report their progress.
+// Download behavior and progress events are limited to the browser context of the tab opened by isolatedTarget.
+// Chrome versions without Browser.setDownloadBehavior report no progress, so the size of files in dir
+// is only checked by waitDownload then.
+func (f *ChromeFetcher) watchDownloads(ctx context.Context, dir string, maxSize int64) (*downloadWatch, error) {
+	w := &downloadWatch{conn: f.conn, maxSize: maxSize, exceeded: make(chan struct{}), inProgress: map[string]bool{}}
+	stream, err := rpcc.NewStream(ctx, "Browser.downloadProgress", f.conn)
+	if err != nil {
+		return nil, err
+	}
+	args := map[string]interface{}{"behavior": "allow", "downloadPath": dir, "eventsEnabled": true}
+	if f.browserContext != "" {
+		args["browserContextId"] = f.browserContext
+	}
+	if err := rpcc.Invoke(ctx, "Browser.setDownloadBehavior", args, nil, f.conn); err != nil {
+		stream.Close()
+		return w, f.cdpClient.Page.SetDownloadBehavior(ctx, page.NewSetDownloadBehaviorArgs("allow").SetDownloadPath(dir))
+	}
+	w.stream = stream
+	go w.follow()
+	return w, nil
+}
+
+// follow cancels downloads once they exceed maxSize. It returns when the stream is closed.
+func (w *downloadWatch) follow() {
+	for {
+		var p downloadProgress
+		if err := w.stream.RecvMsg(&p); err != nil {
+			return
+		}
+		w.mx.Lock()
+		if p.State == "inProgress" {
+			w.inProgress[p.GUID] = true
+		} else {
+			delete(w.inProgress, p.GUID)
+		}
+		w.mx.Unlock()
+		if p.State == "inProgress" && (p.ReceivedBytes > float64(w.maxSize) || p.TotalBytes > float64(w.maxSize)) {
+			w.cancel()
+		}
+	}
+}
+
+// cancel cancels downloads in progress and reports that the size limit is exceeded.
+func (w *downloadWatch) cancel() {
+	w.once.Do(func() { close(w.exceeded) })
+	w.mx.Lock()
+	guids := []string{}
+	for guid := range w.inProgress {
+		guids = append(guids, guid)
+	}
+	w.mx.Unlock()
+	for _, guid := range guids {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := rpcc.Invoke(ctx, "Browser.cancelDownload", map[string]string{"guid": guid}, nil, w.conn); err != nil {
+			logger.Warn("Failed to cancel download. " + err.Error())
+		}
+		cancel()
+	}
+}
+
+func (w *downloadWatch) close() {
+	if w.stream != nil {
+		w.stream.Close()
+	}
+}
+
+// waitDownload waits until a complete file appears in dir and returns its path.
+// It fails with errDownloadTooLarge as soon as a file in dir exceeds maxSize or exceeded is closed.
+func waitDownload(ctx context.Context, dir string, timeout time.Duration, maxSize int64, exceeded <-chan struct{}) (string, error) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		partial := false
+		for _, fi := range files {
+			if fi.Size() > maxSize {
+				return "", errDownloadTooLarge
+			}
+			if strings.HasSuffix(fi.Name(), partialDownloadExt) {
+				partial = true
+			}
+		}
+		if !partial {
+			for _, fi := range files {
+				if !fi.IsDir() {
+					return filepath.Join(dir, fi.Name()), nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-exceeded:
+			return "", errDownloadTooLarge
+		case <-deadline:
+			return "", errs.StatusError{
+				Code: http.StatusGatewayTimeout,
+				Err:  fmt.Errorf("download is not completed in %s", timeout),
+			}
+		case <-ticker.C:
+		}
+	}
+}
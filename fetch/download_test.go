@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "download")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = waitDownload(context.Background(), dir, 300*time.Millisecond, 1024, nil)
+	assert.Error(t, err, "nothing is downloaded")
+
+	partial := filepath.Join(dir, "export.csv"+partialDownloadExt)
+	assert.NoError(t, ioutil.WriteFile(partial, []byte("a,b"), 0644))
+	_, err = waitDownload(context.Background(), dir, 300*time.Millisecond, 1024, nil)
+	assert.Error(t, err, "download is in progress")
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		os.Rename(partial, filepath.Join(dir, "export.csv"))
+	}()
+	path, err := waitDownload(context.Background(), dir, 2*time.Second, 1024, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "export.csv", filepath.Base(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	empty, err := ioutil.TempDir("", "download")
+	assert.NoError(t, err)
+	defer os.RemoveAll(empty)
+	_, err = waitDownload(ctx, empty, time.Second, 1024, nil)
+	assert.Error(t, err)
+
+	//downloads are aborted as soon as a partial file exceeds the limit
+	large := filepath.Join(empty, "dump.zip"+partialDownloadExt)
+	assert.NoError(t, ioutil.WriteFile(large, make([]byte, 2048), 0644))
+	_, err = waitDownload(context.Background(), empty, 2*time.Second, 1024, nil)
+	assert.Equal(t, errDownloadTooLarge, err)
+	os.Remove(large)
+
+	//or when Chrome reports it
+	exceeded := make(chan struct{})
+	close(exceeded)
+	_, err = waitDownload(context.Background(), empty, 2*time.Second, 1024, exceeded)
+	assert.Equal(t, errDownloadTooLarge, err)
+}
+
+func TestDownloadRequest(t *testing.T) {
+	//downloads are supported by chrome fetcher only
+	_, err := FetchService{}.Fetch(Request{URL: "http://example.com", Download: "#export"})
+	assert.Error(t, err)
+
+	req := Request{Type: "chrome", URL: "http://example.com/report"}
+	withDownload := req
+	withDownload.Download = "#export"
+	assert.NotEqual(t, fixtureName(req), fixtureName(withDownload))
+}
@@ -0,0 +1,140 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// FailedRequestsHeader carries requests of the page rendered by Chrome fetcher which failed or were answered
+// with HTTP error status, f.e. blocked scripts or missing images. Its value is base64 encoded JSON array of FailedRequest.
+const FailedRequestsHeader = "X-Fetch-Failed-Requests"
+
+// maxFailedRequests is the number of failed requests passed along with the page. Later ones are counted only.
+const maxFailedRequests = 50
+
+// FailedRequest is a request of the page or its subresource which has failed.
+type FailedRequest struct {
+	URL string `json:"url"`
+	// Type is resource type, f.e. "Document", "Script", "Image" or "XHR".
+	Type string `json:"type,omitempty"`
+	// Status is HTTP status of the response. It is 0 if no response has been received.
+	Status int `json:"status,omitempty"`
+	// Error is network error, f.e. "net::ERR_NAME_NOT_RESOLVED".
+	Error string `json:"error,omitempty"`
+	// Blocked is the reason the request was blocked by the browser, f.e. "csp" or "mixed-content".
+	Blocked string `json:"blocked,omitempty"`
+}
+
+// Reason returns a short reason of the failure so failures may be grouped, f.e. "HTTP 404" or "blocked: csp".
+func (r FailedRequest) Reason() string {
+	switch {
+	case r.Blocked != "":
+		return "blocked: " + r.Blocked
+	case r.Status != 0:
+		return "HTTP " + strconv.Itoa(r.Status)
+	}
+	return r.Error
+}
+
+func (r FailedRequest) String() string {
+	if r.Type == "" {
+		return r.Reason() + " " + r.URL
+	}
+	return fmt.Sprintf("%s %s %s", r.Reason(), r.Type, r.URL)
+}
+
+// sentRequest is a request of the page loaded into Chrome which has not completed yet.
+type sentRequest struct {
+	url string
+	typ string
+}
+
+// requestLog collects failed requests of the page loaded into Chrome.
+type requestLog struct {
+	mx      sync.Mutex
+	sent    map[network.RequestID]sentRequest
+	failed  []FailedRequest
+	dropped int
+}
+
+func newRequestLog() *requestLog {
+	return &requestLog{sent: make(map[network.RequestID]sentRequest)}
+}
+
+func (l *requestLog) started(id network.RequestID, url, typ string) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.sent[id] = sentRequest{url: url, typ: typ}
+}
+
+// responded records the response of request id. Responses with status below 400 are not failures.
+func (l *requestLog) responded(id network.RequestID, url, typ string, status int) {
+	if status < http.StatusBadRequest {
+		return
+	}
+	l.add(FailedRequest{URL: url, Type: typ, Status: status})
+}
+
+// finished forgets completed request id.
+func (l *requestLog) finished(id network.RequestID) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	delete(l.sent, id)
+}
+
+// loadingFailed records request id which has failed with errorText or has been blocked by the browser.
+// Canceled requests, f.e. of the page navigated away, are not failures.
+func (l *requestLog) loadingFailed(id network.RequestID, typ, errorText, blocked string, canceled bool) {
+	l.mx.Lock()
+	req := l.sent[id]
+	delete(l.sent, id)
+	l.mx.Unlock()
+	if canceled {
+		return
+	}
+	if req.typ == "" {
+		req.typ = typ
+	}
+	l.add(FailedRequest{URL: req.url, Type: req.typ, Error: errorText, Blocked: blocked})
+}
+
+func (l *requestLog) add(r FailedRequest) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if len(l.failed) >= maxFailedRequests {
+		l.dropped++
+		return
+	}
+	l.failed = append(l.failed, r)
+}
+
+// header returns failed requests encoded as FailedRequestsHeader. The number of dropped ones is added as the last one.
+func (l *requestLog) header() http.Header {
+	if l == nil {
+		return nil
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if len(l.failed) == 0 {
+		return nil
+	}
+	failed := l.failed
+	if l.dropped > 0 {
+		failed = append(failed, FailedRequest{Error: strconv.Itoa(l.dropped) + " more failed requests are dropped"})
+	}
+	return encodeHeader(FailedRequestsHeader, failed)
+}
+
+// FailedRequests returns failed requests passed along with the page rendered by Chrome fetcher.
+func FailedRequests(rc io.ReadCloser) []FailedRequest {
+	failed := []FailedRequest{}
+	if !decodeHeader(rc, FailedRequestsHeader, &failed) {
+		return nil
+	}
+	return failed
+}
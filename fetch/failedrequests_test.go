@@ -0,0 +1,47 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLog(t *testing.T) {
+	l := newRequestLog()
+	assert.Nil(t, l.header())
+	l.started(network.RequestID("1"), "http://example.com/", "Document")
+	l.responded(network.RequestID("1"), "http://example.com/", "Document", 200)
+	l.finished(network.RequestID("1"))
+	l.started(network.RequestID("2"), "http://example.com/logo.png", "Image")
+	l.responded(network.RequestID("2"), "http://example.com/logo.png", "Image", 404)
+	l.finished(network.RequestID("2"))
+	l.started(network.RequestID("3"), "http://cdn.example.com/app.js", "Script")
+	l.loadingFailed(network.RequestID("3"), "Script", "", "csp", false)
+	l.started(network.RequestID("4"), "http://example.com/api/items", "XHR")
+	l.loadingFailed(network.RequestID("4"), "XHR", "net::ERR_ABORTED", "", true)
+	l.loadingFailed(network.RequestID("5"), "Stylesheet", "net::ERR_NAME_NOT_RESOLVED", "", false)
+
+	failed := FailedRequests(withHeader(ioutil.NopCloser(strings.NewReader("")), l.header()))
+	assert.Equal(t, []FailedRequest{
+		{URL: "http://example.com/logo.png", Type: "Image", Status: 404},
+		{URL: "http://cdn.example.com/app.js", Type: "Script", Blocked: "csp"},
+		{Type: "Stylesheet", Error: "net::ERR_NAME_NOT_RESOLVED"},
+	}, failed)
+	assert.Equal(t, "HTTP 404 Image http://example.com/logo.png", failed[0].String())
+	assert.Equal(t, "blocked: csp", failed[1].Reason())
+	assert.Equal(t, "net::ERR_NAME_NOT_RESOLVED", failed[2].Reason())
+	assert.Empty(t, l.sent)
+
+	for i := 0; i < maxFailedRequests; i++ {
+		l.responded(network.RequestID("6"), "http://example.com/missing.png", "Image", 404)
+	}
+	failed = FailedRequests(withHeader(ioutil.NopCloser(strings.NewReader("")), l.header()))
+	assert.Len(t, failed, maxFailedRequests+1)
+	assert.Equal(t, "3 more failed requests are dropped", failed[maxFailedRequests].Error)
+
+	var none *requestLog
+	assert.Nil(t, none.header())
+}
@@ -7,7 +7,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -15,7 +14,6 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
@@ -25,9 +23,10 @@ import (
 	"github.com/mafredri/cdp/protocol/network"
 	"github.com/mafredri/cdp/protocol/page"
 	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/mafredri/cdp/protocol/target"
 	"github.com/mafredri/cdp/rpcc"
 	"github.com/slotix/dataflowkit/errs"
-	"github.com/spf13/viper"
+	"github.com/slotix/dataflowkit/utils"
 	"golang.org/x/net/publicsuffix"
 	"golang.org/x/sync/errgroup"
 )
@@ -41,20 +40,21 @@ const (
 	Base Type = "Base"
 	//Headless chrome is used to download content from JS driven web pages
 	Chrome = "Chrome"
+	//Mock fetcher serves canned responses. It is used for testing.
+	Mock = "Mock"
 )
 
 // Fetcher is the interface that must be satisfied by things that can fetch
 // remote URLs and return their contents.
 //
-// Note: Fetchers may or may not be safe to use concurrently.  Please read the
-// documentation for each fetcher for more details.
+// Fetchers are safe for concurrent use. They keep no state between requests:
+// cookies and localStorage of a request are kept in its session.
 type Fetcher interface {
 	//  Fetch is called to retrieve HTML content of a document from the remote server.
 	Fetch(request Request) (io.ReadCloser, error)
-	getCookieJar() http.CookieJar
-	setCookieJar(jar http.CookieJar)
-	getCookies(u *url.URL) ([]*http.Cookie, error)
-	setCookies(u *url.URL, cookies []*http.Cookie) error
+	// fetch retrieves the document with cookies and localStorage of sess.
+	// Cookies and localStorage items left by the site are saved back to sess.
+	fetch(request Request, sess *session) (io.ReadCloser, error)
 }
 
 //Request struct contains request information sent to  Fetchers
@@ -63,7 +63,8 @@ type Request struct {
 	Type string `json:"type"`
 	//	URL to be retrieved
 	URL string `json:"url"`
-	//	HTTP method : GET, POST
+	//	HTTP method : GET, HEAD, POST, PUT, PATCH, DELETE or OPTIONS. HEAD requests are sent by Base fetcher only.
+	//	Requests with FormData or Body are sent with POST method if it is omitted.
 	Method string
 	// FormData is a string value for passing formdata parameters.
 	//
@@ -74,74 +75,194 @@ type Request struct {
 	// "auth_key=880ea6a14ea49e853634fbdc5015a024&referer=http%3A%2F%2Fexample.com%2F&ips_username=user&ips_password=userpassword&rememberMe=1"
 	//
 	FormData string `json:"formData,omitempty"`
+	// Body is sent as is with Method, f.e. JSON document of API request. It can't be sent along with FormData.
+	// Its content type is taken from Content-Type header. JSON body is sent as application/json by default.
+	Body string `json:"body,omitempty"`
+	// Headers are added to the request of the page, f.e. Accept-Language or Referer.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Cookies are sent along with cookies stored for UserToken. They may be scoped to a domain and path.
+	Cookies Cookies `json:"cookies,omitempty"`
+	// FillForm is filled in and submitted by Chrome fetcher after the page is loaded.
+	// Unlike FormData it works with forms validated or submitted by JavaScript.
+	FillForm *FillForm `json:"fillForm,omitempty"`
 	//UserToken identifies user to keep personal cookies information.
 	UserToken string `json:"userToken"`
 	// Actions contains the list of action we have to perform on page
 	Actions string `json:"actions"`
+	// Steps are navigations performed by Chrome fetcher one after another in the session of the request,
+	// f.e. accepting cookies, searching and opening a result. If some steps are marked extract,
+	// their snapshots are returned instead of the last page.
+	Steps []Step `json:"steps,omitempty"`
+	// WaitUntil is a condition Chrome fetcher waits for after navigation. It may be "load" (default) or "networkidle".
+	// Pages loading their content asynchronously are complete once network is idle.
+	WaitUntil string `json:"waitUntil,omitempty"`
+	// Download is a CSS selector of the element starting a file download, f.e. "Export CSV" button.
+	// Chrome fetcher clicks it after the page is loaded and steps are performed. Downloaded file is saved
+	// to the storage and JSON encoded BinaryInfo is returned instead of the page.
+	Download string `json:"download,omitempty"`
+	// Frames instructs fetcher to capture content of frames. It may be "inline" or "documents".
+	// Content of every frame is returned as <frame-document data-frame-name="frame name" data-frame-url="frame URL">
+	// element inserted right after its iframe element or appended to the end of the page body respectively.
+	// Base fetcher fetches frames from the host of the page only.
+	Frames string `json:"frames,omitempty"`
+	// Dialogs defines how Chrome fetcher answers JavaScript dialogs. Service settings are used if it is nil.
+	Dialogs *Dialogs `json:"dialogs,omitempty"`
+	// Intercept lists URL patterns of XHR and fetch requests sent by the page. JSON responses of matching requests
+	// are captured by Chrome fetcher and inserted into the page as script elements of "application/json" type
+	// with data-intercepted attribute holding request URL. "*" in patterns matches any sequence of characters.
+	Intercept []string `json:"intercept,omitempty"`
+	// Screenshot instructs Chrome fetcher to capture a screenshot of rendered page.
+	// Screenshots are stored per run and may be compared with /screenshots/diff endpoint.
+	Screenshot *bool `json:"screenshot,omitempty"`
+	// Pause is the time in milliseconds Chrome fetcher waits after the page is loaded and steps are performed,
+	// f.e. for animations to finish. It is limited to 30 seconds.
+	Pause int `json:"pause,omitempty"`
+	// Block lists URL patterns of resources Chrome fetcher doesn't load, f.e. "*.woff2" or "*://ads.example.com/*".
+	// "*" in patterns matches any sequence of characters.
+	Block []string `json:"block,omitempty"`
+	// AutoConsent makes Chrome fetcher dismiss the banner of a known consent manager (OneTrust, Cookiebot, ...)
+	// once the page is loaded, since banners often cover or suppress the content of the page.
+	AutoConsent *bool `json:"autoConsent,omitempty"`
+	// PierceShadow makes Chrome fetcher inline open shadow roots of the page as ShadowRootTag elements,
+	// so selectors may reach content of web components. It changes the structure of the page, so it is off by default.
+	// Payloads with shadow piercing selectors turn it on.
+	PierceShadow *bool `json:"pierceShadow,omitempty"`
+	// SplashArgs are arguments of legacy Splash payloads. They are converted to the fields above by ConvertSplash.
+	SplashArgs
+	// RequestID is a correlation ID of the request which caused fetching.
+	// It is passed between services in X-Request-ID header and doesn't affect caching.
+	RequestID string `json:"-"`
+	// Job identifies fetches sharing a bandwidth cap. It is set by Parse service and passed in JobHeader.
+	Job string `json:"-"`
 }
 
 // BaseFetcher is a Fetcher that uses the Go standard library's http
-// client to fetch URLs.
+// client to fetch URLs. It is safe for concurrent use.
 type BaseFetcher struct {
 	client *http.Client
+	opts   *options
 }
 
-// ChromeFetcher is used to fetch Java Script rendeded pages.
+// ChromeFetcher is used to fetch Java Script rendeded pages. It is safe for concurrent use.
+// Every request is fetched in a new tab by a copy of the fetcher holding the state of the tab.
 type ChromeFetcher struct {
+	client *http.Client
+	opts   *options
+	//conn, cdpClient, cookies, localStorage and network are the state of the tab. They are set for the copy only.
+	conn      *rpcc.Conn
 	cdpClient *cdp.Client
-	client    *http.Client
 	cookies   []*http.Cookie
+	//localStorage items are restored before the page is loaded and read after it
+	localStorage map[string]string
+	//network counts in-flight requests of the page
+	network *networkMonitor
+	//browserContext is the browser context of the tab if it is isolated from other tabs
+	browserContext target.BrowserContextID
 }
 
-//newFetcher creates instances of Fetcher for downloading a web page.
-func newFetcher(t Type) Fetcher {
+//newFetcher creates instances of Fetcher for downloading a web page configured with the service settings.
+//An error is returned if the settings are invalid.
+func newFetcher(t Type, opts ...Option) (Fetcher, error) {
 	switch t {
 	case Base:
-		return newBaseFetcher()
+		//nil pointers are not returned as non-nil Fetcher
+		f, err := newBaseFetcher(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
 	case Chrome:
-		return newChromeFetcher()
+		f, err := newChromeFetcher(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case Mock:
+		return newMockFetcher(), nil
 	default:
 		logger.Panic(fmt.Sprintf("unhandled type: %#v", t))
 	}
 	panic("unreachable")
 }
 
-// newBaseFetcher creates instances of newBaseFetcher{} to fetch
-// a page content from regular websites as-is
-// without running js scripts on the page.
-func newBaseFetcher() *BaseFetcher {
-	var client *http.Client
-	proxy := viper.GetString("PROXY")
-	if len(proxy) > 0 {
-		proxyURL, err := url.Parse(proxy)
-		if err != nil {
-			logger.Error(err.Error())
-			return nil
-		}
-		transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		client = &http.Client{Transport: transport}
-	} else {
-		client = &http.Client{}
-	}
-	f := &BaseFetcher{
-		client: client,
-	}
-	jarOpts := &cookiejar.Options{PublicSuffixList: publicsuffix.List}
-	var err error
-	f.client.Jar, err = cookiejar.New(jarOpts)
+// newBaseFetcher creates BaseFetcher configured with the service settings and opts.
+func newBaseFetcher(opts ...Option) (*BaseFetcher, error) {
+	return NewBaseFetcher(append(settingsOptions(), opts...)...)
+}
+
+// NewBaseFetcher creates BaseFetcher to fetch a page content from regular websites as-is
+// without running js scripts on the page. Unlike fetchers used by Fetch service it is configured
+// with opts only, so it may be embedded into other programs.
+func NewBaseFetcher(opts ...Option) (*BaseFetcher, error) {
+	o, err := newOptions(opts)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	client := o.httpClient()
+	if o.fingerprint != nil {
+		client.Transport = o.fingerprintTransport(o.fingerprint)
 	}
-	return f
+	client.Timeout = o.timeout
+	return &BaseFetcher{client: client, opts: o}, nil
 }
 
 // Fetch retrieves document from the remote server. It returns web page content along with cache and expiration information.
+// Non-HTML resources like PDF, CSV or images are saved to the storage and JSON encoded BinaryInfo is returned instead.
+// Content declared as a document is sniffed, so binary resources mislabeled as text/html are saved as well.
+// Cookies set by the server are kept for redirects and frames of the request only.
 func (bf *BaseFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	return bf.fetch(request, &session{})
+}
+
+// fetch sends the request with a copy of the client having a cookie jar of its own filled with cookies of sess,
+// so concurrent requests never share or swap jars of the client.
+func (bf *BaseFetcher) fetch(request Request, sess *session) (io.ReadCloser, error) {
+	u, err := url.Parse(request.getURL())
+	if err != nil {
+		return nil, err
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	jar.SetCookies(u, sess.cookies)
+	client := *bf.client
+	client.Jar = jar
+	client.Transport = bf.opts.bandwidth.transport(client.Transport, request.Job)
+	content, err := (&BaseFetcher{client: &client, opts: bf.opts}).fetchPage(request)
+	sess.cookies = jar.Cookies(u)
+	return content, err
+}
+
+func (bf *BaseFetcher) fetchPage(request Request) (io.ReadCloser, error) {
 	resp, err := bf.response(request)
 	if err != nil {
 		return nil, err
 	}
-	return resp.Body, nil
+	if request.head() {
+		return headResponse(request.URL, resp), nil
+	}
+	if isBinaryContent(resp.Header.Get("Content-Type")) {
+		defer resp.Body.Close()
+		return bf.opts.storeBinary(request, resp, "")
+	}
+	if sniffed, ok := sniffBinary(resp); ok {
+		defer resp.Body.Close()
+		return bf.opts.storeBinary(request, resp, sniffed)
+	}
+	header := resp.Header
+	if resp.Request != nil {
+		header = joinHeaders(resp.Header, finalURLHeader(request.URL, resp.Request.URL.String()))
+	}
+	if request.Frames != "" {
+		defer resp.Body.Close()
+		page, err := bf.inlineFrames(request, resp.Body, request.Frames)
+		if err != nil {
+			return nil, err
+		}
+		return withHeader(ioutil.NopCloser(strings.NewReader(page)), header), nil
+	}
+	return withHeader(resp.Body, header), nil
 }
 
 //Response return response after document fetching using BaseFetcher
@@ -150,23 +271,25 @@ func (bf *BaseFetcher) response(r Request) (*http.Response, error) {
 	if _, err := url.ParseRequestURI(r.getURL()); err != nil {
 		return nil, err
 	}
-	var err error
-	var req *http.Request
-
-	if r.FormData == "" {
-		req, err = http.NewRequest(r.Method, r.URL, nil)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		//if form data exists send POST request
-		formData := parseFormData(r.FormData)
-		req, err = http.NewRequest("POST", r.URL, strings.NewReader(formData.Encode()))
-		if err != nil {
-			return nil, err
+	body, contentType := r.RequestBody()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(r.HTTPMethod(), r.URL, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, c := range r.Cookies {
+		if c.matches(req.URL) {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
 		}
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Add("Content-Length", strconv.Itoa(len(formData.Encode())))
 	}
 	//TODO: Add UA to requests
 	//req.Header.Add("User-Agent", "Dataflow kit - https://github.com/slotix/dataflowkit")
@@ -174,38 +297,56 @@ func (bf *BaseFetcher) response(r Request) (*http.Response, error) {
 }
 
 func (bf *BaseFetcher) doRequest(req *http.Request) (*http.Response, error) {
-	resp, err := bf.client.Do(req)
+	t := &tracer{}
+	resp, err := bf.client.Do(t.trace(req))
 	if err != nil {
+		//the last redirect response is returned along with the error if redirects are exhausted
+		if resp != nil {
+			return nil, redirectError(req, resp, err)
+		}
 		return nil, err
 	}
-	switch resp.StatusCode {
-	case 200:
-		return resp, nil
-
-	default:
-		return nil, errs.StatusError{
-			resp.StatusCode,
-			errors.New(http.StatusText(resp.StatusCode)),
+	//201 Created, 202 Accepted, 203 Non-Authoritative Information and 206 Partial Content carry the page as well
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		for name, values := range t.header() {
+			resp.Header[name] = values
 		}
+		return resp, nil
 	}
+	//3xx responses left after redirects are followed have no Location header or are 304 Not Modified
+	return nil, responseError(req, resp)
 }
 
-func (bf *BaseFetcher) getCookieJar() http.CookieJar { //*cookiejar.Jar {
-	return bf.client.Jar
-}
-
-func (bf *BaseFetcher) setCookieJar(jar http.CookieJar) {
-
-	bf.client.Jar = jar
-}
-
-func (bf *BaseFetcher) getCookies(u *url.URL) ([]*http.Cookie, error) {
-	return bf.client.Jar.Cookies(u), nil
+// redirectError returns FetchError describing redirect response resp which the client refused to follow.
+func redirectError(req *http.Request, resp *http.Response, err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	e := errs.FetchError{
+		URL:    req.URL.String(),
+		Code:   resp.StatusCode,
+		Reason: fmt.Sprintf("%s, %s", resp.Status, err.Error()),
+	}
+	if resp.Request != nil && resp.Request.URL.String() != e.URL {
+		e.FinalURL = resp.Request.URL.String()
+	}
+	return e
 }
 
-func (bf *BaseFetcher) setCookies(u *url.URL, cookies []*http.Cookie) error {
-	bf.client.Jar.SetCookies(u, cookies)
-	return nil
+// responseError returns FetchError describing failed response to req. Response body is closed.
+func responseError(req *http.Request, resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, errs.BodySnippetSize+1))
+	e := errs.FetchError{
+		URL:    req.URL.String(),
+		Code:   resp.StatusCode,
+		Reason: resp.Status,
+		Body:   errs.Snippet(body),
+	}
+	if resp.Request != nil && resp.Request.URL.String() != e.URL {
+		e.FinalURL = resp.Request.URL.String()
+	}
+	return e
 }
 
 // parseFormData is used for converting formdata string to url.Values type
@@ -223,25 +364,20 @@ func parseFormData(fd string) url.Values {
 // Static type assertion
 var _ Fetcher = &BaseFetcher{}
 
-// NewChromeFetcher returns ChromeFetcher
-func newChromeFetcher() *ChromeFetcher {
-	var client *http.Client
-	proxy := viper.GetString("PROXY")
-	if len(proxy) > 0 {
-		proxyURL, err := url.Parse(proxy)
-		if err != nil {
-			logger.Error(err.Error())
-			return nil
-		}
-		transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		client = &http.Client{Transport: transport}
-	} else {
-		client = &http.Client{}
-	}
-	f := &ChromeFetcher{
-		client: client,
+// newChromeFetcher creates ChromeFetcher configured with the service settings and opts.
+func newChromeFetcher(opts ...Option) (*ChromeFetcher, error) {
+	return NewChromeFetcher(append(settingsOptions(), opts...)...)
+}
+
+// NewChromeFetcher creates ChromeFetcher to fetch JavaScript rendered pages with Headless Chrome
+// running at the address set by WithChrome. Unlike fetchers used by Fetch service it is configured
+// with opts only, so it may be embedded into other programs.
+func NewChromeFetcher(opts ...Option) (*ChromeFetcher, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
 	}
-	return f
+	return &ChromeFetcher{client: o.httpClient(), opts: o}, nil
 }
 
 // LogCodec captures the output from writing RPC requests and reading
@@ -277,6 +413,19 @@ func (c *LogCodec) ReadResponse(resp *rpcc.Response) error {
 
 // Fetch retrieves document from the remote server. It returns web page content along with cache and expiration information.
 func (f *ChromeFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	return f.fetch(request, &session{})
+}
+
+// fetch loads the page in a new tab with cookies and localStorage of sess. The tab is driven by a copy
+// of the fetcher, so the fetcher itself is never modified.
+func (f *ChromeFetcher) fetch(request Request, sess *session) (io.ReadCloser, error) {
+	tab := &ChromeFetcher{client: f.client, opts: f.opts, cookies: sess.cookies, localStorage: sess.localStorage}
+	content, err := tab.fetchPage(request)
+	sess.cookies, sess.localStorage = tab.cookies, tab.localStorage
+	return content, err
+}
+
+func (f *ChromeFetcher) fetchPage(request Request) (io.ReadCloser, error) {
 	//URL validation
 	if _, err := url.ParseRequestURI(strings.TrimSpace(request.getURL())); err != nil {
 		return nil, err
@@ -284,15 +433,28 @@ func (f *ChromeFetcher) Fetch(request Request) (io.ReadCloser, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	devt := devtool.New(viper.GetString("CHROME"), devtool.WithClient(f.client))
+	devt := devtool.New(f.opts.chrome, devtool.WithClient(f.client))
 	//https://github.com/mafredri/cdp/issues/60
 	//pt, err := devt.Get(ctx, devtool.Page)
-	pt, err := devt.Create(ctx)
+	var (
+		pt  *devtool.Target
+		err error
+	)
+	if request.Download != "" {
+		var dispose func()
+		pt, dispose, err = f.isolatedTarget(ctx, devt)
+		if err != nil {
+			return nil, err
+		}
+		defer dispose()
+	} else {
+		pt, err = devt.Create(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
 	var conn *rpcc.Conn
-	if viper.GetBool("CHROME_TRACE") {
+	if f.opts.chromeTrace {
 		newLogCodec := func(conn io.ReadWriter) rpcc.Codec {
 			return &LogCodec{conn: conn}
 		}
@@ -308,6 +470,7 @@ func (f *ChromeFetcher) Fetch(request Request) (io.ReadCloser, error) {
 	defer conn.Close() // Cleanup.
 	defer devt.Close(ctx, pt)
 	// Create a new CDP Client that uses conn.
+	f.conn = conn
 	f.cdpClient = cdp.NewClient(conn)
 
 	if err = runBatch(
@@ -319,34 +482,111 @@ func (f *ChromeFetcher) Fetch(request Request) (io.ReadCloser, error) {
 	); err != nil {
 		return nil, err
 	}
+	if len(request.Block) > 0 {
+		if err := f.cdpClient.Network.SetBlockedURLs(ctx, network.NewSetBlockedURLsArgs(request.Block)); err != nil {
+			return nil, err
+		}
+	}
 
 	err = f.loadCookies()
 	if err != nil {
 		return nil, err
 	}
-	domLoadTimeout := 60 * time.Second
-	if request.FormData == "" {
-		err = f.navigate(ctx, f.cdpClient.Page, "GET", request.getURL(), "", domLoadTimeout)
-	} else {
-		formData := parseFormData(request.FormData)
-		err = f.navigate(ctx, f.cdpClient.Page, "POST", request.getURL(), formData.Encode(), domLoadTimeout)
+	if err := f.setRequestHeaders(ctx, request); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(request.getURL())
+	if err != nil {
+		return nil, err
+	}
+	if err := f.restoreLocalStorage(ctx, u.Host); err != nil {
+		return nil, err
+	}
+	if err := f.startNetworkMonitor(ctx); err != nil {
+		return nil, err
+	}
+	defer f.accountLoaded(request.Job)
+	if err := f.throttle(ctx); err != nil {
+		return nil, err
+	}
+	if err := f.handleDialogs(ctx, request.Dialogs); err != nil {
+		return nil, err
 	}
+	console, err := f.captureConsole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var capture *xhrCapture
+	if len(request.Intercept) > 0 {
+		if capture, err = f.startCapture(ctx, request.Intercept); err != nil {
+			return nil, err
+		}
+	}
+	domLoadTimeout := f.opts.timeout
+	if domLoadTimeout <= 0 {
+		domLoadTimeout = defaultChromeTimeout
+	}
+	body, contentType := request.RequestBody()
+	err = f.navigate(ctx, f.cdpClient.Page, request.HTTPMethod(), request.getURL(), body, contentType, domLoadTimeout)
 	if err != nil {
 		return nil, err
 	}
 
+	if strings.ToLower(request.WaitUntil) == WaitNetworkIdle {
+		f.waitNetworkIdle(ctx)
+	}
+
+	if utils.IsTrue(request.AutoConsent) {
+		if err := f.dismissConsent(ctx, request.getURL()); err != nil {
+			logger.Warn("Failed to dismiss consent banner. " + err.Error())
+		}
+	}
+
+	if request.FillForm != nil {
+		if err := f.fillForm(ctx, request.FillForm); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := f.runActions(ctx, request.Actions); err != nil {
 		logger.Warn(err.Error())
 	}
 
-	u, err := url.Parse(request.getURL())
+	snapshots, err := f.runSteps(ctx, request.Steps, utils.IsTrue(request.PierceShadow))
 	if err != nil {
 		return nil, err
 	}
+
+	if request.Pause > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(request.Pause) * time.Millisecond):
+		}
+	}
+
+	if utils.IsTrue(request.Screenshot) {
+		if err := f.captureScreenshot(ctx, request.getURL()); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+
+	if request.Frames != "" {
+		if err := f.captureFrames(ctx, request.Frames); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+
+	if err := f.readLocalStorage(ctx); err != nil {
+		logger.Warn(err.Error())
+	}
 	f.cookies, err = f.saveCookies(u)
 	if err != nil {
 		return nil, err
 	}
+	if request.Download != "" {
+		return f.download(ctx, request)
+	}
 
 	// Fetch the document root node. We can pass nil here
 	// since this method only takes optional arguments.
@@ -362,8 +602,26 @@ func (f *ChromeFetcher) Fetch(request Request) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	readCloser := ioutil.NopCloser(strings.NewReader(result.OuterHTML))
-	return readCloser, nil
+	page := result.OuterHTML
+	if utils.IsTrue(request.PierceShadow) {
+		if flat, err := f.flattenShadowRoots(ctx, false); err != nil {
+			logger.Warn("Failed to inline shadow roots. " + err.Error())
+		} else if flat != "" {
+			page = flat
+		}
+	}
+	if len(snapshots) > 0 {
+		page = stepsPage(snapshots)
+	}
+	if capture != nil {
+		page = embedResponses(page, f.responses(ctx, capture))
+	}
+	final := ""
+	if doc.Root.DocumentURL != nil {
+		final = *doc.Root.DocumentURL
+	}
+	readCloser := ioutil.NopCloser(strings.NewReader(page))
+	return withHeader(readCloser, joinHeaders(console.header(), f.failedRequests(), finalURLHeader(request.URL, final))), nil
 
 }
 
@@ -387,20 +645,12 @@ func (f *ChromeFetcher) runActions(ctx context.Context, actionsJSON string) erro
 	return nil
 }
 
-func (f *ChromeFetcher) setCookieJar(jar http.CookieJar) {
-	f.client.Jar = jar
-}
-
-func (f *ChromeFetcher) getCookieJar() http.CookieJar {
-	return f.client.Jar
-}
-
 // Static type assertion
 var _ Fetcher = &ChromeFetcher{}
 
 // navigate to the URL and wait for DOMContentEventFired. An error is
 // returned if timeout happens before DOMContentEventFired.
-func (f *ChromeFetcher) navigate(ctx context.Context, pageClient cdp.Page, method, url string, formData string, timeout time.Duration) error {
+func (f *ChromeFetcher) navigate(ctx context.Context, pageClient cdp.Page, method, url, body, contentType string, timeout time.Duration) error {
 	defer time.Sleep(750 * time.Millisecond)
 
 	ctxTimeout, cancelTimeout := context.WithTimeout(context.Background(), timeout)
@@ -449,7 +699,7 @@ func (f *ChromeFetcher) navigate(ctx context.Context, pageClient cdp.Page, metho
 		}
 
 		kill := make(chan bool)
-		go f.interceptRequest(ctxTimeout, url, formData, kill)
+		go f.interceptRequest(ctxTimeout, url, method, body, contentType, kill)
 		_, err = pageClient.Navigate(ctxTimeout, page.NewNavigateArgs(url))
 		if err != nil {
 			return err
@@ -474,7 +724,7 @@ func (f *ChromeFetcher) navigate(ctx context.Context, pageClient cdp.Page, metho
 			return err
 		}
 		if reply.Type == network.ResourceTypeDocument {
-			return errs.StatusError{400, errors.New(reply.ErrorText)}
+			return errs.FetchError{URL: url, Code: http.StatusBadRequest, Reason: reply.ErrorText}
 		}
 	case <-ctx.Done():
 		cancelTimeout()
@@ -485,11 +735,6 @@ func (f *ChromeFetcher) navigate(ctx context.Context, pageClient cdp.Page, metho
 	return nil
 }
 
-func (f *ChromeFetcher) setCookies(u *url.URL, cookies []*http.Cookie) error {
-	f.cookies = cookies
-	return nil
-}
-
 func (f *ChromeFetcher) loadCookies() error {
 	/* 	u, err := url.Parse(cookiesURL)
 	   	if err != nil {
@@ -517,8 +762,25 @@ func (f *ChromeFetcher) loadCookies() error {
 	return nil
 }
 
-func (f *ChromeFetcher) getCookies(u *url.URL) ([]*http.Cookie, error) {
-	return f.cookies, nil
+// setRequestHeaders sets headers and cookies of request to the browser before navigation.
+// Headers are sent with every request of the page.
+func (f *ChromeFetcher) setRequestHeaders(ctx context.Context, request Request) error {
+	if len(request.Headers) > 0 {
+		headers, err := json.Marshal(request.Headers)
+		if err != nil {
+			return err
+		}
+		if err := f.cdpClient.Network.SetExtraHTTPHeaders(ctx, network.NewSetExtraHTTPHeadersArgs(network.Headers(headers))); err != nil {
+			return err
+		}
+	}
+	u := request.getURL()
+	for _, c := range request.Cookies {
+		if _, err := f.cdpClient.Network.SetCookie(ctx, c.setCookieArgs(u)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (f *ChromeFetcher) saveCookies(u *url.URL) ([]*http.Cookie, error) {
@@ -550,7 +812,8 @@ func (f *ChromeFetcher) saveCookies(u *url.URL) ([]*http.Cookie, error) {
 	return cookies, nil
 }
 
-func (f *ChromeFetcher) interceptRequest(ctx context.Context, originURL string, formData string, kill chan bool) {
+// interceptRequest sends navigation request of originURL with method and body.
+func (f *ChromeFetcher) interceptRequest(ctx context.Context, originURL, method, body, contentType string, kill chan bool) {
 	var sig = false
 	cl, err := f.cdpClient.Network.RequestIntercepted(ctx)
 	if err != nil {
@@ -570,12 +833,14 @@ func (f *ChromeFetcher) interceptRequest(ctx context.Context, originURL string,
 				continue
 			}
 
-			if len(formData) > 0 && r.Request.URL == originURL && r.RedirectURL == nil {
+			if r.Request.URL == originURL && r.RedirectURL == nil {
 				interceptedArgs := network.NewContinueInterceptedRequestArgs(r.InterceptionID)
-				interceptedArgs.SetMethod("POST")
-				interceptedArgs.SetPostData(formData)
-				fData := fmt.Sprintf(`{"Content-Type":"application/x-www-form-urlencoded","Content-Length":%d}`, len(formData))
-				interceptedArgs.Headers = []byte(fData)
+				interceptedArgs.SetMethod(method)
+				if body != "" {
+					interceptedArgs.SetPostData(body)
+					fData := fmt.Sprintf(`{"Content-Type":%q,"Content-Length":%d}`, contentType, len(body))
+					interceptedArgs.Headers = []byte(fData)
+				}
 				if err = f.cdpClient.Network.ContinueInterceptedRequest(ctx, interceptedArgs); err != nil {
 					logger.Error(err.Error())
 					sig = true
@@ -583,7 +848,7 @@ func (f *ChromeFetcher) interceptRequest(ctx context.Context, originURL string,
 				}
 			} else {
 				interceptedArgs := network.NewContinueInterceptedRequestArgs(r.InterceptionID)
-				if r.ResourceType == network.ResourceTypeImage || r.ResourceType == network.ResourceTypeStylesheet || isExclude(r.Request.URL) {
+				if r.ResourceType == network.ResourceTypeImage || r.ResourceType == network.ResourceTypeStylesheet || f.opts.isExcluded(r.Request.URL) {
 					interceptedArgs.SetErrorReason(network.ErrorReasonAborted)
 				}
 				if err = f.cdpClient.Network.ContinueInterceptedRequest(ctx, interceptedArgs); err != nil {
@@ -600,16 +865,6 @@ func (f *ChromeFetcher) interceptRequest(ctx context.Context, originURL string,
 	}
 }
 
-func isExclude(origin string) bool {
-	excludeRes := viper.GetStringSlice("EXCLUDERES")
-	for _, res := range excludeRes {
-		if strings.Index(origin, res) != -1 {
-			return true
-		}
-	}
-	return false
-}
-
 func (f ChromeFetcher) RunJSFromFile(ctx context.Context, path string, entryPointFunction string) error {
 	exp, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -1,10 +1,17 @@
 package fetch
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/spf13/viper"
 
 	"github.com/stretchr/testify/assert"
@@ -14,15 +21,144 @@ func TestBaseFetcher_Proxy(t *testing.T) {
 	viper.Set("PROXY", "http://127.0.0.1:3128")
 	viper.Set("CHROME_TRACE", true)
 	//viper.Set("PROXY", "")
-	fetcher := newFetcher(Base)
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
 	assert.NotNil(t, fetcher)
-	fetcher = newFetcher(Chrome)
+	fetcher, err = newFetcher(Chrome)
+	assert.NoError(t, err)
 	assert.NotNil(t, fetcher)
 }
 
+func TestNewFetcher_invalidSettings(t *testing.T) {
+	viper.Set("TLS_FINGERPRINT", "unknown")
+	defer viper.Set("TLS_FINGERPRINT", "")
+	for _, fType := range []Type{Base, Chrome} {
+		fetcher, err := newFetcher(fType)
+		assert.Error(t, err)
+		assert.Nil(t, fetcher, "no typed nil wrapped into the interface")
+	}
+	_, err := FetchService{}.Fetch(Request{URL: "http://example.com"})
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusInternalServerError, err.(errs.StatusError).Status())
+	}
+}
+
+func TestNewBaseFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		if r.URL.Path == "/doc.pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+		}
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	//fetchers don't depend on service settings
+	viper.Set("PROXY", "http://127.0.0.1:3128")
+	defer viper.Set("PROXY", "")
+	fetcher, err := NewBaseFetcher(WithTimeout(100 * time.Millisecond))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/slow"})
+	assert.Error(t, err)
+	//binary resources require storage
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/doc.pdf"})
+	assert.Error(t, err)
+
+	_, err = NewBaseFetcher(WithProxy("://proxy"))
+	assert.Error(t, err)
+	_, err = NewChromeFetcher(WithChrome(""))
+	assert.Error(t, err)
+	chrome, err := NewChromeFetcher(WithChrome("http://chrome:9222"), WithScriptsDir("scripts"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://chrome:9222", chrome.opts.chrome)
+	assert.Equal(t, "scripts/form.js", chrome.opts.scriptPath("form.js"))
+}
+
+func TestBaseFetcher_SharedTransport(t *testing.T) {
+	f1, err := NewBaseFetcher(WithProxy("http://127.0.0.1:3128"))
+	assert.NoError(t, err)
+	f2, err := NewBaseFetcher(WithProxy("http://127.0.0.1:3128"), WithTimeout(time.Second))
+	assert.NoError(t, err)
+	f3, err := NewBaseFetcher(WithProxy("http://127.0.0.1:3129"))
+	assert.NoError(t, err)
+	assert.True(t, f1.client.Transport == f2.client.Transport, "fetchers of the same proxy pool connections")
+	assert.False(t, f1.client.Transport == f3.client.Transport)
+	assert.Equal(t, time.Duration(0), f1.client.Timeout, "Base fetcher waits forever by default")
+}
+
+func TestBaseFetcher_ResponseError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html>Sign in" + strings.Repeat(" ", errs.BodySnippetSize) + "</html>"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcher()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/old"})
+	fetchErr, ok := err.(errs.FetchError)
+	assert.True(t, ok)
+	assert.Equal(t, ts.URL+"/old", fetchErr.URL)
+	assert.Equal(t, ts.URL+"/login", fetchErr.FinalURL)
+	assert.Equal(t, http.StatusForbidden, fetchErr.Status())
+	assert.Equal(t, "403 Forbidden", fetchErr.Reason)
+	assert.True(t, strings.HasPrefix(fetchErr.Body, "<html>Sign in"))
+	assert.True(t, strings.HasSuffix(fetchErr.Body, "..."))
+	assert.Len(t, fetchErr.Body, errs.BodySnippetSize+3)
+}
+
+func TestBaseFetcher_StatusCodes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/loop" {
+			http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+			return
+		}
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+		w.Write([]byte("page"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcher()
+	assert.NoError(t, err)
+	for _, code := range []int{200, 201, 202, 203, 206} {
+		content, err := fetcher.Fetch(Request{URL: fmt.Sprintf("%s/%d", ts.URL, code)})
+		assert.NoError(t, err, code)
+		data, _ := ioutil.ReadAll(content)
+		assert.Equal(t, "page", string(data), code)
+	}
+	for _, code := range []int{300, 304, 404, 408, 410, 451, 500, 503, 599} {
+		_, err := fetcher.Fetch(Request{URL: fmt.Sprintf("%s/%d", ts.URL, code)})
+		fetchErr, ok := err.(errs.FetchError)
+		assert.True(t, ok, code)
+		assert.Equal(t, code, fetchErr.Status())
+		assert.Equal(t, code == 408 || code >= 500, fetchErr.Temporary(), code)
+		assert.Equal(t, code == 410 || code == 451, fetchErr.Gone(), code)
+	}
+
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/loop"})
+	fetchErr, ok := err.(errs.FetchError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusMovedPermanently, fetchErr.Status())
+	assert.Contains(t, fetchErr.Reason, "stopped after 10 redirects")
+}
+
 func TestBaseFetcher_Fetch(t *testing.T) {
 	viper.Set("PROXY", "")
-	fetcher := newFetcher(Base)
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
 	req := Request{
 		URL:    tsURL + "/hello",
 		Method: "GET",
@@ -77,9 +213,41 @@ func TestBaseFetcher_Fetch(t *testing.T) {
 
 }
 
+func TestBaseFetcher_HeadersCookies(t *testing.T) {
+	viper.Set("PROXY", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("region")
+		if err != nil {
+			http.Error(w, "no cookie", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Accept-Language") + " " + c.Value))
+	}))
+	defer ts.Close()
+	req := Request{
+		URL:     ts.URL,
+		Headers: map[string]string{"Accept-Language": "de"},
+		Cookies: Cookies{{Name: "region", Value: "eu"}},
+	}
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(req)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "de eu", string(data))
+
+	plain := Request{URL: ts.URL}
+	assert.NotEqual(t, fixtureName(plain), fixtureName(req))
+	withCookies := plain
+	withCookies.Cookies = req.Cookies
+	assert.NotEqual(t, fixtureName(plain), fixtureName(withCookies))
+}
+
 func TestChromeFetcher_Fetch(t *testing.T) {
 	viper.Set("PROXY", "")
-	fetcher := newFetcher(Chrome)
+	fetcher, err := newFetcher(Chrome)
+	assert.NoError(t, err)
 	req := Request{
 		Type: "chrome",
 		URL:  "http://testserver:12345",
@@ -145,6 +313,5 @@ func TestInvalidFetcher(t *testing.T) {
 			t.Errorf("The code did not panic")
 		}
 	}()
-	fetcher := newFetcher(fType)
-	assert.NotNil(t, fetcher)
+	newFetcher(fType)
 }
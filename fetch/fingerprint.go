@@ -0,0 +1,333 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLS fingerprints of Base fetcher requests.
+const (
+	FingerprintChrome     = "chrome"
+	FingerprintFirefox    = "firefox"
+	FingerprintSafari     = "safari"
+	FingerprintIOS        = "ios"
+	FingerprintEdge       = "edge"
+	FingerprintRandomized = "randomized"
+)
+
+// fingerprint is ClientHello of a browser along with its default request headers in the order the browser sends them.
+type fingerprint struct {
+	hello utls.ClientHelloID
+	//headers are set unless the request sets them
+	headers map[string]string
+	//order of request headers. Other headers follow them
+	order []string
+}
+
+var (
+	chromeHeaders = map[string]string{
+		"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36",
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+		"Accept-Language":           "en-US,en;q=0.9",
+		"Upgrade-Insecure-Requests": "1",
+		"Sec-Fetch-Site":            "none",
+		"Sec-Fetch-Mode":            "navigate",
+		"Sec-Fetch-User":            "?1",
+		"Sec-Fetch-Dest":            "document",
+	}
+	chromeOrder = []string{"Host", "Connection", "Content-Length", "Cache-Control", "Upgrade-Insecure-Requests", "User-Agent",
+		"Content-Type", "Accept", "Sec-Fetch-Site", "Sec-Fetch-Mode", "Sec-Fetch-User", "Sec-Fetch-Dest", "Referer",
+		"Accept-Encoding", "Accept-Language", "Cookie"}
+	firefoxHeaders = map[string]string{
+		"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0",
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+		"Accept-Language":           "en-US,en;q=0.5",
+		"Upgrade-Insecure-Requests": "1",
+	}
+	firefoxOrder = []string{"Host", "User-Agent", "Accept", "Accept-Language", "Accept-Encoding", "Content-Type", "Content-Length",
+		"Referer", "Connection", "Cookie", "Upgrade-Insecure-Requests"}
+	safariHeaders = map[string]string{
+		"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Safari/605.1.15",
+		"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"Accept-Language": "en-US,en;q=0.9",
+	}
+	safariOrder = []string{"Host", "Content-Type", "Cookie", "Accept", "User-Agent", "Accept-Language", "Referer",
+		"Content-Length", "Accept-Encoding", "Connection"}
+)
+
+var fingerprints = map[string]fingerprint{
+	FingerprintChrome:  {hello: utls.HelloChrome_Auto, headers: chromeHeaders, order: chromeOrder},
+	FingerprintEdge:    {hello: utls.HelloEdge_Auto, headers: chromeHeaders, order: chromeOrder},
+	FingerprintFirefox: {hello: utls.HelloFirefox_Auto, headers: firefoxHeaders, order: firefoxOrder},
+	FingerprintSafari:  {hello: utls.HelloSafari_Auto, headers: safariHeaders, order: safariOrder},
+	FingerprintIOS:     {hello: utls.HelloIOS_Auto, headers: safariHeaders, order: safariOrder},
+	//randomized ClientHello doesn't offer HTTP/2, so ALPN is not changed
+	FingerprintRandomized: {hello: utls.HelloRandomizedNoALPN},
+}
+
+// WithTLSFingerprint makes Base fetcher send TLS ClientHello of browser name, one of FingerprintChrome,
+// FingerprintFirefox, FingerprintSafari, FingerprintIOS, FingerprintEdge or FingerprintRandomized, instead of
+// Go TLS stack's one which is blocked by JA3 based bot detection. Requests get default headers of the browser
+// unless they set them, and headers are sent in the browser's order. Requests are sent over HTTP/1.1.
+// Go TLS stack is used if name is empty.
+func WithTLSFingerprint(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			o.fingerprint = nil
+			return nil
+		}
+		fp, ok := fingerprints[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown TLS fingerprint %s", name)
+		}
+		o.fingerprint = &fp
+		return nil
+	}
+}
+
+// fingerprintTransports are shared by fetchers with the same TLS fingerprint and proxy, so their connections are pooled.
+var (
+	fingerprintTransportsMx sync.Mutex
+	fingerprintTransports   = map[string]*fingerprintRoundTripper{}
+)
+
+// fingerprintTransport returns transport sending requests with TLS fingerprint fp. HTTPS requests are tunneled
+// through proxy with CONNECT, so the fingerprint reaches the target.
+func (o *options) fingerprintTransport(fp *fingerprint) http.RoundTripper {
+	key := fp.hello.Str()
+	if o.proxy != nil {
+		key += "|" + o.proxy.String()
+	}
+	fingerprintTransportsMx.Lock()
+	defer fingerprintTransportsMx.Unlock()
+	rt, ok := fingerprintTransports[key]
+	if !ok {
+		rt = newFingerprintRoundTripper(fp, o.proxy)
+		fingerprintTransports[key] = rt
+	}
+	return rt
+}
+
+// newFingerprintRoundTripper returns transport sending requests with TLS fingerprint fp through proxy.
+func newFingerprintRoundTripper(fp *fingerprint, proxy *url.URL) *fingerprintRoundTripper {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t := &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			if req.URL.Scheme == "https" || proxy == nil {
+				return nil, nil
+			}
+			return proxy, nil
+		},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return fp.orderHeaders(conn), nil
+		},
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialTunnel(ctx, dialer, proxy, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn, err := fp.handshake(ctx, conn, addr)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return fp.orderHeaders(tlsConn), nil
+		},
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &fingerprintRoundTripper{transport: t, fp: fp}
+}
+
+// handshake performs TLS handshake over conn to host addr with ClientHello of fp.
+// Only HTTP/1.1 is offered in ALPN as HTTP/2 connections can't be used by http.Transport with custom TLS.
+func (fp *fingerprint) handshake(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	config := &utls.Config{ServerName: host}
+	if fp.hello == utls.HelloRandomizedNoALPN {
+		uconn := utls.UClient(conn, config, fp.hello)
+		return uconn, uconn.HandshakeContext(ctx)
+	}
+	spec, err := utls.UTLSIdToSpec(fp.hello)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = []string{"http/1.1"}
+		}
+	}
+	uconn := utls.UClient(conn, config, utls.HelloCustom)
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		return nil, err
+	}
+	return uconn, uconn.HandshakeContext(ctx)
+}
+
+// dialTunnel connects to addr directly or through CONNECT tunnel of HTTP proxy.
+func dialTunnel(ctx context.Context, dialer *net.Dialer, proxy *url.URL, network, addr string) (net.Conn, error) {
+	if proxy == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+	conn, err := dialer.DialContext(ctx, network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	connect := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		connect += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	if _, err := conn.Write([]byte(connect + "\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %s: %s", addr, resp.Status)
+	}
+	return conn, nil
+}
+
+// fingerprintRoundTripper adds default headers of the browser to requests.
+type fingerprintRoundTripper struct {
+	transport *http.Transport
+	fp        *fingerprint
+}
+
+func (rt *fingerprintRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.fp.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return rt.transport.RoundTrip(req)
+}
+
+// orderHeaders returns conn reordering headers of requests written to it as fp orders them.
+func (fp *fingerprint) orderHeaders(conn net.Conn) net.Conn {
+	if len(fp.order) == 0 {
+		return conn
+	}
+	return &orderedConn{Conn: conn, order: fp.order}
+}
+
+// orderedConn reorders header lines of requests written by http.Transport, which sorts headers alphabetically.
+// Request bodies are passed as is. Once a body of unknown length is written, the rest of the connection is passed as is.
+type orderedConn struct {
+	net.Conn
+	order []string
+	//head buffers the request head until the empty line
+	head bytes.Buffer
+	//body is the number of body bytes to pass before the next request head
+	body int64
+	//passthrough is set after a chunked body
+	passthrough bool
+}
+
+func (c *orderedConn) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if c.passthrough {
+			_, err := c.Conn.Write(p)
+			return n, err
+		}
+		if c.body > 0 {
+			chunk := p
+			if int64(len(chunk)) > c.body {
+				chunk = chunk[:c.body]
+			}
+			if _, err := c.Conn.Write(chunk); err != nil {
+				return 0, err
+			}
+			c.body -= int64(len(chunk))
+			p = p[len(chunk):]
+			continue
+		}
+		c.head.Write(p)
+		p = nil
+		end := bytes.Index(c.head.Bytes(), []byte("\r\n\r\n"))
+		if end < 0 {
+			break
+		}
+		rest := append([]byte{}, c.head.Bytes()[end+4:]...)
+		head := c.reorder(c.head.Bytes()[:end])
+		c.head.Reset()
+		if _, err := c.Conn.Write(head); err != nil {
+			return 0, err
+		}
+		p = rest
+	}
+	return n, nil
+}
+
+// reorder returns request head with header lines sorted by c.order and sets the length of the body to pass.
+func (c *orderedConn) reorder(head []byte) []byte {
+	lines := strings.Split(string(head), "\r\n")
+	rank := func(line string) int {
+		name := line
+		if i := strings.Index(line, ":"); i >= 0 {
+			name = line[:i]
+		}
+		for i, n := range c.order {
+			if strings.EqualFold(n, name) {
+				return i
+			}
+		}
+		return len(c.order)
+	}
+	headers := lines[1:]
+	sorted := make([]string, 0, len(headers))
+	for r := 0; r <= len(c.order); r++ {
+		for _, h := range headers {
+			if rank(h) == r {
+				sorted = append(sorted, h)
+			}
+		}
+	}
+	for _, h := range sorted {
+		name, value := h, ""
+		if i := strings.Index(h, ":"); i >= 0 {
+			name, value = h[:i], strings.TrimSpace(h[i+1:])
+		}
+		switch {
+		case strings.EqualFold(name, "Content-Length"):
+			c.body, _ = strconv.ParseInt(value, 10, 64)
+		case strings.EqualFold(name, "Transfer-Encoding"):
+			c.passthrough = true
+		}
+	}
+	return []byte(lines[0] + "\r\n" + strings.Join(sorted, "\r\n") + "\r\n\r\n")
+}
@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedConn saves data written to connection.
+type recordedConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordedConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func TestWithTLSFingerprint(t *testing.T) {
+	o, err := newOptions([]Option{WithTLSFingerprint("Firefox")})
+	assert.NoError(t, err)
+	assert.Equal(t, firefoxOrder, o.fingerprint.order)
+
+	o, err = newOptions([]Option{WithTLSFingerprint("")})
+	assert.NoError(t, err)
+	assert.Nil(t, o.fingerprint)
+
+	_, err = newOptions([]Option{WithTLSFingerprint("netscape")})
+	assert.Error(t, err)
+
+	f1, err := NewBaseFetcher(WithTLSFingerprint(FingerprintChrome), WithProxy("http://127.0.0.1:3128"))
+	assert.NoError(t, err)
+	f2, err := NewBaseFetcher(WithTLSFingerprint(FingerprintChrome), WithProxy("http://127.0.0.1:3128"))
+	assert.NoError(t, err)
+	f3, err := NewBaseFetcher(WithTLSFingerprint(FingerprintFirefox), WithProxy("http://127.0.0.1:3128"))
+	assert.NoError(t, err)
+	assert.True(t, f1.client.Transport == f2.client.Transport, "fetchers of the same fingerprint pool connections")
+	assert.False(t, f1.client.Transport == f3.client.Transport)
+}
+
+func TestOrderedConn(t *testing.T) {
+	fp := fingerprints[FingerprintChrome]
+	conn := &recordedConn{}
+	c := fp.orderHeaders(conn)
+
+	//head and body are split across writes like bufio.Writer of http.Transport does
+	c.Write([]byte("POST /form HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\nContent-Length: 7\r\n"))
+	c.Write([]byte("X-Custom: 1\r\nUser-Agent: test\r\n\r\na=1&b=2"))
+	c.Write([]byte("GET / HTTP/1.1\r\nUser-Agent: test\r\nHost: example.com\r\n\r\n"))
+	assert.Equal(t, "POST /form HTTP/1.1\r\nHost: example.com\r\nContent-Length: 7\r\nUser-Agent: test\r\nAccept: */*\r\nX-Custom: 1\r\n\r\na=1&b=2"+
+		"GET / HTTP/1.1\r\nHost: example.com\r\nUser-Agent: test\r\n\r\n", conn.written.String())
+
+	//chunked bodies are passed as is along with the rest of connection
+	conn.written.Reset()
+	c.Write([]byte("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nHost: example.com\r\n\r\n3\r\nabc\r\n0\r\n\r\n"))
+	assert.Equal(t, "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n", conn.written.String())
+
+	//randomized fingerprint doesn't reorder headers
+	randomized := fingerprints[FingerprintRandomized]
+	assert.Equal(t, net.Conn(conn), randomized.orderHeaders(conn))
+}
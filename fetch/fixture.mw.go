@@ -0,0 +1,148 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// Fixture modes
+const (
+	// FixtureRecord saves all fetched responses to the fixture directory.
+	FixtureRecord = "record"
+	// FixtureReplay serves responses from the fixture directory without accessing the network.
+	FixtureReplay = "replay"
+)
+
+// fixtureName returns the name of the fixture file for specified request.
+// Requests fetched with different fetcher types, methods, form data, actions, filled forms,
+// intercepted requests, wait conditions, navigation steps, dialog answers, frames, downloads, pauses, blocked resources,
+// headers, cookies or bodies are stored separately.
+// Options added later are appended to the key only if they are set, so names of recorded fixtures don't change.
+func fixtureName(req Request) string {
+	fType := strings.ToLower(req.Type)
+	if fType == "" {
+		fType = "base"
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	parts := []string{fType, method, req.getURL(), req.FormData, req.Actions}
+	if req.FillForm != nil {
+		parts = append(parts, "fillForm "+req.FillForm.key())
+	}
+	if len(req.Intercept) > 0 {
+		parts = append(parts, "intercept "+strings.Join(req.Intercept, " "))
+	}
+	if req.WaitUntil != "" && req.WaitUntil != WaitLoad {
+		parts = append(parts, "waitUntil "+req.WaitUntil)
+	}
+	if len(req.Steps) > 0 {
+		parts = append(parts, "steps "+stepsKey(req.Steps))
+	}
+	if req.Dialogs != nil {
+		parts = append(parts, "dialogs "+req.Dialogs.key())
+	}
+	if req.Frames != "" {
+		parts = append(parts, "frames "+strings.ToLower(req.Frames))
+	}
+	if req.Download != "" {
+		parts = append(parts, "download "+req.Download)
+	}
+	if req.Pause > 0 {
+		parts = append(parts, "pause "+strconv.Itoa(req.Pause))
+	}
+	if len(req.Block) > 0 {
+		parts = append(parts, "block "+strings.Join(req.Block, " "))
+	}
+	if utils.IsTrue(req.AutoConsent) {
+		parts = append(parts, "autoConsent")
+	}
+	//Referer doesn't change the page, so fixtures are replayed to links followed from any page
+	headers := map[string]string{}
+	for name, value := range req.Headers {
+		if !strings.EqualFold(name, "Referer") {
+			headers[name] = value
+		}
+	}
+	if len(headers) > 0 {
+		parts = append(parts, "headers "+valuesKey(headers))
+	}
+	for _, c := range req.Cookies {
+		parts = append(parts, fmt.Sprintf("cookie %s=%s; domain=%s; path=%s", c.Name, c.Value, c.Domain, c.Path))
+	}
+	if req.Body != "" {
+		parts = append(parts, "body "+req.Body)
+	}
+	key := strings.Join(parts, "\n")
+	return hex.EncodeToString(utils.GenerateMD5([]byte(key))) + ".html"
+}
+
+// valuesKey joins name=value pairs sorted by name.
+func valuesKey(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for name, value := range values {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// FixtureMiddleware records fetched responses to dir or replays them from dir depending on mode.
+// Fixtures let payloads and extractors be developed and regression-tested deterministically offline.
+func FixtureMiddleware(mode, dir string) ServiceMiddleware {
+	return func(next Service) Service {
+		return fixtureMiddleware{next, strings.ToLower(mode), dir}
+	}
+}
+
+type fixtureMiddleware struct {
+	Service
+	mode string
+	dir  string
+}
+
+func (mw fixtureMiddleware) Fetch(req Request) (io.ReadCloser, error) {
+	path := filepath.Join(mw.dir, fixtureName(req))
+	switch mw.mode {
+	case FixtureReplay:
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errs.StatusError{
+				Code: http.StatusNotFound,
+				Err:  fmt.Errorf("no fixture recorded for %s", req.getURL()),
+			}
+		}
+		return withHeader(ioutil.NopCloser(bytes.NewReader(content)), http.Header{CacheHeader: {"HIT"}}), nil
+	case FixtureRecord:
+		res, err := mw.Service.Fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Close()
+		content, err := ioutil.ReadAll(res)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(mw.dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return nil, err
+		}
+		return withHeader(ioutil.NopCloser(bytes.NewReader(content)), ResponseHeader(res)), nil
+	}
+	return mw.Service.Fetch(req)
+}
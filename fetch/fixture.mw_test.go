@@ -0,0 +1,54 @@
+package fetch
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticService struct {
+	content string
+	calls   *int
+}
+
+func (s staticService) Fetch(req Request) (io.ReadCloser, error) {
+	*s.calls++
+	return ioutil.NopCloser(strings.NewReader(s.content)), nil
+}
+
+func TestFixtureMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	next := staticService{content: "<html>recorded</html>", calls: &calls}
+	req := Request{URL: "http://example.com/page"}
+
+	//record
+	content, err := FixtureMiddleware(FixtureRecord, dir)(next).Fetch(req)
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	assert.Equal(t, "<html>recorded</html>", string(data))
+	assert.Equal(t, 1, calls)
+
+	//replay
+	replay := FixtureMiddleware(FixtureReplay, dir)(next)
+	content, err = replay.Fetch(Request{Type: "base", Method: "get", URL: "http://example.com/page"})
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(content)
+	assert.Equal(t, "<html>recorded</html>", string(data))
+	assert.Equal(t, 1, calls, "network is not accessed in replay mode")
+	assert.Equal(t, "HIT", ResponseHeader(content).Get(CacheHeader))
+
+	//Referer doesn't change fixture
+	_, err = replay.Fetch(Request{URL: "http://example.com/page", Headers: map[string]string{"Referer": "http://example.com/"}})
+	assert.NoError(t, err)
+
+	_, err = replay.Fetch(Request{Type: "chrome", URL: "http://example.com/page"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/input"
+	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// formSubmitTimeout is the maximum time to wait for a page loaded after form submission or click.
+// Forms submitted with XHR don't load a new page so the current one is returned after timeout.
+const formSubmitTimeout = 10 * time.Second
+
+// FormField is a form control filled in by Chrome fetcher.
+type FormField struct {
+	// Selector is a CSS selector of input, textarea or select element.
+	Selector string `json:"selector"`
+	// Value is typed into text fields, selects an option of select elements by value or text
+	// and checks checkboxes and radio buttons unless it is "false", "off" or empty.
+	Value string `json:"value"`
+}
+
+// FillForm describes a form Chrome fetcher fills in and submits the way a user does it.
+// Text is typed into fields so key, input and change events are fired and
+// JavaScript validation works as in a browser.
+type FillForm struct {
+	// Fields are filled in the order they are listed.
+	Fields []FormField `json:"fields"`
+	// Submit is a CSS selector of the element clicked to submit the form.
+	// If it is empty, Enter key is pressed in the last field.
+	Submit string `json:"submit,omitempty"`
+}
+
+// validate checks that all fields have selectors.
+func (form *FillForm) validate() error {
+	if len(form.Fields) == 0 {
+		return errs.BadPayload{ErrText: "fillForm requires fields"}
+	}
+	for _, field := range form.Fields {
+		if field.Selector == "" {
+			return errs.BadPayload{ErrText: "fillForm field requires selector"}
+		}
+	}
+	return nil
+}
+
+// key returns a string identifying the form in fixture names.
+func (form *FillForm) key() string {
+	if form == nil {
+		return ""
+	}
+	data, _ := json.Marshal(form)
+	return string(data)
+}
+
+// fillForm fills in and submits the form on the page loaded into Chrome.
+// It waits for the page loaded after submission up to formSubmitTimeout.
+func (f *ChromeFetcher) fillForm(ctx context.Context, form *FillForm) error {
+	script, err := f.opts.script("form.js")
+	if err != nil {
+		return err
+	}
+	doc, err := f.cdpClient.DOM.GetDocument(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, field := range form.Fields {
+		reply, err := f.cdpClient.DOM.QuerySelector(ctx, dom.NewQuerySelectorArgs(doc.Root.NodeID, field.Selector))
+		if err != nil {
+			return err
+		}
+		if reply.NodeID == 0 {
+			return fmt.Errorf("form field %q not found", field.Selector)
+		}
+		//setField selects options and checks boxes. Text fields are cleared and typed into.
+		var typed bool
+		if err := f.evaluate(ctx, script, "setField", &typed, field.Selector, field.Value); err != nil {
+			return err
+		}
+		if !typed {
+			continue
+		}
+		if err := f.cdpClient.DOM.Focus(ctx, &dom.FocusArgs{NodeID: &reply.NodeID}); err != nil {
+			return err
+		}
+		if err := f.cdpClient.Input.InsertText(ctx, input.NewInsertTextArgs(field.Value)); err != nil {
+			return err
+		}
+		if err := f.evaluate(ctx, script, "commitField", nil, field.Selector); err != nil {
+			return err
+		}
+	}
+
+	return f.waitLoad(ctx, func() error {
+		if form.Submit != "" {
+			return f.evaluate(ctx, script, "submitForm", nil, form.Submit)
+		}
+		last := form.Fields[len(form.Fields)-1].Selector
+		if err := f.evaluate(ctx, script, "focusField", nil, last); err != nil {
+			return err
+		}
+		for _, typ := range []string{"keyDown", "keyUp"} {
+			args := input.NewDispatchKeyEventArgs(typ).
+				SetKey("Enter").
+				SetCode("Enter").
+				SetWindowsVirtualKeyCode(13)
+			if typ == "keyDown" {
+				args.SetText("\r")
+			}
+			if err := f.cdpClient.Input.DispatchKeyEvent(ctx, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// waitLoad runs trigger and waits for the page loaded after it up to formSubmitTimeout.
+func (f *ChromeFetcher) waitLoad(ctx context.Context, trigger func() error) error {
+	loadCtx, cancel := context.WithTimeout(ctx, formSubmitTimeout)
+	defer cancel()
+	loadEventFired, err := f.cdpClient.Page.LoadEventFired(loadCtx)
+	if err != nil {
+		return err
+	}
+	defer loadEventFired.Close()
+
+	if err := trigger(); err != nil {
+		return err
+	}
+	if _, err := loadEventFired.Recv(); err != nil {
+		logger.Info("No page loaded")
+	}
+	time.Sleep(750 * time.Millisecond)
+	return nil
+}
+
+// evaluate calls function fn defined in script with JSON encoded args and decodes its result into v.
+// Exceptions thrown by the function are returned as errors.
+func (f *ChromeFetcher) evaluate(ctx context.Context, script []byte, fn string, v interface{}, args ...interface{}) error {
+	params, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	expr := fmt.Sprintf("%s\n%s(...%s);", script, fn, params)
+	reply, err := f.cdpClient.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(expr).
+		SetAwaitPromise(true).
+		SetReturnByValue(true))
+	if err != nil {
+		return err
+	}
+	if reply.ExceptionDetails != nil {
+		msg := reply.ExceptionDetails.Text
+		if e := reply.ExceptionDetails.Exception; e != nil && e.Description != nil {
+			msg = *e.Description
+		}
+		return fmt.Errorf("%s: %s", fn, msg)
+	}
+	if v == nil || len(reply.Result.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(reply.Result.Value, v)
+}
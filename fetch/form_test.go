@@ -0,0 +1,32 @@
+package fetch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillForm(t *testing.T) {
+	form := &FillForm{
+		Fields: []FormField{
+			{Selector: "#user", Value: "user"},
+			{Selector: "#password", Value: "password"},
+		},
+		Submit: "button[type=submit]",
+	}
+	assert.NoError(t, form.validate())
+	assert.Error(t, (&FillForm{}).validate())
+	assert.Error(t, (&FillForm{Fields: []FormField{{Value: "user"}}}).validate())
+
+	//fillForm is supported by chrome fetcher only
+	_, err := FetchService{}.Fetch(Request{URL: "http://example.com", FillForm: form})
+	assert.Error(t, err)
+	_, err = FetchService{}.Fetch(Request{Type: "chrome", URL: "http://example.com", FillForm: &FillForm{}})
+	assert.Error(t, err)
+
+	req := Request{Type: "chrome", URL: "http://example.com/login"}
+	withForm := req
+	withForm.FillForm = form
+	assert.NotEqual(t, fixtureName(req), fixtureName(withForm))
+	assert.Equal(t, "", (*FillForm)(nil).key())
+}
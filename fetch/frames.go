@@ -0,0 +1,312 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/mafredri/cdp/protocol/target"
+	cdpsession "github.com/mafredri/cdp/session"
+	"github.com/temoto/robotstxt"
+	"go.uber.org/zap"
+)
+
+// Frame capture modes
+const (
+	// FramesInline inserts content of every frame right after its iframe element.
+	// Nested frames are inlined into their parent frames.
+	FramesInline = "inline"
+	// FramesDocuments appends content of all frames to the end of the page body.
+	FramesDocuments = "documents"
+)
+
+// Frame content is returned as <frame-document data-frame-name="frame name" data-frame-url="frame URL"> elements.
+const (
+	FrameDocumentTag = "frame-document"
+	FrameNameAttr    = "data-frame-name"
+	FrameURLAttr     = "data-frame-url"
+)
+
+// maxBaseFrames is the maximum number of frames of a page fetched by BaseFetcher.
+const maxBaseFrames = 20
+
+// validFrames reports whether s is a known frame capture mode.
+func validFrames(s string) bool {
+	switch strings.ToLower(s) {
+	case "", FramesInline, FramesDocuments:
+		return true
+	}
+	return false
+}
+
+// childFrames returns frames of tree in the order children go before their parents.
+func childFrames(tree page.FrameTree) []page.Frame {
+	frames := []page.Frame{}
+	for _, child := range tree.ChildFrames {
+		frames = append(frames, childFrames(child)...)
+		frames = append(frames, child.Frame)
+	}
+	return frames
+}
+
+// captureFrames copies content of frames of the page loaded into Chrome to the main document.
+// Out-of-process frames, f.e. cross-origin frames isolated by Chrome, are read over sessions of their targets.
+// Frames nested into out-of-process frames are not captured.
+func (f *ChromeFetcher) captureFrames(ctx context.Context, mode string) error {
+	script, err := f.opts.script("frames.js")
+	if err != nil {
+		return err
+	}
+	tree, err := f.cdpClient.Page.GetFrameTree(ctx)
+	if err != nil {
+		return err
+	}
+	//frames are processed bottom up so nested frames are already inlined into their parents
+	frames := childFrames(tree.FrameTree)
+	remote, err := f.frameTargets(ctx)
+	if err != nil {
+		logger.Warn("Failed to attach to out-of-process frames. " + err.Error())
+	}
+	var sessions *cdpsession.Manager
+	if len(remote) > 0 {
+		if sessions, err = cdpsession.NewManager(f.cdpClient); err != nil {
+			return err
+		}
+		defer sessions.Close()
+	}
+	known := map[page.FrameID]bool{}
+	for _, frame := range frames {
+		known[frame.ID] = true
+	}
+	for id, info := range remote {
+		if !known[id] {
+			frames = append(frames, page.Frame{ID: id, URL: info.URL})
+		}
+	}
+	for _, frame := range frames {
+		var content string
+		if _, ok := remote[frame.ID]; ok {
+			content, err = remoteFrameHTML(ctx, sessions, target.ID(frame.ID))
+		} else {
+			content, err = f.frameHTML(ctx, frame.ID)
+		}
+		if err != nil {
+			logger.Warn("Failed to capture frame. "+err.Error(), zap.String("URL", frame.URL))
+			continue
+		}
+		name := ""
+		if frame.Name != nil {
+			name = *frame.Name
+		}
+		args := []interface{}{content, name, frame.URL}
+		if strings.ToLower(mode) == FramesDocuments {
+			err = f.evaluate(ctx, script, "appendFrameDocument", nil, args...)
+		} else {
+			err = f.insertFrameDocument(ctx, script, frame.ID, args...)
+		}
+		if err != nil {
+			logger.Warn("Failed to insert frame. "+err.Error(), zap.String("URL", frame.URL))
+		}
+	}
+	return nil
+}
+
+// frameTargets returns out-of-process frames of the page by their frame IDs.
+// Chrome auto-attaches to them and their target IDs are the IDs of frames.
+func (f *ChromeFetcher) frameTargets(ctx context.Context) (map[page.FrameID]target.Info, error) {
+	attached, err := f.cdpClient.Target.AttachedToTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer attached.Close()
+	if err := f.cdpClient.Target.SetAutoAttach(ctx, target.NewSetAutoAttachArgs(true, false)); err != nil {
+		return nil, err
+	}
+	defer f.cdpClient.Target.SetAutoAttach(ctx, target.NewSetAutoAttachArgs(false, false))
+	frames := map[page.FrameID]target.Info{}
+	//targets existing at the moment are attached before SetAutoAttach returns
+	for {
+		select {
+		case <-attached.Ready():
+			ev, err := attached.Recv()
+			if err != nil {
+				return frames, err
+			}
+			if ev.TargetInfo.Type == "iframe" {
+				frames[page.FrameID(ev.TargetInfo.TargetID)] = ev.TargetInfo
+			}
+		default:
+			return frames, nil
+		}
+	}
+}
+
+// frameHTML returns HTML of the document of the frame rendered in the process of the page.
+func (f *ChromeFetcher) frameHTML(ctx context.Context, id page.FrameID) (string, error) {
+	owner, err := f.cdpClient.DOM.GetFrameOwner(ctx, dom.NewGetFrameOwnerArgs(id))
+	if err != nil {
+		return "", err
+	}
+	node, err := f.cdpClient.DOM.DescribeNode(ctx, dom.NewDescribeNodeArgs().SetBackendNodeID(owner.BackendNodeID))
+	if err != nil {
+		return "", err
+	}
+	if node.Node.ContentDocument == nil {
+		return "", fmt.Errorf("document of frame %s not found", id)
+	}
+	reply, err := f.cdpClient.DOM.GetOuterHTML(ctx, dom.NewGetOuterHTMLArgs().SetBackendNodeID(node.Node.ContentDocument.BackendNodeID))
+	if err != nil {
+		return "", err
+	}
+	return reply.OuterHTML, nil
+}
+
+// remoteFrameHTML returns HTML of the document of the out-of-process frame read over a session of its target.
+func remoteFrameHTML(ctx context.Context, sessions *cdpsession.Manager, id target.ID) (string, error) {
+	conn, err := sessions.Dial(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	c := cdp.NewClient(conn)
+	doc, err := c.DOM.GetDocument(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	reply, err := c.DOM.GetOuterHTML(ctx, dom.NewGetOuterHTMLArgs().SetNodeID(doc.Root.NodeID))
+	if err != nil {
+		return "", err
+	}
+	return reply.OuterHTML, nil
+}
+
+// insertFrameDocument inserts frame content after iframe element owning the frame.
+func (f *ChromeFetcher) insertFrameDocument(ctx context.Context, script []byte, id page.FrameID, args ...interface{}) error {
+	owner, err := f.cdpClient.DOM.GetFrameOwner(ctx, dom.NewGetFrameOwnerArgs(id))
+	if err != nil {
+		return err
+	}
+	node, err := f.cdpClient.DOM.ResolveNode(ctx, dom.NewResolveNodeArgs().SetBackendNodeID(owner.BackendNodeID))
+	if err != nil {
+		return err
+	}
+	if node.Object.ObjectID == nil {
+		return fmt.Errorf("iframe element of frame %s not found", id)
+	}
+	callArgs := []runtime.CallArgument{}
+	for _, arg := range args {
+		value, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		callArgs = append(callArgs, runtime.CallArgument{Value: value})
+	}
+	fn := fmt.Sprintf("function(...args) {\n%s\nreturn insertFrameDocument.apply(this, args);\n}", script)
+	reply, err := f.cdpClient.Runtime.CallFunctionOn(ctx, runtime.NewCallFunctionOnArgs(fn).
+		SetObjectID(*node.Object.ObjectID).
+		SetArguments(callArgs))
+	if err != nil {
+		return err
+	}
+	if reply.ExceptionDetails != nil {
+		return fmt.Errorf("insertFrameDocument: %s", reply.ExceptionDetails.Text)
+	}
+	return nil
+}
+
+// inlineFrames fetches frames of the page read from body and copies their content to the page.
+// BaseFetcher fetches only frames from the host of the page allowed by its robots.txt. Nested frames are not fetched.
+func (bf *BaseFetcher) inlineFrames(req Request, body io.Reader, mode string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(req.getURL())
+	if err != nil {
+		return "", err
+	}
+	var robots *robotstxt.RobotsData
+	robotsRead := false
+	doc.Find("iframe[src]").EachWithBreak(func(i int, iframe *goquery.Selection) bool {
+		if i >= maxBaseFrames {
+			return false
+		}
+		src, _ := iframe.Attr("src")
+		ref, err := url.Parse(strings.TrimSpace(src))
+		if err != nil {
+			return true
+		}
+		u := base.ResolveReference(ref)
+		if u.Host != base.Host || (u.Scheme != "http" && u.Scheme != "https") {
+			return true
+		}
+		//frames are on the host of the page, so its robots.txt is read once
+		if !robotsRead {
+			robotsRead = true
+			if robots, err = RobotstxtData(u.String()); err != nil {
+				logger.Warn("Failed to read robots.txt. "+err.Error(), zap.String("URL", u.String()))
+			}
+		}
+		if !AllowedByRobots(u.String(), robots) {
+			logger.Info("Frame is disallowed by robots.txt", zap.String("URL", u.String()))
+			return true
+		}
+		frame, err := bf.frameContent(req.frameRequest(u.String()))
+		if err != nil {
+			logger.Warn("Failed to fetch frame. "+err.Error(), zap.String("URL", u.String()))
+			return true
+		}
+		name, _ := iframe.Attr("name")
+		element := fmt.Sprintf(`<%s %s="%s" %s="%s">%s</%s>`,
+			FrameDocumentTag, FrameNameAttr, html.EscapeString(name), FrameURLAttr, html.EscapeString(u.String()),
+			frame, FrameDocumentTag)
+		if strings.ToLower(mode) == FramesDocuments {
+			doc.Find("body").AppendHtml(element)
+		} else {
+			iframe.AfterHtml(element)
+		}
+		return true
+	})
+	return doc.Html()
+}
+
+// frameRequest returns the request of the frame at u sent on behalf of the page request,
+// so the frame is fetched with headers, cookies and user token of the page.
+func (req Request) frameRequest(u string) Request {
+	return Request{
+		URL:       u,
+		Headers:   req.Headers,
+		Cookies:   req.Cookies,
+		UserToken: req.UserToken,
+		Job:       req.Job,
+	}
+}
+
+// frameContent fetches the frame with req and returns HTML of its body.
+func (bf *BaseFetcher) frameContent(req Request) (string, error) {
+	resp, err := bf.response(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if isBinaryContent(resp.Header.Get("Content-Type")) {
+		return "", fmt.Errorf("frame content is not a document")
+	}
+	if _, binary := sniffBinary(resp); binary {
+		return "", fmt.Errorf("frame content is not a document")
+	}
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, bf.opts.maxBinarySize))
+	if err != nil {
+		return "", err
+	}
+	return doc.Find("body").Html()
+}
@@ -0,0 +1,112 @@
+package fetch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChildFrames(t *testing.T) {
+	tree := page.FrameTree{
+		Frame: page.Frame{ID: "main"},
+		ChildFrames: []page.FrameTree{
+			{
+				Frame:       page.Frame{ID: "ad"},
+				ChildFrames: []page.FrameTree{{Frame: page.Frame{ID: "tracker"}}},
+			},
+			{Frame: page.Frame{ID: "comments"}},
+		},
+	}
+	ids := []page.FrameID{}
+	for _, frame := range childFrames(tree) {
+		ids = append(ids, frame.ID)
+	}
+	//nested frames go before their parents, the main frame is not included
+	assert.Equal(t, []page.FrameID{"tracker", "ad", "comments"}, ids)
+}
+
+func TestFramesRequest(t *testing.T) {
+	assert.True(t, validFrames(""))
+	assert.True(t, validFrames("Inline"))
+	assert.True(t, validFrames(FramesDocuments))
+	assert.False(t, validFrames("all"))
+
+	_, err := FetchService{}.Fetch(Request{Type: "chrome", URL: "http://example.com", Frames: "all"})
+	assert.Error(t, err)
+
+	req := Request{Type: "chrome", URL: "http://example.com"}
+	withFrames := req
+	withFrames.Frames = FramesInline
+	assert.NotEqual(t, fixtureName(req), fixtureName(withFrames))
+}
+
+func TestBaseFetcher_InlineFrames(t *testing.T) {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><h1>Page</h1>
+		<iframe name="comments" src="/comments"></iframe>
+		<iframe src="http://ads.example.net/banner"></iframe>
+		<iframe src="/missing"></iframe>
+		<iframe src="/private/frame"></iframe>
+		</body></html>`)
+	})
+	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
+		//frames are requested with headers and cookies of the page
+		if r.Header.Get("Accept-Language") != "de" {
+			http.Error(w, "no language", http.StatusBadRequest)
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != "1" {
+			http.Error(w, "no session", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `<html><body><p class="comment">First</p><p class="comment">Second</p></body></html>`)
+	})
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	})
+	mux.HandleFunc("/private/frame", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p class="comment">Private</p></body></html>`)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+
+	viper.Set("PROXY", "")
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	for _, mode := range []string{FramesInline, FramesDocuments} {
+		content, err := fetcher.Fetch(Request{
+			URL:     ts.URL,
+			Frames:  mode,
+			Headers: map[string]string{"Accept-Language": "de"},
+			Cookies: Cookies{{Name: "session", Value: "1"}},
+		})
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(data)))
+		assert.NoError(t, err)
+
+		//frames from other hosts, disallowed by robots.txt and failed frames are skipped
+		frames := doc.Find(FrameDocumentTag)
+		assert.Equal(t, 1, frames.Length(), mode)
+		assert.Equal(t, "comments", frames.AttrOr(FrameNameAttr, ""))
+		assert.Equal(t, ts.URL+"/comments", frames.AttrOr(FrameURLAttr, ""))
+		assert.Equal(t, 2, frames.Find(".comment").Length())
+		if mode == FramesInline {
+			assert.Equal(t, FrameDocumentTag, goquery.NodeName(doc.Find("iframe[name=comments]").Next()))
+		} else {
+			assert.Equal(t, FrameDocumentTag, goquery.NodeName(doc.Find("body").Children().Last()))
+		}
+	}
+}
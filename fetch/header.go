@@ -0,0 +1,143 @@
+package fetch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// passedHeaders are response headers of fetched pages passed to Parse service along with their content.
+// Validators are passed so unchanged pages may be requested conditionally next time.
+// Final URL of redirected pages is passed so Parse service may check it against URL patterns.
+var passedHeaders = []string{"X-Robots-Tag", "ETag", "Last-Modified", FinalURLHeader, CacheHeader, ConsoleHeader, FailedRequestsHeader, TimingHeader, BinaryHeader}
+
+// CacheHeader is set to "HIT" for pages served from recorded fixtures instead of the network.
+const CacheHeader = "X-Fetch-Cache"
+
+// Headers describing the response to HEAD request. They are passed to Parse service instead of the page content.
+// Content-Length of the response is renamed as the content passed between services is empty.
+const (
+	StatusHeader        = "X-Fetch-Status"
+	FinalURLHeader      = "X-Fetch-Final-URL"
+	ContentTypeHeader   = "X-Fetch-Content-Type"
+	ContentLengthHeader = "X-Fetch-Content-Length"
+)
+
+// headHeaders are passed along with empty content of HEAD responses.
+var headHeaders = []string{StatusHeader, ContentTypeHeader, ContentLengthHeader}
+
+// headerCarrier is implemented by fetched content carrying response headers.
+type headerCarrier interface {
+	responseHeader() http.Header
+}
+
+// content is fetched document along with its passed response headers.
+type content struct {
+	io.ReadCloser
+	header http.Header
+}
+
+func (c *content) responseHeader() http.Header {
+	return c.header
+}
+
+// withHeader attaches passed response headers from h to fetched document.
+func withHeader(rc io.ReadCloser, h http.Header) io.ReadCloser {
+	passed := http.Header{}
+	keys := passedHeaders
+	if h.Get(StatusHeader) != "" {
+		keys = append(headHeaders, passedHeaders...)
+	}
+	for _, k := range keys {
+		for _, v := range h[http.CanonicalHeaderKey(k)] {
+			passed.Add(k, v)
+		}
+	}
+	if len(passed) == 0 {
+		return rc
+	}
+	return &content{ReadCloser: rc, header: passed}
+}
+
+// WithResponseHeader attaches response headers passed along with fetched document to rc.
+// It is used when content of fetched document has been read and is replaced with a buffered copy.
+func WithResponseHeader(rc io.ReadCloser, h http.Header) io.ReadCloser {
+	return withHeader(rc, h)
+}
+
+// ResponseHeader returns response headers of fetched document passed along with its content,
+// f.e. X-Robots-Tag. It returns nil if there are none.
+func ResponseHeader(rc io.ReadCloser) http.Header {
+	if c, ok := rc.(headerCarrier); ok {
+		return c.responseHeader()
+	}
+	return nil
+}
+
+// headResponse returns empty content carrying status, final URL, type and length of the response to HEAD request of url.
+func headResponse(url string, resp *http.Response) io.ReadCloser {
+	resp.Body.Close()
+	h := http.Header{}
+	h.Set(StatusHeader, strconv.Itoa(resp.StatusCode))
+	if resp.Request != nil && resp.Request.URL.String() != url {
+		h.Set(FinalURLHeader, resp.Request.URL.String())
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		h.Set(ContentTypeHeader, ct)
+	}
+	if resp.ContentLength >= 0 {
+		h.Set(ContentLengthHeader, strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		h.Set("Last-Modified", lm)
+	}
+	return withHeader(ioutil.NopCloser(strings.NewReader("")), h)
+}
+
+// finalURLHeader returns FinalURLHeader set to final if the request of url has been redirected there.
+func finalURLHeader(url, final string) http.Header {
+	if final == "" || final == url {
+		return nil
+	}
+	h := http.Header{}
+	h.Set(FinalURLHeader, final)
+	return h
+}
+
+// encodeHeader returns header name holding base64 encoded JSON of v. Encoding keeps line breaks and
+// non-ASCII text of v out of header value.
+func encodeHeader(name string, v interface{}) http.Header {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return http.Header{name: {base64.StdEncoding.EncodeToString(data)}}
+}
+
+// decodeHeader decodes header name passed along with rc into v. It returns false if there is no valid header.
+func decodeHeader(rc io.ReadCloser, name string, v interface{}) bool {
+	value := ResponseHeader(rc).Get(name)
+	if value == "" {
+		return false
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// joinHeaders returns all values of headers hs.
+func joinHeaders(hs ...http.Header) http.Header {
+	joined := http.Header{}
+	for _, h := range hs {
+		for k, v := range h {
+			joined[k] = append(joined[k], v...)
+		}
+	}
+	return joined
+}
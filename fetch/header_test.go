@@ -0,0 +1,86 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "text/html")
+	rc := withHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	assert.Nil(t, ResponseHeader(rc))
+
+	h.Set("X-Robots-Tag", "noindex")
+	rc = withHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	assert.Equal(t, "noindex", ResponseHeader(rc).Get("X-Robots-Tag"))
+	assert.Empty(t, ResponseHeader(rc).Get("Content-Type"))
+
+	released := false
+	rc = &releaseCloser{ReadCloser: rc, release: func() { released = true }}
+	assert.Equal(t, "noindex", ResponseHeader(rc).Get("X-Robots-Tag"))
+	rc.Close()
+	assert.True(t, released)
+}
+
+func TestBaseFetcher_Head(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/report.pdf", http.StatusMovedPermanently)
+			return
+		}
+		assert.Equal(t, "HEAD", r.Method)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", "1024")
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcher()
+	assert.NoError(t, err)
+	rc, err := fetcher.Fetch(Request{URL: ts.URL + "/old", Method: "head"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(rc)
+	assert.Empty(t, data)
+	h := ResponseHeader(rc)
+	assert.Equal(t, "200", h.Get(StatusHeader))
+	assert.Equal(t, ts.URL+"/report.pdf", h.Get(FinalURLHeader))
+	assert.Equal(t, "application/pdf", h.Get(ContentTypeHeader))
+	assert.Equal(t, "1024", h.Get(ContentLengthHeader))
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", h.Get("Last-Modified"))
+
+	//headers of HEAD response survive the way from Fetch service to Parse service
+	rc = withHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	assert.Equal(t, h, ResponseHeader(rc))
+
+	_, err = FetchService{}.Fetch(Request{URL: ts.URL, Method: "HEAD", Type: "chrome"})
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_FinalURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcher()
+	assert.NoError(t, err)
+	rc, err := fetcher.Fetch(Request{URL: ts.URL + "/old"})
+	assert.NoError(t, err)
+	rc.Close()
+	assert.Equal(t, ts.URL+"/new", ResponseHeader(rc).Get(FinalURLHeader))
+
+	rc, err = fetcher.Fetch(Request{URL: ts.URL + "/new"})
+	assert.NoError(t, err)
+	rc.Close()
+	assert.Empty(t, ResponseHeader(rc).Get(FinalURLHeader))
+}
@@ -13,6 +13,8 @@ import (
 
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
 )
 
 // NewHTTPClient returns an Fetch Service backed by an HTTP server living at the
@@ -59,6 +61,14 @@ func encodeRequest(ctx context.Context, r *http.Request, request interface{}) er
 	if err := json.NewEncoder(&buf).Encode(request); err != nil {
 		return err
 	}
+	if req, ok := request.(Request); ok {
+		if req.RequestID != "" {
+			r.Header.Set(utils.RequestIDHeader, req.RequestID)
+		}
+		if req.Job != "" {
+			r.Header.Set(JobHeader, req.Job)
+		}
+	}
 	r.Body = ioutil.NopCloser(&buf)
 	return nil
 }
@@ -67,13 +77,16 @@ func decodeFetcherContent(ctx context.Context, r *http.Response) (interface{}, e
 	if r.StatusCode != http.StatusOK {
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(r.Body)
+		if err := errs.DecodeResponse(r.StatusCode, buf.Bytes()); err != nil {
+			return nil, err
+		}
 		return nil, errors.New(buf.String())
 	}
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return nil, err
 	}
-	return data, nil
+	return withHeader(ioutil.NopCloser(bytes.NewReader(data)), r.Header), nil
 }
 
 func copyURL(base *url.URL, path string) *url.URL {
@@ -90,6 +103,29 @@ func (e endpoints) Fetch(req Request) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	readCloser := ioutil.NopCloser(bytes.NewReader(resp.([]byte)))
-	return readCloser, nil
+	return resp.(io.ReadCloser), nil
+}
+
+// GetPoolStats returns worker pool usage counters of Fetch service living at the remote instance.
+func GetPoolStats(instance string) (*PoolStats, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(copyURL(u, "/pool").String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	stats := &PoolStats{}
+	if err := json.NewDecoder(resp.Body).Decode(stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
 }
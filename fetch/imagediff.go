@@ -0,0 +1,56 @@
+package fetch
+
+import (
+	"image"
+	"math"
+)
+
+// diffGridSize is a side of the grid screenshots are reduced to before comparison.
+// Small grid makes the score insensitive to antialiasing, blinking cursors and similar noise
+// while moved or resized page blocks still change the score significantly.
+const diffGridSize = 32
+
+// luminanceGrid reduces image to diffGridSize x diffGridSize grid of average luminance values in range [0, 1].
+func luminanceGrid(img image.Image) []float64 {
+	grid := make([]float64, diffGridSize*diffGridSize)
+	counts := make([]int, diffGridSize*diffGridSize)
+	b := img.Bounds()
+	if b.Empty() {
+		return grid
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		gy := (y - b.Min.Y) * diffGridSize / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gx := (x - b.Min.X) * diffGridSize / b.Dx()
+			r, g, bl, _ := img.At(x, y).RGBA()
+			//ITU-R BT.601 luma
+			lum := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 0xffff
+			grid[gy*diffGridSize+gx] += lum
+			counts[gy*diffGridSize+gx]++
+		}
+	}
+	for i := range grid {
+		if counts[i] > 0 {
+			grid[i] /= float64(counts[i])
+		}
+	}
+	return grid
+}
+
+// diffImages returns perceptual difference score between two images in range [0, 1].
+// 0 means images look the same.
+// Images of different size are compared after reducing to the same grid.
+// Change of page height is taken into account as it usually means the layout has changed.
+func diffImages(a, b image.Image) float64 {
+	ga, gb := luminanceGrid(a), luminanceGrid(b)
+	diff := 0.0
+	for i := range ga {
+		diff += math.Abs(ga[i] - gb[i])
+	}
+	score := diff / float64(len(ga))
+	ha, hb := float64(a.Bounds().Dy()), float64(b.Bounds().Dy())
+	if ha > 0 && hb > 0 {
+		score = math.Max(score, math.Abs(ha-hb)/math.Max(ha, hb))
+	}
+	return score
+}
@@ -0,0 +1,130 @@
+package fetch
+
+import (
+	"context"
+	"encoding/base64"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mafredri/cdp/protocol/network"
+	"go.uber.org/zap"
+)
+
+// InterceptedAttr is an attribute of script elements holding intercepted responses.
+// Its value is URL of XHR or fetch request, so responses may be selected like
+// script[data-intercepted*="/api/products"].
+const InterceptedAttr = "data-intercepted"
+
+// interceptedResponse is a JSON response of XHR or fetch request captured during page load.
+type interceptedResponse struct {
+	URL  string
+	Body string
+}
+
+// xhrCapture collects XHR and fetch requests whose URLs match Request.Intercept patterns.
+type xhrCapture struct {
+	patterns []*regexp.Regexp
+	mx       sync.Mutex
+	ids      []network.RequestID
+	urls     []string
+}
+
+// interceptPattern converts URL glob pattern to regexp. "*" matches any sequence of characters.
+func interceptPattern(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func (c *xhrCapture) match(u string) bool {
+	for _, p := range c.patterns {
+		if p.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// startCapture starts collecting JSON responses of XHR and fetch requests matching patterns.
+// Collection stops when ctx is done.
+func (f *ChromeFetcher) startCapture(ctx context.Context, patterns []string) (*xhrCapture, error) {
+	c := &xhrCapture{}
+	for _, p := range patterns {
+		c.patterns = append(c.patterns, interceptPattern(p))
+	}
+	received, err := f.cdpClient.Network.ResponseReceived(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer received.Close()
+		for {
+			ev, err := received.Recv()
+			if err != nil {
+				return
+			}
+			if ev.Type != network.ResourceTypeXHR && ev.Type != network.ResourceTypeFetch {
+				continue
+			}
+			if !strings.Contains(ev.Response.MimeType, "json") || !c.match(ev.Response.URL) {
+				continue
+			}
+			c.mx.Lock()
+			c.ids = append(c.ids, ev.RequestID)
+			c.urls = append(c.urls, ev.Response.URL)
+			c.mx.Unlock()
+		}
+	}()
+	return c, nil
+}
+
+// responses returns bodies of captured responses in the order they were received.
+// Responses whose bodies are not available are skipped.
+func (f *ChromeFetcher) responses(ctx context.Context, c *xhrCapture) []interceptedResponse {
+	c.mx.Lock()
+	ids, urls := append([]network.RequestID{}, c.ids...), append([]string{}, c.urls...)
+	c.mx.Unlock()
+	res := []interceptedResponse{}
+	for i, id := range ids {
+		reply, err := f.cdpClient.Network.GetResponseBody(ctx, network.NewGetResponseBodyArgs(id))
+		if err != nil {
+			logger.Warn("Failed to get intercepted response. "+err.Error(), zap.String("URL", urls[i]))
+			continue
+		}
+		body := reply.Body
+		if reply.Base64Encoded {
+			data, err := base64.StdEncoding.DecodeString(body)
+			if err != nil {
+				continue
+			}
+			body = string(data)
+		}
+		res = append(res, interceptedResponse{URL: urls[i], Body: body})
+	}
+	return res
+}
+
+// embedResponses inserts intercepted responses into the page as JSON script elements
+// at the end of its body so they can be extracted along with the page content.
+func embedResponses(page string, responses []interceptedResponse) string {
+	if len(responses) == 0 {
+		return page
+	}
+	var b strings.Builder
+	for _, r := range responses {
+		b.WriteString(`<script type="application/json" ` + InterceptedAttr + `="`)
+		b.WriteString(html.EscapeString(r.URL))
+		b.WriteString(`">`)
+		//script element content ends at the first "</"
+		b.WriteString(strings.Replace(r.Body, "</", `<\/`, -1))
+		b.WriteString("</script>")
+	}
+	if i := strings.LastIndex(strings.ToLower(page), "</body>"); i >= 0 {
+		return page[:i] + b.String() + page[i:]
+	}
+	return page + b.String()
+}
@@ -0,0 +1,29 @@
+package fetch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptPattern(t *testing.T) {
+	c := &xhrCapture{}
+	for _, p := range []string{"http://example.com/api/*", "*/graphql"} {
+		c.patterns = append(c.patterns, interceptPattern(p))
+	}
+	assert.True(t, c.match("http://example.com/api/products?page=2"))
+	assert.True(t, c.match("https://cdn.example.com/graphql"))
+	assert.False(t, c.match("http://example.com/static/app.js"))
+	assert.False(t, c.match("http://example.com/api.v2/products"))
+}
+
+func TestEmbedResponses(t *testing.T) {
+	page := "<html><body><div>page</div></body></html>"
+	assert.Equal(t, page, embedResponses(page, nil))
+	res := embedResponses(page, []interceptedResponse{
+		{URL: "http://example.com/api?a=1&b=2", Body: `{"html":"</script>"}`},
+	})
+	assert.Equal(t, `<html><body><div>page</div><script type="application/json" data-intercepted="http://example.com/api?a=1&amp;b=2">{"html":"<\/script>"}</script></body></html>`, res)
+	assert.Equal(t, `<p>page</p><script type="application/json" data-intercepted="u">{}</script>`,
+		embedResponses("<p>page</p>", []interceptedResponse{{URL: "u", Body: "{}"}}))
+}
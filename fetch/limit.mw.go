@@ -0,0 +1,169 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// WorkerPool limits the number of fetches processed concurrently by Fetch service.
+// Total number of fetches and number of Chrome sessions are bounded separately.
+// Requests exceeding the limits are queued until a slot is released or queue timeout expires.
+type WorkerPool struct {
+	fetchSlots  chan struct{}
+	chromeSlots chan struct{}
+	timeout     time.Duration
+	stats       PoolStats
+}
+
+// PoolStats contains WorkerPool usage counters.
+type PoolStats struct {
+	//Active is the number of fetches in progress
+	Active int64 `json:"active"`
+	//ActiveChrome is the number of Chrome sessions in progress
+	ActiveChrome int64 `json:"activeChrome"`
+	//Queued is the number of requests waiting for a free slot
+	Queued int64 `json:"queued"`
+	//Rejected is the total number of requests failed by queue timeout
+	Rejected int64 `json:"rejected"`
+	//MaxFetches is the limit of concurrent fetches. Zero means no limit.
+	MaxFetches int `json:"maxFetches"`
+	//MaxChrome is the limit of concurrent Chrome sessions. Zero means no limit.
+	MaxChrome int `json:"maxChrome"`
+}
+
+// NewWorkerPool creates WorkerPool allowing maxFetches concurrent fetches, maxChrome of them may be Chrome sessions.
+// Zero value disables corresponding limit. Requests wait in a queue not longer than timeout.
+func NewWorkerPool(maxFetches, maxChrome int, timeout time.Duration) *WorkerPool {
+	p := &WorkerPool{
+		timeout: timeout,
+		stats: PoolStats{
+			MaxFetches: maxFetches,
+			MaxChrome:  maxChrome,
+		},
+	}
+	if maxFetches > 0 {
+		p.fetchSlots = make(chan struct{}, maxFetches)
+	}
+	if maxChrome > 0 {
+		p.chromeSlots = make(chan struct{}, maxChrome)
+	}
+	return p
+}
+
+// Stats returns a snapshot of pool usage counters.
+func (p *WorkerPool) Stats() PoolStats {
+	return PoolStats{
+		Active:       atomic.LoadInt64(&p.stats.Active),
+		ActiveChrome: atomic.LoadInt64(&p.stats.ActiveChrome),
+		Queued:       atomic.LoadInt64(&p.stats.Queued),
+		Rejected:     atomic.LoadInt64(&p.stats.Rejected),
+		MaxFetches:   p.stats.MaxFetches,
+		MaxChrome:    p.stats.MaxChrome,
+	}
+}
+
+// acquire blocks until slots for the request are available. It returns a function releasing taken slots.
+// Chrome slot is taken first so Chrome requests waiting for a session don't hold slots needed by Base fetcher.
+func (p *WorkerPool) acquire(chrome bool) (func(), error) {
+	atomic.AddInt64(&p.stats.Queued, 1)
+	defer atomic.AddInt64(&p.stats.Queued, -1)
+
+	var timeout <-chan time.Time
+	if p.timeout > 0 {
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	busy := errs.StatusError{
+		Code: http.StatusServiceUnavailable,
+		Err:  errors.New("fetch queue timeout. Too many concurrent requests"),
+	}
+	chromeTaken := false
+	if chrome && p.chromeSlots != nil {
+		select {
+		case p.chromeSlots <- struct{}{}:
+			chromeTaken = true
+		case <-timeout:
+			atomic.AddInt64(&p.stats.Rejected, 1)
+			return nil, busy
+		}
+	}
+	if p.fetchSlots != nil {
+		select {
+		case p.fetchSlots <- struct{}{}:
+		case <-timeout:
+			if chromeTaken {
+				<-p.chromeSlots
+			}
+			atomic.AddInt64(&p.stats.Rejected, 1)
+			return nil, busy
+		}
+	}
+	atomic.AddInt64(&p.stats.Active, 1)
+	if chrome {
+		atomic.AddInt64(&p.stats.ActiveChrome, 1)
+	}
+	release := func() {
+		if p.fetchSlots != nil {
+			<-p.fetchSlots
+		}
+		if chromeTaken {
+			<-p.chromeSlots
+		}
+		atomic.AddInt64(&p.stats.Active, -1)
+		if chrome {
+			atomic.AddInt64(&p.stats.ActiveChrome, -1)
+		}
+	}
+	return release, nil
+}
+
+// LimitMiddleware runs Fetch service requests through specified WorkerPool.
+func LimitMiddleware(pool *WorkerPool) ServiceMiddleware {
+	return func(next Service) Service {
+		return limitMiddleware{next, pool}
+	}
+}
+
+type limitMiddleware struct {
+	Service
+	pool *WorkerPool
+}
+
+// Fetch holds pool slots until returned content is closed
+// as Base fetcher streams response body directly from the open connection.
+func (mw limitMiddleware) Fetch(req Request) (io.ReadCloser, error) {
+	release, err := mw.pool.acquire(req.Type == "chrome")
+	if err != nil {
+		return nil, err
+	}
+	content, err := mw.Service.Fetch(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releaseCloser{ReadCloser: content, release: release}, nil
+}
+
+// releaseCloser releases pool slots once underlying content is closed.
+type releaseCloser struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (rc *releaseCloser) responseHeader() http.Header {
+	return ResponseHeader(rc.ReadCloser)
+}
+
+func (rc *releaseCloser) Close() error {
+	err := rc.ReadCloser.Close()
+	rc.once.Do(rc.release)
+	return err
+}
@@ -0,0 +1,74 @@
+package fetch
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingService struct {
+	started chan struct{}
+	proceed chan struct{}
+}
+
+func (s blockingService) Fetch(req Request) (io.ReadCloser, error) {
+	s.started <- struct{}{}
+	<-s.proceed
+	return ioutil.NopCloser(strings.NewReader("ok")), nil
+}
+
+func TestLimitMiddleware(t *testing.T) {
+	next := blockingService{
+		started: make(chan struct{}, 10),
+		proceed: make(chan struct{}, 10),
+	}
+	pool := NewWorkerPool(2, 1, 50*time.Millisecond)
+	svc := LimitMiddleware(pool)(next)
+
+	results := make(chan io.ReadCloser, 2)
+	go func() {
+		content, _ := svc.Fetch(Request{Type: "chrome"})
+		results <- content
+	}()
+	<-next.started
+	assert.Equal(t, int64(1), pool.Stats().ActiveChrome)
+
+	//Chrome sessions limit reached
+	_, err := svc.Fetch(Request{Type: "chrome"})
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), pool.Stats().Rejected)
+
+	go func() {
+		content, _ := svc.Fetch(Request{Type: "base"})
+		results <- content
+	}()
+	<-next.started
+	assert.Equal(t, int64(2), pool.Stats().Active)
+
+	//Total fetches limit reached
+	_, err = svc.Fetch(Request{Type: "base"})
+	assert.Error(t, err)
+
+	next.proceed <- struct{}{}
+	next.proceed <- struct{}{}
+	for i := 0; i < 2; i++ {
+		content := <-results
+		//slots are kept until content is closed
+		assert.NotEqual(t, int64(0), pool.Stats().Active)
+		content.Close()
+	}
+	stats := pool.Stats()
+	assert.Equal(t, int64(0), stats.Active)
+	assert.Equal(t, int64(0), stats.ActiveChrome)
+	assert.Equal(t, int64(0), stats.Queued)
+	assert.Equal(t, int64(2), stats.Rejected)
+
+	next.proceed <- struct{}{}
+	content, err := svc.Fetch(Request{Type: "chrome"})
+	assert.NoError(t, err)
+	content.Close()
+}
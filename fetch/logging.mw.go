@@ -29,12 +29,14 @@ func (mw loggingMiddleware) Fetch(req Request) (out io.ReadCloser, err error) {
 			mw.logger.Info("Fetch",
 				zap.String("URL", url),
 				zap.String("fetcher", req.Type),
+				zap.String("requestID", req.RequestID),
 				zap.Duration("took", time.Since(begin)),
 			)
 		} else {
 			mw.logger.Error("Fetch",
 				zap.String("URL", url),
 				zap.String("fetcher", req.Type),
+				zap.String("requestID", req.RequestID),
 				zap.Error(err),
 				zap.Duration("took", time.Since(begin)),
 			)
@@ -0,0 +1,65 @@
+package fetch
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// methods are HTTP methods of fetch requests.
+var methods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// HTTPMethod returns HTTP method the request is sent with. Requests carrying form data or body
+// are sent with POST method unless Method is set.
+func (r Request) HTTPMethod() string {
+	if m := strings.ToUpper(r.Method); m != "" {
+		return m
+	}
+	if r.FormData != "" || r.Body != "" {
+		return "POST"
+	}
+	return "GET"
+}
+
+// head reports whether r is HEAD request. Response to it has no content, its status and headers are passed instead.
+func (r Request) head() bool {
+	return r.HTTPMethod() == "HEAD"
+}
+
+// RequestBody returns the body sent with the request along with its content type. Form data is URL encoded.
+// Content type of Body is taken from Content-Type header of the request. JSON body defaults to application/json.
+func (r Request) RequestBody() (string, string) {
+	switch {
+	case r.FormData != "":
+		return parseFormData(r.FormData).Encode(), "application/x-www-form-urlencoded"
+	case r.Body == "":
+		return "", ""
+	}
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return r.Body, v
+		}
+	}
+	if json.Valid([]byte(r.Body)) {
+		return r.Body, "application/json"
+	}
+	return r.Body, "text/plain; charset=utf-8"
+}
+
+// validateMethod checks HTTP method of the request along with its body.
+func (r Request) validateMethod() error {
+	m := r.HTTPMethod()
+	switch {
+	case !utils.ArrayContains(methods, m):
+		return errs.BadPayload{ErrText: "unsupported HTTP method " + r.Method}
+	case r.FormData != "" && r.Body != "":
+		return errs.BadPayload{ErrText: "formData and body can't be sent together"}
+	case (m == "GET" || m == "HEAD") && (r.FormData != "" || r.Body != ""):
+		return errs.BadPayload{ErrText: m + " requests can't carry formData or body"}
+	case m == "HEAD" && r.Type == "chrome":
+		return errs.BadPayload{ErrText: "HEAD requests are sent by base fetcher only"}
+	}
+	return nil
+}
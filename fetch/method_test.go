@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest_Method(t *testing.T) {
+	for _, tc := range []struct {
+		req         Request
+		method      string
+		body        string
+		contentType string
+		valid       bool
+	}{
+		{Request{}, "GET", "", "", true},
+		{Request{Method: "head"}, "HEAD", "", "", true},
+		{Request{FormData: "q=go"}, "POST", "q=go", "application/x-www-form-urlencoded", true},
+		{Request{Method: "put", FormData: "q=go"}, "PUT", "q=go", "application/x-www-form-urlencoded", true},
+		{Request{Body: `{"stock": 0}`}, "POST", `{"stock": 0}`, "application/json", true},
+		{Request{Method: "PATCH", Body: "stock=0"}, "PATCH", "stock=0", "text/plain; charset=utf-8", true},
+		{Request{Method: "PUT", Body: "<a/>", Headers: map[string]string{"content-type": "application/xml"}}, "PUT", "<a/>", "application/xml", true},
+		{Request{Method: "DELETE"}, "DELETE", "", "", true},
+		{Request{Method: "OPTIONS"}, "OPTIONS", "", "", true},
+		{Request{Method: "TRACE"}, "TRACE", "", "", false},
+		{Request{Method: "GET", FormData: "q=go"}, "GET", "q=go", "application/x-www-form-urlencoded", false},
+		{Request{Method: "HEAD", Type: "chrome"}, "HEAD", "", "", false},
+		{Request{FormData: "q=go", Body: "{}"}, "POST", "q=go", "application/x-www-form-urlencoded", false},
+	} {
+		assert.Equal(t, tc.method, tc.req.HTTPMethod())
+		body, contentType := tc.req.RequestBody()
+		assert.Equal(t, tc.body, body)
+		assert.Equal(t, tc.contentType, contentType)
+		assert.Equal(t, tc.valid, tc.req.validateMethod() == nil, tc.req)
+	}
+}
+
+func TestBaseFetcher_Methods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcher()
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		req      Request
+		expected string
+	}{
+		{Request{URL: ts.URL, Method: "put", Body: `{"stock":0}`}, `PUT application/json {"stock":0}`},
+		{Request{URL: ts.URL, Method: "DELETE"}, "DELETE  "},
+		{Request{URL: ts.URL, Method: "PATCH", FormData: "a=1"}, "PATCH application/x-www-form-urlencoded a=1"},
+	} {
+		content, err := fetcher.Fetch(tc.req)
+		assert.NoError(t, err)
+		data, _ := ioutil.ReadAll(content)
+		assert.Equal(t, tc.expected, string(data))
+	}
+}
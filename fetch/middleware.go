@@ -0,0 +1,25 @@
+package fetch
+
+import "sync"
+
+var registered = struct {
+	sync.Mutex
+	middlewares []ServiceMiddleware
+}{}
+
+// RegisterMiddleware adds custom middlewares to the Fetch service chain built by Start.
+func RegisterMiddleware(mw ...ServiceMiddleware) {
+	registered.Lock()
+	defer registered.Unlock()
+	registered.middlewares = append(registered.middlewares, mw...)
+}
+
+// applyMiddlewares wraps svc with registered middlewares.
+func applyMiddlewares(svc Service) Service {
+	registered.Lock()
+	defer registered.Unlock()
+	for _, mw := range registered.middlewares {
+		svc = mw(svc)
+	}
+	return svc
+}
@@ -0,0 +1,50 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tracingService struct {
+	next  Service
+	name  string
+	calls *[]string
+}
+
+func (s tracingService) Fetch(req Request) (io.ReadCloser, error) {
+	*s.calls = append(*s.calls, s.name)
+	if req.UserToken == "" {
+		return nil, errors.New("unauthorized")
+	}
+	if s.next == nil {
+		return ioutil.NopCloser(strings.NewReader("ok")), nil
+	}
+	return s.next.Fetch(req)
+}
+
+func TestRegisterMiddleware(t *testing.T) {
+	defer func() { registered.middlewares = nil }()
+	calls := []string{}
+	tracing := func(name string) ServiceMiddleware {
+		return func(next Service) Service {
+			return tracingService{next, name, &calls}
+		}
+	}
+	RegisterMiddleware(tracing("auth"), tracing("quota"))
+	RegisterMiddleware(tracing("cache"))
+	svc := applyMiddlewares(tracingService{name: "fetch", calls: &calls})
+
+	_, err := svc.Fetch(Request{UserToken: "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cache", "quota", "auth", "fetch"}, calls)
+
+	calls = calls[:0]
+	_, err = svc.Fetch(Request{})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"cache"}, calls)
+}
@@ -0,0 +1,90 @@
+package fetch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+)
+
+// MockFetcher serves canned responses instead of downloading web pages.
+// It is intended for testing payloads and extractors both in Dataflow Kit and in applications embedding it.
+//
+// A response is looked up in Responses by request URL first.
+// If it is not found there, it is read from Dir where file names follow the fixture naming used by FixtureMiddleware.
+// So fixtures recorded with FIXTURE_MODE=record may be served by MockFetcher.
+// Fixtures recorded by Base fetcher take precedence over Chrome ones.
+type MockFetcher struct {
+	//Responses maps URL to response content
+	Responses map[string]string
+	//Dir is a directory with recorded fixtures
+	Dir string
+}
+
+var (
+	mockMx      sync.Mutex
+	mockFetcher *MockFetcher
+)
+
+// NewMockFetcher returns MockFetcher serving responses from the map and dir.
+// Both of them are optional.
+func NewMockFetcher(responses map[string]string, dir string) *MockFetcher {
+	return &MockFetcher{
+		Responses: responses,
+		Dir:       dir,
+	}
+}
+
+// SetMockFetcher registers MockFetcher used to serve requests of "mock" type.
+// If no MockFetcher is registered, fixtures from FIXTURE_DIR are served.
+func SetMockFetcher(m *MockFetcher) {
+	mockMx.Lock()
+	defer mockMx.Unlock()
+	mockFetcher = m
+}
+
+func newMockFetcher() *MockFetcher {
+	mockMx.Lock()
+	defer mockMx.Unlock()
+	if mockFetcher != nil {
+		return mockFetcher
+	}
+	return NewMockFetcher(nil, viper.GetString("FIXTURE_DIR"))
+}
+
+// Fetch returns canned response for the request. 404 error is returned if there is no response for it.
+func (m *MockFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	for _, u := range []string{request.URL, request.getURL()} {
+		if content, ok := m.Responses[u]; ok {
+			return ioutil.NopCloser(bytes.NewReader([]byte(content))), nil
+		}
+	}
+	if m.Dir != "" {
+		//fixtures are recorded by real fetchers
+		for _, t := range []string{"base", "chrome"} {
+			request.Type = t
+			content, err := ioutil.ReadFile(filepath.Join(m.Dir, fixtureName(request)))
+			if err == nil {
+				return ioutil.NopCloser(bytes.NewReader(content)), nil
+			}
+		}
+	}
+	return nil, errs.StatusError{
+		Code: http.StatusNotFound,
+		Err:  fmt.Errorf("no mock response for %s", request.getURL()),
+	}
+}
+
+// fetch returns canned response for the request. Session is left as is.
+func (m *MockFetcher) fetch(request Request, sess *session) (io.ReadCloser, error) {
+	return m.Fetch(request)
+}
+
+// Static type assertion
+var _ Fetcher = &MockFetcher{}
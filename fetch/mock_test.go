@@ -0,0 +1,39 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fixture := Request{URL: "http://example.com/fixture"}
+	err = ioutil.WriteFile(filepath.Join(dir, fixtureName(fixture)), []byte("from fixture"), 0644)
+	assert.NoError(t, err)
+
+	m := NewMockFetcher(map[string]string{
+		"http://example.com": "from map",
+	}, dir)
+	SetMockFetcher(m)
+	defer SetMockFetcher(nil)
+
+	svc := FetchService{}
+	content, err := svc.Fetch(Request{Type: "mock", URL: "http://example.com/"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	assert.Equal(t, "from map", string(data))
+
+	content, err = svc.Fetch(Request{Type: "mock", URL: fixture.URL})
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(content)
+	assert.Equal(t, "from fixture", string(data))
+
+	_, err = svc.Fetch(Request{Type: "mock", URL: "http://example.com/missing"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,224 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// Navigation wait conditions
+const (
+	// WaitLoad waits for the load event of the page.
+	WaitLoad = "load"
+	// WaitNetworkIdle waits for the load event and network idle afterwards.
+	// Network is idle when no more than NETWORK_IDLE_CONNECTIONS requests are in flight for NETWORK_IDLE_TIME milliseconds.
+	WaitNetworkIdle = "networkidle"
+)
+
+// defaultNetworkIdleTime is used if NETWORK_IDLE_TIME is not set.
+const defaultNetworkIdleTime = 500 * time.Millisecond
+
+// networkIdleTimeout is the maximum time to wait for network idle.
+// Pages polling their servers constantly never become idle.
+const networkIdleTimeout = 30 * time.Second
+
+// networkMonitor counts in-flight requests of the page loaded into Chrome.
+type networkMonitor struct {
+	mx       sync.Mutex
+	inflight map[network.RequestID]bool
+	//maxInflight requests are allowed in idle network
+	maxInflight int
+	//idleSince is the time the number of in-flight requests dropped to maxInflight. It is zero if network is busy.
+	idleSince time.Time
+	now       func() time.Time
+	//requests collects failed requests of the page
+	requests *requestLog
+	//loaded is the number of bytes of finished requests not accounted yet
+	loaded int64
+}
+
+func newNetworkMonitor(maxInflight int) *networkMonitor {
+	m := &networkMonitor{
+		inflight:    make(map[network.RequestID]bool),
+		maxInflight: maxInflight,
+		now:         time.Now,
+		requests:    newRequestLog(),
+	}
+	m.idleSince = m.now()
+	return m
+}
+
+func (m *networkMonitor) started(id network.RequestID) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.inflight[id] = true
+	if len(m.inflight) > m.maxInflight {
+		m.idleSince = time.Time{}
+	}
+}
+
+func (m *networkMonitor) finished(id network.RequestID) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if !m.inflight[id] {
+		return
+	}
+	delete(m.inflight, id)
+	if len(m.inflight) <= m.maxInflight && m.idleSince.IsZero() {
+		m.idleSince = m.now()
+	}
+}
+
+// received adds n bytes of a finished request to loaded ones.
+func (m *networkMonitor) received(n float64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.loaded += int64(n)
+}
+
+// takeLoaded returns bytes loaded since the last call.
+func (m *networkMonitor) takeLoaded() int64 {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	n := m.loaded
+	m.loaded = 0
+	return n
+}
+
+// idle reports whether no more than maxInflight requests have been in flight for quiet period.
+func (m *networkMonitor) idle(quiet time.Duration) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return !m.idleSince.IsZero() && m.now().Sub(m.idleSince) >= quiet
+}
+
+// wait blocks until network is idle for quiet period, timeout expires or ctx is done.
+// It returns false if network has not become idle.
+func (m *networkMonitor) wait(ctx context.Context, quiet, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for !m.idle(quiet) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline:
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// startNetworkMonitor starts counting requests sent by Chrome page and collecting failed ones.
+// Monitoring stops when ctx is done.
+func (f *ChromeFetcher) startNetworkMonitor(ctx context.Context) error {
+	m := newNetworkMonitor(f.opts.idleConnections)
+	sent, err := f.cdpClient.Network.RequestWillBeSent(ctx)
+	if err != nil {
+		return err
+	}
+	finished, err := f.cdpClient.Network.LoadingFinished(ctx)
+	if err != nil {
+		sent.Close()
+		return err
+	}
+	failed, err := f.cdpClient.Network.LoadingFailed(ctx)
+	if err != nil {
+		sent.Close()
+		finished.Close()
+		return err
+	}
+	received, err := f.cdpClient.Network.ResponseReceived(ctx)
+	if err != nil {
+		sent.Close()
+		finished.Close()
+		failed.Close()
+		return err
+	}
+	go func() {
+		defer sent.Close()
+		for {
+			ev, err := sent.Recv()
+			if err != nil {
+				return
+			}
+			m.started(ev.RequestID)
+			m.requests.started(ev.RequestID, ev.Request.URL, string(ev.Type))
+		}
+	}()
+	go func() {
+		defer received.Close()
+		for {
+			ev, err := received.Recv()
+			if err != nil {
+				return
+			}
+			m.requests.responded(ev.RequestID, ev.Response.URL, string(ev.Type), ev.Response.Status)
+		}
+	}()
+	go func() {
+		defer finished.Close()
+		for {
+			ev, err := finished.Recv()
+			if err != nil {
+				return
+			}
+			m.finished(ev.RequestID)
+			m.received(ev.EncodedDataLength)
+			m.requests.finished(ev.RequestID)
+		}
+	}()
+	go func() {
+		defer failed.Close()
+		for {
+			ev, err := failed.Recv()
+			if err != nil {
+				return
+			}
+			m.finished(ev.RequestID)
+			m.requests.loadingFailed(ev.RequestID, string(ev.Type), ev.ErrorText, string(ev.BlockedReason), ev.Canceled != nil && *ev.Canceled)
+		}
+	}()
+	f.network = m
+	return nil
+}
+
+// waitNetworkIdle waits until the page loaded into Chrome stops loading resources.
+func (f *ChromeFetcher) waitNetworkIdle(ctx context.Context) {
+	if f.network == nil {
+		return
+	}
+	if !f.network.wait(ctx, f.opts.idleTime, networkIdleTimeout) {
+		logger.Info("Network has not become idle")
+	}
+}
+
+// throttle makes Chrome load resources of the tab no faster than the job rate of the bandwidth.
+// Bytes loaded by the tab are accounted when the fetch is done, see accountLoaded.
+func (f *ChromeFetcher) throttle(ctx context.Context) error {
+	b := f.opts.bandwidth
+	if b == nil || b.stats.MaxJobRate <= 0 {
+		return nil
+	}
+	return f.cdpClient.Network.EmulateNetworkConditions(ctx, network.NewEmulateNetworkConditionsArgs(false, 0, float64(b.stats.MaxJobRate), -1))
+}
+
+// accountLoaded accounts bytes loaded by the tab for job and holds the fetch back while global or job rate is exceeded.
+func (f *ChromeFetcher) accountLoaded(job string) {
+	if f.network == nil {
+		return
+	}
+	f.opts.bandwidth.account(job, f.network.takeLoaded())
+}
+
+// failedRequests returns failed requests of the page encoded as FailedRequestsHeader.
+func (f *ChromeFetcher) failedRequests() http.Header {
+	if f.network == nil {
+		return nil
+	}
+	return f.network.requests.header()
+}
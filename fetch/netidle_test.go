@@ -0,0 +1,53 @@
+package fetch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkMonitor(t *testing.T) {
+	now := time.Now()
+	m := newNetworkMonitor(1)
+	m.now = func() time.Time { return now }
+	m.idleSince = now
+
+	assert.False(t, m.idle(time.Second), "quiet period has not passed yet")
+	now = now.Add(time.Second)
+	assert.True(t, m.idle(time.Second))
+
+	m.started(network.RequestID("1"))
+	assert.True(t, m.idle(time.Second), "one in-flight request is allowed")
+	m.started(network.RequestID("2"))
+	assert.False(t, m.idle(0), "network is busy")
+
+	now = now.Add(time.Second)
+	m.finished(network.RequestID("3"))
+	assert.False(t, m.idle(0), "unknown request does not change state")
+	m.finished(network.RequestID("2"))
+	assert.True(t, m.idle(0))
+	assert.False(t, m.idle(time.Second))
+	now = now.Add(time.Second)
+	assert.True(t, m.idle(time.Second))
+}
+
+func TestNetworkMonitorWait(t *testing.T) {
+	m := newNetworkMonitor(0)
+	assert.True(t, m.wait(context.Background(), 0, time.Second))
+
+	m.started(network.RequestID("1"))
+	assert.False(t, m.wait(context.Background(), 0, 100*time.Millisecond), "timeout expires")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, m.wait(ctx, 0, time.Second), "context is done")
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		m.finished(network.RequestID("1"))
+	}()
+	assert.True(t, m.wait(context.Background(), 0, time.Second))
+}
@@ -0,0 +1,138 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// Recording is a user flow recorded by Chrome DevTools Recorder and exported as JSON.
+// It is converted to navigation Steps of Chrome fetcher, so complex flows may be captured by a human once
+// and replayed headlessly. navigate, click, doubleClick and change steps are replayed.
+// change steps are collected into FillForm submitted by the following click or Enter key.
+// Other steps like setViewport, scroll, hover or waitForElement are skipped.
+type Recording struct {
+	Title string         `json:"title,omitempty"`
+	Steps []RecordedStep `json:"steps"`
+}
+
+// RecordedStep is a step of Recording.
+type RecordedStep struct {
+	Type string `json:"type"`
+	// URL is loaded by navigate step.
+	URL string `json:"url,omitempty"`
+	// Selectors are alternative selectors of the target element. Only CSS selectors are used.
+	Selectors []RecordedSelector `json:"selectors,omitempty"`
+	// Value is entered by change step.
+	Value string `json:"value,omitempty"`
+	// Key is pressed by keyDown step.
+	Key string `json:"key,omitempty"`
+}
+
+// RecordedSelector is a chain of selectors piercing frames and shadow roots. It is exported either as an array or as a string.
+type RecordedSelector []string
+
+// UnmarshalJSON decodes selector exported as a string or an array.
+func (s *RecordedSelector) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = RecordedSelector{one}
+		return nil
+	}
+	var chain []string
+	if err := json.Unmarshal(data, &chain); err != nil {
+		return err
+	}
+	*s = chain
+	return nil
+}
+
+// recordedSelectorPrefixes mark selectors which are not CSS.
+var recordedSelectorPrefixes = []string{"aria/", "xpath/", "text/", "pierce/"}
+
+// css returns the first CSS selector of the step target. Selectors inside frames or shadow roots are not used.
+func (step RecordedStep) css() string {
+	for _, s := range step.Selectors {
+		if len(s) != 1 || s[0] == "" {
+			continue
+		}
+		css := true
+		for _, prefix := range recordedSelectorPrefixes {
+			if strings.HasPrefix(s[0], prefix) {
+				css = false
+				break
+			}
+		}
+		if css {
+			return s[0]
+		}
+	}
+	return ""
+}
+
+// Apply returns req with steps of the recording. Request URL is taken from the first navigate step if it is empty.
+// Recorded steps follow steps of req, which is fetched by Chrome fetcher.
+func (r *Recording) Apply(req Request) (Request, error) {
+	if r == nil {
+		return req, nil
+	}
+	steps := []Step{}
+	var form *FillForm
+	for i, step := range r.Steps {
+		bad := func(text string) error {
+			return errs.BadPayload{ErrText: fmt.Sprintf("recorded step %d (%s): %s", i+1, step.Type, text)}
+		}
+		switch step.Type {
+		case "navigate":
+			if form != nil {
+				return req, bad("form fields are not submitted")
+			}
+			if step.URL == "" {
+				return req, bad("url required")
+			}
+			if len(steps) == 0 && len(req.Steps) == 0 && (req.URL == "" || req.URL == step.URL) {
+				req.URL = step.URL
+				continue
+			}
+			steps = append(steps, Step{Navigate: step.URL})
+		case "click", "doubleClick":
+			selector := step.css()
+			if selector == "" {
+				return req, bad("CSS selector required")
+			}
+			if form != nil {
+				form.Submit = selector
+				steps = append(steps, Step{FillForm: form})
+				form = nil
+				continue
+			}
+			steps = append(steps, Step{Click: selector})
+		case "change":
+			selector := step.css()
+			if selector == "" {
+				return req, bad("CSS selector required")
+			}
+			if form == nil {
+				form = &FillForm{}
+			}
+			form.Fields = append(form.Fields, FormField{Selector: selector, Value: step.Value})
+		case "keyDown":
+			//Enter submits the form from the last field
+			if step.Key == "Enter" && form != nil {
+				steps = append(steps, Step{FillForm: form})
+				form = nil
+			}
+		}
+	}
+	if form != nil {
+		return req, errs.BadPayload{ErrText: "recorded form fields are not submitted"}
+	}
+	if req.URL == "" {
+		return req, errs.BadPayload{ErrText: "recording has no navigate step"}
+	}
+	req.Type = "chrome"
+	req.Steps = append(append([]Step{}, req.Steps...), steps...)
+	return req, validateSteps(req.Steps)
+}
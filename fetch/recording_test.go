@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const recorded = `{
+  "title": "Search phones",
+  "steps": [
+    {"type": "setViewport", "width": 1280, "height": 800},
+    {"type": "navigate", "url": "https://example.com/", "assertedEvents": [{"type": "navigation"}]},
+    {"type": "click", "selectors": [["aria/Accept"], ["#accept-cookies"]], "offsetX": 10, "offsetY": 5},
+    {"type": "change", "value": "phone", "selectors": [["aria/Search"], ["pierce/#q"], ["#q"]]},
+    {"type": "change", "value": "new", "selectors": ["select[name=condition]"]},
+    {"type": "keyDown", "key": "Enter"},
+    {"type": "keyUp", "key": "Enter"},
+    {"type": "scroll", "x": 0, "y": 600},
+    {"type": "click", "selectors": [["xpath///a[1]"], [".result a"]]},
+    {"type": "navigate", "url": "https://example.com/cart"}
+  ]
+}`
+
+func TestRecording(t *testing.T) {
+	r := &Recording{}
+	assert.NoError(t, json.Unmarshal([]byte(recorded), r))
+	req, err := r.Apply(Request{Headers: map[string]string{"Accept-Language": "en"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "chrome", req.Type)
+	assert.Equal(t, "https://example.com/", req.URL)
+	assert.Equal(t, "en", req.Headers["Accept-Language"])
+	assert.Equal(t, []Step{
+		{Click: "#accept-cookies"},
+		{FillForm: &FillForm{Fields: []FormField{{Selector: "#q", Value: "phone"}, {Selector: "select[name=condition]", Value: "new"}}}},
+		{Click: ".result a"},
+		{Navigate: "https://example.com/cart"},
+	}, req.Steps)
+
+	//form fields submitted by click
+	r = &Recording{Steps: []RecordedStep{
+		{Type: "navigate", URL: "https://example.com/login"},
+		{Type: "change", Value: "user", Selectors: []RecordedSelector{{"#user"}}},
+		{Type: "click", Selectors: []RecordedSelector{{"button[type=submit]"}}},
+	}}
+	req, err = r.Apply(Request{URL: "https://example.com/", Steps: []Step{{Click: "#accept-cookies"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", req.URL)
+	assert.Equal(t, []Step{
+		{Click: "#accept-cookies"},
+		{Navigate: "https://example.com/login"},
+		{FillForm: &FillForm{Fields: []FormField{{Selector: "#user", Value: "user"}}, Submit: "button[type=submit]"}},
+	}, req.Steps)
+
+	var none *Recording
+	req, err = none.Apply(Request{URL: "https://example.com/"})
+	assert.NoError(t, err)
+	assert.Equal(t, Request{URL: "https://example.com/"}, req)
+
+	_, err = (&Recording{Steps: []RecordedStep{{Type: "click", Selectors: []RecordedSelector{{"a"}}}}}).Apply(Request{})
+	assert.Error(t, err, "no URL")
+	_, err = (&Recording{Steps: []RecordedStep{{Type: "navigate", URL: "https://example.com/"}, {Type: "click", Selectors: []RecordedSelector{{"aria/Buy"}}}}}).Apply(Request{})
+	assert.Error(t, err, "no CSS selector")
+	_, err = (&Recording{Steps: []RecordedStep{{Type: "navigate", URL: "https://example.com/"}, {Type: "change", Value: "a", Selectors: []RecordedSelector{{"#q"}}}}}).Apply(Request{})
+	assert.Error(t, err, "form is not submitted")
+}
@@ -16,7 +16,10 @@ func isRobotsTxt(url string) bool {
 
 //fetchRobots is used for getting robots.txt files.
 func fetchRobots(req Request) (*http.Response, error) {
-	fetcher := newBaseFetcher()
+	fetcher, err := newBaseFetcher()
+	if err != nil {
+		return nil, err
+	}
 	return fetcher.response(req)
 }
 
@@ -73,7 +76,7 @@ func AllowedByRobots(rawurl string, robotsData *robotstxt.RobotsData) bool {
 	return robotsData.TestAgent(parsedURL.Path, "Dataflow Kit")
 }
 
-//getCrawlDelay retrieves Crawl-delay directive from robots.txt. Crawl-delay is not in the standard robots.txt protocol, and according to Wikipedia, some bots have different interpretations for this value. That's why maybe many websites don't even bother defining the rate limits in robots.txt. Crawl-delay value does not have an effect on delays between consecutive requests to the same domain for the moment. FetchDelay and RandomizeFetchDelay from ScrapeOptions are used for throttling a crawler speed.
+//GetCrawlDelay retrieves Crawl-delay directive from robots.txt. Crawl-delay is not in the standard robots.txt protocol, and according to Wikipedia, some bots have different interpretations for this value. That's why maybe many websites don't even bother defining the rate limits in robots.txt. Crawl-delay value is used as a minimal interval between consecutive requests to the same host. FetchDelay and RandomizeFetchDelay from ScrapeOptions are applied in addition to it.
 func GetCrawlDelay(r *robotstxt.RobotsData) time.Duration {
 	if r != nil {
 		group := r.FindGroup("Dataflow Kit")
@@ -81,3 +84,11 @@ func GetCrawlDelay(r *robotstxt.RobotsData) time.Duration {
 	}
 	return 0
 }
+
+//GetSitemaps returns URLs listed in Sitemap directives of robots.txt.
+func GetSitemaps(r *robotstxt.RobotsData) []string {
+	if r != nil {
+		return r.Sitemaps
+	}
+	return nil
+}
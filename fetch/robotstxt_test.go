@@ -15,6 +15,14 @@ func TestIsRobotsTxt(t *testing.T) {
 
 }
 
+func TestGetSitemaps(t *testing.T) {
+	robots, err := robotstxt.FromString("User-agent: *\nCrawl-delay: 2\nSitemap: http://example.com/sitemap.xml\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/sitemap.xml"}, GetSitemaps(robots))
+	assert.Equal(t, 2*time.Second, GetCrawlDelay(robots))
+	assert.Nil(t, GetSitemaps(nil))
+}
+
 func TestRobotstxtData(t *testing.T) {
 	addr := "localhost:12345"
 	//test AllowedByRobots func
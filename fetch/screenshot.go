@@ -0,0 +1,183 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"sync"
+
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/segmentio/ksuid"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// maxScreenshotRuns is the number of the latest screenshots kept for every URL.
+const maxScreenshotRuns = 50
+
+// screenshotsMx serializes screenshot index updates of the process. Replicas sharing the storage
+// update the index of a URL holding its lease.
+var screenshotsMx sync.Mutex
+
+// ScreenshotDiffRequest specifies two screenshot runs of the same URL to be compared.
+// If runs are omitted the two latest runs are compared.
+type ScreenshotDiffRequest struct {
+	URL  string `json:"url"`
+	RunA string `json:"runA,omitempty"`
+	RunB string `json:"runB,omitempty"`
+}
+
+// ScreenshotDiff is a result of screenshots comparison.
+type ScreenshotDiff struct {
+	URL  string `json:"url"`
+	RunA string `json:"runA"`
+	RunB string `json:"runB"`
+	//Score is a perceptual difference between screenshots in range [0, 1]. 0 means no visible changes.
+	Score float64 `json:"score"`
+	//Changed is true if Score exceeds SCREENSHOT_DIFF_THRESHOLD.
+	//Layout changes often precede selector breakage.
+	Changed bool `json:"changed"`
+}
+
+// screenshotIndexKey returns a key of the record listing screenshot runs of specified URL.
+func screenshotIndexKey(url string) string {
+	return "screenshots-" + hex.EncodeToString(utils.GenerateMD5([]byte(url)))
+}
+
+func screenshotKey(url, runID string) string {
+	return screenshotIndexKey(url) + "-" + runID
+}
+
+// captureScreenshot takes a screenshot of the page loaded into Chrome and saves it as a new run of specified URL.
+func (f *ChromeFetcher) captureScreenshot(ctx context.Context, url string) error {
+	shot, err := f.cdpClient.Page.CaptureScreenshot(ctx, page.NewCaptureScreenshotArgs().SetFormat("png"))
+	if err != nil {
+		return err
+	}
+	s, err := f.opts.store()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	_, err = storeScreenshot(s, url, shot.Data)
+	return err
+}
+
+// storeScreenshot writes PNG screenshot to storage s and returns its run ID.
+// Only maxScreenshotRuns latest runs are kept.
+func storeScreenshot(s storage.Store, url string, data []byte) (string, error) {
+	screenshotsMx.Lock()
+	defer screenshotsMx.Unlock()
+	unlock, err := storage.Lock(s, screenshotIndexKey(url))
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	runs, err := screenshotRuns(s, url)
+	if err != nil {
+		return "", err
+	}
+	//ksuid values are sortable by creation time
+	runID := ksuid.New().String()
+	err = s.Write(storage.Record{
+		Type:    storage.BINARY,
+		Key:     screenshotKey(url, runID),
+		Value:   data,
+		ExpTime: 0,
+	})
+	if err != nil {
+		return "", err
+	}
+	runs = append(runs, runID)
+	for len(runs) > maxScreenshotRuns {
+		err = s.Delete(storage.Record{Type: storage.BINARY, Key: screenshotKey(url, runs[0])})
+		if err != nil {
+			logger.Warn(err.Error())
+		}
+		runs = runs[1:]
+	}
+	index, err := json.Marshal(runs)
+	if err != nil {
+		return "", err
+	}
+	err = s.Write(storage.Record{
+		Type:    storage.INTERMEDIATE,
+		Key:     screenshotIndexKey(url),
+		Value:   index,
+		ExpTime: 0,
+	})
+	if err != nil {
+		return "", err
+	}
+	return runID, nil
+}
+
+// screenshotRuns returns IDs of screenshot runs of specified URL ordered by time.
+func screenshotRuns(s storage.Store, url string) ([]string, error) {
+	runs := []string{}
+	rec := storage.Record{Type: storage.INTERMEDIATE, Key: screenshotIndexKey(url)}
+	if !s.IsExists(rec) {
+		return runs, nil
+	}
+	index, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(index, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// DiffScreenshots computes perceptual difference between two screenshot runs of the same URL.
+func DiffScreenshots(req ScreenshotDiffRequest) (*ScreenshotDiff, error) {
+	if req.URL == "" {
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	if req.RunA == "" || req.RunB == "" {
+		runs, err := screenshotRuns(s, req.URL)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) < 2 {
+			return nil, errs.StatusError{
+				Code: http.StatusNotFound,
+				Err:  fmt.Errorf("at least two screenshot runs required for %s", req.URL),
+			}
+		}
+		req.RunA, req.RunB = runs[len(runs)-2], runs[len(runs)-1]
+	}
+	a, err := s.Read(storage.Record{Type: storage.BINARY, Key: screenshotKey(req.URL, req.RunA)})
+	if err != nil {
+		return nil, errs.StatusError{Code: http.StatusNotFound, Err: errors.New("screenshot run " + req.RunA + " not found")}
+	}
+	b, err := s.Read(storage.Record{Type: storage.BINARY, Key: screenshotKey(req.URL, req.RunB)})
+	if err != nil {
+		return nil, errs.StatusError{Code: http.StatusNotFound, Err: errors.New("screenshot run " + req.RunB + " not found")}
+	}
+	imgA, err := png.Decode(bytes.NewReader(a))
+	if err != nil {
+		return nil, err
+	}
+	imgB, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	score := diffImages(imgA, imgB)
+	return &ScreenshotDiff{
+		URL:     req.URL,
+		RunA:    req.RunA,
+		RunB:    req.RunB,
+		Score:   score,
+		Changed: score > viper.GetFloat64("SCREENSHOT_DIFF_THRESHOLD"),
+	}, nil
+}
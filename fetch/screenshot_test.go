@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// testScreenshot draws white page of specified size with black block at (x, y).
+func testScreenshot(width, height, x, y int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width; i++ {
+		for j := 0; j < height; j++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if i >= x && i < x+width/4 && j >= y && j < y+height/4 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(i, j, c)
+		}
+	}
+	return img
+}
+
+func TestDiffImages(t *testing.T) {
+	a := testScreenshot(200, 100, 0, 0)
+	assert.Equal(t, 0.0, diffImages(a, testScreenshot(200, 100, 0, 0)))
+	//block moved
+	moved := diffImages(a, testScreenshot(200, 100, 100, 50))
+	assert.True(t, moved > 0.1, "layout change detected")
+	//page height changed
+	assert.True(t, diffImages(a, testScreenshot(200, 150, 0, 0)) > 0.3)
+}
+
+func TestDiffScreenshots(t *testing.T) {
+	viper.Set("SCREENSHOT_DIFF_THRESHOLD", 0.05)
+	url := "http://example.com/screenshot"
+	_, err := DiffScreenshots(ScreenshotDiffRequest{URL: url})
+	assert.Error(t, err, "at least two runs required")
+
+	runs := []string{}
+	for _, img := range []image.Image{
+		testScreenshot(200, 100, 0, 0),
+		testScreenshot(200, 100, 0, 0),
+		testScreenshot(200, 100, 100, 50),
+	} {
+		buf := bytes.Buffer{}
+		assert.NoError(t, png.Encode(&buf, img))
+		runID, err := storeScreenshot(st, url, buf.Bytes())
+		assert.NoError(t, err)
+		runs = append(runs, runID)
+	}
+	diff, err := DiffScreenshots(ScreenshotDiffRequest{URL: url, RunA: runs[0], RunB: runs[1]})
+	assert.NoError(t, err)
+	assert.False(t, diff.Changed)
+
+	//latest runs are compared by default
+	diff, err = DiffScreenshots(ScreenshotDiffRequest{URL: url})
+	assert.NoError(t, err)
+	assert.Equal(t, runs[1], diff.RunA)
+	assert.Equal(t, runs[2], diff.RunB)
+	assert.True(t, diff.Changed)
+
+	_, err = DiffScreenshots(ScreenshotDiffRequest{URL: url, RunA: runs[0], RunB: "invalid"})
+	assert.Error(t, err)
+	_, err = DiffScreenshots(ScreenshotDiffRequest{})
+	assert.Error(t, err)
+	for _, runID := range runs {
+		st.Delete(storage.Record{Type: storage.BINARY, Key: screenshotKey(url, runID)})
+	}
+	st.Delete(storage.Record{Type: storage.INTERMEDIATE, Key: screenshotIndexKey(url)})
+}
+
+func TestScreenshotIndexKey(t *testing.T) {
+	//URLs with the same CRC32 checksum don't share screenshot history
+	assert.NotEqual(t, screenshotIndexKey("plumless"), screenshotIndexKey("buckeroo"))
+	assert.Equal(t, screenshotIndexKey("http://example.com"), screenshotIndexKey("http://example.com"))
+}
+
+func TestStoreScreenshot_concurrent(t *testing.T) {
+	url := "http://example.com/concurrent"
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := storeScreenshot(st, url, []byte("png"))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	runs, err := screenshotRuns(st, url)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 10, "no run is lost by concurrent index updates")
+	for _, runID := range runs {
+		st.Delete(storage.Record{Type: storage.BINARY, Key: screenshotKey(url, runID)})
+	}
+	st.Delete(storage.Record{Type: storage.INTERMEDIATE, Key: screenshotIndexKey(url)})
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -46,13 +47,28 @@ func Start(cfg Config) *HTMLServer {
 	defer logger.Sync() // flushes buffer, if any
 
 	var svc Service
-	svc = FetchService{}
+	bandwidth := NewBandwidth(viper.GetInt64("MAX_BANDWIDTH"), viper.GetInt64("MAX_JOB_BANDWIDTH"))
+	svc = FetchService{Bandwidth: bandwidth}
+	//domain stats are counted inside the pool so latency doesn't include time waiting for a slot
+	domainStats := NewDomainStats()
+	svc = DomainStatsMiddleware(domainStats)(svc)
 
 	//svc = RobotsTxtMiddleware()(svc)
+	pool := NewWorkerPool(viper.GetInt("MAX_FETCHES"), viper.GetInt("MAX_CHROME_SESSIONS"),
+		time.Duration(viper.GetInt("FETCH_QUEUE_TIMEOUT"))*time.Second)
+	svc = LimitMiddleware(pool)(svc)
+	if mode := viper.GetString("FIXTURE_MODE"); mode != "" {
+		svc = FixtureMiddleware(mode, viper.GetString("FIXTURE_DIR"))(svc)
+	}
+	svc = applyMiddlewares(svc)
 	svc = LoggingMiddleware(logger)(svc)
 
 	endpoints := endpoints{
-		fetchEndpoint: makeFetchEndpoint(svc),
+		fetchEndpoint:          makeFetchEndpoint(svc),
+		screenshotDiffEndpoint: makeScreenshotDiffEndpoint(),
+		poolStatsEndpoint:      makePoolStatsEndpoint(pool),
+		domainStatsEndpoint:    makeDomainStatsEndpoint(domainStats),
+		bandwidthStatsEndpoint: makeBandwidthStatsEndpoint(bandwidth),
 	}
 
 	r := newHttpHandler(ctx, endpoints)
@@ -2,11 +2,15 @@ package fetch
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -16,8 +20,10 @@ type Service interface {
 	Fetch(req Request) (io.ReadCloser, error)
 }
 
-// FetchService implements service with empty struct
+// FetchService implements Fetch service
 type FetchService struct {
+	//Bandwidth accounts and throttles bytes fetched by the service. Fetches are not throttled if it is nil
+	Bandwidth *Bandwidth
 }
 
 // ServiceMiddleware defines a middleware for a Fetch service
@@ -25,83 +31,105 @@ type ServiceMiddleware func(Service) Service
 
 // Fetch method implements fetching content from web page with Base or Chrome fetcher.
 func (fs FetchService) Fetch(req Request) (io.ReadCloser, error) {
-	var fetcher Fetcher
+	if !validWaitUntil(req.WaitUntil) {
+		return nil, errs.BadPayload{ErrText: "unknown waitUntil value " + req.WaitUntil}
+	}
+	if req.FillForm != nil {
+		if req.Type != "chrome" {
+			return nil, errs.BadPayload{ErrText: "fillForm requires chrome fetcher"}
+		}
+		if err := req.FillForm.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if !validFrames(req.Frames) {
+		return nil, errs.BadPayload{ErrText: "unknown frames value " + req.Frames}
+	}
+	if err := req.Dialogs.validate(); err != nil {
+		return nil, err
+	}
+	if err := req.validateMethod(); err != nil {
+		return nil, err
+	}
+	if err := validateCookies(req.Cookies); err != nil {
+		return nil, err
+	}
+	if req.Download != "" && req.Type != "chrome" {
+		return nil, errs.BadPayload{ErrText: "download requires chrome fetcher"}
+	}
+	if (req.Pause > 0 || len(req.Block) > 0) && req.Type != "chrome" {
+		return nil, errs.BadPayload{ErrText: "pause and block require chrome fetcher"}
+	}
+	if utils.IsTrue(req.AutoConsent) && req.Type != "chrome" {
+		return nil, errs.BadPayload{ErrText: "autoConsent requires chrome fetcher"}
+	}
+	if req.Pause < 0 || time.Duration(req.Pause)*time.Millisecond > maxPause {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("pause must be between 0 and %d ms", maxPause/time.Millisecond)}
+	}
+	if len(req.Steps) > 0 {
+		if req.Type != "chrome" {
+			return nil, errs.BadPayload{ErrText: "steps require chrome fetcher"}
+		}
+		if err := validateSteps(req.Steps); err != nil {
+			return nil, err
+		}
+	}
+	fType := Base
 	switch req.Type {
 	case "chrome":
-		fetcher = newFetcher(Chrome)
-	default:
-		fetcher = newFetcher(Base)
-	}
-	var (
-		//jar     http.CookieJar
-		cookies []byte
-		cArr    []*http.Cookie
-		s       storage.Store
-	)
-
-	/* jarOpts := &cookiejar.Options{PublicSuffixList: publicsuffix.List}
-	jar, err := cookiejar.New(jarOpts)
+		fType = Chrome
+	case "mock":
+		fType = Mock
+	}
+	fetcher, err := newFetcher(fType, WithBandwidth(fs.Bandwidth))
 	if err != nil {
-		logger.Error("failed to create Cookie Jar")
-
-	} */
+		return nil, errs.StatusError{Code: http.StatusInternalServerError, Err: fmt.Errorf("invalid fetcher settings. %s", err.Error())}
+	}
 	u, err := url.Parse(req.getURL())
 	if err != nil {
 		return nil, err
 	}
+	//every request gets its own session, so concurrent requests of different users don't share cookies
+	sess := &session{}
+	var s storage.Store
+	//userID identifies cookies and localStorage of the user
+	userID := req.UserToken
 	if req.UserToken != "" {
+		if userID, err = UserTokenID(req.UserToken); err != nil {
+			return nil, err
+		}
 		storageType := viper.GetString("STORAGE_TYPE")
 		s = storage.NewStore(storageType)
 		defer s.Close()
-		cookies, err = s.Read(storage.Record{
+		cookies, err := s.Read(storage.Record{
 			Type: storage.COOKIES,
-			Key:  req.UserToken + u.Host,
+			Key:  cookiesKey(userID, u.Host),
 		})
 		if err != nil {
 			logger.Warn(err.Error(),
-				zap.String("User Token", req.UserToken))
+				zap.String("User ID", userID))
 
 		}
-		cArr = []*http.Cookie{}
 		if len(cookies) != 0 {
-			err = json.Unmarshal(cookies, &cArr)
-			if err != nil {
+			if err := json.Unmarshal(cookies, &sess.cookies); err != nil {
 				return nil, err
 			}
-
-			/* tempCarr := []*http.Cookie{}
-			for i := 0; i < len(cArr); i++ {
-				c := cArr[i]
-				if u.Host == c.Domain {
-					tempCarr = append(tempCarr, c)
-					cArr = append(cArr[:i], cArr[i+1:]...)
-					i--
-				}
-			}
-			jar.SetCookies(u, tempCarr) */
-			fetcher.setCookies(u, cArr)
 		}
+		loadLocalStorage(s, sess, userID, u)
 	}
-	//fetcher.setCookieJar(jar)
-	res, err := fetcher.Fetch(req)
+	res, err := fetcher.fetch(req, sess)
 	if err != nil {
 		return nil, err
 	}
 	if req.UserToken != "" {
-		//jar = fetcher.getCookieJar()
-		cooks, err := fetcher.getCookies(u)
-		if err != nil {
-			logger.Warn(err.Error())
-			return res, nil
-		}
-		//cArr = append(cArr, cooks...)
-		cookies, err = json.Marshal(cooks)
+		saveLocalStorage(s, sess, userID, u)
+		cookies, err := json.Marshal(sess.cookies)
 		if err != nil {
 			return nil, err
 		}
 		err = s.Write(storage.Record{
 			Type:    storage.COOKIES,
-			Key:     req.UserToken + u.Host,
+			Key:     cookiesKey(userID, u.Host),
 			Value:   cookies,
 			ExpTime: 0,
 		})
@@ -109,7 +137,7 @@ func (fs FetchService) Fetch(req Request) (io.ReadCloser, error) {
 		if err != nil {
 			logger.Warn(
 				"Failed to write cookie. ",
-				zap.String("User Token", req.UserToken),
+				zap.String("User ID", userID),
 				zap.Error(err))
 		}
 	}
@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/slotix/dataflowkit/storage"
+	"go.uber.org/zap"
+)
+
+// session is the state of a web site kept for a user between requests: cookies and localStorage items
+// of the host of request URL. It is passed to fetchers along with the request and updated by them,
+// so a fetcher may serve concurrent requests of different users.
+type session struct {
+	cookies []*http.Cookie
+	//localStorage is kept by Chrome fetcher only, f.e. to stay signed in to single page applications storing tokens there
+	localStorage map[string]string
+}
+
+// cookiesKey returns a storage key of cookies saved for user token and host.
+// Hosts never contain "|", so keys of different tokens and hosts never collide.
+func cookiesKey(userToken, host string) string {
+	return userToken + "|" + host
+}
+
+// localStorageKey returns a storage key of localStorage items saved for user token and host.
+func localStorageKey(userToken, host string) string {
+	return "localStorage-" + cookiesKey(userToken, host)
+}
+
+// loadLocalStorage puts localStorage items saved for user token to sess.
+func loadLocalStorage(s storage.Store, sess *session, userToken string, u *url.URL) {
+	rec := storage.Record{Type: storage.COOKIES, Key: localStorageKey(userToken, u.Host)}
+	if !s.IsExists(rec) {
+		return
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		logger.Warn(err.Error(), zap.String("User Token", userToken))
+		return
+	}
+	items := map[string]string{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		logger.Warn(err.Error(), zap.String("User Token", userToken))
+		return
+	}
+	sess.localStorage = items
+}
+
+// saveLocalStorage saves localStorage items of sess for user token.
+func saveLocalStorage(s storage.Store, sess *session, userToken string, u *url.URL) {
+	if sess.localStorage == nil {
+		return
+	}
+	data, err := json.Marshal(sess.localStorage)
+	if err != nil {
+		return
+	}
+	err = s.Write(storage.Record{
+		Type:    storage.COOKIES,
+		Key:     localStorageKey(userToken, u.Host),
+		Value:   data,
+		ExpTime: 0,
+	})
+	if err != nil {
+		logger.Warn("Failed to write localStorage. ",
+			zap.String("User Token", userToken),
+			zap.Error(err))
+	}
+}
+
+// restoreLocalStorage puts saved localStorage items back before scripts of pages from host are run.
+func (f *ChromeFetcher) restoreLocalStorage(ctx context.Context, host string) error {
+	if len(f.localStorage) == 0 {
+		return nil
+	}
+	items, err := json.Marshal(f.localStorage)
+	if err != nil {
+		return err
+	}
+	hostJSON, _ := json.Marshal(host)
+	src := fmt.Sprintf(`if (location.host === %s) {
+  let items = %s;
+  for (let k in items) { window.localStorage.setItem(k, items[k]); }
+}`, hostJSON, items)
+	_, err = f.cdpClient.Page.AddScriptToEvaluateOnNewDocument(ctx, page.NewAddScriptToEvaluateOnNewDocumentArgs(src))
+	return err
+}
+
+// readLocalStorage reads localStorage items of the page loaded into Chrome.
+func (f *ChromeFetcher) readLocalStorage(ctx context.Context) error {
+	reply, err := f.cdpClient.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(
+		`JSON.stringify(Object.assign({}, window.localStorage))`).SetReturnByValue(true))
+	if err != nil {
+		return err
+	}
+	if reply.ExceptionDetails != nil {
+		return fmt.Errorf("Failed to read localStorage. %s", reply.ExceptionDetails.Text)
+	}
+	var data string
+	if err := json.Unmarshal(reply.Result.Value, &data); err != nil {
+		return err
+	}
+	items := map[string]string{}
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		return err
+	}
+	f.localStorage = items
+	return nil
+}
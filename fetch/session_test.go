@@ -0,0 +1,85 @@
+package fetch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	u, _ := url.Parse("http://example.com/account")
+
+	saveLocalStorage(s, &session{localStorage: map[string]string{"token": "abc"}}, "user", u)
+
+	restored := &session{}
+	loadLocalStorage(s, restored, "user", u)
+	assert.Equal(t, map[string]string{"token": "abc"}, restored.localStorage)
+
+	other := &session{}
+	loadLocalStorage(s, other, "admin", u)
+	assert.Nil(t, other.localStorage)
+
+	//token "user" on host "example.com" is not token "userexample" on host ".com"
+	assert.NotEqual(t, localStorageKey("user", "example.com"), localStorageKey("userexample", ".com"))
+	tld, _ := url.Parse("http://.com/account")
+	loadLocalStorage(s, other, "userexample", tld)
+	assert.Nil(t, other.localStorage)
+}
+
+func TestBaseFetcher_ConcurrentSessions(t *testing.T) {
+	viper.Set("PROXY", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.URL.Query().Get("login"); user != "" {
+			http.SetCookie(w, &http.Cookie{Name: "user", Value: user})
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		if c, err := r.Cookie("user"); err == nil {
+			w.Write([]byte(c.Value))
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := newFetcher(Base)
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			sess := &session{}
+			content, err := fetcher.fetch(Request{URL: ts.URL + "/?login=" + user}, sess)
+			if !assert.NoError(t, err) {
+				return
+			}
+			data, _ := ioutil.ReadAll(content)
+			content.Close()
+			assert.Equal(t, user, string(data), "cookie set by redirect is kept for the request")
+
+			content, err = fetcher.fetch(Request{URL: ts.URL + "/"}, sess)
+			if !assert.NoError(t, err) {
+				return
+			}
+			data, _ = ioutil.ReadAll(content)
+			content.Close()
+			assert.Equal(t, user, string(data), "session cookies are sent")
+		}(fmt.Sprintf("user%d", i))
+	}
+	wg.Wait()
+
+	//Fetch keeps no cookies between requests
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	assert.Empty(t, string(data))
+}
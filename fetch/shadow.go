@@ -0,0 +1,24 @@
+package fetch
+
+import (
+	"context"
+)
+
+// ShadowRootTag is a name of elements holding content of open shadow roots in pages returned by Chrome fetcher.
+// Shadow root is inlined as the first child of its host element.
+const ShadowRootTag = "shadow-root"
+
+// flattenShadowRoots returns HTML of the page loaded into Chrome with open shadow roots inlined.
+// Only the content of the body is returned if bodyOnly is true. Empty string is returned if the page
+// has no shadow roots.
+func (f *ChromeFetcher) flattenShadowRoots(ctx context.Context, bodyOnly bool) (string, error) {
+	script, err := f.opts.script("shadow.js")
+	if err != nil {
+		return "", err
+	}
+	var html string
+	if err := f.evaluate(ctx, script, "flattenShadowRoots", &html, bodyOnly); err != nil {
+		return "", err
+	}
+	return html, nil
+}
@@ -0,0 +1,273 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/sandbox"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxPause limits the time Chrome fetcher waits after the page is loaded.
+const maxPause = 30 * time.Second
+
+// splashScriptTimeout limits execution of Splash Lua scripts converted to requests.
+const splashScriptTimeout = 100 * time.Millisecond
+
+// imagePatterns block images of pages fetched with Splash images=0 argument.
+var imagePatterns = []string{"*.png*", "*.jpg*", "*.jpeg*", "*.gif*", "*.webp*", "*.svg*", "*.ico*"}
+
+// splashFilterName is a valid name of Splash request filter file.
+var splashFilterName = regexp.MustCompile(`^[\w.-]+$`)
+
+// SplashArgs are arguments of Splash render and execute endpoints taken from legacy Splash payloads.
+// url, headers and body arguments are the same as Request fields. Other arguments are ignored.
+// They are decoded along with the request and converted to Chrome fetcher request by ConvertSplash.
+type SplashArgs struct {
+	LuaSource  string  `json:"lua_source,omitempty"`
+	Wait       float64 `json:"wait,omitempty"`
+	Images     *int    `json:"images,omitempty"`
+	Filters    string  `json:"filters,omitempty"`
+	HTTPMethod string  `json:"http_method,omitempty"`
+}
+
+// render reports whether args require the page to be rendered.
+func (a SplashArgs) render() bool {
+	return a.LuaSource != "" || a.Wait > 0 || a.Images != nil || a.Filters != ""
+}
+
+// ConvertSplash converts Splash arguments of the request, so Splash users may migrate without rewriting payloads.
+// wait seconds of render endpoints become Pause, images=0 and resource filters become Block patterns.
+// Filter names refer to Adblock Plus filter files <name>.txt in SPLASH_FILTERS directory like in Splash.
+// lua_source script is run once with splash object recording navigations, waits and settings, see runSplashScript.
+// Splash arguments are cleared. Transports call ConvertSplash once the request is decoded.
+func (r *Request) ConvertSplash() error {
+	args := r.SplashArgs
+	r.SplashArgs = SplashArgs{}
+	if args.HTTPMethod != "" && r.Method == "" {
+		r.Method = strings.ToUpper(args.HTTPMethod)
+	}
+	if !args.render() {
+		return nil
+	}
+	r.Type = "chrome"
+	if args.Images != nil && *args.Images == 0 {
+		r.Block = append(r.Block, imagePatterns...)
+	}
+	for _, name := range strings.Split(args.Filters, ",") {
+		name = strings.TrimSpace(name)
+		//"none" turns default Splash filter off
+		if name == "" || name == "none" {
+			continue
+		}
+		patterns, err := splashFilter(name)
+		if err != nil {
+			return err
+		}
+		r.Block = append(r.Block, patterns...)
+	}
+	if args.LuaSource != "" {
+		//wait is passed to the script like other arguments of Splash execute endpoint
+		return r.runSplashScript(args.LuaSource, args.Wait)
+	}
+	r.Pause += int(args.Wait * 1000)
+	return nil
+}
+
+// splashFilter reads Splash request filter name and converts its rules to Block patterns.
+func splashFilter(name string) ([]string, error) {
+	dir := viper.GetString("SPLASH_FILTERS")
+	if dir == "" {
+		return nil, errs.BadPayload{ErrText: "Splash filters require SPLASH_FILTERS directory"}
+	}
+	if !splashFilterName.MatchString(name) || strings.Trim(name, ".") == "" {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("invalid Splash filter name %q", name)}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, name+".txt"))
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("unknown Splash filter %q", name)}
+	}
+	return adblockPatterns(string(data)), nil
+}
+
+// adblockPatterns converts URL blocking rules of Adblock Plus filter list to Block patterns.
+// Element hiding rules, exceptions and regular expressions are skipped. Rule options are ignored.
+func adblockPatterns(list string) []string {
+	patterns := []string{}
+	for _, rule := range strings.Split(list, "\n") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || strings.HasPrefix(rule, "!") || strings.HasPrefix(rule, "[") ||
+			strings.HasPrefix(rule, "@@") || strings.Contains(rule, "#") {
+			continue
+		}
+		if i := strings.LastIndex(rule, "$"); i >= 0 {
+			rule = rule[:i]
+		}
+		if len(rule) > 1 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+			continue
+		}
+		pattern := strings.Replace(strings.TrimLeft(strings.TrimRight(rule, "|"), "|"), "^", "*", -1)
+		if pattern == "" {
+			continue
+		}
+		//"||" anchors domain name, "|" anchors the beginning of URL
+		if (strings.HasPrefix(rule, "||") || !strings.HasPrefix(rule, "|")) && !strings.HasPrefix(pattern, "*") {
+			pattern = "*" + pattern
+		}
+		if !strings.HasSuffix(rule, "|") && !strings.HasSuffix(pattern, "*") {
+			pattern += "*"
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// splashRecorder converts calls of splash object made by Splash Lua script to the request.
+type splashRecorder struct {
+	req *Request
+	//current is the URL of the page the script is on
+	current string
+}
+
+// runSplashScript runs main function of Splash Lua script in the sandbox once with splash object recording
+// its calls. splash:go navigates, splash:wait adds to Pause, splash:png takes a screenshot,
+// splash:set_user_agent and splash:set_custom_headers set request headers and splash.images_enabled = false blocks images.
+// splash:html, splash:url and other settings are accepted. Other methods are not supported as the script
+// is not run by the browser, so scripts using them should be rewritten with steps.
+func (r *Request) runSplashScript(src string, wait float64) error {
+	bad := func(err error) error {
+		return errs.BadPayload{ErrText: "Splash script: " + err.Error()}
+	}
+	L := sandbox.New()
+	defer L.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), splashScriptTimeout)
+	defer cancel()
+	L.SetContext(sandbox.Guard(ctx, L, len(r.URL)))
+	if err := L.DoString(src); err != nil {
+		return bad(err)
+	}
+	main, ok := L.GetGlobal("main").(*lua.LFunction)
+	if !ok {
+		return bad(fmt.Errorf("main function required"))
+	}
+	rec := &splashRecorder{req: r, current: r.URL}
+	splash := rec.splash(L)
+	args := L.NewTable()
+	args.RawSetString("url", lua.LString(r.URL))
+	args.RawSetString("wait", lua.LNumber(wait))
+	if err := L.CallByParam(lua.P{Fn: main, NRet: 1, Protect: true}, splash, args); err != nil {
+		return bad(err)
+	}
+	if splash.RawGetString("images_enabled") == lua.LFalse {
+		r.Block = append(r.Block, imagePatterns...)
+	}
+	if r.URL == "" {
+		return bad(fmt.Errorf("splash:go is not called"))
+	}
+	return validateSteps(r.Steps)
+}
+
+// splash returns splash object of Splash Lua script.
+func (rec *splashRecorder) splash(L *lua.LState) *lua.LTable {
+	splash := L.NewTable()
+	for name, fn := range map[string]lua.LGFunction{
+		"go":                 rec.goTo,
+		"wait":               rec.wait,
+		"png":                rec.png,
+		"html":               func(L *lua.LState) int { L.Push(lua.LString("")); return 1 },
+		"url":                func(L *lua.LState) int { L.Push(lua.LString(rec.current)); return 1 },
+		"set_user_agent":     rec.setUserAgent,
+		"set_custom_headers": rec.setHeaders,
+	} {
+		splash.RawSetString(name, L.NewFunction(fn))
+	}
+	for name, value := range map[string]lua.LValue{
+		"images_enabled":       lua.LTrue,
+		"js_enabled":           lua.LTrue,
+		"plugins_enabled":      lua.LFalse,
+		"private_mode_enabled": lua.LTrue,
+		"resource_timeout":     lua.LNumber(0),
+	} {
+		splash.RawSetString(name, value)
+	}
+	meta := L.NewTable()
+	meta.RawSetString("__index", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("splash:%s is not supported", L.CheckString(2))
+		return 0
+	}))
+	L.SetMetatable(splash, meta)
+	return splash
+}
+
+// goTo records splash:go(url) or splash:go{url=url}. The first navigation loads the request URL.
+func (rec *splashRecorder) goTo(L *lua.LState) int {
+	var target string
+	if t, ok := L.Get(2).(*lua.LTable); ok {
+		target = lua.LVAsString(t.RawGetString("url"))
+	} else {
+		target = L.CheckString(2)
+	}
+	if rec.current != "" {
+		base, err := url.Parse(rec.current)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+		u, err := base.Parse(target)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+		target = u.String()
+	}
+	if len(rec.req.Steps) == 0 && (rec.req.URL == "" || rec.req.URL == target) {
+		rec.req.URL = target
+	} else {
+		rec.req.Steps = append(rec.req.Steps, Step{Navigate: target})
+	}
+	rec.current = target
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// wait records splash:wait(seconds).
+func (rec *splashRecorder) wait(L *lua.LState) int {
+	rec.req.Pause += int(float64(L.CheckNumber(2)) * 1000)
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// png records splash:png().
+func (rec *splashRecorder) png(L *lua.LState) int {
+	screenshot := true
+	rec.req.Screenshot = &screenshot
+	L.Push(lua.LString(""))
+	return 1
+}
+
+// setUserAgent records splash:set_user_agent(value).
+func (rec *splashRecorder) setUserAgent(L *lua.LState) int {
+	rec.header("User-Agent", L.CheckString(2))
+	return 0
+}
+
+// setHeaders records splash:set_custom_headers(headers).
+func (rec *splashRecorder) setHeaders(L *lua.LState) int {
+	L.CheckTable(2).ForEach(func(k, v lua.LValue) {
+		rec.header(lua.LVAsString(k), lua.LVAsString(v))
+	})
+	return 0
+}
+
+func (rec *splashRecorder) header(name, value string) {
+	if rec.req.Headers == nil {
+		rec.req.Headers = map[string]string{}
+	}
+	rec.req.Headers[name] = value
+}
@@ -0,0 +1,116 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeSplash decodes request the way transports do.
+func decodeSplash(data string) (Request, error) {
+	req := Request{}
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		return req, err
+	}
+	return req, req.ConvertSplash()
+}
+
+func TestRequest_ConvertSplash(t *testing.T) {
+	req, err := decodeSplash(`{"type": "base", "url": "http://example.com", "pause": 500, "block": ["*.css"]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Type: "base", URL: "http://example.com", Pause: 500, Block: []string{"*.css"}}, req)
+
+	//decoding alone doesn't convert Splash arguments
+	req = Request{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"url": "http://example.com", "wait": 1.5, "lua_source": "while true do end"}`), &req))
+	assert.Empty(t, req.Type)
+	assert.Equal(t, 1.5, req.Wait)
+
+	//render endpoint arguments
+	req, err = decodeSplash(`{"url": "http://example.com", "wait": 1.5, "images": 0, "http_method": "post", "body": "q=1", "timeout": 30}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "chrome", req.Type)
+	assert.Equal(t, 1500, req.Pause)
+	assert.Equal(t, imagePatterns, req.Block)
+	assert.Equal(t, "POST", req.HTTPMethod())
+	assert.Equal(t, "q=1", req.Body)
+	assert.Equal(t, SplashArgs{}, req.SplashArgs)
+
+	//method alone doesn't require rendering
+	req, err = decodeSplash(`{"url": "http://example.com", "http_method": "PUT"}`)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Type)
+	assert.Equal(t, "PUT", req.Method)
+}
+
+func TestRequest_SplashScript(t *testing.T) {
+	payload := `{"url": "http://example.com/", "wait": 0.5, "lua_source": ` + jsonString(`
+function main(splash, args)
+  splash:set_user_agent("Mozilla/5.0")
+  splash:set_custom_headers({["Accept-Language"] = "de"})
+  splash.images_enabled = false
+  assert(splash:go(args.url))
+  splash:wait(args.wait)
+  assert(splash:go{url = "search?q=" .. splash:url()})
+  splash:wait(1)
+  return {html = splash:html(), png = splash:png()}
+end`) + `}`
+	req, err := decodeSplash(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "chrome", req.Type)
+	assert.Equal(t, "http://example.com/", req.URL)
+	assert.Equal(t, []Step{{Navigate: "http://example.com/search?q=http://example.com/"}}, req.Steps)
+	assert.Equal(t, 1500, req.Pause)
+	assert.True(t, *req.Screenshot)
+	assert.Equal(t, imagePatterns, req.Block)
+	assert.Equal(t, map[string]string{"User-Agent": "Mozilla/5.0", "Accept-Language": "de"}, req.Headers)
+
+	for _, script := range []string{
+		`function main(splash) splash:go("http://example.com") splash:mouse_click(10, 10) end`,
+		`function main(splash) return splash:html() end`,
+		`function main(splash) while true do end end`,
+		`splash:go("http://example.com")`,
+		`function main(splash`,
+		`function main(splash) local s = string.rep("x", 1e6) while true do s = s .. s end end`,
+		`function main(splash) return string.gsub(string.rep("x", 1e3), "x", string.rep("y", 1e4)) end`,
+	} {
+		_, err := decodeSplash(`{"lua_source": ` + jsonString(script) + `}`)
+		assert.Error(t, err, script)
+	}
+}
+
+func TestRequest_SplashFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filters")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ads.txt"), []byte(`[Adblock Plus 2.0]
+! comment
+||ads.example.com^
+/banner/*$image,third-party
+|http://tracker.example.com/pixel.gif|
+@@||ads.example.com/allowed^
+example.com##.ad
+/ad[0-9]+/
+`), 0644))
+	viper.Set("SPLASH_FILTERS", dir)
+	defer viper.Set("SPLASH_FILTERS", "")
+
+	req, err := decodeSplash(`{"url": "http://example.com", "filters": "none, ads"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*ads.example.com*", "*/banner/*", "http://tracker.example.com/pixel.gif"}, req.Block)
+
+	for _, filters := range []string{"missing", "../ads", ".."} {
+		_, err := decodeSplash(`{"url": "http://example.com", "filters": "` + filters + `"}`)
+		assert.Error(t, err, filters)
+	}
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,179 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// Snapshot attributes. Pages taken at steps marked extract are returned as
+// <div data-step="step name" data-step-url="page URL"> elements.
+const (
+	StepAttr    = "data-step"
+	StepURLAttr = "data-step-url"
+)
+
+// Step is a navigation performed by Chrome fetcher after the request page is loaded.
+// Steps run one after another in the same browser session, so cookies, storage and
+// page state set by previous steps are kept. Exactly one of Navigate, Click or FillForm is required.
+type Step struct {
+	// Name identifies the snapshot of the step. Defaults to the step number starting from 1.
+	Name string `json:"name,omitempty"`
+	// Navigate loads URL. Relative URLs are resolved against the current page.
+	Navigate string `json:"navigate,omitempty"`
+	// Click is a CSS selector of the element clicked, f.e. a link or a button accepting cookies.
+	Click string `json:"click,omitempty"`
+	// FillForm is filled in and submitted on the current page.
+	FillForm *FillForm `json:"fillForm,omitempty"`
+	// Actions are performed after the step the same way as Request.Actions.
+	Actions string `json:"actions,omitempty"`
+	// WaitUntil is a condition to wait for after the step. It may be "load" (default) or "networkidle".
+	WaitUntil string `json:"waitUntil,omitempty"`
+	// Extract takes a snapshot of the page after the step.
+	Extract bool `json:"extract,omitempty"`
+}
+
+// stepSnapshot is the content of a page taken after the step.
+type stepSnapshot struct {
+	Name string
+	URL  string
+	Body string
+}
+
+// validWaitUntil reports whether s is a known wait condition.
+func validWaitUntil(s string) bool {
+	switch strings.ToLower(s) {
+	case "", WaitLoad, WaitNetworkIdle:
+		return true
+	}
+	return false
+}
+
+// validateSteps checks that every step performs exactly one navigation.
+func validateSteps(steps []Step) error {
+	for i, step := range steps {
+		n := 0
+		for _, set := range []bool{step.Navigate != "", step.Click != "", step.FillForm != nil} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return errs.BadPayload{ErrText: "step " + strconv.Itoa(i+1) + " requires one of navigate, click or fillForm"}
+		}
+		if step.FillForm != nil {
+			if err := step.FillForm.validate(); err != nil {
+				return err
+			}
+		}
+		if !validWaitUntil(step.WaitUntil) {
+			return errs.BadPayload{ErrText: "unknown waitUntil value " + step.WaitUntil}
+		}
+	}
+	return nil
+}
+
+// stepsKey returns a string identifying steps in fixture names.
+func stepsKey(steps []Step) string {
+	data, _ := json.Marshal(steps)
+	return string(data)
+}
+
+// runSteps performs steps on the page loaded into Chrome and returns snapshots of steps marked extract.
+// Shadow roots are inlined into snapshots if pierceShadow is true.
+func (f *ChromeFetcher) runSteps(ctx context.Context, steps []Step, pierceShadow bool) ([]stepSnapshot, error) {
+	snapshots := []stepSnapshot{}
+	for i, step := range steps {
+		name := step.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		var err error
+		switch {
+		case step.Navigate != "":
+			err = f.navigateStep(ctx, step.Navigate)
+		case step.Click != "":
+			err = f.waitLoad(ctx, func() error {
+				return f.evaluate(ctx, []byte(clickScript), "click", nil, step.Click)
+			})
+		case step.FillForm != nil:
+			err = f.fillForm(ctx, step.FillForm)
+		}
+		if err != nil {
+			return nil, errs.StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("Step %s failed. %s", name, err.Error())}
+		}
+		if strings.ToLower(step.WaitUntil) == WaitNetworkIdle {
+			f.waitNetworkIdle(ctx)
+		}
+		if err := f.runActions(ctx, step.Actions); err != nil {
+			logger.Warn(err.Error())
+		}
+		if !step.Extract {
+			continue
+		}
+		snapshot := stepSnapshot{Name: name}
+		if err := f.evaluate(ctx, nil, "(() => location.href)", &snapshot.URL); err != nil {
+			return nil, err
+		}
+		if pierceShadow {
+			if snapshot.Body, err = f.flattenShadowRoots(ctx, true); err != nil {
+				logger.Warn("Failed to inline shadow roots. " + err.Error())
+			}
+		}
+		if snapshot.Body == "" {
+			if err := f.evaluate(ctx, nil, "(() => document.body ? document.body.innerHTML : '')", &snapshot.Body); err != nil {
+				return nil, err
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// clickScript clicks the element found by selector. It throws if there is no such element.
+const clickScript = `function click(selector) {
+  let elem = document.querySelector(selector);
+  if (elem === null) {
+    throw new Error('element ' + selector + ' not found');
+  }
+  elem.click();
+}`
+
+// navigateStep loads u resolved against the URL of the current page.
+func (f *ChromeFetcher) navigateStep(ctx context.Context, u string) error {
+	var current string
+	if err := f.evaluate(ctx, nil, "(() => location.href)", &current); err != nil {
+		return err
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return err
+	}
+	ref, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	return f.navigate(ctx, f.cdpClient.Page, "GET", base.ResolveReference(ref).String(), "", "", 60*time.Second)
+}
+
+// stepsPage combines snapshots into a single page. Every snapshot is wrapped into an element marked with step name and URL.
+func stepsPage(snapshots []stepSnapshot) string {
+	var b strings.Builder
+	b.WriteString("<html><head></head><body>")
+	for _, s := range snapshots {
+		b.WriteString(`<div ` + StepAttr + `="` + html.EscapeString(s.Name) + `" ` +
+			StepURLAttr + `="` + html.EscapeString(s.URL) + `">`)
+		b.WriteString(s.Body)
+		b.WriteString("</div>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
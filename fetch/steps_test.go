@@ -0,0 +1,48 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSteps(t *testing.T) {
+	steps := []Step{
+		{Click: "#accept-cookies"},
+		{FillForm: &FillForm{Fields: []FormField{{Selector: "#q", Value: "phone"}}}, WaitUntil: "networkidle"},
+		{Click: ".result a", Extract: true},
+		{Navigate: "/cart", Extract: true},
+	}
+	assert.NoError(t, validateSteps(steps))
+	assert.Error(t, validateSteps([]Step{{}}), "step does nothing")
+	assert.Error(t, validateSteps([]Step{{Click: "a", Navigate: "/"}}), "step does two things")
+	assert.Error(t, validateSteps([]Step{{FillForm: &FillForm{}}}))
+	assert.Error(t, validateSteps([]Step{{Click: "a", WaitUntil: "forever"}}))
+
+	//steps are supported by chrome fetcher only
+	_, err := FetchService{}.Fetch(Request{URL: "http://example.com", Steps: steps})
+	assert.Error(t, err)
+	_, err = FetchService{}.Fetch(Request{Type: "chrome", URL: "http://example.com", Steps: []Step{{}}})
+	assert.Error(t, err)
+
+	req := Request{Type: "chrome", URL: "http://example.com"}
+	withSteps := req
+	withSteps.Steps = steps
+	assert.NotEqual(t, fixtureName(req), fixtureName(withSteps))
+}
+
+func TestStepsPage(t *testing.T) {
+	page := stepsPage([]stepSnapshot{
+		{Name: "search", URL: "http://example.com/search?q=a&p=1", Body: "<p class=\"item\">A</p>"},
+		{Name: "3", URL: "http://example.com/item/1", Body: "<p class=\"item\">B</p>"},
+	})
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	assert.NoError(t, err)
+	steps := doc.Find("div[" + StepAttr + "]")
+	assert.Equal(t, 2, steps.Length())
+	assert.Equal(t, "search", steps.First().AttrOr(StepAttr, ""))
+	assert.Equal(t, "http://example.com/search?q=a&p=1", steps.First().AttrOr(StepURLAttr, ""))
+	assert.Equal(t, "B", steps.Last().Find(".item").Text())
+}
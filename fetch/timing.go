@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TimingHeader carries network timings of the page fetched by Base fetcher.
+// Its value is base64 encoded JSON of Timing.
+const TimingHeader = "X-Fetch-Timing"
+
+// Timing breaks down the time Base fetcher took to get the response of a page in milliseconds,
+// so slow network path may be told from slow target. Timings of redirects are summed up.
+// TLS handshake of connections with TLS fingerprint is not traced.
+type Timing struct {
+	// Reused reports whether the response has been received over a kept-alive connection.
+	// DNS, Connect and TLS are zero then.
+	Reused  bool    `json:"reused"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	TLS     float64 `json:"tls"`
+	// TTFB is the time from the start of the request to the first byte of the response,
+	// including connection setup.
+	TTFB float64 `json:"ttfb"`
+}
+
+// tracer collects Timing of a request with httptrace.
+type tracer struct {
+	mx           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       Timing
+}
+
+// trace returns req traced by t. The request is considered started now.
+func (t *tracer) trace(req *http.Request) *http.Request {
+	t.start = time.Now()
+	ms := func(since time.Time) float64 {
+		return float64(time.Since(since)) / float64(time.Millisecond)
+	}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mx.Lock()
+			t.timing.Reused = info.Reused
+			t.mx.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mx.Lock()
+			t.dnsStart = time.Now()
+			t.mx.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mx.Lock()
+			t.timing.DNS += ms(t.dnsStart)
+			t.mx.Unlock()
+		},
+		//dual stack dialers connect to several addresses at once, the first connection is timed
+		ConnectStart: func(network, addr string) {
+			t.mx.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mx.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mx.Lock()
+			if err == nil && !t.connectStart.IsZero() {
+				t.timing.Connect += ms(t.connectStart)
+				t.connectStart = time.Time{}
+			}
+			t.mx.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mx.Lock()
+			t.tlsStart = time.Now()
+			t.mx.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mx.Lock()
+			t.timing.TLS += ms(t.tlsStart)
+			t.mx.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mx.Lock()
+			t.timing.TTFB = ms(t.start)
+			t.mx.Unlock()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// header returns collected timing encoded as TimingHeader.
+func (t *tracer) header() http.Header {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return encodeHeader(TimingHeader, t.timing)
+}
+
+// ResponseTiming returns network timing passed along with the page fetched by Base fetcher.
+// It returns nil if the page has not been traced.
+func ResponseTiming(rc io.ReadCloser) *Timing {
+	t := &Timing{}
+	if !decodeHeader(rc, TimingHeader, t) {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,41 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseTiming(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>timed</body></html>"))
+	}))
+	defer ts.Close()
+	bf, err := NewBaseFetcher()
+	assert.NoError(t, err)
+
+	content, err := bf.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	ioutil.ReadAll(content)
+	content.Close()
+	timing := ResponseTiming(content)
+	if assert.NotNil(t, timing) {
+		assert.False(t, timing.Reused)
+		assert.True(t, timing.TTFB > 0)
+		assert.True(t, timing.TTFB >= timing.Connect)
+	}
+
+	content, err = bf.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	content.Close()
+	timing = ResponseTiming(content)
+	if assert.NotNil(t, timing) {
+		assert.True(t, timing.Reused)
+		assert.Equal(t, float64(0), timing.Connect)
+	}
+
+	assert.Nil(t, ResponseTiming(ioutil.NopCloser(nil)))
+}
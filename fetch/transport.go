@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
 )
 
 // newHttpHandler mounts all of the service endpoints into an http.Handler.
@@ -20,6 +22,8 @@ func newHttpHandler(ctx context.Context, endpoint endpoints) http.Handler {
 	options := []httptransport.ServerOption{
 		//httptransport.ServerErrorLogger(logger),
 		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(utils.RequestIDFromHTTP),
+		httptransport.ServerAfter(utils.RequestIDToHTTP),
 	}
 	r.Methods("GET").Path("/ping").HandlerFunc(healthCheckHandler)
 	r.Methods("POST").Path("/fetch").Handler(httptransport.NewServer(
@@ -28,6 +32,30 @@ func newHttpHandler(ctx context.Context, endpoint endpoints) http.Handler {
 		encodeFetcherContent,
 		options...,
 	))
+	r.Methods("GET").Path("/pool").Handler(httptransport.NewServer(
+		endpoint.poolStatsEndpoint,
+		decodeEmptyRequest,
+		encodeJSONResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/domains").Handler(httptransport.NewServer(
+		endpoint.domainStatsEndpoint,
+		decodeEmptyRequest,
+		encodeJSONResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/bandwidth").Handler(httptransport.NewServer(
+		endpoint.bandwidthStatsEndpoint,
+		decodeEmptyRequest,
+		encodeJSONResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/screenshots/diff").Handler(httptransport.NewServer(
+		endpoint.screenshotDiffEndpoint,
+		decodeScreenshotDiffRequest,
+		encodeJSONResponse,
+		options...,
+	))
 	return r
 }
 
@@ -37,9 +65,47 @@ func decodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		return nil, err
 	}
+	if err := request.ConvertSplash(); err != nil {
+		return nil, err
+	}
+	request.RequestID = utils.RequestIDFromContext(ctx)
+	request.Job = r.Header.Get(JobHeader)
+	if request.Job == "" {
+		request.Job = clientHost(r)
+	}
 	return request, nil
 }
 
+// clientHost returns the host of the client which sent r.
+func clientHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+//decodeScreenshotDiffRequest decodes ScreenshotDiffRequest
+func decodeScreenshotDiffRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request ScreenshotDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+//decodeEmptyRequest is used for endpoints which don't need request parameters
+func decodeEmptyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+//encodeJSONResponse encodes response as JSON
+func encodeJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	return json.NewEncoder(w).Encode(response)
+}
+
 //EncodeFetcherContent encodes HTML Content returned by fetcher
 func encodeFetcherContent(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	fetcherContent, ok := response.(io.ReadCloser)
@@ -48,7 +114,11 @@ func encodeFetcherContent(ctx context.Context, w http.ResponseWriter, response i
 		encodeError(ctx, e, w)
 		return nil
 	}
+	defer fetcherContent.Close()
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	for k, v := range ResponseHeader(fetcherContent) {
+		w.Header()[k] = v
+	}
 	_, err := io.Copy(w, fetcherContent)
 	if err != nil {
 		encodeError(ctx, err, w)
@@ -57,27 +127,22 @@ func encodeFetcherContent(ctx context.Context, w http.ResponseWriter, response i
 	return nil
 }
 
-// encodeError encodes erroneous responses and writes http status header.
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+// encodeError encodes erroneous responses as errs.Response and writes http status header.
+// Error message contains correlation ID of the request.
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	switch e := err.(type) {
-	case errs.Error:
-		// We can retrieve the status here and write out a specific
-		// HTTP status code.
-		http.Error(w, e.Error(), e.Status())
-	/* case context.DeadlineExceeded:
-	http.Error(w, "Fetch timeout", 400) */
-	default:
-		// Any error types we don't specifically look out for default
-		// to serving a HTTP 500
-		http.Error(w, e.Error(), http.StatusInternalServerError)
-	}
+	utils.RequestIDToHTTP(ctx, w)
+	id := utils.RequestIDFromContext(ctx)
+	errs.NewResponse(err, utils.ErrorWithRequestID(err.Error(), id), id).Write(w)
 }
 
 // endpoints wrapper
 type endpoints struct {
-	fetchEndpoint endpoint.Endpoint
+	fetchEndpoint          endpoint.Endpoint
+	screenshotDiffEndpoint endpoint.Endpoint
+	poolStatsEndpoint      endpoint.Endpoint
+	domainStatsEndpoint    endpoint.Endpoint
+	bandwidthStatsEndpoint endpoint.Endpoint
 }
 
 // MakeFetchEndpoint creates Fetch Endpoint
@@ -87,6 +152,34 @@ func makeFetchEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+// makePoolStatsEndpoint creates Endpoint returning WorkerPool usage counters
+func makePoolStatsEndpoint(pool *WorkerPool) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return pool.Stats(), nil
+	}
+}
+
+// makeDomainStatsEndpoint creates Endpoint returning per-domain fetch counters
+func makeDomainStatsEndpoint(stats *DomainStats) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return stats.Stats(), nil
+	}
+}
+
+// makeBandwidthStatsEndpoint creates Endpoint returning bandwidth counters
+func makeBandwidthStatsEndpoint(b *Bandwidth) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return b.Stats(), nil
+	}
+}
+
+// makeScreenshotDiffEndpoint creates Screenshot Diff Endpoint
+func makeScreenshotDiffEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return DiffScreenshots(request.(ScreenshotDiffRequest))
+	}
+}
+
 //healthCheckHandler is used to check if Fetch service is alive.
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -1,12 +1,16 @@
 package fetch
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +46,29 @@ func TestQuery(t *testing.T) {
 		t.Errorf("query did not hit")
 	}
 }
+
+func TestEncodeFetchError(t *testing.T) {
+	fetchErr := errs.FetchError{
+		URL:      "http://example.com/old",
+		FinalURL: "http://example.com/login",
+		Code:     http.StatusForbidden,
+		Reason:   "403 Forbidden",
+		Body:     "<html>Sign in</html>",
+	}
+	w := httptest.NewRecorder()
+	encodeError(utils.ContextWithRequestID(context.Background(), "req1"), fetchErr, w)
+	resp := w.Result()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode, "the status of the site is kept in the body")
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"))
+
+	//Parse service gets details of failed response
+	_, err := decodeFetcherContent(context.Background(), resp)
+	assert.Equal(t, fetchErr, err)
+	assert.Contains(t, err.Error(), "Final URL: http://example.com/login")
+	assert.Contains(t, err.Error(), "Sign in")
+
+	w = httptest.NewRecorder()
+	encodeError(context.Background(), errs.StatusError{Code: http.StatusNotFound, Err: errs.Cancel{}}, w)
+	_, err = decodeFetcherContent(context.Background(), w.Result())
+	assert.Error(t, err)
+}
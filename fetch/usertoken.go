@@ -0,0 +1,160 @@
+package fetch
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+)
+
+// User tokens issued by IssueUserToken consist of kind, body and HMAC-SHA256 signature of kind and body
+// separated with dots. Body is base64 encoded JSON claims of signed tokens ("s1") or claims encrypted
+// with AES-GCM and USER_TOKEN_KEY for encrypted tokens ("e1").
+const (
+	signedToken    = "s1"
+	encryptedToken = "e1"
+)
+
+// userTokenIDSize is the number of random bytes identifying the user of a token.
+const userTokenIDSize = 16
+
+// UserTokenRequest specifies a user token to be issued.
+type UserTokenRequest struct {
+	//TTL is the number of seconds the token is valid for. Tokens issued with 0 TTL don't expire.
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+// UserToken is a token issued by the service. It is passed as Request.UserToken to keep cookies and sessions.
+type UserToken struct {
+	UserToken string `json:"userToken"`
+	//Expires is unset for tokens which don't expire
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type userTokenClaims struct {
+	ID      string `json:"id"`
+	Issued  int64  `json:"iat"`
+	Expires int64  `json:"exp,omitempty"`
+}
+
+var errInvalidUserToken = errs.StatusError{Code: http.StatusUnauthorized, Err: errors.New("invalid userToken")}
+
+// userTokenSecret returns the key tokens are signed with. Free-form tokens are accepted if it is empty.
+func userTokenSecret() []byte {
+	return []byte(viper.GetString("USER_TOKEN_SECRET"))
+}
+
+// userTokenCipher returns AES-GCM cipher keyed with USER_TOKEN_KEY or nil if tokens are not encrypted.
+func userTokenCipher() (cipher.AEAD, error) {
+	key := viper.GetString("USER_TOKEN_KEY")
+	if key == "" {
+		return nil, nil
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IssueUserToken returns a new user token signed with USER_TOKEN_SECRET. Claims of the token are encrypted
+// if USER_TOKEN_KEY is set, so clients can't learn user ID the cookie jar is stored with.
+func IssueUserToken(req UserTokenRequest) (*UserToken, error) {
+	secret := userTokenSecret()
+	if len(secret) == 0 {
+		return nil, errs.StatusError{Code: http.StatusNotImplemented, Err: errors.New("USER_TOKEN_SECRET is not set")}
+	}
+	if req.TTL < 0 {
+		return nil, errs.BadPayload{ErrText: "ttl must not be negative"}
+	}
+	id := make([]byte, userTokenIDSize)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	claims := userTokenClaims{ID: hex.EncodeToString(id), Issued: now.Unix()}
+	t := &UserToken{}
+	if req.TTL > 0 {
+		expires := now.Add(time.Duration(req.TTL) * time.Second)
+		claims.Expires = expires.Unix()
+		t.Expires = &expires
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	kind := signedToken
+	aead, err := userTokenCipher()
+	if err != nil {
+		return nil, err
+	}
+	if aead != nil {
+		kind = encryptedToken
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		body = aead.Seal(nonce, nonce, body, []byte(kind))
+	}
+	unsigned := kind + "." + base64.RawURLEncoding.EncodeToString(body)
+	t.UserToken = unsigned + "." + base64.RawURLEncoding.EncodeToString(signUserToken(secret, unsigned))
+	return t, nil
+}
+
+func signUserToken(secret []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// UserTokenID verifies user token and returns the ID cookies and localStorage of the user are stored with.
+// If USER_TOKEN_SECRET is not set, tokens are free-form strings used as IDs as they are.
+func UserTokenID(token string) (string, error) {
+	secret := userTokenSecret()
+	if len(secret) == 0 {
+		return token, nil
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || (parts[0] != signedToken && parts[0] != encryptedToken) {
+		return "", errInvalidUserToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, signUserToken(secret, parts[0]+"."+parts[1])) {
+		return "", errInvalidUserToken
+	}
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errInvalidUserToken
+	}
+	if parts[0] == encryptedToken {
+		aead, err := userTokenCipher()
+		if err != nil || aead == nil || len(body) < aead.NonceSize() {
+			return "", errInvalidUserToken
+		}
+		nonce := body[:aead.NonceSize()]
+		if body, err = aead.Open(nil, nonce, body[aead.NonceSize():], []byte(parts[0])); err != nil {
+			return "", errInvalidUserToken
+		}
+	}
+	var claims userTokenClaims
+	if err := json.Unmarshal(body, &claims); err != nil || claims.ID == "" {
+		return "", errInvalidUserToken
+	}
+	if claims.Expires != 0 && time.Now().Unix() >= claims.Expires {
+		return "", errs.StatusError{Code: http.StatusUnauthorized, Err: errors.New("userToken expired")}
+	}
+	return "u" + claims.ID, nil
+}
@@ -0,0 +1,65 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserToken(t *testing.T) {
+	defer viper.Set("USER_TOKEN_SECRET", "")
+	defer viper.Set("USER_TOKEN_KEY", "")
+
+	//free-form tokens
+	viper.Set("USER_TOKEN_SECRET", "")
+	id, err := UserTokenID("any")
+	assert.NoError(t, err)
+	assert.Equal(t, "any", id)
+	_, err = IssueUserToken(UserTokenRequest{})
+	assert.Error(t, err)
+
+	viper.Set("USER_TOKEN_SECRET", "secret")
+	for _, key := range []string{"", "encryption key"} {
+		viper.Set("USER_TOKEN_KEY", key)
+		token, err := IssueUserToken(UserTokenRequest{})
+		assert.NoError(t, err)
+		assert.Nil(t, token.Expires)
+		id, err := UserTokenID(token.UserToken)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, id)
+
+		other, err := IssueUserToken(UserTokenRequest{TTL: 60})
+		assert.NoError(t, err)
+		assert.NotNil(t, other.Expires)
+		otherID, err := UserTokenID(other.UserToken)
+		assert.NoError(t, err)
+		assert.NotEqual(t, id, otherID)
+
+		tampered := token.UserToken[:len(token.UserToken)-2] + "xx"
+		_, err = UserTokenID(tampered)
+		assert.Error(t, err)
+	}
+	assert.True(t, strings.HasPrefix(mustIssue(t).UserToken, encryptedToken+"."))
+
+	_, err = UserTokenID("any")
+	assert.Error(t, err, "free-form tokens are rejected")
+
+	viper.Set("USER_TOKEN_SECRET", "other secret")
+	_, err = UserTokenID(mustIssue(t).UserToken)
+	assert.NoError(t, err)
+	token := mustIssue(t)
+	viper.Set("USER_TOKEN_SECRET", "secret")
+	_, err = UserTokenID(token.UserToken)
+	assert.Error(t, err, "tokens signed with another secret are rejected")
+
+	_, err = IssueUserToken(UserTokenRequest{TTL: -1})
+	assert.Error(t, err)
+}
+
+func mustIssue(t *testing.T) *UserToken {
+	token, err := IssueUserToken(UserTokenRequest{})
+	assert.NoError(t, err)
+	return token
+}
@@ -0,0 +1,160 @@
+// Dataflow kit - lang
+//
+// Copyright © 2017-2018 Slotix s.r.o. <dm@slotix.sk>
+//
+//
+// All rights reserved. Use of this source code is governed
+// by the BSD 3-Clause License license.
+
+// Package lang of the Dataflow kit detects natural language of a text.
+//
+// Detect returns ISO 639-1 language code. Languages with their own script like Greek, Japanese or Thai
+// are detected by script. Languages using Latin and Cyrillic scripts are told apart by the most frequent
+// words and specific letters. Empty string is returned for too short texts and unknown languages.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MinLetters is the minimum number of letters required for detection.
+const MinLetters = 10
+
+// scripts maps languages with their own writing system to Unicode ranges.
+var scripts = []struct {
+	lang  string
+	table *unicode.RangeTable
+}{
+	{"ko", unicode.Hangul},
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"ar", unicode.Arabic},
+	{"th", unicode.Thai},
+	{"hi", unicode.Devanagari},
+	{"ka", unicode.Georgian},
+	{"hy", unicode.Armenian},
+}
+
+type profile struct {
+	words   map[string]bool
+	letters string
+}
+
+func newProfile(words, letters string) profile {
+	p := profile{words: map[string]bool{}, letters: letters}
+	for _, w := range strings.Fields(words) {
+		p.words[w] = true
+	}
+	return p
+}
+
+var latin = map[string]profile{
+	"en": newProfile("the and of to in is that it for was on are with as be this by have from not but or at which you", ""),
+	"de": newProfile("der die und das ist nicht ein eine zu den mit von sich des auf für im dem auch es wird", "äöüß"),
+	"fr": newProfile("le la les et des est une un du pour dans que qui pas sur au avec ce sont plus par", "éèêàùç"),
+	"es": newProfile("el la los las y de que en un una es por con para del se no al lo como más", "ñ¿¡"),
+	"it": newProfile("il la di che e è un una per non sono con del della gli le nel alla anche come", ""),
+	"pt": newProfile("o a os as de que e um uma é do da em para com não por se dos mais", "ãõç"),
+	"nl": newProfile("de het een en van is dat op te in niet zijn met voor die er aan ook", "ĳ"),
+	"sv": newProfile("och att det som en är på för med av den till inte har jag om ett", "åäö"),
+	"pl": newProfile("i w nie na się z do to że jest o jak a ale po są przez dla", "ąęłńśźż"),
+	"tr": newProfile("ve bir bu da de için ile çok ne gibi daha olan var mı ama en kadar", "ğışç"),
+	"cs": newProfile("a se na je že v to s z do jsou jak ale o by pro také jsem", "ěřůčš"),
+}
+
+var cyrillic = map[string]profile{
+	"ru": newProfile("и в не на что я с он как это по но из у за от же все так", "ыэё"),
+	"uk": newProfile("і в не на що я з він як це та але до у за від же все так ми", "іїєґ"),
+	"bg": newProfile("и в не на че е се да за с от са като по това но му ще", "ъ"),
+}
+
+// Detect returns ISO 639-1 code of the language text is written in.
+// Empty string is returned if language is not recognized.
+func Detect(text string) string {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		default:
+			for _, s := range scripts {
+				if unicode.Is(s.table, r) {
+					counts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters < MinLetters {
+		return ""
+	}
+	script, max := "", 0
+	for s, c := range counts {
+		if c > max || (c == max && s < script) {
+			script, max = s, c
+		}
+	}
+	switch script {
+	case "kana":
+		return "ja"
+	case "han":
+		//Japanese texts mix Han characters with kana
+		if counts["kana"]*10 >= counts["han"] {
+			return "ja"
+		}
+		return "zh"
+	case "latin":
+		return byProfile(text, latin)
+	case "cyrillic":
+		return byProfile(text, cyrillic)
+	case "ar":
+		//Persian uses a few letters absent in Arabic
+		if strings.ContainsAny(text, "پچژگ") {
+			return "fa"
+		}
+	}
+	return script
+}
+
+// byProfile returns the language with the highest score of the most frequent words and specific letters.
+// Empty string is returned if there is no single best language.
+func byProfile(text string, profiles map[string]profile) string {
+	text = strings.ToLower(text)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	best, bestScore, tie := "", 0, false
+	for lang, p := range profiles {
+		score := 0
+		for _, w := range words {
+			if p.words[w] {
+				score++
+			}
+		}
+		for _, r := range p.letters {
+			score += strings.Count(text, string(r))
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if tie || bestScore == 0 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,32 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog and runs to the forest.", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund und läuft in den Wald.", "de"},
+		{"Le renard brun rapide saute par-dessus le chien paresseux et court dans la forêt.", "fr"},
+		{"El rápido zorro marrón salta sobre el perro perezoso y corre hacia el bosque.", "es"},
+		{"La volpe marrone veloce salta sopra il cane pigro e corre nella foresta.", "it"},
+		{"A raposa marrom rápida pula sobre o cão preguiçoso e corre para a floresta.", "pt"},
+		{"Быстрая коричневая лиса прыгает через ленивую собаку и бежит в лес.", "ru"},
+		{"Швидка бура лисиця стрибає через ледачого собаку і біжить до лісу.", "uk"},
+		{"Η γρήγορη καφέ αλεπού πηδάει πάνω από τον τεμπέλη σκύλο.", "el"},
+		{"素早い茶色の狐が怠け者の犬を飛び越えて森へ走っていく。", "ja"},
+		{"敏捷的棕色狐狸跳过了懒惰的狗然后跑进森林。", "zh"},
+		{"빠른 갈색 여우가 게으른 개를 뛰어넘어 숲으로 달려간다.", "ko"},
+		{"Hello", ""},
+		{"1234567890 !!! 1234567890", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Detect(tt.text), tt.text)
+	}
+}
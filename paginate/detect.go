@@ -0,0 +1,180 @@
+package paginate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+var (
+	nextTextRe = regexp.MustCompile(`(?i)^(next( page)?|older( posts)?)?\s*[›»>→]*$`)
+	moreTextRe = regexp.MustCompile(`(?i)^((load|show|view|see) more|more results)\b`)
+	currentRe  = regexp.MustCompile(`(?i)(^|[-_ ])(active|current|selected)($|[-_ ])`)
+)
+
+// Detected describes pagination found on a web page.
+type Detected struct {
+	// Type is "next" for next page links, "number" for numbered pagination widgets
+	// and "more" for "Load more" buttons.
+	Type string `json:"type"`
+	// Selector of the next page link, pagination widget or "Load more" button.
+	Selector string `json:"selector"`
+	// Attribute containing the next page URL.
+	Attribute string `json:"attr,omitempty"`
+}
+
+// Detect looks for pagination on a web page. It checks rel=next links, links labelled "next",
+// numbered pagination widgets and "Load more" buttons in that order.
+// Detect returns nil if no pagination is found.
+func Detect(doc *goquery.Selection) *Detected {
+	for _, sel := range []string{"a[rel~=next][href]", "link[rel~=next][href]"} {
+		if doc.Find(sel).Length() > 0 {
+			return &Detected{Type: "next", Selector: sel, Attribute: "href"}
+		}
+	}
+	if d := detectNextLink(doc); d != nil {
+		return d
+	}
+	if d := detectNumbers(doc); d != nil {
+		return d
+	}
+	return detectMore(doc)
+}
+
+// followable returns true if href leads to another page rather than to a script or anchor.
+func followable(s *goquery.Selection) bool {
+	href, _ := s.Attr("href")
+	href = strings.TrimSpace(href)
+	return href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:")
+}
+
+// hasClass returns the first class name of s containing substr.
+func hasClass(s *goquery.Selection, substr string) string {
+	for _, c := range strings.Fields(s.AttrOr("class", "")) {
+		if strings.Contains(strings.ToLower(c), substr) {
+			return c
+		}
+	}
+	return ""
+}
+
+func detectNextLink(doc *goquery.Selection) (d *Detected) {
+	doc.Find("a[href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !followable(s) {
+			return true
+		}
+		text := strings.TrimSpace(s.Text())
+		label := s.AttrOr("aria-label", "")
+		var sel string
+		switch {
+		case hasClass(s, "next") != "":
+			sel = "a." + hasClass(s, "next")
+		case hasClass(s.Parent(), "next") != "":
+			sel = fmt.Sprintf("%s.%s > a", goquery.NodeName(s.Parent()), hasClass(s.Parent(), "next"))
+		case strings.Contains(strings.ToLower(label), "next"):
+			sel = fmt.Sprintf("a[aria-label=%q]", label)
+		case text != "" && nextTextRe.MatchString(text):
+			sel = fmt.Sprintf("a:contains(%q)", text)
+		default:
+			return true
+		}
+		d = &Detected{Type: "next", Selector: sel, Attribute: "href"}
+		return false
+	})
+	return
+}
+
+// pageNumbers returns elements of widget containing page numbers only.
+func pageNumbers(widget *goquery.Selection) *goquery.Selection {
+	return widget.Find("*").FilterFunction(func(i int, s *goquery.Selection) bool {
+		if s.Children().Length() > 0 {
+			return false
+		}
+		_, err := strconv.Atoi(strings.TrimSpace(s.Text()))
+		return err == nil
+	})
+}
+
+func detectNumbers(doc *goquery.Selection) (d *Detected) {
+	doc.Find("nav, ul, ol, div, p").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		links := pageNumbers(s).Filter("a[href]").FilterFunction(func(i int, a *goquery.Selection) bool {
+			return followable(a)
+		})
+		//the innermost element containing all the numbers is the widget
+		if links.Length() < 2 || s.Find("nav, ul, ol, div, p").FilterFunction(func(i int, inner *goquery.Selection) bool {
+			return pageNumbers(inner).Length() == pageNumbers(s).Length()
+		}).Length() > 0 {
+			return true
+		}
+		sel := goquery.NodeName(s)
+		if id := s.AttrOr("id", ""); id != "" {
+			sel = "#" + id
+		} else if c := hasClass(s, "pag"); c != "" {
+			sel += "." + c
+		} else if classes := strings.Fields(s.AttrOr("class", "")); len(classes) > 0 {
+			sel += "." + strings.Join(classes, ".")
+		}
+		d = &Detected{Type: "number", Selector: sel, Attribute: "href"}
+		return false
+	})
+	return
+}
+
+func detectMore(doc *goquery.Selection) (d *Detected) {
+	doc.Find("button, a, [role=button]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := strings.TrimSpace(s.Text())
+		if !moreTextRe.MatchString(text) {
+			return true
+		}
+		sel := fmt.Sprintf("%s:contains(%q)", goquery.NodeName(s), text)
+		if id := s.AttrOr("id", ""); id != "" {
+			sel = "#" + id
+		}
+		d = &Detected{Type: "more", Selector: sel}
+		return false
+	})
+	return
+}
+
+type byNumberPaginator struct {
+	sel string
+}
+
+// ByNumber returns a Paginator that extracts the next page from a numbered pagination widget
+// found by a given CSS selector. Current page is either marked with active/current/selected class
+// or aria-current attribute, or is not a link. The link with the following number is the next page.
+func ByNumber(sel string) Paginator {
+	return &byNumberPaginator{sel}
+}
+
+func (p *byNumberPaginator) NextPage(uri string, doc *goquery.Selection) (string, error) {
+	numbers := pageNumbers(doc.Find(p.sel).First())
+	current := 1
+	numbers.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		marked := false
+		for n := s; n.Length() > 0 && !n.Is(p.sel); n = n.Parent() {
+			if currentRe.MatchString(n.AttrOr("class", "")) || n.AttrOr("aria-current", "") != "" {
+				marked = true
+				break
+			}
+		}
+		if !marked && s.Is("a[href]") {
+			return true
+		}
+		current, _ = strconv.Atoi(strings.TrimSpace(s.Text()))
+		return false
+	})
+	next := numbers.Filter("a[href]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		n, _ := strconv.Atoi(strings.TrimSpace(s.Text()))
+		return n == current+1
+	}).First()
+	val, found := next.Attr("href")
+	if !found {
+		return "", nil
+	}
+	return utils.RelUrl(uri, val)
+}
@@ -0,0 +1,52 @@
+package paginate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		html string
+		want *Detected
+	}{
+		{`<head><link rel="next" href="/page/2"></head>`,
+			&Detected{Type: "next", Selector: "link[rel~=next][href]", Attribute: "href"}},
+		{`<ul class="pager"><li class="previous"><a href="/1">previous</a></li><li class="next"><a href="/3">next</a></li></ul>`,
+			&Detected{Type: "next", Selector: "li.next > a", Attribute: "href"}},
+		{`<a href="/about">About</a><a href="/page/2">Next »</a>`,
+			&Detected{Type: "next", Selector: `a:contains("Next »")`, Attribute: "href"}},
+		{`<div class="footer"><ul class="pagination"><li class="active"><span>1</span></li><li><a href="?p=2">2</a></li><li><a href="?p=3">3</a></li></ul></div>`,
+			&Detected{Type: "number", Selector: "ul.pagination", Attribute: "href"}},
+		{`<div class="items"></div><button id="more" class="btn">Load more</button>`,
+			&Detected{Type: "more", Selector: "#more"}},
+		{`<a href="#">Next</a><a href="/about">About</a>`, nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Detect(selFrom(tt.html)), tt.html)
+	}
+}
+
+func TestByNumber(t *testing.T) {
+	sel := selFrom(`<ul class="pagination">
+		<li><a href="?p=1">1</a></li>
+		<li class="active"><a href="?p=2">2</a></li>
+		<li><a href="?p=3">3</a></li>
+	</ul>`)
+	pg, err := ByNumber("ul.pagination").NextPage("http://example.com/list", sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/list?p=3", pg)
+
+	//current page is not a link
+	sel = selFrom(`<div class="pages"><a href="/1">1</a><b>2</b><a href="/3">3</a></div>`)
+	pg, err = ByNumber("div.pages").NextPage("http://example.com/2", sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/3", pg)
+
+	//last page
+	sel = selFrom(`<div class="pages"><a href="/1">1</a><a href="/2">2</a><span aria-current="page">3</span></div>`)
+	pg, err = ByNumber("div.pages").NextPage("http://example.com/3", sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "", pg)
+}
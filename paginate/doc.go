@@ -18,6 +18,12 @@
 // infinitely - you probably want to specify a maximum number of pages to
 // scrape by using MaxPages parameter of ScrapeOptions.
 //
+// ByNumber returns a Paginator that follows the link next to the current page
+// of a numbered pagination widget.
+//
+// Detect looks for rel=next links, "next" links, numbered pagination widgets
+// and "Load more" buttons on a page which has no paginator specified.
+//
 package paginate
 
 // EOF
@@ -0,0 +1,336 @@
+package parse
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/scrape"
+)
+
+// Role grants access to API endpoints. Every role is granted access of lower roles.
+type Role int
+
+// Roles of API clients
+const (
+	RoleUser Role = iota + 1
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{RoleUser: "user", RoleOperator: "operator", RoleAdmin: "admin"}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole returns Role named s. Names are case insensitive.
+func ParseRole(s string) (Role, error) {
+	for r, name := range roleNames {
+		if strings.EqualFold(s, name) {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", s)
+}
+
+// Principal is an authenticated API client.
+type Principal struct {
+	//Subject identifies the client, f.e. "sub" claim of JWT
+	Subject string
+	//Tenant owns results of parse jobs run by the client
+	Tenant string
+	Role   Role
+	//id identifies the credential the client authenticated with. It is empty for anonymous clients.
+	id string
+}
+
+// APIKey assigns a role and a tenant to an API key sent in TokenHeader or "Authorization: Bearer" header.
+type APIKey struct {
+	Key    string `json:"key"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// LoadAPIKeys reads JSON encoded list of APIKey from file.
+func LoadAPIKeys(file string) ([]APIKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("Cannot read API keys from %s. %s", file, err.Error())
+	}
+	return keys, nil
+}
+
+// accessRule sets the role required for requests with method to paths matching pattern. Empty method matches any.
+type accessRule struct {
+	method  string
+	pattern string
+	role    Role
+}
+
+// accessRules are checked in order and the first matching one applies. Other requests require RoleUser.
+// Payloads and watches saved to the registry are run by the scheduler, so changing them is reserved to operators.
+// So is managing the credentials vault.
+var accessRules = []accessRule{
+	{"", "/cache", RoleAdmin},
+	{"", "/purge", RoleAdmin},
+	{"POST", "/payloads/*/parse", RoleUser},
+	{"POST", "/payloads", RoleOperator},
+	{"PUT", "/payloads/*", RoleOperator},
+	{"DELETE", "/payloads/*", RoleOperator},
+	{"POST", "/watches", RoleOperator},
+	{"PUT", "/watches/*", RoleOperator},
+	{"DELETE", "/watches/*", RoleOperator},
+	{"", "/credentials", RoleOperator},
+	{"", "/credentials/*", RoleOperator},
+}
+
+// publicPaths are served without authentication.
+var publicPaths = []string{"/ping", "/metrics"}
+
+// requiredRole returns the role required for request r.
+func requiredRole(r *http.Request) Role {
+	for _, rule := range accessRules {
+		if rule.method != "" && rule.method != r.Method {
+			continue
+		}
+		if ok, _ := path.Match(rule.pattern, r.URL.Path); ok {
+			return rule.role
+		}
+	}
+	return RoleUser
+}
+
+var errInvalidToken = errors.New("invalid API token")
+
+// accessControl authenticates API clients with API keys or JWTs and authorizes requests by role of the client.
+// Results of parse jobs are available to the tenant which ran the job, operators and admins.
+// If neither API keys nor JWT secret are configured, anonymous clients are granted RoleUser,
+// or RoleOperator if anonymousOperator is set.
+type accessControl struct {
+	keys      map[[sha256.Size]byte]Principal
+	jwtSecret []byte
+	enabled   bool
+	//anonymous is the role of clients without credentials if access control is not enabled
+	anonymous Role
+	now       func() time.Time
+}
+
+// newAccessControl creates accessControl accepting API keys, JWTs signed with jwtSecret
+// and adminToken granting RoleAdmin. anonymousOperator grants RoleOperator to anonymous clients
+// if neither API keys nor JWT secret are configured.
+func newAccessControl(adminToken string, keys []APIKey, jwtSecret string, anonymousOperator bool) (*accessControl, error) {
+	a := &accessControl{
+		keys:      make(map[[sha256.Size]byte]Principal),
+		enabled:   len(keys) > 0 || jwtSecret != "",
+		anonymous: RoleUser,
+		now:       time.Now,
+	}
+	if anonymousOperator {
+		a.anonymous = RoleOperator
+	}
+	if jwtSecret != "" {
+		a.jwtSecret = []byte(jwtSecret)
+	}
+	for i, k := range keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("API key %d is empty", i)
+		}
+		role, err := ParseRole(k.Role)
+		if err != nil {
+			return nil, fmt.Errorf("API key %d. %s", i, err.Error())
+		}
+		sum := sha256.Sum256([]byte(k.Key))
+		a.keys[sum] = Principal{Subject: k.Tenant, Tenant: k.Tenant, Role: role, id: "key:" + hex.EncodeToString(sum[:8])}
+	}
+	if adminToken != "" {
+		a.keys[sha256.Sum256([]byte(adminToken))] = Principal{Subject: "admin", Role: RoleAdmin, id: "admin"}
+	}
+	return a, nil
+}
+
+// Handler wraps next so requests are authenticated and authorized.
+// Authenticated client is passed to next in the request context.
+func (a *accessControl) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range publicPaths {
+			if r.URL.Path == p {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		p, err := a.authenticate(r)
+		anonymous := err != nil || p == nil
+		if anonymous {
+			if a.enabled {
+				if err == nil {
+					err = errors.New("authentication required")
+				}
+				unauthorized(w, err.Error())
+				return
+			}
+			p = &Principal{Role: a.anonymous}
+		}
+		if role := requiredRole(r); p.Role < role {
+			if anonymous {
+				unauthorized(w, role.String()+" role required")
+				return
+			}
+			errs.WriteError(w, http.StatusForbidden, errs.CodeForbidden, role.String()+" role required")
+			return
+		}
+		if id := resultsID(r.URL.Path); id != "" && !canViewResults(*p, id) {
+			errs.WriteError(w, http.StatusForbidden, errs.CodeForbidden, errForeignResults.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(contextWithPrincipal(r.Context(), *p)))
+	})
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="dataflowkit"`)
+	errs.WriteError(w, http.StatusUnauthorized, errs.CodeUnauthorized, msg)
+}
+
+// authenticate returns the client identified by API key or JWT of r. Nil is returned if r has no credentials.
+func (a *accessControl) authenticate(r *http.Request) (*Principal, error) {
+	token := r.Header.Get(TokenHeader)
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return nil, nil
+	}
+	if p, ok := a.keys[sha256.Sum256([]byte(token))]; ok {
+		return &p, nil
+	}
+	if a.jwtSecret != nil && strings.Count(token, ".") == 2 {
+		return a.verifyJWT(token)
+	}
+	return nil, errInvalidToken
+}
+
+// jwtClaims are claims of JWTs accepted by accessControl.
+type jwtClaims struct {
+	Subject string `json:"sub"`
+	Role    string `json:"role"`
+	Tenant  string `json:"tenant"`
+	Expires int64  `json:"exp"`
+}
+
+// verifyJWT checks HS256 signature and expiration of JWT and returns the client it identifies.
+func (a *accessControl) verifyJWT(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errInvalidToken
+	}
+	if claims.Expires != 0 && a.now().Unix() >= claims.Expires {
+		return nil, errors.New("API token expired")
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	return &Principal{Subject: claims.Subject, Tenant: claims.Tenant, Role: role, id: "jwt:" + claims.Tenant + "/" + claims.Subject}, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// resultsID returns results ID of /results/{id} paths.
+func resultsID(p string) string {
+	if !strings.HasPrefix(p, "/results/") {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(p, "/results/"), "/", 2)[0]
+}
+
+// resultsOwners returns tenants owning results
+var resultsOwners = scrape.ResultsOwners
+
+var errForeignResults = errors.New("results belong to another tenant")
+
+// canViewResults reports whether p may access results with id. Operators may access all results.
+// Results stored without owners are available to everyone.
+func canViewResults(p Principal, id string) bool {
+	if p.Role >= RoleOperator {
+		return true
+	}
+	owners, err := resultsOwners(id)
+	if err != nil {
+		return false
+	}
+	if len(owners) == 0 {
+		return true
+	}
+	for _, o := range owners {
+		if o == p.Tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeResults checks access of the client carried by ctx to results with id passed in the request body.
+func authorizeResults(ctx context.Context, id string) error {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	if ok && !canViewResults(p, id) {
+		return errs.StatusError{Code: http.StatusForbidden, Err: errForeignResults}
+	}
+	return nil
+}
+
+type principalKey struct{}
+
+func contextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the client authenticated by Parse service carried by ctx,
+// f.e. to HTTP middlewares registered with RegisterHTTPMiddleware.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// tenantFromContext returns the tenant of authenticated client carried by ctx or empty string.
+func tenantFromContext(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(Principal)
+	return p.Tenant
+}
@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(secret, claims string) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestAccessControl(t *testing.T) {
+	owners := resultsOwners
+	defer func() { resultsOwners = owners }()
+	resultsOwners = func(id string) ([]string, error) {
+		if id == "acme-results" {
+			return []string{"acme"}, nil
+		}
+		return nil, nil
+	}
+
+	var tenant string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = tenantFromContext(r.Context())
+	})
+	do := func(a *accessControl, method, path string, header http.Header) int {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v[0])
+		}
+		rr := httptest.NewRecorder()
+		a.Handler(next).ServeHTTP(rr, req)
+		return rr.Code
+	}
+	token := func(t string) http.Header {
+		return http.Header{TokenHeader: {t}}
+	}
+
+	//access control is not configured
+	a, err := newAccessControl("secret", nil, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, do(a, "GET", "/cache", nil))
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/cache", token("secret")))
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/parse", nil))
+	assert.Equal(t, http.StatusUnauthorized, do(a, "POST", "/payloads", nil))
+	assert.Equal(t, http.StatusUnauthorized, do(a, "PUT", "/credentials/shop", nil))
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/payloads", token("secret")))
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/results/shared-results", token("unknown")), "tokens are used by rate limiter")
+	assert.Equal(t, http.StatusForbidden, do(a, "GET", "/results/acme-results", nil), "anonymous users don't read results of tenants")
+
+	a, err = newAccessControl("secret", nil, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/payloads", nil))
+	assert.Equal(t, http.StatusUnauthorized, do(a, "GET", "/cache", nil))
+
+	a, err = newAccessControl("secret", []APIKey{
+		{Key: "user-key", Role: "user", Tenant: "acme"},
+		{Key: "other-key", Role: "User", Tenant: "globex"},
+		{Key: "operator-key", Role: "operator"},
+	}, "jwt-secret", false)
+	assert.NoError(t, err)
+	a.now = func() time.Time { return time.Unix(1000, 0) }
+
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/ping", nil))
+	assert.Equal(t, http.StatusUnauthorized, do(a, "POST", "/parse", nil))
+	assert.Equal(t, http.StatusUnauthorized, do(a, "POST", "/parse", token("unknown")))
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/parse", token("user-key")))
+	assert.Equal(t, "acme", tenant)
+
+	assert.Equal(t, http.StatusForbidden, do(a, "DELETE", "/cache", token("operator-key")))
+	assert.Equal(t, http.StatusOK, do(a, "DELETE", "/cache", http.Header{"Authorization": {"Bearer secret"}}))
+
+	assert.Equal(t, http.StatusForbidden, do(a, "PUT", "/payloads/books", token("user-key")))
+	assert.Equal(t, http.StatusForbidden, do(a, "POST", "/watches", token("user-key")))
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/payloads/books/parse", token("user-key")))
+	assert.Equal(t, http.StatusOK, do(a, "PUT", "/payloads/books", token("operator-key")))
+
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/results/acme-results/report", token("user-key")))
+	assert.Equal(t, http.StatusForbidden, do(a, "GET", "/results/acme-results/report", token("other-key")))
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/results/acme-results", token("operator-key")))
+	assert.Equal(t, http.StatusOK, do(a, "GET", "/results/shared", token("other-key")))
+
+	bearer := func(jwt string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + jwt}}
+	}
+	jwt := signJWT("jwt-secret", `{"sub":"u1","role":"user","tenant":"globex","exp":2000}`)
+	assert.Equal(t, http.StatusOK, do(a, "POST", "/parse", bearer(jwt)))
+	assert.Equal(t, "globex", tenant)
+	assert.Equal(t, http.StatusForbidden, do(a, "GET", "/results/acme-results", bearer(jwt)))
+	expired := signJWT("jwt-secret", `{"sub":"u1","role":"admin","exp":1000}`)
+	assert.Equal(t, http.StatusUnauthorized, do(a, "GET", "/cache", bearer(expired)))
+	forged := signJWT("other-secret", `{"sub":"u1","role":"admin"}`)
+	assert.Equal(t, http.StatusUnauthorized, do(a, "GET", "/cache", bearer(forged)))
+
+	_, err = newAccessControl("", []APIKey{{Key: "k", Role: "root"}}, "", false)
+	assert.Error(t, err)
+
+	ctx := contextWithPrincipal(context.Background(), Principal{Tenant: "globex", Role: RoleUser})
+	assert.Error(t, authorizeResults(ctx, "acme-results"))
+	assert.NoError(t, authorizeResults(context.Background(), "acme-results"))
+}
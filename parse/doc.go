@@ -8,6 +8,19 @@
 
 // Package parse of the Dataflow kit is used by parse.d service which parses html content from web pages following the rules described in Payload JSON file.
 //
+// Applications embedding parse service add their own middlewares (authentication, quotas, caching)
+// before calling Start, f.e. from init functions. Service middlewares registered with RegisterMiddleware
+// wrap built-in ones, so requests rejected by them are still logged. They embed Passthrough to implement
+// only the methods they handle:
+//
+//	type cache struct{ parse.Passthrough }
+//	func (c cache) Parse(p scrape.Payload) (io.ReadCloser, error) { ...; return c.Service.Parse(p) }
+//	parse.RegisterMiddleware(func(next parse.Service) parse.Service { return cache{parse.Passthrough{next}} })
+//
+// Service middlewares get no HTTP request, so middlewares depending on the client or its tenant are registered
+// with RegisterHTTPMiddleware. They are called after built-in authentication and rate limits and take the client
+// with PrincipalFromContext. Middlewares are applied in the order of registration, so the last registered is called first.
+//
 package parse
 
 // EOF
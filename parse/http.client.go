@@ -9,10 +9,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
 )
 
@@ -44,11 +47,179 @@ func NewHTTPClient(instance string) (Service, error) {
 		).Endpoint()
 	}
 
+	var linksEndpoint endpoint.Endpoint
+	{
+		linksEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/links"),
+			encodeParseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var resultsEndpoint endpoint.Endpoint
+	{
+		resultsEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/results"),
+			encodeResultsRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var snapshotsEndpoint endpoint.Endpoint
+	{
+		snapshotsEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/results"),
+			encodeSnapshotsRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var reparseEndpoint endpoint.Endpoint
+	{
+		reparseEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/results"),
+			encodeReparseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var reportEndpoint endpoint.Endpoint
+	{
+		reportEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/results"),
+			encodeReportRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var issueUserTokenEndpoint endpoint.Endpoint
+	{
+		issueUserTokenEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/tokens"),
+			encodeParseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var purgeEndpoint endpoint.Endpoint
+	{
+		purgeEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/purge"),
+			encodePurgeRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var queryEndpoint endpoint.Endpoint
+	{
+		queryEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/query"),
+			encodeParseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var suggestEndpoint endpoint.Endpoint
+	{
+		suggestEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/suggest"),
+			encodeParseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	var autoEndpoint endpoint.Endpoint
+	{
+		autoEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/auto"),
+			encodeParseRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+
+	// payload registry endpoints
+	payloadClient := func(method, suffix string, enc httptransport.EncodeRequestFunc) endpoint.Endpoint {
+		return httptransport.NewClient(
+			method,
+			copyURL(u, "/payloads"),
+			encodePayloadRequest(suffix, enc),
+			decodeParseResponse,
+		).Endpoint()
+	}
+	watchClient := func(method string, enc httptransport.EncodeRequestFunc) endpoint.Endpoint {
+		return httptransport.NewClient(
+			method,
+			copyURL(u, "/watches"),
+			encodeWatchRequest(enc),
+			decodeParseResponse,
+		).Endpoint()
+	}
+	credentialsClient := func(method string, enc httptransport.EncodeRequestFunc) endpoint.Endpoint {
+		return httptransport.NewClient(
+			method,
+			copyURL(u, "/credentials"),
+			encodeCredentialsRequest(enc),
+			decodeParseResponse,
+		).Endpoint()
+	}
+	cacheClient := func(method string) endpoint.Endpoint {
+		return httptransport.NewClient(
+			method,
+			copyURL(u, "/cache"),
+			encodeCacheRequest,
+			decodeParseResponse,
+		).Endpoint()
+	}
+	noBody := func(context.Context, *http.Request, interface{}) error { return nil }
+
 	// Returning the endpoint.Set as a service.Service relies on the
 	// endpoint.Set implementing the Service methods. That's just a simple bit
 	// of glue code.
 	return Endpoints{
-		ParseEndpoint: parseEndpoint,
+		ParseEndpoint:   parseEndpoint,
+		LinksEndpoint:   linksEndpoint,
+		ResultsEndpoint: resultsEndpoint,
+		QueryEndpoint:   queryEndpoint,
+
+		SnapshotsEndpoint: snapshotsEndpoint,
+		ReparseEndpoint:   reparseEndpoint,
+		ReportEndpoint:    reportEndpoint,
+
+		CreatePayloadEndpoint:   payloadClient("POST", "", encodeParseRequest),
+		UpdatePayloadEndpoint:   payloadClient("PUT", "", encodeParseRequest),
+		GetPayloadEndpoint:      payloadClient("GET", "", noBody),
+		DeletePayloadEndpoint:   payloadClient("DELETE", "", noBody),
+		ListPayloadsEndpoint:    payloadClient("GET", "", noBody),
+		PayloadVersionsEndpoint: payloadClient("GET", "/versions", noBody),
+		ParsePayloadEndpoint:    payloadClient("POST", "/parse", encodeParseRequest),
+
+		SaveWatchEndpoint:   watchClient("POST", encodeParseRequest),
+		GetWatchEndpoint:    watchClient("GET", noBody),
+		DeleteWatchEndpoint: watchClient("DELETE", noBody),
+		ListWatchesEndpoint: watchClient("GET", noBody),
+
+		SaveCredentialsEndpoint:   credentialsClient("POST", encodeParseRequest),
+		DeleteCredentialsEndpoint: credentialsClient("DELETE", noBody),
+		ListCredentialsEndpoint:   credentialsClient("GET", noBody),
+
+		SuggestEndpoint: suggestEndpoint,
+		AutoEndpoint:    autoEndpoint,
+
+		ListCacheEndpoint:   cacheClient("GET"),
+		DeleteCacheEndpoint: cacheClient("DELETE"),
+
+		IssueUserTokenEndpoint: issueUserTokenEndpoint,
+		PurgeEndpoint:          purgeEndpoint,
 	}, nil
 }
 
@@ -63,8 +234,131 @@ func encodeParseRequest(ctx context.Context, r *http.Request, request interface{
 	return nil
 }
 
+// encodeResultsRequest puts results ID to the request path and offset, limit to the query string.
+func encodeResultsRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.ResultsRequest)
+	r.URL.Path += "/" + url.PathEscape(req.ID)
+	q := r.URL.Query()
+	if req.Offset != 0 {
+		q.Set("offset", strconv.Itoa(req.Offset))
+	}
+	if req.Limit != 0 {
+		q.Set("limit", strconv.Itoa(req.Limit))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// encodeSnapshotsRequest puts results ID to the request path, run and page URL to the query string.
+func encodeSnapshotsRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.SnapshotRequest)
+	r.URL.Path += "/" + url.PathEscape(req.ID)
+	q := url.Values{}
+	if req.Run != "" {
+		q.Set("run", req.Run)
+	}
+	if req.URL == "" {
+		r.URL.Path += "/snapshots"
+	} else {
+		r.URL.Path += "/snapshot"
+		q.Set("url", req.URL)
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// encodeReportRequest puts results ID to the request path.
+func encodeReportRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.ReportRequest)
+	r.URL.Path += "/" + url.PathEscape(req.ID) + "/report"
+	return nil
+}
+
+// encodeReparseRequest puts results ID to the request path and JSON-encodes the payload to the request body.
+func encodeReparseRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.ReparseRequest)
+	r.URL.Path += "/" + url.PathEscape(req.ID) + "/reparse"
+	return encodeParseRequest(ctx, r, req.Payload)
+}
+
+// encodePayloadRequest returns EncodeRequestFunc which puts payload name followed by suffix to the request path
+// and version to the query string. The request is encoded with enc afterwards.
+// Nothing is added to the path for requests without payload name.
+func encodePayloadRequest(suffix string, enc httptransport.EncodeRequestFunc) httptransport.EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request interface{}) error {
+		switch req := request.(type) {
+		case scrape.Payload:
+			if r.Method == "PUT" {
+				r.URL.Path += "/" + url.PathEscape(req.Name) + suffix
+			}
+		case scrape.PayloadRequest:
+			r.URL.Path += "/" + url.PathEscape(req.Name) + suffix
+			if req.Version != 0 {
+				r.URL.RawQuery = "version=" + strconv.Itoa(req.Version)
+			}
+		}
+		return enc(ctx, r, request)
+	}
+}
+
+// encodeCacheRequest puts the filter to the query string and the admin token to TokenHeader.
+func encodeCacheRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.CacheRequest)
+	q := r.URL.Query()
+	for name, v := range map[string]string{"type": req.Type, "prefix": req.Prefix, "domain": req.Domain} {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	if req.OlderThan != 0 {
+		q.Set("olderThan", strconv.FormatInt(req.OlderThan, 10))
+	}
+	if req.Limit != 0 {
+		q.Set("limit", strconv.Itoa(req.Limit))
+	}
+	r.URL.RawQuery = q.Encode()
+	r.Header.Set(TokenHeader, req.Token)
+	return nil
+}
+
+// encodePurgeRequest encodes the request to the body and the admin token to TokenHeader.
+func encodePurgeRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(scrape.PurgeRequest)
+	r.Header.Set(TokenHeader, req.Token)
+	return encodeParseRequest(ctx, r, req)
+}
+
+// encodeWatchRequest returns EncodeRequestFunc which puts watch name to the request path
+// and limit to the query string. The request is encoded with enc afterwards.
+func encodeWatchRequest(enc httptransport.EncodeRequestFunc) httptransport.EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request interface{}) error {
+		if req, ok := request.(scrape.WatchRequest); ok {
+			r.URL.Path += "/" + url.PathEscape(req.Name)
+			if req.Limit != 0 {
+				r.URL.RawQuery = "limit=" + strconv.Itoa(req.Limit)
+			}
+		}
+		return enc(ctx, r, request)
+	}
+}
+
+// encodeCredentialsRequest returns EncodeRequestFunc which puts credentials name to the request path.
+// The request is encoded with enc afterwards.
+func encodeCredentialsRequest(enc httptransport.EncodeRequestFunc) httptransport.EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request interface{}) error {
+		if req, ok := request.(scrape.CredentialsRequest); ok && req.Name != "" {
+			r.URL.Path += "/" + url.PathEscape(req.Name)
+		}
+		return enc(ctx, r, request)
+	}
+}
+
 func decodeParseResponse(ctx context.Context, r *http.Response) (interface{}, error) {
 	if r.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := errs.DecodeResponse(r.StatusCode, data); err != nil {
+			return nil, err
+		}
 		return nil, errors.New(r.Status)
 	}
 	data, err := ioutil.ReadAll(r.Body)
@@ -91,3 +385,240 @@ func (e Endpoints) Parse(p scrape.Payload) (io.ReadCloser, error) {
 	return readCloser, nil
 
 }
+
+// Links method is used for sending link extraction requests to parse service.
+func (e Endpoints) Links(req fetch.Request) (io.ReadCloser, error) {
+	ctx := context.Background()
+	resp, err := e.LinksEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Results method is used for retrieving a page of parse results from parse service.
+func (e Endpoints) Results(req scrape.ResultsRequest) (io.ReadCloser, error) {
+	ctx := context.Background()
+	resp, err := e.ResultsEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Snapshots method is used for retrieving pages stored by parse job.
+func (e Endpoints) Snapshots(req scrape.SnapshotRequest) (io.ReadCloser, error) {
+	resp, err := e.SnapshotsEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Report method is used for retrieving the report of the latest parse job.
+func (e Endpoints) Report(req scrape.ReportRequest) (io.ReadCloser, error) {
+	resp, err := e.ReportEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Reparse method is used for processing payload over pages stored by parse job.
+func (e Endpoints) Reparse(req scrape.ReparseRequest) (io.ReadCloser, error) {
+	resp, err := e.ReparseEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Query method is used for querying results of previous runs stored by parse service.
+func (e Endpoints) Query(q scrape.Query) (io.ReadCloser, error) {
+	ctx := context.Background()
+	resp, err := e.QueryEndpoint(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// CreatePayload method saves a new payload to the registry of parse service.
+func (e Endpoints) CreatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	resp, err := e.CreatePayloadEndpoint(context.Background(), p)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// UpdatePayload method saves a new version of the payload to the registry of parse service.
+func (e Endpoints) UpdatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	resp, err := e.UpdatePayloadEndpoint(context.Background(), p)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// GetPayload method returns the payload from the registry of parse service.
+func (e Endpoints) GetPayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	resp, err := e.GetPayloadEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// DeletePayload method removes the payload from the registry of parse service.
+func (e Endpoints) DeletePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	resp, err := e.DeletePayloadEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// ListPayloads method returns all payloads saved to the registry of parse service.
+func (e Endpoints) ListPayloads() (io.ReadCloser, error) {
+	resp, err := e.ListPayloadsEndpoint(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// PayloadVersions method returns version history of the payload saved to the registry of parse service.
+func (e Endpoints) PayloadVersions(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	resp, err := e.PayloadVersionsEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// ParsePayload method runs the payload saved to the registry of parse service.
+func (e Endpoints) ParsePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	resp, err := e.ParsePayloadEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// SaveWatch method creates or replaces a watch of parse service.
+func (e Endpoints) SaveWatch(watch scrape.Watch) (io.ReadCloser, error) {
+	resp, err := e.SaveWatchEndpoint(context.Background(), watch)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// GetWatch method returns time series of the watched value.
+func (e Endpoints) GetWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	resp, err := e.GetWatchEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// DeleteWatch method removes the watch from parse service.
+func (e Endpoints) DeleteWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	resp, err := e.DeleteWatchEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// ListWatches method returns all watches of parse service.
+func (e Endpoints) ListWatches() (io.ReadCloser, error) {
+	resp, err := e.ListWatchesEndpoint(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// SaveCredentials method encrypts and stores credentials in the vault of parse service.
+func (e Endpoints) SaveCredentials(c scrape.Credentials) (io.ReadCloser, error) {
+	resp, err := e.SaveCredentialsEndpoint(context.Background(), c)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// DeleteCredentials method removes credentials from the vault of parse service.
+func (e Endpoints) DeleteCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	resp, err := e.DeleteCredentialsEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// ListCredentials method returns names and domains of credentials stored by parse service for the client's tenant.
+func (e Endpoints) ListCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	resp, err := e.ListCredentialsEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Suggest method returns candidate CSS selectors matching example values on a web page.
+func (e Endpoints) Suggest(req scrape.SuggestRequest) (io.ReadCloser, error) {
+	resp, err := e.SuggestEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Auto method returns records extracted from the dominant repeating structure of a web page.
+func (e Endpoints) Auto(req fetch.Request) (io.ReadCloser, error) {
+	resp, err := e.AutoEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// ListCache method returns stored items matching the filter. Admin token is required.
+func (e Endpoints) ListCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	resp, err := e.ListCacheEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// DeleteCache method deletes stored items matching the filter. Admin token is required.
+func (e Endpoints) DeleteCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	resp, err := e.DeleteCacheEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// IssueUserToken method returns a new signed user token.
+func (e Endpoints) IssueUserToken(req fetch.UserTokenRequest) (io.ReadCloser, error) {
+	resp, err := e.IssueUserTokenEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
+
+// Purge method deletes data of a job, a tenant or a URL. Admin token is required.
+func (e Endpoints) Purge(req scrape.PurgeRequest) (io.ReadCloser, error) {
+	resp, err := e.PurgeEndpoint(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(resp.([]byte))), nil
+}
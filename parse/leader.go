@@ -0,0 +1,170 @@
+package parse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// schedulerLease is the name of the lease held by the replica triggering scheduled runs.
+const schedulerLease = "scheduler"
+
+// errLeaseLost is returned by hold if another replica took over the lease while fn was running.
+var errLeaseLost = errors.New("lease was taken over by another replica")
+
+// leaderElector elects one of Parse service replicas sharing the storage to trigger scheduled payloads
+// and watches and to purge expired data. The leader renews its lease every third of the lease TTL, so another replica takes over
+// within TTL if the leader stops. Replicas keep serving requests and running dispatched payloads
+// whether they lead or not.
+type leaderElector struct {
+	id     string
+	ttl    time.Duration
+	logger *zap.Logger
+	leader int32
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newLeaderElector(ttl time.Duration, logger *zap.Logger) *leaderElector {
+	return &leaderElector{id: replicaID(), ttl: ttl, logger: logger, stop: make(chan struct{})}
+}
+
+// replicaID identifies the replica holding the lease.
+func replicaID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// start elects the leader. It fails if the storage can't grant leases, as every replica would lead then.
+func (e *leaderElector) start() error {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	_, ok := s.(storage.Leaser)
+	s.Close()
+	if !ok {
+		return fmt.Errorf("storage %s doesn't support leases", viper.GetString("STORAGE_TYPE"))
+	}
+	e.elect()
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.elect()
+			}
+		}
+	}()
+	return nil
+}
+
+// shutdown stops renewing the lease and releases it, so another replica takes over at once.
+func (e *leaderElector) shutdown() {
+	close(e.stop)
+	e.wg.Wait()
+	if !e.isLeader() {
+		return
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	if err := storage.ReleaseLease(s, schedulerLease, e.id); err != nil {
+		e.logger.Error("Failed to release scheduler lease. " + err.Error())
+	}
+}
+
+// elect acquires or renews the lease. Leadership is given up if the storage fails.
+func (e *leaderElector) elect() {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	ok, err := storage.AcquireLease(s, schedulerLease, e.id, e.ttl)
+	if err != nil {
+		e.logger.Error("Scheduler leader election failed. " + err.Error())
+	}
+	e.setLeader(ok)
+}
+
+func (e *leaderElector) setLeader(leader bool) {
+	var v int32
+	if leader {
+		v = 1
+	}
+	if atomic.SwapInt32(&e.leader, v) == v {
+		return
+	}
+	if leader {
+		e.logger.Info("Replica became scheduler leader", zap.String("replica", e.id))
+	} else {
+		e.logger.Info("Replica lost scheduler leadership", zap.String("replica", e.id))
+	}
+}
+
+// isLeader reports whether the replica holds the lease.
+func (e *leaderElector) isLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// hold runs fn holding lease name, which is renewed every third of the lease TTL while fn runs.
+// It returns false without running fn if the lease is held by another replica. If the lease is found
+// taken over by another replica on renewal, the context passed to fn is canceled and errLeaseLost is returned.
+func (e *leaderElector) hold(name string, fn func(ctx context.Context)) (bool, error) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	ok, err := storage.AcquireLease(s, name, e.id, e.ttl)
+	if err != nil || !ok {
+		return false, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	lost := int32(0)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				held, err := storage.AcquireLease(s, name, e.id, e.ttl)
+				if err != nil {
+					e.logger.Error("Failed to renew lease "+name+". "+err.Error(), zap.String("replica", e.id))
+					continue
+				}
+				if !held {
+					e.logger.Warn("Lease "+name+" was taken over by another replica", zap.String("replica", e.id))
+					atomic.StoreInt32(&lost, 1)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	fn(ctx)
+	close(done)
+	wg.Wait()
+	if atomic.LoadInt32(&lost) == 1 {
+		return true, errLeaseLost
+	}
+	if err := storage.ReleaseLease(s, name, e.id); err != nil {
+		e.logger.Error("Failed to release lease "+name+". "+err.Error(), zap.String("replica", e.id))
+	}
+	return true, nil
+}
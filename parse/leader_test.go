@@ -0,0 +1,88 @@
+package parse
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLeaderElector(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	logger := zap.NewNop()
+	a := newLeaderElector(time.Minute, logger)
+	b := newLeaderElector(time.Minute, logger)
+	assert.NoError(t, a.start())
+	assert.NoError(t, b.start())
+	assert.True(t, a.isLeader())
+	assert.False(t, b.isLeader())
+	assert.False(t, (&scheduler{elector: b}).leading(), "only the leader dispatches scheduled payloads")
+	assert.True(t, (&scheduler{}).leading(), "every replica leads without election")
+
+	//the lease is released on shutdown, so b takes over on the next election
+	a.shutdown()
+	b.elect()
+	assert.True(t, b.isLeader())
+	b.shutdown()
+}
+
+func TestLeaderElector_hold(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	logger := zap.NewNop()
+	a := newLeaderElector(time.Minute, logger)
+	b := newLeaderElector(time.Minute, logger)
+	ran := 0
+	ok, err := a.hold(runLease("shop"), func(context.Context) {
+		ran++
+		held, err := b.hold(runLease("shop"), func(context.Context) { ran++ })
+		assert.NoError(t, err)
+		assert.False(t, held, "run is taken by one replica")
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, ran)
+
+	//the lease is released after the run
+	ok, err = b.hold(runLease("shop"), func(context.Context) { ran++ })
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, ran)
+}
+
+func TestLeaderElector_holdLost(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	logger := zap.NewNop()
+	a := newLeaderElector(30*time.Millisecond, logger)
+	b := newLeaderElector(time.Minute, logger)
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	canceled := false
+	ok, err := a.hold(runLease("shop"), func(ctx context.Context) {
+		//b takes over the lease, f.e. after renewals of a stalled
+		assert.NoError(t, storage.ReleaseLease(s, runLease("shop"), a.id))
+		held, err := storage.AcquireLease(s, runLease("shop"), b.id, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, held)
+		select {
+		case <-ctx.Done():
+			canceled = true
+		case <-time.After(time.Second):
+		}
+	})
+	assert.True(t, ok)
+	assert.Equal(t, errLeaseLost, err)
+	assert.True(t, canceled, "run is canceled once the lease is lost")
+
+	//the lease of b is not released by a
+	held, err := storage.AcquireLease(s, runLease("shop"), a.id, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, held)
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
 	"go.uber.org/zap"
 )
@@ -30,15 +31,380 @@ func (mw loggingMiddleware) Parse(payload scrape.Payload) (output io.ReadCloser,
 		if err != nil {
 			mw.logger.Info("Parse",
 				zap.String("URL", url),
+				zap.String("requestID", payload.RequestID),
 				zap.String("fetcher", payload.Request.Type),
 				zap.Error(err),
 				zap.Duration("took", time.Since(begin)))
 		} else {
 			mw.logger.Info("Parse",
 				zap.String("URL", url),
+				zap.String("requestID", payload.RequestID),
 				zap.String("fetcher", payload.Request.Type),
 				zap.Duration("took", time.Since(begin)))
 		}
 	}(time.Now())
 	return
 }
+
+// Logging Links Service
+func (mw loggingMiddleware) Links(req fetch.Request) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Links",
+				zap.String("URL", req.URL),
+				zap.String("requestID", req.RequestID),
+				zap.String("fetcher", req.Type),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Links",
+				zap.String("URL", req.URL),
+				zap.String("requestID", req.RequestID),
+				zap.String("fetcher", req.Type),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Links(req)
+	return
+}
+
+// Logging Results Service
+func (mw loggingMiddleware) Results(req scrape.ResultsRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Results",
+				zap.String("ID", req.ID),
+				zap.Int("offset", req.Offset),
+				zap.Int("limit", req.Limit),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Results",
+				zap.String("ID", req.ID),
+				zap.Int("offset", req.Offset),
+				zap.Int("limit", req.Limit),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Results(req)
+	return
+}
+
+// Logging Snapshots Service
+func (mw loggingMiddleware) Snapshots(req scrape.SnapshotRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Snapshots",
+				zap.String("ID", req.ID),
+				zap.String("URL", req.URL),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Snapshots",
+				zap.String("ID", req.ID),
+				zap.String("URL", req.URL),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Snapshots(req)
+	return
+}
+
+// Logging Report Service
+func (mw loggingMiddleware) Report(req scrape.ReportRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Report",
+				zap.String("ID", req.ID),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Report",
+				zap.String("ID", req.ID),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Report(req)
+	return
+}
+
+// Logging Reparse Service
+func (mw loggingMiddleware) Reparse(req scrape.ReparseRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Reparse",
+				zap.String("ID", req.ID),
+				zap.String("requestID", req.Payload.RequestID),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Reparse",
+				zap.String("ID", req.ID),
+				zap.String("requestID", req.Payload.RequestID),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Reparse(req)
+	return
+}
+
+// Logging Query Service
+func (mw loggingMiddleware) Query(q scrape.Query) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Query",
+				zap.String("ID", q.ID),
+				zap.Int("runs", q.Runs),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Query",
+				zap.String("ID", q.ID),
+				zap.Int("runs", q.Runs),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Query(q)
+	return
+}
+
+// logPayload logs registry operations
+func (mw loggingMiddleware) logPayload(method, name string, version int, err error, begin time.Time) {
+	fields := []zap.Field{
+		zap.String("name", name),
+		zap.Int("version", version),
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	fields = append(fields, zap.Duration("took", time.Since(begin)))
+	mw.logger.Info(method, fields...)
+}
+
+// Logging CreatePayload Service
+func (mw loggingMiddleware) CreatePayload(p scrape.Payload) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("CreatePayload", p.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.CreatePayload(p)
+	return
+}
+
+// Logging UpdatePayload Service
+func (mw loggingMiddleware) UpdatePayload(p scrape.Payload) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("UpdatePayload", p.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.UpdatePayload(p)
+	return
+}
+
+// Logging GetPayload Service
+func (mw loggingMiddleware) GetPayload(req scrape.PayloadRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("GetPayload", req.Name, req.Version, err, begin) }(time.Now())
+	output, err = mw.Service.GetPayload(req)
+	return
+}
+
+// Logging DeletePayload Service
+func (mw loggingMiddleware) DeletePayload(req scrape.PayloadRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("DeletePayload", req.Name, req.Version, err, begin) }(time.Now())
+	output, err = mw.Service.DeletePayload(req)
+	return
+}
+
+// Logging ListPayloads Service
+func (mw loggingMiddleware) ListPayloads() (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("ListPayloads", "", 0, err, begin) }(time.Now())
+	output, err = mw.Service.ListPayloads()
+	return
+}
+
+// Logging PayloadVersions Service
+func (mw loggingMiddleware) PayloadVersions(req scrape.PayloadRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("PayloadVersions", req.Name, req.Version, err, begin) }(time.Now())
+	output, err = mw.Service.PayloadVersions(req)
+	return
+}
+
+// Logging ParsePayload Service
+func (mw loggingMiddleware) ParsePayload(req scrape.PayloadRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("ParsePayload", req.Name, req.Version, err, begin) }(time.Now())
+	output, err = mw.Service.ParsePayload(req)
+	return
+}
+
+// Logging SaveWatch Service
+func (mw loggingMiddleware) SaveWatch(watch scrape.Watch) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("SaveWatch", watch.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.SaveWatch(watch)
+	return
+}
+
+// Logging GetWatch Service
+func (mw loggingMiddleware) GetWatch(req scrape.WatchRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("GetWatch", req.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.GetWatch(req)
+	return
+}
+
+// Logging DeleteWatch Service
+func (mw loggingMiddleware) DeleteWatch(req scrape.WatchRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("DeleteWatch", req.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.DeleteWatch(req)
+	return
+}
+
+// Logging ListWatches Service
+func (mw loggingMiddleware) ListWatches() (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("ListWatches", "", 0, err, begin) }(time.Now())
+	output, err = mw.Service.ListWatches()
+	return
+}
+
+// Logging SaveCredentials Service
+func (mw loggingMiddleware) SaveCredentials(c scrape.Credentials) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("SaveCredentials", c.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.SaveCredentials(c)
+	return
+}
+
+// Logging DeleteCredentials Service
+func (mw loggingMiddleware) DeleteCredentials(req scrape.CredentialsRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("DeleteCredentials", req.Name, 0, err, begin) }(time.Now())
+	output, err = mw.Service.DeleteCredentials(req)
+	return
+}
+
+// Logging ListCredentials Service
+func (mw loggingMiddleware) ListCredentials(req scrape.CredentialsRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) { mw.logPayload("ListCredentials", "", 0, err, begin) }(time.Now())
+	output, err = mw.Service.ListCredentials(req)
+	return
+}
+
+// Logging Suggest Service
+func (mw loggingMiddleware) Suggest(req scrape.SuggestRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Suggest",
+				zap.String("URL", req.Request.URL),
+				zap.String("requestID", req.Request.RequestID),
+				zap.Strings("examples", req.Examples),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Suggest",
+				zap.String("URL", req.Request.URL),
+				zap.String("requestID", req.Request.RequestID),
+				zap.Strings("examples", req.Examples),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Suggest(req)
+	return
+}
+
+// Logging Auto Service
+func (mw loggingMiddleware) Auto(req fetch.Request) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Auto",
+				zap.String("URL", req.URL),
+				zap.String("requestID", req.RequestID),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Auto",
+				zap.String("URL", req.URL),
+				zap.String("requestID", req.RequestID),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Auto(req)
+	return
+}
+
+// Logging ListCache Service
+func (mw loggingMiddleware) ListCache(req scrape.CacheRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("ListCache",
+				zap.String("type", req.Type),
+				zap.String("prefix", req.Prefix),
+				zap.String("domain", req.Domain),
+				zap.Int64("olderThan", req.OlderThan),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("ListCache",
+				zap.String("type", req.Type),
+				zap.String("prefix", req.Prefix),
+				zap.String("domain", req.Domain),
+				zap.Int64("olderThan", req.OlderThan),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.ListCache(req)
+	return
+}
+
+// Logging DeleteCache Service
+func (mw loggingMiddleware) DeleteCache(req scrape.CacheRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("DeleteCache",
+				zap.String("type", req.Type),
+				zap.String("prefix", req.Prefix),
+				zap.String("domain", req.Domain),
+				zap.Int64("olderThan", req.OlderThan),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("DeleteCache",
+				zap.String("type", req.Type),
+				zap.String("prefix", req.Prefix),
+				zap.String("domain", req.Domain),
+				zap.Int64("olderThan", req.OlderThan),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.DeleteCache(req)
+	return
+}
+
+// Logging IssueUserToken Service
+func (mw loggingMiddleware) IssueUserToken(req fetch.UserTokenRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("IssueUserToken",
+				zap.Int64("ttl", req.TTL),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("IssueUserToken",
+				zap.Int64("ttl", req.TTL),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.IssueUserToken(req)
+	return
+}
+
+// Logging Purge Service
+func (mw loggingMiddleware) Purge(req scrape.PurgeRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			mw.logger.Info("Purge",
+				zap.String("id", req.ID),
+				zap.String("tenant", req.Tenant),
+				zap.String("url", req.URL),
+				zap.Error(err),
+				zap.Duration("took", time.Since(begin)))
+		} else {
+			mw.logger.Info("Purge",
+				zap.String("id", req.ID),
+				zap.String("tenant", req.Tenant),
+				zap.String("url", req.URL),
+				zap.Duration("took", time.Since(begin)))
+		}
+	}(time.Now())
+	output, err = mw.Service.Purge(req)
+	return
+}
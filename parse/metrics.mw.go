@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/metrics"
+	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
 )
 
@@ -19,6 +20,133 @@ func (mw metricsMiddleware) Parse(payload scrape.Payload) (output io.ReadCloser,
 	return
 }
 
+func (mw metricsMiddleware) Links(req fetch.Request) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "Links"}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	output, err = mw.Service.Links(req)
+	return
+}
+
+func (mw metricsMiddleware) Results(req scrape.ResultsRequest) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "Results"}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	output, err = mw.Service.Results(req)
+	return
+}
+
+func (mw metricsMiddleware) Snapshots(req scrape.SnapshotRequest) (io.ReadCloser, error) {
+	defer mw.observe("Snapshots", time.Now())
+	return mw.Service.Snapshots(req)
+}
+
+func (mw metricsMiddleware) Report(req scrape.ReportRequest) (io.ReadCloser, error) {
+	defer mw.observe("Report", time.Now())
+	return mw.Service.Report(req)
+}
+
+func (mw metricsMiddleware) Reparse(req scrape.ReparseRequest) (io.ReadCloser, error) {
+	defer mw.observe("Reparse", time.Now())
+	return mw.Service.Reparse(req)
+}
+
+func (mw metricsMiddleware) Query(q scrape.Query) (output io.ReadCloser, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "Query"}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	output, err = mw.Service.Query(q)
+	return
+}
+
+// observe counts a request to the method and its latency
+func (mw metricsMiddleware) observe(method string, begin time.Time) {
+	lvs := []string{"method", method}
+	mw.requestCount.With(lvs...).Add(1)
+	mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+}
+
+func (mw metricsMiddleware) CreatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	defer mw.observe("CreatePayload", time.Now())
+	return mw.Service.CreatePayload(p)
+}
+
+func (mw metricsMiddleware) UpdatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	defer mw.observe("UpdatePayload", time.Now())
+	return mw.Service.UpdatePayload(p)
+}
+
+func (mw metricsMiddleware) GetPayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	defer mw.observe("GetPayload", time.Now())
+	return mw.Service.GetPayload(req)
+}
+
+func (mw metricsMiddleware) DeletePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	defer mw.observe("DeletePayload", time.Now())
+	return mw.Service.DeletePayload(req)
+}
+
+func (mw metricsMiddleware) ListPayloads() (io.ReadCloser, error) {
+	defer mw.observe("ListPayloads", time.Now())
+	return mw.Service.ListPayloads()
+}
+
+func (mw metricsMiddleware) PayloadVersions(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	defer mw.observe("PayloadVersions", time.Now())
+	return mw.Service.PayloadVersions(req)
+}
+
+func (mw metricsMiddleware) ParsePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	defer mw.observe("ParsePayload", time.Now())
+	return mw.Service.ParsePayload(req)
+}
+
+func (mw metricsMiddleware) SaveWatch(watch scrape.Watch) (io.ReadCloser, error) {
+	defer mw.observe("SaveWatch", time.Now())
+	return mw.Service.SaveWatch(watch)
+}
+
+func (mw metricsMiddleware) GetWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	defer mw.observe("GetWatch", time.Now())
+	return mw.Service.GetWatch(req)
+}
+
+func (mw metricsMiddleware) DeleteWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	defer mw.observe("DeleteWatch", time.Now())
+	return mw.Service.DeleteWatch(req)
+}
+
+func (mw metricsMiddleware) ListWatches() (io.ReadCloser, error) {
+	defer mw.observe("ListWatches", time.Now())
+	return mw.Service.ListWatches()
+}
+
+func (mw metricsMiddleware) SaveCredentials(c scrape.Credentials) (io.ReadCloser, error) {
+	defer mw.observe("SaveCredentials", time.Now())
+	return mw.Service.SaveCredentials(c)
+}
+
+func (mw metricsMiddleware) DeleteCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	defer mw.observe("DeleteCredentials", time.Now())
+	return mw.Service.DeleteCredentials(req)
+}
+
+func (mw metricsMiddleware) ListCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	defer mw.observe("ListCredentials", time.Now())
+	return mw.Service.ListCredentials(req)
+}
+
+func (mw metricsMiddleware) Suggest(req scrape.SuggestRequest) (io.ReadCloser, error) {
+	defer mw.observe("Suggest", time.Now())
+	return mw.Service.Suggest(req)
+}
+
 // metrics function
 func Metrics(requestCount metrics.Counter,
 	requestLatency metrics.Histogram) ServiceMiddleware {
@@ -38,3 +166,28 @@ type metricsMiddleware struct {
 	requestCount   metrics.Counter
 	requestLatency metrics.Histogram
 }
+
+func (mw metricsMiddleware) Auto(req fetch.Request) (io.ReadCloser, error) {
+	defer mw.observe("Auto", time.Now())
+	return mw.Service.Auto(req)
+}
+
+func (mw metricsMiddleware) ListCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	defer mw.observe("ListCache", time.Now())
+	return mw.Service.ListCache(req)
+}
+
+func (mw metricsMiddleware) DeleteCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	defer mw.observe("DeleteCache", time.Now())
+	return mw.Service.DeleteCache(req)
+}
+
+func (mw metricsMiddleware) IssueUserToken(req fetch.UserTokenRequest) (io.ReadCloser, error) {
+	defer mw.observe("IssueUserToken", time.Now())
+	return mw.Service.IssueUserToken(req)
+}
+
+func (mw metricsMiddleware) Purge(req scrape.PurgeRequest) (io.ReadCloser, error) {
+	defer mw.observe("Purge", time.Now())
+	return mw.Service.Purge(req)
+}
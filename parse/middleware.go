@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"net/http"
+	"sync"
+)
+
+var registered = struct {
+	sync.Mutex
+	middlewares []ServiceMiddleware
+	handlers    []HTTPMiddleware
+}{}
+
+// Passthrough forwards all calls to the wrapped Service, so middlewares embedding it implement only the methods they handle.
+type Passthrough struct {
+	Service
+}
+
+// RegisterMiddleware adds custom middlewares to the Parse service chain built by Start.
+func RegisterMiddleware(mw ...ServiceMiddleware) {
+	registered.Lock()
+	defer registered.Unlock()
+	registered.middlewares = append(registered.middlewares, mw...)
+}
+
+// applyMiddlewares wraps svc with registered middlewares.
+func applyMiddlewares(svc Service) Service {
+	registered.Lock()
+	defer registered.Unlock()
+	for _, mw := range registered.middlewares {
+		svc = mw(svc)
+	}
+	return svc
+}
+
+// HTTPMiddleware wraps the HTTP handler of Parse service.
+type HTTPMiddleware func(http.Handler) http.Handler
+
+// RegisterHTTPMiddleware adds custom middlewares to the HTTP handler built by Start.
+func RegisterHTTPMiddleware(mw ...HTTPMiddleware) {
+	registered.Lock()
+	defer registered.Unlock()
+	registered.handlers = append(registered.handlers, mw...)
+}
+
+// applyHTTPMiddlewares wraps h with registered HTTP middlewares.
+func applyHTTPMiddlewares(h http.Handler) http.Handler {
+	registered.Lock()
+	defer registered.Unlock()
+	for _, mw := range registered.handlers {
+		h = mw(h)
+	}
+	return h
+}
@@ -0,0 +1,96 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/scrape"
+	"github.com/stretchr/testify/assert"
+)
+
+// quotaService rejects parse requests of payloads without a name and passes other calls through.
+type quotaService struct {
+	Passthrough
+}
+
+func (s quotaService) Parse(p scrape.Payload) (io.ReadCloser, error) {
+	if p.Name == "" {
+		return nil, errors.New("quota exceeded")
+	}
+	return s.Service.Parse(p)
+}
+
+// stubService implements the methods called by tests.
+type stubService struct {
+	Passthrough
+}
+
+func (stubService) Parse(scrape.Payload) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("parsed")), nil
+}
+
+func (stubService) GetPayload(scrape.PayloadRequest) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("payload")), nil
+}
+
+func TestRegisterMiddleware(t *testing.T) {
+	defer func() { registered.middlewares = nil }()
+	RegisterMiddleware(func(next Service) Service { return quotaService{Passthrough{next}} })
+	svc := applyMiddlewares(stubService{})
+	read := func(out io.ReadCloser, err error) string {
+		assert.NoError(t, err)
+		data, _ := ioutil.ReadAll(out)
+		return string(data)
+	}
+
+	_, err := svc.Parse(scrape.Payload{})
+	assert.EqualError(t, err, "quota exceeded")
+	assert.Equal(t, "parsed", read(svc.Parse(scrape.Payload{Name: "shop"})))
+	//methods not implemented by the middleware are passed through
+	assert.Equal(t, "payload", read(svc.GetPayload(scrape.PayloadRequest{Name: "shop"})))
+}
+
+func TestRegisterHTTPMiddleware(t *testing.T) {
+	defer func() { registered.handlers = nil }()
+	calls := []string{}
+	tenantOnly := func(name string) HTTPMiddleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				if p, ok := PrincipalFromContext(r.Context()); !ok || p.Tenant == "" {
+					http.Error(w, "tenant required", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	RegisterHTTPMiddleware(tenantOnly("a"), tenantOnly("b"))
+	a, err := newAccessControl("", []APIKey{{Key: "k1", Role: "user", Tenant: "acme"}, {Key: "k2", Role: "user"}}, "", false)
+	assert.NoError(t, err)
+	h := a.Handler(applyHTTPMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/parse", strings.NewReader("{}"))
+		req.Header.Set(TokenHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body, _ := ioutil.ReadAll(w.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, []string{"b", "a"}, calls)
+
+	calls = calls[:0]
+	assert.Equal(t, http.StatusForbidden, do("k2").Code)
+	assert.Equal(t, []string{"b"}, calls)
+}
@@ -0,0 +1,148 @@
+package parse
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// TokenHeader is HTTP header carrying API key or JWT of API client.
+const TokenHeader = "X-API-Token"
+
+// rateLimiter applies per-client fixed window rate limit and quota to HTTP handler.
+// It runs after accessControl, so clients are told apart by the credential they authenticated with.
+// Anonymous clients, including ones sending tokens which are not verified, are limited by IP address.
+// Limit and quota usage are returned in X-RateLimit-* and X-Quota-* response headers
+// so clients may throttle themselves before they get 429 Too Many Requests.
+type rateLimiter struct {
+	limit       int
+	window      time.Duration
+	quota       int
+	quotaPeriod time.Duration
+
+	mx        sync.Mutex
+	usage     map[string]*tokenUsage
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenUsage struct {
+	windowStart time.Time
+	count       int
+	quotaStart  time.Time
+	quotaUsed   int
+}
+
+// newRateLimiter creates rateLimiter allowing limit requests per window and quota requests per quotaPeriod
+// for every token. Zero limit or quota disables it.
+func newRateLimiter(limit int, window time.Duration, quota int, quotaPeriod time.Duration) *rateLimiter {
+	if window <= 0 {
+		window = time.Minute
+	}
+	if quotaPeriod <= 0 {
+		quotaPeriod = 24 * time.Hour
+	}
+	return &rateLimiter{
+		limit:       limit,
+		window:      window,
+		quota:       quota,
+		quotaPeriod: quotaPeriod,
+		usage:       make(map[string]*tokenUsage),
+		now:         time.Now,
+	}
+}
+
+// Handler wraps next with rate limit and quota checks. Health checks and metrics are not limited.
+func (l *rateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed, retryAfter := l.take(requestClient(r), w.Header())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+			errs.WriteError(w, http.StatusTooManyRequests, errs.CodeRateLimited, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts request of token and sets limit headers. It returns false and time to wait
+// if either rate limit or quota is exhausted. Rejected requests are not counted.
+func (l *rateLimiter) take(token string, h http.Header) (bool, time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	now := l.now()
+	l.sweep(now)
+	u, ok := l.usage[token]
+	if !ok {
+		u = &tokenUsage{windowStart: now, quotaStart: now}
+		l.usage[token] = u
+	}
+	if now.Sub(u.windowStart) >= l.window {
+		u.windowStart, u.count = now, 0
+	}
+	if now.Sub(u.quotaStart) >= l.quotaPeriod {
+		u.quotaStart, u.quotaUsed = now, 0
+	}
+	windowReset := u.windowStart.Add(l.window)
+	quotaReset := u.quotaStart.Add(l.quotaPeriod)
+
+	var wait time.Duration
+	if l.limit > 0 && u.count >= l.limit {
+		wait = windowReset.Sub(now)
+	}
+	if l.quota > 0 && u.quotaUsed >= l.quota {
+		if d := quotaReset.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait == 0 {
+		u.count++
+		u.quotaUsed++
+	}
+	if l.limit > 0 {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(l.limit-u.count))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(windowReset.Unix(), 10))
+	}
+	if l.quota > 0 {
+		h.Set("X-Quota-Limit", strconv.Itoa(l.quota))
+		h.Set("X-Quota-Used", strconv.Itoa(u.quotaUsed))
+		h.Set("X-Quota-Remaining", strconv.Itoa(l.quota-u.quotaUsed))
+		h.Set("X-Quota-Reset", strconv.FormatInt(quotaReset.Unix(), 10))
+	}
+	return wait == 0, wait
+}
+
+// sweep removes usage of tokens whose window and quota period are both over.
+// It runs at most once per window.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for token, u := range l.usage {
+		if now.Sub(u.windowStart) >= l.window && (l.quota == 0 || now.Sub(u.quotaStart) >= l.quotaPeriod) {
+			delete(l.usage, token)
+		}
+	}
+}
+
+// requestClient returns the authenticated client of request or its IP address for anonymous clients.
+func requestClient(r *http.Request) string {
+	if p, ok := PrincipalFromContext(r.Context()); ok && p.id != "" {
+		return p.id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newRateLimiter(2, time.Minute, 3, time.Hour)
+	l.now = func() time.Time { return now }
+	access, err := newAccessControl("", []APIKey{{Key: "a", Role: "user"}, {Key: "b", Role: "user"}}, "", false)
+	assert.NoError(t, err)
+	h := access.Handler(l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	do := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/parse", nil)
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("a")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1060", rr.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, "1", rr.Header().Get("X-Quota-Used"))
+	assert.Equal(t, "2", rr.Header().Get("X-Quota-Remaining"))
+
+	rr = do("a")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+
+	now = now.Add(10 * time.Second)
+	rr = do("a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "50", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "2", rr.Header().Get("X-Quota-Used"))
+
+	// other clients are counted separately, unauthenticated requests are not counted
+	assert.Equal(t, http.StatusOK, do("b").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("c").Code)
+	bearer := httptest.NewRequest("POST", "/parse", nil)
+	bearer.Header.Set("Authorization", "Bearer a")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, bearer)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "bearer tokens are counted as the same client")
+
+	now = now.Add(time.Minute)
+	rr = do("a")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("X-Quota-Remaining"))
+
+	now = now.Add(time.Minute)
+	rr = do("a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "3470", rr.Header().Get("Retry-After"))
+
+	// health checks are never limited
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set(TokenHeader, "a")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimiter_unverifiedTokens(t *testing.T) {
+	l := newRateLimiter(1, time.Minute, 0, 0)
+	access, err := newAccessControl("", nil, "", false)
+	assert.NoError(t, err)
+	h := access.Handler(l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	for i, token := range []string{"x", "y", "z"} {
+		req := httptest.NewRequest("POST", "/parse", nil)
+		req.Header.Set(TokenHeader, token)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, rr.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code, "changing unverified token doesn't reset the limit")
+		}
+	}
+	assert.Len(t, l.usage, 1)
+}
@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/scrape"
+	"go.uber.org/zap"
+)
+
+// reaper purges data of parse jobs and audit log entries older than retention policies allow.
+// If elector is set, only the replica elected leader purges.
+type reaper struct {
+	policies scrape.RetentionPolicies
+	interval time.Duration
+	logger   *zap.Logger
+	elector  *leaderElector
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newReaper(policies scrape.RetentionPolicies, interval time.Duration, logger *zap.Logger, elector *leaderElector) *reaper {
+	return &reaper{policies: policies, interval: interval, logger: logger, elector: elector, stop: make(chan struct{})}
+}
+
+func (r *reaper) start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.reap(time.Now())
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// shutdown waits for the running purge to finish.
+func (r *reaper) shutdown() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+func (r *reaper) reap(t time.Time) {
+	if r.elector != nil && !r.elector.isLeader() {
+		return
+	}
+	res, err := scrape.Reap(r.policies, t)
+	if err != nil {
+		r.logger.Error("Reaper failed to purge expired data. " + err.Error())
+	}
+	if res != nil && (len(res.Jobs) > 0 || res.Snapshots > 0 || res.Audit > 0) {
+		r.logger.Info("Reaper purged expired data",
+			zap.Strings("jobs", res.Jobs),
+			zap.Int("snapshots", res.Snapshots),
+			zap.Int("audit", res.Audit))
+	}
+}
@@ -0,0 +1,348 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/scrape"
+	"go.uber.org/zap"
+)
+
+// schedulerTick is the interval between checks for due payloads and watches.
+const schedulerTick = 30 * time.Second
+
+// maxPrewarms limits the number of payloads prewarmed at the same time.
+const maxPrewarms = 2
+
+// scheduler runs payloads saved to the registry with a schedule and checks watches when they are due.
+// Payloads and watches are run one by one to keep the load on target sites low.
+// Pages of payloads are prewarmed in the background, so prewarming doesn't delay due runs.
+// If elector is set, only the replica elected leader checks watches and dispatches due payloads.
+// Dispatched payloads are run by schedulers of all replicas, each payload by the replica holding its run lease.
+type scheduler struct {
+	svc     Service
+	logger  *zap.Logger
+	elector *leaderElector
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	//prewarms holds slots of running prewarms
+	prewarms chan struct{}
+}
+
+func newScheduler(svc Service, logger *zap.Logger, elector *leaderElector) *scheduler {
+	return &scheduler{svc: svc, logger: logger, elector: elector, stop: make(chan struct{}), prewarms: make(chan struct{}, maxPrewarms)}
+}
+
+// leading reports whether the replica triggers scheduled runs.
+func (s *scheduler) leading() bool {
+	return s.elector == nil || s.elector.isLeader()
+}
+
+func (s *scheduler) start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			s.runDue(time.Now())
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// shutdown waits for the running payload and prewarms to finish.
+func (s *scheduler) shutdown() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// runDue runs or dispatches scheduled payloads and checks watches which are due at t.
+// Then it runs payloads dispatched to replicas.
+func (s *scheduler) runDue(t time.Time) {
+	if s.leading() {
+		if s.elector == nil {
+			s.runPayloads(t)
+		} else {
+			s.dispatchPayloads(t)
+		}
+		s.prewarmPayloads(t)
+		s.checkWatches(t)
+	}
+	if s.elector != nil {
+		s.runDispatched()
+	}
+}
+
+func (s *scheduler) runPayloads(t time.Time) {
+	r := scrape.NewRegistry()
+	list, err := r.List()
+	r.Close()
+	if err != nil {
+		s.logger.Error("Scheduler failed to list payloads. " + err.Error())
+		return
+	}
+	for _, info := range list {
+		if info.Schedule == nil || !info.Recrawl.Due(t) {
+			continue
+		}
+		//leadership may be lost while earlier payloads run
+		if !s.leading() {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.run(context.Background(), info.Name)
+	}
+}
+
+// dispatchPayloads marks runs due at t as dispatched, so they are picked up by schedulers of all replicas.
+func (s *scheduler) dispatchPayloads(t time.Time) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	list, err := r.List()
+	if err != nil {
+		s.logger.Error("Scheduler failed to list payloads. " + err.Error())
+		return
+	}
+	for _, info := range list {
+		if info.Schedule == nil || !info.Recrawl.Due(t) || info.Recrawl.Pending() {
+			continue
+		}
+		if _, err := r.Dispatch(info.Name, t); err != nil {
+			s.logger.Error("Failed to dispatch scheduled run. "+err.Error(), zap.String("payload", info.Name))
+		}
+	}
+}
+
+// runLease is the name of the lease held by the replica running dispatched payload name.
+func runLease(name string) string {
+	return "run-" + name
+}
+
+// runDispatched runs dispatched payloads one by one. Every run is held by a lease, so other replicas
+// skip it. A run left by a stopped replica is taken over by another one once the lease expires.
+func (s *scheduler) runDispatched() {
+	list, err := s.pending()
+	if err != nil {
+		s.logger.Error("Scheduler failed to list payloads. " + err.Error())
+		return
+	}
+	for name := range list {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		_, err := s.elector.hold(runLease(name), func(ctx context.Context) {
+			//the run may have been finished by another replica after the list was read
+			if pending, err := s.pending(); err != nil || !pending[name] {
+				return
+			}
+			s.run(ctx, name)
+		})
+		if err != nil {
+			s.logger.Error("Failed to take scheduled run. "+err.Error(), zap.String("payload", name))
+		}
+	}
+}
+
+// pending returns names of payloads with dispatched runs which haven't finished yet.
+func (s *scheduler) pending() (map[string]bool, error) {
+	r := scrape.NewRegistry()
+	list, err := r.List()
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+	pending := map[string]bool{}
+	for _, info := range list {
+		if info.Recrawl.Pending() {
+			pending[info.Name] = true
+		}
+	}
+	return pending, nil
+}
+
+// prewarmPayloads starts caching pages of scheduled runs which are about to start. Up to maxPrewarms payloads
+// are prewarmed in the background at the same time. Others are left for the next ticks.
+func (s *scheduler) prewarmPayloads(t time.Time) {
+	r := scrape.NewRegistry()
+	list, err := r.List()
+	r.Close()
+	if err != nil {
+		s.logger.Error("Scheduler failed to list payloads. " + err.Error())
+		return
+	}
+	for _, info := range list {
+		if info.Schedule == nil || !info.Recrawl.PrewarmDue(*info.Schedule, t) {
+			continue
+		}
+		if !s.leading() {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		select {
+		case s.prewarms <- struct{}{}:
+		default:
+			return
+		}
+		//the run is recorded prewarmed before prewarming starts, so it is not started again by the next ticks
+		if err := s.recordPrewarm(info.Name, info.Recrawl.NextRun); err != nil {
+			s.logger.Error("Failed to update recrawl state. "+err.Error(), zap.String("payload", info.Name))
+			<-s.prewarms
+			continue
+		}
+		s.wg.Add(1)
+		go func(name string, run time.Time, interval time.Duration) {
+			defer s.wg.Done()
+			defer func() { <-s.prewarms }()
+			s.prewarm(name, run, interval)
+		}(info.Name, info.Recrawl.NextRun, time.Duration(info.Schedule.Interval)*time.Second)
+	}
+}
+
+func (s *scheduler) recordPrewarm(name string, run time.Time) error {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return r.RecordPrewarm(name, run)
+}
+
+// prewarm caches pages of the run of payload name scheduled at run. Cached pages expire
+// interval after the run, so they are not taken by later runs if the run fails to start.
+func (s *scheduler) prewarm(name string, run time.Time, interval time.Duration) {
+	r := scrape.NewRegistry()
+	p, err := r.Get(scrape.PayloadRequest{Name: name})
+	r.Close()
+	if err == nil {
+		*p, err = resolvePayload(*p)
+	}
+	if err != nil {
+		s.logger.Warn("Prewarm failed. "+err.Error(), zap.String("payload", name))
+		return
+	}
+	n, err := scrape.Prewarm(*p, run.Add(interval))
+	if err != nil {
+		s.logger.Warn("Prewarm failed. "+err.Error(), zap.String("payload", name))
+		return
+	}
+	s.logger.Info("Pages prewarmed", zap.String("payload", name), zap.Int("pages", n), zap.Time("run", run))
+}
+
+func (s *scheduler) checkWatches(t time.Time) {
+	w := scrape.NewWatches()
+	defer w.Close()
+	list, err := w.List()
+	if err != nil {
+		s.logger.Error("Scheduler failed to list watches. " + err.Error())
+		return
+	}
+	for _, info := range list {
+		if !info.Due(t) {
+			continue
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		point, err := w.Check(info.Name)
+		if err != nil {
+			s.logger.Error("Watch check failed. "+err.Error(), zap.String("watch", info.Name))
+			continue
+		}
+		if point.Error != "" {
+			s.logger.Warn("Failed to extract watched value. "+point.Error, zap.String("watch", info.Name))
+		}
+	}
+}
+
+// run parses payload, sends alerts matching the diff of results and updates recrawl state of the payload.
+// Nothing is alerted or recorded if ctx is canceled by then, f.e. as another replica took over the run.
+func (s *scheduler) run(ctx context.Context, name string) {
+	diff, err := s.parse(name)
+	if err != nil {
+		s.logger.Warn("Scheduled run failed. "+err.Error(), zap.String("payload", name))
+	}
+	if ctx.Err() != nil {
+		s.logger.Warn("Scheduled run is not recorded as it was taken over by another replica", zap.String("payload", name))
+		return
+	}
+	r := scrape.NewRegistry()
+	defer r.Close()
+	if diff != nil {
+		s.alert(r, name, diff)
+	}
+	state, err := r.RecordRun(name, time.Now(), diff)
+	if err != nil {
+		s.logger.Error("Failed to update recrawl state. "+err.Error(), zap.String("payload", name))
+		return
+	}
+	s.logger.Info("Scheduled run finished",
+		zap.String("payload", name),
+		zap.Bool("changed", diff != nil && diff.Changed()),
+		zap.Bool("suspect", len(state.Suspect) > 0),
+		zap.Int("interval", state.Interval),
+		zap.Time("next run", state.NextRun))
+}
+
+// parse runs payload and returns the diff of its results with the previous run. A failed run, f.e. failed
+// by a fill gate, is returned as a suspect diff along with the error, so it is alerted and recorded as suspect.
+func (s *scheduler) parse(name string) (*scrape.RunDiff, error) {
+	out, err := s.svc.ParsePayload(scrape.PayloadRequest{Name: name})
+	if err != nil {
+		return &scrape.RunDiff{Suspect: []string{"run failed: " + err.Error()}}, err
+	}
+	if out == nil {
+		return nil, nil
+	}
+	defer out.Close()
+	var resp struct {
+		ResultsID string `json:"Results ID"`
+	}
+	if err := json.NewDecoder(out).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return scrape.DiffRuns(resp.ResultsID)
+}
+
+// alert sends notifications of payload alerts matching diff. If the run failed or violates canary checks
+// of the payload, a notification about suspect run is sent to all alert channels instead.
+func (s *scheduler) alert(r *scrape.Registry, name string, diff *scrape.RunDiff) {
+	p, err := r.Get(scrape.PayloadRequest{Name: name})
+	if err == nil {
+		*p, err = r.Resolve(*p)
+	}
+	if err != nil {
+		s.logger.Error("Failed to read alerts. "+err.Error(), zap.String("payload", name))
+		return
+	}
+	if p.Canary != nil && len(diff.Suspect) == 0 {
+		if err := scrape.CheckCanary(*p.Canary, diff); err != nil {
+			s.logger.Warn("Canary check failed. "+err.Error(), zap.String("payload", name))
+		}
+	}
+	if len(diff.Suspect) > 0 {
+		s.logger.Warn("Scheduled run is suspect", zap.String("payload", name), zap.Strings("violations", diff.Suspect))
+		if err := scrape.NotifySuspect(name, p.Alerts, diff); err != nil {
+			s.logger.Warn(err.Error(), zap.String("payload", name))
+		}
+		return
+	}
+	if err := scrape.Notify(name, p.Alerts, diff); err != nil {
+		s.logger.Warn(err.Error(), zap.String("payload", name))
+	}
+}
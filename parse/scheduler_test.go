@@ -0,0 +1,28 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/slotix/dataflowkit/scrape"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingService struct {
+	Service
+}
+
+func (failingService) ParsePayload(scrape.PayloadRequest) (io.ReadCloser, error) {
+	return nil, errors.New("Fill rate gate failed: price filled in 10% of records (min 90%)")
+}
+
+func TestScheduler_parseFailed(t *testing.T) {
+	s := newScheduler(failingService{}, nil, nil)
+	diff, err := s.parse("shop")
+	assert.Error(t, err)
+	if assert.NotNil(t, diff, "failed runs are suspect") {
+		assert.Equal(t, []string{"run failed: Fill rate gate failed: price filled in 10% of records (min 90%)"}, diff.Suspect)
+		assert.Equal(t, "", diff.ID)
+	}
+}
@@ -8,8 +8,9 @@ import (
 	"sync"
 	"time"
 
-	//kitprometheus "github.com/go-kit/kit/metrics/prometheus"
-	//stdprometheus "github.com/prometheus/client_golang/prometheus"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/slotix/dataflowkit/scrape"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -19,14 +20,46 @@ type Config struct {
 	Host         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// RateLimit is the number of requests allowed for every API token within RateLimitWindow.
+	// Zero disables rate limiting.
+	RateLimit       int
+	RateLimitWindow time.Duration
+	// Quota is the number of requests allowed for every API token within QuotaPeriod.
+	// Zero disables quotas.
+	Quota       int
+	QuotaPeriod time.Duration
+	// Scheduler turns on periodic runs of payloads saved to the registry with a schedule.
+	Scheduler bool
+	// LeaderLease is the TTL of the lease held by the replica elected to dispatch scheduled payloads
+	// to replicas with Scheduler and to purge expired data. Zero turns off leader election, so every replica
+	// with Scheduler runs them and every replica purges.
+	LeaderLease time.Duration
+	// AdminToken is an API key granted admin role, f.e. for /cache endpoints.
+	AdminToken string
+	// APIKeys and JWTs signed with JWTSecret authenticate API clients and assign them roles and tenants.
+	// If neither is set, anonymous clients are granted user role,
+	// or operator role if AnonymousOperator is set.
+	APIKeys           []APIKey
+	JWTSecret         string
+	AnonymousOperator bool
+	// Retention policies are applied by the reaper every RetentionInterval.
+	// The reaper is off if policies keep all data forever or the interval is zero.
+	Retention         scrape.RetentionPolicies
+	RetentionInterval time.Duration
 }
 
 // HTMLServer represents the web service that serves up HTML
 type HTMLServer struct {
-	server *http.Server
-	wg     sync.WaitGroup
+	server    *http.Server
+	wg        sync.WaitGroup
+	scheduler *scheduler
+	reaper    *reaper
+	elector   *leaderElector
 }
 
+// instrumentFetches registers fetch histograms once, so the service may be started again f.e. in tests.
+var instrumentFetches sync.Once
+
 // Start func launches Parsing service
 func Start(cfg Config) *HTMLServer {
 	ctx := context.Background()
@@ -61,16 +94,79 @@ func Start(cfg Config) *HTMLServer {
 	// 	Name:      "request_latency_microseconds",
 	// 	Help:      "Total duration of requests in microseconds.",
 	// }, fieldKeys)
+	instrumentFetches.Do(func() {
+		scrape.InstrumentFetches(
+			kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "dfk",
+				Subsystem: "parse_service",
+				Name:      "fetch_latency_seconds",
+				Help:      "Time to fetch a page by fetcher type.",
+				Buckets:   scrape.FetchLatencyBuckets,
+			}, []string{"fetcher"}),
+			kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "dfk",
+				Subsystem: "parse_service",
+				Name:      "fetch_response_bytes",
+				Help:      "Size of fetched pages by fetcher type.",
+				Buckets:   scrape.FetchSizeBuckets,
+			}, []string{"fetcher"}),
+		)
+	})
 	var svc Service
 	svc = ParseService{}
+	svc = applyMiddlewares(svc)
 	svc = LoggingMiddleware(logger)(svc)
 	// svc = Metrics(requestCount, requestLatency)(svc)
 
 	endpoints := Endpoints{
-		ParseEndpoint: MakeParseEndpoint(svc),
+		ParseEndpoint:   MakeParseEndpoint(svc),
+		LinksEndpoint:   MakeLinksEndpoint(svc),
+		ResultsEndpoint: MakeResultsEndpoint(svc),
+		QueryEndpoint:   MakeQueryEndpoint(svc),
+
+		SnapshotsEndpoint: MakeSnapshotsEndpoint(svc),
+		ReparseEndpoint:   MakeReparseEndpoint(svc),
+		ReportEndpoint:    MakeReportEndpoint(svc),
+
+		CreatePayloadEndpoint:   MakeCreatePayloadEndpoint(svc),
+		UpdatePayloadEndpoint:   MakeUpdatePayloadEndpoint(svc),
+		GetPayloadEndpoint:      MakeGetPayloadEndpoint(svc),
+		DeletePayloadEndpoint:   MakeDeletePayloadEndpoint(svc),
+		ListPayloadsEndpoint:    MakeListPayloadsEndpoint(svc),
+		PayloadVersionsEndpoint: MakePayloadVersionsEndpoint(svc),
+		ParsePayloadEndpoint:    MakeParsePayloadEndpoint(svc),
+
+		SaveWatchEndpoint:   MakeSaveWatchEndpoint(svc),
+		GetWatchEndpoint:    MakeGetWatchEndpoint(svc),
+		DeleteWatchEndpoint: MakeDeleteWatchEndpoint(svc),
+		ListWatchesEndpoint: MakeListWatchesEndpoint(svc),
+
+		SaveCredentialsEndpoint:   MakeSaveCredentialsEndpoint(svc),
+		DeleteCredentialsEndpoint: MakeDeleteCredentialsEndpoint(svc),
+		ListCredentialsEndpoint:   MakeListCredentialsEndpoint(svc),
+
+		SuggestEndpoint: MakeSuggestEndpoint(svc),
+		AutoEndpoint:    MakeAutoEndpoint(svc),
+
+		ListCacheEndpoint:   MakeListCacheEndpoint(svc),
+		DeleteCacheEndpoint: MakeDeleteCacheEndpoint(svc),
+
+		IssueUserTokenEndpoint: MakeIssueUserTokenEndpoint(svc),
+		PurgeEndpoint:          MakePurgeEndpoint(svc),
 	}
 
-	r := NewHttpHandler(ctx, endpoints)
+	var r http.Handler
+	r = NewHttpHandler(ctx, endpoints)
+	r = applyHTTPMiddlewares(r)
+	access, err := newAccessControl(cfg.AdminToken, cfg.APIKeys, cfg.JWTSecret, cfg.AnonymousOperator)
+	if err != nil {
+		logger.Fatal("Invalid access control settings. " + err.Error())
+	}
+	//requests are rate limited after authentication, so unverified tokens don't get limits of their own
+	if cfg.RateLimit > 0 || cfg.Quota > 0 {
+		r = newRateLimiter(cfg.RateLimit, cfg.RateLimitWindow, cfg.Quota, cfg.QuotaPeriod).Handler(r)
+	}
+	r = access.Handler(r)
 
 	// Create the HTML Server
 	htmlServer := HTMLServer{
@@ -82,6 +178,21 @@ func Start(cfg Config) *HTMLServer {
 			MaxHeaderBytes: 1 << 20,
 		},
 	}
+	reaping := !cfg.Retention.Empty() && cfg.RetentionInterval > 0
+	if cfg.LeaderLease > 0 && (cfg.Scheduler || reaping) {
+		htmlServer.elector = newLeaderElector(cfg.LeaderLease, logger)
+		if err := htmlServer.elector.start(); err != nil {
+			logger.Fatal("Invalid leader lease settings. " + err.Error())
+		}
+	}
+	if cfg.Scheduler {
+		htmlServer.scheduler = newScheduler(svc, logger, htmlServer.elector)
+		htmlServer.scheduler.start()
+	}
+	if reaping {
+		htmlServer.reaper = newReaper(cfg.Retention, cfg.RetentionInterval, logger, htmlServer.elector)
+		htmlServer.reaper.start()
+	}
 	// Add to the WaitGroup for the listener goroutine
 	htmlServer.wg.Add(1)
 
@@ -113,6 +224,15 @@ func (htmlServer *HTMLServer) Stop() error {
 	}
 	// Wait for the listener to report that it is closed.
 	htmlServer.wg.Wait()
+	if htmlServer.scheduler != nil {
+		htmlServer.scheduler.shutdown()
+	}
+	if htmlServer.reaper != nil {
+		htmlServer.reaper.shutdown()
+	}
+	if htmlServer.elector != nil {
+		htmlServer.elector.shutdown()
+	}
 	fmt.Printf("\nFetch Server : Stopped\n")
 	return nil
 }
@@ -1,11 +1,16 @@
 package parse
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -64,3 +69,25 @@ func TestHealthCheckHandler(t *testing.T) {
 	body, _ := ioutil.ReadAll(resp.Body)
 	assert.Equal(t, []byte(`{"alive": true}`), body)
 }
+
+func TestDecodeParseRequest_tooLarge(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("x", maxPayloadSize) + `"}`
+	req := httptest.NewRequest("POST", "/parse", strings.NewReader(body))
+	_, err := DecodeParseRequest(context.Background(), req)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(errs.StatusError).Status())
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("payload", `{"name":"large urls","request":{"url":"http://example.com"}}`)
+	urls, _ := mw.CreateFormFile("urls", "urls.txt")
+	urls.Write([]byte(strings.Repeat("http://example.com/\n", (maxPayloadSize+multipartOverhead)/20+1)))
+	mw.Close()
+	req = httptest.NewRequest("POST", "/parse", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	_, err = DecodeParseRequest(context.Background(), req)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(errs.StatusError).Status())
+	}
+}
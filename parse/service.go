@@ -1,14 +1,44 @@
 package parse
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"io/ioutil"
 
+	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
 )
 
 // Service defines Parse service interface
 type Service interface {
 	Parse(scrape.Payload) (io.ReadCloser, error)
+	Links(fetch.Request) (io.ReadCloser, error)
+	Results(scrape.ResultsRequest) (io.ReadCloser, error)
+	Query(scrape.Query) (io.ReadCloser, error)
+	Snapshots(scrape.SnapshotRequest) (io.ReadCloser, error)
+	Reparse(scrape.ReparseRequest) (io.ReadCloser, error)
+	Report(scrape.ReportRequest) (io.ReadCloser, error)
+	CreatePayload(scrape.Payload) (io.ReadCloser, error)
+	UpdatePayload(scrape.Payload) (io.ReadCloser, error)
+	GetPayload(scrape.PayloadRequest) (io.ReadCloser, error)
+	DeletePayload(scrape.PayloadRequest) (io.ReadCloser, error)
+	ListPayloads() (io.ReadCloser, error)
+	PayloadVersions(scrape.PayloadRequest) (io.ReadCloser, error)
+	ParsePayload(scrape.PayloadRequest) (io.ReadCloser, error)
+	SaveWatch(scrape.Watch) (io.ReadCloser, error)
+	GetWatch(scrape.WatchRequest) (io.ReadCloser, error)
+	DeleteWatch(scrape.WatchRequest) (io.ReadCloser, error)
+	ListWatches() (io.ReadCloser, error)
+	SaveCredentials(scrape.Credentials) (io.ReadCloser, error)
+	DeleteCredentials(scrape.CredentialsRequest) (io.ReadCloser, error)
+	ListCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error)
+	Suggest(scrape.SuggestRequest) (io.ReadCloser, error)
+	Auto(fetch.Request) (io.ReadCloser, error)
+	ListCache(scrape.CacheRequest) (io.ReadCloser, error)
+	DeleteCache(scrape.CacheRequest) (io.ReadCloser, error)
+	IssueUserToken(fetch.UserTokenRequest) (io.ReadCloser, error)
+	Purge(scrape.PurgeRequest) (io.ReadCloser, error)
 }
 
 // ParseService implements service with empty struct
@@ -18,12 +48,271 @@ type ParseService struct {
 // ServiceMiddleware defines a middleware for a Parse service
 type ServiceMiddleware func(Service) Service
 
+// newTask creates a task processing payload p. See resolvePayload.
+func newTask(p scrape.Payload) (*scrape.Task, error) {
+	p, err := resolvePayload(p)
+	if err != nil {
+		return nil, err
+	}
+	return scrape.NewTask(p), nil
+}
+
+// resolvePayload returns payload p extended from the base payload of the registry
+// with imported recording, credentials from the vault and expanded variables.
+func resolvePayload(p scrape.Payload) (scrape.Payload, error) {
+	if p.Extends != "" {
+		r := scrape.NewRegistry()
+		resolved, err := r.Resolve(p)
+		r.Close()
+		if err != nil {
+			return p, err
+		}
+		p = resolved
+	}
+	req, err := p.Recording.Apply(p.Request)
+	if err != nil {
+		return p, err
+	}
+	p.Request = req
+	if p.Credentials != "" {
+		v := scrape.NewVault()
+		defer v.Close()
+		return v.Apply(p)
+	}
+	return p.ExpandVars()
+}
+
 //Parse service processes fetched page following the rules from Payload.
 func (ps ParseService) Parse(p scrape.Payload) (io.ReadCloser, error) {
-	task := scrape.NewTask(p)
+	task, err := newTask(p)
+	if err != nil {
+		return nil, err
+	}
 	r, err := task.Parse()
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
+
+//Links service returns JSON encoded list of all links found on a web page specified by Request.
+func (ps ParseService) Links(req fetch.Request) (io.ReadCloser, error) {
+	links, err := scrape.Links(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+//Results service returns JSON encoded page of parse results specified by ResultsRequest.
+func (ps ParseService) Results(req scrape.ResultsRequest) (io.ReadCloser, error) {
+	page, err := scrape.GetResults(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+//Reparse service processes payload over page snapshots stored by parse job instead of fetching pages again.
+func (ps ParseService) Reparse(req scrape.ReparseRequest) (io.ReadCloser, error) {
+	task, err := newTask(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return task.Reparse(req.ID)
+}
+
+//Report service returns JSON encoded report of the latest job run with the results ID.
+func (ps ParseService) Report(req scrape.ReportRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.GetReport(req))
+}
+
+//Snapshots service returns HTML of the page stored by parse job exactly as it was fetched.
+//JSON encoded list of all stored pages of the job is returned if no URL is specified.
+func (ps ParseService) Snapshots(req scrape.SnapshotRequest) (io.ReadCloser, error) {
+	if req.URL == "" {
+		return jsonReadCloser(scrape.GetSnapshots(req))
+	}
+	data, err := scrape.GetSnapshot(req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+//Query service returns JSON encoded records of previous runs matching Query.
+func (ps ParseService) Query(q scrape.Query) (io.ReadCloser, error) {
+	res, err := scrape.QueryResults(q)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// jsonReadCloser returns JSON encoded v.
+func jsonReadCloser(v interface{}, err error) (io.ReadCloser, error) {
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+//CreatePayload service saves a new named payload to the registry.
+func (ps ParseService) CreatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(r.Create(p))
+}
+
+//UpdatePayload service saves a new version of the payload to the registry.
+func (ps ParseService) UpdatePayload(p scrape.Payload) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(r.Update(p))
+}
+
+//GetPayload service returns specified version of the payload from the registry.
+func (ps ParseService) GetPayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(r.Get(req))
+}
+
+//DeletePayload service removes the payload along with all its versions from the registry.
+func (ps ParseService) DeletePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(req, r.Delete(req))
+}
+
+//ListPayloads service returns all payloads saved to the registry.
+func (ps ParseService) ListPayloads() (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(r.List())
+}
+
+//PayloadVersions service returns version history of the payload.
+func (ps ParseService) PayloadVersions(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	defer r.Close()
+	return jsonReadCloser(r.Versions(req))
+}
+
+//ParsePayload service processes a payload saved to the registry.
+func (ps ParseService) ParsePayload(req scrape.PayloadRequest) (io.ReadCloser, error) {
+	r := scrape.NewRegistry()
+	p, err := r.Get(req)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+	p.RequestID = req.RequestID
+	p.Tenant = req.Tenant
+	if len(req.Vars) > 0 {
+		vars := map[string]string{}
+		for k, v := range p.Vars {
+			vars[k] = v
+		}
+		for k, v := range req.Vars {
+			vars[k] = v
+		}
+		p.Vars = vars
+	}
+	return ps.Parse(*p)
+}
+
+//SaveWatch service creates or replaces a watch of a single element.
+func (ps ParseService) SaveWatch(watch scrape.Watch) (io.ReadCloser, error) {
+	w := scrape.NewWatches()
+	defer w.Close()
+	return jsonReadCloser(w.Save(watch))
+}
+
+//GetWatch service returns time series of the watched value.
+func (ps ParseService) GetWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	w := scrape.NewWatches()
+	defer w.Close()
+	return jsonReadCloser(w.Series(req))
+}
+
+//DeleteWatch service removes the watch along with its time series.
+func (ps ParseService) DeleteWatch(req scrape.WatchRequest) (io.ReadCloser, error) {
+	w := scrape.NewWatches()
+	defer w.Close()
+	return jsonReadCloser(req, w.Delete(req))
+}
+
+//ListWatches service returns all watches with their latest values.
+func (ps ParseService) ListWatches() (io.ReadCloser, error) {
+	w := scrape.NewWatches()
+	defer w.Close()
+	return jsonReadCloser(w.List())
+}
+
+//SaveCredentials service encrypts and stores credentials payloads may refer to by name.
+func (ps ParseService) SaveCredentials(c scrape.Credentials) (io.ReadCloser, error) {
+	v := scrape.NewVault()
+	defer v.Close()
+	return jsonReadCloser(v.Save(c))
+}
+
+//DeleteCredentials service removes credentials from the vault.
+func (ps ParseService) DeleteCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	v := scrape.NewVault()
+	defer v.Close()
+	return jsonReadCloser(req, v.Delete(req))
+}
+
+//ListCredentials service returns names and domains of credentials of the tenant. Secrets are never returned.
+func (ps ParseService) ListCredentials(req scrape.CredentialsRequest) (io.ReadCloser, error) {
+	v := scrape.NewVault()
+	defer v.Close()
+	return jsonReadCloser(v.List(req.Tenant))
+}
+
+//Suggest service returns JSON encoded candidate CSS selectors matching example values on a web page.
+func (ps ParseService) Suggest(req scrape.SuggestRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.SuggestSelectors(req))
+}
+
+//Auto service detects the dominant repeating structure on a web page and returns JSON encoded records with best-guess fields.
+func (ps ParseService) Auto(req fetch.Request) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.AutoExtract(req))
+}
+
+//ListCache service returns JSON encoded stored items matching the filter with their sizes and ages.
+func (ps ParseService) ListCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.ListCache(req))
+}
+
+//DeleteCache service deletes stored items matching the filter and returns their number.
+func (ps ParseService) DeleteCache(req scrape.CacheRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.DeleteCache(req))
+}
+
+//IssueUserToken service returns JSON encoded new signed user token.
+func (ps ParseService) IssueUserToken(req fetch.UserTokenRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(fetch.IssueUserToken(req))
+}
+
+//Purge service deletes data of jobs, tenants or URLs and returns JSON encoded summary of deleted data.
+func (ps ParseService) Purge(req scrape.PurgeRequest) (io.ReadCloser, error) {
+	return jsonReadCloser(scrape.Purge(req))
+}
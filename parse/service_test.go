@@ -1,9 +1,11 @@
 package parse
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/slotix/dataflowkit/extract"
 	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
 	"github.com/spf13/viper"
@@ -85,13 +87,20 @@ func Test_service(t *testing.T) {
 	time.Sleep(500 * time.Millisecond)
 	svc := ParseService{}
 	result, err := svc.Parse(payloadBase)
-	assert.NoError(t, err)
+	if !assert.NoError(t, err) {
+		return
+	}
 	assert.NotNil(t, result)
+	parseInfo := make(map[string]interface{})
+	err = json.NewDecoder(result).Decode(&parseInfo)
+	assert.NoError(t, err)
 
 	//start parse server
 	parseServerAddr := "127.0.0.1:8001"
 	serverCfg := Config{
 		Host: parseServerAddr,
+		//registry requests are sent without credentials
+		AnonymousOperator: true,
 	}
 	parseServer := Start(serverCfg)
 	defer parseServer.Stop()
@@ -110,6 +119,64 @@ func Test_service(t *testing.T) {
 	_, err = svc1.Parse(invPayload)
 	assert.Error(t, err)
 
+	//Links
+	result, err = svc1.Links(fetch.Request{URL: "http://127.0.0.1:12345"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	links := []extract.Link{}
+	err = json.NewDecoder(result).Decode(&links)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, links)
+
+	_, err = svc1.Links(fetch.Request{})
+	assert.Error(t, err)
+
+	//Results
+	result, err = svc1.Results(scrape.ResultsRequest{ID: parseInfo["Results ID"].(string), Limit: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	page := scrape.ResultsPage{}
+	err = json.NewDecoder(result).Decode(&page)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Limit)
+	assert.NotZero(t, page.Total)
+	assert.NotEmpty(t, page.Results)
+
+	_, err = svc1.Results(scrape.ResultsRequest{ID: "notexists"})
+	assert.Error(t, err)
+
+	//Payload registry
+	result, err = svc1.CreatePayload(payloadBase)
+	assert.NoError(t, err)
+	_, err = svc1.UpdatePayload(payloadBase)
+	assert.NoError(t, err)
+	result, err = svc1.GetPayload(scrape.PayloadRequest{Name: payloadBase.Name, Version: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	p := scrape.Payload{}
+	err = json.NewDecoder(result).Decode(&p)
+	assert.NoError(t, err)
+	assert.Equal(t, payloadBase.Request.URL, p.Request.URL)
+	result, err = svc1.PayloadVersions(scrape.PayloadRequest{Name: payloadBase.Name})
+	if !assert.NoError(t, err) {
+		return
+	}
+	versions := []scrape.PayloadVersion{}
+	err = json.NewDecoder(result).Decode(&versions)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	_, err = svc1.ListPayloads()
+	assert.NoError(t, err)
+	_, err = svc1.ParsePayload(scrape.PayloadRequest{Name: payloadBase.Name})
+	assert.NoError(t, err)
+	_, err = svc1.DeletePayload(scrape.PayloadRequest{Name: payloadBase.Name})
+	assert.NoError(t, err)
+	_, err = svc1.GetPayload(scrape.PayloadRequest{Name: payloadBase.Name})
+	assert.Error(t, err)
+
 	//Invalid Payload - no fields
 	invPayload = scrape.Payload{
 		Name: "invalid payload",
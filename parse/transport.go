@@ -2,9 +2,14 @@ package parse
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/kit/endpoint"
 
@@ -14,22 +19,357 @@ import (
 	"github.com/gorilla/mux"
 	stdprometheus "github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
 	"github.com/slotix/dataflowkit/scrape"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
 )
 
+// maxPayloadSize limits the size of payloads read from request bodies.
+const maxPayloadSize = 32 << 20
+
+// multipartOverhead is allowed for boundaries and part headers of multipart requests over maxPayloadSize.
+const multipartOverhead = 1 << 20
+
 //DecodeParseRequest decodes request sent to Parser
 //if error occures, server returns 400 Bad Request
+//Payloads may be encoded either in JSON or in YAML. YAML payloads are detected by Content-Type header.
+//Multipart form requests carry the payload in "payload" field and URL list file in "urls" field.
+//Payloads larger than maxPayloadSize are refused with 413 Request Entity Too Large.
 func DecodeParseRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		p, err := decodeMultipartPayload(r)
+		if err != nil {
+			return nil, err
+		}
+		p.RequestID = utils.RequestIDFromContext(ctx)
+		p.Tenant = tenantFromContext(ctx)
+		return p, nil
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxPayloadSize))
+	if err != nil {
+		if tooLarge, ok := payloadTooLarge(err); ok {
+			return nil, tooLarge
+		}
+		return nil, err
+	}
+	p, err := scrape.UnmarshalPayload(data, scrape.IsYAML(r.Header.Get("Content-Type")))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Request.ConvertSplash(); err != nil {
+		return nil, err
+	}
+	p.RequestID = utils.RequestIDFromContext(ctx)
+	p.Tenant = tenantFromContext(ctx)
+	return p, nil
+}
+
+// payloadTooLarge returns 413 Request Entity Too Large error if reading of the request body failed with err
+// because the body exceeds the size limit.
+func payloadTooLarge(err error) (errs.StatusError, bool) {
+	var maxBytes *http.MaxBytesError
+	if !errors.As(err, &maxBytes) {
+		return errs.StatusError{}, false
+	}
+	return errs.StatusError{
+		Code: http.StatusRequestEntityTooLarge,
+		Err:  fmt.Errorf("payload exceeds size limit of %d bytes", maxBytes.Limit),
+	}, true
+}
+
+// decodeMultipartPayload decodes payload uploaded along with URL list file.
+// JSON is a subset of YAML so the payload is decoded as YAML.
+func decodeMultipartPayload(r *http.Request) (scrape.Payload, error) {
 	var p scrape.Payload
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxPayloadSize+multipartOverhead)
+	if err := r.ParseMultipartForm(maxPayloadSize); err != nil {
+		if tooLarge, ok := payloadTooLarge(err); ok {
+			return p, tooLarge
+		}
+		return p, errs.BadPayload{ErrText: err.Error()}
+	}
+	data := []byte(r.FormValue("payload"))
+	if f, _, err := r.FormFile("payload"); err == nil {
+		data, err = ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return p, err
+		}
+	}
+	p, err := scrape.UnmarshalPayload(data, true)
+	if err != nil {
+		return p, errs.BadPayload{ErrText: err.Error()}
+	}
+	if err := p.Request.ConvertSplash(); err != nil {
+		return p, err
+	}
+	f, header, err := r.FormFile("urls")
+	if err == http.ErrMissingFile {
+		return p, nil
+	}
+	if err != nil {
+		return p, errs.BadPayload{ErrText: err.Error()}
+	}
+	defer f.Close()
+	list, err := ioutil.ReadAll(f)
+	if err != nil {
+		return p, err
+	}
+	if p.URLList == nil {
+		p.URLList = &scrape.URLList{}
+	}
+	p.URLList.Data = string(list)
+	p.URLList.Location = ""
+	switch ext := strings.ToLower(filepath.Ext(header.Filename)); {
+	case p.URLList.Format != "":
+	case ext == ".csv", ext == ".json", ext == ".txt":
+		p.URLList.Format = ext[1:]
+	}
+	return p, nil
+}
+
+//DecodeLinksRequest decodes request sent to Links endpoint
+func DecodeLinksRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req fetch.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	if err := req.ConvertSplash(); err != nil {
+		return nil, err
+	}
+	if req.URL == "" {
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	}
+	req.RequestID = utils.RequestIDFromContext(ctx)
+	return req, nil
+}
+
+//DecodeResultsRequest decodes request sent to Results endpoint.
+//Results ID is taken from the path, offset and limit are taken from the query string.
+func DecodeResultsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req := scrape.ResultsRequest{
+		ID: mux.Vars(r)["id"],
+	}
+	q := r.URL.Query()
+	for name, v := range map[string]*int{"offset": &req.Offset, "limit": &req.Limit} {
+		if q.Get(name) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(q.Get(name))
+		if err != nil {
+			return nil, errs.BadPayload{ErrText: "invalid " + name + " value " + q.Get(name)}
+		}
+		*v = n
+	}
+	return req, nil
+}
+
+//DecodeSnapshotsRequest decodes request sent to Snapshots endpoint.
+//Results ID is taken from the path, run and page URL are taken from the query string.
+func DecodeSnapshotsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return scrape.SnapshotRequest{
+		ID:  mux.Vars(r)["id"],
+		Run: r.URL.Query().Get("run"),
+		URL: r.URL.Query().Get("url"),
+	}, nil
+}
+
+//DecodeReportRequest decodes request sent to Report endpoint. Results ID is taken from the path.
+func DecodeReportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return scrape.ReportRequest{ID: mux.Vars(r)["id"]}, nil
+}
+
+//DecodeReparseRequest decodes request sent to Reparse endpoint.
+//Results ID is taken from the path, the payload is taken from the body.
+func DecodeReparseRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	p, err := DecodeParseRequest(ctx, r)
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	return scrape.ReparseRequest{
+		ID:      mux.Vars(r)["id"],
+		Payload: p.(scrape.Payload),
+	}, nil
+}
+
+//DecodeQueryRequest decodes request sent to Query endpoint
+func DecodeQueryRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var q scrape.Query
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	if err := authorizeResults(ctx, q.ID); err != nil {
 		return nil, err
 	}
+	return q, nil
+}
+
+//DecodeUpdatePayloadRequest decodes request sent to UpdatePayload endpoint.
+//Payload name is taken from the path.
+func DecodeUpdatePayloadRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req, err := DecodeParseRequest(ctx, r)
+	if err != nil {
+		if _, ok := err.(errs.StatusError); ok {
+			return nil, err
+		}
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	p := req.(scrape.Payload)
+	p.Name = mux.Vars(r)["name"]
 	return p, nil
 }
 
+//DecodePayloadRequest decodes requests referring to a payload in the registry.
+//Payload name is taken from the path, optional version is taken from the query string.
+func DecodePayloadRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req := scrape.PayloadRequest{
+		Name: mux.Vars(r)["name"],
+	}
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 0 {
+			return nil, errs.BadPayload{ErrText: "invalid version value " + v}
+		}
+		req.Version = version
+	}
+	return req, nil
+}
+
+//DecodeParsePayloadRequest decodes request sent to ParsePayload endpoint.
+//Request body may contain variables overriding payload ones: {"vars": {"query": "laptops"}}
+func DecodeParsePayloadRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req, err := DecodePayloadRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	payloadReq := req.(scrape.PayloadRequest)
+	var body struct {
+		Vars map[string]string `json:"vars"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	payloadReq.Vars = body.Vars
+	payloadReq.RequestID = utils.RequestIDFromContext(ctx)
+	payloadReq.Tenant = tenantFromContext(ctx)
+	return payloadReq, nil
+}
+
+//DecodeSaveWatchRequest decodes request sent to SaveWatch endpoint.
+//Watch name is taken from the path if it is present.
+func DecodeSaveWatchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var watch scrape.Watch
+	if err := json.NewDecoder(r.Body).Decode(&watch); err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	if name := mux.Vars(r)["name"]; name != "" {
+		watch.Name = name
+	}
+	watch.Request.RequestID = utils.RequestIDFromContext(ctx)
+	return watch, nil
+}
+
+//DecodeWatchRequest decodes requests referring to a watch.
+//Watch name is taken from the path, optional limit of returned points is taken from the query string.
+func DecodeWatchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req := scrape.WatchRequest{
+		Name: mux.Vars(r)["name"],
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, errs.BadPayload{ErrText: "invalid limit value " + v}
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+//DecodeSaveCredentialsRequest decodes request sent to SaveCredentials endpoint.
+//Credentials name is taken from the path if it is present.
+func DecodeSaveCredentialsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var c scrape.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	if name := mux.Vars(r)["name"]; name != "" {
+		c.Name = name
+	}
+	c.Tenant = tenantFromContext(ctx)
+	return c, nil
+}
+
+//DecodeCredentialsRequest decodes requests referring to credentials by the name taken from the path.
+//Credentials of the client's tenant are referred to.
+func DecodeCredentialsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return scrape.CredentialsRequest{Name: mux.Vars(r)["name"], Tenant: tenantFromContext(ctx)}, nil
+}
+
+//DecodeSuggestRequest decodes request sent to Suggest endpoint
+func DecodeSuggestRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req scrape.SuggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	if err := req.Request.ConvertSplash(); err != nil {
+		return nil, err
+	}
+	req.Request.RequestID = utils.RequestIDFromContext(ctx)
+	return req, nil
+}
+
+//DecodeUserTokenRequest decodes request sent to IssueUserToken endpoint. Request body is optional.
+func DecodeUserTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req fetch.UserTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	return req, nil
+}
+
+//DecodePurgeRequest decodes request sent to Purge endpoint
+func DecodePurgeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req scrape.PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errs.BadPayload{ErrText: err.Error()}
+	}
+	return req, nil
+}
+
+//DecodeCacheRequest decodes request sent to cache admin endpoints. Filter is taken from the query string.
+func DecodeCacheRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := scrape.CacheRequest{
+		Filter: storage.Filter{
+			Type:   q.Get("type"),
+			Prefix: q.Get("prefix"),
+			Domain: q.Get("domain"),
+		},
+	}
+	if v := q.Get("olderThan"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errs.BadPayload{ErrText: "invalid olderThan value " + v}
+		}
+		req.OlderThan = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errs.BadPayload{ErrText: "invalid limit value " + v}
+		}
+		req.Limit = n
+	}
+	return req, nil
+}
+
+func decodeEmptyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
 //EncodeParseResponse encodes response returned by Parser
-func EncodeParseResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
-	ctx := context.Background()
+func EncodeParseResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	data, err := ioutil.ReadAll(response.(io.Reader))
 	if err != nil {
 		encodeError(ctx, err, w)
@@ -45,27 +385,56 @@ func EncodeParseResponse(_ context.Context, w http.ResponseWriter, response inte
 	return nil
 }
 
-// encodeError encodes erroneous responses and writes http status header.
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	switch e := err.(type) {
-	case errs.Error:
-		// We can retrieve the status here and write out a specific
-		// HTTP status code.
-		http.Error(w, e.Error(), e.Status())
-	//case errs.BadPayload:
-	//	http.Error(w, e.Error(), e.Status())
-	default:
-		// Any error types we don't specifically look out for default
-		// to serving a HTTP 500
-		http.Error(w, err.Error(),
-			http.StatusInternalServerError)
-	}
+//EncodeSnapshotResponse encodes HTML of stored page snapshot
+func EncodeSnapshotResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return EncodeParseResponse(ctx, w, response)
+}
+
+// encodeError encodes erroneous responses as errs.Response and writes http status header.
+// Error message contains correlation ID of the request.
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	utils.RequestIDToHTTP(ctx, w)
+	id := utils.RequestIDFromContext(ctx)
+	errs.NewResponse(err, utils.ErrorWithRequestID(err.Error(), id), id).Write(w)
 }
 
 // Endpoints wrapper
 type Endpoints struct {
 	ParseEndpoint endpoint.Endpoint
+	LinksEndpoint   endpoint.Endpoint
+	ResultsEndpoint endpoint.Endpoint
+	QueryEndpoint   endpoint.Endpoint
+
+	SnapshotsEndpoint endpoint.Endpoint
+	ReparseEndpoint   endpoint.Endpoint
+	ReportEndpoint    endpoint.Endpoint
+
+	CreatePayloadEndpoint   endpoint.Endpoint
+	UpdatePayloadEndpoint   endpoint.Endpoint
+	GetPayloadEndpoint      endpoint.Endpoint
+	DeletePayloadEndpoint   endpoint.Endpoint
+	ListPayloadsEndpoint    endpoint.Endpoint
+	PayloadVersionsEndpoint endpoint.Endpoint
+	ParsePayloadEndpoint    endpoint.Endpoint
+
+	SaveWatchEndpoint   endpoint.Endpoint
+	GetWatchEndpoint    endpoint.Endpoint
+	DeleteWatchEndpoint endpoint.Endpoint
+	ListWatchesEndpoint endpoint.Endpoint
+
+	SaveCredentialsEndpoint   endpoint.Endpoint
+	DeleteCredentialsEndpoint endpoint.Endpoint
+	ListCredentialsEndpoint   endpoint.Endpoint
+
+	SuggestEndpoint endpoint.Endpoint
+	AutoEndpoint    endpoint.Endpoint
+
+	ListCacheEndpoint   endpoint.Endpoint
+	DeleteCacheEndpoint endpoint.Endpoint
+
+	IssueUserTokenEndpoint endpoint.Endpoint
+	PurgeEndpoint          endpoint.Endpoint
 }
 
 // MakeParseEndpoint creates Parse Endpoint
@@ -79,6 +448,200 @@ func MakeParseEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+// MakeLinksEndpoint creates Links Endpoint
+func MakeLinksEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		v, err := svc.Links(request.(fetch.Request))
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// MakeResultsEndpoint creates Results Endpoint
+func MakeResultsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		v, err := svc.Results(request.(scrape.ResultsRequest))
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// MakeSnapshotsEndpoint creates Snapshots Endpoint
+func MakeSnapshotsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Snapshots(request.(scrape.SnapshotRequest))
+	}
+}
+
+// MakeReparseEndpoint creates Reparse Endpoint
+func MakeReparseEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Reparse(request.(scrape.ReparseRequest))
+	}
+}
+
+// MakeReportEndpoint creates Report Endpoint
+func MakeReportEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Report(request.(scrape.ReportRequest))
+	}
+}
+
+// MakeQueryEndpoint creates Query Endpoint
+func MakeQueryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		v, err := svc.Query(request.(scrape.Query))
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// MakeCreatePayloadEndpoint creates CreatePayload Endpoint
+func MakeCreatePayloadEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.CreatePayload(request.(scrape.Payload))
+	}
+}
+
+// MakeUpdatePayloadEndpoint creates UpdatePayload Endpoint
+func MakeUpdatePayloadEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.UpdatePayload(request.(scrape.Payload))
+	}
+}
+
+// MakeGetPayloadEndpoint creates GetPayload Endpoint
+func MakeGetPayloadEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.GetPayload(request.(scrape.PayloadRequest))
+	}
+}
+
+// MakeDeletePayloadEndpoint creates DeletePayload Endpoint
+func MakeDeletePayloadEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.DeletePayload(request.(scrape.PayloadRequest))
+	}
+}
+
+// MakeListPayloadsEndpoint creates ListPayloads Endpoint
+func MakeListPayloadsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.ListPayloads()
+	}
+}
+
+// MakePayloadVersionsEndpoint creates PayloadVersions Endpoint
+func MakePayloadVersionsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.PayloadVersions(request.(scrape.PayloadRequest))
+	}
+}
+
+// MakeParsePayloadEndpoint creates ParsePayload Endpoint
+func MakeParsePayloadEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.ParsePayload(request.(scrape.PayloadRequest))
+	}
+}
+
+// MakeSaveWatchEndpoint creates SaveWatch Endpoint
+func MakeSaveWatchEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.SaveWatch(request.(scrape.Watch))
+	}
+}
+
+// MakeGetWatchEndpoint creates GetWatch Endpoint
+func MakeGetWatchEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.GetWatch(request.(scrape.WatchRequest))
+	}
+}
+
+// MakeDeleteWatchEndpoint creates DeleteWatch Endpoint
+func MakeDeleteWatchEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.DeleteWatch(request.(scrape.WatchRequest))
+	}
+}
+
+// MakeListWatchesEndpoint creates ListWatches Endpoint
+func MakeListWatchesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.ListWatches()
+	}
+}
+
+// MakeSaveCredentialsEndpoint creates SaveCredentials Endpoint
+func MakeSaveCredentialsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.SaveCredentials(request.(scrape.Credentials))
+	}
+}
+
+// MakeDeleteCredentialsEndpoint creates DeleteCredentials Endpoint
+func MakeDeleteCredentialsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.DeleteCredentials(request.(scrape.CredentialsRequest))
+	}
+}
+
+// MakeListCredentialsEndpoint creates ListCredentials Endpoint
+func MakeListCredentialsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.ListCredentials(request.(scrape.CredentialsRequest))
+	}
+}
+
+// MakeSuggestEndpoint creates Suggest Endpoint
+func MakeSuggestEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Suggest(request.(scrape.SuggestRequest))
+	}
+}
+
+// MakeAutoEndpoint creates Auto Endpoint
+func MakeAutoEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Auto(request.(fetch.Request))
+	}
+}
+
+// MakeListCacheEndpoint creates ListCache Endpoint
+func MakeListCacheEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.ListCache(request.(scrape.CacheRequest))
+	}
+}
+
+// MakeDeleteCacheEndpoint creates DeleteCache Endpoint
+func MakeDeleteCacheEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.DeleteCache(request.(scrape.CacheRequest))
+	}
+}
+
+// MakeIssueUserTokenEndpoint creates IssueUserToken Endpoint
+func MakeIssueUserTokenEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.IssueUserToken(request.(fetch.UserTokenRequest))
+	}
+}
+
+// MakePurgeEndpoint creates Purge Endpoint
+func MakePurgeEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.Purge(request.(scrape.PurgeRequest))
+	}
+}
+
 //HealthCheckHandler is used to check if Parse service is alive
 func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -91,6 +654,8 @@ func NewHttpHandler(ctx context.Context, endpoint Endpoints) http.Handler {
 	r := mux.NewRouter()
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(utils.RequestIDFromHTTP),
+		httptransport.ServerAfter(utils.RequestIDToHTTP),
 	}
 
 	r.Methods("POST").Path("/parse").Handler(httptransport.NewServer(
@@ -100,6 +665,198 @@ func NewHttpHandler(ctx context.Context, endpoint Endpoints) http.Handler {
 		options...,
 	))
 
+	r.Methods("POST").Path("/links").Handler(httptransport.NewServer(
+		endpoint.LinksEndpoint,
+		DecodeLinksRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/results/{id}").Handler(httptransport.NewServer(
+		endpoint.ResultsEndpoint,
+		DecodeResultsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/results/{id}/snapshots").Handler(httptransport.NewServer(
+		endpoint.SnapshotsEndpoint,
+		DecodeSnapshotsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/results/{id}/snapshot").Queries("url", "{url}").Handler(httptransport.NewServer(
+		endpoint.SnapshotsEndpoint,
+		DecodeSnapshotsRequest,
+		EncodeSnapshotResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/results/{id}/report").Handler(httptransport.NewServer(
+		endpoint.ReportEndpoint,
+		DecodeReportRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/results/{id}/reparse").Handler(httptransport.NewServer(
+		endpoint.ReparseEndpoint,
+		DecodeReparseRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/query").Handler(httptransport.NewServer(
+		endpoint.QueryEndpoint,
+		DecodeQueryRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/suggest").Handler(httptransport.NewServer(
+		endpoint.SuggestEndpoint,
+		DecodeSuggestRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/auto").Handler(httptransport.NewServer(
+		endpoint.AutoEndpoint,
+		DecodeLinksRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	// cache admin and data purge. Requests require admin role.
+	r.Methods("GET").Path("/cache").Handler(httptransport.NewServer(
+		endpoint.ListCacheEndpoint,
+		DecodeCacheRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/cache").Handler(httptransport.NewServer(
+		endpoint.DeleteCacheEndpoint,
+		DecodeCacheRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/purge").Handler(httptransport.NewServer(
+		endpoint.PurgeEndpoint,
+		DecodePurgeRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/tokens").Handler(httptransport.NewServer(
+		endpoint.IssueUserTokenEndpoint,
+		DecodeUserTokenRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	// payload registry
+	r.Methods("POST").Path("/payloads").Handler(httptransport.NewServer(
+		endpoint.CreatePayloadEndpoint,
+		DecodeParseRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/payloads").Handler(httptransport.NewServer(
+		endpoint.ListPayloadsEndpoint,
+		decodeEmptyRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/payloads/{name}").Handler(httptransport.NewServer(
+		endpoint.GetPayloadEndpoint,
+		DecodePayloadRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("PUT").Path("/payloads/{name}").Handler(httptransport.NewServer(
+		endpoint.UpdatePayloadEndpoint,
+		DecodeUpdatePayloadRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/payloads/{name}").Handler(httptransport.NewServer(
+		endpoint.DeletePayloadEndpoint,
+		DecodePayloadRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/payloads/{name}/versions").Handler(httptransport.NewServer(
+		endpoint.PayloadVersionsEndpoint,
+		DecodePayloadRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/payloads/{name}/parse").Handler(httptransport.NewServer(
+		endpoint.ParsePayloadEndpoint,
+		DecodeParsePayloadRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	// single element watches
+	r.Methods("POST").Path("/watches").Handler(httptransport.NewServer(
+		endpoint.SaveWatchEndpoint,
+		DecodeSaveWatchRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/watches").Handler(httptransport.NewServer(
+		endpoint.ListWatchesEndpoint,
+		decodeEmptyRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/watches/{name}").Handler(httptransport.NewServer(
+		endpoint.GetWatchEndpoint,
+		DecodeWatchRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("PUT").Path("/watches/{name}").Handler(httptransport.NewServer(
+		endpoint.SaveWatchEndpoint,
+		DecodeSaveWatchRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/watches/{name}").Handler(httptransport.NewServer(
+		endpoint.DeleteWatchEndpoint,
+		DecodeWatchRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
+	// credentials vault. Stored secrets are never returned.
+	r.Methods("POST").Path("/credentials").Handler(httptransport.NewServer(
+		endpoint.SaveCredentialsEndpoint,
+		DecodeSaveCredentialsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/credentials").Handler(httptransport.NewServer(
+		endpoint.ListCredentialsEndpoint,
+		DecodeCredentialsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("PUT").Path("/credentials/{name}").Handler(httptransport.NewServer(
+		endpoint.SaveCredentialsEndpoint,
+		DecodeSaveCredentialsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/credentials/{name}").Handler(httptransport.NewServer(
+		endpoint.DeleteCredentialsEndpoint,
+		DecodeCredentialsRequest,
+		EncodeParseResponse,
+		options...,
+	))
+
 	r.Methods("GET").Path("/ping").HandlerFunc(HealthCheckHandler)
 
 	// GET /metrics
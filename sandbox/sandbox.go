@@ -0,0 +1,390 @@
+// Dataflow kit - sandbox
+//
+// Copyright © 2017-2018 Slotix s.r.o. <dm@slotix.sk>
+//
+//
+// All rights reserved. Use of this source code is governed
+// by the BSD 3-Clause License license.
+
+// Package sandbox of the Dataflow kit runs untrusted Lua scripts of payloads and legacy Splash requests.
+//
+// New creates an interpreter without access to the file system and without code loading functions.
+// Guard limits memory held by a running script. string.rep, string.gsub, string.format and table.concat
+// are capped as they allocate arbitrary amounts in a single call.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/pm"
+)
+
+// MaxString limits the length of strings built by scripts with string.rep, string.gsub, string.format,
+// table.concat and concatenation. Strings passed to a script may be longer. See Guard.
+const MaxString = 1 << 20
+
+// memCheckSteps is the minimal number of Lua instructions between walks of the script heap.
+const memCheckSteps = 256
+
+// unsafeGlobals are removed from the sandbox as they give access to the file system
+// or load code bypassing compilation checks.
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage", "print"}
+
+// New creates Lua interpreter with base, table, string and math libraries only.
+// The size of interpreter stack is limited by SCRIPT_MAX_STACK.
+func New() *lua.LState {
+	maxStack := viper.GetInt("SCRIPT_MAX_STACK")
+	if maxStack <= 0 {
+		maxStack = 1024 * 64
+	}
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   200,
+		RegistrySize:    1024,
+		RegistryMaxSize: maxStack,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, g := range unsafeGlobals {
+		L.SetGlobal(g, lua.LNil)
+	}
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(stringRep))
+		if gsub := builtin(str, "gsub"); gsub != nil {
+			str.RawSetString("gsub", L.NewFunction(stringGsub(gsub)))
+		}
+		if format := builtin(str, "format"); format != nil {
+			str.RawSetString("format", L.NewFunction(stringFormat(format)))
+		}
+	}
+	if tbl, ok := L.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		if concat := builtin(tbl, "concat"); concat != nil {
+			tbl.RawSetString("concat", L.NewFunction(tableConcat(concat)))
+		}
+	}
+	return L
+}
+
+// builtin returns Go function name of library lib.
+func builtin(lib *lua.LTable, name string) lua.LGFunction {
+	if fn, ok := lib.RawGetString(name).(*lua.LFunction); ok {
+		return fn.GFunction
+	}
+	return nil
+}
+
+// stringRep is string.rep limited to MaxString bytes.
+func stringRep(L *lua.LState) int {
+	s := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n > 0 && len(s) > 0 && n > MaxString/len(s) {
+		L.RaiseError("string.rep result is too long")
+	}
+	L.Push(lua.LString(strings.Repeat(s, n)))
+	return 1
+}
+
+// stringGsub limits string.gsub results to MaxString bytes. The length of the result is computed
+// before replacing with a string. Values of replacement tables and functions are counted as they are looked up.
+func stringGsub(gsub lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		str := L.CheckString(1)
+		pat := L.CheckString(2)
+		size := len(str)
+		tooLong := func() {
+			if size > MaxString {
+				L.RaiseError("string.gsub result is too long")
+			}
+		}
+		switch repl := L.Get(3).(type) {
+		case lua.LString:
+			matches, err := pm.Find(pat, []byte(str), 0, L.OptInt(4, -1))
+			if err != nil {
+				//the error is raised by gsub
+				break
+			}
+			for _, m := range matches {
+				size += replacementSize(string(repl), m) - (m.Capture(1) - m.Capture(0))
+				tooLong()
+			}
+		case *lua.LTable, *lua.LFunction:
+			L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+				var v lua.LValue
+				if t, ok := repl.(*lua.LTable); ok {
+					v = L.GetTable(t, L.Get(1))
+				} else {
+					n := L.GetTop()
+					L.Push(repl)
+					for i := 1; i <= n; i++ {
+						L.Push(L.Get(i))
+					}
+					L.Call(n, 1)
+					v = L.Get(-1)
+				}
+				if !lua.LVIsFalse(v) {
+					size += len(lua.LVAsString(v))
+					tooLong()
+				}
+				L.Push(v)
+				return 1
+			}))
+		}
+		return gsub(L)
+	}
+}
+
+// replacementSize returns the length of string replacement repl of match m. %0-%9 are replaced with captures.
+func replacementSize(repl string, m *pm.MatchData) int {
+	size := 0
+	for i := 0; i < len(repl); i++ {
+		if repl[i] != '%' || i == len(repl)-1 {
+			size++
+			continue
+		}
+		i++
+		c := repl[i]
+		if c < '0' || c > '9' {
+			size += 2
+			continue
+		}
+		idx := 2 * int(c-'0')
+		if idx == 2 && m.CaptureLength() == 2 {
+			//%1 is the whole match if the pattern has no captures
+			idx = 0
+		}
+		switch {
+		case idx >= m.CaptureLength():
+		case m.IsPosCapture(idx):
+			size += len(strconv.Itoa(m.Capture(idx)))
+		default:
+			size += m.Capture(idx+1) - m.Capture(idx)
+		}
+	}
+	return size
+}
+
+// stringFormat limits string.format results to MaxString bytes. Every directive is counted with its width,
+// precision and the escaped string form of its argument.
+func stringFormat(format lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		f := L.CheckString(1)
+		size := len(f)
+		arg := 2
+		for i := 0; i < len(f) && size <= MaxString; i++ {
+			if f[i] != '%' {
+				continue
+			}
+			i++
+			if i < len(f) && f[i] == '%' {
+				continue
+			}
+			//flags, width and precision
+			for ; i < len(f) && strings.IndexByte("-+ #0.123456789", f[i]) >= 0; i++ {
+				if f[i] < '0' || f[i] > '9' {
+					continue
+				}
+				n := 0
+				for ; i < len(f) && f[i] >= '0' && f[i] <= '9' && n <= MaxString; i++ {
+					n = n*10 + int(f[i]-'0')
+				}
+				size += n
+				i--
+			}
+			if arg <= L.GetTop() {
+				size += 4 * len(L.Get(arg).String())
+				arg++
+			}
+		}
+		if size > MaxString {
+			L.RaiseError("string.format result is too long")
+		}
+		return format(L)
+	}
+}
+
+// tableConcat limits table.concat results to MaxString bytes.
+func tableConcat(concat lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		tbl := L.CheckTable(1)
+		sep := L.OptString(2, "")
+		j := L.OptInt(4, tbl.Len())
+		if n := tbl.Len(); j > n {
+			j = n
+		}
+		size := 0
+		for i := L.OptInt(3, 1); i <= j; i++ {
+			size += len(lua.LVAsString(tbl.RawGetInt(i))) + len(sep)
+			if size > MaxString {
+				L.RaiseError("table.concat result is too long")
+			}
+		}
+		return concat(L)
+	}
+}
+
+// memoryGuard is the context of a sandboxed interpreter aborting scripts holding more than SCRIPT_MAX_MEMORY bytes.
+// Gopher-lua has no allocation hooks, but it polls Done of its context before every instruction.
+// Strings in registers of the running function are checked on every poll, as concatenation doubles them
+// in a few instructions. They may not be longer than MaxString or the longest string passed to the script.
+// All values reachable from globals and the call stack are measured
+// every memCheckSteps instructions or more, so large heaps are not walked too often.
+// Memory held by Go functions and values passed to the script is not accounted.
+type memoryGuard struct {
+	context.Context
+	L     *lua.LState
+	limit int
+	//maxString limits the length of strings built by concatenation
+	maxString int
+	steps     int
+	next      int
+	err       error
+	//aborted is closed once the limit is exceeded
+	aborted chan struct{}
+}
+
+// Guard returns the context of L aborting scripts holding more than SCRIPT_MAX_MEMORY bytes or building strings
+// longer than MaxString. input is the length of the longest string passed to the script, it may be longer.
+// The context is done when ctx is done as well.
+func Guard(ctx context.Context, L *lua.LState, input int) context.Context {
+	return newMemoryGuard(ctx, L, input)
+}
+
+func newMemoryGuard(ctx context.Context, L *lua.LState, input int) *memoryGuard {
+	limit := viper.GetInt("SCRIPT_MAX_MEMORY")
+	if limit <= 0 {
+		limit = 64 << 20
+	}
+	maxString := MaxString
+	if input > maxString {
+		maxString = input
+	}
+	return &memoryGuard{Context: ctx, L: L, limit: limit, maxString: maxString, next: memCheckSteps, aborted: make(chan struct{})}
+}
+
+// Done is polled by the interpreter before every instruction.
+func (g *memoryGuard) Done() <-chan struct{} {
+	if g.err == nil {
+		g.check()
+	}
+	if g.err != nil {
+		return g.aborted
+	}
+	return g.Context.Done()
+}
+
+func (g *memoryGuard) Err() error {
+	if g.err != nil {
+		return g.err
+	}
+	return g.Context.Err()
+}
+
+func (g *memoryGuard) check() {
+	for i := 1; i <= g.L.GetTop(); i++ {
+		s, ok := g.L.Get(i).(lua.LString)
+		if !ok {
+			continue
+		}
+		if len(s) > g.limit {
+			g.abort(fmt.Errorf("script memory limit of %d bytes exceeded", g.limit))
+			return
+		}
+		if len(s) > g.maxString {
+			g.abort(fmt.Errorf("script string is too long, the limit is %d bytes", g.maxString))
+			return
+		}
+	}
+	g.steps++
+	if g.steps < g.next {
+		return
+	}
+	size, visited := g.heapSize()
+	if size > g.limit {
+		g.abort(fmt.Errorf("script memory limit of %d bytes exceeded", g.limit))
+		return
+	}
+	g.steps = 0
+	//walking costs at most a quarter of instructions run in between
+	g.next = memCheckSteps
+	if 4*visited > g.next {
+		g.next = 4 * visited
+	}
+}
+
+func (g *memoryGuard) abort(err error) {
+	g.err = err
+	close(g.aborted)
+}
+
+// heapSize estimates the number of bytes held by strings and tables reachable from globals,
+// the registry and the call stack. visited is the number of values walked.
+// Walking stops as soon as the limit is exceeded.
+func (g *memoryGuard) heapSize() (size, visited int) {
+	seen := map[lua.LValue]bool{}
+	var walk func(v lua.LValue)
+	walk = func(v lua.LValue) {
+		if size > g.limit {
+			return
+		}
+		visited++
+		switch value := v.(type) {
+		case lua.LString:
+			size += len(value) + 16
+		case *lua.LTable:
+			if seen[value] {
+				return
+			}
+			seen[value] = true
+			size += 64
+			value.ForEach(func(k, v lua.LValue) {
+				size += 32
+				walk(k)
+				walk(v)
+			})
+			walk(value.Metatable)
+		case *lua.LFunction:
+			if seen[value] {
+				return
+			}
+			seen[value] = true
+			for _, uv := range value.Upvalues {
+				walk(uv.Value())
+			}
+		}
+	}
+	walk(g.L.G.Global)
+	walk(g.L.G.Registry)
+	for level := 0; ; level++ {
+		dbg, ok := g.L.GetStack(level)
+		if !ok {
+			break
+		}
+		for n := 1; ; n++ {
+			name, v := g.L.GetLocal(dbg, n)
+			if name == "" {
+				break
+			}
+			walk(v)
+		}
+		if fn, err := g.L.GetInfo("f", dbg, lua.LNil); err == nil {
+			walk(fn)
+		}
+	}
+	return size, visited
+}
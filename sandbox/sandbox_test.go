@@ -0,0 +1,62 @@
+package sandbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestNew(t *testing.T) {
+	L := New()
+	defer L.Close()
+	for _, g := range unsafeGlobals {
+		assert.Equal(t, lua.LNil, L.GetGlobal(g), g)
+	}
+	for _, g := range []string{"io", "os", "debug", "package"} {
+		assert.Equal(t, lua.LNil, L.GetGlobal(g), g)
+	}
+	assert.NoError(t, L.DoString("s = string.upper('x') .. table.concat({1, 2}) .. math.floor(3.5)"))
+	assert.Equal(t, lua.LString("X123"), L.GetGlobal("s"))
+	assert.Error(t, L.DoString("s = string.rep('x', 2^40)"))
+}
+
+func TestGuard(t *testing.T) {
+	viper.Set("SCRIPT_MAX_MEMORY", 1<<20)
+	defer viper.Set("SCRIPT_MAX_MEMORY", 0)
+	run := func(src string, input int) error {
+		L := New()
+		defer L.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		L.SetContext(Guard(ctx, L, input))
+		return L.DoString(src)
+	}
+	err := run("local s = 'x' while true do s = s .. s end", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "memory limit")
+	}
+	err = run("local t = {} for i = 1, 1e9 do t[i] = {i} end", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "memory limit")
+	}
+	assert.NoError(t, run("local t = {} for i = 1, 1000 do t[i] = 'item ' .. i end", 0))
+
+	viper.Set("SCRIPT_MAX_MEMORY", 64<<20)
+	err = run("local s = string.rep('x', 600000) s = s .. s", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "too long")
+	}
+	assert.NoError(t, run("local s = string.rep('x', 600000) s = s .. s", 2*MaxString))
+
+	//the guard is done when the parent context is done
+	L := New()
+	defer L.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	L.SetContext(Guard(ctx, L, 0))
+	assert.Error(t, L.DoString("while true do end"))
+}
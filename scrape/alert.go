@@ -0,0 +1,270 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+)
+
+// Alert rules
+const (
+	//AlertAny fires if results of a scheduled run differ from the previous run.
+	AlertAny = "any"
+	//AlertField fires if values of Field differ between the runs.
+	AlertField = "field"
+	//AlertThreshold fires if more records have numeric Field satisfying Op and Value than in the previous run,
+	//f.e. a price dropped below Value.
+	AlertThreshold = "threshold"
+)
+
+// Alert sends a notification when a scheduled run of a payload detects changes matching the rule.
+// Notification is sent to every specified channel.
+type Alert struct {
+	//Rule is one of "any" (default), "field" and "threshold".
+	Rule  string `json:"rule,omitempty"`
+	Field string `json:"field,omitempty"`
+	//Op is one of "<", "<=", ">", ">=" used by threshold rule.
+	Op    string  `json:"op,omitempty"`
+	Value float64 `json:"value,omitempty"`
+	//Slack is an incoming webhook URL of Slack channel.
+	Slack string `json:"slack,omitempty"`
+	//Webhook is an URL JSON encoded alert is posted to.
+	Webhook string `json:"webhook,omitempty"`
+	//Email lists recipients of alert. Mail is sent with SMTP server specified by SMTP_HOST.
+	Email []string `json:"email,omitempty"`
+}
+
+// AlertMessage is posted to alert webhook.
+type AlertMessage struct {
+	Payload     string `json:"payload"`
+	Rule        string `json:"rule"`
+	Summary     string `json:"summary"`
+	ResultsID   string `json:"resultsId"`
+	Run         string `json:"run"`
+	PreviousRun string `json:"previousRun"`
+	//ResultsURL refers to the latest results
+	ResultsURL string                   `json:"resultsUrl"`
+	Added      []map[string]interface{} `json:"added"`
+	Removed    []map[string]interface{} `json:"removed"`
+}
+
+// validate checks alert rule and channels.
+func (a Alert) validate() error {
+	switch a.rule() {
+	case AlertAny:
+	case AlertField:
+		if a.Field == "" {
+			return errs.BadPayload{ErrText: "field alert requires field"}
+		}
+	case AlertThreshold:
+		if a.Field == "" {
+			return errs.BadPayload{ErrText: "threshold alert requires field"}
+		}
+		switch a.Op {
+		case "<", "<=", ">", ">=":
+		default:
+			return errs.BadPayload{ErrText: fmt.Sprintf("unknown threshold operator %q", a.Op)}
+		}
+	default:
+		return errs.BadPayload{ErrText: fmt.Sprintf("unknown alert rule %q", a.Rule)}
+	}
+	return validateChannels("alert", a.Slack, a.Webhook, a.Email)
+}
+
+func (a Alert) rule() string {
+	if a.Rule == "" {
+		return AlertAny
+	}
+	return strings.ToLower(a.Rule)
+}
+
+// match reports whether diff matches alert rule.
+func (a Alert) match(diff *RunDiff) bool {
+	if !diff.Changed() {
+		return false
+	}
+	switch a.rule() {
+	case AlertField:
+		added, removed := map[string]int{}, map[string]int{}
+		for _, r := range diff.Added {
+			added[fmt.Sprint(r[a.Field])]++
+		}
+		for _, r := range diff.Removed {
+			removed[fmt.Sprint(r[a.Field])]++
+		}
+		if len(added) != len(removed) {
+			return true
+		}
+		for v, n := range added {
+			if removed[v] != n {
+				return true
+			}
+		}
+		return false
+	case AlertThreshold:
+		return a.crossing(diff.Added) > a.crossing(diff.Removed)
+	}
+	return true
+}
+
+// crossing returns the number of records satisfying threshold.
+func (a Alert) crossing(records []map[string]interface{}) int {
+	n := 0
+	for _, r := range records {
+		v, ok := toFloat(r[a.Field])
+		if !ok {
+			continue
+		}
+		if (a.Op == "<" && v < a.Value) || (a.Op == "<=" && v <= a.Value) ||
+			(a.Op == ">" && v > a.Value) || (a.Op == ">=" && v >= a.Value) {
+			n++
+		}
+	}
+	return n
+}
+
+// Notify sends alerts of payload whose rules match diff. Errors of all channels are returned together.
+func Notify(payload string, alerts []Alert, diff *RunDiff) error {
+	if diff == nil {
+		return nil
+	}
+	failed := []string{}
+	for _, a := range alerts {
+		if !a.match(diff) {
+			continue
+		}
+		msg := newAlertMessage(payload, a, diff)
+		if a.Slack != "" {
+			if err := postJSON(a.Slack, map[string]string{"text": msg.Summary}); err != nil {
+				failed = append(failed, "slack: "+err.Error())
+			}
+		}
+		if a.Webhook != "" {
+			if err := postJSON(a.Webhook, msg); err != nil {
+				failed = append(failed, "webhook: "+err.Error())
+			}
+		}
+		if len(a.Email) > 0 {
+			if err := sendMail(a.Email, "Dataflow Kit alert: "+payload, msg.Summary); err != nil {
+				failed = append(failed, "email: "+err.Error())
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to send alerts. %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func newAlertMessage(payload string, a Alert, diff *RunDiff) AlertMessage {
+	resultsURL := alertBaseURL() + "/results/" + diff.ID
+	rule := a.rule()
+	switch rule {
+	case AlertField:
+		rule += " " + a.Field
+	case AlertThreshold:
+		rule += fmt.Sprintf(" %s %s %v", a.Field, a.Op, a.Value)
+	}
+	return AlertMessage{
+		Payload:     payload,
+		Rule:        rule,
+		Summary:     fmt.Sprintf("Payload %q changed (%s): %d records added, %d removed. Results: %s", payload, rule, len(diff.Added), len(diff.Removed), resultsURL),
+		ResultsID:   diff.ID,
+		Run:         diff.Run,
+		PreviousRun: diff.PreviousRun,
+		ResultsURL:  resultsURL,
+		Added:       diff.Added,
+		Removed:     diff.Removed,
+	}
+}
+
+// alertBaseURL returns public URL of Parse service used for links in notifications.
+func alertBaseURL() string {
+	base := viper.GetString("ALERT_BASE_URL")
+	if base == "" {
+		base = "http://" + viper.GetString("DFK_PARSE")
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+func postJSON(u string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(u, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", u, resp.Status)
+	}
+	return nil
+}
+
+// sendMail sends plain text mail with SMTP server specified by SMTP_HOST.
+// PLAIN authentication is used if SMTP_USER is set.
+func sendMail(to []string, subject, body string) error {
+	host := viper.GetString("SMTP_HOST")
+	if host == "" {
+		return fmt.Errorf("SMTP_HOST is not set")
+	}
+	var auth smtp.Auth
+	if user := viper.GetString("SMTP_USER"); user != "" {
+		auth = smtp.PlainAuth("", user, viper.GetString("SMTP_PASSWORD"), strings.Split(host, ":")[0])
+	}
+	from := viper.GetString("SMTP_FROM")
+	msg, err := mailMessage(from, to, subject, body)
+	if err != nil {
+		return err
+	}
+	envelope, err := envelopeAddresses(append([]string{from}, to...))
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(host, auth, envelope[0], envelope[1:], msg)
+}
+
+// envelopeAddresses returns bare addresses of addrs for SMTP envelope. Display names like "Ops <ops@example.com>"
+// are kept in message headers only.
+func envelopeAddresses(addrs []string) ([]string, error) {
+	envelope := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email address %q", a)
+		}
+		envelope = append(envelope, addr.Address)
+	}
+	return envelope, nil
+}
+
+// mailMessage composes plain text mail. Header values come from payloads,
+// so line breaks are refused and the subject is Q-encoded.
+func mailMessage(from string, to []string, subject, body string) ([]byte, error) {
+	for _, addr := range append([]string{from}, to...) {
+		if _, err := mail.ParseAddress(addr); err != nil || strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("invalid email address %q", addr)
+		}
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return nil, fmt.Errorf("line break in mail subject %q", subject)
+	}
+	msg := "From: " + from + "\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" +
+		body + "\r\n"
+	return []byte(msg), nil
+}
@@ -0,0 +1,93 @@
+package scrape
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var alertDiff = &RunDiff{
+	ID:          "results",
+	Run:         "2",
+	PreviousRun: "1",
+	Added:       []map[string]interface{}{{"title": "a", "price": "90", "stock": "in"}},
+	Removed:     []map[string]interface{}{{"title": "a", "price": "120", "stock": "in"}},
+}
+
+func TestAlertValidate(t *testing.T) {
+	assert.NoError(t, Alert{Webhook: "http://example.com"}.validate())
+	assert.Error(t, Alert{}.validate(), "no channels")
+	assert.Error(t, Alert{Rule: "unknown", Webhook: "http://example.com"}.validate())
+	assert.Error(t, Alert{Rule: AlertField, Webhook: "http://example.com"}.validate())
+	assert.Error(t, Alert{Rule: AlertThreshold, Field: "price", Op: "~", Webhook: "http://example.com"}.validate())
+	assert.Error(t, Alert{Slack: "ftp://example.com"}.validate())
+	assert.NoError(t, Alert{Email: []string{"ops@example.com", "Ops <ops@example.org>"}}.validate())
+	assert.Error(t, Alert{Email: []string{"ops@example.com\r\nBcc: all@example.com"}}.validate())
+	assert.Error(t, Alert{Email: []string{"ops"}}.validate())
+}
+
+func TestMailMessage(t *testing.T) {
+	msg, err := mailMessage("dfk@example.com", []string{"ops@example.com"}, "Dataflow Kit alert: książki", "changed")
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg), "\r\nSubject: =?utf-8?q?Dataflow_Kit_alert:_ksi=C4=85=C5=BCki?=\r\n")
+
+	_, err = mailMessage("dfk@example.com", []string{"ops@example.com"}, "Dataflow Kit alert: books\r\nBcc: all@example.com", "changed")
+	assert.Error(t, err, "header injection")
+	_, err = mailMessage("dfk@example.com", []string{"ops@example.com\nBcc: all@example.com"}, "Dataflow Kit alert", "changed")
+	assert.Error(t, err)
+
+	//display names are kept in headers, the envelope gets bare addresses
+	msg, err = mailMessage("Dataflow Kit <dfk@example.com>", []string{"Ops Team <ops@example.com>"}, "Dataflow Kit alert", "changed")
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg), "\r\nTo: Ops Team <ops@example.com>\r\n")
+	envelope, err := envelopeAddresses([]string{"Dataflow Kit <dfk@example.com>", "Ops Team <ops@example.com>", "dev@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dfk@example.com", "ops@example.com", "dev@example.com"}, envelope)
+}
+
+func TestAlertMatch(t *testing.T) {
+	assert.True(t, Alert{}.match(alertDiff))
+	assert.False(t, Alert{}.match(&RunDiff{PreviousRun: "1"}))
+	assert.False(t, Alert{}.match(&RunDiff{Added: alertDiff.Added}), "the first run is not compared")
+	assert.True(t, Alert{Rule: AlertField, Field: "price"}.match(alertDiff))
+	assert.False(t, Alert{Rule: AlertField, Field: "stock"}.match(alertDiff))
+	assert.True(t, Alert{Rule: AlertThreshold, Field: "price", Op: "<", Value: 100}.match(alertDiff))
+	assert.False(t, Alert{Rule: AlertThreshold, Field: "price", Op: ">", Value: 100}.match(alertDiff))
+}
+
+func TestNotify(t *testing.T) {
+	viper.Set("ALERT_BASE_URL", "https://dfk.example.com/")
+	defer viper.Set("ALERT_BASE_URL", "")
+	var slack map[string]string
+	var hook AlertMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slack" {
+			json.NewDecoder(r.Body).Decode(&slack)
+			return
+		}
+		if r.URL.Path == "/hook" {
+			json.NewDecoder(r.Body).Decode(&hook)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	err := Notify("books", []Alert{
+		{Rule: AlertThreshold, Field: "price", Op: "<", Value: 100, Slack: ts.URL + "/slack", Webhook: ts.URL + "/hook"},
+		{Rule: AlertField, Field: "stock", Webhook: ts.URL + "/unmatched"},
+	}, alertDiff)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(slack["text"], `Payload "books" changed (threshold price < 100)`))
+	assert.Equal(t, "https://dfk.example.com/results/results", hook.ResultsURL)
+	assert.Len(t, hook.Added, 1)
+
+	err = Notify("books", []Alert{{Webhook: ts.URL + "/fail"}}, alertDiff)
+	assert.Error(t, err)
+	assert.NoError(t, Notify("books", []Alert{{Webhook: ts.URL + "/fail"}}, nil))
+}
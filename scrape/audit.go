@@ -0,0 +1,82 @@
+package scrape
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+)
+
+// auditKey is the key of the record holding the audit log of deleted data.
+const auditKey = "audit"
+
+// AuditEntry records deletion of data by a purge request or the reaper, so deletions may be proven later.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	//Action is "purge" or "reap"
+	Action string `json:"action"`
+	//Tenant, ID and URL select purged data, see PurgeRequest
+	Tenant string `json:"tenant,omitempty"`
+	ID     string `json:"id,omitempty"`
+	URL    string `json:"url,omitempty"`
+	PurgeResult
+}
+
+func readAudit(s storage.Store) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	rec := storage.Record{Type: storage.BINARY, Key: auditKey}
+	if !s.IsExists(rec) {
+		return entries, nil
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// updateAudit replaces the audit log with the result of update holding the lock of the log.
+func updateAudit(s storage.Store, update func([]AuditEntry) []AuditEntry) error {
+	unlock, err := storage.Lock(s, auditKey)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := readAudit(s)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(update(entries))
+	if err != nil {
+		return err
+	}
+	return s.Write(storage.Record{Type: storage.BINARY, Key: auditKey, Value: data})
+}
+
+// audit appends e to the audit log.
+func audit(s storage.Store, e AuditEntry) error {
+	return updateAudit(s, func(entries []AuditEntry) []AuditEntry {
+		return append(entries, e)
+	})
+}
+
+// reapAudit deletes audit log entries older than policies of their tenants allow at now and returns their number.
+func reapAudit(s storage.Store, p RetentionPolicies, now time.Time) (int, error) {
+	reaped := 0
+	err := updateAudit(s, func(entries []AuditEntry) []AuditEntry {
+		kept := []AuditEntry{}
+		for _, e := range entries {
+			ttl := p.forTenant(e.Tenant).Audit
+			if ttl > 0 && int64(now.Sub(e.Time)/time.Second) > ttl {
+				reaped++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		return kept
+	})
+	return reaped, err
+}
@@ -0,0 +1,245 @@
+package scrape
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/paginate"
+	"github.com/slotix/dataflowkit/utils"
+	"golang.org/x/net/html"
+)
+
+// minRepeats is the minimum number of siblings with the same structure to be considered as a list.
+const minRepeats = 3
+
+// AutoResult contains records extracted from the dominant repeating structure of a web page.
+// Its Payload may be used as a starting point for writing payloads.
+type AutoResult struct {
+	//ItemSelector selects every list or grid item
+	ItemSelector string `json:"itemSelector"`
+	//Items is the number of detected items
+	Items   int                      `json:"items"`
+	Payload Payload                  `json:"payload"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// priceRe matches price-like text
+var priceRe = regexp.MustCompile(`(?i)([$€£¥₽]\s?\d[\d\s.,]*\d|\d[\d\s.,]*\s?([$€£¥₽]|usd|eur|gbp))`)
+
+// autoField describes a best-guess field found inside an item
+type autoField struct {
+	name string
+	typ  string
+	//selector relative to item
+	selector string
+}
+
+// AutoExtract downloads a web page, detects the dominant repeating DOM structure (list or grid items)
+// and extracts best-guess fields from every item: title, link, image and price.
+func AutoExtract(req fetch.Request) (*AutoResult, error) {
+	if req.URL == "" {
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	}
+	content, err := fetchContent(req)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	res, err := autoDetect(doc, extract.DocumentBaseURL(doc.Selection, req.URL))
+	if err != nil {
+		return nil, err
+	}
+	res.Payload.Request = req
+	if d := paginate.Detect(doc.Selection); d != nil {
+		res.Payload.Paginator = &paginator{
+			Selector:  d.Selector,
+			Attribute: d.Attribute,
+			Type:      d.Type,
+		}
+	}
+	return res, nil
+}
+
+// signature identifies elements with the same structure: tag name and sorted class names.
+func signature(n *html.Node) string {
+	cls := classes(n)
+	sort.Strings(cls)
+	return strings.Join(append([]string{n.Data}, cls...), ".")
+}
+
+// descendants returns the number of element nodes under n
+func descendants(n *html.Node) int {
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			count += 1 + descendants(c)
+		}
+	}
+	return count
+}
+
+// autoDetect finds a group of sibling elements sharing the same signature with the highest score.
+// Score grows with the number of items and with item richness, so navigation menus lose to product cards.
+func autoDetect(doc *goquery.Document, baseURL string) (*AutoResult, error) {
+	var (
+		bestScore  float64
+		bestParent *html.Node
+		bestSig    string
+	)
+	doc.Find("body, body *").Each(func(i int, s *goquery.Selection) {
+		parent := s.Get(0)
+		groups := map[string][]*html.Node{}
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				sig := signature(c)
+				groups[sig] = append(groups[sig], c)
+			}
+		}
+		for sig, nodes := range groups {
+			if len(nodes) < minRepeats {
+				continue
+			}
+			size := 0
+			for _, n := range nodes {
+				size += descendants(n)
+			}
+			avg := float64(size) / float64(len(nodes))
+			score := float64(len(nodes)) * math.Sqrt(1+math.Min(avg, 50))
+			if score > bestScore {
+				bestScore, bestParent, bestSig = score, parent, sig
+			}
+		}
+	})
+	if bestParent == nil {
+		return nil, &errs.NoBlocksToParse{URL: baseURL}
+	}
+	itemSelector := nodePath(doc, bestParent) + " > " + bestSig
+	items := doc.Find(itemSelector)
+
+	fields := []autoField{}
+	items.EachWithBreak(func(i int, item *goquery.Selection) bool {
+		fields = guessFields(item)
+		return len(fields) == 0
+	})
+
+	res := &AutoResult{
+		ItemSelector: itemSelector,
+		Items:        items.Length(),
+		Payload: Payload{
+			Name:   "auto",
+			Format: "json",
+		},
+		Records: []map[string]interface{}{},
+	}
+	for _, f := range fields {
+		res.Payload.Fields = append(res.Payload.Fields, Field{
+			Name:      f.name,
+			Selector:  strings.TrimSpace(itemSelector + " " + f.selector),
+			Extractor: Extractor{Types: []string{f.typ}, Filters: []string{"trim"}},
+		})
+	}
+	items.Each(func(i int, item *goquery.Selection) {
+		record := map[string]interface{}{}
+		for _, f := range fields {
+			sel := item
+			if f.selector != "" {
+				sel = item.Find(f.selector).First()
+			}
+			if v := fieldValue(sel, f.typ, baseURL); v != "" {
+				record[f.name] = v
+			}
+		}
+		if len(record) > 0 {
+			res.Records = append(res.Records, record)
+		}
+	})
+	return res, nil
+}
+
+// nodePath returns selector of n starting from body, like the one built by DividePageByIntersection.
+func nodePath(doc *goquery.Document, n *html.Node) string {
+	if n.Data == "body" {
+		return "body"
+	}
+	sel := doc.FindNodes(n)
+	path := []string{attrOrDataValue(sel)}
+	sel.ParentsUntil("body").Each(func(i int, s *goquery.Selection) {
+		path = append([]string{attrOrDataValue(s)}, path...)
+	})
+	return "body > " + strings.Join(path, " > ")
+}
+
+// relPath returns selector of n relative to item
+func relPath(item, n *html.Node) string {
+	path := []string{}
+	for p := n; p != nil && p != item; p = p.Parent {
+		compound := p.Data
+		if cls := classes(p); len(cls) > 0 {
+			compound += "." + strings.Join(cls, ".")
+		}
+		path = append([]string{compound}, path...)
+	}
+	return strings.Join(path, " > ")
+}
+
+// guessFields looks for title, link, image and price inside item.
+func guessFields(item *goquery.Selection) []autoField {
+	root := item.Get(0)
+	fields := []autoField{}
+	field := func(name, typ string, sel *goquery.Selection) {
+		if sel.Length() == 0 {
+			return
+		}
+		fields = append(fields, autoField{name: name, typ: typ, selector: relPath(root, sel.Get(0))})
+	}
+	//title is a heading or the link with the longest text
+	title := item.Find("h1, h2, h3, h4, h5, h6, [class*=title], [class*=name]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return strings.TrimSpace(s.Text()) != ""
+	}).First()
+	links := item.Find("a[href]")
+	if goquery.NodeName(item) == "a" {
+		links = item
+	}
+	if title.Length() == 0 {
+		longest := 0
+		links.Each(func(i int, s *goquery.Selection) {
+			if l := len(strings.TrimSpace(s.Text())); l > longest {
+				longest = l
+				title = s
+			}
+		})
+	}
+	field("title", "text", title)
+	field("link", "href", links.First())
+	field("image", "src", item.Find("img[src]").First())
+	price := item.Find("*").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Children().Length() == 0 && priceRe.MatchString(s.Text())
+	}).First()
+	field("price", "text", price)
+	return fields
+}
+
+// fieldValue returns text or attribute value of sel. Relative URLs are resolved against baseURL.
+func fieldValue(sel *goquery.Selection, typ, baseURL string) string {
+	if typ == "text" {
+		return strings.TrimSpace(spaceRe.ReplaceAllString(sel.Text(), " "))
+	}
+	v, ok := sel.Attr(typ)
+	if !ok {
+		return ""
+	}
+	if abs, err := utils.RelUrl(baseURL, v); err == nil {
+		return abs
+	}
+	return v
+}
@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const autoHTML = `<html><body>
+<ul class="menu">
+	<li><a href="/">Home</a></li>
+	<li><a href="/shop">Shop</a></li>
+	<li><a href="/about">About</a></li>
+</ul>
+<div id="catalog">
+	<div class="item">
+		<img src="/img/1.jpg">
+		<h3><a href="/p/1">Red Chair</a></h3>
+		<p class="desc">Wooden chair</p>
+		<span class="price">$10.50</span>
+	</div>
+	<div class="item">
+		<img src="/img/2.jpg">
+		<h3><a href="/p/2">Blue Table</a></h3>
+		<p class="desc">Oak table</p>
+		<span class="price">$120</span>
+	</div>
+	<div class="item">
+		<img src="/img/3.jpg">
+		<h3><a href="/p/3">Green Sofa</a></h3>
+		<p class="desc">Leather sofa</p>
+		<span class="price">$999</span>
+	</div>
+</div>
+</body></html>`
+
+func TestAutoDetect(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(autoHTML))
+	assert.NoError(t, err)
+
+	res, err := autoDetect(doc, "http://example.com")
+	assert.NoError(t, err)
+	//product cards win over navigation menu
+	assert.Equal(t, "body > #catalog > div.item", res.ItemSelector)
+	assert.Equal(t, 3, res.Items)
+	assert.Len(t, res.Records, 3)
+	assert.Equal(t, map[string]interface{}{
+		"title": "Red Chair",
+		"link":  "http://example.com/p/1",
+		"image": "http://example.com/img/1.jpg",
+		"price": "$10.50",
+	}, res.Records[0])
+	assert.Len(t, res.Payload.Fields, 4)
+	assert.Equal(t, "body > #catalog > div.item h3", res.Payload.Fields[0].Selector)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(`<html><body><p>Nothing here</p></body></html>`))
+	assert.NoError(t, err)
+	_, err = autoDetect(doc, "http://example.com")
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package scrape
+
+import (
+	"sync"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// newPageSlots returns page slots limiting the number of fetched pages of a job held in memory
+// until their blocks are extracted and stored. Pages are not limited if MAX_PENDING_PAGES is 0.
+func newPageSlots() chan struct{} {
+	n := viper.GetInt("MAX_PENDING_PAGES")
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// acquirePage takes a page slot before the page is fetched. It waits while the pages taken before
+// are not processed, so fetching slows down to the pace of extraction and result storage
+// instead of buffering fetched pages.
+func (task *Task) acquirePage(url string) error {
+	if task.pageSlots == nil {
+		return nil
+	}
+	select {
+	case task.pageSlots <- struct{}{}:
+		return nil
+	default:
+	}
+	task.log().Debug("Fetching waits for extraction of previous pages", zap.String("URL", url))
+	select {
+	case task.pageSlots <- struct{}{}:
+		return nil
+	case <-task.ctx.Done():
+		return &errs.Cancel{}
+	}
+}
+
+// releasePage frees the page slot taken by acquirePage.
+func (task *Task) releasePage() {
+	if task.pageSlots != nil {
+		<-task.pageSlots
+	}
+}
+
+// releasePageAfter frees the page slot once blocks of the page processed by block workers are done.
+func (task *Task) releasePageAfter(blocks *sync.WaitGroup) {
+	blocks.Wait()
+	task.releasePage()
+}
+
+// blockDone marks the block processed.
+func (task *Task) blockDone(block *blockStruct) {
+	if block.wg != nil {
+		block.wg.Done()
+	} else {
+		task.jobDone.Done()
+	}
+	if block.page != nil {
+		block.page.Done()
+	}
+}
@@ -0,0 +1,44 @@
+package scrape
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageSlots(t *testing.T) {
+	viper.Set("MAX_PENDING_PAGES", 0)
+	assert.Nil(t, newPageSlots())
+
+	viper.Set("MAX_PENDING_PAGES", 1)
+	defer viper.Set("MAX_PENDING_PAGES", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &Task{ctx: ctx, Cancel: cancel, pageSlots: newPageSlots()}
+	assert.NoError(t, task.acquirePage("http://example.com/1"))
+
+	//the next page waits until blocks of the previous one are done
+	blocks := &sync.WaitGroup{}
+	blocks.Add(1)
+	go task.releasePageAfter(blocks)
+	acquired := make(chan error)
+	go func() { acquired <- task.acquirePage("http://example.com/2") }()
+	select {
+	case <-acquired:
+		t.Fatal("page slot is taken before the previous page is processed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	task.blockDone(&blockStruct{wg: wg, page: blocks})
+	assert.NoError(t, <-acquired)
+
+	//waiting stops when the job is cancelled
+	go func() { acquired <- task.acquirePage("http://example.com/3") }()
+	cancel()
+	assert.IsType(t, &errs.Cancel{}, <-acquired)
+}
@@ -0,0 +1,260 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// sourceURLField is the name of the field holding the seed URL a record of batch payload is extracted from.
+const sourceURLField = "source_url"
+
+// SeedReport describes the result of processing a seed URL of batch payload.
+type SeedReport struct {
+	URL string `json:"url"`
+	//Status is either "OK", "Unchanged" if the seed page hasn't changed since the previous run, "Failed"
+	//or "Skipped" if the job has stopped before the seed was fetched or the seed is excluded by URL patterns.
+	Status string `json:"status"`
+	//Records is the number of records extracted from the seed page and its paginated pages.
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Seed is a seed URL of batch payload. It is written either as a URL string or as an object
+// overriding request settings for the URL, f.e. a POST search among GET details pages.
+type Seed struct {
+	URL string `json:"url"`
+	//Method overrides HTTP method of payload request.
+	Method string `json:"method,omitempty"`
+	//FormData overrides form data of payload request. The page is requested with POST method if it is not empty.
+	FormData string `json:"formData,omitempty"`
+	//Body overrides request body of payload request, f.e. JSON document of API request.
+	Body string `json:"body,omitempty"`
+	//Headers and Cookies are added to headers and cookies of payload request. They replace headers with the same names
+	//and cookies with the same name, domain and path.
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies fetch.Cookies     `json:"cookies,omitempty"`
+	//Type overrides fetcher type of payload request.
+	Type string `json:"type,omitempty"`
+}
+
+// UnmarshalJSON decodes seed written as a URL string or as an object.
+func (s *Seed) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		*s = Seed{}
+		return json.Unmarshal(trimmed, &s.URL)
+	}
+	type seedFields Seed
+	return json.Unmarshal(data, (*seedFields)(s))
+}
+
+// MarshalJSON encodes seed without overrides as a URL string.
+func (s Seed) MarshalJSON() ([]byte, error) {
+	if s.Method == "" && s.FormData == "" && s.Body == "" && len(s.Headers) == 0 && len(s.Cookies) == 0 && s.Type == "" {
+		return json.Marshal(s.URL)
+	}
+	type seedFields Seed
+	return json.Marshal(seedFields(s))
+}
+
+// request returns base request with seed URL and overrides applied.
+func (s Seed) request(base fetch.Request) fetch.Request {
+	req := base
+	req.URL = s.URL
+	if s.Method != "" {
+		req.Method = strings.ToUpper(s.Method)
+	}
+	if s.FormData != "" {
+		req.FormData, req.Body = s.FormData, ""
+	}
+	if s.Body != "" {
+		req.Body, req.FormData = s.Body, ""
+	}
+	if s.Type != "" {
+		req.Type = s.Type
+	}
+	req.Headers = mergeValues(base.Headers, s.Headers)
+	req.Cookies = base.Cookies.Merge(s.Cookies)
+	return req
+}
+
+// mergeValues returns values of base replaced and extended by values of override.
+func mergeValues(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// batch reports whether payload carries a list of seed URLs.
+func (p Payload) batch() bool {
+	return len(p.URLs) > 0 || p.URLList != nil
+}
+
+// initSeeds makes the first seed of batch payload the initial URL if request URL is omitted.
+func (p *Payload) initSeeds() {
+	if p.Request.URL != "" {
+		return
+	}
+	if seeds := p.seeds(); len(seeds) > 0 {
+		p.Request.URL = seeds[0].URL
+	}
+}
+
+// loadURLList adds seeds of payload URL list to payload URLs.
+func (task *Task) loadURLList() error {
+	if task.Payload.URLList == nil {
+		return nil
+	}
+	seeds, err := task.Payload.URLList.load()
+	if err != nil {
+		return err
+	}
+	if len(seeds) == 0 && len(task.Payload.seeds()) == 0 {
+		return errs.BadPayload{ErrText: "URL list is empty"}
+	}
+	task.seedVars = make(map[string]map[string]string, len(seeds))
+	for _, s := range seeds {
+		task.Payload.URLs = append(task.Payload.URLs, Seed{URL: s.URL})
+		if _, ok := task.seedVars[s.URL]; !ok {
+			task.seedVars[s.URL] = s.Vars
+		}
+	}
+	task.Payload.initSeeds()
+	return nil
+}
+
+// seeds returns request URL followed by URLs of batch payload. Empty and repeated seeds are skipped.
+// The same URL requested with different overrides, f.e. form data, is kept.
+func (p Payload) seeds() []Seed {
+	seeds := []Seed{}
+	seen := map[string]bool{}
+	for _, s := range append([]Seed{{URL: p.Request.URL}}, p.URLs...) {
+		s.URL = strings.TrimSpace(s.URL)
+		key, err := json.Marshal(s)
+		if s.URL == "" || err != nil || seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		seeds = append(seeds, s)
+	}
+	return seeds
+}
+
+// addSeedFields sets the seed URL the record is extracted from along with per-URL variables of the seed
+// if payload is a batch one.
+func (p Payload) addSeedFields(record map[string]interface{}, seed string, vars map[string]string) {
+	if !p.batch() || seed == "" {
+		return
+	}
+	record[sourceURLField] = seed
+	for name, v := range vars {
+		record[name] = v
+	}
+}
+
+// scrapeSeeds scrapes the initial page of payload. Seed URLs of batch payload are scraped in turn with payload request settings
+// and their own overrides.
+// Pages of every seed are numbered after the pages of the previous one, so results keep the order of seeds.
+// A failed seed doesn't stop the job. Its error is reported in task seed reports instead.
+// Pages of the next seeds are fetched while the previous ones are extracted. See prefetcher.
+func (task *Task) scrapeSeeds(tw *taskWorker) error {
+	if !task.Payload.batch() {
+		task.jobDone.Add(1)
+		_, err := task.scrape(tw)
+		task.jobDone.Wait()
+		return err
+	}
+	seeds := task.Payload.seeds()
+	reports := make([]SeedReport, len(seeds))
+	var firstErr error
+	//seeds excluded by URL patterns are skipped without fetching
+	allowed := []int{}
+	for i, s := range seeds {
+		reports[i] = SeedReport{URL: s.URL, Status: "Skipped"}
+		if !task.urlFilter.allowed(s.URL) {
+			reports[i].Error = errExcludedURL.Error()
+			if firstErr == nil {
+				firstErr = errs.BadPayload{ErrText: errExcludedURL.Error()}
+			}
+			continue
+		}
+		allowed = append(allowed, i)
+	}
+	reqs := make([]fetch.Request, len(allowed))
+	for j, i := range allowed {
+		reqs[j] = seeds[i].request(tw.scraper.Request)
+	}
+	//seed pages are fetched ahead while the previous seeds are extracted
+	pf := task.newPrefetcher("initial", reqs)
+	defer pf.close()
+	succeeded := false
+	for j, i := range allowed {
+		s := seeds[i]
+		if task.ctx.Err() != nil || task.limiter.limitReached() != "" {
+			break
+		}
+		scraper := *tw.scraper
+		scraper.Request = reqs[j]
+		firstPage, records := task.pagesTaken(tw.keys)
+		seedTW := taskWorker{
+			currentPageNum:  firstPage,
+			firstPageNum:    firstPage,
+			scraper:         &scraper,
+			UID:             tw.UID,
+			useBlockCounter: tw.useBlockCounter,
+			keys:            tw.keys,
+			seed:            s.URL,
+			seedNum:         j,
+			prefetched:      pf.take(),
+		}
+		task.jobDone.Add(1)
+		_, err := task.scrape(&seedTW)
+		task.jobDone.Wait()
+		_, total := task.pagesTaken(tw.keys)
+		reports[i].Status = "OK"
+		reports[i].Records = total - records
+		if err == errUnchanged {
+			reports[i].Status = "Unchanged"
+			succeeded = true
+		} else if err != nil {
+			reports[i].Status = "Failed"
+			reports[i].Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			succeeded = true
+		}
+	}
+	task.mx.Lock()
+	task.seedReports = reports
+	task.mx.Unlock()
+	if !succeeded {
+		return firstErr
+	}
+	return nil
+}
+
+// pagesTaken returns the number following the last page stored in keys and the number of records stored.
+func (task *Task) pagesTaken(keys map[int][]int) (next int, records int) {
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	for page, blocks := range keys {
+		if page >= next {
+			next = page + 1
+		}
+		records += len(blocks)
+	}
+	return next, records
+}
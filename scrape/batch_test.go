@@ -0,0 +1,75 @@
+package scrape
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeeds(t *testing.T) {
+	p := Payload{Request: fetch.Request{URL: "http://example.com/a"}}
+	assert.False(t, p.batch())
+	assert.Equal(t, []Seed{{URL: "http://example.com/a"}}, p.seeds())
+	record := map[string]interface{}{"title": "A"}
+	p.addSeedFields(record, "http://example.com/a", nil)
+	assert.NotContains(t, record, sourceURLField)
+	assert.Equal(t, []string{"title"}, p.columns([]string{"title"}))
+
+	p.URLs = []Seed{{URL: "http://example.com/b"}, {URL: " http://example.com/a"}, {}, {URL: "http://example.com/c"},
+		{URL: "http://example.com/b"}, {URL: "http://example.com/b", FormData: "q=1"}}
+	assert.True(t, p.batch())
+	assert.Equal(t, []Seed{{URL: "http://example.com/a"}, {URL: "http://example.com/b"}, {URL: "http://example.com/c"},
+		{URL: "http://example.com/b", FormData: "q=1"}}, p.seeds())
+	p.addSeedFields(record, "http://example.com/b", map[string]string{"sku": "42"})
+	assert.Equal(t, "http://example.com/b", record[sourceURLField])
+	assert.Equal(t, "42", record["sku"])
+	detect := true
+	p.DetectLanguage = &detect
+	assert.Equal(t, []string{"title", "language", "source_url"}, p.columns([]string{"title"}))
+	assert.Equal(t, []string{"source_url", "language"}, p.columns([]string{"source_url"}))
+
+	_, ok := p.streamParts()
+	assert.False(t, ok, "batch payloads are processed with DOM scraper")
+
+	//request URL may be omitted
+	p = Payload{URLs: []Seed{{URL: "http://example.com/b"}, {URL: "http://example.com/c"}},
+		Fields: []Field{{Name: "title", Selector: "h1", Extractor: Extractor{Types: []string{"text"}}}}}
+	task := NewTask(p)
+	defer task.storage.Close()
+	assert.Equal(t, "http://example.com/b", task.Payload.Request.URL)
+	assert.Equal(t, []Seed{{URL: "http://example.com/b"}, {URL: "http://example.com/c"}}, task.Payload.seeds())
+}
+
+func TestSeedOverrides(t *testing.T) {
+	var p Payload
+	err := json.Unmarshal([]byte(`{"request": {"url": "http://example.com/", "headers": {"Accept": "text/html"}},
+		"urls": ["http://example.com/item/1",
+			{"url": "http://example.com/search", "method": "post", "formData": "q=shoes",
+			 "headers": {"Referer": "http://example.com/"}, "cookies": {"region": "eu"}, "type": "chrome"}]}`), &p)
+	assert.NoError(t, err)
+	assert.Equal(t, []Seed{{URL: "http://example.com/item/1"}, {
+		URL: "http://example.com/search", Method: "post", FormData: "q=shoes",
+		Headers: map[string]string{"Referer": "http://example.com/"}, Cookies: fetch.Cookies{{Name: "region", Value: "eu"}}, Type: "chrome",
+	}}, p.URLs)
+
+	req := p.URLs[0].request(p.Request)
+	assert.Equal(t, fetch.Request{URL: "http://example.com/item/1", Headers: map[string]string{"Accept": "text/html"}}, req)
+	req = p.URLs[1].request(p.Request)
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "q=shoes", req.FormData)
+	assert.Equal(t, "chrome", req.Type)
+	assert.Equal(t, map[string]string{"Accept": "text/html", "Referer": "http://example.com/"}, req.Headers)
+	assert.Equal(t, fetch.Cookies{{Name: "region", Value: "eu"}}, req.Cookies)
+	assert.Equal(t, map[string]string{"Accept": "text/html"}, p.Request.Headers, "payload request is intact")
+	req = Seed{URL: "http://example.com/api/items/1", Method: "patch", Body: `{"stock": 0}`}.request(req)
+	assert.Equal(t, "PATCH", req.HTTPMethod())
+	assert.Equal(t, `{"stock": 0}`, req.Body)
+	assert.Empty(t, req.FormData)
+
+	//seeds without overrides are encoded as strings
+	data, err := json.Marshal(p.URLs)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `["http://example.com/item/1",{"url":"http://example.com/search"`)
+}
@@ -0,0 +1,67 @@
+package scrape
+
+import (
+	"net/http"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// CacheRequest selects stored items listed or deleted by cache admin endpoints.
+type CacheRequest struct {
+	storage.Filter
+	//Token is the admin token a client sends along with the request
+	Token string `json:"-"`
+}
+
+// CacheListing contains stored items matching CacheRequest.
+type CacheListing struct {
+	//Count and Size are the number and the total size in bytes of listed items
+	Count   int             `json:"count"`
+	Size    int64           `json:"size"`
+	Entries []storage.Entry `json:"entries"`
+}
+
+// CacheDeleted contains the number of stored items deleted by CacheRequest.
+type CacheDeleted struct {
+	Deleted int `json:"deleted"`
+}
+
+// ListCache returns stored items matching req with their sizes and ages.
+func ListCache(req CacheRequest) (*CacheListing, error) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	entries, err := storage.List(s, req.Filter)
+	if err != nil {
+		return nil, listError(err)
+	}
+	l := &CacheListing{Count: len(entries), Entries: entries}
+	for _, e := range entries {
+		l.Size += e.Size
+	}
+	return l, nil
+}
+
+// DeleteCache deletes stored items matching req. At least one of filter criteria is required.
+func DeleteCache(req CacheRequest) (*CacheDeleted, error) {
+	if req.Empty() {
+		return nil, errs.BadPayload{ErrText: "type, prefix, domain or olderThan filter required"}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	n, err := storage.DeleteMatching(s, req.Filter)
+	if err != nil {
+		return nil, listError(err)
+	}
+	return &CacheDeleted{Deleted: n}, nil
+}
+
+// listError returns 501 Not Implemented status error for storage which can't list its items
+// or delete them by type.
+func listError(err error) error {
+	if err == storage.ErrListNotSupported || err == storage.ErrTypeFilterNotSupported {
+		return errs.StatusError{Code: http.StatusNotImplemented, Err: err}
+	}
+	return err
+}
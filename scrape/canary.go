@@ -0,0 +1,163 @@
+package scrape
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// Canary lists invariants results of every scheduled run of a payload are expected to hold.
+// A run violating them is marked suspect and alerts are sent instead of change notifications.
+// Fields are referred to by record keys as they appear in results, f.e. "price_text".
+type Canary struct {
+	//MinRecords is the minimum number of records.
+	MinRecords int `json:"minRecords,omitempty"`
+	//Records are known records which must appear in results. A record matches if it has all listed values.
+	Records []map[string]interface{} `json:"records,omitempty"`
+	//Numeric lists fields which must parse as numbers in every record having them.
+	Numeric []string `json:"numeric,omitempty"`
+}
+
+// statusSuspect is the report status of a run violating canary checks.
+const statusSuspect = "Suspect"
+
+// validate checks canary invariants.
+func (c *Canary) validate() error {
+	if c.MinRecords < 0 {
+		return errs.BadPayload{ErrText: "canary minRecords should not be negative"}
+	}
+	for _, r := range c.Records {
+		if len(r) == 0 {
+			return errs.BadPayload{ErrText: "canary record should not be empty"}
+		}
+	}
+	for _, f := range c.Numeric {
+		if f == "" {
+			return errs.BadPayload{ErrText: "canary numeric field name should not be empty"}
+		}
+	}
+	return nil
+}
+
+// check returns violations of canary invariants by records.
+func (c *Canary) check(records []map[string]interface{}) []string {
+	var violations []string
+	if len(records) < c.MinRecords {
+		violations = append(violations, fmt.Sprintf("%d records, at least %d expected", len(records), c.MinRecords))
+	}
+	for _, known := range c.Records {
+		if !containsRecord(records, known) {
+			violations = append(violations, "known record "+recordKey(known)+" not found")
+		}
+	}
+	for _, f := range c.Numeric {
+		invalid := 0
+		for _, r := range records {
+			if v, ok := r[f]; ok && !emptyValue(v) {
+				if _, ok := toFloat(v); !ok {
+					invalid++
+				}
+			}
+		}
+		if invalid > 0 {
+			violations = append(violations, fmt.Sprintf("%s is not a number in %d records", f, invalid))
+		}
+	}
+	return violations
+}
+
+// containsRecord reports whether any of records has all values of known.
+func containsRecord(records []map[string]interface{}, known map[string]interface{}) bool {
+	for _, r := range records {
+		match := true
+		for k, v := range known {
+			if fmt.Sprint(r[k]) != fmt.Sprint(v) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCanary checks records of the run diff refers to against canary invariants of the payload.
+// Violations are set to diff.Suspect and the stored job report of the run is marked suspect.
+func CheckCanary(c Canary, diff *RunDiff) error {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	records, err := readRun(s, diff.ID, diff.Run)
+	if err != nil {
+		return err
+	}
+	diff.Suspect = c.check(records)
+	if len(diff.Suspect) == 0 {
+		return nil
+	}
+	r, err := GetReport(ReportRequest{ID: diff.ID})
+	if err != nil {
+		return err
+	}
+	r.Status = statusSuspect
+	r.Suspect = diff.Suspect
+	return saveReport(*r)
+}
+
+// NotifySuspect sends a notification about suspect run of payload to channels of its alerts regardless of their rules.
+// Diff of a failed run has no results ID.
+func NotifySuspect(payload string, alerts []Alert, diff *RunDiff) error {
+	summary := fmt.Sprintf("Run of payload %q is suspect: %s.", payload, strings.Join(diff.Suspect, "; "))
+	resultsURL := ""
+	if diff.ID != "" {
+		resultsURL = alertBaseURL() + "/results/" + diff.ID
+		summary += " Results: " + resultsURL
+	}
+	msg := AlertMessage{
+		Payload:     payload,
+		Rule:        "canary",
+		Summary:     summary,
+		ResultsID:   diff.ID,
+		Run:         diff.Run,
+		PreviousRun: diff.PreviousRun,
+		ResultsURL:  resultsURL,
+		Added:       []map[string]interface{}{},
+		Removed:     []map[string]interface{}{},
+	}
+	sent := map[string]bool{}
+	failed := []string{}
+	for _, a := range alerts {
+		if a.Slack != "" && !sent[a.Slack] {
+			sent[a.Slack] = true
+			if err := postJSON(a.Slack, map[string]string{"text": msg.Summary}); err != nil {
+				failed = append(failed, "slack: "+err.Error())
+			}
+		}
+		if a.Webhook != "" && !sent[a.Webhook] {
+			sent[a.Webhook] = true
+			if err := postJSON(a.Webhook, msg); err != nil {
+				failed = append(failed, "webhook: "+err.Error())
+			}
+		}
+		email := []string{}
+		for _, to := range a.Email {
+			if !sent[to] {
+				sent[to] = true
+				email = append(email, to)
+			}
+		}
+		if len(email) > 0 {
+			if err := sendMail(email, "Dataflow Kit alert: "+payload+" run is suspect", msg.Summary); err != nil {
+				failed = append(failed, "email: "+err.Error())
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to send alerts. %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package scrape
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var canaryRecords = []map[string]interface{}{
+	{"title_text": "Lamp", "price_text": "10.5"},
+	{"title_text": "Desk", "price_text": 120.0},
+	{"title_text": "Chair", "price_text": "$40"},
+	{"title_text": "Shelf"},
+}
+
+func TestCanary_check(t *testing.T) {
+	c := &Canary{
+		MinRecords: 4,
+		Records:    []map[string]interface{}{{"title_text": "Desk", "price_text": 120}},
+		Numeric:    []string{"title_text"},
+	}
+	assert.NoError(t, c.validate())
+	assert.Equal(t, []string{"title_text is not a number in 4 records"}, c.check(canaryRecords))
+
+	c = &Canary{
+		MinRecords: 5,
+		Records:    []map[string]interface{}{{"title_text": "Sofa"}},
+		Numeric:    []string{"price_text"},
+	}
+	assert.Equal(t, []string{
+		"4 records, at least 5 expected",
+		`known record {"title_text":"Sofa"} not found`,
+		"price_text is not a number in 1 records",
+	}, c.check(canaryRecords))
+	assert.Nil(t, (&Canary{}).check(nil))
+
+	assert.Error(t, (&Canary{MinRecords: -1}).validate())
+	assert.Error(t, (&Canary{Records: []map[string]interface{}{{}}}).validate())
+	assert.Error(t, (&Canary{Numeric: []string{""}}).validate())
+}
+
+func TestCheckCanary(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	data, _ := json.Marshal(canaryRecords)
+	assert.NoError(t, s.Write(storage.Record{Type: storage.BINARY, Key: runKey("results", "run1"), Value: data}))
+	s.Close()
+	assert.NoError(t, saveReport(JobReport{ResultsID: "results", Status: "Completed", Records: 4}))
+
+	diff := &RunDiff{ID: "results", Run: "run1"}
+	assert.NoError(t, CheckCanary(Canary{MinRecords: 2}, diff))
+	assert.Empty(t, diff.Suspect)
+	r, err := GetReport(ReportRequest{ID: "results"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Completed", r.Status)
+
+	assert.NoError(t, CheckCanary(Canary{MinRecords: 10}, diff))
+	assert.Equal(t, []string{"4 records, at least 10 expected"}, diff.Suspect)
+	r, err = GetReport(ReportRequest{ID: "results"})
+	assert.NoError(t, err)
+	assert.Equal(t, statusSuspect, r.Status)
+	assert.Equal(t, diff.Suspect, r.Suspect)
+
+	assert.Error(t, CheckCanary(Canary{}, &RunDiff{ID: "results", Run: "missing"}))
+}
+
+func TestNotifySuspect(t *testing.T) {
+	var slack []string
+	var hook AlertMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slack" {
+			var msg map[string]string
+			json.NewDecoder(r.Body).Decode(&msg)
+			slack = append(slack, msg["text"])
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&hook)
+	}))
+	defer ts.Close()
+	alerts := []Alert{
+		{Rule: AlertAny, Slack: ts.URL + "/slack"},
+		{Rule: AlertThreshold, Field: "price_text", Op: "<", Value: 10, Slack: ts.URL + "/slack", Webhook: ts.URL + "/hook"},
+	}
+	diff := &RunDiff{ID: "results", Run: "run2", PreviousRun: "run1", Suspect: []string{"0 records, at least 10 expected"}}
+	assert.NoError(t, NotifySuspect("shop", alerts, diff))
+	assert.Len(t, slack, 1, "channels are notified once")
+	assert.True(t, strings.Contains(slack[0], "0 records, at least 10 expected"))
+	assert.Equal(t, "canary", hook.Rule)
+	assert.Equal(t, "results", hook.ResultsID)
+
+	//failed runs have no results
+	slack = nil
+	assert.NoError(t, NotifySuspect("shop", alerts, &RunDiff{Suspect: []string{"run failed: fill rate gate failed"}}))
+	if assert.Len(t, slack, 1) {
+		assert.True(t, strings.HasSuffix(slack[0], "run failed: fill rate gate failed."))
+	}
+	assert.Equal(t, "", hook.ResultsURL)
+}
+
+func TestRecrawlStateSuspect(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	store := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer store.Close()
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	sch := Schedule{Interval: 600}
+	urls, err := recordURLRuns(store, flatPayload, sch, now, &RunDiff{PreviousRun: "1", Added: []map[string]interface{}{{"a": "b"}}, Suspect: []string{"bad"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, urls[0].Runs, "suspect runs are not compared")
+	urls, err = recordURLRuns(store, flatPayload, sch, now, &RunDiff{PreviousRun: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, urls[0].Runs)
+	assert.Equal(t, 0, urls[0].Changes)
+}
+
+func TestRegistryCanary(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	r := NewRegistry()
+	defer r.Close()
+
+	p := flatPayload
+	p.Canary = &Canary{MinRecords: 1}
+	_, err := r.Create(p)
+	assert.Error(t, err, "canary requires schedule")
+	p.Schedule = &Schedule{Interval: 600}
+	p.Canary.MinRecords = -1
+	_, err = r.Create(p)
+	assert.Error(t, err)
+	p.Canary.MinRecords = 1
+	_, err = r.Create(p)
+	assert.NoError(t, err)
+}
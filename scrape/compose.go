@@ -0,0 +1,131 @@
+package scrape
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// maxExtendsDepth limits the length of payload inheritance chain.
+const maxExtendsDepth = 10
+
+// parseExtends splits Extends value "name@version" into the payload name and version.
+// Version is 0 (the latest one) if omitted.
+func parseExtends(extends string) (PayloadRequest, error) {
+	req := PayloadRequest{Name: extends}
+	if i := strings.LastIndex(extends, "@"); i >= 0 {
+		version, err := strconv.Atoi(extends[i+1:])
+		if err != nil || version < 1 {
+			return req, errs.BadPayload{ErrText: fmt.Sprintf("invalid payload version in %q", extends)}
+		}
+		req.Name = extends[:i]
+		req.Version = version
+	}
+	return req, nil
+}
+
+// Resolve returns payload p merged with base payloads from the registry it extends.
+// Payloads which don't extend others are returned as is.
+//
+// Base payload settings are overridden by non-empty settings of extending payload.
+// Request settings are overridden one by one, so a base payload may define fetcher settings
+// while extending payloads specify URLs only.
+// Fields of a base payload are kept unless extending payload has fields with the same names which replace them.
+// Other fields of extending payload are appended.
+// Variables of a base payload serve as defaults.
+func (r *Registry) Resolve(p Payload) (Payload, error) {
+	return r.resolve(p, map[string]bool{}, 0)
+}
+
+func (r *Registry) resolve(p Payload, visited map[string]bool, depth int) (Payload, error) {
+	if p.Extends == "" {
+		return p, nil
+	}
+	if depth >= maxExtendsDepth {
+		return p, errs.BadPayload{ErrText: fmt.Sprintf("payload inheritance chain is longer than %d", maxExtendsDepth)}
+	}
+	req, err := parseExtends(p.Extends)
+	if err != nil {
+		return p, err
+	}
+	if visited[req.Name] {
+		return p, errs.BadPayload{ErrText: fmt.Sprintf("circular payload inheritance: %q", req.Name)}
+	}
+	visited[req.Name] = true
+	base, err := r.Get(req)
+	if err != nil {
+		return p, err
+	}
+	resolvedBase, err := r.resolve(*base, visited, depth+1)
+	if err != nil {
+		return p, err
+	}
+	return mergePayloads(resolvedBase, p), nil
+}
+
+// mergePayloads returns base payload extended with p.
+func mergePayloads(base, p Payload) Payload {
+	merged := base
+	request := base.Request
+	overrideNonZero(&request, &p.Request)
+
+	//Request and Fields are merged separately
+	top := p
+	top.Request = fetch.Request{}
+	top.Fields = nil
+	overrideNonZero(&merged, &top)
+
+	merged.Request = request
+	merged.Fields = mergeFields(base.Fields, p.Fields)
+	//base payload variables are defaults
+	if len(base.Vars) > 0 && len(p.Vars) > 0 {
+		merged.Vars = map[string]string{}
+		for k, v := range base.Vars {
+			merged.Vars[k] = v
+		}
+		for k, v := range p.Vars {
+			merged.Vars[k] = v
+		}
+	}
+	merged.Extends = ""
+	return merged
+}
+
+// mergeFields returns base fields with the ones having the same names replaced by fields.
+// Other fields are appended.
+func mergeFields(base, fields []Field) []Field {
+	merged := append([]Field{}, base...)
+	for _, f := range fields {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == f.Name {
+				merged[i] = f
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
+
+// overrideNonZero sets exported fields of struct pointed by dst to non-zero values of the corresponding fields of src.
+// Optional flags are pointers so the ones explicitly set to false override base values as well.
+func overrideNonZero(dst, src interface{}) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src).Elem()
+	for i := 0; i < s.NumField(); i++ {
+		if d.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if f := s.Field(i); !f.IsZero() {
+			d.Field(i).Set(f)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package scrape
+
+import (
+	"os"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePayload(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	r := NewRegistry()
+	defer r.Close()
+
+	base := Payload{
+		Name: "base",
+		Request: fetch.Request{
+			Type:   "chrome",
+			URL:    "http://127.0.0.1:12345",
+			Method: "GET",
+		},
+		Fields: []Field{
+			{Name: "Title", Selector: "h1", Extractor: Extractor{Types: []string{"text"}}},
+			{Name: "Image", Selector: "img", Extractor: Extractor{Types: []string{"src"}}},
+		},
+		Format: "json",
+	}
+	_, err := r.Create(base)
+	assert.NoError(t, err)
+
+	//fields with the same names are replaced
+	site := Payload{
+		Name:    "site",
+		Extends: "base",
+		Request: fetch.Request{URL: "http://127.0.0.1:12345/persons/page-0"},
+		Fields: []Field{
+			{Name: "Title", Selector: "h2", Extractor: Extractor{Types: []string{"text"}}},
+			{Name: "Link", Selector: "a", Extractor: Extractor{Types: []string{"href"}}},
+		},
+		Format: "csv",
+	}
+	_, err = r.Create(site)
+	assert.NoError(t, err)
+
+	p, err := r.Resolve(Payload{Name: "page", Extends: "site@1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "page", p.Name)
+	assert.Empty(t, p.Extends)
+	assert.Equal(t, "chrome", p.Request.Type)
+	assert.Equal(t, "http://127.0.0.1:12345/persons/page-0", p.Request.URL)
+	assert.Equal(t, "csv", p.Format)
+	assert.Len(t, p.Fields, 3)
+	assert.Equal(t, "h2", p.Fields[0].Selector)
+	assert.Equal(t, "Link", p.Fields[2].Name)
+
+	//flags explicitly turned off override base ones
+	on, off := true, false
+	p = mergePayloads(Payload{Snapshots: &on, Request: fetch.Request{Screenshot: &on}},
+		Payload{Snapshots: &off, Request: fetch.Request{Screenshot: &off}})
+	assert.False(t, *p.Snapshots)
+	assert.False(t, *p.Request.Screenshot)
+	p = mergePayloads(Payload{Snapshots: &on}, Payload{})
+	assert.True(t, *p.Snapshots)
+
+	//circular inheritance
+	base.Extends = "site"
+	_, err = r.Update(base)
+	assert.Error(t, err)
+
+	_, err = r.Resolve(Payload{Extends: "notexists"})
+	assert.Error(t, err)
+	_, err = r.Resolve(Payload{Extends: "base@x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,405 @@
+package scrape
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// Credentials are secrets of a web site a payload refers to by name with Payload.Credentials,
+// so usernames, passwords and session headers are not embedded in every payload file.
+// Credentials belong to the tenant of the client which saved them and only payloads of the tenant may refer to them.
+type Credentials struct {
+	Name string `json:"name"`
+	//Tenant owning the credentials. Credentials saved without a tenant are used by clients without a tenant
+	//and by scheduled runs.
+	Tenant string `json:"-"`
+	//Domain restricts the credentials to payloads fetching pages of the domain and its subdomains.
+	Domain string `json:"domain"`
+	//Username and Password are substituted for {{credentials.username}} and {{credentials.password}} payload variables
+	//in login form fields, form data, body and headers of the request.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	//FormData is sent with requests to the domain unless they have their own form data or body.
+	FormData string `json:"formData,omitempty"`
+	//Headers and Cookies are added to requests to the domain. Values set by payload take precedence.
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies fetch.Cookies     `json:"cookies,omitempty"`
+}
+
+// CredentialsInfo describes saved credentials without revealing secrets.
+type CredentialsInfo struct {
+	Name   string `json:"name"`
+	Tenant string `json:"tenant,omitempty"`
+	Domain string `json:"domain"`
+	//Headers and Cookies are names of headers and cookies of the credentials.
+	Headers []string  `json:"headers,omitempty"`
+	Cookies []string  `json:"cookies,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// CredentialsRequest refers to saved credentials.
+type CredentialsRequest struct {
+	Name string `json:"name"`
+	//Tenant of the client which sent the request
+	Tenant string `json:"-"`
+}
+
+// credentialsIndexKey is a key of the record listing all credentials.
+const credentialsIndexKey = "credentials"
+
+// credentialsMx serializes credentials index updates
+var credentialsMx sync.Mutex
+
+var errNoCredentialsKey = errs.StatusError{Code: http.StatusNotImplemented, Err: errors.New("CREDENTIALS_KEY is not set")}
+
+// credentialsID returns the name credentials of tenant are indexed and encrypted with.
+func credentialsID(tenant, name string) string {
+	if tenant == "" {
+		return name
+	}
+	return tenant + "/" + name
+}
+
+// credentialsKey returns the storage key of credentials with id.
+func credentialsKey(id string) string {
+	return "credentials-" + hex.EncodeToString(utils.GenerateMD5([]byte(id)))
+}
+
+func errCredentialsNotFound(name string) error {
+	return errs.StatusError{
+		Code: http.StatusNotFound,
+		Err:  fmt.Errorf("credentials %q not found", name),
+	}
+}
+
+// credentialsCipher returns AES-GCM cipher keyed with CREDENTIALS_KEY.
+func credentialsCipher() (cipher.AEAD, error) {
+	key := viper.GetString("CREDENTIALS_KEY")
+	if key == "" {
+		return nil, errNoCredentialsKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Vault keeps credentials in the storage. Credentials are encrypted with AES-GCM and CREDENTIALS_KEY,
+// only their names, domains and header names are stored in plain text.
+type Vault struct {
+	store storage.Store
+}
+
+// NewVault opens vault in the storage specified by STORAGE_TYPE.
+// Vault should be closed after use.
+func NewVault() *Vault {
+	return &Vault{store: storage.NewStore(viper.GetString("STORAGE_TYPE"))}
+}
+
+// Close closes storage connection.
+func (v *Vault) Close() {
+	v.store.Close()
+}
+
+func (v *Vault) index() (map[string]*CredentialsInfo, error) {
+	index := map[string]*CredentialsInfo{}
+	rec := storage.Record{Type: storage.BINARY, Key: credentialsIndexKey}
+	if !v.store.IsExists(rec) {
+		return index, nil
+	}
+	data, err := v.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (v *Vault) writeIndex(index map[string]*CredentialsInfo) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return v.store.Write(storage.Record{Type: storage.BINARY, Key: credentialsIndexKey, Value: data})
+}
+
+// Save encrypts and stores credentials replacing the ones with the same name.
+func (v *Vault) Save(c Credentials) (*CredentialsInfo, error) {
+	c.Domain = strings.Trim(strings.ToLower(strings.TrimSpace(c.Domain)), ".")
+	switch {
+	case c.Name == "":
+		return nil, errs.BadPayload{ErrText: "no credentials name provided"}
+	case strings.Contains(c.Name, "/"):
+		return nil, errs.BadPayload{ErrText: "credentials name should not contain /"}
+	case c.Domain == "" || strings.ContainsAny(c.Domain, "/:*"):
+		return nil, errs.BadPayload{ErrText: "credentials require a domain name"}
+	}
+	aead, err := credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	id := credentialsID(c.Tenant, c.Name)
+	//the name and the tenant are authenticated, so records can't be swapped
+	sealed := aead.Seal(nonce, nonce, data, []byte(id))
+
+	credentialsMx.Lock()
+	defer credentialsMx.Unlock()
+	index, err := v.index()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.store.Write(storage.Record{Type: storage.BINARY, Key: credentialsKey(id), Value: sealed}); err != nil {
+		return nil, err
+	}
+	info := &CredentialsInfo{
+		Name:    c.Name,
+		Tenant:  c.Tenant,
+		Domain:  c.Domain,
+		Headers: sortedKeys(c.Headers),
+		Cookies: cookieNames(c.Cookies),
+		Updated: time.Now().UTC(),
+	}
+	index[id] = info
+	if err := v.writeIndex(index); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// List returns descriptions of credentials of tenant sorted by name.
+func (v *Vault) List(tenant string) ([]CredentialsInfo, error) {
+	credentialsMx.Lock()
+	index, err := v.index()
+	credentialsMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	list := []CredentialsInfo{}
+	for _, info := range index {
+		if info.Tenant == tenant {
+			list = append(list, *info)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Delete removes credentials of the tenant of req.
+func (v *Vault) Delete(req CredentialsRequest) error {
+	credentialsMx.Lock()
+	defer credentialsMx.Unlock()
+	index, err := v.index()
+	if err != nil {
+		return err
+	}
+	id := credentialsID(req.Tenant, req.Name)
+	if _, ok := index[id]; !ok {
+		return errCredentialsNotFound(req.Name)
+	}
+	rec := storage.Record{Type: storage.BINARY, Key: credentialsKey(id)}
+	if v.store.IsExists(rec) {
+		if err := v.store.Delete(rec); err != nil {
+			return err
+		}
+	}
+	delete(index, id)
+	return v.writeIndex(index)
+}
+
+// get reads and decrypts credentials name of tenant.
+func (v *Vault) get(tenant, name string) (*Credentials, error) {
+	aead, err := credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+	id := credentialsID(tenant, name)
+	rec := storage.Record{Type: storage.BINARY, Key: credentialsKey(id)}
+	if !v.store.IsExists(rec) {
+		return nil, errCredentialsNotFound(name)
+	}
+	sealed, err := v.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("credentials %q are corrupted", name)
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("credentials %q can't be decrypted with CREDENTIALS_KEY", name)
+	}
+	c := &Credentials{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Apply resolves credentials of the payload tenant p refers to and expands its variables, see ExpandVars.
+// Secrets are substituted in login form fields at once. Form data of the credentials is referred to by the request
+// as {{credentials.formData}} unless payload sets form data or body. Headers and cookies of the credentials along with
+// secrets referred to by the request are attached to every fetched request of the credentials domain, see Credentials.attach.
+// An error is returned if request, seed or login URL of the payload is outside of the credentials domain.
+func (v *Vault) Apply(p Payload) (Payload, error) {
+	if p.Credentials == "" {
+		return p.ExpandVars()
+	}
+	c, err := v.get(p.Tenant, p.Credentials)
+	if err != nil {
+		return p, err
+	}
+	if p.Request.FormData == "" && p.Request.Body == "" && c.FormData != "" {
+		p.Request.FormData = "{{" + formDataVar + "}}"
+	}
+	p, err = p.expandVars(c.secrets())
+	if err != nil {
+		return p, err
+	}
+	urls := []string{p.Request.URL}
+	for _, s := range p.URLs {
+		urls = append(urls, s.URL)
+	}
+	if p.Login != nil {
+		urls = append(urls, p.Login.URL)
+	}
+	for _, u := range urls {
+		if u != "" && !inDomain(u, c.Domain) {
+			return p, errs.BadPayload{ErrText: fmt.Sprintf("credentials %q are not allowed for %s", c.Name, u)}
+		}
+	}
+	p.credentials = c
+	return p, nil
+}
+
+// formDataVar is replaced with form data of the credentials. Unlike other variables it is not escaped.
+const formDataVar = secretVarPrefix + "formData"
+
+// secrets returns values of {{credentials.*}} variables.
+func (c *Credentials) secrets() map[string]string {
+	return map[string]string{
+		secretVarPrefix + "username": c.Username,
+		secretVarPrefix + "password": c.Password,
+		formDataVar:                  c.FormData,
+	}
+}
+
+// attach returns req with credentials added if its host is within the credentials domain.
+// Headers and cookies of the credentials are used unless req sets them and {{credentials.*}} variables
+// left in form data, body and headers are expanded.
+// Requests to other hosts, f.e. paginator or details links leading off the domain, get no credentials and
+// their form data, body and headers referring to secrets are dropped.
+func (c *Credentials) attach(req fetch.Request) fetch.Request {
+	if c == nil {
+		return req
+	}
+	if !inDomain(req.URL, c.Domain) {
+		req.FormData = stripSecrets(req.FormData)
+		req.Body = stripSecrets(req.Body)
+		if len(req.Headers) > 0 {
+			headers := make(map[string]string, len(req.Headers))
+			for k, v := range req.Headers {
+				if stripSecrets(v) != "" {
+					headers[k] = v
+				}
+			}
+			req.Headers = headers
+		}
+		return req
+	}
+	secrets := c.secrets()
+	expand := func(s string, escape func(string) string) string {
+		return varRe.ReplaceAllStringFunc(s, func(m string) string {
+			name := varRe.FindStringSubmatch(m)[1]
+			v, ok := secrets[name]
+			if !ok {
+				return m
+			}
+			if name == formDataVar {
+				return v
+			}
+			return escape(v)
+		})
+	}
+	raw := func(s string) string { return s }
+	req.FormData = expand(req.FormData, url.QueryEscape)
+	req.Body = expand(req.Body, raw)
+	headers := make(map[string]string, len(c.Headers)+len(req.Headers))
+	for k, v := range mergeValues(c.Headers, req.Headers) {
+		headers[k] = expand(v, raw)
+	}
+	req.Headers = headers
+	req.Cookies = c.Cookies.Merge(req.Cookies)
+	return req
+}
+
+// stripSecrets returns s unless it refers to {{credentials.*}} variables.
+func stripSecrets(s string) string {
+	for _, m := range varRe.FindAllStringSubmatch(s, -1) {
+		if strings.HasPrefix(m[1], secretVarPrefix) {
+			return ""
+		}
+	}
+	return s
+}
+
+// inDomain reports whether host of rawurl is domain or its subdomain.
+func inDomain(rawurl, domain string) bool {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// cookieNames returns sorted unique names of cookies.
+func cookieNames(cookies fetch.Cookies) []string {
+	names := map[string]string{}
+	for _, c := range cookies {
+		names[c.Name] = ""
+	}
+	return sortedKeys(names)
+}
+
+func sortedKeys(m map[string]string) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
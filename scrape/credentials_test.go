@@ -0,0 +1,214 @@
+package scrape
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVault(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	v := NewVault()
+	defer v.Close()
+
+	c := Credentials{
+		Name:     "shopX",
+		Domain:   "ShopX.com.",
+		Username: "bot@example.com",
+		Password: "s3cret",
+		Headers:  map[string]string{"X-Api-Key": "k1", "Accept-Language": "de"},
+		Cookies:  fetch.Cookies{{Name: "region", Value: "eu"}},
+		FormData: "remember=1",
+	}
+	_, err := v.Save(c)
+	assert.Error(t, err, "no CREDENTIALS_KEY")
+
+	viper.Set("CREDENTIALS_KEY", "key1")
+	defer viper.Set("CREDENTIALS_KEY", "")
+	for _, invalid := range []Credentials{{Domain: "shopx.com"}, {Name: "shopX"}, {Name: "shopX", Domain: "http://shopx.com"}} {
+		_, err := v.Save(invalid)
+		assert.Error(t, err)
+	}
+	info, err := v.Save(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "shopx.com", info.Domain)
+	assert.Equal(t, []string{"Accept-Language", "X-Api-Key"}, info.Headers)
+	assert.Equal(t, []string{"region"}, info.Cookies)
+
+	//secrets are encrypted at rest
+	data, err := v.store.Read(storage.Record{Type: storage.BINARY, Key: credentialsKey("shopX")})
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(data), "s3cret"))
+	list, err := v.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []CredentialsInfo{*info}, list)
+
+	p := Payload{
+		Credentials: "shopX",
+		Vars:        map[string]string{"page": "orders"},
+		Request:     fetch.Request{URL: "https://www.shopx.com/{{page}}", Headers: map[string]string{"Accept-Language": "en", "X-User": "{{credentials.username}}"}},
+		Login: &Login{
+			URL: "https://shopx.com/login",
+			Form: fetch.FillForm{Fields: []fetch.FormField{
+				{Selector: "#email", Value: "{{credentials.username}}"},
+				{Selector: "#password", Value: "{{credentials.password}}"},
+			}},
+		},
+	}
+	resolved, err := v.Apply(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.shopx.com/orders", resolved.Request.URL)
+	//credentials are attached per fetch, so they are not copied to followed requests
+	assert.Equal(t, map[string]string{"Accept-Language": "en", "X-User": "{{credentials.username}}"}, resolved.Request.Headers)
+	assert.Nil(t, resolved.Request.Cookies)
+	assert.Equal(t, "{{credentials.formData}}", resolved.Request.FormData)
+	req := resolved.credentials.attach(resolved.Request)
+	assert.Equal(t, map[string]string{"X-Api-Key": "k1", "Accept-Language": "en", "X-User": "bot@example.com"}, req.Headers)
+	assert.Equal(t, fetch.Cookies{{Name: "region", Value: "eu"}}, req.Cookies)
+	assert.Equal(t, "remember=1", req.FormData)
+	assert.Equal(t, "{{credentials.username}}", resolved.Request.Headers["X-User"], "payload request is not modified")
+	//pages of other hosts get no credentials
+	next := resolved.Request
+	next.URL = "https://cdn.example.org/orders?page=2"
+	off := resolved.credentials.attach(next)
+	assert.Equal(t, map[string]string{"Accept-Language": "en"}, off.Headers)
+	assert.Empty(t, off.Cookies)
+	assert.Empty(t, off.FormData)
+	assert.Equal(t, next, (*Credentials)(nil).attach(next))
+	assert.Equal(t, "bot@example.com", resolved.Login.Form.Fields[0].Value)
+	assert.Equal(t, "s3cret", resolved.Login.Form.Fields[1].Value)
+	assert.Equal(t, "{{credentials.password}}", p.Login.Form.Fields[1].Value, "payload is not modified")
+	assert.Nil(t, resolved.Vars)
+
+	//secrets are not expanded into values ending up in results
+	leak := p
+	leak.Fields = []Field{{Name: "pass", Extractor: Extractor{Types: []string{"const"}, Params: map[string]interface{}{"value": "{{credentials.password}}"}}}}
+	_, err = v.Apply(leak)
+	assert.EqualError(t, err, "credentials variables are only allowed in form data, body, headers and login form: credentials.password")
+	leak = p
+	leak.Request.URL = "https://shopx.com/?p={{credentials.password}}"
+	_, err = v.Apply(leak)
+	assert.Error(t, err)
+
+	//credentials are not available to other tenants
+	other := p
+	other.Tenant = "acme"
+	_, err = v.Apply(other)
+	assert.Error(t, err)
+	acme := c
+	acme.Tenant = "acme"
+	acme.Password = "acme-secret"
+	_, err = v.Save(acme)
+	assert.NoError(t, err)
+	resolved, err = v.Apply(other)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-secret", resolved.Login.Form.Fields[1].Value)
+	list, err = v.List("acme")
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "acme", list[0].Tenant)
+	}
+	assert.NoError(t, v.Delete(CredentialsRequest{Name: "shopX", Tenant: "acme"}))
+
+	//credentials are not sent to other domains
+	p.URLs = []Seed{{URL: "https://evilshopx.com/"}}
+	_, err = v.Apply(p)
+	assert.Error(t, err)
+
+	p.Credentials = "missing"
+	_, err = v.Apply(p)
+	assert.Error(t, err)
+
+	viper.Set("CREDENTIALS_KEY", "key2")
+	p.Credentials = "shopX"
+	p.URLs = nil
+	_, err = v.Apply(p)
+	assert.Error(t, err, "wrong key")
+	viper.Set("CREDENTIALS_KEY", "key1")
+
+	assert.NoError(t, v.Delete(CredentialsRequest{Name: "shopX"}))
+	assert.Error(t, v.Delete(CredentialsRequest{Name: "shopX"}))
+	list, err = v.List("")
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestCredentials_crossHostPaginator(t *testing.T) {
+	os.RemoveAll("./diskv")
+	os.RemoveAll("./results")
+	defer os.RemoveAll("./diskv")
+	defer os.RemoveAll("./results")
+	viper.Set("CREDENTIALS_KEY", "key1")
+	defer viper.Set("CREDENTIALS_KEY", "")
+	viper.Set("MAX_PAGES", 2)
+	fetchServer := fetch.Start(fetch.Config{Host: viper.GetString("DFK_FETCH")})
+	defer fetchServer.Stop()
+
+	mx := sync.Mutex{}
+	received := map[string]http.Header{}
+	record := func(site string, r *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		if r.URL.Path != "/robots.txt" {
+			received[site] = r.Header
+		}
+	}
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("other", r)
+		w.Write([]byte(`<html><body><ul><li class="item">b</li></ul></body></html>`))
+	}))
+	defer other.Close()
+	shop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("shop", r)
+		w.Write([]byte(`<html><body><ul><li class="item">a</li></ul><a class="next" href="` + other.URL + `/page/2">next</a></body></html>`))
+	}))
+	defer shop.Close()
+
+	v := NewVault()
+	defer v.Close()
+	_, err := v.Save(Credentials{
+		Name:     "shop",
+		Domain:   "localhost",
+		Password: "s3cret",
+		Headers:  map[string]string{"X-Api-Key": "k1"},
+		Cookies:  fetch.Cookies{{Name: "sid", Value: "42"}},
+	})
+	assert.NoError(t, err)
+	p, err := v.Apply(Payload{
+		Name:        "credentials",
+		Credentials: "shop",
+		Request: fetch.Request{
+			URL:     strings.Replace(shop.URL, "127.0.0.1", "localhost", 1) + "/list",
+			Headers: map[string]string{"X-Pass": "{{credentials.password}}"},
+		},
+		Fields:    []Field{{Name: "item", Selector: ".item", Extractor: Extractor{Types: []string{"text"}}}},
+		Paginator: &paginator{Selector: "a.next", Attribute: "href", Type: "next", MaxPages: 2},
+		Format:    "json",
+	})
+	assert.NoError(t, err)
+	_, err = NewTask(p).Parse()
+	assert.NoError(t, err)
+
+	mx.Lock()
+	defer mx.Unlock()
+	if assert.NotNil(t, received["shop"]) {
+		assert.Equal(t, "k1", received["shop"].Get("X-Api-Key"))
+		assert.Equal(t, "s3cret", received["shop"].Get("X-Pass"))
+		assert.Contains(t, received["shop"].Get("Cookie"), "sid=42")
+	}
+	//the next page is on another host
+	if assert.NotNil(t, received["other"], "paginator is followed") {
+		assert.Empty(t, received["other"].Get("X-Api-Key"))
+		assert.Empty(t, received["other"].Get("X-Pass"))
+		assert.NotContains(t, received["other"].Get("Cookie"), "sid=42")
+	}
+}
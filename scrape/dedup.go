@@ -0,0 +1,124 @@
+package scrape
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+const (
+	// shingleSize is the number of consecutive words hashed together.
+	shingleSize = 3
+	// nearDuplicateDistance is the maximum number of different bits of fingerprints of near-duplicate pages.
+	// Fingerprints of unrelated pages differ in about 32 bits.
+	nearDuplicateDistance = 6
+)
+
+// errNearDuplicate is returned for pages skipped as near-duplicates of already extracted ones.
+var errNearDuplicate = errors.New("Near-duplicate page is skipped")
+
+// dedup keeps simhash fingerprints of pages extracted by the task.
+type dedup struct {
+	mx           sync.Mutex
+	fingerprints []uint64
+	collapsed    int
+}
+
+// duplicate reports whether page text is a near-duplicate of one of already seen pages.
+// Otherwise its fingerprint is remembered. Pages without text are never duplicates.
+func (d *dedup) duplicate(text string) bool {
+	if d == nil {
+		return false
+	}
+	fp, ok := simhash(text)
+	if !ok {
+		return false
+	}
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	for _, seen := range d.fingerprints {
+		if bits.OnesCount64(fp^seen) <= nearDuplicateDistance {
+			d.collapsed++
+			return true
+		}
+	}
+	d.fingerprints = append(d.fingerprints, fp)
+	return false
+}
+
+// count returns the number of collapsed near-duplicates.
+func (d *dedup) count() int {
+	if d == nil {
+		return 0
+	}
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	return d.collapsed
+}
+
+// simhash returns 64-bit simhash fingerprint of word shingles of text.
+// It returns false if text contains no words.
+func simhash(text string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0, false
+	}
+	var v [64]int
+	for i := 0; i+shingleSize <= len(words) || i == 0; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+		for b := uint(0); b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				v[b]++
+			} else {
+				v[b]--
+			}
+		}
+	}
+	var fp uint64
+	for b := uint(0); b < 64; b++ {
+		if v[b] > 0 {
+			fp |= 1 << b
+		}
+	}
+	return fp, true
+}
+
+// pageText returns visible text of document. Scripts, styles and other non-content elements are skipped.
+func pageText(doc *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template", "head":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const dedupArticle = `Dataflow kit is a web scraping tool for structured data extraction. It follows the specified
+extractors described in JSON file and returns parsed data as CSV, JSON or XML data. Pages are fetched with
+either base fetcher or headless Chrome. Paginated and details pages are followed and extracted as well.
+Results are stored in Diskv, MongoDB or Cassandra storage and encoded to the requested output format.`
+
+func TestDedup(t *testing.T) {
+	d := &dedup{}
+	assert.False(t, d.duplicate(dedupArticle))
+	//print view with a footer and a version with a changed word
+	assert.True(t, d.duplicate(dedupArticle+" Printed from example.com"))
+	assert.True(t, d.duplicate(strings.Replace(dedupArticle, "Chrome", "Firefox", 1)))
+	assert.False(t, d.duplicate("Completely different page about weather forecast for the next week in several cities across the country"))
+	assert.False(t, d.duplicate(""))
+	assert.False(t, d.duplicate("  "))
+	assert.Equal(t, 2, d.count())
+
+	var off *dedup
+	assert.False(t, off.duplicate(dedupArticle))
+	assert.Equal(t, 0, off.count())
+}
+
+func TestPageText(t *testing.T) {
+	html := `<html><head><title>Title</title><style>p{}</style></head>
+<body><p>Hello <b>world</b></p><script>var a = 1;</script><noscript>Enable JS</noscript></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Hello", "world"}, strings.Fields(pageText(doc.Selection)))
+}
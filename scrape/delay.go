@@ -0,0 +1,54 @@
+package scrape
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+)
+
+// Delay spaces out consecutive requests to the same host by a random time between Min and Max milliseconds.
+// It is added to robots.txt Crawl-delay, so crawl traffic is not periodic and less likely to be banned.
+// Payload Delay replaces FETCH_DELAY and RANDOMIZE_FETCH_DELAY settings, so the two are never applied together.
+type Delay struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+var (
+	jitterMx sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func (d *Delay) validate() error {
+	if d == nil {
+		return nil
+	}
+	if d.Min < 0 || d.Max < d.Min {
+		return errs.BadPayload{ErrText: fmt.Sprintf("invalid delay: min %d, max %d", d.Min, d.Max)}
+	}
+	return nil
+}
+
+// next returns random delay before the next request to the host.
+func (d *Delay) next() time.Duration {
+	if d == nil {
+		return 0
+	}
+	ms := d.Min
+	if d.Max > d.Min {
+		jitterMx.Lock()
+		ms += jitter.Intn(d.Max - d.Min + 1)
+		jitterMx.Unlock()
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// serviceDelay reports whether FETCH_DELAY and RANDOMIZE_FETCH_DELAY settings apply to requests of the task,
+// i.e. the payload sets no delay of its own.
+func (task *Task) serviceDelay() bool {
+	return task.Payload.Delay == nil && !viper.GetBool("IGNORE_FETCH_DELAY")
+}
@@ -0,0 +1,39 @@
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	var d *Delay
+	assert.NoError(t, d.validate())
+	assert.Equal(t, time.Duration(0), d.next())
+
+	assert.Error(t, (&Delay{Min: -1, Max: 10}).validate())
+	assert.Error(t, (&Delay{Min: 200, Max: 100}).validate())
+
+	d = &Delay{Min: 100, Max: 300}
+	assert.NoError(t, d.validate())
+	delays := map[time.Duration]bool{}
+	for i := 0; i < 50; i++ {
+		delay := d.next()
+		assert.True(t, delay >= 100*time.Millisecond && delay <= 300*time.Millisecond)
+		delays[delay] = true
+	}
+	assert.True(t, len(delays) > 1)
+
+	d = &Delay{Min: 100, Max: 100}
+	assert.Equal(t, 100*time.Millisecond, d.next())
+}
+
+func TestServiceDelay(t *testing.T) {
+	assert.True(t, NewTask(Payload{}).serviceDelay())
+	assert.False(t, NewTask(Payload{Delay: &Delay{Min: 100, Max: 200}}).serviceDelay(), "payload delay replaces FETCH_DELAY")
+	viper.Set("IGNORE_FETCH_DELAY", true)
+	defer viper.Set("IGNORE_FETCH_DELAY", false)
+	assert.False(t, NewTask(Payload{}).serviceDelay())
+}
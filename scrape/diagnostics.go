@@ -0,0 +1,45 @@
+package scrape
+
+import (
+	"io"
+
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// maxDiagnosedPages is the number of pages with diagnostics listed in the job report.
+const maxDiagnosedPages = 20
+
+// PageDiagnostics holds console errors, uncaught exceptions and failed requests of a page rendered by Chrome.
+// They help to tell a page broken or blocked by the site from a wrong selector.
+type PageDiagnostics struct {
+	URL            string   `json:"url"`
+	Console        []string `json:"console,omitempty"`
+	FailedRequests []string `json:"failedRequests,omitempty"`
+
+	failed []fetch.FailedRequest
+}
+
+// pageDiagnostics returns diagnostics passed along with content of the page url. It returns nil if there are none.
+func pageDiagnostics(url string, content io.ReadCloser) *PageDiagnostics {
+	d := &PageDiagnostics{URL: url, failed: fetch.FailedRequests(content)}
+	for _, m := range fetch.ConsoleMessages(content) {
+		if m.Error() {
+			d.Console = append(d.Console, m.String())
+		}
+	}
+	for _, r := range d.failed {
+		d.FailedRequests = append(d.FailedRequests, r.String())
+	}
+	if len(d.Console) == 0 && len(d.FailedRequests) == 0 {
+		return nil
+	}
+	return d
+}
+
+// failedRequestReason groups failed requests by reason and resource type.
+func failedRequestReason(r fetch.FailedRequest) string {
+	if r.Type == "" {
+		return r.Reason()
+	}
+	return r.Reason() + " (" + r.Type + ")"
+}
@@ -0,0 +1,43 @@
+package scrape
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodedHeader(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestPageDiagnostics(t *testing.T) {
+	h := http.Header{}
+	h.Set(fetch.ConsoleHeader, encodedHeader([]fetch.ConsoleMessage{
+		{Level: "log", Text: "loaded"},
+		{Level: fetch.ConsoleException, Text: "Uncaught TypeError: render is not a function", URL: "http://example.com/app.js", Line: 12},
+	}))
+	h.Set(fetch.FailedRequestsHeader, encodedHeader([]fetch.FailedRequest{
+		{URL: "http://example.com/api/items", Type: "XHR", Status: 403},
+		{URL: "http://example.com/app.js", Type: "Script", Blocked: "csp"},
+	}))
+	content := fetch.WithResponseHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	d := pageDiagnostics("http://example.com", content)
+	assert.Equal(t, []string{"exception: Uncaught TypeError: render is not a function (http://example.com/app.js:12)"}, d.Console)
+	assert.Equal(t, []string{"HTTP 403 XHR http://example.com/api/items", "blocked: csp Script http://example.com/app.js"}, d.FailedRequests)
+
+	s := newJobStats()
+	s.diagnostics(d)
+	s.diagnostics(d)
+	s.diagnostics(nil)
+	assert.Len(t, s.diagnosed, 2)
+	assert.Equal(t, map[string]int{"HTTP 403 (XHR)": 2, "blocked: csp (Script)": 2}, s.failedRequests)
+
+	assert.Nil(t, pageDiagnostics("http://example.com", ioutil.NopCloser(strings.NewReader(""))))
+}
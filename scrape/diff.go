@@ -0,0 +1,124 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// RunDiff lists records added and removed by the latest run of a payload compared to the previous run.
+// Changed records are listed in both Removed and Added.
+type RunDiff struct {
+	//ID is a results ID returned by Parse.
+	ID  string `json:"id"`
+	Run string `json:"run"`
+	//PreviousRun is empty if the latest run is the first stored one. All its records are added then.
+	PreviousRun string                   `json:"previousRun,omitempty"`
+	Added       []map[string]interface{} `json:"added"`
+	Removed     []map[string]interface{} `json:"removed"`
+	//Suspect lists violations of payload canary checks by the latest run. See CheckCanary.
+	Suspect []string `json:"suspect,omitempty"`
+}
+
+// Changed reports whether the latest run differs from the previous one.
+func (d *RunDiff) Changed() bool {
+	return d.PreviousRun != "" && (len(d.Added) > 0 || len(d.Removed) > 0)
+}
+
+// changedURL reports whether records extracted from url differ between the runs. Records of batch payload
+// are told apart by their seed URL in source_url field, all records of other payloads come from the request URL.
+func (d *RunDiff) changedURL(url string, batch bool) bool {
+	if !batch {
+		return d.Changed()
+	}
+	for _, records := range [][]map[string]interface{}{d.Added, d.Removed} {
+		for _, r := range records {
+			if r[sourceURLField] == url {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiffRuns compares records of the latest two stored runs of results ID.
+// Runs are stored if QUERY_STORE_RUNS is not 0.
+func DiffRuns(id string) (*RunDiff, error) {
+	if id == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	runs, err := storedRuns(s, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("no stored runs found for %s", id),
+		}
+	}
+	diff := &RunDiff{ID: id, Run: runs[len(runs)-1]}
+	latest, err := readRun(s, id, diff.Run)
+	if err != nil {
+		return nil, err
+	}
+	var previous []map[string]interface{}
+	if len(runs) > 1 {
+		diff.PreviousRun = runs[len(runs)-2]
+		if previous, err = readRun(s, id, diff.PreviousRun); err != nil {
+			return nil, err
+		}
+	}
+	diff.Added, diff.Removed = diffRecords(previous, latest)
+	return diff, nil
+}
+
+func readRun(s storage.Store, id, runID string) ([]map[string]interface{}, error) {
+	data, err := s.Read(storage.Record{Type: storage.BINARY, Key: runKey(id, runID)})
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// diffRecords compares records as multisets. Record order is ignored.
+func diffRecords(previous, latest []map[string]interface{}) (added, removed []map[string]interface{}) {
+	counts := map[string]int{}
+	for _, r := range previous {
+		counts[recordKey(r)]++
+	}
+	added = []map[string]interface{}{}
+	for _, r := range latest {
+		k := recordKey(r)
+		if counts[k] > 0 {
+			counts[k]--
+			continue
+		}
+		added = append(added, r)
+	}
+	removed = []map[string]interface{}{}
+	for _, r := range previous {
+		k := recordKey(r)
+		if counts[k] > 0 {
+			counts[k]--
+			removed = append(removed, r)
+		}
+	}
+	return added, removed
+}
+
+// recordKey returns canonical JSON of record. Map keys are sorted by encoding/json.
+func recordKey(r map[string]interface{}) string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
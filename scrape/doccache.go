@@ -0,0 +1,102 @@
+package scrape
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// docCache is an LRU cache of parsed documents keyed by MD5 hash of their content.
+// Identical pages are often fetched more than once, f.e. the same details page referred from several blocks
+// or a page fetched again with Chrome fetcher. Such pages are parsed only once.
+//
+// Cached documents are shared between workers so they must be used read-only.
+type docCache struct {
+	mx    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type docCacheEntry struct {
+	key string
+	doc *goquery.Document
+}
+
+func newDocCache() *docCache {
+	return &docCache{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// documents is a cache of documents parsed by all Tasks.
+var documents = newDocCache()
+
+func (c *docCache) get(key string) (*goquery.Document, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*docCacheEntry).doc, true
+}
+
+// add puts doc to the cache evicting least recently used documents so that no more than size documents are kept.
+func (c *docCache) add(key string, doc *goquery.Document, size int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*docCacheEntry).doc = doc
+		return
+	}
+	c.items[key] = c.ll.PushFront(&docCacheEntry{key: key, doc: doc})
+	for c.ll.Len() > size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*docCacheEntry).key)
+	}
+}
+
+func (c *docCache) len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.ll.Len()
+}
+
+// parseDocument returns goquery document built from content.
+// Previously parsed document is returned from cache if content has not changed.
+// Cache size is specified by DOC_CACHE_SIZE. Caching is disabled if it is 0.
+// Binary content is refused with errBinaryContent.
+func parseDocument(content io.Reader) (*goquery.Document, error) {
+	content, err := sniffText(content)
+	if err != nil {
+		return nil, err
+	}
+	size := viper.GetInt("DOC_CACHE_SIZE")
+	if size <= 0 {
+		return goquery.NewDocumentFromReader(content)
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	key := string(utils.GenerateMD5(data))
+	if doc, ok := documents.get(key); ok {
+		return doc, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	documents.add(key, doc, size)
+	return doc, nil
+}
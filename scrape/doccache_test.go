@@ -0,0 +1,47 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocument(t *testing.T) {
+	viper.Set("DOC_CACHE_SIZE", 2)
+	defer viper.Set("DOC_CACHE_SIZE", 0)
+	documents = newDocCache()
+
+	doc1, err := parseDocument(strings.NewReader("<p>1</p>"))
+	assert.NoError(t, err)
+	doc2, err := parseDocument(strings.NewReader("<p>1</p>"))
+	assert.NoError(t, err)
+	assert.True(t, doc1 == doc2, "identical content should be parsed once")
+	assert.Equal(t, "1", doc2.Find("p").Text())
+
+	_, err = parseDocument(strings.NewReader("<p>2</p>"))
+	assert.NoError(t, err)
+	_, err = parseDocument(strings.NewReader("<p>3</p>"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, documents.len())
+	//least recently used document is evicted
+	doc3, err := parseDocument(strings.NewReader("<p>1</p>"))
+	assert.NoError(t, err)
+	assert.False(t, doc1 == doc3)
+
+	viper.Set("DOC_CACHE_SIZE", 0)
+	doc4, err := parseDocument(strings.NewReader("<p>1</p>"))
+	assert.NoError(t, err)
+	assert.False(t, doc3 == doc4, "caching should be disabled")
+
+	//binary content is not parsed
+	_, err = parseDocument(strings.NewReader("%PDF-1.4\n<p>1</p>"))
+	assert.Equal(t, errBinaryContent{"application/pdf"}, err)
+	_, err = parseDocument(strings.NewReader("\x89PNG\r\n\x1a\n"))
+	assert.Error(t, err)
+	_, err = parseDocument(strings.NewReader(""))
+	assert.NoError(t, err)
+	_, err = parseDocument(strings.NewReader("<p>Привет " + strings.Repeat("мир ", 200) + "</p>"))
+	assert.NoError(t, err)
+}
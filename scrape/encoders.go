@@ -238,6 +238,16 @@ func (r *storageResultReader) Read() (map[string]interface{}, error) {
 		r.block++
 		return nil, err
 	}
+	r.readDetails(blockMap)
+	r.block++
+	if nextPage {
+		err = &errs.ErrStorageResult{Err: errs.NextPage}
+	}
+	return blockMap, err
+}
+
+// readDetails replaces details references of the block with details blocks read from storage.
+func (r *storageResultReader) readDetails(blockMap map[string]interface{}) {
 	for field, value := range blockMap {
 		if strings.Contains(field, "details") {
 			details := []map[string]interface{}{}
@@ -273,11 +283,6 @@ func (r *storageResultReader) Read() (map[string]interface{}, error) {
 			}
 		}
 	}
-	r.block++
-	if nextPage {
-		err = &errs.ErrStorageResult{Err: errs.NextPage}
-	}
-	return blockMap, err
 }
 
 func (r *storageResultReader) getValue() (map[string]interface{}, error) {
@@ -374,6 +379,8 @@ func (e CSVEncoder) formatFieldValue(block *map[string]interface{}, fieldName st
 		formatedString = floatArrayToString(v, ";")
 	case float64:
 		formatedString = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		formatedString = strconv.FormatBool(v)
 	case nil:
 		formatedString = ""
 	case []interface{}:
@@ -448,19 +455,22 @@ func (e XMLEncoder) writeXML(w io.Writer, block *map[string]interface{}) {
 		} else {
 			io.WriteString(w, fmt.Sprintf("<%s>", field))
 			// have to escape predefined entities to obtain valid xml
-			v, ok := value.(string)
-			if ok {
+			switch v := value.(type) {
+			case string:
 				xml.Escape(w, []byte(v))
-			} else {
-				for i, val := range value.([]interface{}) {
-					v, ok = val.(string)
+			case []interface{}:
+				for i, val := range v {
+					s, ok := val.(string)
 					if ok {
-						xml.Escape(w, []byte(v))
-						if i < len(value.([]interface{}))-1 {
+						xml.Escape(w, []byte(s))
+						if i < len(v)-1 {
 							io.WriteString(w, ";")
 						}
 					}
 				}
+			default:
+				//numbers and flags like count and exists values
+				io.WriteString(w, fmt.Sprint(v))
 			}
 			io.WriteString(w, fmt.Sprintf("</%s>", field))
 		}
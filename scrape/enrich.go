@@ -0,0 +1,190 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Enrichment describes an external HTTP endpoint extracted records are sent to.
+// Fields returned by the endpoint are merged back into records, f.e. geocoding, categorization or sentiment.
+type Enrichment struct {
+	//URL of the endpoint. Records are sent with POST method.
+	URL string `json:"url"`
+	//Headers are added to every request, f.e. Authorization.
+	Headers map[string]string `json:"headers"`
+	//BatchSize is the number of records sent in a single request.
+	//If BatchSize is 0 or 1 every record is sent as JSON object and JSON object is expected in response.
+	//Otherwise JSON array of records is sent and JSON array of the same length is expected in response.
+	BatchSize int `json:"batchSize"`
+	//Concurrency is the number of simultaneous requests.
+	//If Concurrency is omitted or exceeds ENRICH_CONCURRENCY of parse.d service, ENRICH_CONCURRENCY is used.
+	Concurrency int `json:"concurrency"`
+	//Timeout of a single request in seconds.
+	//If Timeout is omitted or exceeds ENRICH_TIMEOUT of parse.d service, ENRICH_TIMEOUT is used.
+	Timeout int `json:"timeout"`
+}
+
+// limits returns the number of simultaneous requests and request timeout in seconds.
+// ENRICH_CONCURRENCY and ENRICH_TIMEOUT are both defaults and maximums, so a payload can't exhaust the service.
+func (e *Enrichment) limits() (concurrency, timeout int) {
+	concurrency = e.Concurrency
+	if max := viper.GetInt("ENRICH_CONCURRENCY"); max > 0 && (concurrency <= 0 || concurrency > max) {
+		concurrency = max
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	timeout = e.Timeout
+	if max := viper.GetInt("ENRICH_TIMEOUT"); max > 0 && (timeout <= 0 || timeout > max) {
+		timeout = max
+	}
+	return concurrency, timeout
+}
+
+// validate checks enrichment endpoint URL.
+func (e *Enrichment) validate() error {
+	if e == nil {
+		return nil
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errs.BadPayload{ErrText: fmt.Sprintf("invalid enrichment URL %q", e.URL)}
+	}
+	return nil
+}
+
+// enrich sends records stored by the task to enrichment endpoint and merges returned fields back in.
+// Failed requests are logged and corresponding records are left as they are.
+func (task *Task) enrich(uid string, keys map[int][]int) {
+	e := task.Payload.Enrich
+	if e == nil {
+		return
+	}
+	pages := make([]int, 0, len(keys))
+	for page := range keys {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+	recordKeys := []string{}
+	for _, page := range pages {
+		for _, block := range keys[page] {
+			recordKeys = append(recordKeys, fmt.Sprintf("%s-%d-%d", uid, page, block))
+		}
+	}
+
+	batchSize := e.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	concurrency, timeout := e.limits()
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	slots := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for start := 0; start < len(recordKeys); start += batchSize {
+		end := start + batchSize
+		if end > len(recordKeys) {
+			end = len(recordKeys)
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(batch []string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := task.enrichBatch(client, batch); err != nil {
+				task.log().Warn("Failed to enrich records. "+err.Error(),
+					zap.String("URL", e.URL),
+					zap.Strings("keys", batch))
+			}
+		}(recordKeys[start:end])
+	}
+	wg.Wait()
+}
+
+// enrichBatch reads records specified by keys, posts them to enrichment endpoint
+// and writes records with merged fields back to storage.
+func (task *Task) enrichBatch(client *http.Client, keys []string) error {
+	e := task.Payload.Enrich
+	records := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		data, err := task.storage.Read(storage.Record{Type: storage.INTERMEDIATE, Key: key})
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &records[i]); err != nil {
+			return err
+		}
+	}
+	var body interface{} = records
+	if e.BatchSize <= 1 {
+		body = records[0]
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	ctx, cancel := context.WithCancel(task.ctx)
+	defer cancel()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("enrichment endpoint responded with %s", resp.Status)
+	}
+	enriched := []map[string]interface{}{}
+	if e.BatchSize <= 1 {
+		enriched = append(enriched, map[string]interface{}{})
+		err = json.NewDecoder(resp.Body).Decode(&enriched[0])
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&enriched)
+	}
+	if err != nil {
+		return err
+	}
+	if len(enriched) != len(records) {
+		return fmt.Errorf("enrichment endpoint returned %d records instead of %d", len(enriched), len(records))
+	}
+	for i, key := range keys {
+		for k, v := range enriched[i] {
+			records[i][k] = v
+		}
+		output, err := json.Marshal(records[i])
+		if err != nil {
+			return err
+		}
+		err = task.storage.Write(storage.Record{
+			Type:    storage.INTERMEDIATE,
+			Key:     key,
+			Value:   output,
+			ExpTime: 0,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
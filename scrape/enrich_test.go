@@ -0,0 +1,85 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrich(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		records := []map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&records)
+		for _, rec := range records {
+			if rec["n"].(float64) == 4 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			rec["category"] = fmt.Sprintf("c%v", rec["n"])
+		}
+		json.NewEncoder(w).Encode(records)
+	}))
+	defer ts.Close()
+
+	task := NewTask(Payload{Enrich: &Enrichment{
+		URL:       ts.URL,
+		BatchSize: 2,
+		Headers:   map[string]string{"X-Token": "secret"},
+	}})
+	defer task.storage.Close()
+	keys := map[int][]int{0: {0, 1, 2}, 1: {0, 1}}
+	for page, blocks := range keys {
+		for _, block := range blocks {
+			value, _ := json.Marshal(map[string]interface{}{"n": page*3 + block})
+			err := task.storage.Write(storage.Record{
+				Type:  storage.INTERMEDIATE,
+				Key:   fmt.Sprintf("enrich-%d-%d", page, block),
+				Value: value,
+			})
+			assert.NoError(t, err)
+		}
+	}
+	task.enrich("enrich", keys)
+
+	read := func(key string) map[string]interface{} {
+		data, err := task.storage.Read(storage.Record{Type: storage.INTERMEDIATE, Key: key})
+		assert.NoError(t, err)
+		rec := map[string]interface{}{}
+		json.Unmarshal(data, &rec)
+		return rec
+	}
+	assert.Equal(t, map[string]interface{}{"n": 0.0, "category": "c0"}, read("enrich-0-0"))
+	assert.Equal(t, map[string]interface{}{"n": 2.0, "category": "c2"}, read("enrich-0-2"))
+	//failed batch is left as it is
+	assert.Equal(t, map[string]interface{}{"n": 4.0}, read("enrich-1-1"))
+
+	assert.Error(t, (&Enrichment{URL: "ftp://example.com"}).validate())
+	assert.NoError(t, (*Enrichment)(nil).validate())
+}
+
+func TestEnrichmentLimits(t *testing.T) {
+	viper.Set("ENRICH_CONCURRENCY", 4)
+	viper.Set("ENRICH_TIMEOUT", 10)
+	defer viper.Set("ENRICH_CONCURRENCY", 0)
+	defer viper.Set("ENRICH_TIMEOUT", 0)
+
+	concurrency, timeout := (&Enrichment{}).limits()
+	assert.Equal(t, 4, concurrency)
+	assert.Equal(t, 10, timeout)
+	concurrency, timeout = (&Enrichment{Concurrency: 2, Timeout: 5}).limits()
+	assert.Equal(t, 2, concurrency)
+	assert.Equal(t, 5, timeout)
+	concurrency, timeout = (&Enrichment{Concurrency: 100000, Timeout: 86400}).limits()
+	assert.Equal(t, 4, concurrency, "payload can't exceed service concurrency")
+	assert.Equal(t, 10, timeout, "payload can't exceed service timeout")
+}
@@ -0,0 +1,91 @@
+package scrape
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// Names of record fields describing the fetch of the page the record is extracted from.
+const (
+	fetchedAtField     = "fetched_at"
+	fetchMsField       = "fetch_ms"
+	responseBytesField = "response_bytes"
+	cacheHitField      = "cache_hit"
+	fetcherField       = "fetcher"
+)
+
+// fetchMetaFields are added to records if FetchMetadata is on.
+var fetchMetaFields = []string{fetchedAtField, fetchMsField, responseBytesField, cacheHitField, fetcherField}
+
+// pageMeta describes how a page has been fetched. It is filled by fetch worker before the page is passed on
+// and its size is counted once the page is read.
+type pageMeta struct {
+	fetched  time.Time
+	duration time.Duration
+	fetcher  string
+	cacheHit bool
+	size     int64
+}
+
+// newPageMeta returns pageMeta to be filled by fetch worker if FetchMetadata is on, otherwise nil.
+func (task *Task) newPageMeta() *pageMeta {
+	if !utils.IsTrue(task.Payload.FetchMetadata) {
+		return nil
+	}
+	return &pageMeta{}
+}
+
+// observe fills m with the fetch of content which took duration.
+// Pages read from source archive or served from fixtures are cache hits.
+func (m *pageMeta) observe(fetcher string, begin time.Time, duration time.Duration, content io.ReadCloser, archived bool) {
+	if m == nil {
+		return
+	}
+	m.fetched = begin.UTC()
+	m.duration = duration
+	m.fetcher = fetcher
+	m.cacheHit = archived || fetch.ResponseHeader(content).Get(fetch.CacheHeader) == "HIT"
+}
+
+// count returns r counting the size of the page as it is read.
+func (m *pageMeta) count(r io.Reader) io.Reader {
+	if m == nil {
+		return r
+	}
+	return &metaReader{Reader: r, meta: m}
+}
+
+type metaReader struct {
+	io.Reader
+	meta *pageMeta
+}
+
+func (r *metaReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	atomic.AddInt64(&r.meta.size, int64(n))
+	return n, err
+}
+
+// addFetchMeta sets fields describing the fetch of the page the record is extracted from.
+// Fields with the same names extracted from the page are kept intact.
+func (p Payload) addFetchMeta(record map[string]interface{}, m *pageMeta) {
+	if !utils.IsTrue(p.FetchMetadata) || m == nil {
+		return
+	}
+	values := map[string]interface{}{
+		fetchedAtField:     m.fetched.Format(time.RFC3339),
+		fetchMsField:       int64(m.duration / time.Millisecond),
+		responseBytesField: atomic.LoadInt64(&m.size),
+		cacheHitField:      m.cacheHit,
+		fetcherField:       m.fetcher,
+	}
+	for name, v := range values {
+		if _, ok := record[name]; !ok {
+			record[name] = v
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package scrape
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload_addFetchMeta(t *testing.T) {
+	meta := true
+	p := Payload{FetchMetadata: &meta}
+	task := &Task{Payload: p}
+	m := task.newPageMeta()
+	begin := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	content := fetch.WithResponseHeader(ioutil.NopCloser(strings.NewReader("<html>page</html>")), http.Header{fetch.CacheHeader: {"HIT"}})
+	m.observe("chrome", begin, 1500*time.Millisecond, content, false)
+	data, err := ioutil.ReadAll(m.count(content))
+	assert.NoError(t, err)
+
+	record := map[string]interface{}{"title": "Page", "fetcher": "extracted"}
+	p.addFetchMeta(record, m)
+	assert.Equal(t, "2019-03-01T10:00:00Z", record[fetchedAtField])
+	assert.Equal(t, int64(1500), record[fetchMsField])
+	assert.Equal(t, int64(len(data)), record[responseBytesField])
+	assert.Equal(t, true, record[cacheHitField])
+	assert.Equal(t, "extracted", record[fetcherField], "extracted field is kept")
+
+	live := &pageMeta{}
+	live.observe("base", begin, time.Second, ioutil.NopCloser(strings.NewReader("")), false)
+	assert.False(t, live.cacheHit)
+	archived := &pageMeta{}
+	archived.observe("base", begin, time.Second, ioutil.NopCloser(strings.NewReader("")), true)
+	assert.True(t, archived.cacheHit)
+
+	assert.Equal(t, []string{"title", fetchedAtField, fetchMsField, responseBytesField, cacheHitField, fetcherField}, p.columns([]string{"title"}))
+
+	//turned off
+	off := &Task{}
+	assert.Nil(t, off.newPageMeta())
+	record = map[string]interface{}{"title": "Page"}
+	Payload{}.addFetchMeta(record, m)
+	assert.Len(t, record, 1)
+}
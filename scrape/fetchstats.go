@@ -0,0 +1,205 @@
+package scrape
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// FetchLatencyBuckets are upper bounds in seconds of fetch latency histograms.
+var FetchLatencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// FetchSizeBuckets are upper bounds in bytes of fetched page size histograms.
+var FetchSizeBuckets = []float64{10e3, 50e3, 100e3, 250e3, 500e3, 1e6, 2.5e6, 5e6, 10e6}
+
+// fetchLatency and fetchSize are service wide histograms labeled with "fetcher" type. See InstrumentFetches.
+var (
+	fetchLatency metrics.Histogram = discard.NewHistogram()
+	fetchSize    metrics.Histogram = discard.NewHistogram()
+)
+
+// InstrumentFetches sets histograms observing latency in seconds and size in bytes of pages fetched by parse jobs.
+// Observations are labeled with "fetcher" type, so the cost of Chrome and Base fetchers may be compared.
+// It should be called before parse jobs are started.
+func InstrumentFetches(latency, size metrics.Histogram) {
+	fetchLatency = latency
+	fetchSize = size
+}
+
+// FetchSummary describes pages fetched by a job with one fetcher type.
+type FetchSummary struct {
+	//Count is the number of fetched pages, Errors is the number of failed fetches
+	Count  int `json:"count"`
+	Errors int `json:"errors"`
+	//AvgLatency is the average time in milliseconds to get a page
+	AvgLatency float64 `json:"avgLatency"`
+	//Latency holds the number of pages fetched within every bucket of FetchLatencyBuckets. "+Inf" counts slower ones.
+	Latency map[string]int `json:"latency"`
+	//Bytes is the total size of fetched pages
+	Bytes int64 `json:"bytes"`
+	//Size holds the number of pages within every bucket of FetchSizeBuckets.
+	Size map[string]int `json:"size"`
+	//Network breaks down network time of pages fetched by Base fetcher
+	Network *NetworkSummary `json:"network,omitempty"`
+
+	totalLatency time.Duration
+}
+
+// NetworkSummary tells whether slow fetches are caused by network path or by the target.
+// Times are averages in milliseconds. DNS, Connect and TLS are averaged over new connections only.
+type NetworkSummary struct {
+	//Traced is the number of pages with network timings. Reused of them were received over kept-alive connections.
+	Traced     int     `json:"traced"`
+	Reused     int     `json:"reused"`
+	ReuseRate  float64 `json:"reuseRate"`
+	AvgDNS     float64 `json:"avgDns"`
+	AvgConnect float64 `json:"avgConnect"`
+	AvgTLS     float64 `json:"avgTls"`
+	//AvgTTFB is the average time to the first byte of response including connection setup
+	AvgTTFB float64 `json:"avgTtfb"`
+
+	total fetch.Timing
+}
+
+// add adds timing t of a page.
+func (n *NetworkSummary) add(t fetch.Timing) {
+	n.Traced++
+	if t.Reused {
+		n.Reused++
+	}
+	n.total.DNS += t.DNS
+	n.total.Connect += t.Connect
+	n.total.TLS += t.TLS
+	n.total.TTFB += t.TTFB
+	n.ReuseRate = float64(n.Reused) / float64(n.Traced)
+	if created := n.Traced - n.Reused; created > 0 {
+		n.AvgDNS = n.total.DNS / float64(created)
+		n.AvgConnect = n.total.Connect / float64(created)
+		n.AvgTLS = n.total.TLS / float64(created)
+	}
+	n.AvgTTFB = n.total.TTFB / float64(n.Traced)
+}
+
+// fetchStats collects FetchSummary of a job per fetcher type.
+type fetchStats struct {
+	mx      sync.Mutex
+	fetches map[string]*FetchSummary
+}
+
+func newFetchStats() *fetchStats {
+	return &fetchStats{fetches: make(map[string]*FetchSummary)}
+}
+
+// summary returns summary of fetcherType. Caller should hold the lock.
+func (s *fetchStats) summary(fetcherType string) *FetchSummary {
+	f, ok := s.fetches[fetcherType]
+	if !ok {
+		f = &FetchSummary{Latency: map[string]int{}, Size: map[string]int{}}
+		s.fetches[fetcherType] = f
+	}
+	return f
+}
+
+// observe records a fetch which took latency. Size of the fetched page is recorded by the returned reader
+// once content is read or closed. Live fetches are observed by service wide histograms as well.
+func (s *fetchStats) observe(fetcherType string, latency time.Duration, content io.ReadCloser, err error, live bool) io.ReadCloser {
+	if s == nil {
+		return content
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	f := s.summary(fetcherType)
+	if err != nil {
+		f.Errors++
+		return content
+	}
+	f.Count++
+	f.totalLatency += latency
+	f.AvgLatency = float64(f.totalLatency/time.Millisecond) / float64(f.Count)
+	f.Latency[bucket(FetchLatencyBuckets, latency.Seconds())]++
+	if t := fetch.ResponseTiming(content); t != nil {
+		if f.Network == nil {
+			f.Network = &NetworkSummary{}
+		}
+		f.Network.add(*t)
+	}
+	if live {
+		fetchLatency.With("fetcher", fetcherType).Observe(latency.Seconds())
+	}
+	sized := &sizeReader{ReadCloser: content, done: func(n int64) {
+		s.mx.Lock()
+		f.Bytes += n
+		f.Size[bucket(FetchSizeBuckets, float64(n))]++
+		s.mx.Unlock()
+		if live {
+			fetchSize.With("fetcher", fetcherType).Observe(float64(n))
+		}
+	}}
+	return fetch.WithResponseHeader(sized, fetch.ResponseHeader(content))
+}
+
+// summaries returns a copy of collected summaries.
+func (s *fetchStats) summaries() map[string]FetchSummary {
+	if s == nil {
+		return nil
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	summary := make(map[string]FetchSummary, len(s.fetches))
+	for t, f := range s.fetches {
+		copied := *f
+		copied.Latency = copyCounts(f.Latency)
+		copied.Size = copyCounts(f.Size)
+		if f.Network != nil {
+			network := *f.Network
+			copied.Network = &network
+		}
+		summary[t] = copied
+	}
+	return summary
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for k, v := range counts {
+		copied[k] = v
+	}
+	return copied
+}
+
+// bucket returns the label of the first bucket v fits in.
+func bucket(buckets []float64, v float64) string {
+	for _, b := range buckets {
+		if v <= b {
+			return strconv.FormatFloat(b, 'f', -1, 64)
+		}
+	}
+	return "+Inf"
+}
+
+// sizeReader counts bytes read from content and passes their number to done at EOF or when content is closed.
+type sizeReader struct {
+	io.ReadCloser
+	n    int64
+	done func(n int64)
+	once sync.Once
+}
+
+func (r *sizeReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err == io.EOF {
+		r.once.Do(func() { r.done(r.n) })
+	}
+	return n, err
+}
+
+func (r *sizeReader) Close() error {
+	r.once.Do(func() { r.done(r.n) })
+	return r.ReadCloser.Close()
+}
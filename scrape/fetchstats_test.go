@@ -0,0 +1,55 @@
+package scrape
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchStats(t *testing.T) {
+	s := newFetchStats()
+	content := s.observe("base", 200*time.Millisecond, ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 20000))), nil, true)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, 20000, len(data))
+	content.Close()
+	s.observe("base", 0, nil, errors.New("connection refused"), true)
+	content = s.observe("chrome", 3*time.Second, ioutil.NopCloser(strings.NewReader("<html></html>")), nil, false)
+	content.Close()
+
+	summary := s.summaries()
+	assert.Equal(t, 1, summary["base"].Count)
+	assert.Equal(t, 1, summary["base"].Errors)
+	assert.Equal(t, float64(200), summary["base"].AvgLatency)
+	assert.Equal(t, map[string]int{"0.25": 1}, summary["base"].Latency)
+	assert.Equal(t, int64(20000), summary["base"].Bytes)
+	assert.Equal(t, map[string]int{"50000": 1}, summary["base"].Size)
+	assert.Equal(t, map[string]int{"5": 1}, summary["chrome"].Latency)
+	assert.Equal(t, map[string]int{"10000": 1}, summary["chrome"].Size)
+	assert.Equal(t, "+Inf", bucket(FetchLatencyBuckets, 120))
+}
+
+func TestNetworkSummary(t *testing.T) {
+	s := newFetchStats()
+	timed := func(timing string) io.ReadCloser {
+		header := http.Header{fetch.TimingHeader: {base64.StdEncoding.EncodeToString([]byte(timing))}}
+		return fetch.WithResponseHeader(ioutil.NopCloser(strings.NewReader("<html></html>")), header)
+	}
+	s.observe("base", time.Second, timed(`{"reused": false, "dns": 20, "connect": 40, "tls": 60, "ttfb": 300}`), nil, false).Close()
+	s.observe("base", time.Second, timed(`{"reused": true, "ttfb": 100}`), nil, false).Close()
+	s.observe("base", time.Second, ioutil.NopCloser(strings.NewReader("<html></html>")), nil, false).Close()
+	s.observe("chrome", time.Second, ioutil.NopCloser(strings.NewReader("<html></html>")), nil, false).Close()
+
+	summary := s.summaries()
+	assert.Equal(t, &NetworkSummary{Traced: 2, Reused: 1, ReuseRate: 0.5, AvgDNS: 20, AvgConnect: 40, AvgTLS: 60, AvgTTFB: 200,
+		total: fetch.Timing{DNS: 20, Connect: 40, TLS: 60, TTFB: 400}}, summary["base"].Network)
+	assert.Nil(t, summary["chrome"].Network)
+}
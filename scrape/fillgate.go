@@ -0,0 +1,106 @@
+package scrape
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// FillGate is a quality gate checking the share of records filling a field once the job completes,
+// so broken selectors are detected as soon as a site is redesigned.
+type FillGate struct {
+	//Field is a payload field name. "*" applies the gate to every field.
+	Field string `json:"field"`
+	//MinRate is the minimum share of records having non-empty value of the field, f.e. 0.9.
+	MinRate float64 `json:"minRate"`
+	//Action is "warn" (default) to report the violation or "fail" to fail the job.
+	Action string `json:"action,omitempty"`
+}
+
+// FillViolation is a field filled in less records than its gate requires.
+type FillViolation struct {
+	Field   string  `json:"field"`
+	Rate    float64 `json:"rate"`
+	MinRate float64 `json:"minRate"`
+	Action  string  `json:"action"`
+}
+
+// Fill gate actions
+const (
+	fillWarn = "warn"
+	fillFail = "fail"
+)
+
+// statusFillFailed is the status of a job failed by a fill gate.
+const statusFillFailed = "Fill rate gate failed"
+
+// validateFillGates checks gates refer to payload fields and have valid rates and actions.
+func (p Payload) validateFillGates() error {
+	for _, g := range p.FillGates {
+		if g.Field != "*" && !p.hasField(g.Field) {
+			return errs.BadPayload{ErrText: fmt.Sprintf("fill gate: unknown field %q", g.Field)}
+		}
+		if g.MinRate <= 0 || g.MinRate > 1 {
+			return errs.BadPayload{ErrText: fmt.Sprintf("fill gate of %s: minRate should be in (0, 1]", g.Field)}
+		}
+		switch strings.ToLower(g.Action) {
+		case "", fillWarn, fillFail:
+		default:
+			return errs.BadPayload{ErrText: fmt.Sprintf("fill gate of %s: unknown action %q", g.Field, g.Action)}
+		}
+	}
+	return nil
+}
+
+func (p Payload) hasField(name string) bool {
+	for _, f := range p.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFill returns fields whose fill rates are below their gates. A field is checked against the strictest
+// of gates applying to it, failing gates take precedence over warning ones of the same rate.
+func checkFill(gates []FillGate, fields []FieldFill) []FillViolation {
+	var violations []FillViolation
+	for _, f := range fields {
+		var v *FillViolation
+		for _, g := range gates {
+			if (g.Field != "*" && g.Field != f.Name) || f.Rate >= g.MinRate {
+				continue
+			}
+			action := strings.ToLower(g.Action)
+			if action == "" {
+				action = fillWarn
+			}
+			if v == nil || g.MinRate > v.MinRate || (g.MinRate == v.MinRate && action == fillFail) {
+				v = &FillViolation{Field: f.Name, Rate: f.Rate, MinRate: g.MinRate, Action: action}
+			}
+		}
+		if v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations
+}
+
+// fillError returns an error if the report has a failing fill gate violation.
+func (r JobReport) fillError() error {
+	failed := []string{}
+	for _, v := range r.FillViolations {
+		if v.Action == fillFail {
+			failed = append(failed, fmt.Sprintf("%s filled in %.0f%% of records (min %.0f%%)", v.Field, v.Rate*100, v.MinRate*100))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errs.StatusError{
+		Code: http.StatusUnprocessableEntity,
+		Err:  fmt.Errorf("Fill rate gate failed: %s. Results ID %s", strings.Join(failed, ", "), r.ResultsID),
+	}
+}
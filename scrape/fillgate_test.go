@@ -0,0 +1,65 @@
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFillGates(t *testing.T) {
+	p := Payload{Fields: []Field{{Name: "title"}, {Name: "price"}}}
+	assert.NoError(t, p.validateFillGates())
+	p.FillGates = []FillGate{{Field: "price", MinRate: 0.9, Action: "fail"}, {Field: "*", MinRate: 0.5}}
+	assert.NoError(t, p.validateFillGates())
+	for _, g := range []FillGate{
+		{Field: "stock", MinRate: 0.9},
+		{Field: "price", MinRate: 0},
+		{Field: "price", MinRate: 90},
+		{Field: "price", MinRate: 0.9, Action: "stop"},
+	} {
+		p.FillGates = []FillGate{g}
+		assert.Error(t, p.validateFillGates(), g.Field)
+	}
+}
+
+func TestCheckFill(t *testing.T) {
+	task := &Task{
+		Payload: Payload{
+			Fields: []Field{
+				{Name: "title", Extractor: Extractor{Types: []string{"text", "href"}}},
+				{Name: "price", Extractor: Extractor{Types: []string{"text"}}},
+				{Name: "stock", Extractor: Extractor{Types: []string{"text"}}},
+			},
+			FillGates: []FillGate{
+				{Field: "*", MinRate: 0.5},
+				{Field: "price", MinRate: 0.9, Action: "fail"},
+			},
+		},
+		stats:      newJobStats(),
+		fetchStats: newFetchStats(),
+	}
+	for i := 0; i < 4; i++ {
+		record := map[string]interface{}{"title_text": "Lamp", "title_href": "", "price_text": "10", "stock_text": ""}
+		if i == 0 {
+			record["price_text"] = ""
+			record["stock_text"] = "in stock"
+		}
+		task.stats.record(record)
+	}
+
+	r := task.report("results", "Completed", time.Now())
+	assert.Equal(t, []FieldFill{{Name: "title", Filled: 4, Rate: 1}, {Name: "price", Filled: 3, Rate: 0.75}, {Name: "stock", Filled: 1, Rate: 0.25}}, r.Fields)
+	assert.Equal(t, []FillViolation{
+		{Field: "price", Rate: 0.75, MinRate: 0.9, Action: fillFail},
+		{Field: "stock", Rate: 0.25, MinRate: 0.5, Action: fillWarn},
+	}, r.FillViolations)
+	assert.Equal(t, statusFillFailed, r.Status)
+	assert.EqualError(t, r.fillError(), "Fill rate gate failed: price filled in 75% of records (min 90%). Results ID results")
+	assert.Contains(t, r.summary(), "stock filled in 25% of records, 50% required")
+
+	task.Payload.FillGates = task.Payload.FillGates[:1]
+	r = task.report("results", "Completed", time.Now())
+	assert.Equal(t, "Completed", r.Status)
+	assert.NoError(t, r.fillError())
+}
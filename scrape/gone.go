@@ -0,0 +1,34 @@
+package scrape
+
+import (
+	"github.com/slotix/dataflowkit/errs"
+	"go.uber.org/zap"
+)
+
+// isGone reports whether err says the page has been removed permanently or is blocked for legal reasons.
+func isGone(err error) bool {
+	e, ok := err.(errs.FetchError)
+	return ok && e.Gone()
+}
+
+// markGone remembers the error of the page at url if the page is gone, so the task doesn't request it again,
+// f.e. with Chrome fetcher or as a details page linked from other blocks.
+func (task *Task) markGone(url string, err error) {
+	if !isGone(err) {
+		return
+	}
+	task.log().Info("Page is gone and won't be requested again", zap.String("URL", url))
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	if task.gone == nil {
+		task.gone = make(map[string]error)
+	}
+	task.gone[url] = err
+}
+
+// goneErr returns the error of the page at url if the task found it gone earlier.
+func (task *Task) goneErr(url string) error {
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	return task.gone[url]
+}
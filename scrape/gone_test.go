@@ -0,0 +1,71 @@
+package scrape
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// goneSource answers every request with 410 Gone and counts requests by URL.
+type goneSource struct {
+	requests map[string]int
+}
+
+func (s *goneSource) fetch(req fetch.Request) (io.ReadCloser, error) {
+	s.requests[req.URL]++
+	if strings.HasSuffix(req.URL, "/gone") {
+		return nil, errs.FetchError{URL: req.URL, Code: http.StatusGone, Reason: "410 Gone"}
+	}
+	return ioutil.NopCloser(strings.NewReader("<html><body><h1>Title</h1></body></html>")), nil
+}
+
+func TestGonePages(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	viper.Set("IGNORE_ROBOTS_TXT", true)
+	defer viper.Set("IGNORE_ROBOTS_TXT", false)
+	src := &goneSource{requests: map[string]int{}}
+	task := NewTask(Payload{
+		Name:    "gone",
+		Request: fetch.Request{URL: "http://example.com/gone"},
+		Fields:  []Field{{Name: "title", Selector: "h1", Extractor: Extractor{Types: []string{"text"}}}},
+		Format:  "json",
+	})
+	task.source = src
+	_, err := task.Parse()
+	e, ok := err.(errs.FetchError)
+	if assert.True(t, ok, "%v", err) {
+		assert.Equal(t, http.StatusGone, e.Code)
+	}
+	//the page is neither fetched again with Chrome fetcher
+	assert.Equal(t, 1, src.requests["http://example.com/gone"])
+	assert.NotEqual(t, "chrome", task.Payload.Request.Type)
+
+	//nor requested again by the task, f.e. as a details page linked from several blocks
+	task.fetchChannel = make(chan *fetchInfo)
+	defer close(task.fetchChannel)
+	go task.fetchWorker()
+	for i := 0; i < 2; i++ {
+		result, errc := make(chan io.ReadCloser, 1), make(chan error, 1)
+		task.fetchChannel <- &fetchInfo{
+			request: fetch.Request{URL: "http://example.com/gone"},
+			reqType: "details",
+			result:  result,
+			err:     errc,
+			meta:    task.newPageMeta(),
+		}
+		assert.True(t, isGone(<-errc))
+	}
+	assert.Equal(t, 1, src.requests["http://example.com/gone"])
+
+	assert.False(t, isGone(errs.FetchError{Code: http.StatusNotFound}))
+	assert.True(t, isGone(errs.FetchError{Code: http.StatusUnavailableForLegalReasons}))
+}
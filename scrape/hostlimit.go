@@ -0,0 +1,34 @@
+package scrape
+
+import (
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out consecutive requests to the same host.
+// It is shared by all fetch workers of a Task.
+type hostLimiter struct {
+	mx   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{next: make(map[string]time.Time)}
+}
+
+// reserve books the next request slot for host and returns how long the caller has to wait before sending a request.
+// Slots are spaced by specified interval.
+func (l *hostLimiter) reserve(host string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	now := time.Now()
+	slot, ok := l.next[host]
+	if !ok || slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(interval)
+	return slot.Sub(now)
+}
@@ -0,0 +1,53 @@
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/temoto/robotstxt"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter()
+	assert.Equal(t, time.Duration(0), l.reserve("example.com", time.Second))
+	wait := l.reserve("example.com", time.Second)
+	assert.True(t, wait > 900*time.Millisecond && wait <= time.Second)
+	wait = l.reserve("example.com", time.Second)
+	assert.True(t, wait > 1900*time.Millisecond && wait <= 2*time.Second)
+	//other hosts are not affected
+	assert.Equal(t, time.Duration(0), l.reserve("example.org", time.Second))
+	//zero interval never waits
+	assert.Equal(t, time.Duration(0), l.reserve("example.com", 0))
+}
+
+func TestTaskSitemaps(t *testing.T) {
+	a, _ := robotstxt.FromString("User-agent: *\nSitemap: http://example.com/sitemap.xml\n")
+	b, _ := robotstxt.FromString("Sitemap: http://example.com/sitemap.xml\nSitemap: http://example.org/news.xml\n")
+	task := &Task{Robots: map[string]*robotstxt.RobotsData{"example.com": a, "example.org": b, "example.net": nil}}
+	assert.ElementsMatch(t, []string{"http://example.com/sitemap.xml", "http://example.org/news.xml"}, task.Sitemaps())
+	assert.Empty(t, (&Task{Robots: map[string]*robotstxt.RobotsData{}}).Sitemaps())
+}
+
+func TestTaskCrawlDelay(t *testing.T) {
+	defer viper.Set("IGNORE_ROBOTS_TXT", false)
+	robots, _ := robotstxt.FromString("User-agent: *\nCrawl-delay: 2\nDisallow: /private\n")
+	task := &Task{Robots: map[string]*robotstxt.RobotsData{"example.com": robots}}
+	req := fetch.Request{URL: "http://example.com/private/1"}
+
+	viper.Set("IGNORE_ROBOTS_TXT", false)
+	host, delay := task.crawlDelay(req)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, 2*time.Second, delay)
+	assert.Error(t, task.allowedByRobots(req, false))
+
+	//Crawl-delay is honored only when robots.txt is complied with
+	viper.Set("IGNORE_ROBOTS_TXT", true)
+	host, delay = task.crawlDelay(req)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, time.Duration(0), delay)
+	other := &Task{Robots: map[string]*robotstxt.RobotsData{}}
+	assert.NoError(t, other.allowedByRobots(fetch.Request{URL: "http://127.0.0.1:1/private"}, false))
+}
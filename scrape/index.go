@@ -0,0 +1,96 @@
+package scrape
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// matchIndex selects matches of a field selector within a block by their position.
+// Positions start from 0. Negative positions count from the last match.
+type matchIndex struct {
+	start, end int
+	//hasEnd is false for open ranges like "2:"
+	hasEnd bool
+	//single is true for a single match like "1" or "-1"
+	single bool
+}
+
+// parseMatchIndex parses field index. It may be a single position "1", the last match "-1",
+// or a range "start:end" where end is exclusive and either bound may be omitted, f.e. "0:3" or "2:".
+func parseMatchIndex(s string) (*matchIndex, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	bad := errs.BadPayload{ErrText: fmt.Sprintf("invalid field index %q", s)}
+	parts := strings.Split(s, ":")
+	if len(parts) > 2 {
+		return nil, bad
+	}
+	bound := func(b string, def int) (int, error) {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			return def, nil
+		}
+		return strconv.Atoi(b)
+	}
+	m := &matchIndex{}
+	var err error
+	if len(parts) == 1 {
+		if m.start, err = strconv.Atoi(s); err != nil {
+			return nil, bad
+		}
+		m.single = true
+		return m, nil
+	}
+	if m.start, err = bound(parts[0], 0); err != nil {
+		return nil, bad
+	}
+	m.hasEnd = strings.TrimSpace(parts[1]) != ""
+	if m.end, err = bound(parts[1], 0); err != nil {
+		return nil, bad
+	}
+	return m, nil
+}
+
+// pick returns matches of sel at the index positions. Positions out of range are ignored.
+func (m *matchIndex) pick(sel *goquery.Selection) *goquery.Selection {
+	if m == nil {
+		return sel
+	}
+	n := sel.Length()
+	abs := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > n {
+			return n
+		}
+		return i
+	}
+	if m.single {
+		i := m.start
+		if i < 0 {
+			i += n
+		}
+		if i < 0 || i >= n {
+			return sel.Slice(0, 0)
+		}
+		return sel.Eq(i)
+	}
+	start, end := abs(m.start), n
+	if m.hasEnd {
+		end = abs(m.end)
+	}
+	if start >= end {
+		return sel.Slice(0, 0)
+	}
+	return sel.Slice(start, end)
+}
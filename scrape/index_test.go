@@ -0,0 +1,40 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchIndex(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<p class="price">1</p><p class="price">2</p><p class="price">3</p><p class="price">4</p>`))
+	assert.NoError(t, err)
+	sel := doc.Find(".price")
+	pick := func(index string) string {
+		m, err := parseMatchIndex(index)
+		assert.NoError(t, err, index)
+		return strings.Join(m.pick(sel).Map(func(i int, s *goquery.Selection) string { return s.Text() }), ",")
+	}
+	assert.Equal(t, "1,2,3,4", pick(""))
+	assert.Equal(t, "2", pick("1"))
+	assert.Equal(t, "4", pick("-1"))
+	assert.Equal(t, "3", pick(" -2 "))
+	assert.Equal(t, "", pick("4"))
+	assert.Equal(t, "", pick("-5"))
+	assert.Equal(t, "1,2,3", pick("0:3"))
+	assert.Equal(t, "1,2", pick(":2"))
+	assert.Equal(t, "3,4", pick("2:"))
+	assert.Equal(t, "2,3", pick("1:-1"))
+	assert.Equal(t, "3,4", pick("-2:"))
+	assert.Equal(t, "1,2,3,4", pick("0:10"))
+	assert.Equal(t, "", pick("3:1"))
+	assert.Equal(t, "", pick("10:"))
+
+	for _, bad := range []string{"a", "1:b", "1:2:3", "last"} {
+		_, err := parseMatchIndex(bad)
+		assert.Error(t, err, bad)
+	}
+}
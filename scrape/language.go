@@ -0,0 +1,90 @@
+package scrape
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/slotix/dataflowkit/lang"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// languageField is the name of the record field containing detected language.
+const languageField = "language"
+
+// addLanguage sets language detected over text values of record if DetectLanguage is turned on.
+// A field with the same name extracted from the page is kept intact.
+func (p Payload) addLanguage(record map[string]interface{}) {
+	if !utils.IsTrue(p.DetectLanguage) {
+		return
+	}
+	if _, ok := record[languageField]; ok {
+		return
+	}
+	if l := lang.Detect(recordText(record)); l != "" {
+		record[languageField] = l
+	}
+}
+
+// columns returns names of output columns for CSV and XLSX formats.
+func (p Payload) columns(partNames []string) []string {
+	if utils.IsTrue(p.DetectLanguage) {
+		partNames = addColumn(partNames, languageField)
+	}
+	if p.batch() {
+		partNames = addColumn(partNames, sourceURLField)
+		for _, name := range p.URLList.varNames() {
+			partNames = addColumn(partNames, name)
+		}
+	}
+	if utils.IsTrue(p.FetchMetadata) {
+		for _, name := range fetchMetaFields {
+			partNames = addColumn(partNames, name)
+		}
+	}
+	return partNames
+}
+
+// addColumn appends column name unless there is a field with the same name.
+func addColumn(partNames []string, name string) []string {
+	for _, n := range partNames {
+		if n == name {
+			return partNames
+		}
+	}
+	return append(partNames, name)
+}
+
+// recordText joins text values of record including details. URLs are skipped.
+func recordText(v interface{}) string {
+	texts := []string{}
+	switch value := v.(type) {
+	case string:
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+			return ""
+		}
+		return value
+	case []string:
+		for _, s := range value {
+			texts = append(texts, recordText(s))
+		}
+	case []interface{}:
+		for _, s := range value {
+			texts = append(texts, recordText(s))
+		}
+	case []map[string]interface{}:
+		for _, s := range value {
+			texts = append(texts, recordText(s))
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			texts = append(texts, recordText(value[k]))
+		}
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,32 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddLanguage(t *testing.T) {
+	record := map[string]interface{}{
+		"title": "Der schnelle braune Fuchs springt über den faulen Hund",
+		"link":  "https://example.com/der-fuchs-und-der-hund",
+		"details": []map[string]interface{}{
+			{"text": "und läuft in den Wald"},
+		},
+	}
+	p := Payload{}
+	p.addLanguage(record)
+	assert.NotContains(t, record, "language")
+	assert.Equal(t, []string{"title"}, p.columns([]string{"title"}))
+
+	detect := true
+	p.DetectLanguage = &detect
+	p.addLanguage(record)
+	assert.Equal(t, "de", record["language"])
+	assert.Equal(t, []string{"title", "language"}, p.columns([]string{"title"}))
+
+	//extracted field is kept intact
+	record = map[string]interface{}{"title": "The quick brown fox jumps over the lazy dog", "language": "English"}
+	p.addLanguage(record)
+	assert.Equal(t, "English", record["language"])
+}
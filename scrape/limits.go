@@ -0,0 +1,130 @@
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Limits are ceilings of a single parse job. Once any of them is reached no more pages are fetched
+// and results extracted so far are returned. Zero value means no limit.
+// Limits set by JOB_MAX_PAGES, JOB_MAX_BYTES and JOB_MAX_DURATION of parse.d service can't be exceeded by payload.
+type Limits struct {
+	//MaxPages is the maximum number of fetched pages including paginated and details pages.
+	MaxPages int `json:"maxPages"`
+	//MaxBytes is the maximum total size of fetched pages in bytes.
+	MaxBytes int64 `json:"maxBytes"`
+	//MaxDuration is the maximum duration of the job in seconds.
+	MaxDuration int `json:"maxDuration"`
+}
+
+// Names of reached limits
+const (
+	limitPages    = "max pages"
+	limitBytes    = "max bytes"
+	limitDuration = "max duration"
+)
+
+// errLimitReached is returned instead of fetching a page once job limit is reached.
+type errLimitReached struct {
+	limit string
+}
+
+func (e errLimitReached) Error() string {
+	return fmt.Sprintf("Job limit reached: %s", e.limit)
+}
+
+// effective returns payload limits capped by service ones.
+func (l *Limits) effective() Limits {
+	var res Limits
+	if l != nil {
+		res = *l
+	}
+	res.MaxPages = int(minLimit(int64(res.MaxPages), int64(viper.GetInt("JOB_MAX_PAGES"))))
+	res.MaxBytes = minLimit(res.MaxBytes, viper.GetInt64("JOB_MAX_BYTES"))
+	res.MaxDuration = int(minLimit(int64(res.MaxDuration), int64(viper.GetInt("JOB_MAX_DURATION"))))
+	return res
+}
+
+// minLimit returns the smaller of positive limits a and b. Non-positive limit means no limit.
+func minLimit(a, b int64) int64 {
+	if a <= 0 {
+		return b
+	}
+	if b > 0 && b < a {
+		return b
+	}
+	return a
+}
+
+// jobLimiter enforces job limits.
+type jobLimiter struct {
+	limits  Limits
+	start   time.Time
+	mx      sync.Mutex
+	pages   int
+	bytes   int64
+	reached string
+}
+
+func newJobLimiter(limits Limits) *jobLimiter {
+	return &jobLimiter{limits: limits, start: time.Now()}
+}
+
+// take accounts a page about to be fetched. It returns errLimitReached if any of limits is reached.
+func (j *jobLimiter) take() error {
+	if j == nil {
+		return nil
+	}
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	switch {
+	case j.reached != "":
+	case j.limits.MaxDuration > 0 && time.Since(j.start) >= time.Duration(j.limits.MaxDuration)*time.Second:
+		j.reached = limitDuration
+	case j.limits.MaxPages > 0 && j.pages >= j.limits.MaxPages:
+		j.reached = limitPages
+	default:
+		j.pages++
+		return nil
+	}
+	return errLimitReached{j.reached}
+}
+
+// countBytes returns reader accounting bytes of fetched page read from r.
+// The page being read is processed completely even if it exceeds the limit.
+func (j *jobLimiter) countBytes(r io.Reader) io.Reader {
+	if j == nil || j.limits.MaxBytes <= 0 {
+		return r
+	}
+	return &countingReader{Reader: r, j: j}
+}
+
+// limitReached returns the name of reached limit or empty string.
+func (j *jobLimiter) limitReached() string {
+	if j == nil {
+		return ""
+	}
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	return j.reached
+}
+
+type countingReader struct {
+	io.Reader
+	j *jobLimiter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.j.mx.Lock()
+	r.j.bytes += int64(n)
+	if r.j.bytes >= r.j.limits.MaxBytes && r.j.reached == "" {
+		r.j.reached = limitBytes
+	}
+	r.j.mx.Unlock()
+	return n, err
+}
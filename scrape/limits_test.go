@@ -0,0 +1,50 @@
+package scrape
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsEffective(t *testing.T) {
+	viper.Set("JOB_MAX_PAGES", 100)
+	viper.Set("JOB_MAX_BYTES", 0)
+	viper.Set("JOB_MAX_DURATION", 60)
+	defer func() {
+		viper.Set("JOB_MAX_PAGES", 0)
+		viper.Set("JOB_MAX_DURATION", 0)
+	}()
+	var l *Limits
+	assert.Equal(t, Limits{MaxPages: 100, MaxDuration: 60}, l.effective())
+	l = &Limits{MaxPages: 500, MaxBytes: 1024, MaxDuration: 10}
+	assert.Equal(t, Limits{MaxPages: 100, MaxBytes: 1024, MaxDuration: 10}, l.effective())
+}
+
+func TestJobLimiter(t *testing.T) {
+	var off *jobLimiter
+	assert.NoError(t, off.take())
+	assert.Equal(t, "", off.limitReached())
+
+	j := newJobLimiter(Limits{MaxPages: 2})
+	assert.NoError(t, j.take())
+	assert.NoError(t, j.take())
+	assert.Equal(t, errLimitReached{limitPages}, j.take())
+	assert.Equal(t, limitPages, j.limitReached())
+
+	j = newJobLimiter(Limits{MaxBytes: 10})
+	assert.NoError(t, j.take())
+	data, err := ioutil.ReadAll(j.countBytes(strings.NewReader("0123456789abcdef")))
+	assert.NoError(t, err)
+	assert.Len(t, data, 16, "current page is read completely")
+	assert.Error(t, j.take())
+	assert.Equal(t, limitBytes, j.limitReached())
+
+	j = newJobLimiter(Limits{MaxDuration: 1})
+	j.start = time.Now().Add(-2 * time.Second)
+	assert.Error(t, j.take())
+	assert.Equal(t, limitDuration, j.limitReached())
+}
@@ -0,0 +1,135 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// LinkCheckJob is the type of payload checking request URL and seed URLs with HEAD requests
+// instead of extracting fields from their pages.
+const LinkCheckJob = "linkcheck"
+
+// linkCheckColumns are fields of records of link check job.
+var linkCheckColumns = []string{"url", "status", "final_url", "content_type", "content_length", "last_modified", "error"}
+
+// linkCheck reports whether payload is a link check job.
+func (p Payload) linkCheck() bool {
+	return strings.EqualFold(p.Type, LinkCheckJob)
+}
+
+// validateType checks payload job type. HEAD requests return no content to extract, so they are sent by link check jobs only.
+func (p Payload) validateType() error {
+	switch {
+	case p.Type != "" && !p.linkCheck():
+		return errs.BadPayload{ErrText: "unknown payload type " + p.Type}
+	case !p.linkCheck() && strings.EqualFold(p.Request.Method, "HEAD"):
+		return errs.BadPayload{ErrText: "HEAD requests are sent by " + LinkCheckJob + " payloads only"}
+	}
+	return nil
+}
+
+// linkChecker returns scraper of link check job. Its parts name record fields, nothing is extracted.
+func (p Payload) linkChecker() *Scraper {
+	parts := make([]Part, len(linkCheckColumns))
+	for i, name := range linkCheckColumns {
+		parts[i] = Part{Name: name}
+	}
+	return &Scraper{Request: p.Request, reqType: "initial", Parts: parts}
+}
+
+// checkLinks sends HEAD request to every seed URL with Base fetcher and stores a record describing the response
+// of every URL: status code, the URL redirects end at, content type and length. Page bodies are not downloaded.
+// Records keep the order of seeds. A failed URL is recorded along with its error and doesn't stop the job.
+func (task *Task) checkLinks(tw *taskWorker) error {
+	workers := viper.GetInt("FETCH_WORKER_NUM")
+	if workers < 1 {
+		workers = 1
+	}
+	task.fetchChannel = make(chan *fetchInfo, viper.GetInt("FETCH_CHANNEL_SIZE"))
+	for i := 0; i < workers; i++ {
+		go task.fetchWorker()
+	}
+	seeds := task.Payload.seeds()
+	reqs := make([]fetch.Request, len(seeds))
+	for i, s := range seeds {
+		req := s.request(tw.scraper.Request)
+		req.Method, req.Type, req.FormData, req.Body = "HEAD", "base", "", ""
+		reqs[i] = req
+	}
+	pf := task.newPrefetcher("initial", reqs)
+	if pf.window < workers {
+		pf.window = workers
+	}
+	defer pf.close()
+	for i, req := range reqs {
+		if task.ctx.Err() != nil || task.limiter.limitReached() != "" {
+			break
+		}
+		record, err := checkLink(req, pf.take())
+		task.stats.page(err)
+		task.Payload.addSeedFields(record, seeds[i].URL, task.seedVars[seeds[i].URL])
+		if err := task.storeLink(tw, i, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLink waits for the response to HEAD request req and returns the record describing it.
+func checkLink(req fetch.Request, pending *pendingFetch) (map[string]interface{}, error) {
+	record := map[string]interface{}{"url": req.URL}
+	var content io.ReadCloser
+	select {
+	case content = <-pending.result:
+	case err := <-pending.err:
+		switch e := err.(type) {
+		case errs.FetchError:
+			record["status"] = e.Code
+			record["final_url"] = e.FinalURL
+			record["error"] = e.Reason
+		default:
+			//the page has not been requested, f.e. it is disallowed by robots.txt
+			record["error"] = err.Error()
+		}
+		return record, err
+	}
+	defer content.Close()
+	h := fetch.ResponseHeader(content)
+	if h == nil {
+		return record, fmt.Errorf("No response headers of %s", req.URL)
+	}
+	record["status"], _ = strconv.Atoi(h.Get(fetch.StatusHeader))
+	record["final_url"] = h.Get(fetch.FinalURLHeader)
+	record["content_type"] = h.Get(fetch.ContentTypeHeader)
+	if length, err := strconv.ParseInt(h.Get(fetch.ContentLengthHeader), 10, 64); err == nil {
+		record["content_length"] = length
+	}
+	record["last_modified"] = h.Get("Last-Modified")
+	return record, nil
+}
+
+// storeLink stores record of the seed number i as the only block of page i.
+func (task *Task) storeLink(tw *taskWorker, i int, record map[string]interface{}) error {
+	task.stats.record(record)
+	output, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	task.Parsed = true
+	tw.keys[i] = append(tw.keys[i], 0)
+	return task.storage.Write(storage.Record{
+		Type:  storage.INTERMEDIATE,
+		Key:   fmt.Sprintf("%s-%d-%d", tw.UID, i, 0),
+		Value: output,
+	})
+}
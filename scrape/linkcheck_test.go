@@ -0,0 +1,59 @@
+package scrape
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload_validateType(t *testing.T) {
+	assert.NoError(t, Payload{}.validateType())
+	assert.NoError(t, Payload{Type: "LinkCheck", Request: fetch.Request{Method: "HEAD"}}.validateType())
+	assert.Error(t, Payload{Type: "crawl"}.validateType())
+	assert.Error(t, Payload{Request: fetch.Request{Method: "head"}}.validateType())
+
+	p := Payload{Type: LinkCheckJob, URLs: []Seed{{URL: "http://example.com/a"}}}
+	assert.Equal(t, []string{"url", "status", "final_url", "content_type", "content_length", "last_modified", "error", "source_url"},
+		p.columns(p.linkChecker().partNames()))
+}
+
+func TestCheckLink(t *testing.T) {
+	req := fetch.Request{URL: "http://example.com/old", Method: "HEAD"}
+	h := http.Header{}
+	h.Set(fetch.StatusHeader, "200")
+	h.Set(fetch.FinalURLHeader, "http://example.com/new")
+	h.Set(fetch.ContentTypeHeader, "text/html")
+	h.Set(fetch.ContentLengthHeader, "512")
+	pending := &pendingFetch{result: make(chan io.ReadCloser, 1), err: make(chan error, 1)}
+	pending.result <- fetch.WithResponseHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	record, err := checkLink(req, pending)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"url":            "http://example.com/old",
+		"status":         200,
+		"final_url":      "http://example.com/new",
+		"content_type":   "text/html",
+		"content_length": int64(512),
+		"last_modified":  "",
+	}, record)
+
+	pending.err <- errs.FetchError{URL: req.URL, FinalURL: "http://example.com/gone", Code: 410, Reason: "410 Gone"}
+	record, err = checkLink(req, pending)
+	assert.Error(t, err)
+	assert.Equal(t, 410, record["status"])
+	assert.Equal(t, "http://example.com/gone", record["final_url"])
+	assert.Equal(t, "410 Gone", record["error"])
+
+	pending.err <- errors.New("connection refused")
+	record, err = checkLink(req, pending)
+	assert.Error(t, err)
+	assert.NotContains(t, record, "status")
+	assert.Equal(t, "connection refused", record["error"])
+}
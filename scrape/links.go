@@ -0,0 +1,28 @@
+package scrape
+
+import (
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// Links downloads a web page specified by req and returns all links found on it.
+// Relative links are resolved against the document base URL.
+func Links(req fetch.Request) ([]extract.Link, error) {
+	content, err := fetchContent(req)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	links, err := extract.Links{
+		BaseURL:        extract.DocumentBaseURL(doc.Selection, req.URL),
+		IncludeIfEmpty: true,
+	}.Extract(doc.Selection)
+	if err != nil {
+		return nil, err
+	}
+	return links.([]extract.Link), nil
+}
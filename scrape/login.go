@@ -0,0 +1,176 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"go.uber.org/zap"
+)
+
+// Login signs in to a web site before payload pages are fetched. It is performed by Chrome fetcher
+// once per Request.UserToken. Cookies and localStorage of the session are kept by Fetch service
+// for the user token and reused by all subsequent fetches until the session expires.
+type Login struct {
+	// URL of the login page.
+	URL string `json:"url"`
+	// Form is filled in and submitted on the login page.
+	Form fetch.FillForm `json:"form"`
+	// Success is a selector of an element present on the page loaded after successful login,
+	// f.e. a link to sign out.
+	Success string `json:"success"`
+	// LoggedOut is a selector of an element detecting expired session on fetched pages,
+	// f.e. a login form. Login is repeated and the page is fetched again once it is found.
+	LoggedOut string `json:"loggedOut,omitempty"`
+}
+
+// validate checks login settings of payload with request r.
+func (l *Login) validate(r fetch.Request) error {
+	if l == nil {
+		return nil
+	}
+	switch {
+	case l.URL == "":
+		return errs.BadPayload{ErrText: "login requires url"}
+	case len(l.Form.Fields) == 0:
+		return errs.BadPayload{ErrText: "login requires form fields"}
+	case l.Success == "":
+		return errs.BadPayload{ErrText: "login requires success selector"}
+	case r.UserToken == "":
+		return errs.BadPayload{ErrText: "login requires request userToken to keep the session"}
+	}
+	return nil
+}
+
+// sessionKey returns a storage key of the record marking that user is signed in.
+func (l *Login) sessionKey(userToken string) string {
+	return "session-" + hex.EncodeToString(utils.GenerateMD5([]byte(userToken+"\n"+l.URL)))
+}
+
+// loggedOut reports whether fetched page shows that the session has expired.
+func (l *Login) loggedOut(data []byte) bool {
+	if l == nil || l.LoggedOut == "" {
+		return false
+	}
+	doc, err := parseDocument(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	return doc.Find(l.LoggedOut).Length() > 0
+}
+
+// login signs in unless the session of payload user token is already established.
+// Login is performed anyway if force is true.
+func (task *Task) login(force bool) error {
+	l := task.Payload.Login
+	if l == nil {
+		return nil
+	}
+	token := task.Payload.Request.UserToken
+	rec := storage.Record{Type: storage.COOKIES, Key: l.sessionKey(token)}
+	if !force && task.storage.IsExists(rec) {
+		return nil
+	}
+	form := l.Form
+	req := fetch.Request{
+		Type:      "chrome",
+		URL:       l.URL,
+		FillForm:  &form,
+		UserToken: token,
+		RequestID: task.Payload.RequestID,
+		Job:       task.job(),
+	}
+	content, err := fetchContent(req)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return err
+	}
+	if doc.Find(l.Success).Length() == 0 {
+		if task.storage.IsExists(rec) {
+			if err := task.storage.Delete(rec); err != nil {
+				task.log().Warn(err.Error())
+			}
+		}
+		return errs.StatusError{
+			Code: http.StatusUnauthorized,
+			Err:  fmt.Errorf("Login failed. %q not found on %s", l.Success, l.URL),
+		}
+	}
+	task.log().Info("Logged in", zap.String("URL", l.URL))
+	return task.storage.Write(storage.Record{
+		Type:  storage.COOKIES,
+		Key:   l.sessionKey(token),
+		Value: []byte(time.Now().UTC().Format(time.RFC3339)),
+	})
+}
+
+// fetchSession fetches req with the session of payload user token. If the page shows that the session
+// has expired, login is repeated and the page is fetched again.
+// Pages are taken from source archive if payload specifies it. Credentials of the payload are attached to req
+// if it is sent to their domain.
+func (task *Task) fetchSession(req fetch.Request) (io.ReadCloser, error) {
+	if task.source != nil {
+		return task.source.fetch(req)
+	}
+	req = task.Payload.credentials.attach(req)
+	req.Job = task.job()
+	l := task.Payload.Login
+	if l == nil {
+		return fetchContent(req)
+	}
+	req.UserToken = task.Payload.Request.UserToken
+	for attempt := 0; ; attempt++ {
+		task.loginMx.Lock()
+		session := task.session
+		task.loginMx.Unlock()
+
+		content, err := fetchContent(req)
+		if err != nil || l.LoggedOut == "" {
+			return content, err
+		}
+		data, err := ioutil.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !l.loggedOut(data) {
+			return fetch.WithResponseHeader(ioutil.NopCloser(bytes.NewReader(data)), fetch.ResponseHeader(content)), nil
+		}
+		if attempt > 0 {
+			return nil, errs.StatusError{
+				Code: http.StatusUnauthorized,
+				Err:  fmt.Errorf("Session expired again after login while fetching %s", req.URL),
+			}
+		}
+		task.log().Info("Session expired", zap.String("URL", req.URL))
+		if err := task.relogin(session); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// relogin logs in again unless another worker has already done it since session was established.
+func (task *Task) relogin(session int) error {
+	task.loginMx.Lock()
+	defer task.loginMx.Unlock()
+	if task.session != session {
+		return nil
+	}
+	if err := task.login(true); err != nil {
+		return err
+	}
+	task.session++
+	return nil
+}
@@ -0,0 +1,32 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogin(t *testing.T) {
+	l := &Login{
+		URL: "http://example.com/login",
+		Form: fetch.FillForm{
+			Fields: []fetch.FormField{{Selector: "#user", Value: "user"}},
+		},
+		Success:   "a.logout",
+		LoggedOut: "form#login",
+	}
+	req := fetch.Request{URL: "http://example.com/orders", UserToken: "user"}
+	assert.NoError(t, l.validate(req))
+	assert.NoError(t, (*Login)(nil).validate(req))
+	assert.Error(t, l.validate(fetch.Request{URL: req.URL}), "user token is required")
+	noSuccess := *l
+	noSuccess.Success = ""
+	assert.Error(t, noSuccess.validate(req))
+
+	assert.NotEqual(t, l.sessionKey("user"), l.sessionKey("admin"))
+
+	assert.True(t, l.loggedOut([]byte(`<html><body><form id="login"></form></body></html>`)))
+	assert.False(t, l.loggedOut([]byte(`<html><body><a class="logout">Sign out</a></body></html>`)))
+	assert.False(t, (*Login)(nil).loggedOut([]byte(`<form id="login"></form>`)))
+}
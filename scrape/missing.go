@@ -0,0 +1,120 @@
+package scrape
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// Policies applied to fields whose selectors matched nothing or whose extracted value is empty.
+// By default such fields are left out of the record.
+const (
+	//missingEmpty sets the field to an empty string
+	missingEmpty = "empty"
+	//missingNull sets the field to null
+	missingNull = "null"
+	//missingDefault sets the field to Field.Default
+	missingDefault = "default"
+	//missingDrop drops the whole record
+	missingDrop = "drop"
+	//missingFail fails the job
+	missingFail = "fail"
+)
+
+// missingPolicy is a policy applied by a part to missing values.
+type missingPolicy struct {
+	policy string
+	value  interface{}
+}
+
+// parseMissing returns the policy of field f. Field policy overrides the payload one.
+// Field Default implies "default" policy unless another one is set for the field.
+func (p Payload) parseMissing(f *Field) (*missingPolicy, error) {
+	policy := strings.ToLower(f.OnMissing)
+	if policy == "" && f.Default != nil {
+		policy = missingDefault
+	}
+	if policy == "" {
+		policy = strings.ToLower(p.OnMissing)
+	}
+	switch policy {
+	case "":
+		return nil, nil
+	case missingEmpty, missingNull, missingDrop, missingFail:
+		return &missingPolicy{policy: policy}, nil
+	case missingDefault:
+		if f.Default == nil {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("field %s: onMissing default requires default value", f.Name)}
+		}
+		return &missingPolicy{policy: policy, value: f.Default}, nil
+	}
+	return nil, errs.BadPayload{ErrText: fmt.Sprintf("field %s: unknown onMissing policy %q", f.Name, policy)}
+}
+
+// hasMissingPolicy reports whether payload or any of its fields sets a missing value policy.
+func (p Payload) hasMissingPolicy() bool {
+	if p.OnMissing != "" {
+		return true
+	}
+	for _, f := range p.Fields {
+		if f.OnMissing != "" || f.Default != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// errMissingValue fails the job if a field with "fail" policy has no value.
+func errMissingValue(part, url string) error {
+	return errs.StatusError{
+		Code: http.StatusUnprocessableEntity,
+		Err:  fmt.Errorf("field %s has no value on %s", part, url),
+	}
+}
+
+// applyMissing fills missing values of record extracted by parts according to their policies.
+// It returns false if the record should be dropped and an error if the job should fail.
+func applyMissing(record map[string]interface{}, parts []Part, url string) (bool, error) {
+	keep := true
+	for _, part := range parts {
+		if part.missing == nil {
+			continue
+		}
+		if v, ok := record[part.Name]; ok && !emptyValue(v) {
+			continue
+		}
+		switch part.missing.policy {
+		case missingEmpty:
+			record[part.Name] = ""
+		case missingNull:
+			record[part.Name] = nil
+		case missingDefault:
+			record[part.Name] = part.missing.value
+		case missingDrop:
+			keep = false
+		case missingFail:
+			return false, errMissingValue(part.Name, url)
+		}
+	}
+	return keep, nil
+}
+
+// fail cancels the task. The first err is returned by Parse.
+func (task *Task) fail(err error) {
+	task.mx.Lock()
+	if task.failure == nil {
+		task.failure = err
+		task.log().Error("Job failed. " + err.Error())
+	}
+	task.mx.Unlock()
+	task.Cancel()
+}
+
+// failed returns an error the task has failed with.
+func (task *Task) failed() error {
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	return task.failure
+}
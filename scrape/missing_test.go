@@ -0,0 +1,57 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMissing(t *testing.T) {
+	p := Payload{OnMissing: "Null"}
+	m, err := p.parseMissing(&Field{Name: "title"})
+	assert.NoError(t, err)
+	assert.Equal(t, &missingPolicy{policy: missingNull}, m)
+
+	m, err = p.parseMissing(&Field{Name: "price", OnMissing: "fail"})
+	assert.NoError(t, err)
+	assert.Equal(t, missingFail, m.policy)
+
+	m, err = p.parseMissing(&Field{Name: "stock", Default: "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, &missingPolicy{policy: missingDefault, value: "unknown"}, m)
+
+	m, err = Payload{}.parseMissing(&Field{Name: "title"})
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = p.parseMissing(&Field{Name: "title", OnMissing: "default"})
+	assert.Error(t, err)
+	_, err = p.parseMissing(&Field{Name: "title", OnMissing: "skip"})
+	assert.Error(t, err)
+
+	assert.False(t, Payload{Fields: []Field{{Name: "title"}}}.hasMissingPolicy())
+	assert.True(t, Payload{Fields: []Field{{Name: "title", Default: 0}}}.hasMissingPolicy())
+}
+
+func TestApplyMissing(t *testing.T) {
+	parts := []Part{
+		{Name: "title_text", missing: &missingPolicy{policy: missingEmpty}},
+		{Name: "brand_text", missing: &missingPolicy{policy: missingNull}},
+		{Name: "stock_text", missing: &missingPolicy{policy: missingDefault, value: 0}},
+		{Name: "desc_text"},
+	}
+	record := map[string]interface{}{"title_text": "Lamp", "brand_text": []string{}}
+	keep, err := applyMissing(record, parts, "http://example.com")
+	assert.NoError(t, err)
+	assert.True(t, keep)
+	assert.Equal(t, map[string]interface{}{"title_text": "Lamp", "brand_text": nil, "stock_text": 0}, record)
+
+	record = map[string]interface{}{"title_text": "Lamp"}
+	keep, err = applyMissing(record, append(parts, Part{Name: "price_text", missing: &missingPolicy{policy: missingDrop}}), "http://example.com")
+	assert.NoError(t, err)
+	assert.False(t, keep)
+
+	_, err = applyMissing(map[string]interface{}{"price_text": ""},
+		[]Part{{Name: "price_text", missing: &missingPolicy{policy: missingFail}}}, "http://example.com")
+	assert.EqualError(t, err, "field price_text has no value on http://example.com")
+}
@@ -0,0 +1,64 @@
+package scrape
+
+import (
+	"encoding/json"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// ownersKey returns the key tenants owning results are stored with.
+func ownersKey(id string) string {
+	return "owners-" + id
+}
+
+// storedOwners returns tenants owning results with id.
+func storedOwners(s storage.Store, id string) ([]string, error) {
+	owners := []string{}
+	rec := storage.Record{Type: storage.BINARY, Key: ownersKey(id)}
+	if !s.IsExists(rec) {
+		return owners, nil
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &owners); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// saveOwner adds the tenant of the payload to owners of results.
+// Results ID depends on the payload only, so identical payloads of several tenants share results.
+func (task *Task) saveOwner(uid string) error {
+	tenant := task.Payload.Tenant
+	if tenant == "" {
+		return nil
+	}
+	owners, err := storedOwners(task.storage, uid)
+	if err != nil {
+		return err
+	}
+	for _, o := range owners {
+		if o == tenant {
+			return nil
+		}
+	}
+	data, err := json.Marshal(append(owners, tenant))
+	if err != nil {
+		return err
+	}
+	return task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   ownersKey(uid),
+		Value: data,
+	})
+}
+
+// ResultsOwners returns tenants owning results with id. The list is empty for results parsed without a tenant.
+func ResultsOwners(id string) ([]string, error) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	return storedOwners(s, id)
+}
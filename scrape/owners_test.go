@@ -0,0 +1,30 @@
+package scrape
+
+import (
+	"os"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsOwners(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	save := func(tenant string) {
+		task := NewTask(Payload{Name: "owned", Request: fetch.Request{URL: "http://example.com"}, Tenant: tenant})
+		assert.NoError(t, task.saveOwner("owned"))
+		task.storage.Close()
+	}
+	save("")
+	owners, err := ResultsOwners("owned")
+	assert.NoError(t, err)
+	assert.Empty(t, owners)
+
+	save("acme")
+	save("globex")
+	save("acme")
+	owners, err = ResultsOwners("owned")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme", "globex"}, owners)
+}
@@ -0,0 +1,164 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// ExtractorFactory creates a new extractor of a custom type. It is called for every payload part of the type,
+// so extractors keeping state are never shared between fields or payloads.
+type ExtractorFactory func() extract.Extractor
+
+// ConfigurableExtractor is implemented by custom extractors taking params and filters of the payload field.
+// Configure is called once on a new extractor before it is used.
+type ConfigurableExtractor interface {
+	extract.Extractor
+	Configure(params map[string]interface{}, filters []string) error
+}
+
+// Records iterates over parsed records. Next returns io.EOF after the last record.
+type Records interface {
+	Next() (map[string]interface{}, error)
+}
+
+// Encoder writes parsed records in a custom output format.
+// columns contains names of record fields in the order they are specified in payload.
+type Encoder interface {
+	Encode(ctx context.Context, w io.Writer, columns []string, records Records) error
+}
+
+// builtinExtractors and builtinFormats may not be overridden by custom ones.
+var (
+	builtinExtractors = []string{"text", "href", "src", "path", "alt", "width", "height", "srcset", "image", "email", "phone", "regex", "const", "count", "exists", "json", "html", "outerhtml"}
+	builtinFormats    = []string{"csv", "json", "jsonl", "xml", "xlsx"}
+)
+
+var plugins = struct {
+	sync.RWMutex
+	extractors map[string]ExtractorFactory
+	encoders   map[string]Encoder
+}{
+	extractors: make(map[string]ExtractorFactory),
+	encoders:   make(map[string]Encoder),
+}
+
+// RegisterExtractor makes a custom extractor type available by name in payload fields' "types".
+// Names are case insensitive. Built-in extractor types may not be overridden.
+// RegisterExtractor is intended to be called from init functions of packages embedding scrape or of Go plugins.
+func RegisterExtractor(name string, factory ExtractorFactory) error {
+	name = strings.ToLower(name)
+	if name == "" || factory == nil {
+		return fmt.Errorf("extractor name and factory are required")
+	}
+	for _, b := range builtinExtractors {
+		if name == b {
+			return fmt.Errorf("%s is a built-in extractor type", name)
+		}
+	}
+	plugins.Lock()
+	defer plugins.Unlock()
+	if _, ok := plugins.extractors[name]; ok {
+		return fmt.Errorf("extractor type %s is already registered", name)
+	}
+	plugins.extractors[name] = factory
+	return nil
+}
+
+// RegisterEncoder makes a custom output format available by name in payload's "format".
+// Names are case insensitive. Built-in formats may not be overridden.
+func RegisterEncoder(name string, e Encoder) error {
+	name = strings.ToLower(name)
+	if name == "" || e == nil {
+		return fmt.Errorf("encoder name and implementation are required")
+	}
+	for _, b := range builtinFormats {
+		if name == b {
+			return fmt.Errorf("%s is a built-in format", name)
+		}
+	}
+	plugins.Lock()
+	defer plugins.Unlock()
+	if _, ok := plugins.encoders[name]; ok {
+		return fmt.Errorf("format %s is already registered", name)
+	}
+	plugins.encoders[name] = e
+	return nil
+}
+
+// Extractors returns names of registered custom extractor types.
+func Extractors() []string {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	names := []string{}
+	for name := range plugins.extractors {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Encoders returns names of registered custom output formats.
+func Encoders() []string {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	names := []string{}
+	for name := range plugins.encoders {
+		names = append(names, name)
+	}
+	return names
+}
+
+func customExtractor(name string) (ExtractorFactory, bool) {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	f, ok := plugins.extractors[strings.ToLower(name)]
+	return f, ok
+}
+
+func customEncoder(name string) (Encoder, bool) {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	e, ok := plugins.encoders[strings.ToLower(name)]
+	return e, ok
+}
+
+// pluginEncoder adapts custom Encoder to encoder interface used by EncodeToFile.
+type pluginEncoder struct {
+	Encoder
+	partNames []string
+}
+
+func (e pluginEncoder) encode(ctx context.Context, w io.Writer, payloadMD5 string, keys *map[int][]int) error {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	return e.Encode(ctx, w, e.partNames, &storageRecords{reader: newStorageReader(&s, payloadMD5, keys)})
+}
+
+// storageRecords implements Records reading parsed blocks from storage.
+type storageRecords struct {
+	reader *storageResultReader
+}
+
+func (r *storageRecords) Next() (map[string]interface{}, error) {
+	for {
+		block, err := r.reader.Read()
+		if err == nil {
+			return block, nil
+		}
+		switch err.Error() {
+		case errs.EOF:
+			return nil, io.EOF
+		case errs.NextPage:
+			return block, nil
+		default:
+			logger.Error(err.Error())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type upperText struct {
+	suffix string
+}
+
+func (u *upperText) Extract(sel *goquery.Selection) (interface{}, error) {
+	return strings.ToUpper(sel.Text()) + u.suffix, nil
+}
+
+func (u *upperText) Configure(params map[string]interface{}, filters []string) error {
+	suffix, ok := params["suffix"]
+	if !ok {
+		return nil
+	}
+	if u.suffix, ok = suffix.(string); !ok {
+		return fmt.Errorf("suffix should be a string")
+	}
+	return nil
+}
+
+type lineEncoder struct{}
+
+func (lineEncoder) Encode(ctx context.Context, w io.Writer, columns []string, records Records) error {
+	for {
+		r, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, c := range columns {
+			fmt.Fprintf(w, "%v;", r[c])
+		}
+		io.WriteString(w, "\n")
+	}
+}
+
+func TestRegisterExtractor(t *testing.T) {
+	factory := func() extract.Extractor { return &upperText{} }
+	assert.NoError(t, RegisterExtractor("Upper", factory))
+	assert.Error(t, RegisterExtractor("upper", factory))
+	assert.Error(t, RegisterExtractor("text", factory))
+	assert.Contains(t, Extractors(), "upper")
+
+	p := Payload{Fields: []Field{
+		{Name: "title", Selector: "h1", Extractor: Extractor{Types: []string{"upper"}, Params: map[string]interface{}{"suffix": "!"}}},
+		{Name: "subtitle", Selector: "h1", Extractor: Extractor{Types: []string{"upper"}}},
+	}}
+	parts, err := p.fields2parts()
+	assert.NoError(t, err)
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "title_upper", parts[0].Name)
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<h1>hello</h1>"))
+	v, err := parts[0].Extractor.Extract(doc.Find("h1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO!", v)
+	//every part gets an extractor of its own
+	v, err = parts[1].Extractor.Extract(doc.Find("h1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO", v)
+
+	p.Fields[0].Extractor.Params = map[string]interface{}{"suffix": 1}
+	_, err = p.fields2parts()
+	assert.Error(t, err)
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	assert.NoError(t, RegisterEncoder("lines", lineEncoder{}))
+	assert.Error(t, RegisterEncoder("lines", lineEncoder{}))
+	assert.Error(t, RegisterEncoder("csv", lineEncoder{}))
+	assert.Contains(t, Encoders(), "lines")
+
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	keys := map[int][]int{0: {0, 1}, 1: {0}}
+	for page, blocks := range keys {
+		for _, block := range blocks {
+			value, _ := json.Marshal(map[string]interface{}{"a": page, "b": block})
+			err := s.Write(storage.Record{
+				Type:  storage.INTERMEDIATE,
+				Key:   fmt.Sprintf("lines-%d-%d", page, block),
+				Value: value,
+			})
+			assert.NoError(t, err)
+		}
+	}
+	s.Close()
+
+	custom, ok := customEncoder("LINES")
+	assert.True(t, ok)
+	e := pluginEncoder{Encoder: custom, partNames: []string{"b", "a"}}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, e.encode(context.Background(), buf, "lines", &keys))
+	assert.Equal(t, "0;0;\n1;0;\n0;1;\n", buf.String())
+}
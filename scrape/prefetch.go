@@ -0,0 +1,88 @@
+package scrape
+
+import (
+	"io"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+)
+
+// pendingFetch is a page submitted to fetch workers ahead of its extraction.
+// Its channels are buffered so fetch workers don't wait for the page to be taken.
+type pendingFetch struct {
+	result chan io.ReadCloser
+	err    chan error
+	meta   *pageMeta
+}
+
+// discard waits for the page fetched ahead and releases it. It is used for pages which won't be extracted.
+func (pf *pendingFetch) discard() {
+	select {
+	case content := <-pf.result:
+		content.Close()
+	case <-pf.err:
+	}
+}
+
+// prefetcher submits known requests of the job, f.e. seed URLs of batch payload or details links of a block,
+// to fetch workers up to PREFETCH_PAGES pages ahead of the page being extracted.
+// So extraction of fetched pages overlaps with fetching of the next ones instead of waiting for them in turn.
+type prefetcher struct {
+	task    *Task
+	reqType string
+	reqs    []fetch.Request
+	pending []*pendingFetch
+	taken   int
+	window  int
+}
+
+func (task *Task) newPrefetcher(reqType string, reqs []fetch.Request) *prefetcher {
+	window := 1 + viper.GetInt("PREFETCH_PAGES")
+	if window < 1 {
+		window = 1
+	}
+	return &prefetcher{
+		task:    task,
+		reqType: reqType,
+		reqs:    reqs,
+		window:  window,
+	}
+}
+
+// take returns pending fetch of the next request and submits the following requests within the window.
+func (p *prefetcher) take() *pendingFetch {
+	for len(p.pending) < len(p.reqs) && len(p.pending) < p.taken+p.window {
+		p.submit(p.reqs[len(p.pending)])
+	}
+	pf := p.pending[p.taken]
+	p.taken++
+	return pf
+}
+
+// submit sends req to fetch workers unless it is disallowed by robots.txt.
+func (p *prefetcher) submit(req fetch.Request) {
+	pf := &pendingFetch{
+		result: make(chan io.ReadCloser, 1),
+		err:    make(chan error, 1),
+		meta:   p.task.newPageMeta(),
+	}
+	p.pending = append(p.pending, pf)
+	if err := p.task.allowedByRobots(req, false); err != nil {
+		pf.err <- err
+		return
+	}
+	p.task.fetchChannel <- &fetchInfo{
+		request: req,
+		reqType: p.reqType,
+		result:  pf.result,
+		err:     pf.err,
+		meta:    pf.meta,
+	}
+}
+
+// close releases pages fetched ahead which have not been taken.
+func (p *prefetcher) close() {
+	for _, pf := range p.pending[p.taken:] {
+		go pf.discard()
+	}
+}
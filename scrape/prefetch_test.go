@@ -0,0 +1,45 @@
+package scrape
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/temoto/robotstxt"
+)
+
+func TestPrefetcher(t *testing.T) {
+	viper.Set("PREFETCH_PAGES", 2)
+	defer viper.Set("PREFETCH_PAGES", 0)
+	task := &Task{
+		Robots:       map[string]*robotstxt.RobotsData{"example.com": nil},
+		fetchChannel: make(chan *fetchInfo, 10),
+	}
+	reqs := []fetch.Request{}
+	for _, u := range []string{"http://example.com/1", "http://example.com/2", "http://example.com/3", "http://example.com/4"} {
+		reqs = append(reqs, fetch.Request{URL: u})
+	}
+	pf := task.newPrefetcher("details", reqs)
+	first := pf.take()
+	//the page being taken and 2 next ones are submitted
+	assert.Equal(t, 3, len(task.fetchChannel))
+	for i := 0; i < 3; i++ {
+		fi := <-task.fetchChannel
+		assert.Equal(t, reqs[i].URL, fi.request.URL)
+		assert.Equal(t, "details", fi.reqType)
+		fi.result <- ioutil.NopCloser(strings.NewReader(fi.request.URL))
+	}
+	content, _ := ioutil.ReadAll(<-first.result)
+	assert.Equal(t, "http://example.com/1", string(content))
+	pf.take()
+	assert.Equal(t, 1, len(task.fetchChannel))
+	<-task.fetchChannel
+	//the rest are submitted already
+	pf.take()
+	pf.take()
+	assert.Equal(t, 0, len(task.fetchChannel))
+	pf.close()
+}
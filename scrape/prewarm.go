@@ -0,0 +1,238 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// defaultPrewarmMaxPages is used if PREWARM_MAX_PAGES is not set.
+const defaultPrewarmMaxPages = 100
+
+// prewarmedPage is a page fetched by Prewarm ahead of a scheduled run of the payload.
+type prewarmedPage struct {
+	Expires time.Time   `json:"expires"`
+	Header  http.Header `json:"header,omitempty"`
+	Content []byte      `json:"content"`
+}
+
+// prewarmKey identifies cached page of req fetched for the payload of the task. Pages are cached per results ID,
+// tenant and user token of the payload along with request cookies and headers, so payloads on the same URL
+// don't take pages fetched with each other's session. Referer is not taken into account as it differs
+// for details and paginated pages between runs.
+func (task *Task) prewarmKey(req fetch.Request) string {
+	fetcherType := req.Type
+	if fetcherType == "" {
+		fetcherType = "base"
+	}
+	token := req.UserToken
+	if token == "" {
+		token = task.Payload.Request.UserToken
+	}
+	parts := []string{task.resultsID(), task.Payload.Tenant, token, fetcherType, req.HTTPMethod(), req.URL, req.FormData, req.Body}
+	for _, name := range sortedKeys(req.Headers) {
+		if !strings.EqualFold(name, "Referer") {
+			parts = append(parts, http.CanonicalHeaderKey(name)+": "+req.Headers[name])
+		}
+	}
+	for _, c := range req.Cookies {
+		parts = append(parts, fmt.Sprintf("cookie %s=%s; domain=%s; path=%s", c.Name, c.Value, c.Domain, c.Path))
+	}
+	key := strings.Join(parts, "\n")
+	return "prewarm-" + hex.EncodeToString(utils.GenerateMD5([]byte(key)))
+}
+
+// Prewarm fetches seed pages of payload p along with the first level of details and paginated pages linked
+// from them and caches them until expires. The scheduled run of the payload takes cached pages instead
+// of fetching them, so it finishes quickly within tight delivery windows. Up to PREWARM_MAX_PAGES pages
+// are fetched one by one honoring robots.txt and fetch delays. It returns the number of cached pages.
+func Prewarm(p Payload, expires time.Time) (int, error) {
+	if p.Login != nil || p.Source != "" || p.linkCheck() {
+		return 0, errs.BadPayload{ErrText: "pages of payloads with login, source archive or link check can't be prewarmed"}
+	}
+	task := NewTask(p)
+	defer task.storage.Close()
+	var err error
+	if task.urlFilter, err = newURLFilter(p.Include, p.Exclude); err != nil {
+		return 0, err
+	}
+	if err := task.loadURLList(); err != nil {
+		return 0, err
+	}
+	maxPages := viper.GetInt("PREWARM_MAX_PAGES")
+	if maxPages <= 0 {
+		maxPages = defaultPrewarmMaxPages
+	}
+	cached := 0
+	queue := []fetch.Request{}
+	for _, s := range task.Payload.seeds() {
+		if task.urlFilter.allowed(s.URL) {
+			queue = append(queue, s.request(task.Payload.Request))
+		}
+	}
+	seeds := len(queue)
+	seen := map[string]bool{}
+	for i := 0; i < len(queue) && cached < maxPages; i++ {
+		req := queue[i]
+		key := task.prewarmKey(req)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		data, header, err := task.prewarmFetch(req)
+		if err != nil {
+			task.log().Warn("Failed to prewarm page. "+err.Error(), zap.String("URL", req.URL))
+			continue
+		}
+		page := prewarmedPage{Expires: expires, Header: header, Content: data}
+		value, err := json.Marshal(page)
+		if err != nil {
+			return cached, err
+		}
+		if err := task.storage.Write(storage.Record{Type: storage.CACHE, Key: key, Value: value}); err != nil {
+			return cached, err
+		}
+		cached++
+		//links are followed from seed pages only
+		if i < seeds {
+			queue = append(queue, task.Payload.linkedRequests(req, data, task.urlFilter)...)
+		}
+	}
+	return cached, nil
+}
+
+// prewarmFetch fetches req once robots.txt allows it and fetch delays have passed.
+func (task *Task) prewarmFetch(req fetch.Request) ([]byte, http.Header, error) {
+	if err := task.allowedByRobots(req, false); err != nil {
+		return nil, nil, err
+	}
+	host, interval := task.crawlDelay(req)
+	if task.serviceDelay() {
+		interval += *task.Payload.FetchDelay
+	} else if !viper.GetBool("IGNORE_FETCH_DELAY") {
+		interval += task.Payload.Delay.next()
+	}
+	if wait := task.hostLimiter.reserve(host, interval); wait > 0 {
+		time.Sleep(wait)
+	}
+	req.RequestID = task.Payload.RequestID
+	content, err := task.fetchSession(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer content.Close()
+	data, err := ioutil.ReadAll(content)
+	return data, fetch.ResponseHeader(content), err
+}
+
+// linkedRequests returns requests of details pages and the next page linked from seed page data fetched by req.
+// Pages loaded by Chrome while scrolling or clicking "Load more" buttons are not linked.
+func (p Payload) linkedRequests(req fetch.Request, data []byte, filter *urlFilter) []fetch.Request {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	base := extract.DocumentBaseURL(doc.Selection, req.URL)
+	link := func(sel *goquery.Selection, attr string) string {
+		href, ok := sel.Attr(attr)
+		if !ok {
+			href, ok = sel.Find("a[href]").First().Attr("href")
+		}
+		if !ok {
+			return ""
+		}
+		u, err := utils.RelUrl(base, strings.TrimSpace(href))
+		if err != nil || !filter.allowed(u) {
+			return ""
+		}
+		return u
+	}
+	reqs := []fetch.Request{}
+	for _, f := range p.Fields {
+		if f.Details == nil || f.Selector == "" {
+			continue
+		}
+		doc.Find(f.Selector).Each(func(i int, s *goquery.Selection) {
+			if u := link(s, "href"); u != "" {
+				reqs = append(reqs, fetch.Request{URL: u, Type: p.Request.Type})
+			}
+		})
+	}
+	if pag := p.Paginator; pag != nil && pag.Selector != "" && pag.Type == "next" {
+		attr := pag.Attribute
+		if attr == "" {
+			attr = "href"
+		}
+		if u := link(doc.Find(pag.Selector).First(), attr); u != "" && u != req.URL {
+			next := req
+			next.URL = u
+			reqs = append(reqs, next)
+		}
+	}
+	return reqs
+}
+
+// prewarmed returns the page of req cached by Prewarm ahead of the scheduled run. A cached page is taken once.
+// It returns nil if the page has not been prewarmed or its cached copy has expired.
+func (task *Task) prewarmed(req fetch.Request) io.ReadCloser {
+	if task.source != nil || task.Payload.Schedule == nil || task.Payload.Schedule.Prewarm <= 0 {
+		return nil
+	}
+	rec := storage.Record{Type: storage.CACHE, Key: task.prewarmKey(req)}
+	if !task.storage.IsExists(rec) {
+		return nil
+	}
+	data, err := task.storage.Read(rec)
+	if err := task.storage.Delete(rec); err != nil {
+		task.log().Warn(err.Error())
+	}
+	if err != nil {
+		return nil
+	}
+	page := prewarmedPage{}
+	if err := json.Unmarshal(data, &page); err != nil || time.Now().After(page.Expires) {
+		return nil
+	}
+	header := page.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(fetch.CacheHeader, "HIT")
+	return fetch.WithResponseHeader(ioutil.NopCloser(bytes.NewReader(page.Content)), header)
+}
+
+// offPeak reports whether t is within PREWARM_HOURS, f.e. "1-6" or "22-5" in local time.
+// Any time is off-peak if PREWARM_HOURS is empty or invalid.
+func offPeak(t time.Time) bool {
+	hours := strings.SplitN(viper.GetString("PREWARM_HOURS"), "-", 2)
+	if len(hours) != 2 {
+		return true
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(hours[0]))
+	to, err2 := strconv.Atoi(strings.TrimSpace(hours[1]))
+	if err1 != nil || err2 != nil {
+		logger.Warn(fmt.Sprintf("Invalid PREWARM_HOURS %q", viper.GetString("PREWARM_HOURS")))
+		return true
+	}
+	h := t.Local().Hour()
+	if from <= to {
+		return h >= from && h < to
+	}
+	return h >= from || h < to
+}
@@ -0,0 +1,132 @@
+package scrape
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrewarmKey(t *testing.T) {
+	task := NewTask(Payload{Name: "a", Request: fetch.Request{URL: "http://example.com/list"}})
+	req := fetch.Request{URL: "http://example.com/list"}
+	assert.Equal(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, Type: "base", Headers: map[string]string{"Referer": "http://example.com"}}))
+	assert.NotEqual(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, Type: "chrome"}))
+	assert.NotEqual(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, FormData: "q=1"}))
+	assert.NotEqual(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, Headers: map[string]string{"Authorization": "Bearer b"}}))
+	assert.NotEqual(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, Cookies: fetch.Cookies{{Name: "sid", Value: "b"}}}))
+	assert.NotEqual(t, task.prewarmKey(req), task.prewarmKey(fetch.Request{URL: req.URL, UserToken: "b"}))
+
+	//payloads on the same URL don't share cached pages
+	other := NewTask(Payload{Name: "b", Request: fetch.Request{URL: "http://example.com/list"}})
+	assert.NotEqual(t, task.prewarmKey(req), other.prewarmKey(req))
+	tenant := NewTask(Payload{Name: "a", Tenant: "acme", Request: fetch.Request{URL: "http://example.com/list"}})
+	assert.NotEqual(t, task.prewarmKey(req), tenant.prewarmKey(req))
+}
+
+func TestLinkedRequests(t *testing.T) {
+	p := Payload{
+		Request: fetch.Request{Type: "chrome"},
+		Fields: []Field{
+			{Name: "title", Selector: "h2", Extractor: Extractor{Types: []string{"text"}}},
+			{Name: "link", Selector: ".item", Details: &details{}},
+		},
+		Paginator: &paginator{Selector: ".next", Type: "next"},
+	}
+	html := `<html><body>
+	<div class="item"><a href="/item/1">1</a></div>
+	<a class="item" href="http://example.com/item/2">2</a>
+	<a class="item" href="http://other.com/item/3">3</a>
+	<h2><a href="/ignored">title</a></h2>
+	<a class="next" href="?page=2">next</a>
+	</body></html>`
+	req := fetch.Request{URL: "http://example.com/list", Type: "chrome"}
+	filter, err := newURLFilter([]string{"http://example.com/*"}, nil)
+	assert.NoError(t, err)
+	reqs := p.linkedRequests(req, []byte(html), filter)
+	urls := []string{}
+	for _, r := range reqs {
+		urls = append(urls, r.URL)
+		assert.Equal(t, "chrome", r.Type)
+	}
+	assert.Equal(t, []string{"http://example.com/item/1", "http://example.com/item/2", "http://example.com/list?page=2"}, urls)
+
+	//scrolling paginators load no linked pages
+	p.Paginator.Type = ""
+	assert.Len(t, p.linkedRequests(req, []byte(html), nil), 3)
+}
+
+func TestOffPeak(t *testing.T) {
+	defer viper.Set("PREWARM_HOURS", "")
+	at := func(h int) time.Time {
+		return time.Date(2019, 5, 1, h, 30, 0, 0, time.Local)
+	}
+	viper.Set("PREWARM_HOURS", "")
+	assert.True(t, offPeak(at(12)))
+	viper.Set("PREWARM_HOURS", "1-6")
+	assert.True(t, offPeak(at(1)))
+	assert.False(t, offPeak(at(6)))
+	assert.False(t, offPeak(at(0)))
+	viper.Set("PREWARM_HOURS", "22-5")
+	assert.True(t, offPeak(at(23)))
+	assert.True(t, offPeak(at(4)))
+	assert.False(t, offPeak(at(12)))
+	viper.Set("PREWARM_HOURS", "night")
+	assert.True(t, offPeak(at(12)))
+}
+
+func TestPrewarmDue(t *testing.T) {
+	next := time.Date(2019, 5, 1, 3, 0, 0, 0, time.Local)
+	sch := Schedule{Interval: 3600, Prewarm: 1800}
+	s := &RecrawlState{NextRun: next}
+	assert.False(t, s.PrewarmDue(sch, next.Add(-time.Hour)))
+	assert.True(t, s.PrewarmDue(sch, next.Add(-10*time.Minute)))
+	assert.False(t, s.PrewarmDue(sch, next))
+	assert.False(t, s.PrewarmDue(Schedule{Interval: 3600}, next.Add(-10*time.Minute)))
+	s.Prewarmed = next
+	assert.False(t, s.PrewarmDue(sch, next.Add(-10*time.Minute)))
+	var none *RecrawlState
+	assert.False(t, none.PrewarmDue(sch, next))
+}
+
+func TestPrewarmed(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	task := &Task{
+		ID:      ksuid.New().String(),
+		Payload: Payload{PayloadMD5: "a", Schedule: &Schedule{Interval: 3600, Prewarm: 1800}},
+		storage: storage.NewStore(viper.GetString("STORAGE_TYPE")),
+	}
+	defer task.storage.Close()
+	other := &Task{Payload: Payload{PayloadMD5: "b", Schedule: task.Payload.Schedule}, storage: task.storage}
+	write := func(req fetch.Request, expires time.Time) {
+		value, _ := json.Marshal(prewarmedPage{Expires: expires, Content: []byte("<html>list</html>")})
+		assert.NoError(t, task.storage.Write(storage.Record{Type: storage.CACHE, Key: task.prewarmKey(req), Value: value}))
+	}
+	req := fetch.Request{URL: "http://example.com/list"}
+	write(req, time.Now().Add(time.Hour))
+	assert.Nil(t, other.prewarmed(req), "pages are not shared by payloads on the same URL")
+	content := task.prewarmed(req)
+	if assert.NotNil(t, content) {
+		data, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		assert.Equal(t, "<html>list</html>", string(data))
+		assert.Equal(t, "HIT", fetch.ResponseHeader(content).Get(fetch.CacheHeader))
+	}
+	//cached page is taken once
+	assert.Nil(t, task.prewarmed(req))
+
+	write(req, time.Now().Add(-time.Minute))
+	assert.Nil(t, task.prewarmed(req), "expired")
+
+	write(req, time.Now().Add(time.Hour))
+	task.Payload.Schedule = nil
+	assert.Nil(t, task.prewarmed(req), "not scheduled for prewarming")
+}
@@ -0,0 +1,334 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+// Query selects records extracted by previous runs of a payload.
+// F.e. all products where price dropped below X across the last 30 runs.
+type Query struct {
+	//ID is a results ID returned by Parse. It is the same for all runs of a payload.
+	ID string `json:"id"`
+	//Runs is the number of the latest runs to be queried. All stored runs are queried if it is 0.
+	Runs int `json:"runs,omitempty"`
+	//Filters are combined with AND.
+	Filters []QueryFilter `json:"filters,omitempty"`
+	Sort    []QuerySort   `json:"sort,omitempty"`
+	//Fields is a projection. All fields are returned if it is empty.
+	Fields []string `json:"fields,omitempty"`
+	Offset int      `json:"offset,omitempty"`
+	Limit  int      `json:"limit,omitempty"`
+}
+
+// QueryFilter compares record field with Value.
+// Op is one of "=", "!=", "<", "<=", ">", ">=", "contains".
+// Values are compared as numbers if both of them are numeric. Otherwise they are compared as strings.
+type QueryFilter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// QuerySort specifies sort order by Field.
+type QuerySort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// QueryResult contains records matching Query.
+// Every record has "_run" and "_time" fields containing Task ID and start time of the run it was extracted by.
+type QueryResult struct {
+	//Total is the number of matching records before Offset and Limit are applied
+	Total   int                      `json:"total"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// run fields added to every queried record
+const (
+	runField  = "_run"
+	timeField = "_time"
+)
+
+// runsMx serializes runs index updates of the process. Replicas sharing the storage
+// update the index of a results ID holding its lease.
+var runsMx sync.Mutex
+
+func runsKey(id string) string {
+	return "runs-" + id
+}
+
+func runKey(id, runID string) string {
+	return "run-" + id + "-" + runID
+}
+
+// storedRuns returns IDs of stored runs ordered by time.
+func storedRuns(s storage.Store, id string) ([]string, error) {
+	runs := []string{}
+	rec := storage.Record{Type: storage.BINARY, Key: runsKey(id)}
+	if !s.IsExists(rec) {
+		return runs, nil
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// saveRun stores all results of the task so that they can be queried after subsequent runs overwrite them.
+// Only QUERY_STORE_RUNS latest runs are kept.
+func (task *Task) saveRun(uid string) error {
+	maxRuns := viper.GetInt("QUERY_STORE_RUNS")
+	if maxRuns <= 0 {
+		return nil
+	}
+	records := []map[string]interface{}{}
+	reader := newStorageReader(&task.storage, uid, nil)
+	for {
+		block, err := reader.Read()
+		if err != nil {
+			if err.Error() == errs.EOF {
+				break
+			} else if err.Error() != errs.NextPage {
+				task.log().Error(err.Error())
+				continue
+			}
+		}
+		records = append(records, block)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	err = task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   runKey(uid, task.ID),
+		Value: data,
+	})
+	if err != nil {
+		return err
+	}
+	runsMx.Lock()
+	defer runsMx.Unlock()
+	unlock, err := storage.Lock(task.storage, runsKey(uid))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	runs, err := storedRuns(task.storage, uid)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, task.ID)
+	for len(runs) > maxRuns {
+		if err := task.storage.Delete(storage.Record{Type: storage.BINARY, Key: runKey(uid, runs[0])}); err != nil {
+			task.log().Warn(err.Error())
+		}
+		runs = runs[1:]
+	}
+	index, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+	return task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   runsKey(uid),
+		Value: index,
+	})
+}
+
+// QueryResults runs q over stored results of previous runs.
+func QueryResults(q Query) (*QueryResult, error) {
+	if q.ID == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	if q.Offset < 0 || q.Limit < 0 || q.Runs < 0 {
+		return nil, errs.BadPayload{ErrText: "runs, offset and limit should not be negative"}
+	}
+	for _, f := range q.Filters {
+		if !validOp(f.Op) {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("unknown filter operator %q", f.Op)}
+		}
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultResultsLimit
+	}
+	if q.Limit > MaxResultsLimit {
+		q.Limit = MaxResultsLimit
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	runs, err := storedRuns(s, q.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("no stored runs found for %s", q.ID),
+		}
+	}
+	if q.Runs > 0 && q.Runs < len(runs) {
+		runs = runs[len(runs)-q.Runs:]
+	}
+	matched := []map[string]interface{}{}
+	for _, runID := range runs {
+		data, err := s.Read(storage.Record{Type: storage.BINARY, Key: runKey(q.ID, runID)})
+		if err != nil {
+			logger.Warn(err.Error())
+			continue
+		}
+		records := []map[string]interface{}{}
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		runTime := ""
+		if id, err := ksuid.Parse(runID); err == nil {
+			runTime = id.Time().Format(time.RFC3339)
+		}
+		for _, r := range records {
+			r[runField] = runID
+			r[timeField] = runTime
+			if q.match(r) {
+				matched = append(matched, r)
+			}
+		}
+	}
+	if len(q.Sort) > 0 {
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, s := range q.Sort {
+				c := compareValues(matched[i][s.Field], matched[j][s.Field])
+				if c == 0 {
+					continue
+				}
+				if s.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	res := &QueryResult{
+		Total:   len(matched),
+		Records: []map[string]interface{}{},
+	}
+	for i := q.Offset; i < len(matched) && len(res.Records) < q.Limit; i++ {
+		res.Records = append(res.Records, q.project(matched[i]))
+	}
+	return res, nil
+}
+
+func validOp(op string) bool {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=", "contains":
+		return true
+	}
+	return false
+}
+
+// match checks if record r satisfies all query filters.
+func (q Query) match(r map[string]interface{}) bool {
+	for _, f := range q.Filters {
+		v, ok := r[f.Field]
+		if !ok {
+			return false
+		}
+		if f.Op == "contains" {
+			if !strings.Contains(fmt.Sprint(v), fmt.Sprint(f.Value)) {
+				return false
+			}
+			continue
+		}
+		c := compareValues(v, f.Value)
+		switch f.Op {
+		case "=":
+			ok = c == 0
+		case "!=":
+			ok = c != 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// project returns record fields listed in query Fields.
+func (q Query) project(r map[string]interface{}) map[string]interface{} {
+	if len(q.Fields) == 0 {
+		return r
+	}
+	p := map[string]interface{}{
+		runField:  r[runField],
+		timeField: r[timeField],
+	}
+	for _, f := range q.Fields {
+		if v, ok := r[f]; ok {
+			p[f] = v
+		}
+	}
+	return p
+}
+
+// compareValues compares a and b numerically if both of them are numbers. Otherwise they are compared as strings.
+// Missing values are less than any other value.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package scrape
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareValues(t *testing.T) {
+	assert.Equal(t, -1, compareValues("9", "10"))
+	assert.Equal(t, -1, compareValues(" 9.5", 10.0))
+	assert.Equal(t, 1, compareValues("b", "a"))
+	assert.Equal(t, 0, compareValues("a", "a"))
+	assert.Equal(t, -1, compareValues(nil, "a"))
+	assert.Equal(t, 1, compareValues("a", nil))
+}
+
+func TestQueryResults(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	viper.Set("QUERY_STORE_RUNS", 2)
+	defer viper.Set("QUERY_STORE_RUNS", 0)
+
+	prices := [][]string{{"12", "8"}, {"11", "7"}, {"9", "7"}}
+	for _, run := range prices {
+		task := &Task{
+			ID:      ksuid.New().String(),
+			storage: storage.NewStore(viper.GetString("STORAGE_TYPE")),
+		}
+		keys, _ := json.Marshal(map[int][]int{0: {0, 1}})
+		task.storage.Write(storage.Record{Type: storage.INTERMEDIATE, Key: "query", Value: keys})
+		for i, price := range run {
+			block, _ := json.Marshal(map[string]interface{}{"Name": []string{"a", "b"}[i], "Price": price})
+			task.storage.Write(storage.Record{Type: storage.INTERMEDIATE, Key: "query-0-" + strconv.Itoa(i), Value: block})
+		}
+		err := task.saveRun("query")
+		assert.NoError(t, err)
+		task.storage.Close()
+	}
+
+	//the first run is evicted
+	res, err := QueryResults(Query{ID: "query"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, res.Total)
+
+	res, err = QueryResults(Query{
+		ID:      "query",
+		Filters: []QueryFilter{{Field: "Price", Op: "<", Value: 10}},
+		Sort:    []QuerySort{{Field: "Price", Desc: true}},
+		Fields:  []string{"Price"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, "9", res.Records[0]["Price"])
+	assert.Nil(t, res.Records[0]["Name"])
+	assert.NotEmpty(t, res.Records[0][runField])
+
+	res, err = QueryResults(Query{ID: "query", Runs: 1, Filters: []QueryFilter{{Field: "Name", Op: "=", Value: "b"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Total)
+	assert.Equal(t, "7", res.Records[0]["Price"])
+
+	_, err = QueryResults(Query{ID: "query", Filters: []QueryFilter{{Field: "Price", Op: "~"}}})
+	assert.Error(t, err)
+	_, err = QueryResults(Query{ID: "noquery"})
+	assert.Error(t, err)
+}
+
+func TestSaveRun_concurrent(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	viper.Set("QUERY_STORE_RUNS", 10)
+	defer viper.Set("QUERY_STORE_RUNS", 0)
+
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	keys, _ := json.Marshal(map[int][]int{0: {0}})
+	s.Write(storage.Record{Type: storage.INTERMEDIATE, Key: "concurrent", Value: keys})
+	block, _ := json.Marshal(map[string]interface{}{"Name": "a"})
+	s.Write(storage.Record{Type: storage.INTERMEDIATE, Key: "concurrent-0-0", Value: block})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task := &Task{ID: ksuid.New().String(), storage: s}
+			assert.NoError(t, task.saveRun("concurrent"))
+		}()
+	}
+	wg.Wait()
+	runs, err := storedRuns(s, "concurrent")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 5, "no run is lost by concurrent index updates")
+}
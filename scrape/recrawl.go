@@ -0,0 +1,187 @@
+package scrape
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// Schedule makes a payload saved to the registry run periodically by Parse service scheduler.
+type Schedule struct {
+	//Interval between runs in seconds.
+	Interval int `json:"interval"`
+	//Adaptive adjusts interval to the observed change frequency of results.
+	//Interval is halved after a run with changed results and increased by half after a run without changes
+	//within RECRAWL_MIN_INTERVAL and RECRAWL_MAX_INTERVAL bounds.
+	Adaptive bool `json:"adaptive,omitempty"`
+	//Prewarm caches seed pages and pages linked from them within Prewarm seconds before the run,
+	//during PREWARM_HOURS of Parse service. The run takes cached pages instead of fetching them.
+	Prewarm int `json:"prewarm,omitempty"`
+}
+
+// RecrawlState keeps scheduling state of a scheduled payload.
+type RecrawlState struct {
+	//Interval is the current interval between runs in seconds. The interval of adaptive schedule is
+	//the shortest interval of seed URLs of the payload.
+	Interval int       `json:"interval"`
+	LastRun  time.Time `json:"lastRun"`
+	NextRun  time.Time `json:"nextRun"`
+	//URLs are change statistics of seed URLs of the payload recorded by the latest run.
+	URLs []URLRecrawl `json:"urls,omitempty"`
+	//Prewarmed is the time of the run pages have been prewarmed for.
+	Prewarmed time.Time `json:"prewarmed,omitempty"`
+	//Suspect lists violations of canary checks by the latest run.
+	Suspect []string `json:"suspect,omitempty"`
+	//Dispatched is the time the run has been dispatched to schedulers of replicas by the leader.
+	//It is reset when the run finishes.
+	Dispatched time.Time `json:"dispatched,omitempty"`
+}
+
+// URLRecrawl keeps change statistics of a page visited by scheduled runs. It is stored by URL,
+// so statistics are shared by payloads scheduled over the same page and survive changes of payloads.
+type URLRecrawl struct {
+	URL string `json:"url"`
+	//Interval is the adaptive interval between visits of the page in seconds.
+	Interval int `json:"interval"`
+	//Runs is the number of compared runs. Changes is the number of them with changed results.
+	Runs    int `json:"runs"`
+	Changes int `json:"changes"`
+	//LastChange is the time of the latest run with changed results.
+	LastChange time.Time `json:"lastChange,omitempty"`
+}
+
+// Due reports whether scheduled payload should run at t.
+func (s *RecrawlState) Due(t time.Time) bool {
+	return s == nil || !t.Before(s.NextRun)
+}
+
+// Pending reports whether the dispatched run hasn't finished yet.
+func (s *RecrawlState) Pending() bool {
+	return s != nil && !s.Dispatched.IsZero()
+}
+
+// PrewarmDue reports whether pages of the next run scheduled with sch should be prewarmed at t.
+func (s *RecrawlState) PrewarmDue(sch Schedule, t time.Time) bool {
+	if s == nil || sch.Prewarm <= 0 || s.NextRun.IsZero() || s.Prewarmed.Equal(s.NextRun) {
+		return false
+	}
+	return t.Before(s.NextRun) && !t.Before(s.NextRun.Add(-time.Duration(sch.Prewarm)*time.Second)) && offPeak(t)
+}
+
+// update accounts the run finished at t in the state of the payload. Urls are states of its seed URLs
+// updated by the run. Suspect lists violations of canary checks by the run.
+func (s *RecrawlState) update(sch Schedule, t time.Time, urls []URLRecrawl, suspect []string) {
+	s.Interval = sch.Interval
+	if sch.Adaptive {
+		for i, u := range urls {
+			if i == 0 || u.Interval < s.Interval {
+				s.Interval = u.Interval
+			}
+		}
+		s.Interval = clampInterval(s.Interval)
+	}
+	s.LastRun = t
+	s.Dispatched = time.Time{}
+	s.URLs = urls
+	s.Suspect = suspect
+	s.NextRun = t.Add(time.Duration(s.Interval) * time.Second)
+}
+
+// update accounts the run finished at t in the state of the page. Compared is false if the run failed
+// or its results can't be compared with the previous run. Changed reports whether results of the page have changed.
+func (u *URLRecrawl) update(sch Schedule, t time.Time, compared, changed bool) {
+	if u.Interval == 0 {
+		u.Interval = sch.Interval
+	}
+	if compared {
+		u.Runs++
+		if changed {
+			u.Changes++
+			u.LastChange = t
+		}
+		if sch.Adaptive {
+			if changed {
+				u.Interval /= 2
+			} else {
+				u.Interval += u.Interval / 2
+			}
+		}
+	}
+	if sch.Adaptive {
+		u.Interval = clampInterval(u.Interval)
+	}
+}
+
+func recrawlKey(url string) string {
+	return "recrawl-" + hex.EncodeToString(utils.GenerateMD5([]byte(url)))
+}
+
+// recordURLRuns updates states of seed URLs of payload p after its run scheduled with sch finished at t
+// and returns them. Diff is nil if the run failed or its results can't be compared with the previous run.
+// Suspect runs are not compared either.
+func recordURLRuns(s storage.Store, p Payload, sch Schedule, t time.Time, diff *RunDiff) ([]URLRecrawl, error) {
+	compared := diff != nil && len(diff.Suspect) == 0 && diff.PreviousRun != ""
+	urls := []URLRecrawl{}
+	seen := map[string]bool{}
+	for _, seed := range p.seeds() {
+		if seen[seed.URL] {
+			continue
+		}
+		seen[seed.URL] = true
+		changed := compared && diff.changedURL(seed.URL, p.batch())
+		u, err := updateURLRecrawl(s, seed.URL, func(u *URLRecrawl) {
+			u.update(sch, t, compared, changed)
+		})
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// updateURLRecrawl applies update to the stored state of url holding the lock of the state.
+func updateURLRecrawl(s storage.Store, url string, update func(*URLRecrawl)) (URLRecrawl, error) {
+	key := recrawlKey(url)
+	unlock, err := storage.Lock(s, key)
+	if err != nil {
+		return URLRecrawl{}, err
+	}
+	defer unlock()
+	u := URLRecrawl{URL: url}
+	rec := storage.Record{Type: storage.BINARY, Key: key}
+	if s.IsExists(rec) {
+		data, err := s.Read(rec)
+		if err != nil {
+			return URLRecrawl{}, err
+		}
+		if err := json.Unmarshal(data, &u); err != nil {
+			return URLRecrawl{}, err
+		}
+	}
+	update(&u)
+	data, err := json.Marshal(u)
+	if err != nil {
+		return URLRecrawl{}, err
+	}
+	rec.Value = data
+	return u, s.Write(rec)
+}
+
+// clampInterval bounds adaptive interval by RECRAWL_MIN_INTERVAL and RECRAWL_MAX_INTERVAL.
+func clampInterval(interval int) int {
+	if min := viper.GetInt("RECRAWL_MIN_INTERVAL"); interval < min {
+		interval = min
+	}
+	if max := viper.GetInt("RECRAWL_MAX_INTERVAL"); max > 0 && interval > max {
+		interval = max
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	return interval
+}
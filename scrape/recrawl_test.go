@@ -0,0 +1,194 @@
+package scrape
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLRecrawlUpdate(t *testing.T) {
+	viper.Set("RECRAWL_MIN_INTERVAL", 300)
+	viper.Set("RECRAWL_MAX_INTERVAL", 3600)
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	u := &URLRecrawl{}
+	sch := Schedule{Interval: 1000, Adaptive: true}
+	u.update(sch, now, false, false)
+	assert.Equal(t, 1000, u.Interval, "the first run is not compared")
+	assert.Equal(t, 0, u.Runs)
+
+	u.update(sch, now, true, false)
+	assert.Equal(t, 1500, u.Interval)
+	u.update(sch, now, true, false)
+	u.update(sch, now, true, false)
+	assert.Equal(t, 3375, u.Interval)
+	u.update(sch, now, true, false)
+	assert.Equal(t, 3600, u.Interval, "bounded by RECRAWL_MAX_INTERVAL")
+	u.update(sch, now, true, true)
+	assert.Equal(t, 1800, u.Interval)
+	for i := 0; i < 5; i++ {
+		u.update(sch, now, true, true)
+	}
+	assert.Equal(t, 300, u.Interval, "bounded by RECRAWL_MIN_INTERVAL")
+	assert.Equal(t, 10, u.Runs)
+	assert.Equal(t, 6, u.Changes)
+	assert.Equal(t, now, u.LastChange)
+
+	//failed run keeps interval
+	u.update(sch, now, false, false)
+	assert.Equal(t, 300, u.Interval)
+	assert.Equal(t, 10, u.Runs)
+
+	//fixed schedule
+	u = &URLRecrawl{}
+	for i := 0; i < 3; i++ {
+		u.update(Schedule{Interval: 60}, now, true, true)
+	}
+	assert.Equal(t, 60, u.Interval)
+	assert.Equal(t, 3, u.Changes)
+}
+
+func TestRecrawlStateUpdate(t *testing.T) {
+	viper.Set("RECRAWL_MIN_INTERVAL", 300)
+	viper.Set("RECRAWL_MAX_INTERVAL", 3600)
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var s *RecrawlState
+	assert.True(t, s.Due(now))
+
+	s = &RecrawlState{Dispatched: now}
+	urls := []URLRecrawl{{URL: "http://example.com/a", Interval: 2000}, {URL: "http://example.com/b", Interval: 600}}
+	s.update(Schedule{Interval: 1000, Adaptive: true}, now, urls, nil)
+	assert.Equal(t, 600, s.Interval, "the shortest interval of URLs")
+	assert.Equal(t, urls, s.URLs)
+	assert.False(t, s.Pending())
+	assert.False(t, s.Due(now.Add(599*time.Second)))
+	assert.True(t, s.Due(now.Add(600*time.Second)))
+
+	s.update(Schedule{Interval: 1000}, now, urls, []string{"bad"})
+	assert.Equal(t, 1000, s.Interval, "fixed schedule")
+	assert.Equal(t, []string{"bad"}, s.Suspect)
+}
+
+func TestChangedURL(t *testing.T) {
+	diff := &RunDiff{
+		PreviousRun: "1",
+		Added:       []map[string]interface{}{{sourceURLField: "http://example.com/a", "title": "x"}},
+	}
+	assert.True(t, diff.changedURL("http://example.com/a", true))
+	assert.False(t, diff.changedURL("http://example.com/b", true))
+	assert.True(t, diff.changedURL("http://example.com/b", false))
+	assert.False(t, (&RunDiff{PreviousRun: "1"}).changedURL("http://example.com/a", false))
+}
+
+func TestDiffRecords(t *testing.T) {
+	previous := []map[string]interface{}{
+		{"title": "a", "price": 1.0},
+		{"title": "b", "price": 2.0},
+		{"title": "b", "price": 2.0},
+	}
+	latest := []map[string]interface{}{
+		{"price": 2.0, "title": "b"},
+		{"title": "c", "price": 3.0},
+		{"title": "a", "price": 1.0},
+	}
+	added, removed := diffRecords(previous, latest)
+	assert.Equal(t, []map[string]interface{}{{"title": "c", "price": 3.0}}, added)
+	assert.Equal(t, []map[string]interface{}{{"title": "b", "price": 2.0}}, removed)
+
+	added, removed = diffRecords(previous, previous)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+	assert.False(t, (&RunDiff{PreviousRun: "1", Added: added, Removed: removed}).Changed())
+}
+
+func TestRegistryRecordRun(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	r := NewRegistry()
+	defer r.Close()
+
+	p := flatPayload
+	_, err := r.Create(p)
+	assert.NoError(t, err)
+	_, err = r.RecordRun(p.Name, time.Now(), nil)
+	assert.Error(t, err, "payload is not scheduled")
+
+	p.Schedule = &Schedule{Interval: -1}
+	_, err = r.Update(p)
+	assert.Error(t, err)
+
+	p.Schedule = &Schedule{Interval: 600}
+	_, err = r.Update(p)
+	assert.NoError(t, err)
+	now := time.Now()
+	state, err := r.RecordRun(p.Name, now, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 600, state.Interval)
+	assert.Equal(t, p.Request.URL, state.URLs[0].URL)
+
+	//change statistics are kept by URL and shared by payloads scheduled over the same page
+	other := p
+	other.Name = "persons flat copy"
+	_, err = r.Create(other)
+	assert.NoError(t, err)
+	state, err = r.RecordRun(other.Name, now, &RunDiff{PreviousRun: "1", Added: []map[string]interface{}{{"a": "b"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, state.URLs[0].Runs)
+	state, err = r.RecordRun(p.Name, now, &RunDiff{PreviousRun: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, state.URLs[0].Runs)
+	assert.Equal(t, 1, state.URLs[0].Changes)
+
+	list, err := r.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 600, list[0].Schedule.Interval)
+	assert.False(t, list[0].Recrawl.Due(now))
+
+	p.Schedule = nil
+	_, err = r.Update(p)
+	assert.NoError(t, err)
+	list, err = r.List()
+	assert.NoError(t, err)
+	assert.Nil(t, list[0].Recrawl)
+}
+
+func TestRegistryDispatch(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	r := NewRegistry()
+	defer r.Close()
+
+	p := flatPayload
+	_, err := r.Create(p)
+	assert.NoError(t, err)
+	now := time.Now()
+	ok, err := r.Dispatch(p.Name, now)
+	assert.NoError(t, err)
+	assert.False(t, ok, "payload is not scheduled")
+
+	p.Schedule = &Schedule{Interval: 600}
+	_, err = r.Update(p)
+	assert.NoError(t, err)
+	ok, err = r.Dispatch(p.Name, now)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.Dispatch(p.Name, now)
+	assert.NoError(t, err)
+	assert.False(t, ok, "run is dispatched once")
+	list, err := r.List()
+	assert.NoError(t, err)
+	assert.True(t, list[0].Recrawl.Pending())
+
+	_, err = r.RecordRun(p.Name, now, nil)
+	assert.NoError(t, err)
+	list, err = r.List()
+	assert.NoError(t, err)
+	assert.False(t, list[0].Recrawl.Pending(), "finished run is not pending")
+	ok, err = r.Dispatch(p.Name, now)
+	assert.NoError(t, err)
+	assert.False(t, ok, "run is not due")
+}
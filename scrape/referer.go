@@ -0,0 +1,51 @@
+package scrape
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// withReferer returns req with Referer header set to the URL of the page the link to req has been found on,
+// since many sites serve different content to requests without Referer or block them.
+// Like browsers do by default, only the origin of the page is sent to other sites and nothing is sent
+// from HTTPS page to HTTP one. req is returned as is if payload opts out of Referer chaining.
+func (p Payload) withReferer(req fetch.Request, page string) fetch.Request {
+	if utils.IsTrue(p.NoReferer) {
+		return req
+	}
+	referer := refererOf(page, req.URL)
+	headers := make(map[string]string, len(req.Headers)+1)
+	for k, v := range req.Headers {
+		if !strings.EqualFold(k, "Referer") {
+			headers[k] = v
+		}
+	}
+	if referer != "" {
+		headers["Referer"] = referer
+	}
+	req.Headers = headers
+	return req
+}
+
+// refererOf returns Referer of the request to target sent from page.
+func refererOf(page, target string) string {
+	from, err := url.Parse(page)
+	if err != nil || (from.Scheme != "http" && from.Scheme != "https") {
+		return ""
+	}
+	to, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	if from.Scheme == "https" && to.Scheme == "http" {
+		return ""
+	}
+	from.Fragment, from.User = "", nil
+	if from.Scheme != to.Scheme || from.Host != to.Host {
+		return from.Scheme + "://" + from.Host + "/"
+	}
+	return from.String()
+}
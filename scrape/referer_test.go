@@ -0,0 +1,30 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithReferer(t *testing.T) {
+	p := Payload{}
+	req := fetch.Request{URL: "https://example.com/item/1", Headers: map[string]string{"Accept-Language": "en", "referer": "https://example.com/"}}
+	got := p.withReferer(req, "https://example.com/list?page=2#top")
+	assert.Equal(t, map[string]string{"Accept-Language": "en", "Referer": "https://example.com/list?page=2"}, got.Headers)
+	//request headers are not modified
+	assert.Equal(t, "https://example.com/", req.Headers["referer"])
+
+	//only origin is sent to other sites
+	got = p.withReferer(fetch.Request{URL: "https://shop.example.org/item"}, "https://example.com/list")
+	assert.Equal(t, "https://example.com/", got.Headers["Referer"])
+
+	//nothing is sent from HTTPS page to HTTP one
+	got = p.withReferer(fetch.Request{URL: "http://example.com/item"}, "https://example.com/list")
+	assert.Empty(t, got.Headers)
+
+	noReferer := true
+	p.NoReferer = &noReferer
+	got = p.withReferer(fetch.Request{URL: "https://example.com/item"}, "https://example.com/list")
+	assert.Nil(t, got.Headers)
+}
@@ -0,0 +1,396 @@
+package scrape
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// PayloadInfo describes a payload saved to the registry.
+type PayloadInfo struct {
+	Name string `json:"name"`
+	//Version is the latest version number. Versions are numbered from 1.
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	//Schedule of the latest version of the payload
+	Schedule *Schedule `json:"schedule,omitempty"`
+	//Recrawl is the state of scheduled runs
+	Recrawl *RecrawlState `json:"recrawl,omitempty"`
+}
+
+// PayloadRequest refers to a payload saved to the registry.
+type PayloadRequest struct {
+	Name string `json:"name"`
+	//Version of the payload. The latest version is used if it is 0.
+	Version int `json:"version,omitempty"`
+	//Vars override variables of the payload when it is parsed.
+	Vars map[string]string `json:"vars,omitempty"`
+	//RequestID is a correlation ID of the parse request.
+	RequestID string `json:"-"`
+	//Tenant of the client which requested the parse.
+	Tenant string `json:"-"`
+}
+
+// PayloadVersion is an entry of payload version history.
+type PayloadVersion struct {
+	Version int       `json:"version"`
+	Saved   time.Time `json:"saved"`
+}
+
+// payloadIndexKey is a key of the record listing all payloads in the registry.
+const payloadIndexKey = "payloads"
+
+// registryMx serializes registry index updates of the process. Replicas sharing the storage
+// update the index holding the lease of payloadIndexKey.
+var registryMx sync.Mutex
+
+func payloadKey(name string, version int) string {
+	return "payload-" + hex.EncodeToString(utils.GenerateMD5([]byte(name))) + "-" + strconv.Itoa(version)
+}
+
+// Registry keeps named payloads along with their version history in the storage,
+// so payloads may be shared and reused by name.
+type Registry struct {
+	store storage.Store
+}
+
+// NewRegistry opens payload registry in the storage specified by STORAGE_TYPE.
+// Registry should be closed after use.
+func NewRegistry() *Registry {
+	return &Registry{store: storage.NewStore(viper.GetString("STORAGE_TYPE"))}
+}
+
+// Close closes registry storage connection.
+func (r *Registry) Close() {
+	r.store.Close()
+}
+
+type payloadIndex map[string]*registryEntry
+
+type registryEntry struct {
+	PayloadInfo
+	History []PayloadVersion `json:"history"`
+}
+
+func (r *Registry) index() (payloadIndex, error) {
+	index := payloadIndex{}
+	rec := storage.Record{Type: storage.BINARY, Key: payloadIndexKey}
+	if !r.store.IsExists(rec) {
+		return index, nil
+	}
+	data, err := r.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// lock serializes index updates and returns the function releasing the lock.
+func (r *Registry) lock() (func(), error) {
+	registryMx.Lock()
+	unlock, err := storage.Lock(r.store, payloadIndexKey)
+	if err != nil {
+		registryMx.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		registryMx.Unlock()
+	}, nil
+}
+
+func (r *Registry) writeIndex(index payloadIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return r.store.Write(storage.Record{Type: storage.BINARY, Key: payloadIndexKey, Value: data})
+}
+
+func errPayloadNotFound(name string) error {
+	return errs.StatusError{
+		Code: http.StatusNotFound,
+		Err:  fmt.Errorf("payload %q not found", name),
+	}
+}
+
+// Create saves a new payload. Payload name is required and should be unique.
+func (r *Registry) Create(p Payload) (*PayloadInfo, error) {
+	return r.save(p, true)
+}
+
+// Update saves a new version of existing payload.
+func (r *Registry) Update(p Payload) (*PayloadInfo, error) {
+	return r.save(p, false)
+}
+
+func (r *Registry) save(p Payload, create bool) (*PayloadInfo, error) {
+	if p.Name == "" {
+		return nil, errs.BadPayload{ErrText: "no payload name provided"}
+	}
+	//validate payload before saving. Payloads extending others are validated along with inherited settings.
+	//Payload must not extend itself through the chain of base payloads.
+	resolved, err := r.resolve(p, map[string]bool{p.Name: true}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolved.validateType(); err != nil {
+		return nil, err
+	}
+	if !resolved.linkCheck() {
+		if _, err := resolved.fields2parts(); err != nil {
+			return nil, err
+		}
+	}
+	if resolved.Schedule != nil && resolved.Schedule.Interval <= 0 {
+		return nil, errs.BadPayload{ErrText: "schedule interval should be positive"}
+	}
+	for _, a := range resolved.Alerts {
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if resolved.Canary != nil {
+		if resolved.Schedule == nil {
+			return nil, errs.BadPayload{ErrText: "canary requires schedule"}
+		}
+		if err := resolved.Canary.validate(); err != nil {
+			return nil, err
+		}
+	}
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	index, err := r.index()
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := index[p.Name]
+	if create && exists {
+		return nil, errs.StatusError{
+			Code: http.StatusConflict,
+			Err:  fmt.Errorf("payload %q already exists", p.Name),
+		}
+	}
+	if !create && !exists {
+		return nil, errPayloadNotFound(p.Name)
+	}
+	now := time.Now().UTC()
+	if !exists {
+		entry = &registryEntry{PayloadInfo: PayloadInfo{Name: p.Name, Created: now}}
+		index[p.Name] = entry
+	}
+	entry.Version++
+	entry.Updated = now
+	entry.Schedule = resolved.Schedule
+	if entry.Schedule == nil {
+		entry.Recrawl = nil
+	}
+	entry.History = append(entry.History, PayloadVersion{Version: entry.Version, Saved: now})
+	//PayloadMD5 is calculated for every task
+	p.PayloadMD5 = ""
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	err = r.store.Write(storage.Record{Type: storage.BINARY, Key: payloadKey(p.Name, entry.Version), Value: data})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.writeIndex(index); err != nil {
+		return nil, err
+	}
+	info := entry.PayloadInfo
+	return &info, nil
+}
+
+// Get returns specified version of the payload.
+func (r *Registry) Get(req PayloadRequest) (*Payload, error) {
+	registryMx.Lock()
+	index, err := r.index()
+	registryMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index[req.Name]
+	if !ok {
+		return nil, errPayloadNotFound(req.Name)
+	}
+	version := req.Version
+	if version == 0 {
+		version = entry.Version
+	}
+	data, err := r.store.Read(storage.Record{Type: storage.BINARY, Key: payloadKey(req.Name, version)})
+	if err != nil {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("version %d of payload %q not found", version, req.Name),
+		}
+	}
+	p := Payload{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Delete removes the payload along with all its versions.
+func (r *Registry) Delete(req PayloadRequest) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index, err := r.index()
+	if err != nil {
+		return err
+	}
+	entry, ok := index[req.Name]
+	if !ok {
+		return errPayloadNotFound(req.Name)
+	}
+	for _, v := range entry.History {
+		if err := r.store.Delete(storage.Record{Type: storage.BINARY, Key: payloadKey(req.Name, v.Version)}); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	delete(index, req.Name)
+	return r.writeIndex(index)
+}
+
+// List returns all payloads saved to the registry sorted by name.
+func (r *Registry) List() ([]PayloadInfo, error) {
+	registryMx.Lock()
+	index, err := r.index()
+	registryMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	list := []PayloadInfo{}
+	for _, entry := range index {
+		list = append(list, entry.PayloadInfo)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Versions returns version history of the payload.
+func (r *Registry) Versions(req PayloadRequest) ([]PayloadVersion, error) {
+	registryMx.Lock()
+	index, err := r.index()
+	registryMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index[req.Name]
+	if !ok {
+		return nil, errPayloadNotFound(req.Name)
+	}
+	return entry.History, nil
+}
+
+// RecordRun updates recrawl state of scheduled payload and change statistics of its seed URLs after its run finished at t.
+// Diff is nil if the run failed or its results can't be compared with the previous run.
+func (r *Registry) RecordRun(name string, t time.Time, diff *RunDiff) (*RecrawlState, error) {
+	p, err := r.Get(PayloadRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	index, err := r.index()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index[name]
+	if !ok {
+		return nil, errPayloadNotFound(name)
+	}
+	if entry.Schedule == nil {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("payload %q is not scheduled", name)}
+	}
+	urls, err := recordURLRuns(r.store, *p, *entry.Schedule, t, diff)
+	if err != nil {
+		return nil, err
+	}
+	var suspect []string
+	if diff != nil {
+		suspect = diff.Suspect
+	}
+	if entry.Recrawl == nil {
+		entry.Recrawl = &RecrawlState{}
+	}
+	entry.Recrawl.update(*entry.Schedule, t, urls, suspect)
+	if err := r.writeIndex(index); err != nil {
+		return nil, err
+	}
+	state := *entry.Recrawl
+	return &state, nil
+}
+
+// RecordPrewarm marks pages of the run of payload name scheduled at run as prewarmed.
+func (r *Registry) RecordPrewarm(name string, run time.Time) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index, err := r.index()
+	if err != nil {
+		return err
+	}
+	entry, ok := index[name]
+	if !ok {
+		return errPayloadNotFound(name)
+	}
+	if entry.Recrawl == nil {
+		entry.Recrawl = &RecrawlState{}
+	}
+	entry.Recrawl.Prewarmed = run
+	return r.writeIndex(index)
+}
+
+// Dispatch marks the run of scheduled payload name due at t as dispatched, so it is picked up
+// by a scheduler of any replica. It returns false if the payload has been dispatched already.
+func (r *Registry) Dispatch(name string, t time.Time) (bool, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	index, err := r.index()
+	if err != nil {
+		return false, err
+	}
+	entry, ok := index[name]
+	if !ok {
+		return false, errPayloadNotFound(name)
+	}
+	if entry.Schedule == nil || !entry.Recrawl.Due(t) || entry.Recrawl.Pending() {
+		return false, nil
+	}
+	if entry.Recrawl == nil {
+		entry.Recrawl = &RecrawlState{}
+	}
+	entry.Recrawl.Dispatched = t
+	return true, r.writeIndex(index)
+}
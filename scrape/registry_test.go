@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	r := NewRegistry()
+	defer r.Close()
+
+	info, err := r.Create(flatPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Version)
+	//duplicate name
+	_, err = r.Create(flatPayload)
+	assert.Error(t, err)
+	//no name
+	_, err = r.Create(Payload{Fields: flatPayload.Fields})
+	assert.Error(t, err)
+	//no fields
+	_, err = r.Create(Payload{Name: "empty"})
+	assert.Error(t, err)
+
+	updated := flatPayload
+	updated.Format = "csv"
+	info, err = r.Update(updated)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, info.Version)
+	_, err = r.Update(Payload{Name: "notexists", Fields: flatPayload.Fields})
+	assert.Error(t, err)
+
+	p, err := r.Get(PayloadRequest{Name: flatPayload.Name})
+	assert.NoError(t, err)
+	assert.Equal(t, "csv", p.Format)
+	p, err = r.Get(PayloadRequest{Name: flatPayload.Name, Version: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "json", p.Format)
+	_, err = r.Get(PayloadRequest{Name: flatPayload.Name, Version: 3})
+	assert.Error(t, err)
+
+	versions, err := r.Versions(PayloadRequest{Name: flatPayload.Name})
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	list, err := r.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, flatPayload.Name, list[0].Name)
+
+	err = r.Delete(PayloadRequest{Name: flatPayload.Name})
+	assert.NoError(t, err)
+	_, err = r.Get(PayloadRequest{Name: flatPayload.Name})
+	assert.Error(t, err)
+	list, err = r.List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
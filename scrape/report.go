@@ -0,0 +1,423 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// JobReport summarizes a completed parse job. It is stored along with results and returned by Parse.
+type JobReport struct {
+	TaskID    string     `json:"taskId"`
+	ResultsID string     `json:"resultsId"`
+	Payload   string     `json:"payload,omitempty"`
+	Status    string     `json:"status"`
+	Finished  time.Time  `json:"finished"`
+	Pages     PageCounts `json:"pages"`
+	//Records is the number of records emitted by the job
+	Records int         `json:"records"`
+	Fields  []FieldFill `json:"fields"`
+	//LowFill lists fields filled in less than REPORT_LOW_FILL_RATE share of records.
+	//They are likely to have broken selectors.
+	LowFill   []string  `json:"lowFill,omitempty"`
+	Durations Durations `json:"durations"`
+	//Diagnostics lists pages rendered by Chrome which logged console errors, threw uncaught exceptions
+	//or had failed requests. Up to maxDiagnosedPages pages are listed.
+	Diagnostics []PageDiagnostics `json:"diagnostics,omitempty"`
+	//FailedRequests counts failed requests of pages rendered by Chrome by reason and resource type,
+	//f.e. "HTTP 403 (Document)" or "blocked: csp (Script)".
+	FailedRequests map[string]int `json:"failedRequests,omitempty"`
+	//FillViolations lists fields filled in less records than required by payload fill gates.
+	FillViolations []FillViolation `json:"fillViolations,omitempty"`
+	//Suspect lists violations of canary checks of scheduled payload. Status of such run is "Suspect".
+	Suspect []string `json:"suspect,omitempty"`
+}
+
+// PageCounts contains the number of processed pages. Failed pages are counted by failure reason,
+// f.e. "HTTP 404", "no blocks" or "near duplicate".
+type PageCounts struct {
+	OK     int            `json:"ok"`
+	Failed map[string]int `json:"failed,omitempty"`
+	//Unchanged pages haven't changed since the previous run. Their records are kept, nothing is extracted.
+	Unchanged int `json:"unchanged,omitempty"`
+}
+
+// FieldFill is the number and the share of records having non-empty value of the field.
+type FieldFill struct {
+	Name   string  `json:"name"`
+	Filled int     `json:"filled"`
+	Rate   float64 `json:"rate"`
+}
+
+// Durations break job duration down in milliseconds.
+// Total, Login, Scrape, Enrich and Encode are wall times of the job and its stages.
+// Fetch, Extract and Store are summed up over concurrent workers.
+type Durations struct {
+	Total   int64 `json:"total"`
+	Login   int64 `json:"login"`
+	Scrape  int64 `json:"scrape"`
+	Enrich  int64 `json:"enrich"`
+	Encode  int64 `json:"encode"`
+	Fetch   int64 `json:"fetch"`
+	Extract int64 `json:"extract"`
+	Store   int64 `json:"store"`
+}
+
+// ReportDelivery specifies channels the job report is sent to once the job completes.
+type ReportDelivery struct {
+	//Slack is an incoming webhook URL of Slack channel.
+	Slack string `json:"slack,omitempty"`
+	//Webhook is an URL JSON encoded JobReport is posted to.
+	Webhook string `json:"webhook,omitempty"`
+	//Email lists recipients of the report. Mail is sent with SMTP server specified by SMTP_HOST.
+	Email []string `json:"email,omitempty"`
+}
+
+// ReportRequest specifies stored job report to be retrieved.
+type ReportRequest struct {
+	//ID is a results ID returned by Parse
+	ID string `json:"id"`
+}
+
+func reportKey(id string) string {
+	return "report-" + id
+}
+
+// validate checks report channels.
+func (d *ReportDelivery) validate() error {
+	if d == nil {
+		return nil
+	}
+	return validateChannels("report", d.Slack, d.Webhook, d.Email)
+}
+
+// validateChannels checks Slack and webhook URLs and email addresses of a notification
+// and that at least one channel is specified.
+func validateChannels(what, slack, webhook string, email []string) error {
+	for _, u := range []string{slack, webhook} {
+		if u == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return errs.BadPayload{ErrText: fmt.Sprintf("invalid %s URL %q", what, u)}
+		}
+	}
+	for _, addr := range email {
+		if _, err := mail.ParseAddress(addr); err != nil || strings.ContainsAny(addr, "\r\n") {
+			return errs.BadPayload{ErrText: fmt.Sprintf("invalid %s email address %q", what, addr)}
+		}
+	}
+	if slack == "" && webhook == "" && len(email) == 0 {
+		return errs.BadPayload{ErrText: what + " requires slack, webhook or email"}
+	}
+	return nil
+}
+
+// Job stages and operations timed by jobStats
+const (
+	stageLogin   = "login"
+	stageScrape  = "scrape"
+	stageEnrich  = "enrich"
+	stageEncode  = "encode"
+	stageExtract = "extract"
+	stageStore   = "store"
+)
+
+// jobStats collects page outcomes, field fill and durations of the job for its report.
+type jobStats struct {
+	mx        sync.Mutex
+	pagesOK   int
+	unchanged int
+	failed    map[string]int
+	records   int
+	filled    map[string]int
+	durations map[string]time.Duration
+	//diagnosed pages and failed requests by reason
+	diagnosed      []PageDiagnostics
+	failedRequests map[string]int
+}
+
+func newJobStats() *jobStats {
+	return &jobStats{failed: map[string]int{}, filled: map[string]int{}, durations: map[string]time.Duration{}}
+}
+
+// page counts the outcome of a processed page.
+func (s *jobStats) page(err error) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	switch err {
+	case nil:
+		s.pagesOK++
+		return
+	case errUnchanged:
+		s.unchanged++
+		return
+	}
+	s.failed[failureReason(err)]++
+}
+
+// failureReason returns a short reason of page failure so failures may be grouped.
+func failureReason(err error) string {
+	switch e := err.(type) {
+	case errs.Cancel, *errs.Cancel:
+		return "cancelled"
+	case *errs.NoBlocksToParse:
+		return "no blocks"
+	case errLimitReached:
+		return "limit reached"
+	case errBinaryContent:
+		return "binary content"
+	case errs.Error:
+		if strings.Contains(e.Error(), "noindex") {
+			return "noindex"
+		}
+		return fmt.Sprintf("HTTP %d", e.Status())
+	}
+	if err == errNearDuplicate {
+		return "near duplicate"
+	}
+	return "other"
+}
+
+// record counts a record emitted by the job along with its non-empty fields.
+func (s *jobStats) record(record map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.records++
+	for name, v := range record {
+		if !emptyValue(v) {
+			s.filled[name]++
+		}
+	}
+}
+
+// emptyValue reports whether extracted value is nil, an empty string or an empty list or map.
+func emptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// diagnostics keeps diagnostics of a page for the report.
+func (s *jobStats) diagnostics(d *PageDiagnostics) {
+	if s == nil || d == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, r := range d.failed {
+		if s.failedRequests == nil {
+			s.failedRequests = map[string]int{}
+		}
+		s.failedRequests[failedRequestReason(r)]++
+	}
+	if len(s.diagnosed) < maxDiagnosedPages {
+		s.diagnosed = append(s.diagnosed, *d)
+	}
+}
+
+// since adds time passed since begin to the duration of stage.
+func (s *jobStats) since(stage string, begin time.Time) {
+	if s == nil {
+		return
+	}
+	d := time.Since(begin)
+	s.mx.Lock()
+	s.durations[stage] += d
+	s.mx.Unlock()
+}
+
+// report builds JobReport of the task.
+func (task *Task) report(uid, status string, begin time.Time) JobReport {
+	s := task.stats
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	r := JobReport{
+		TaskID:    task.ID,
+		ResultsID: uid,
+		Payload:   task.Payload.Name,
+		Status:    status,
+		Finished:  time.Now(),
+		Pages:     PageCounts{OK: s.pagesOK, Unchanged: s.unchanged},
+		Records:   s.records,
+		Fields:    []FieldFill{},
+		Durations: Durations{
+			Total:   milliseconds(time.Since(begin)),
+			Login:   milliseconds(s.durations[stageLogin]),
+			Scrape:  milliseconds(s.durations[stageScrape]),
+			Enrich:  milliseconds(s.durations[stageEnrich]),
+			Encode:  milliseconds(s.durations[stageEncode]),
+			Extract: milliseconds(s.durations[stageExtract]),
+			Store:   milliseconds(s.durations[stageStore]),
+		},
+	}
+	if len(s.failed) > 0 {
+		r.Pages.Failed = map[string]int{}
+		for reason, n := range s.failed {
+			r.Pages.Failed[reason] = n
+		}
+	}
+	r.Diagnostics = s.diagnosed
+	if len(s.failedRequests) > 0 {
+		r.FailedRequests = map[string]int{}
+		for reason, n := range s.failedRequests {
+			r.FailedRequests[reason] = n
+		}
+	}
+	for _, f := range task.fetchStats.summaries() {
+		r.Durations.Fetch += milliseconds(f.totalLatency)
+	}
+	lowFill := viper.GetFloat64("REPORT_LOW_FILL_RATE")
+	for _, f := range task.Payload.Fields {
+		fill := FieldFill{Name: f.Name, Filled: s.fieldFilled(f)}
+		if s.records > 0 {
+			fill.Rate = float64(fill.Filled) / float64(s.records)
+		}
+		r.Fields = append(r.Fields, fill)
+		if s.records > 0 && fill.Rate < lowFill {
+			r.LowFill = append(r.LowFill, f.Name)
+		}
+	}
+	if s.records > 0 {
+		r.FillViolations = checkFill(task.Payload.FillGates, r.Fields)
+		if r.fillError() != nil {
+			r.Status = statusFillFailed
+		}
+	}
+	return r
+}
+
+// fieldFilled returns the number of records filling field f. Records contain a value for every extractor type
+// of the field, f.e. "title_text" and "title_href". The field is as filled as the most filled of them.
+func (s *jobStats) fieldFilled(f Field) int {
+	filled := s.filled[f.Name]
+	for _, t := range f.Extractor.Types {
+		if n := s.filled[f.Name+"_"+t]; n > filled {
+			filled = n
+		}
+	}
+	return filled
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// saveReport stores the report of the job. It replaces the report of the previous run of the payload.
+func saveReport(r JobReport) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	return s.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   reportKey(r.ResultsID),
+		Value: data,
+	})
+}
+
+// GetReport returns the report of the latest job specified by results ID.
+func GetReport(req ReportRequest) (*JobReport, error) {
+	if req.ID == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	rec := storage.Record{Type: storage.BINARY, Key: reportKey(req.ID)}
+	if !s.IsExists(rec) {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("report of %s not found", req.ID),
+		}
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	var r JobReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// summary returns a short description of the report used by Slack and email messages.
+func (r JobReport) summary() string {
+	failed := 0
+	for _, n := range r.Pages.Failed {
+		failed += n
+	}
+	s := fmt.Sprintf("Job %s of payload %q: %s. %d pages OK, %d failed, %d records. Took %s.",
+		r.TaskID, r.Payload, r.Status, r.Pages.OK, failed, r.Records, time.Duration(r.Durations.Total)*time.Millisecond)
+	if len(r.LowFill) > 0 {
+		s += " Low fill rate: " + strings.Join(r.LowFill, ", ") + "."
+	}
+	for _, v := range r.FillViolations {
+		s += fmt.Sprintf(" Fill rate gate (%s): %s filled in %.0f%% of records, %.0f%% required.", v.Action, v.Field, v.Rate*100, v.MinRate*100)
+	}
+	return s + " Report: " + alertBaseURL() + "/results/" + r.ResultsID + "/report"
+}
+
+// deliver sends the report to channels of d. Errors of all channels are returned together.
+func (d *ReportDelivery) deliver(r JobReport) error {
+	if d == nil {
+		return nil
+	}
+	failed := []string{}
+	if d.Slack != "" {
+		if err := postJSON(d.Slack, map[string]string{"text": r.summary()}); err != nil {
+			failed = append(failed, "slack: "+err.Error())
+		}
+	}
+	if d.Webhook != "" {
+		if err := postJSON(d.Webhook, r); err != nil {
+			failed = append(failed, "webhook: "+err.Error())
+		}
+	}
+	if len(d.Email) > 0 {
+		if err := sendMail(d.Email, "Dataflow Kit job report: "+r.Payload, r.summary()); err != nil {
+			failed = append(failed, "email: "+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to send job report. %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// completeReport builds, stores and delivers the report of the completed job.
+// Failures are logged as they don't affect results.
+func (task *Task) completeReport(uid, status string, begin time.Time) JobReport {
+	r := task.report(uid, status, begin)
+	if err := saveReport(r); err != nil {
+		task.log().Warn("Cannot store job report. " + err.Error())
+	}
+	if err := task.Payload.Report.deliver(r); err != nil {
+		task.log().Warn(err.Error(), zap.String("results", uid))
+	}
+	return r
+}
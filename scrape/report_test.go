@@ -0,0 +1,80 @@
+package scrape
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureReason(t *testing.T) {
+	assert.Equal(t, "HTTP 404", failureReason(errs.StatusError{Code: 404, Err: errors.New("Not Found")}))
+	assert.Equal(t, "noindex", failureReason(errs.StatusError{Code: 403, Err: errors.New("Page is marked noindex")}))
+	assert.Equal(t, "no blocks", failureReason(&errs.NoBlocksToParse{URL: "http://example.com"}))
+	assert.Equal(t, "HTTP 404", failureReason(errs.FetchError{URL: "http://example.com", Code: 404, Reason: "404 Not Found"}))
+	assert.Equal(t, "cancelled", failureReason(&errs.Cancel{}))
+	assert.Equal(t, "limit reached", failureReason(errLimitReached{limit: limitPages}))
+	assert.Equal(t, "near duplicate", failureReason(errNearDuplicate))
+	assert.Equal(t, "binary content", failureReason(errBinaryContent{"application/pdf"}))
+	assert.Equal(t, "other", failureReason(errors.New("connection refused")))
+}
+
+func TestJobReport(t *testing.T) {
+	viper.Set("REPORT_LOW_FILL_RATE", 0.5)
+	defer viper.Set("REPORT_LOW_FILL_RATE", 0)
+	task := &Task{
+		ID: "task",
+		Payload: Payload{Name: "books", Fields: []Field{
+			{Name: "title", Extractor: Extractor{Types: []string{"text", "href"}}},
+			{Name: "price", Extractor: Extractor{Types: []string{"text"}}},
+		}},
+		stats:      newJobStats(),
+		fetchStats: newFetchStats(),
+	}
+	task.stats.page(nil)
+	task.stats.page(nil)
+	task.stats.page(errs.StatusError{Code: 404, Err: errors.New("Not Found")})
+	//records are keyed by field name and extractor type
+	task.stats.record(map[string]interface{}{"title_text": "a", "title_href": "/a", "price_text": "1"})
+	task.stats.record(map[string]interface{}{"title_text": "b", "title_href": "", "price_text": ""})
+	task.stats.record(map[string]interface{}{"title_text": "c", "title_href": "/c", "price_text": []string{}})
+	task.stats.since(stageScrape, time.Now().Add(-time.Second))
+
+	r := task.report("results", "Completed", time.Now().Add(-2*time.Second))
+	assert.Equal(t, PageCounts{OK: 2, Failed: map[string]int{"HTTP 404": 1}}, r.Pages)
+	assert.Equal(t, 3, r.Records)
+	assert.Equal(t, []FieldFill{{Name: "title", Filled: 3, Rate: 1}, {Name: "price", Filled: 1, Rate: 1.0 / 3}}, r.Fields)
+	assert.Equal(t, []string{"price"}, r.LowFill)
+	assert.True(t, r.Durations.Scrape >= 1000)
+	assert.True(t, r.Durations.Total >= 2000)
+}
+
+func TestReportDelivery(t *testing.T) {
+	assert.NoError(t, (*ReportDelivery)(nil).validate())
+	assert.Error(t, (&ReportDelivery{}).validate())
+	assert.Error(t, (&ReportDelivery{Webhook: "ftp://example.com"}).validate())
+
+	var slack map[string]string
+	var hook JobReport
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slack" {
+			json.NewDecoder(r.Body).Decode(&slack)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&hook)
+	}))
+	defer ts.Close()
+	d := &ReportDelivery{Slack: ts.URL + "/slack", Webhook: ts.URL + "/hook"}
+	assert.NoError(t, d.validate())
+	report := JobReport{TaskID: "task", ResultsID: "results", Payload: "books", Status: "Completed", Records: 3}
+	assert.NoError(t, d.deliver(report))
+	assert.Equal(t, "results", hook.ResultsID)
+	assert.True(t, strings.Contains(slack["text"], "/results/results/report"))
+}
@@ -0,0 +1,114 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultResultsLimit is the number of results returned if no limit is specified.
+	DefaultResultsLimit = 100
+	// MaxResultsLimit is the maximum number of results returned at once.
+	MaxResultsLimit = 1000
+)
+
+// ResultsRequest specifies a page of parse results to be retrieved.
+type ResultsRequest struct {
+	//ID is a results ID returned by Parse
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+// ResultsPage is a page of parse results.
+type ResultsPage struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	//Total is the number of results of all pages
+	Total   int                      `json:"total"`
+	Results []map[string]interface{} `json:"results"`
+}
+
+// GetResults reads a page of stored parse results.
+// Results are ordered the same way as in the output file, so clients don't have to download the whole output file
+// to show the first rows.
+func GetResults(req ResultsRequest) (*ResultsPage, error) {
+	if req.ID == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, errs.BadPayload{ErrText: "offset and limit should not be negative"}
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultResultsLimit
+	}
+	if req.Limit > MaxResultsLimit {
+		req.Limit = MaxResultsLimit
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	rec := storage.Record{Type: storage.INTERMEDIATE, Key: req.ID}
+	if !s.IsExists(rec) {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("results %s not found", req.ID),
+		}
+	}
+	keysJSON, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[int][]int)
+	if err := json.Unmarshal(keysJSON, &keys); err != nil {
+		return nil, err
+	}
+	pages := []int{}
+	total := 0
+	for page, blocks := range keys {
+		pages = append(pages, page)
+		total += len(blocks)
+	}
+	sort.Ints(pages)
+
+	res := &ResultsPage{
+		ID:      req.ID,
+		Offset:  req.Offset,
+		Limit:   req.Limit,
+		Total:   total,
+		Results: []map[string]interface{}{},
+	}
+	reader := &storageResultReader{
+		storage:    &s,
+		payloadMD5: req.ID,
+		payloadMap: keys,
+	}
+	skip := req.Offset
+	for _, page := range pages {
+		if skip >= len(keys[page]) {
+			skip -= len(keys[page])
+			continue
+		}
+		reader.page = page
+		for reader.block = skip; reader.block < len(keys[page]); reader.block++ {
+			if len(res.Results) == req.Limit {
+				return res, nil
+			}
+			block, err := reader.getValue()
+			if err != nil {
+				logger.Error(err.Error())
+				continue
+			}
+			reader.readDetails(block)
+			res.Results = append(res.Results, block)
+		}
+		skip = 0
+	}
+	return res, nil
+}
@@ -4,8 +4,13 @@ package scrape
 // https://github.com/andrew-d/goscrape package governed by MIT license.
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"testing"
 
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,3 +45,48 @@ func TestResultsAllBlocks(t *testing.T) {
 		{"baz": 3, "asdf": 4},
 	})
 }
+
+func TestGetResults(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	//2 pages, 3 blocks on each page
+	keys := map[int][]int{0: {0, 1, 2}, 1: {0, 1, 2}}
+	for page, blocks := range keys {
+		for _, block := range blocks {
+			value, _ := json.Marshal(map[string]interface{}{"n": page*3 + block})
+			err := s.Write(storage.Record{
+				Type:  storage.INTERMEDIATE,
+				Key:   fmt.Sprintf("results-%d-%d", page, block),
+				Value: value,
+			})
+			assert.NoError(t, err)
+		}
+	}
+	keysJSON, _ := json.Marshal(keys)
+	err := s.Write(storage.Record{Type: storage.INTERMEDIATE, Key: "results", Value: keysJSON})
+	assert.NoError(t, err)
+	s.Close()
+
+	res, err := GetResults(ResultsRequest{ID: "results", Offset: 2, Limit: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, res.Total)
+	assert.Len(t, res.Results, 3)
+	for i, r := range res.Results {
+		assert.Equal(t, float64(i+2), r["n"])
+	}
+
+	res, err = GetResults(ResultsRequest{ID: "results", Offset: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultResultsLimit, res.Limit)
+	assert.Len(t, res.Results, 1)
+
+	res, err = GetResults(ResultsRequest{ID: "results", Offset: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Results)
+
+	_, err = GetResults(ResultsRequest{ID: "results", Offset: -1})
+	assert.Error(t, err)
+	_, err = GetResults(ResultsRequest{ID: "noresults"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,419 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// RetentionPolicy sets for how many seconds after completion of a parse job its data are kept. Zero keeps data forever.
+type RetentionPolicy struct {
+	//Results covers output and WARC files, stored records, runs, the report and snapshots of the job
+	Results int64 `json:"results,omitempty"`
+	//Snapshots covers page snapshots only. Snapshots are kept as long as results if it is zero.
+	Snapshots int64 `json:"snapshots,omitempty"`
+	//Audit covers entries of the audit log of deleted data. Entries are aged since the deletion.
+	Audit int64 `json:"audit,omitempty"`
+}
+
+// snapshotsTTL returns effective retention of snapshots.
+func (p RetentionPolicy) snapshotsTTL() int64 {
+	if p.Snapshots == 0 || (p.Results != 0 && p.Results < p.Snapshots) {
+		return p.Results
+	}
+	return p.Snapshots
+}
+
+// RetentionPolicies contain the global policy and policies of tenants overriding it.
+// Zero values of tenant policy are taken from the global one.
+type RetentionPolicies struct {
+	Global  RetentionPolicy            `json:"global"`
+	Tenants map[string]RetentionPolicy `json:"tenants,omitempty"`
+}
+
+// LoadTenantRetention reads JSON encoded retention policies of tenants, f.e. {"acme": {"results": 2592000}}.
+func LoadTenantRetention(file string) (map[string]RetentionPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tenants := map[string]RetentionPolicy{}
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("Cannot read retention policies from %s. %s", file, err.Error())
+	}
+	return tenants, nil
+}
+
+// Empty reports whether p keep all data forever.
+func (p RetentionPolicies) Empty() bool {
+	if p.Global != (RetentionPolicy{}) {
+		return false
+	}
+	for _, t := range p.Tenants {
+		if t != (RetentionPolicy{}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p RetentionPolicies) forTenant(tenant string) RetentionPolicy {
+	policy := p.Global
+	t := p.Tenants[tenant]
+	if t.Results != 0 {
+		policy.Results = t.Results
+	}
+	if t.Snapshots != 0 {
+		policy.Snapshots = t.Snapshots
+	}
+	if t.Audit != 0 {
+		policy.Audit = t.Audit
+	}
+	return policy
+}
+
+// forOwners returns the policy of results owned by tenants. Results shared by several tenants
+// are kept as long as the longest policy of their owners requires.
+func (p RetentionPolicies) forOwners(owners []string) RetentionPolicy {
+	if len(owners) == 0 {
+		return p.Global
+	}
+	policy := p.forTenant(owners[0])
+	for _, o := range owners[1:] {
+		t := p.forTenant(o)
+		policy.Results = longerRetention(policy.Results, t.Results)
+		policy.Snapshots = longerRetention(policy.snapshotsTTL(), t.snapshotsTTL())
+	}
+	return policy
+}
+
+func longerRetention(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// PurgeRequest selects data to be deleted. ID purges all data of the job. Tenant purges jobs owned
+// by the tenant only and releases jobs shared with other tenants. URL purges page snapshots of the URL
+// taken by the job with ID or by all jobs if ID is empty.
+type PurgeRequest struct {
+	ID     string `json:"id,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	URL    string `json:"url,omitempty"`
+	//Token is the admin token a client sends along with the request
+	Token string `json:"-"`
+}
+
+// PurgeResult describes deleted data.
+type PurgeResult struct {
+	//Jobs lists results IDs of purged jobs
+	Jobs []string `json:"jobs"`
+	//Released lists results IDs the tenant is no longer an owner of, as they are owned by other tenants too
+	Released []string `json:"released,omitempty"`
+	//Snapshots is the number of deleted page snapshots
+	Snapshots int `json:"snapshots"`
+	//Audit is the number of expired audit log entries deleted by the reaper
+	Audit int `json:"audit,omitempty"`
+}
+
+// resultsIDRe matches results IDs. They are hex encoded checksums of payloads, so anything else
+// is refused before job files are globbed in RESULTS_DIR.
+var resultsIDRe = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+func errInvalidResultsID(id string) error {
+	return errs.BadPayload{ErrText: fmt.Sprintf("invalid results ID %q", id)}
+}
+
+// Purge deletes data selected by req. The deletion is recorded in the audit log.
+func Purge(req PurgeRequest) (*PurgeResult, error) {
+	if req.ID != "" && !resultsIDRe.MatchString(req.ID) {
+		return nil, errInvalidResultsID(req.ID)
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	res, err := purge(s, req)
+	if err != nil {
+		return nil, err
+	}
+	err = audit(s, AuditEntry{
+		Time:        time.Now().UTC(),
+		Action:      "purge",
+		Tenant:      req.Tenant,
+		ID:          req.ID,
+		URL:         req.URL,
+		PurgeResult: *res,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func purge(s storage.Store, req PurgeRequest) (*PurgeResult, error) {
+	res := &PurgeResult{Jobs: []string{}}
+	switch {
+	case req.URL != "" && req.Tenant != "":
+		return nil, errs.BadPayload{ErrText: "url may be combined with id only"}
+	case req.URL != "":
+		ids := []string{req.ID}
+		if req.ID == "" {
+			var err error
+			if ids, err = storedIDs(s, snapshotsKey("")); err != nil {
+				return nil, err
+			}
+		}
+		for _, id := range ids {
+			n, err := purgeSnapshots(s, id, func(sn Snapshot) bool { return sn.URL == req.URL })
+			if err != nil {
+				return nil, err
+			}
+			res.Snapshots += n
+		}
+	case req.ID != "":
+		n, err := purgeJob(s, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		res.Jobs = append(res.Jobs, req.ID)
+		res.Snapshots = n
+	case req.Tenant != "":
+		if err := purgeTenant(s, req.Tenant, res); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errs.BadPayload{ErrText: "id, tenant or url required"}
+	}
+	return res, nil
+}
+
+// storedIDs returns results IDs of records stored with key prefix followed by results ID.
+func storedIDs(s storage.Store, prefix string) ([]string, error) {
+	entries, err := storage.List(s, storage.Filter{Type: storage.BINARY, Prefix: prefix})
+	if err != nil {
+		return nil, listError(err)
+	}
+	ids := []string{}
+	for _, e := range entries {
+		ids = append(ids, strings.TrimPrefix(e.Key, prefix))
+	}
+	return ids, nil
+}
+
+// purgeTenant purges jobs owned by tenant only and removes tenant from owners of shared ones.
+func purgeTenant(s storage.Store, tenant string, res *PurgeResult) error {
+	ids, err := storedIDs(s, ownersKey(""))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		owners, err := storedOwners(s, id)
+		if err != nil {
+			return err
+		}
+		others := []string{}
+		for _, o := range owners {
+			if o != tenant {
+				others = append(others, o)
+			}
+		}
+		if len(others) == len(owners) {
+			continue
+		}
+		if len(others) == 0 {
+			n, err := purgeJob(s, id)
+			if err != nil {
+				return err
+			}
+			res.Jobs = append(res.Jobs, id)
+			res.Snapshots += n
+			continue
+		}
+		data, err := json.Marshal(others)
+		if err != nil {
+			return err
+		}
+		if err := s.Write(storage.Record{Type: storage.BINARY, Key: ownersKey(id), Value: data}); err != nil {
+			return err
+		}
+		res.Released = append(res.Released, id)
+	}
+	return nil
+}
+
+// purgeJob deletes all data of the job with results ID and returns the number of deleted snapshots.
+func purgeJob(s storage.Store, id string) (int, error) {
+	if !resultsIDRe.MatchString(id) {
+		return 0, errInvalidResultsID(id)
+	}
+	snapshots, err := purgeSnapshots(s, id, func(Snapshot) bool { return true })
+	if err != nil {
+		return 0, err
+	}
+	runs, err := storedRuns(s, id)
+	if err != nil {
+		return snapshots, err
+	}
+	binary := []string{snapshotsKey(id), runsKey(id), reportKey(id), ownersKey(id)}
+	for _, run := range runs {
+		binary = append(binary, runKey(id, run))
+	}
+	for _, key := range binary {
+		if err := deleteIfExists(s, storage.Record{Type: storage.BINARY, Key: key}); err != nil {
+			return snapshots, err
+		}
+	}
+	if err := purgeRecords(s, id); err != nil {
+		return snapshots, err
+	}
+	files, err := filepath.Glob(filepath.Join(viper.GetString("RESULTS_DIR"), id+"_*"))
+	if err != nil {
+		return snapshots, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return snapshots, err
+		}
+	}
+	return snapshots, nil
+}
+
+// purgeRecords deletes stored records of the job along with their key map.
+func purgeRecords(s storage.Store, id string) error {
+	rec := storage.Record{Type: storage.INTERMEDIATE, Key: id}
+	if !s.IsExists(rec) {
+		return nil
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return err
+	}
+	keys := map[int][]int{}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for page, blocks := range keys {
+		for _, block := range blocks {
+			key := fmt.Sprintf("%s-%d-%d", id, page, block)
+			if err := deleteIfExists(s, storage.Record{Type: storage.INTERMEDIATE, Key: key}); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Delete(rec)
+}
+
+// purgeSnapshots deletes snapshots of the job selected by match and returns their number.
+func purgeSnapshots(s storage.Store, id string, match func(Snapshot) bool) (int, error) {
+	snapshots, err := storedSnapshots(s, id)
+	if err != nil {
+		return 0, err
+	}
+	kept := []Snapshot{}
+	for _, sn := range snapshots {
+		if !match(sn) {
+			kept = append(kept, sn)
+			continue
+		}
+		if err := deleteIfExists(s, storage.Record{Type: storage.BINARY, Key: snapshotKey(id, sn.Run, sn.URL)}); err != nil {
+			return 0, err
+		}
+	}
+	deleted := len(snapshots) - len(kept)
+	if deleted == 0 {
+		return 0, nil
+	}
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, s.Write(storage.Record{Type: storage.BINARY, Key: snapshotsKey(id), Value: data})
+}
+
+func deleteIfExists(s storage.Store, rec storage.Record) error {
+	if !s.IsExists(rec) {
+		return nil
+	}
+	return s.Delete(rec)
+}
+
+// Reap purges data of jobs and audit log entries which are older than retention policies allow at now.
+// The age of a job is taken from its report, so jobs completed without a report are not reaped.
+// Jobs with unreadable reports or owners are skipped, so they don't hold back the others.
+// Purged jobs are recorded in the audit log.
+func Reap(p RetentionPolicies, now time.Time) (*PurgeResult, error) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	res, err := reapJobs(s, p, now)
+	if res == nil {
+		return nil, err
+	}
+	if len(res.Jobs) > 0 || res.Snapshots > 0 {
+		auditErr := audit(s, AuditEntry{Time: now.UTC(), Action: "reap", PurgeResult: *res})
+		if err == nil {
+			err = auditErr
+		}
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Audit, err = reapAudit(s, p, now)
+	return res, err
+}
+
+func reapJobs(s storage.Store, p RetentionPolicies, now time.Time) (*PurgeResult, error) {
+	res := &PurgeResult{Jobs: []string{}}
+	ids, err := storedIDs(s, reportKey(""))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		data, err := s.Read(storage.Record{Type: storage.BINARY, Key: reportKey(id)})
+		if err != nil {
+			logger.Warn("Reaper skipped job with unreadable report. "+err.Error(), zap.String("id", id))
+			continue
+		}
+		var r JobReport
+		if err := json.Unmarshal(data, &r); err != nil {
+			logger.Warn("Reaper skipped job with corrupt report. "+err.Error(), zap.String("id", id))
+			continue
+		}
+		owners, err := storedOwners(s, id)
+		if err != nil {
+			logger.Warn("Reaper skipped job with unreadable owners. "+err.Error(), zap.String("id", id))
+			continue
+		}
+		policy := p.forOwners(owners)
+		age := int64(now.Sub(r.Finished) / time.Second)
+		if policy.Results > 0 && age > policy.Results {
+			n, err := purgeJob(s, id)
+			if err != nil {
+				return res, err
+			}
+			res.Jobs = append(res.Jobs, id)
+			res.Snapshots += n
+			continue
+		}
+		if ttl := policy.snapshotsTTL(); ttl > 0 && age > ttl {
+			n, err := purgeSnapshots(s, id, func(Snapshot) bool { return true })
+			if err != nil {
+				return res, err
+			}
+			res.Snapshots += n
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,156 @@
+package scrape
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// storeJob writes a report, owners, a snapshot and a record of the job finished at finished.
+func storeJob(t *testing.T, id string, finished time.Time, owners ...string) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	write := func(sType, key string, v interface{}) {
+		data, err := json.Marshal(v)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Write(storage.Record{Type: sType, Key: key, Value: data}))
+	}
+	write(storage.BINARY, reportKey(id), JobReport{ResultsID: id, Finished: finished})
+	if len(owners) > 0 {
+		write(storage.BINARY, ownersKey(id), owners)
+	}
+	write(storage.BINARY, snapshotsKey(id), []Snapshot{{Run: "1", URL: "http://example.com"}})
+	write(storage.BINARY, snapshotKey(id, "1", "http://example.com"), "<html></html>")
+	write(storage.INTERMEDIATE, id, map[int][]int{0: {0}})
+	write(storage.INTERMEDIATE, id+"-0-0", map[string]string{"title": "example"})
+}
+
+func jobExists(id string) (report, snapshot, records bool) {
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	return s.IsExists(storage.Record{Type: storage.BINARY, Key: reportKey(id)}),
+		s.IsExists(storage.Record{Type: storage.BINARY, Key: snapshotKey(id, "1", "http://example.com")}),
+		s.IsExists(storage.Record{Type: storage.INTERMEDIATE, Key: id + "-0-0"})
+}
+
+func TestRetentionPolicies(t *testing.T) {
+	p := RetentionPolicies{
+		Global: RetentionPolicy{Results: 100, Snapshots: 10},
+		Tenants: map[string]RetentionPolicy{
+			"acme":   {Results: 1000},
+			"globex": {Results: 50, Snapshots: 80},
+			"brief":  {Snapshots: 5},
+		},
+	}
+	assert.Equal(t, RetentionPolicy{Results: 100, Snapshots: 10}, p.forOwners(nil))
+	assert.Equal(t, RetentionPolicy{Results: 1000, Snapshots: 10}, p.forOwners([]string{"acme"}))
+	assert.Equal(t, int64(50), p.forOwners([]string{"globex"}).snapshotsTTL())
+	assert.Equal(t, RetentionPolicy{Results: 1000, Snapshots: 50}, p.forOwners([]string{"acme", "globex"}))
+	assert.Equal(t, RetentionPolicy{Results: 100, Snapshots: 5}, p.forOwners([]string{"brief"}))
+	assert.True(t, RetentionPolicies{Tenants: map[string]RetentionPolicy{"acme": {}}}.Empty())
+	assert.False(t, p.Empty())
+}
+
+func TestReap(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	now := time.Now()
+	storeJob(t, "expired", now.Add(-3*time.Hour))
+	storeJob(t, "stale", now.Add(-90*time.Minute))
+	storeJob(t, "fresh", now)
+	storeJob(t, "acme", now.Add(-3*time.Hour), "acme")
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	//a corrupt report doesn't stop reaping of other jobs
+	assert.NoError(t, s.Write(storage.Record{Type: storage.BINARY, Key: reportKey("corrupt"), Value: []byte("{")}))
+	assert.NoError(t, audit(s, AuditEntry{Time: now.Add(-2 * time.Hour), Action: "purge", ID: "old"}))
+	assert.NoError(t, audit(s, AuditEntry{Time: now.Add(-2 * time.Hour), Action: "purge", Tenant: "acme"}))
+
+	p := RetentionPolicies{
+		Global:  RetentionPolicy{Results: 7200, Snapshots: 3600, Audit: 3600},
+		Tenants: map[string]RetentionPolicy{"acme": {Results: 86400, Audit: 86400}},
+	}
+	res, err := Reap(p, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"expired"}, res.Jobs)
+	assert.Equal(t, 3, res.Snapshots)
+	assert.Equal(t, 1, res.Audit)
+	entries, err := readAudit(s)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "acme", entries[0].Tenant)
+		assert.Equal(t, "reap", entries[1].Action)
+		assert.Equal(t, []string{"expired"}, entries[1].Jobs)
+	}
+
+	report, snapshot, records := jobExists("expired")
+	assert.False(t, report || snapshot || records)
+	report, snapshot, records = jobExists("stale")
+	assert.True(t, report && records)
+	assert.False(t, snapshot)
+	report, snapshot, records = jobExists("acme")
+	assert.True(t, report && records)
+	assert.False(t, snapshot)
+	report, snapshot, records = jobExists("fresh")
+	assert.True(t, report && snapshot && records)
+}
+
+func TestPurge(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	now := time.Now()
+	storeJob(t, "own", now, "acme")
+	storeJob(t, "shared", now, "acme", "globex")
+	storeJob(t, "other", now, "globex")
+
+	_, err := Purge(PurgeRequest{})
+	assert.Error(t, err)
+	_, err = Purge(PurgeRequest{Tenant: "acme", URL: "http://example.com"})
+	assert.Error(t, err)
+	for _, id := range []string{"*", "../other", "ot?er"} {
+		_, err = Purge(PurgeRequest{ID: id})
+		assert.Error(t, err, id)
+	}
+	report, _, _ := jobExists("other")
+	assert.True(t, report, "jobs are not purged by patterns")
+
+	res, err := Purge(PurgeRequest{Tenant: "acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"own"}, res.Jobs)
+	assert.Equal(t, []string{"shared"}, res.Released)
+	report, _, _ = jobExists("own")
+	assert.False(t, report)
+	owners, err := ResultsOwners("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"globex"}, owners)
+
+	res, err = Purge(PurgeRequest{URL: "http://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Snapshots)
+	report, snapshot, _ := jobExists("other")
+	assert.True(t, report)
+	assert.False(t, snapshot)
+
+	res, err = Purge(PurgeRequest{ID: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other"}, res.Jobs)
+	report, _, records := jobExists("other")
+	assert.False(t, report || records)
+
+	//refused requests are not audited
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	entries, err := readAudit(s)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "acme", entries[0].Tenant)
+		assert.Equal(t, []string{"shared"}, entries[0].Released)
+		assert.Equal(t, "http://example.com", entries[1].URL)
+		assert.Equal(t, "other", entries[2].ID)
+	}
+}
@@ -0,0 +1,121 @@
+package scrape
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// robotsMeta holds indexing directives of a page given by meta robots tags and X-Robots-Tag headers.
+type robotsMeta struct {
+	noindex  bool
+	nofollow bool
+	//noarchive and nosnippet forbid storing a copy of the page
+	noarchive bool
+	nosnippet bool
+	//links contains absolute URLs of links marked with rel="nofollow"
+	links map[string]bool
+}
+
+// readRobotsMeta collects directives of the page fetched from pageURL. Relative links are resolved against
+// both pageURL and baseURL as extractors resolve them against the initial URL of payload.
+func readRobotsMeta(doc *goquery.Selection, header http.Header, pageURL, baseURL string) *robotsMeta {
+	m := &robotsMeta{links: make(map[string]bool)}
+	m.addPage(doc, header)
+	doc.Find(`a[rel][href]`).Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !hasToken(rel, "nofollow") {
+			return
+		}
+		href, _ := s.Attr("href")
+		for _, base := range []string{pageURL, baseURL} {
+			if u, err := utils.RelUrl(base, href); err == nil {
+				m.links[u] = true
+			}
+		}
+	})
+	return m
+}
+
+// addPage adds directives of meta robots tags of doc if it is not nil and X-Robots-Tag headers.
+func (m *robotsMeta) addPage(doc *goquery.Selection, header http.Header) {
+	if doc != nil {
+		doc.Find(`meta[name]`).Each(func(i int, s *goquery.Selection) {
+			name, _ := s.Attr("name")
+			if !strings.EqualFold(name, "robots") {
+				return
+			}
+			content, _ := s.Attr("content")
+			m.add(content)
+		})
+	}
+	for _, v := range header["X-Robots-Tag"] {
+		m.add(v)
+	}
+}
+
+// add parses comma separated directives. Directives addressed to specific user agents
+// like "googlebot: noindex" are ignored.
+func (m *robotsMeta) add(directives string) {
+	for _, d := range strings.Split(directives, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if strings.Contains(d, ":") {
+			continue
+		}
+		switch d {
+		case "noindex":
+			m.noindex = true
+		case "nofollow":
+			m.nofollow = true
+		case "none":
+			m.noindex, m.nofollow = true, true
+		case "noarchive":
+			m.noarchive = true
+		case "nosnippet":
+			m.nosnippet = true
+		}
+	}
+}
+
+// follow reports whether link to u may be followed.
+func (m *robotsMeta) follow(u string) bool {
+	if m == nil {
+		return true
+	}
+	return !m.nofollow && !m.links[u]
+}
+
+// hasToken reports whether space separated list contains token.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// respectRobotsMeta reports whether noindex and nofollow directives are respected.
+func (p Payload) respectRobotsMeta() bool {
+	return p.RobotsMeta != nil && *p.RobotsMeta
+}
+
+// noArchive reports whether the page data fetched with header forbids storing its copy
+// by noarchive or nosnippet directive. Such pages are extracted but not kept as snapshots
+// unless IGNORE_NOARCHIVE is set.
+func noArchive(data []byte, header http.Header) bool {
+	if viper.GetBool("IGNORE_NOARCHIVE") {
+		return false
+	}
+	m := &robotsMeta{}
+	var sel *goquery.Selection
+	if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data)); err == nil {
+		sel = doc.Selection
+	}
+	m.addPage(sel, header)
+	return m.noarchive || m.nosnippet
+}
@@ -0,0 +1,63 @@
+package scrape
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRobotsMeta(t *testing.T) {
+	html := `<html><head><meta name="Robots" content="noindex, follow"></head><body>
+<a href="/page2" rel="nofollow noopener">2</a>
+<a href="details/1">1</a>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.NoError(t, err)
+
+	m := readRobotsMeta(doc.Selection, nil, "http://example.com/list/", "http://example.com/")
+	assert.True(t, m.noindex)
+	assert.False(t, m.nofollow)
+	assert.False(t, m.follow("http://example.com/page2"))
+	assert.True(t, m.follow("http://example.com/list/details/1"))
+
+	header := http.Header{}
+	header.Add("X-Robots-Tag", "googlebot: noindex")
+	header.Add("X-Robots-Tag", "nofollow")
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(`<html><body></body></html>`))
+	assert.NoError(t, err)
+	m = readRobotsMeta(doc.Selection, header, "http://example.com/", "http://example.com/")
+	assert.False(t, m.noindex)
+	assert.True(t, m.nofollow)
+	assert.False(t, m.follow("http://example.com/list/details/1"))
+
+	m = &robotsMeta{}
+	m.add("none")
+	assert.True(t, m.noindex)
+	assert.True(t, m.nofollow)
+
+	m = &robotsMeta{}
+	m.add("noarchive, nosnippet")
+	assert.True(t, m.noarchive)
+	assert.True(t, m.nosnippet)
+	assert.False(t, m.noindex)
+
+	var off *robotsMeta
+	assert.True(t, off.follow("http://example.com/page2"))
+}
+
+func TestNoArchive(t *testing.T) {
+	assert.True(t, noArchive([]byte(`<html><head><meta name="robots" content="noarchive"></head></html>`), nil))
+	assert.False(t, noArchive([]byte(`<html><head><meta name="robots" content="noindex"></head></html>`), nil))
+	header := http.Header{}
+	header.Add("X-Robots-Tag", "nosnippet")
+	assert.True(t, noArchive([]byte(`{"contentType": "application/pdf"}`), header))
+	assert.False(t, noArchive([]byte(`<html></html>`), http.Header{}))
+
+	viper.Set("IGNORE_NOARCHIVE", true)
+	defer viper.Set("IGNORE_NOARCHIVE", false)
+	assert.False(t, noArchive([]byte(`<html><head><meta name="robots" content="noarchive"></head></html>`), nil))
+}
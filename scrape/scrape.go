@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -39,28 +41,33 @@ func NewTask(p Payload) *Task {
 	if err != nil {
 		panic(err)
 	}
-	p.PayloadMD5 = string(utils.GenerateCRC32(utils.GenerateMD5(data)))
+	p.PayloadMD5 = hex.EncodeToString(utils.GenerateMD5(data))
+	p.initSeeds()
 
 	delay := time.Duration(viper.GetInt("FETCH_DELAY")) * time.Millisecond
 	p.FetchDelay = &delay
 	rand := viper.GetBool("RANDOMIZE_FETCH_DELAY")
 	p.RandomizeFetchDelay = &rand
 
-	if p.Paginator != nil {
-		if p.Paginator.MaxPages == 0 {
-			p.Paginator.MaxPages = viper.GetInt("MAX_PAGES")
-		}
-		if p.Paginator.Type != "next" {
-			p.Request.Actions = fmt.Sprintf(`{"paginate":{"maxpage":%d, "element":"%s"}}`, p.Paginator.MaxPages, p.Paginator.Selector)
-			p.Request.Type = "chrome"
-		}
-	}
+	p.initPaginator()
 	if p.PaginateResults == nil {
 		pag := viper.GetBool("PAGINATE_RESULTS")
 		p.PaginateResults = &pag
 	}
+	if p.AutoPaginate == nil {
+		auto := viper.GetBool("AUTO_PAGINATE")
+		p.AutoPaginate = &auto
+	}
+	if p.RobotsMeta == nil {
+		polite := viper.GetBool("POLITE")
+		p.RobotsMeta = &polite
+	}
 	//https://blog.kowalczyk.info/article/JyRZ/generating-good-random-and-unique-ids-in-go.html
 	id := ksuid.New()
+	if p.RequestID == "" {
+		p.RequestID = id.String()
+	}
+	p.Request.RequestID = p.RequestID
 	//tQueue := make(chan *Scraper, 100)
 	storageType := viper.GetString("STORAGE_TYPE")
 	ctx, cancel := context.WithCancel(context.Background())
@@ -69,37 +76,177 @@ func NewTask(p Payload) *Task {
 		Payload: p,
 		//Errors:       []error{},
 		Robots:       make(map[string]*robotstxt.RobotsData),
+		hostLimiter:  newHostLimiter(),
 		Parsed:       false,
 		requestCount: make(map[string]uint32),
 		storage:      storage.NewStore(storageType),
-		mx:           &sync.Mutex{},
 		jobDone:      sync.WaitGroup{},
 		ctx:          ctx,
 		Cancel:       cancel,
 		statePool:    make(map[string]scrapeState),
+		extractSlots: make(chan struct{}, extractWorkerNum()),
+		pageSlots:    newPageSlots(),
+		fetchStats:   newFetchStats(),
+		stats:        newJobStats(),
+		logger:       logger.With(zap.String("requestID", p.RequestID)),
 	}
 
 }
 
+// resultsID returns the ID results of the task payload are stored with. It is the same for all runs of the payload.
+// Results are retrieved by the ID, so it is the full MD5 of the payload rather than a short checksum which may be guessed.
+func (task *Task) resultsID() string {
+	return task.Payload.PayloadMD5
+}
+
+// job returns the job fetches of the task belong to. Fetch service caps bandwidth of every job separately.
+func (task *Task) job() string {
+	if task.Payload.Tenant != "" {
+		return task.Payload.Tenant + "/" + task.ID
+	}
+	return task.ID
+}
+
+// log returns logger adding request correlation ID to log lines.
+func (task *Task) log() *zap.Logger {
+	if task.logger == nil {
+		return logger
+	}
+	return task.logger
+}
+
+// initPaginator sets default number of pages to scrape.
+// Infinite scroll and "Load more" pages are paginated by Chrome fetcher.
+func (p *Payload) initPaginator() {
+	if p.Paginator == nil {
+		return
+	}
+	if p.Paginator.MaxPages == 0 {
+		p.Paginator.MaxPages = viper.GetInt("MAX_PAGES")
+	}
+	if p.Paginator.Type != "next" && p.Paginator.Type != "number" {
+		actions, _ := json.Marshal([]map[string]interface{}{{
+			"paginate": map[string]interface{}{"maxpage": p.Paginator.MaxPages, "element": p.Paginator.Selector},
+		}})
+		p.Request.Actions = string(actions)
+		p.Request.Type = "chrome"
+	}
+}
+
+// detectPaginator downloads the initial page and sets Payload paginator if pagination is found there.
+func (task *Task) detectPaginator() {
+	req := task.Payload.Request
+	if err := task.allowedByRobots(req, false); err != nil {
+		return
+	}
+	content, err := task.fetchSession(req)
+	if err != nil {
+		task.log().Warn("Pagination detection failed. "+err.Error(), zap.String("URL", req.URL))
+		return
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return
+	}
+	d := paginate.Detect(doc.Selection)
+	if d == nil {
+		return
+	}
+	task.log().Info("Pagination detected",
+		zap.String("URL", req.URL),
+		zap.String("type", d.Type),
+		zap.String("selector", d.Selector))
+	task.Payload.Paginator = &paginator{
+		Selector:  d.Selector,
+		Attribute: d.Attribute,
+		Type:      d.Type,
+	}
+	task.Payload.initPaginator()
+}
+
 // Parse specified payload.
 func (task *Task) Parse() (io.ReadCloser, error) {
 	begin := time.Now()
-	scraper, err := task.Payload.newScraper("initial")
+	if err := task.Payload.validateType(); err != nil {
+		return nil, err
+	}
+	task.limiter = newJobLimiter(task.Payload.Limits.effective())
+	if utils.IsTrue(task.Payload.SkipNearDuplicates) {
+		task.dedup = &dedup{}
+	}
+	if err := task.Payload.Delay.validate(); err != nil {
+		return nil, err
+	}
+	if err := task.Payload.Enrich.validate(); err != nil {
+		return nil, err
+	}
+	if err := task.Payload.Report.validate(); err != nil {
+		return nil, err
+	}
+	if err := task.Payload.validateFillGates(); err != nil {
+		return nil, err
+	}
+	if token := task.Payload.Request.UserToken; token != "" {
+		if _, err := fetch.UserTokenID(token); err != nil {
+			return nil, err
+		}
+	}
+	recordScript, err := compileScript("record", task.Payload.Script)
 	if err != nil {
 		return nil, err
 	}
-	//scrape request and return results.
-
-	task.fetchChannel = make(chan *fetchInfo, viper.GetInt("FETCH_CHANNEL_SIZE"))
-	go task.fetchWorker()
-	task.blockChannel = make(chan *blockStruct, viper.GetInt("BLOCK_CHANNEL_SIZE"))
-	for i := 0; i < viper.GetInt("BLOCK_WORKER_NUM"); i++ {
-		go task.blockWorker(task.blockChannel)
+	task.recordScript = recordScript
+	task.urlFilter, err = newURLFilter(task.Payload.Include, task.Payload.Exclude)
+	if err != nil {
+		return nil, err
 	}
-
+	if err := task.loadURLList(); err != nil {
+		return nil, err
+	}
+	if !task.Payload.batch() && !task.urlFilter.allowed(task.Payload.Request.URL) {
+		return nil, errs.BadPayload{ErrText: errExcludedURL.Error()}
+	}
+	if err := task.Payload.Login.validate(task.Payload.Request); err != nil {
+		return nil, err
+	}
+	if task.Payload.piercesShadow() {
+		pierce := true
+		task.Payload.Request.PierceShadow = &pierce
+	}
+	if task.Payload.Source != "" {
+		if task.Payload.Login != nil {
+			return nil, errs.BadPayload{ErrText: "login can't be used with source archive"}
+		}
+		archive, err := openSource(task.Payload.Source)
+		if err != nil {
+			return nil, err
+		}
+		task.source = archive
+	}
+	//pages of source have been fetched with the session already
+	if task.source == nil {
+		loginBegin := time.Now()
+		if err := task.login(false); err != nil {
+			return nil, err
+		}
+		task.stats.since(stageLogin, loginBegin)
+	}
+	var scraper *Scraper
+	if task.Payload.linkCheck() {
+		scraper = task.Payload.linkChecker()
+	} else {
+		if task.Payload.Paginator == nil && task.Payload.AutoPaginate != nil && *task.Payload.AutoPaginate {
+			task.detectPaginator()
+		}
+		if scraper, err = task.Payload.newScraper("initial"); err != nil {
+			return nil, err
+		}
+	}
+	//scrape request and return results.
 	defer task.closeTask()
 
-	uid := string(utils.GenerateCRC32([]byte(task.Payload.PayloadMD5)))
+	uid := task.resultsID()
 	tw := taskWorker{
 		currentPageNum:  0,
 		scraper:         scraper,
@@ -107,41 +254,49 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 		useBlockCounter: false,
 		keys:            make(map[int][]int),
 	}
-	task.jobDone.Add(1)
-	_, err = task.scrape(&tw)
-	task.jobDone.Wait()
-	switch e := err.(type) {
-	//don't try to fetch a page with chrome fetcher if forbiddenByRobots error returned
-	case errs.Error:
-		if e.Status() == http.StatusForbidden {
-			return nil, e
+	if utils.IsTrue(task.Payload.SkipUnchanged) && !task.Payload.linkCheck() {
+		task.loadPages(uid)
+	}
+	var warcFile string
+	if utils.IsTrue(task.Payload.WARC) {
+		if task.warc, warcFile, err = createWARC(uid); err != nil {
+			return nil, fmt.Errorf("Cannot create WARC file. %s", err.Error())
 		}
-	case errs.Cancel:
-		return nil, e
+	}
+	scrapeBegin := time.Now()
+	parts, stream := task.Payload.streamParts()
+	//meta robots tags are not visible to streaming tokenizer. Streamed pages are not compared with the previous run
+	//and their records don't get fetch metadata
+	stream = stream && viper.GetBool("STREAM_EXTRACTION") && !task.Payload.respectRobotsMeta() &&
+		!utils.IsTrue(task.Payload.SkipUnchanged) && !utils.IsTrue(task.Payload.FetchMetadata)
+	switch {
+	case task.Payload.linkCheck():
+		err = task.checkLinks(&tw)
+	case stream:
+		err = task.streamScrape(&tw, parts)
+	default:
+		err = task.domScrape(&tw)
+	}
+	task.stats.since(stageScrape, scrapeBegin)
+	if err := task.warc.Close(); err != nil {
+		task.log().Warn("Cannot close WARC file. " + err.Error())
+	}
+	if failure := task.failed(); failure != nil {
+		return nil, failure
+	}
+	if err != nil {
+		return nil, err
 	}
 	if !task.Parsed {
-		logger.Info("Failed to scrape with base fetcher. Reinitializing to scrape with Chrome fetcher.")
-		if task.Payload.Request.Type == "chrome" {
-			return nil, err
-		}
-		task.Payload.Request.Type = "chrome"
-		scraper.Request.Type = "chrome"
-		delete(task.statePool, tw.UID)
-		task.jobDone.Add(1)
-		_, err = task.scrape(&tw)
-		task.jobDone.Wait()
-		if err != nil {
-			return nil, err
-		}
-
-		if !task.Parsed {
-			return nil, err
-		}
+		return nil, nil
 	}
 
 	for k := range tw.keys {
 		sort.Slice(tw.keys[k], func(i, j int) bool { return tw.keys[k][i] < tw.keys[k][j] })
 	}
+	enrichBegin := time.Now()
+	task.enrich(uid, tw.keys)
+	task.stats.since(stageEnrich, enrichBegin)
 
 	j, err := json.Marshal(tw.keys)
 	if err != nil {
@@ -156,6 +311,21 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Cannot write parse results key map. %s", err.Error())
 	}
+	if err := task.saveRun(uid); err != nil {
+		task.log().Warn("Cannot store run results for querying. " + err.Error())
+	}
+	//pages of a job stopped by a limit may miss records
+	if task.limiter.limitReached() == "" {
+		if err := task.savePages(uid); err != nil {
+			task.log().Warn("Cannot store states of pages. " + err.Error())
+		}
+	}
+	if err := task.saveSnapshots(uid); err != nil {
+		task.log().Warn("Cannot store the list of page snapshots. " + err.Error())
+	}
+	if err := task.saveOwner(uid); err != nil {
+		task.log().Warn("Cannot store the owner of results. " + err.Error())
+	}
 
 	task.storage.Close()
 
@@ -167,7 +337,7 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 	case "csv":
 		e = CSVEncoder{
 			comma:     ",",
-			partNames: scraper.partNames(),
+			partNames: task.Payload.columns(scraper.partNames()),
 		}
 	case "json":
 		e = JSONEncoder{
@@ -182,11 +352,19 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 		e = XMLEncoder{}
 	case "xlsx":
 		e = XLSXEncoder{
-			partNames: scraper.partNames(),
+			partNames: task.Payload.columns(scraper.partNames()),
 		}
 	default:
-		return nil, errors.New("invalid output format specified")
+		custom, ok := customEncoder(task.Payload.Format)
+		if !ok {
+			return nil, errors.New("invalid output format specified")
+		}
+		e = pluginEncoder{
+			Encoder:   custom,
+			partNames: task.Payload.columns(scraper.partNames()),
+		}
 	}
+	encodeBegin := time.Now()
 	r, err := EncodeToFile(task.ctx, &e, encodeInfo{
 		payloadMD5: string(uid),
 		extension:  task.Payload.Format,
@@ -196,13 +374,48 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
+	task.stats.since(stageEncode, encodeBegin)
+	status := "Completed"
+	if limit := task.limiter.limitReached(); limit != "" {
+		task.log().Info("Job stopped with partial results", zap.String("limit", limit))
+		status = "Limit reached: " + limit
+	}
 	m := map[string]interface{}{
+		"Status":      status,
 		"Task ID":     task.ID,
+		"Request ID":  task.Payload.RequestID,
+		"Results ID":  uid,
 		"Requests":    task.requestCount,
 		"Responses":   task.responseCount,
 		"Output file": string(r),
 		"Took":        time.Since(begin).String(),
 	}
+	if warcFile != "" {
+		m["WARC file"] = warcFile
+	}
+	if utils.IsTrue(task.Payload.SkipNearDuplicates) {
+		m["Near duplicates"] = task.dedup.count()
+	}
+	if task.unchanged != nil {
+		m["Unchanged pages"] = task.unchanged.count()
+	}
+	if task.seedReports != nil {
+		m["URLs"] = task.seedReports
+	}
+	if fetches := task.fetchStats.summaries(); len(fetches) > 0 {
+		m["Fetches"] = fetches
+	}
+	if sitemaps := task.Sitemaps(); len(sitemaps) > 0 {
+		m["Sitemaps"] = sitemaps
+	}
+	report := task.completeReport(uid, status, begin)
+	if err := report.fillError(); err != nil {
+		return nil, err
+	}
+	for _, v := range report.FillViolations {
+		task.log().Warn("Low fill rate", zap.String("field", v.Field), zap.Float64("rate", v.Rate), zap.Float64("minRate", v.MinRate))
+	}
+	m["Report"] = report
 	parseResults, err := json.Marshal(m)
 	if err != nil {
 		return nil, err
@@ -210,6 +423,52 @@ func (task *Task) Parse() (io.ReadCloser, error) {
 	return ioutil.NopCloser(bytes.NewReader(parseResults)), err
 }
 
+// domScrape processes payload building goquery DOM for every fetched page.
+// Base fetcher is used first. Chrome fetcher is used if nothing has been parsed with Base fetcher.
+func (task *Task) domScrape(tw *taskWorker) error {
+	task.fetchChannel = make(chan *fetchInfo, viper.GetInt("FETCH_CHANNEL_SIZE"))
+	go task.fetchWorker()
+	task.blockChannel = make(chan *blockStruct, viper.GetInt("BLOCK_CHANNEL_SIZE"))
+	for i := 0; i < viper.GetInt("BLOCK_WORKER_NUM"); i++ {
+		go task.blockWorker(task.blockChannel)
+	}
+
+	err := task.scrapeSeeds(tw)
+	switch e := err.(type) {
+	//pages the site refused to serve to base fetcher may be fetched with chrome fetcher
+	//unless they are gone
+	case errs.FetchError:
+		if e.Gone() && !task.Payload.batch() {
+			return e
+		}
+	//don't try to fetch a page with chrome fetcher if forbiddenByRobots error returned
+	case errs.Error:
+		if e.Status() == http.StatusForbidden {
+			return e
+		}
+	case errs.Cancel:
+		return e
+	}
+	if !task.Parsed && task.limiter.limitReached() == "" {
+		task.log().Info("Failed to scrape with base fetcher. Reinitializing to scrape with Chrome fetcher.")
+		if task.Payload.Request.Type == "chrome" {
+			return err
+		}
+		task.Payload.Request.Type = "chrome"
+		tw.scraper.Request.Type = "chrome"
+		//pages fetched with base fetcher are fetched again
+		if task.dedup != nil {
+			task.dedup = &dedup{}
+		}
+		delete(task.statePool, tw.UID)
+		err = task.scrapeSeeds(tw)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Create a new scraper with the provided configuration.
 func (p Payload) newScraper(reqType string) (*Scraper, error) {
 	parts, err := p.fields2parts()
@@ -221,8 +480,11 @@ func (p Payload) newScraper(reqType string) (*Scraper, error) {
 	if p.Paginator == nil {
 		paginator = &dummyPaginator{}
 
+	} else if p.Paginator.Type == "number" {
+		paginator = paginate.ByNumber(shadowSelector(p.Paginator.Selector))
+		paginatorType = "next"
 	} else {
-		paginator = paginate.BySelector(p.Paginator.Selector, p.Paginator.Attribute)
+		paginator = paginate.BySelector(shadowSelector(p.Paginator.Selector), p.Paginator.Attribute)
 		paginatorType = p.Paginator.Type
 	}
 
@@ -261,11 +523,36 @@ func (p Payload) fields2parts() ([]Part, error) {
 		if f.Extractor.Params != nil {
 			params = f.Extractor.Params
 		}
+		fieldScript, err := compileScript(f.Name, f.Extractor.Script)
+		if err != nil {
+			return nil, err
+		}
+		textMatch, err := parseTextMatch(f.Name, f.TextMatch)
+		if err != nil {
+			return nil, err
+		}
+		steps, err := parseTraverse(f.Traverse)
+		if err != nil {
+			return nil, err
+		}
+		index, err := parseMatchIndex(f.Index)
+		if err != nil {
+			return nil, err
+		}
+		missing, err := p.parseMissing(&f)
+		if err != nil {
+			return nil, err
+		}
 
 		for _, t := range f.Extractor.Types {
 			part := Part{
-				Name:     f.Name + "_" + t,
-				Selector: f.Selector,
+				Name:      f.Name + "_" + t,
+				Selector:  shadowSelector(f.Selector),
+				textMatch: textMatch,
+				traverse:  steps,
+				index:     index,
+				script:    fieldScript,
+				missing:   missing,
 			}
 			e, err := p.newExtractor(t, &f, &part, &params)
 			if err != nil {
@@ -297,7 +584,9 @@ func (p Payload) newExtractor(t string, f *Field, part *Part, params *map[string
 	var e extract.Extractor
 	switch strings.ToLower(t) {
 	case "text":
+		ownText, _ := (*params)["ownText"].(bool)
 		e = &extract.Text{
+			OwnText: ownText,
 			Filters: f.Extractor.Filters,
 		}
 	case "href", "src", "path":
@@ -329,8 +618,26 @@ func (p Payload) newExtractor(t string, f *Field, part *Part, params *map[string
 			Attr:    t,
 			Filters: f.Extractor.Filters,
 		}
-	case "width", "height":
+	case "width", "height", "srcset":
 		e = &extract.Attr{Attr: t}
+	case "image":
+		img := &extract.Image{}
+		//JSON numbers are decoded as float64 while YAML ones are ints
+		switch w := (*params)["width"].(type) {
+		case float64:
+			img.Width = int(w)
+		case int:
+			img.Width = w
+		}
+		e = img
+	case "email":
+		e = &extract.Email{}
+	case "phone":
+		country, _ := (*params)["country"].(string)
+		if _, ok := extract.CallingCode(country); country != "" && !ok {
+			return nil, errs.BadPayload{ErrText: "phone extractor: unknown country " + country}
+		}
+		e = &extract.Phone{Country: country}
 	case "regex":
 		r := &extract.Regex{}
 		regExp := (*params)["regexp"]
@@ -350,15 +657,34 @@ func (p Payload) newExtractor(t string, f *Field, part *Part, params *map[string
 	case "const":
 		e = &extract.Const{Val: (*params)["value"]}
 	case "count":
-		e = &extract.Count{}
-	// case "html":
-	// 	e = &extract.Html{}
+		includeIfEmpty, _ := (*params)["includeIfEmpty"].(bool)
+		e = &extract.Count{IncludeIfEmpty: includeIfEmpty}
+	case "exists":
+		e = &extract.Exists{}
+	case "json":
+		path, _ := (*params)["path"].(string)
+		e = &extract.JSON{Path: path}
+	case "html":
+		e = &extract.Html{}
 	case "outerhtml":
 		e = &extract.OuterHtml{}
 
 	default:
-		logger.Error(t + ": Unknown selector type")
-		return nil, nil
+		factory, ok := customExtractor(t)
+		if !ok {
+			logger.Error(t + ": Unknown selector type")
+			return nil, nil
+		}
+		custom := factory()
+		if custom == nil {
+			return nil, fmt.Errorf("%s extractor: factory returned no extractor", t)
+		}
+		if c, ok := custom.(ConfigurableExtractor); ok {
+			if err := c.Configure(*params, f.Extractor.Filters); err != nil {
+				return nil, errs.BadPayload{ErrText: fmt.Sprintf("%s extractor: %s", t, err.Error())}
+			}
+		}
+		e = custom
 	}
 	return &e, nil
 }
@@ -369,26 +695,35 @@ func (task *Task) allowedByRobots(req fetch.Request, initFetchWorkers bool) erro
 	if err != nil {
 		return err
 	}
-	if _, ok := task.Robots[host]; !ok {
-		robots, err := fetch.RobotstxtData(req.URL)
+	task.mx.Lock()
+	robots, ok := task.Robots[host]
+	task.mx.Unlock()
+	//archived pages have been captured already. robots.txt is not read if it is ignored
+	if !ok && (task.source != nil || !checkRobotsTxt()) {
+		ok = true
+	}
+	if !ok {
+		robots, err = fetch.RobotstxtData(req.URL)
 		if err != nil {
 			robotsURL, err1 := fetch.AssembleRobotstxtURL(req.URL)
 			if err1 != nil {
 				return err1
 			}
-			logger.Warn(err.Error(),
+			task.log().Warn(err.Error(),
 				zap.String("Robots.txt URL", robotsURL))
 		}
+		task.mx.Lock()
 		task.Robots[host] = robots
+		task.mx.Unlock()
 	}
 
 	//check if scraping of current url is not forbidden
-	if !fetch.AllowedByRobots(req.URL, task.Robots[host]) {
+	if !fetch.AllowedByRobots(req.URL, robots) {
 		return errs.StatusError{403, errors.New(http.StatusText(http.StatusForbidden))}
 	}
 
 	if initFetchWorkers {
-		crawlDelay := int(fetch.GetCrawlDelay(task.Robots[host]) / time.Second)
+		crawlDelay := int(fetch.GetCrawlDelay(robots) / time.Second)
 		if crawlDelay == 0 {
 			crawlDelay = 1
 		}
@@ -403,6 +738,14 @@ func (task *Task) allowedByRobots(req fetch.Request, initFetchWorkers bool) erro
 // scrape is a core function which follows the rules listed in task payload, processes all pages/ details pages. It stores parsed results to Task.Results
 func (task *Task) scrape(tw *taskWorker) (*Results, error) {
 	defer task.jobDone.Done()
+	res, err := task.scrapePage(tw)
+	//the outcome is counted before the job is marked done so the report doesn't miss it
+	task.stats.page(err)
+	return res, err
+}
+
+// scrapePage fetches and processes a single page of the job.
+func (task *Task) scrapePage(tw *taskWorker) (*Results, error) {
 	/* task.mx.Lock()
 	if err, scraped := task.statePool[tw.UID]; scraped {
 		return nil, err.state
@@ -411,101 +754,169 @@ func (task *Task) scrape(tw *taskWorker) (*Results, error) {
 	req := tw.scraper.Request
 	url := req.URL
 
-	err := task.allowedByRobots(req, tw.scraper.reqType == "initial")
+	//fetch workers are started once for all seeds of batch payload
+	err := task.allowedByRobots(req, tw.scraper.reqType == "initial" && tw.seedNum == 0)
 	if err != nil {
-		task.Cancel()
+		//the next seed of batch payload is scraped anyway
+		if tw.seed == "" {
+			task.Cancel()
+		}
 		return nil, err
 	}
 
-	//call remote fetcher to download web page
+	//pages fetched ahead and details pages are bounded by the prefetch window and block workers.
+	//Other pages wait for a page slot, so paginated pages don't run ahead of extraction
+	pageHeld := false
+	if tw.prefetched == nil && tw.scraper.reqType != "details" {
+		if err := task.acquirePage(req.URL); err != nil {
+			return nil, err
+		}
+		pageHeld = true
+	}
+	defer func() {
+		if pageHeld {
+			task.releasePage()
+		}
+	}()
+
+	//call remote fetcher to download web page unless it has been submitted ahead
 	//content, err := fetchContent(req)
-	errorChan := make(chan error)
-	resultChan := make(chan io.ReadCloser)
-	fi := fetchInfo{
-		request: req,
-		reqType: tw.scraper.reqType,
-		result:  resultChan,
-		err:     errorChan,
-	}
-	task.fetchChannel <- &fi
+	fetched := tw.prefetched
+	if fetched == nil {
+		fetched = &pendingFetch{
+			result: make(chan io.ReadCloser),
+			err:    make(chan error),
+			meta:   task.newPageMeta(),
+		}
+		task.fetchChannel <- &fetchInfo{
+			request: req,
+			reqType: tw.scraper.reqType,
+			result:  fetched.result,
+			err:     fetched.err,
+			meta:    fetched.meta,
+		}
+	}
 	var content io.ReadCloser
 	select {
-	case err := <-errorChan:
+	case err := <-fetched.err:
+		if prev := task.unchanged.notModified(req.URL, err); prev != nil {
+			return nil, task.replayPage(tw, req.URL, prev, prev.Next)
+		}
 		task.mx.Lock()
 		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 		task.mx.Unlock()
 		return nil, err
-	case content = <-resultChan:
+	case content = <-fetched.result:
 		//increment Task response count
 		task.mx.Lock()
 		count := task.responseCount
 		task.responseCount = atomic.AddUint32(&count, 1)
 		task.mx.Unlock()
 	case <-task.ctx.Done():
+		if tw.prefetched != nil {
+			go tw.prefetched.discard()
+		}
 		task.mx.Lock()
 		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 		task.mx.Unlock()
 		return nil, &errs.Cancel{}
 	}
 
-	// Create a goquery document.
-	doc, err := goquery.NewDocumentFromReader(content)
+	if err := task.urlFilter.checkRedirect(content); err != nil {
+		content.Close()
+		task.log().Info(err.Error(), zap.String("URL", req.URL))
+		task.mx.Lock()
+		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
+		task.mx.Unlock()
+		return nil, err
+	}
+
+	// Create a goquery document. Blocks and parts of the page share it.
+	diagnostics := pageDiagnostics(req.URL, content)
+	if diagnostics != nil {
+		task.log().Info("Page has script errors or failed requests", zap.String("URL", req.URL),
+			zap.Strings("console", diagnostics.Console), zap.Strings("failedRequests", diagnostics.FailedRequests))
+		task.stats.diagnostics(diagnostics)
+	}
+	body, pageHash := task.unchanged.hasher(tw.scraper, fetched.meta.count(task.limiter.countBytes(content)))
+	err = binaryContent(content)
+	var doc *goquery.Document
+	if err == nil {
+		doc, err = parseDocument(body)
+	}
 	if err != nil {
 		task.mx.Lock()
 		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 		task.mx.Unlock()
 		return nil, err
 	}
+	var robots *robotsMeta
+	if task.Payload.respectRobotsMeta() {
+		robots = readRobotsMeta(doc.Selection, fetch.ResponseHeader(content), req.URL, task.Payload.Request.URL)
+	}
 
+	next := ""
 	if tw.scraper.paginatorType == "next" {
-		url, err = tw.scraper.Paginator.NextPage(url, doc.Selection)
+		next, err = tw.scraper.Paginator.NextPage(url, doc.Selection)
 		if err != nil {
 			task.mx.Lock()
 			task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 			task.mx.Unlock()
 			return nil, err
 		}
-		// Repeat until we don't have any more URLs, or until we hit our page limit.
-		if len(url) != 0 &&
-			viper.GetInt("MAX_PAGES") > 0 && tw.currentPageNum < viper.GetInt("MAX_PAGES")-1 {
-			paginatorScraper := Scraper{
-				DividePage:    tw.scraper.DividePage,
-				IsPath:        tw.scraper.IsPath,
-				Paginator:     tw.scraper.Paginator,
-				paginatorType: tw.scraper.paginatorType,
-				Parts:         tw.scraper.Parts,
-				reqType:       tw.scraper.reqType,
-				Request:       tw.scraper.Request,
-			}
-			paginatorScraper.Request.URL = url
-			paginatorScraper.reqType = "paginator"
-			curPageNum := tw.currentPageNum + 1
-			if tw.useBlockCounter {
-				curPageNum = 0
-			}
-			paginatorTW := taskWorker{
-				currentPageNum:  curPageNum,
-				scraper:         &paginatorScraper,
-				UID:             tw.UID,
-				keys:            tw.keys,
-				useBlockCounter: tw.useBlockCounter,
-			}
-			task.jobDone.Add(1)
-			go task.scrape(&paginatorTW)
+		if len(next) != 0 && !robots.follow(next) {
+			task.log().Info("Next page is not followed as link is marked nofollow", zap.String("URL", next))
+			next = ""
+		}
+		if len(next) != 0 && !task.urlFilter.allowed(next) {
+			task.log().Info("Next page is not followed as it is excluded by URL patterns", zap.String("URL", next))
+			next = ""
 		}
 	}
+	if prev := task.unchanged.sameContent(req.URL, pageHash); prev != nil {
+		return nil, task.replayPage(tw, req.URL, prev, next)
+	}
+	task.followPage(tw, next)
+
+	if robots != nil && robots.noindex {
+		err = errs.StatusError{Code: http.StatusForbidden, Err: errors.New("Page is marked noindex")}
+		task.log().Info(err.Error(), zap.String("URL", req.URL))
+		task.mx.Lock()
+		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
+		task.mx.Unlock()
+		return nil, err
+	}
+
+	if task.dedup.duplicate(pageText(doc.Selection)) {
+		task.log().Info(errNearDuplicate.Error(), zap.String("URL", req.URL))
+		task.mx.Lock()
+		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: errNearDuplicate}
+		task.mx.Unlock()
+		return nil, errNearDuplicate
+	}
 
+	task.unchanged.visit(req.URL, pageHash, fetch.ResponseHeader(content), next)
+	pageURL := ""
+	if task.unchanged.tracked(tw.scraper) {
+		pageURL = req.URL
+	}
+	baseURL := extract.DocumentBaseURL(doc.Selection, req.URL)
 	blockSelections := tw.scraper.DividePage(doc.Selection)
 	if len(blockSelections) == 0 {
 		task.mx.Lock()
 		task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 		task.mx.Unlock()
-		return nil, &errs.NoBlocksToParse{URL: req.URL}
+		return nil, noBlocks(req.URL, tw.scraper, doc, diagnostics)
 	}
 
 	// Divide this page into blocks
 	var miscChannel chan *blockStruct
 	var miscWG sync.WaitGroup
+	//blocks of the initial page are processed by task block workers after scrape returns
+	var pageBlocks *sync.WaitGroup
+	if pageHeld && tw.scraper.reqType == "initial" {
+		pageBlocks = &sync.WaitGroup{}
+	}
 	if tw.scraper.reqType != "initial" {
 		miscChannel = make(chan *blockStruct)
 		miscWG = sync.WaitGroup{}
@@ -525,16 +936,30 @@ func (task *Task) scrape(tw *taskWorker) (*Results, error) {
 			useBlockCounter: tw.useBlockCounter,
 			keys:            &tw.keys,
 			scraper:         tw.scraper,
+			robots:          robots,
+			baseURL:         baseURL,
+			seed:            tw.seed,
+			page:            pageBlocks,
+			pageURL:         pageURL,
+			referer:         req.URL,
+			meta:            fetched.meta,
 		}
 		if tw.scraper.reqType == "initial" {
-			task.blockChannel <- &block
 			task.jobDone.Add(1)
+			if pageBlocks != nil {
+				pageBlocks.Add(1)
+			}
+			task.blockChannel <- &block
 		} else {
 			block.wg = &miscWG
 			miscWG.Add(1)
 			miscChannel <- &block
 		}
 	}
+	if pageBlocks != nil {
+		pageHeld = false
+		go task.releasePageAfter(pageBlocks)
+	}
 	if tw.scraper.reqType != "initial" {
 		miscWG.Wait()
 		close(miscChannel)
@@ -549,12 +974,66 @@ func (task *Task) scrape(tw *taskWorker) (*Results, error) {
 
 }
 
+// followPage scrapes the next page of paginated pages of tw in a new goroutine.
+// Nothing is done if next is empty or MAX_PAGES pages have been scraped already.
+func (task *Task) followPage(tw *taskWorker, next string) {
+	// Repeat until we don't have any more URLs, or until we hit our page limit.
+	if len(next) == 0 ||
+		viper.GetInt("MAX_PAGES") <= 0 || tw.currentPageNum-tw.firstPageNum >= viper.GetInt("MAX_PAGES")-1 {
+		return
+	}
+	paginatorScraper := Scraper{
+		DividePage:    tw.scraper.DividePage,
+		IsPath:        tw.scraper.IsPath,
+		Paginator:     tw.scraper.Paginator,
+		paginatorType: tw.scraper.paginatorType,
+		Parts:         tw.scraper.Parts,
+		reqType:       tw.scraper.reqType,
+		Request:       tw.scraper.Request,
+	}
+	paginatorScraper.Request.URL = next
+	paginatorScraper.Request = task.Payload.withReferer(paginatorScraper.Request, tw.scraper.Request.URL)
+	paginatorScraper.reqType = "paginator"
+	curPageNum := tw.currentPageNum + 1
+	if tw.useBlockCounter {
+		curPageNum = 0
+	}
+	paginatorTW := taskWorker{
+		currentPageNum:  curPageNum,
+		firstPageNum:    tw.firstPageNum,
+		scraper:         &paginatorScraper,
+		UID:             tw.UID,
+		keys:            tw.keys,
+		useBlockCounter: tw.useBlockCounter,
+		seed:            tw.seed,
+		seedNum:         tw.seedNum,
+	}
+	task.jobDone.Add(1)
+	go task.scrape(&paginatorTW)
+}
+
+// noBlocks returns NoBlocksToParse error listing fields of scraper which match nothing on the page doc
+// along with console errors and failed requests of the page.
+func noBlocks(url string, scraper *Scraper, doc *goquery.Document, d *PageDiagnostics) error {
+	e := &errs.NoBlocksToParse{URL: url}
+	if d != nil {
+		e.Console, e.FailedRequests = d.Console, d.FailedRequests
+	}
+	for _, part := range scraper.Parts {
+		e.Fields = append(e.Fields, fmt.Sprintf("%s (%s)", part.Name, part.Selector))
+	}
+	if html, err := doc.Html(); err == nil {
+		e.Body = errs.Snippet([]byte(html))
+	}
+	return e
+}
+
 //selectors returns selectors from payload
 func (p Payload) selectors() ([]string, error) {
 	selectors := []string{}
 	for _, f := range p.Fields {
 		if f.Selector != "" {
-			selectors = append(selectors, f.Selector)
+			selectors = append(selectors, shadowSelector(f.Selector))
 		}
 	}
 	if len(selectors) == 0 {
@@ -617,7 +1096,7 @@ func (r *Results) AllBlocks() []map[string]interface{} {
 }
 
 //KSUID stores the timestamp portion in ID. So we can retrieve it from Task object as a Time object
-func (task Task) startTime() (*time.Time, error) {
+func (task *Task) startTime() (*time.Time, error) {
 	id, err := ksuid.Parse(task.ID)
 	if err != nil {
 		return nil, err
@@ -628,6 +1107,7 @@ func (task Task) startTime() (*time.Time, error) {
 
 func (task *Task) blockWorker(blocks chan *blockStruct) {
 	//defer wrk.wg.Done()
+nextBlock:
 	for block := range blocks {
 		select {
 		default:
@@ -638,44 +1118,47 @@ func (task *Task) blockWorker(blocks chan *blockStruct) {
 				if part.Selector != "." {
 					sel = sel.Find(part.Selector)
 				}
-				//update base URL to reflect attr relative URL change
-				/* switch part.Extractor.(type) {
-				case *extract.Attr: */
+				sel = part.index.pick(traverse(matchText(sel, part.textMatch), part.traverse))
+				//extractors are shared by all blocks of the task.
+				//Attr extractor is copied before its base URL is updated to reflect attr relative URL change
+				extractor := part.Extractor
 				retryImageIfFail := false
 				attr, ok := part.Extractor.(*extract.Attr)
-				if ok && (attr.Attr == "href" || attr.Attr == "src" || attr.Attr == "style") {
-					task.mx.Lock()
-					attr.BaseURL = block.scraper.Request.URL
-					task.mx.Unlock()
+				if ok && (attr.Attr == "href" || attr.Attr == "src" || attr.Attr == "srcset" || attr.Attr == "style") {
+					a := *attr
+					a.BaseURL = block.baseURL
+					attr = &a
+					extractor = attr
 					if attr.Attr == "src" || attr.Attr == "style" {
 						retryImageIfFail = true
 					}
 				}
-				/* } */
-				task.mx.Lock()
-				extractedPartResults, err := part.Extractor.Extract(sel)
-				task.mx.Unlock()
+				if img, ok := part.Extractor.(*extract.Image); ok {
+					i := *img
+					i.BaseURL = block.baseURL
+					extractor = &i
+				}
+				extractedPartResults, err := task.extract(extractor, sel)
 				if err != nil {
-					logger.Error(err.Error())
-					return
+					task.log().Error(err.Error(), zap.String("part", part.Name))
+					task.blockDone(block)
+					continue nextBlock
 				}
 				// A nil response from an extractor means that we don't even include it in
 				// the results.
 				if extractedPartResults == nil {
-					if retryImageIfFail {
-						task.mx.Lock()
-						attr, _ := part.Extractor.(*extract.Attr)
-						attr.Attr = "style"
-						extractedPartResults, err = part.Extractor.Extract(sel)
-						if err != nil {
-							logger.Error(err.Error())
-							continue
-						}
-						task.mx.Unlock()
-						if extractedPartResults == nil {
-							continue
-						}
-					} else {
+					if !retryImageIfFail {
+						continue
+					}
+					a := *attr
+					a.Attr = "style"
+					attr = &a
+					extractedPartResults, err = task.extract(attr, sel)
+					if err != nil {
+						task.log().Error(err.Error())
+						continue
+					}
+					if extractedPartResults == nil {
 						continue
 					}
 				}
@@ -687,6 +1170,16 @@ func (task *Task) blockWorker(blocks chan *blockStruct) {
 						extractedPartResults = imgSrc[nStart:nEnd]
 					}
 				}
+				if part.script != nil {
+					extractedPartResults, err = part.script.run(task.ctx, "value", extractedPartResults)
+					if err != nil {
+						task.log().Warn(err.Error(), zap.String("part", part.Name))
+						continue
+					}
+					if extractedPartResults == nil {
+						continue
+					}
+				}
 				if !block.scraper.IsPath {
 					blockResults[part.Name] = extractedPartResults
 				}
@@ -698,25 +1191,62 @@ func (task *Task) blockWorker(blocks chan *blockStruct) {
 				}
 				//********* end details
 			}
+			if !block.scraper.IsPath {
+				keep, err := applyMissing(blockResults, block.scraper.Parts, block.pageURL)
+				if err != nil {
+					task.fail(err)
+					task.blockDone(block)
+					continue
+				}
+				if !keep {
+					task.blockDone(block)
+					continue
+				}
+			}
+			//details blocks are parts of parent records
+			if len(blockResults) > 0 && task.recordScript != nil && block.scraper.reqType != "details" && !block.scraper.IsPath {
+				record, err := task.recordScript.runRecord(task.ctx, blockResults)
+				if err != nil {
+					task.log().Warn(err.Error(), zap.String("block", block.key))
+				} else {
+					blockResults = record
+				}
+			}
 			if len(blockResults) > 0 {
+				task.Payload.addLanguage(blockResults)
+				if block.scraper.reqType != "details" {
+					task.Payload.addSeedFields(blockResults, block.seed, task.seedVars[block.seed])
+					task.Payload.addFetchMeta(blockResults, block.meta)
+				}
 				task.saveToStorage(&blockResults, block)
 			}
-			if block.wg != nil {
-				block.wg.Done()
-			} else {
-				task.jobDone.Done()
-			}
+			task.blockDone(block)
 		case <-task.ctx.Done():
-			if block.wg != nil {
-				block.wg.Done()
-			} else {
-				task.jobDone.Done()
-			}
+			task.blockDone(block)
 			//return
 		}
 	}
 }
 
+// extract runs extractor on sel. Extraction is CPU bound so the number of concurrent extractions is limited
+// by the task extraction slots.
+func (task *Task) extract(extractor extract.Extractor, sel *goquery.Selection) (interface{}, error) {
+	task.extractSlots <- struct{}{}
+	defer func() { <-task.extractSlots }()
+	defer task.stats.since(stageExtract, time.Now())
+	return extractor.Extract(sel)
+}
+
+// extractWorkerNum returns the number of concurrent extractions specified by EXTRACT_WORKER_NUM.
+// It is bounded by GOMAXPROCS.
+func extractWorkerNum() int {
+	n := viper.GetInt("EXTRACT_WORKER_NUM")
+	if max := runtime.GOMAXPROCS(0); n <= 0 || n > max {
+		n = max
+	}
+	return n
+}
+
 func (task *Task) scrapeDetails(extractedPartResults interface{}, part *Part, block *blockStruct, blockResults *map[string]interface{}) bool {
 	var requests []fetch.Request
 
@@ -730,7 +1260,24 @@ func (task *Task) scrapeDetails(extractedPartResults interface{}, part *Part, bl
 			requests = append(requests, rq)
 		}
 	}
+	followed := []fetch.Request{}
 	for _, r := range requests {
+		if !block.robots.follow(r.URL) {
+			task.log().Info("Details page is not followed as link is marked nofollow", zap.String("URL", r.URL))
+			continue
+		}
+		if !task.urlFilter.allowed(r.URL) {
+			task.log().Debug("Details page is not followed as it is excluded by URL patterns", zap.String("URL", r.URL))
+			continue
+		}
+		r.Type = task.Payload.Request.Type
+		r.PierceShadow = task.Payload.Request.PierceShadow
+		followed = append(followed, task.Payload.withReferer(r, block.referer))
+	}
+	//details pages are fetched ahead while the previous ones are extracted
+	pf := task.newPrefetcher("details", followed)
+	defer pf.close()
+	for _, r := range followed {
 		part.Details.Request = r
 		//check if domain is the same for initial URL and details' URLs
 		//If original host is the same as details' host sleep for some time before  fetching of details page  to avoid ban and other sanctions
@@ -752,8 +1299,8 @@ func (task *Task) scrapeDetails(extractedPartResults interface{}, part *Part, bl
 			UID:             uid,
 			useBlockCounter: ubc,
 			keys:            blockKeys,
+			prefetched:      pf.take(),
 		}
-		tw.scraper.Request.Type = task.Payload.Request.Type
 		task.jobDone.Add(1)
 		_, err := task.scrape(&tw)
 		if err != nil {
@@ -766,7 +1313,7 @@ func (task *Task) scrapeDetails(extractedPartResults interface{}, part *Part, bl
 		// Sort keys to keep an order before write them into storage.
 		err = task.updateKeys(uid, tw.keys)
 		if err != nil {
-			// logger.Warning(fmt.Errorf("Failed to write %s. %s", string(uid), err.Error()))
+			// task.log().Warning(fmt.Errorf("Failed to write %s. %s", string(uid), err.Error()))
 			task.statePool[tw.UID] = scrapeState{url: tw.scraper.Request.URL, state: err}
 			return false
 		}
@@ -783,8 +1330,13 @@ func (task *Task) saveToStorage(blockResults *map[string]interface{}, block *blo
 
 	output, err := json.Marshal(blockResults)
 	if err != nil {
-		logger.Error(err.Error())
+		task.log().Error(err.Error())
+	}
+	if !block.scraper.IsPath && block.scraper.reqType != "details" {
+		task.stats.record(*blockResults)
+		task.unchanged.keep(block, *blockResults)
 	}
+	defer task.stats.since(stageStore, time.Now())
 	if !block.scraper.IsPath {
 		task.mx.Lock()
 		key := block.key
@@ -796,7 +1348,7 @@ func (task *Task) saveToStorage(blockResults *map[string]interface{}, block *blo
 		keys := strings.Split(block.key, "-")
 		pageNum, err := strconv.Atoi(keys[1])
 		if err != nil {
-			logger.Error(fmt.Errorf("Failed to convert string to int %s. %s", string(key[1]), err.Error()).Error())
+			task.log().Error(fmt.Errorf("Failed to convert string to int %s. %s", string(key[1]), err.Error()).Error())
 		}
 		var blockNum int
 		if block.useBlockCounter {
@@ -805,7 +1357,7 @@ func (task *Task) saveToStorage(blockResults *map[string]interface{}, block *blo
 		} else {
 			blockNum, err = strconv.Atoi(keys[2])
 			if err != nil {
-				logger.Error(fmt.Errorf("Failed to convert string to int %s. %s", string(key[1]), err.Error()).Error())
+				task.log().Error(fmt.Errorf("Failed to convert string to int %s. %s", string(key[1]), err.Error()).Error())
 			}
 		}
 
@@ -818,7 +1370,7 @@ func (task *Task) saveToStorage(blockResults *map[string]interface{}, block *blo
 			ExpTime: 0,
 		})
 		if err != nil {
-			logger.Error(fmt.Errorf("Failed to write %s. %s", key, err.Error()).Error())
+			task.log().Error(fmt.Errorf("Failed to write %s. %s", key, err.Error()).Error())
 		}
 		task.mx.Unlock()
 	}
@@ -826,7 +1378,17 @@ func (task *Task) saveToStorage(blockResults *map[string]interface{}, block *blo
 
 func (task *Task) fetchWorker() {
 	for fetch := range task.fetchChannel {
-		if !viper.GetBool("IGNORE_FETCH_DELAY") {
+		if err := task.goneErr(fetch.request.URL); err != nil {
+			fetch.err <- err
+			continue
+		}
+		if err := task.limiter.take(); err != nil {
+			fetch.err <- err
+			continue
+		}
+		//pages cached ahead of scheduled run are not fetched
+		cached := task.prewarmed(fetch.request)
+		if cached == nil && task.serviceDelay() && task.source == nil {
 			if *task.Payload.RandomizeFetchDelay {
 				//Sleep for time equal to FetchDelay * random value between 500 and 1500 msec
 				rand := utils.Random(500, 1500)
@@ -836,14 +1398,43 @@ func (task *Task) fetchWorker() {
 				time.Sleep(*task.Payload.FetchDelay)
 			}
 		}
+		//honor robots.txt Crawl-delay along with payload delay
+		if cached == nil {
+			host, interval := task.crawlDelay(fetch.request)
+			if !viper.GetBool("IGNORE_FETCH_DELAY") {
+				interval += task.Payload.Delay.next()
+			}
+			if wait := task.hostLimiter.reserve(host, interval); wait > 0 && task.source == nil {
+				time.Sleep(wait)
+			}
+		}
 		//increment Task request count
 		task.mx.Lock()
 		count := task.requestCount[fetch.reqType]
 		task.requestCount[fetch.reqType] = atomic.AddUint32(&count, 1)
 		task.mx.Unlock()
 
-		content, err := fetchContent(fetch.request)
+		fetch.request = task.unchanged.conditional(fetch.request, fetch.reqType)
+		//details requests are created from extracted links
+		fetch.request.RequestID = task.Payload.RequestID
+		fetcherType := fetch.request.Type
+		if fetcherType == "" {
+			fetcherType = "base"
+		}
+		begin := time.Now()
+		content, err := cached, error(nil)
+		if cached == nil {
+			content, err = task.fetchSession(fetch.request)
+		}
+		took := time.Since(begin)
+		//archived and prewarmed pages are not counted by service wide histograms
+		content = task.fetchStats.observe(fetcherType, took, content, err, task.source == nil && cached == nil)
+		if err == nil {
+			fetch.meta.observe(fetcherType, begin, took, content, task.source != nil || cached != nil)
+			content, err = task.keepPage(fetch.request, content)
+		}
 		if err != nil {
+			task.markGone(fetch.request.URL, err)
 			fetch.err <- err
 		} else {
 			fetch.result <- content
@@ -851,6 +1442,42 @@ func (task *Task) fetchWorker() {
 	}
 }
 
+// checkRobotsTxt reports whether robots.txt of web sites is complied with unless IGNORE_ROBOTS_TXT is set.
+func checkRobotsTxt() bool {
+	return !viper.GetBool("IGNORE_ROBOTS_TXT")
+}
+
+// crawlDelay returns request host along with Crawl-delay specified in its robots.txt.
+// Crawl-delay is honored only if robots.txt is complied with.
+func (task *Task) crawlDelay(req fetch.Request) (string, time.Duration) {
+	host, err := req.Host()
+	if err != nil {
+		return "", 0
+	}
+	if !checkRobotsTxt() {
+		return host, 0
+	}
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	return host, fetch.GetCrawlDelay(task.Robots[host])
+}
+
+// Sitemaps returns sitemap URLs found in robots.txt of the hosts visited by Task.
+// They are returned in "Sitemaps" of Parse response as a starting point for sitemap expansion.
+func (task *Task) Sitemaps() []string {
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	sitemaps := []string{}
+	for _, robots := range task.Robots {
+		for _, s := range fetch.GetSitemaps(robots) {
+			if !utils.ArrayContains(sitemaps, s) {
+				sitemaps = append(sitemaps, s)
+			}
+		}
+	}
+	return sitemaps
+}
+
 func (task *Task) updateKeys(uid string, keys map[int][]int) error {
 	task.mx.Lock()
 	for k := range keys {
@@ -874,8 +1501,12 @@ func (task *Task) updateKeys(uid string, keys map[int][]int) error {
 }
 
 func (task *Task) closeTask() {
-	close(task.fetchChannel)
-	close(task.blockChannel)
+	if task.fetchChannel != nil {
+		close(task.fetchChannel)
+	}
+	if task.blockChannel != nil {
+		close(task.blockChannel)
+	}
 	task.storage.Close()
 }
 
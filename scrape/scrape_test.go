@@ -9,9 +9,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/slotix/dataflowkit/fetch"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -816,3 +821,89 @@ func TestParseDetailsWithRegex(t *testing.T) {
 	os.RemoveAll("./diskv")
 	os.RemoveAll("./results")
 }
+
+func TestExtractWorkerNum(t *testing.T) {
+	defer viper.Set("EXTRACT_WORKER_NUM", 0)
+	viper.Set("EXTRACT_WORKER_NUM", 0)
+	assert.Equal(t, runtime.GOMAXPROCS(0), extractWorkerNum())
+	viper.Set("EXTRACT_WORKER_NUM", runtime.GOMAXPROCS(0)+10)
+	assert.Equal(t, runtime.GOMAXPROCS(0), extractWorkerNum())
+	viper.Set("EXTRACT_WORKER_NUM", 1)
+	assert.Equal(t, 1, extractWorkerNum())
+}
+
+func TestNoBlocks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><p>Access denied</p></body></html>`))
+	assert.NoError(t, err)
+	scraper := &Scraper{Parts: []Part{{Name: "title", Selector: ".title"}, {Name: "price", Selector: ".price"}}}
+	d := &PageDiagnostics{Console: []string{"exception: Uncaught TypeError: render is not a function (http://example.com/app.js:12)"}}
+	err = noBlocks("http://example.com", scraper, doc, d)
+	e, ok := err.(*errs.NoBlocksToParse)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"title (.title)", "price (.price)"}, e.Fields)
+	assert.Contains(t, e.Body, "Access denied")
+	assert.Contains(t, err.Error(), "title (.title), price (.price)")
+	assert.Contains(t, err.Error(), "Console errors: exception: Uncaught TypeError")
+}
+
+func TestDetectPaginator_more(t *testing.T) {
+	har := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "http://example.com/catalog"},
+		 "response": {"status": 200, "content": {"text": "<html><body><div class=\"items\"></div><button class=\"btn\">Load more \"items\"</button></body></html>"}}}
+	]}}`
+	s, err := readHAR(strings.NewReader(har))
+	assert.NoError(t, err)
+	task := NewTask(Payload{Request: fetch.Request{URL: "http://example.com/catalog"}})
+	task.source = s
+	task.detectPaginator()
+	if assert.NotNil(t, task.Payload.Paginator) {
+		assert.Equal(t, "more", task.Payload.Paginator.Type)
+	}
+	assert.Equal(t, "chrome", task.Payload.Request.Type)
+
+	//actions are parsed by Chrome fetcher as a list of actions
+	acts := []map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal([]byte(task.Payload.Request.Actions), &acts))
+	if assert.Len(t, acts, 1) {
+		action, err := fetch.NewAction("paginate", acts[0]["paginate"])
+		assert.NoError(t, err)
+		assert.Equal(t, `button:contains("Load more \"items\"")`, action.(*fetch.PaginateAction).Element)
+		assert.Equal(t, viper.GetInt("MAX_PAGES"), action.(*fetch.PaginateAction).MaxPage)
+	}
+}
+
+type failingExtractor struct{}
+
+func (failingExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	return nil, errors.New("invalid JSON")
+}
+
+func TestBlockWorker_extractError(t *testing.T) {
+	task := NewTask(Payload{})
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<div>{not JSON</div><div>{}</div>"))
+	assert.NoError(t, err)
+	scraper := &Scraper{Parts: []Part{{Name: "data_json", Selector: ".", Extractor: failingExtractor{}}}}
+	blocks := make(chan *blockStruct, 2)
+	wg := &sync.WaitGroup{}
+	page := &sync.WaitGroup{}
+	doc.Find("div").Each(func(i int, s *goquery.Selection) {
+		wg.Add(1)
+		page.Add(1)
+		blocks <- &blockStruct{blockSelection: s, scraper: scraper, wg: wg, page: page}
+	})
+	close(blocks)
+
+	//the worker keeps processing blocks after an extractor error and marks every block done
+	stopped := make(chan struct{})
+	go func() {
+		task.blockWorker(blocks)
+		wg.Wait()
+		page.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("blocks are not done after extractor error")
+	}
+}
@@ -0,0 +1,191 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/sandbox"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// script is a compiled Lua chunk used for transformations too complex for declarative filters.
+// Every invocation runs in a new sandboxed interpreter with base, table, string and math libraries only.
+// Execution time is limited by SCRIPT_TIMEOUT, the size of interpreter stack is limited by SCRIPT_MAX_STACK
+// and memory held by Lua values is limited by SCRIPT_MAX_MEMORY. See sandbox package.
+type script struct {
+	name  string
+	proto *lua.FunctionProto
+}
+
+// compileScript compiles Lua source. Compilation errors are reported as BadPayload.
+func compileScript(name, src string) (*script, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	chunk, err := parse.Parse(strings.NewReader(src), name)
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("%s script: %s", name, err.Error())}
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("%s script: %s", name, err.Error())}
+	}
+	return &script{name: name, proto: proto}, nil
+}
+
+// run executes script with global variable name set to v. Value returned by the script is returned.
+func (s *script) run(ctx context.Context, name string, v interface{}) (interface{}, error) {
+	L := sandbox.New()
+	defer L.Close()
+	timeout := time.Duration(viper.GetInt("SCRIPT_TIMEOUT")) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	L.SetContext(sandbox.Guard(ctx, L, longestString(v)))
+	L.SetGlobal(name, toLua(L, v))
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, 1, nil); err != nil {
+		return nil, fmt.Errorf("%s script: %s", s.name, err.Error())
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	return fromLua(ret), nil
+}
+
+// runRecord executes record script. Returned table replaces the record. Nil or false drops it.
+func (s *script) runRecord(ctx context.Context, record map[string]interface{}) (map[string]interface{}, error) {
+	v, err := s.run(ctx, "record", record)
+	if err != nil {
+		return nil, err
+	}
+	switch r := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if !r {
+			return nil, nil
+		}
+	case map[string]interface{}:
+		return r, nil
+	}
+	return nil, fmt.Errorf("%s script must return a table, nil or false", s.name)
+}
+
+// longestString returns the length of the longest string in v.
+func longestString(v interface{}) int {
+	longest := 0
+	switch value := v.(type) {
+	case string:
+		longest = len(value)
+	case []string:
+		for _, s := range value {
+			if len(s) > longest {
+				longest = len(s)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if n := longestString(item); n > longest {
+				longest = n
+			}
+		}
+	case []map[string]interface{}:
+		for _, item := range value {
+			if n := longestString(item); n > longest {
+				longest = n
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range value {
+			if n := longestString(item); n > longest {
+				longest = n
+			}
+		}
+	}
+	return longest
+}
+
+func toLua(L *lua.LState, v interface{}) lua.LValue {
+	switch value := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(value)
+	case string:
+		return lua.LString(value)
+	case int:
+		return lua.LNumber(value)
+	case int64:
+		return lua.LNumber(value)
+	case float64:
+		return lua.LNumber(value)
+	case []string:
+		t := L.NewTable()
+		for _, s := range value {
+			t.Append(lua.LString(s))
+		}
+		return t
+	case []interface{}:
+		t := L.NewTable()
+		for _, item := range value {
+			t.Append(toLua(L, item))
+		}
+		return t
+	case []map[string]interface{}:
+		t := L.NewTable()
+		for _, item := range value {
+			t.Append(toLua(L, item))
+		}
+		return t
+	case map[string]interface{}:
+		t := L.NewTable()
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			t.RawSetString(k, toLua(L, value[k]))
+		}
+		return t
+	}
+	return lua.LString(fmt.Sprint(v))
+}
+
+func fromLua(v lua.LValue) interface{} {
+	switch value := v.(type) {
+	case lua.LBool:
+		return bool(value)
+	case lua.LString:
+		return string(value)
+	case lua.LNumber:
+		return float64(value)
+	case *lua.LTable:
+		//tables with keys 1..n are converted to arrays
+		if n := value.Len(); n > 0 {
+			count := 0
+			value.ForEach(func(lua.LValue, lua.LValue) { count++ })
+			if count == n {
+				arr := make([]interface{}, 0, n)
+				for i := 1; i <= n; i++ {
+					arr = append(arr, fromLua(value.RawGetInt(i)))
+				}
+				return arr
+			}
+		}
+		m := map[string]interface{}{}
+		value.ForEach(func(k, item lua.LValue) {
+			m[k.String()] = fromLua(item)
+		})
+		return m
+	}
+	return nil
+}
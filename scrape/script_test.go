@@ -0,0 +1,143 @@
+package scrape
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/sandbox"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript(t *testing.T) {
+	s, err := compileScript("price", "return tonumber((value:gsub('[^%d.]', '')))")
+	assert.NoError(t, err)
+	v, err := s.run(context.Background(), "value", "$ 1,234.50")
+	assert.NoError(t, err)
+	assert.Equal(t, 1234.5, v)
+
+	s, err = compileScript("names", "local r = {} for i, v in ipairs(value) do r[i] = v:upper() end return r")
+	assert.NoError(t, err)
+	v, err = s.run(context.Background(), "value", []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"A", "B"}, v)
+
+	s, err = compileScript("empty", " ")
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	_, err = compileScript("broken", "return (")
+	assert.Error(t, err)
+}
+
+func TestRecordScript(t *testing.T) {
+	s, err := compileScript("record", `
+		if record.price > 100 then return nil end
+		record.currency = "USD"
+		record.tags = nil
+		return record`)
+	assert.NoError(t, err)
+
+	r, err := s.runRecord(context.Background(), map[string]interface{}{"price": 10, "tags": []string{"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"price": 10.0, "currency": "USD"}, r)
+
+	r, err = s.runRecord(context.Background(), map[string]interface{}{"price": 200})
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	s, _ = compileScript("record", "return 1")
+	_, err = s.runRecord(context.Background(), map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestScriptSandbox(t *testing.T) {
+	for _, src := range []string{
+		"while true do end",
+		"return string.rep('x', 1e9)",
+		"return dofile('/etc/passwd')",
+		"return io.open('/etc/passwd')",
+		"return os.exit(1)",
+		"local function f() return f() + 1 end return f()",
+	} {
+		s, err := compileScript("sandbox", src)
+		assert.NoError(t, err)
+		_, err = s.run(context.Background(), "value", nil)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestScriptMemoryLimit(t *testing.T) {
+	viper.Set("SCRIPT_TIMEOUT", 60000)
+	viper.Set("SCRIPT_MAX_MEMORY", 1<<20)
+	defer viper.Set("SCRIPT_TIMEOUT", 100)
+	defer viper.Set("SCRIPT_MAX_MEMORY", 0)
+	for _, src := range []string{
+		"local s = 'x' while true do s = s .. s end",
+		"local t = {} for i = 1, 1e9 do t[i] = string.rep('x', 1000) .. i end",
+		"local t = {} for i = 1, 1e9 do t[i] = {i} end",
+		"local function f(t) local s = t .. t return f(s) end return f('x')",
+	} {
+		s, err := compileScript("bomb", src)
+		assert.NoError(t, err)
+		_, err = s.run(context.Background(), "value", nil)
+		if assert.Error(t, err, src) {
+			assert.Contains(t, err.Error(), "memory limit", src)
+		}
+	}
+
+	s, err := compileScript("records", "local t = {} for i = 1, 1000 do t[i] = {id = i, name = 'item ' .. i} end return #t")
+	assert.NoError(t, err)
+	v, err := s.run(context.Background(), "value", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, v)
+}
+
+func TestScriptStringLimits(t *testing.T) {
+	viper.Set("SCRIPT_TIMEOUT", 60000)
+	defer viper.Set("SCRIPT_TIMEOUT", 100)
+	for _, src := range []string{
+		"return string.gsub(string.rep('x', 1000), 'x', string.rep('y', 10000))",
+		"return string.gsub(string.rep('ab', 1000), '(a)(b)', '%0%0%0%1%2' .. string.rep('y', 2000))",
+		"return string.gsub(string.rep('x', 1000), 'x', {x = string.rep('y', 10000)})",
+		"return string.gsub(string.rep('x', 1000), 'x', function(c) return string.rep('y', 10000) end)",
+		"return string.format(string.rep('%999999s', 10), 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a')",
+		"local s = string.rep('x', 500000) return string.format('%s%s%s', s, s, s)",
+		"local t = {} for i = 1, 1e5 do t[i] = 'x' end return table.concat(t, string.rep('-', 100))",
+		"local t = {} for i = 1, 100 do t[i] = string.rep('x', 100000) end return table.concat(t)",
+		"local s = string.rep('x', 1e6) return s .. s",
+		"local s = 'x' for i = 1, 100 do s = s .. string.rep('y', 100000) end return s",
+	} {
+		s, err := compileScript("bomb", src)
+		assert.NoError(t, err)
+		_, err = s.run(context.Background(), "value", nil)
+		if assert.Error(t, err, src) {
+			assert.Contains(t, err.Error(), "too long", src)
+		}
+	}
+
+	//results within the limit are not affected
+	for src, expected := range map[string]interface{}{
+		"return (string.gsub('hello world', '(o)', '[%1]'))":             "hell[o] w[o]rld",
+		"return (string.gsub('hello', 'l', {l = 'L'}))":                  "heLLo",
+		"return (string.gsub('hello', 'l+', function(s) return #s end))": "he2o",
+		"return string.format('%5.1f|%-3s|%d', 3.14159, 'a', 42)":        "  3.1|a  |42",
+		"return table.concat({1, 'b', 3}, ', ')":                         "1, b, 3",
+		"return table.concat({'a', 'b', 'c'}, '', 2)":                    "bc",
+		"return #(string.rep('x', 500000) .. string.rep('y', 500000))":   1000000.0,
+	} {
+		s, err := compileScript("script", src)
+		assert.NoError(t, err)
+		v, err := s.run(context.Background(), "value", nil)
+		assert.NoError(t, err, src)
+		assert.Equal(t, expected, v, src)
+	}
+
+	//strings passed to the script may be longer than the limit
+	s, err := compileScript("long", "return string.upper(value):sub(1, 3)")
+	assert.NoError(t, err)
+	v, err := s.run(context.Background(), "value", strings.Repeat("x", 2*sandbox.MaxString))
+	assert.NoError(t, err)
+	assert.Equal(t, "XXX", v)
+}
@@ -0,0 +1,48 @@
+package scrape
+
+import (
+	"regexp"
+
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// shadowCombinator descends from a host element into its shadow tree, f.e. "product-card >>> .price".
+var shadowCombinator = regexp.MustCompile(`\s*>>>\s*`)
+
+// shadowSelector converts selector with shadow piercing combinators to a selector of elements
+// inside shadow roots inlined into the page by Chrome fetcher. Other selectors are returned as is.
+func shadowSelector(selector string) string {
+	return shadowCombinator.ReplaceAllString(selector, " > "+fetch.ShadowRootTag+" ")
+}
+
+// piercesShadow reports whether selectors of payload fields, their details or paginators descend into shadow trees.
+// Chrome fetcher inlines shadow roots into pages of such payloads only.
+func (p Payload) piercesShadow() bool {
+	if p.Paginator != nil && shadowCombinator.MatchString(p.Paginator.Selector) {
+		return true
+	}
+	return fieldsPierceShadow(p.Fields)
+}
+
+func fieldsPierceShadow(fields []Field) bool {
+	for _, f := range fields {
+		if shadowCombinator.MatchString(f.Selector) {
+			return true
+		}
+		for _, step := range f.Traverse {
+			if shadowCombinator.MatchString(step) {
+				return true
+			}
+		}
+		if f.Details == nil {
+			continue
+		}
+		if f.Details.Paginator != nil && shadowCombinator.MatchString(f.Details.Paginator.Selector) {
+			return true
+		}
+		if fieldsPierceShadow(f.Details.Fields) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowSelector(t *testing.T) {
+	assert.Equal(t, ".price", shadowSelector(".price"))
+	assert.Equal(t, "product-card > shadow-root .price", shadowSelector("product-card >>> .price"))
+	assert.Equal(t, "app-root > shadow-root product-list > shadow-root .item", shadowSelector("app-root>>>product-list >>> .item"))
+
+	html := `<html><body>
+	<product-card><shadow-root><span class="price">10</span><slot></slot></shadow-root><span class="price">light</span></product-card>
+	<product-card><shadow-root><span class="price">20</span></shadow-root></product-card>
+	</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.NoError(t, err)
+	prices := doc.Find(shadowSelector("product-card >>> .price"))
+	assert.Equal(t, []string{"10", "20"}, prices.Map(func(i int, s *goquery.Selection) string { return s.Text() }))
+}
+
+func TestPayload_piercesShadow(t *testing.T) {
+	assert.False(t, Payload{Fields: []Field{{Selector: ".price", Traverse: []string{"closest(div)"}}}}.piercesShadow())
+	for _, p := range []Payload{
+		{Fields: []Field{{Selector: "product-card >>> .price"}}},
+		{Fields: []Field{{Selector: "product-card", Traverse: []string{"find(>>> .price)"}}}},
+		{Fields: []Field{{Selector: "a", Details: &details{Fields: []Field{{Selector: "app-root>>>h1"}}}}}},
+		{Fields: []Field{{Selector: "a"}}, Paginator: &paginator{Selector: "app-pager >>> .next"}},
+	} {
+		assert.True(t, p.piercesShadow(), p)
+	}
+}
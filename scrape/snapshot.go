@@ -0,0 +1,304 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Snapshot describes a page stored by payload with Snapshots option.
+type Snapshot struct {
+	//Run is the ID of the parse task which took the snapshot
+	Run     string    `json:"run"`
+	URL     string    `json:"url"`
+	Fetched time.Time `json:"fetched"`
+	//Size is the length of stored HTML in bytes
+	Size int `json:"size"`
+}
+
+// SnapshotRequest specifies stored page snapshots to be retrieved.
+type SnapshotRequest struct {
+	//ID is a results ID returned by Parse
+	ID string `json:"id"`
+	//Run selects snapshots taken by the run. Snapshots of all stored runs are listed and
+	//the latest snapshot of URL is returned if it is empty.
+	Run string `json:"run,omitempty"`
+	//URL of the page. The list of snapshots of the job is requested if it is empty.
+	URL string `json:"url,omitempty"`
+}
+
+// ReparseRequest specifies payload processed over page snapshots of parse job.
+type ReparseRequest struct {
+	//ID is a results ID of the job run with Snapshots option
+	ID      string  `json:"id"`
+	Payload Payload `json:"payload"`
+}
+
+// snapshotsMx serializes snapshot list updates of the process. Replicas sharing the storage
+// are serialized with the storage lock of the list.
+var snapshotsMx sync.Mutex
+
+func snapshotsKey(id string) string {
+	return "snapshots-" + id
+}
+
+// snapshotKey returns the key of the snapshot of url taken by run of the job id.
+// Snapshots stored before runs were recorded have no run.
+func snapshotKey(id, run, url string) string {
+	key := "snapshot-" + id + "-"
+	if run != "" {
+		key += run + "-"
+	}
+	return key + hex.EncodeToString(utils.GenerateMD5([]byte(url)))
+}
+
+// keepPage stores content of the page fetched by req as a snapshot and writes it to WARC file
+// if payload Snapshots or WARC options are on. Pages marked noarchive are neither stored nor archived. The returned reader replaces content which is consumed.
+func (task *Task) keepPage(req fetch.Request, content io.ReadCloser) (io.ReadCloser, error) {
+	if !utils.IsTrue(task.Payload.Snapshots) && task.warc == nil {
+		return content, nil
+	}
+	data, err := ioutil.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return nil, err
+	}
+	header := fetch.ResponseHeader(content)
+	if noArchive(data, header) {
+		task.log().Info("Page is not stored as it is marked noarchive", zap.String("URL", req.URL))
+		return fetch.WithResponseHeader(ioutil.NopCloser(bytes.NewReader(data)), header), nil
+	}
+	if utils.IsTrue(task.Payload.Snapshots) {
+		task.snapshot(req, data)
+	}
+	if err := task.warc.writeExchange(req, data); err != nil {
+		task.log().Warn(fmt.Sprintf("Failed to write %s to WARC file. %s", req.URL, err.Error()))
+	}
+	return fetch.WithResponseHeader(ioutil.NopCloser(bytes.NewReader(data)), header), nil
+}
+
+// snapshot stores data of the page fetched by req.
+func (task *Task) snapshot(req fetch.Request, data []byte) {
+	uid := task.resultsID()
+	err := task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   snapshotKey(uid, task.ID, req.URL),
+		Value: data,
+	})
+	if err != nil {
+		task.log().Warn(fmt.Sprintf("Failed to store snapshot of %s. %s", req.URL, err.Error()))
+	} else {
+		task.addSnapshot(Snapshot{Run: task.ID, URL: req.URL, Fetched: time.Now().UTC(), Size: len(data)})
+	}
+}
+
+// addSnapshot adds s to the list of snapshots taken by the task.
+// The page fetched again, f.e. with Chrome fetcher, replaces the previous snapshot of the same URL.
+func (task *Task) addSnapshot(s Snapshot) {
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	for i := range task.snapshots {
+		if task.snapshots[i].URL == s.URL {
+			task.snapshots[i] = s
+			return
+		}
+	}
+	task.snapshots = append(task.snapshots, s)
+}
+
+// saveSnapshots adds snapshots taken by the task to the list of snapshots of the job.
+// Only snapshots of SNAPSHOT_RUNS latest runs of the same payload are kept.
+func (task *Task) saveSnapshots(uid string) error {
+	if !utils.IsTrue(task.Payload.Snapshots) {
+		return nil
+	}
+	snapshotsMx.Lock()
+	defer snapshotsMx.Unlock()
+	unlock, err := storage.Lock(task.storage, snapshotsKey(uid))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	previous, err := storedSnapshots(task.storage, uid)
+	if err != nil {
+		task.log().Warn(err.Error())
+	}
+	snapshots := append(previous, task.snapshots...)
+	runs := snapshotRuns(snapshots)
+	if maxRuns := viper.GetInt("SNAPSHOT_RUNS"); maxRuns > 0 && len(runs) > maxRuns {
+		expired := map[string]bool{}
+		for _, run := range runs[:len(runs)-maxRuns] {
+			expired[run] = true
+		}
+		kept := []Snapshot{}
+		for _, s := range snapshots {
+			if !expired[s.Run] {
+				kept = append(kept, s)
+				continue
+			}
+			if err := task.storage.Delete(storage.Record{Type: storage.BINARY, Key: snapshotKey(uid, s.Run, s.URL)}); err != nil {
+				task.log().Warn(err.Error())
+			}
+		}
+		snapshots = kept
+	}
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	return task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   snapshotsKey(uid),
+		Value: data,
+	})
+}
+
+// snapshotRuns returns distinct runs of snapshots in the order they were taken.
+func snapshotRuns(snapshots []Snapshot) []string {
+	runs := []string{}
+	seen := map[string]bool{}
+	for _, s := range snapshots {
+		if !seen[s.Run] {
+			seen[s.Run] = true
+			runs = append(runs, s.Run)
+		}
+	}
+	return runs
+}
+
+// latestSnapshot returns the latest snapshot of url taken by run, or by any run if run is empty.
+func latestSnapshot(snapshots []Snapshot, run, url string) (Snapshot, bool) {
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := snapshots[i]
+		if s.URL == url && (run == "" || s.Run == run) {
+			return s, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// storedSnapshots returns snapshots of the job specified by results ID in the order they were taken.
+func storedSnapshots(s storage.Store, id string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	rec := storage.Record{Type: storage.BINARY, Key: snapshotsKey(id)}
+	if !s.IsExists(rec) {
+		return snapshots, nil
+	}
+	data, err := s.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// snapshotSource replays pages stored by the job with Snapshots option.
+// The latest snapshot of every page is replayed.
+type snapshotSource struct {
+	store     storage.Store
+	id        string
+	snapshots []Snapshot
+}
+
+func (s snapshotSource) fetch(req fetch.Request) (io.ReadCloser, error) {
+	sn, ok := latestSnapshot(s.snapshots, "", req.URL)
+	rec := storage.Record{Type: storage.BINARY, Key: snapshotKey(s.id, sn.Run, req.URL)}
+	if !ok || !s.store.IsExists(rec) {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("snapshot of %s not found in results %s", req.URL, s.id),
+		}
+	}
+	data, err := s.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Reparse processes payload over page snapshots stored by the job with results ID id instead of fetching pages.
+// It is used to iterate on fields of the payload against pages which are expensive to fetch or have changed since.
+func (task *Task) Reparse(id string) (io.ReadCloser, error) {
+	if id == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	if task.Payload.Source != "" {
+		return nil, errs.BadPayload{ErrText: "source archive can't be used to re-parse snapshots"}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	snapshots, err := storedSnapshots(s, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("no snapshots found in results %s. Run the payload with snapshots option first", id),
+		}
+	}
+	task.source = snapshotSource{store: s, id: id, snapshots: snapshots}
+	return task.Parse()
+}
+
+// GetSnapshots returns the list of page snapshots stored by the job specified by results ID,
+// only those taken by req.Run if it is set.
+func GetSnapshots(req SnapshotRequest) ([]Snapshot, error) {
+	if req.ID == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	snapshots, err := storedSnapshots(s, req.ID)
+	if err != nil || req.Run == "" {
+		return snapshots, err
+	}
+	run := []Snapshot{}
+	for _, sn := range snapshots {
+		if sn.Run == req.Run {
+			run = append(run, sn)
+		}
+	}
+	return run, nil
+}
+
+// GetSnapshot returns HTML of the page stored by the job specified by results ID exactly as it was fetched.
+// The latest snapshot of the page is returned unless req.Run is set.
+func GetSnapshot(req SnapshotRequest) ([]byte, error) {
+	if req.ID == "" {
+		return nil, errs.BadPayload{ErrText: "no results ID provided"}
+	}
+	if req.URL == "" {
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	}
+	s := storage.NewStore(viper.GetString("STORAGE_TYPE"))
+	defer s.Close()
+	snapshots, err := storedSnapshots(s, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	sn, ok := latestSnapshot(snapshots, req.Run, req.URL)
+	rec := storage.Record{Type: storage.BINARY, Key: snapshotKey(req.ID, sn.Run, req.URL)}
+	if !ok || !s.IsExists(rec) {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("snapshot of %s not found in results %s", req.URL, req.ID),
+		}
+	}
+	return s.Read(rec)
+}
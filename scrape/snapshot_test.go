@@ -0,0 +1,129 @@
+package scrape
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshots(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	keep := true
+	run := func(pages map[string]string, urls ...string) string {
+		task := NewTask(Payload{Name: "snapshots", Request: fetch.Request{URL: urls[0]}, Snapshots: &keep})
+		for _, u := range urls {
+			content, err := task.keepPage(fetch.Request{URL: u}, ioutil.NopCloser(strings.NewReader(pages[u])))
+			assert.NoError(t, err)
+			data, err := ioutil.ReadAll(content)
+			assert.NoError(t, err)
+			assert.Equal(t, pages[u], string(data), "content is passed on")
+		}
+		uid := task.resultsID()
+		assert.NoError(t, task.saveSnapshots(uid))
+		task.storage.Close()
+		return uid
+	}
+	pages := map[string]string{
+		"http://example.com/":  "<html><body>index</body></html>",
+		"http://example.com/2": "<html><body>page 2</body></html>",
+	}
+	uid := run(pages, "http://example.com/", "http://example.com/2", "http://example.com/")
+
+	snapshots, err := GetSnapshots(SnapshotRequest{ID: uid})
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, "http://example.com/", snapshots[0].URL)
+	assert.Equal(t, len(pages["http://example.com/"]), snapshots[0].Size)
+
+	data, err := GetSnapshot(SnapshotRequest{ID: uid, URL: "http://example.com/2"})
+	assert.NoError(t, err)
+	assert.Equal(t, pages["http://example.com/2"], string(data))
+
+	//the next run doesn't fetch page 2, it's kept from the first run
+	first := snapshots[0].Run
+	pages["http://example.com/"] = "<html><body>updated</body></html>"
+	assert.Equal(t, uid, run(pages, "http://example.com/"))
+	data, err = GetSnapshot(SnapshotRequest{ID: uid, URL: "http://example.com/"})
+	assert.NoError(t, err)
+	assert.Equal(t, pages["http://example.com/"], string(data))
+	data, err = GetSnapshot(SnapshotRequest{ID: uid, Run: first, URL: "http://example.com/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>index</body></html>", string(data), "snapshots of previous runs are not overwritten")
+	data, err = GetSnapshot(SnapshotRequest{ID: uid, URL: "http://example.com/2"})
+	assert.NoError(t, err)
+	assert.Equal(t, pages["http://example.com/2"], string(data))
+	snapshots, err = GetSnapshots(SnapshotRequest{ID: uid})
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 3)
+	snapshots, err = GetSnapshots(SnapshotRequest{ID: uid, Run: first})
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	//snapshots of the first run expire
+	viper.Set("SNAPSHOT_RUNS", 2)
+	defer viper.Set("SNAPSHOT_RUNS", 0)
+	run(pages, "http://example.com/")
+	snapshots, err = GetSnapshots(SnapshotRequest{ID: uid, Run: first})
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+	_, err = GetSnapshot(SnapshotRequest{ID: uid, URL: "http://example.com/2"})
+	assert.Error(t, err)
+	assert.IsType(t, errs.StatusError{}, err)
+	snapshots, err = GetSnapshots(SnapshotRequest{ID: uid})
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	_, err = GetSnapshot(SnapshotRequest{ID: uid})
+	assert.Error(t, err)
+	snapshots, err = GetSnapshots(SnapshotRequest{ID: "notexists"})
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestReparse(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	_, err := NewTask(Payload{}).Reparse("")
+	assert.Error(t, err)
+	assert.IsType(t, errs.BadPayload{}, err)
+
+	_, err = NewTask(Payload{Source: "site.har"}).Reparse("123")
+	assert.Error(t, err)
+	assert.IsType(t, errs.BadPayload{}, err)
+
+	_, err = NewTask(Payload{}).Reparse("notexists")
+	assert.Error(t, err)
+	assert.IsType(t, errs.StatusError{}, err)
+}
+
+func TestKeepPage_noarchive(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	var buf bytes.Buffer
+	ww, err := newWARCWriter(&buf, "test.warc.gz")
+	assert.NoError(t, err)
+	snapshots := true
+	task := NewTask(Payload{Name: "noarchive", Request: fetch.Request{URL: "http://example.com/"}, Snapshots: &snapshots})
+	defer task.storage.Close()
+	task.warc = ww
+	page := `<html><head><meta name="robots" content="noarchive"></head></html>`
+	content, err := task.keepPage(fetch.Request{URL: "http://example.com/"}, ioutil.NopCloser(strings.NewReader(page)))
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, page, string(data), "content is passed on")
+	assert.Empty(t, task.snapshots)
+
+	s, err := readWARC(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	_, err = s.fetch(fetch.Request{URL: "http://example.com/"})
+	assert.Error(t, err, "page is not archived")
+}
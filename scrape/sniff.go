@@ -0,0 +1,40 @@
+package scrape
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// errBinaryContent is returned for fetched content which is not a text document, f.e. PDF or image
+// served as text/html. Such content is not passed to HTML parser, so no corrupted records are extracted from it.
+type errBinaryContent struct {
+	contentType string
+}
+
+func (e errBinaryContent) Error() string {
+	return fmt.Sprintf("content is not a text document (%s)", e.contentType)
+}
+
+// binaryContent returns an error if content is a resource saved to the storage by binary pipeline of Fetch service.
+func binaryContent(content io.ReadCloser) error {
+	if contentType := fetch.ResponseHeader(content).Get(fetch.BinaryHeader); contentType != "" {
+		return errBinaryContent{contentType}
+	}
+	return nil
+}
+
+// sniffText returns a reader of content from its beginning or an error if the first bytes of content show it is binary.
+func sniffText(content io.Reader) (io.Reader, error) {
+	r := bufio.NewReaderSize(content, fetch.SniffLen)
+	head, err := r.Peek(fetch.SniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if contentType, binary := fetch.SniffBinary(head); binary {
+		return nil, errBinaryContent{contentType}
+	}
+	return r, nil
+}
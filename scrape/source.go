@@ -0,0 +1,211 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+)
+
+// pageSource provides pages of the task instead of fetching them from the network.
+type pageSource interface {
+	fetch(req fetch.Request) (io.ReadCloser, error)
+}
+
+// source is an archive of stored responses replayed instead of fetching pages from the network.
+// Responses are looked up by URL. The first successful response is kept if an archive contains several ones for the same URL.
+type source struct {
+	pages map[string][]byte
+}
+
+func newSource() *source {
+	return &source{pages: make(map[string][]byte)}
+}
+
+// sourceKey normalizes URL of archived response. Fragment is dropped, scheme and host are lower-cased.
+func sourceKey(u string) string {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return u
+	}
+	parsed.Fragment = ""
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	return parsed.String()
+}
+
+func (s *source) add(u string, body []byte) {
+	key := sourceKey(u)
+	if _, ok := s.pages[key]; !ok {
+		s.pages[key] = body
+	}
+}
+
+// fetch returns archived content of the page requested by req.
+func (s *source) fetch(req fetch.Request) (io.ReadCloser, error) {
+	body, ok := s.pages[sourceKey(req.URL)]
+	if !ok {
+		return nil, errs.StatusError{
+			Code: http.StatusNotFound,
+			Err:  fmt.Errorf("%s not found in source archive", req.URL),
+		}
+	}
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
+
+// openSource reads WARC (.warc, .warc.gz) or HAR (.har) archive named name.
+// The archive is looked up in SOURCE_DIR. Names referring to files outside of it are rejected.
+func openSource(name string) (*source, error) {
+	dir := viper.GetString("SOURCE_DIR")
+	clean := filepath.Clean("/" + name)
+	if clean != "/"+name {
+		return nil, errs.BadPayload{ErrText: "invalid source name " + name}
+	}
+	f, err := os.Open(filepath.Join(dir, clean))
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: "cannot open source. " + err.Error()}
+	}
+	defer f.Close()
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".har"):
+		return readHAR(f)
+	case strings.HasSuffix(lower, ".warc"), strings.HasSuffix(lower, ".warc.gz"):
+		return readWARC(f)
+	}
+	return nil, errs.BadPayload{ErrText: "unknown source format " + name + ". WARC or HAR file is expected"}
+}
+
+// harFile is a subset of HTTP Archive format used to replay responses.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// readHAR reads successful GET responses of HAR file, f.e. exported from browser developer tools.
+func readHAR(r io.Reader) (*source, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, errs.BadPayload{ErrText: "failed to parse HAR file. " + err.Error()}
+	}
+	s := newSource()
+	for _, e := range har.Log.Entries {
+		method := strings.ToUpper(e.Request.Method)
+		if method != "" && method != "GET" || e.Response.Status/100 != 2 {
+			continue
+		}
+		body := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			data, err := base64.StdEncoding.DecodeString(e.Response.Content.Text)
+			if err != nil {
+				continue
+			}
+			body = data
+		}
+		s.add(e.Request.URL, body)
+	}
+	return s, nil
+}
+
+// readWARC reads successful responses and resources of WARC file. Gzipped files are detected by content.
+func readWARC(r io.Reader) (*source, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	}
+	s := newSource()
+	tp := textproto.NewReader(br)
+	for {
+		line, err := tp.ReadLine()
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		//records are separated by empty lines
+		if !strings.HasPrefix(line, "WARC/") {
+			continue
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC record header. %s", err.Error())
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid WARC record length %q", header.Get("Content-Length"))
+		}
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, fmt.Errorf("failed to read WARC record. %s", err.Error())
+		}
+		target := header.Get("WARC-Target-URI")
+		switch header.Get("WARC-Type") {
+		case "response":
+			if body, ok := warcResponseBody(block); ok {
+				s.add(target, body)
+			}
+		case "resource":
+			s.add(target, block)
+		}
+	}
+}
+
+// warcResponseBody returns decoded body of successful HTTP response stored in WARC response record.
+func warcResponseBody(block []byte) ([]byte, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil || resp.StatusCode/100 != 2 {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, false
+		}
+		defer gr.Close()
+		body = gr
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,102 @@
+package scrape
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceKey(t *testing.T) {
+	assert.Equal(t, "http://example.com/", sourceKey("HTTP://Example.com"))
+	assert.Equal(t, "http://example.com/a?b=1", sourceKey("http://example.com/a?b=1#top"))
+}
+
+func TestReadHAR(t *testing.T) {
+	har := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "http://example.com/"},
+		 "response": {"status": 200, "content": {"text": "<html>index</html>"}}},
+		{"request": {"method": "GET", "url": "http://example.com/"},
+		 "response": {"status": 200, "content": {"text": "<html>reloaded</html>"}}},
+		{"request": {"method": "GET", "url": "http://example.com/b64"},
+		 "response": {"status": 200, "content": {"text": "PGh0bWw+YjY0PC9odG1sPg==", "encoding": "base64"}}},
+		{"request": {"method": "GET", "url": "http://example.com/missing"},
+		 "response": {"status": 404, "content": {"text": "not found"}}},
+		{"request": {"method": "POST", "url": "http://example.com/search"},
+		 "response": {"status": 200, "content": {"text": "results"}}}
+	]}}`
+	s, err := readHAR(strings.NewReader(har))
+	assert.NoError(t, err)
+	assert.Len(t, s.pages, 2)
+	content, err := s.fetch(fetch.Request{URL: "http://example.com#main"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	assert.Equal(t, "<html>index</html>", string(data))
+	content, err = s.fetch(fetch.Request{URL: "http://example.com/b64"})
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(content)
+	assert.Equal(t, "<html>b64</html>", string(data))
+	_, err = s.fetch(fetch.Request{URL: "http://example.com/missing"})
+	assert.Error(t, err)
+
+	_, err = readHAR(strings.NewReader("not json"))
+	assert.Error(t, err)
+}
+
+func TestReadWARC(t *testing.T) {
+	var buf bytes.Buffer
+	ww, err := newWARCWriter(&buf, "test.warc.gz")
+	assert.NoError(t, err)
+	assert.NoError(t, ww.writeExchange(fetch.Request{URL: "http://example.com/"}, []byte("<html>index</html>")))
+	assert.NoError(t, ww.writeExchange(fetch.Request{URL: "http://example.com/2"}, []byte("<html>page 2</html>")))
+
+	s, err := readWARC(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, s.pages, 2)
+	assert.Equal(t, "<html>page 2</html>", string(s.pages["http://example.com/2"]))
+
+	record := func(typ, uri, block string) string {
+		return "WARC/1.0\r\nWARC-Type: " + typ + "\r\nWARC-Target-URI: " + uri +
+			"\r\nContent-Length: " + strconv.Itoa(len(block)) + "\r\n\r\n" + block + "\r\n\r\n"
+	}
+	plain := record("response", "http://example.com/chunked",
+		"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nbody\r\n0\r\n\r\n") +
+		record("response", "http://example.com/404", "HTTP/1.1 404 Not Found\r\n\r\n") +
+		record("resource", "http://example.com/res", "resource")
+	s, err = readWARC(strings.NewReader(plain))
+	assert.NoError(t, err)
+	assert.Len(t, s.pages, 2)
+	assert.Equal(t, "body", string(s.pages["http://example.com/chunked"]))
+	assert.Equal(t, "resource", string(s.pages["http://example.com/res"]))
+
+	_, err = readWARC(strings.NewReader("WARC/1.0\r\nContent-Length: 100\r\n\r\nshort"))
+	assert.Error(t, err)
+}
+
+func TestOpenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sources")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	viper.Set("SOURCE_DIR", dir)
+	defer viper.Set("SOURCE_DIR", "")
+	har := `{"log": {"entries": [{"request": {"url": "http://example.com/"}, "response": {"status": 200, "content": {"text": "index"}}}]}}`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "session.har"), []byte(har), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "session.txt"), []byte(har), 0644))
+
+	s, err := openSource("session.har")
+	assert.NoError(t, err)
+	assert.Len(t, s.pages, 1)
+	_, err = openSource("session.txt")
+	assert.Error(t, err)
+	_, err = openSource("../session.har")
+	assert.Error(t, err)
+	_, err = openSource("notexists.warc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/storage"
+)
+
+// streamParts converts payload fields to parts of extract.StreamExtractor.
+// Only flat payloads may be processed by StreamExtractor. So false is returned for payloads containing
+// paginator, seed URLs, details, path, field scripts, text matches, traversal steps, field indexes, missing value policies
+// or extractor types other than text and attributes.
+func (p Payload) streamParts() ([]extract.StreamPart, bool) {
+	if p.Paginator != nil || p.IsPath || p.batch() || p.hasMissingPolicy() {
+		return nil, false
+	}
+	parts := []extract.StreamPart{}
+	for _, f := range p.Fields {
+		selector := shadowSelector(f.Selector)
+		if f.Details != nil || f.Extractor.Script != "" || f.Index != "" || f.TextMatch != "" || len(f.Traverse) > 0 || !extract.IsStreamable(selector) {
+			return nil, false
+		}
+		for _, t := range f.Extractor.Types {
+			part := extract.StreamPart{
+				Name:     f.Name + "_" + t,
+				Selector: selector,
+			}
+			switch strings.ToLower(t) {
+			case "text":
+				//streaming tokenizer collects text of descendants
+				if ownText, _ := f.Extractor.Params["ownText"].(bool); ownText {
+					return nil, false
+				}
+				part.Filters = f.Extractor.Filters
+			case "alt":
+				part.Filters = f.Extractor.Filters
+			case "href", "src", "width", "height":
+			default:
+				return nil, false
+			}
+			if strings.ToLower(t) != "text" {
+				part.Attr = strings.ToLower(t)
+			}
+			parts = append(parts, part)
+		}
+	}
+	return parts, len(parts) > 0
+}
+
+// streamScrape processes flat payload with streaming tokenizer instead of building goquery DOM.
+// It cuts memory consumption on huge listing pages.
+// Results are stored the same way as block results of DOM based scraper so all encoders work as usual.
+func (task *Task) streamScrape(tw *taskWorker, parts []extract.StreamPart) error {
+	rows, err := task.streamExtract(tw.scraper.Request.Type, tw, parts)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 && task.Payload.Request.Type != "chrome" {
+		task.log().Info("Failed to scrape with base fetcher. Reinitializing to scrape with Chrome fetcher.")
+		task.Payload.Request.Type = "chrome"
+		rows, err = task.streamExtract("chrome", tw, parts)
+		if err != nil {
+			return err
+		}
+	}
+	i := 0
+	for _, row := range rows {
+		if task.recordScript != nil {
+			record, err := task.recordScript.runRecord(task.ctx, row)
+			switch {
+			case err != nil:
+				task.log().Warn(err.Error())
+			case record == nil:
+				continue
+			default:
+				row = record
+			}
+		}
+		task.Payload.addLanguage(row)
+		task.stats.record(row)
+		output, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		err = task.storage.Write(storage.Record{
+			Type:    storage.INTERMEDIATE,
+			Key:     fmt.Sprintf("%s-0-%d", tw.UID, i),
+			Value:   output,
+			ExpTime: 0,
+		})
+		if err != nil {
+			return err
+		}
+		tw.keys[0] = append(tw.keys[0], i)
+		i++
+	}
+	task.stats.page(nil)
+	task.Parsed = i > 0
+	return nil
+}
+
+// streamExtract downloads a page with specified fetcher type and extracts parts from it.
+func (task *Task) streamExtract(fetcherType string, tw *taskWorker, parts []extract.StreamPart) ([]map[string]interface{}, error) {
+	req := tw.scraper.Request
+	req.Type = fetcherType
+	if err := task.allowedByRobots(req, false); err != nil {
+		return nil, err
+	}
+	task.mx.Lock()
+	task.requestCount[tw.scraper.reqType]++
+	task.mx.Unlock()
+	var content io.ReadCloser
+	var err error
+	if task.source != nil {
+		content, err = task.source.fetch(req)
+	} else {
+		req.Job = task.job()
+		content, err = fetchContent(task.Payload.credentials.attach(req))
+	}
+	if err == nil {
+		content, err = task.keepPage(req, content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	if err := task.urlFilter.checkRedirect(content); err != nil {
+		return nil, err
+	}
+	atomic.AddUint32(&task.responseCount, 1)
+	e, err := extract.NewStreamExtractor(req.URL, parts)
+	if err != nil {
+		return nil, err
+	}
+	return e.Extract(content)
+}
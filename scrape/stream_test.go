@@ -0,0 +1,93 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/slotix/dataflowkit/extract"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var flatPayload = Payload{
+	Name: "persons flat",
+	Request: fetch.Request{
+		Type: "base",
+		URL:  "http://127.0.0.1:12345/persons/page-0",
+	},
+	Fields: []Field{
+		{
+			Name:     "Names",
+			Selector: "#cards a",
+			Extractor: Extractor{
+				Types:   []string{"text", "href"},
+				Filters: []string{"trim"},
+			},
+		},
+		{
+			Name:     "Images",
+			Selector: ".card-img-top",
+			Extractor: Extractor{
+				Types: []string{"src"},
+			},
+		},
+	},
+	Format: "json",
+}
+
+func TestStreamParts(t *testing.T) {
+	parts, ok := flatPayload.streamParts()
+	assert.True(t, ok)
+	assert.Equal(t, []extract.StreamPart{
+		{Name: "Names_text", Selector: "#cards a", Filters: []string{"trim"}},
+		{Name: "Names_href", Selector: "#cards a", Attr: "href"},
+		{Name: "Images_src", Selector: ".card-img-top", Attr: "src"},
+	}, parts)
+
+	//paginator
+	_, ok = personsPayload.streamParts()
+	assert.False(t, ok)
+	//details
+	_, ok = detailsPayload.streamParts()
+	assert.False(t, ok)
+	//unsupported selector
+	p := flatPayload
+	p.Fields = []Field{{Name: "a", Selector: "ul > li", Extractor: Extractor{Types: []string{"text"}}}}
+	_, ok = p.streamParts()
+	assert.False(t, ok)
+}
+
+func TestStreamParse(t *testing.T) {
+	os.RemoveAll("./diskv")
+	os.RemoveAll("./results")
+	viper.Set("STREAM_EXTRACTION", true)
+	defer viper.Set("STREAM_EXTRACTION", false)
+	fetchServer := fetch.Start(fetch.Config{
+		Host: viper.GetString("DFK_FETCH"),
+	})
+	defer fetchServer.Stop()
+
+	task := NewTask(flatPayload)
+	r, err := task.Parse()
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(r)
+	info := make(map[string]interface{})
+	err = json.Unmarshal(buf.Bytes(), &info)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(info["Output file"].(string))
+	assert.NoError(t, err)
+	results := []map[string]interface{}{}
+	err = json.Unmarshal(data, &results)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+	assert.NotEmpty(t, results[0]["Names_text"])
+	assert.NotEmpty(t, results[0]["Images_src"])
+
+	os.RemoveAll("./diskv")
+	os.RemoveAll("./results")
+}
@@ -3,6 +3,7 @@ package scrape
 import (
 	"context"
 	"io"
+	"regexp"
 	"sync"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/slotix/dataflowkit/paginate"
 	"github.com/slotix/dataflowkit/storage"
 	"github.com/temoto/robotstxt"
+	"go.uber.org/zap"
 )
 
 type details struct {
@@ -33,19 +35,23 @@ type paginator struct {
 	// Set this value to 0 to indicate an unlimited number of pages to be scraped.
 	//
 	MaxPages int `json:"-"`
-	// Type identify paginator type (next link, infinite scroll or more button)
+	// Type identify paginator type (next link, infinite scroll or more button).
+	// "number" type follows the link next to the current page of numbered pagination widget specified by Selector.
 	Type string `json:"type"`
 }
 
 // Extractor type represents Extractor types available for scraping.
 // Here is the list of Extractor types are currently supported:
-// text, html, outerHtml, attr, link, image, regex, const, count
+// text, html, outerHtml, attr, link, image, srcset, email, phone, regex, const, count, exists, json
 // Find more actual information in docs/extractors.md
 type Extractor struct {
 	Types []string `json:"types"`
 	// Params are unique for each type
 	Params  map[string]interface{} `json:"params"`
 	Filters []string               `json:"filters"`
+	//Script is Lua code transforming extracted value of every extractor type of the field.
+	//Extracted value is available as "value" global. The value returned by the script replaces it. Nil excludes it from results.
+	Script string `json:"script,omitempty"`
 }
 
 //A Field corresponds to a given chunk of data to be extracted from every block in each page of a scrape.
@@ -53,11 +59,27 @@ type Field struct {
 	//Name is a name of fields. It is required, and will be used to aggregate results.
 	Name string `json:"name"`
 	//Selector is a CSS selector within the given block to process.  Pass in "." to use the root block's selector.
+	//Elements may be picked by their text with :contains("text"), :containsOwn("text"), :matches(regexp) and :matchesOwn(regexp)
+	//predicates, f.e. "div:has(h3:contains('Specifications'))".
 	Selector string `json:"selector"`
+	//TextMatch is a regular expression matched against text of elements matched by Selector. Other elements are skipped,
+	//f.e. selector "h3" with textMatch "^Specifications$" picks the heading of specifications only.
+	TextMatch string `json:"textMatch,omitempty"`
+	//Traverse moves from elements matched by Selector to related ones, f.e. ["closest(tr)", "find(td.value)"].
+	//Steps are parent, closest(selector), next, prev, children, find(selector) and filter(selector).
+	//next(selector) and prev(selector) move to the nearest following or preceding sibling matching selector.
+	Traverse []string `json:"traverse,omitempty"`
+	//Index picks matches of Selector within the block by their position starting from 0.
+	//It may be a single match "1", the last match "-1" or a range "start:end" with exclusive end, f.e. "0:3" or "2:".
+	Index string `json:"index,omitempty"`
 	//Extractor contains the logic on how to extract some results from the selector that is provided to this Field.
 	Extractor Extractor `json:"extractor"`
 	//Details is an optional field strictly for Link extractor type. It guides scraper to parse additional pages following the links according to the set of fields specified inside "details"
 	Details *details `json:"details"`
+	//OnMissing overrides Payload.OnMissing for the field.
+	OnMissing string `json:"onMissing,omitempty"`
+	//Default is the value of the field if nothing is extracted. It implies "default" OnMissing policy.
+	Default interface{} `json:"default,omitempty"`
 }
 
 // Payload structure contain information and rules to be passed to a scraper
@@ -65,12 +87,36 @@ type Field struct {
 type Payload struct {
 	// Name - Collection name.
 	Name string `json:"name"`
+	//Type is the job type. Fields are extracted from fetched pages by default.
+	//"linkcheck" jobs check status codes, redirect targets and content types of request URL and seed URLs
+	//with HEAD requests instead. Fields and paginator are not required by them.
+	Type string `json:"type,omitempty"`
+	//Extends refers to a base payload saved to the registry as "name" or "name@version".
+	//Base payload settings and fields are inherited and may be overridden. See Registry.Resolve.
+	Extends string `json:"extends,omitempty"`
+	//Vars are substituted for {{variables}} in request URL, form data, body, headers, login form and const field values. See ExpandVars.
+	Vars map[string]string `json:"vars,omitempty"`
+	//Credentials refers to credentials of the payload tenant saved to the vault by name. Their headers, cookies and form data
+	//are added to requests to the credentials domain, username and password are available as {{credentials.username}}
+	//and {{credentials.password}} variables in the request and login form. Requests to other hosts, f.e. followed links,
+	//get no credentials. See Vault.Apply.
+	Credentials string `json:"credentials,omitempty"`
 	//Request struct represents HTTP request to be sent to a server. It combines parameters for passing for downloading html pages by Fetch Endpoint.
 	//Request.URL field is required. All other fields including Params, Cookies, Func are optional.
 	Request fetch.Request `json:"request"`
+	//URLs is a list of seed URLs of batch payload. Every seed is fetched with Request settings and processed with the same fields
+	//after Request.URL (if any). A seed may override method, form data, headers, cookies and fetcher type of Request.
+	//Records get "source_url" field containing their seed URL. Results of every seed are reported in "URLs" of the job status.
+	URLs []Seed `json:"urls,omitempty"`
+	//URLList adds seed URLs read from uploaded file, S3 object or remote URL to URLs.
+	URLList *URLList `json:"urlList,omitempty"`
 	//Fields is a set of fields used to extract data from a web page.
 	Fields []Field `json:"fields"`
-	//PayloadMD5 encodes payload content to MD5. It is used for generating file name to be stored.
+	//OnMissing is a policy for fields with no extracted value: "empty" string, "null", "default" value of the field,
+	//"drop" the record or "fail" the job. Such fields are left out of records by default.
+	//It may be overridden by Field.OnMissing.
+	OnMissing string `json:"onMissing,omitempty"`
+	//PayloadMD5 is hex encoded MD5 of payload content. It is used as results ID and for generating file name to be stored.
 	PayloadMD5 string
 	//FetcherType represent fetcher which is used for document download.
 	//Set up it to either `base` or `chrome` values
@@ -91,6 +137,66 @@ type Payload struct {
 	//
 	// Combined list of results is always returned for CSV format.
 	PaginateResults *bool `json:"paginateResults"`
+	//AutoPaginate turns on pagination detection if Paginator is not specified.
+	//Next page links, numbered pagination widgets and "Load more" buttons are followed up to MAX_PAGES pages.
+	//If AutoPaginate is omitted the value of AUTO_PAGINATE of parse.d service is used by default.
+	AutoPaginate *bool `json:"autoPaginate"`
+	//DetectLanguage adds "language" field to every record. It contains ISO 639-1 code of the language
+	//detected over extracted text. Records with too short texts or texts in unknown languages are left without it.
+	DetectLanguage *bool `json:"detectLanguage"`
+	//RobotsMeta skips extraction of pages marked noindex by meta robots tag or X-Robots-Tag header
+	//and doesn't follow nofollow links to paginated and details pages.
+	//If RobotsMeta is omitted the value of POLITE of parse.d service is used by default.
+	RobotsMeta *bool `json:"robotsMeta"`
+	//Include and Exclude are URL patterns gating seed URLs, paginated and details pages and URLs they are redirected to.
+	//A URL is fetched and extracted if it matches one of Include patterns (if any) and none of Exclude patterns.
+	//Patterns enclosed in slashes are regular expressions, others are globs matching the whole URL.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	//Recording is a user flow recorded by Chrome DevTools Recorder. It is replayed by Chrome fetcher as request steps.
+	Recording *fetch.Recording `json:"recording,omitempty"`
+	//Login signs in to a web site before pages are fetched. Session is kept for Request.UserToken.
+	Login *Login `json:"login,omitempty"`
+	//Limits stop the job gracefully once the number of fetched pages, downloaded bytes or duration reaches them.
+	Limits *Limits `json:"limits,omitempty"`
+	//NoReferer disables Referer header of paginated and details pages requests.
+	//By default it is set to the URL of the page the link has been found on.
+	NoReferer *bool `json:"noReferer,omitempty"`
+	//Delay is a random delay between consecutive requests to the same host in addition to robots.txt Crawl-delay.
+	Delay *Delay `json:"delay,omitempty"`
+	//SkipNearDuplicates skips extraction of pages whose text is nearly the same as the text of already extracted page,
+	//f.e. print views or URLs differing in tracking parameters only.
+	SkipNearDuplicates *bool `json:"skipNearDuplicates"`
+	//SkipUnchanged skips extraction of pages which haven't changed since the previous run of the payload.
+	//Records extracted from them by the previous run are kept instead.
+	SkipUnchanged *bool `json:"skipUnchanged,omitempty"`
+	//FetchMetadata adds fields describing the fetch of the page every record is extracted from: fetch timestamp,
+	//fetch duration, response size, cache hit flag and fetcher type.
+	FetchMetadata *bool `json:"fetchMetadata,omitempty"`
+	//Snapshots stores HTML of every fetched page exactly as it was fetched or rendered by Chrome.
+	//Snapshots are kept for the results ID until the next run of the payload so pages may be extracted again later.
+	Snapshots *bool `json:"snapshots,omitempty"`
+	//Source is a name of WARC (.warc, .warc.gz) or HAR (.har) file in SOURCE_DIR of Parse service.
+	//Pages are taken from responses stored in the file instead of being fetched from the network.
+	Source string `json:"source,omitempty"`
+	//WARC writes request and response records of every fetched page to a gzipped WARC file in RESULTS_DIR.
+	//Its path is returned along with the path of output file.
+	WARC *bool `json:"warc,omitempty"`
+	//Schedule makes the payload saved to the registry run periodically by Parse service scheduler.
+	Schedule *Schedule `json:"schedule,omitempty"`
+	//Alerts send notifications when scheduled runs detect changes of results.
+	Alerts []Alert `json:"alerts,omitempty"`
+	//Canary lists invariants of results of scheduled runs. Runs violating them are marked suspect.
+	Canary *Canary `json:"canary,omitempty"`
+	//Report sends the job report to specified channels once the job completes.
+	Report *ReportDelivery `json:"report,omitempty"`
+	//FillGates warn about or fail the job if fields are filled in too small share of records.
+	FillGates []FillGate `json:"fillGates,omitempty"`
+	//Enrich sends every extracted record to external HTTP endpoint and merges returned fields back in.
+	Enrich *Enrichment `json:"enrich"`
+	//Script is Lua code transforming every record. Record is available as "record" global table.
+	//The table returned by the script replaces the record. Nil or false drops the record.
+	Script string `json:"script,omitempty"`
 	//FetchDelay should be used for a scraper to throttle the crawling speed to avoid hitting the web servers too frequently.
 	//FetchDelay specifies sleep time for multiple requests for the same domain. It is equal to FetchDelay * random value between 500 and 1500 msec
 	FetchDelay *time.Duration
@@ -104,6 +210,13 @@ type Payload struct {
 	// ContainPath means that one of the field just a path and we have to ignore all other fields (if present)
 	// that are not a path
 	IsPath bool `json:"path"`
+	//RequestID is a correlation ID of the parse request. It is passed to fetch service along with every
+	//fetch request and is added to every log line of the task. If it is empty Task ID is used.
+	RequestID string `json:"-"`
+	//Tenant of the client which submitted the payload. It is recorded as an owner of results.
+	Tenant string `json:"-"`
+	//credentials resolved by Vault.Apply are attached to requests to their domain only. See Credentials.attach.
+	credentials *Credentials
 }
 
 // The DividePageFunc type is used to extract a page's blocks during a scrape.
@@ -120,11 +233,22 @@ type Part struct {
 	// the root block's selector with no modification.
 	Selector string
 
+	// textMatch keeps matches of Selector with matching text
+	textMatch *regexp.Regexp
+
 	// Extractor contains the logic on how to extract some results from the
 	// selector that is provided to this Piece.
 	Extractor extract.Extractor
+	// traverse moves from matches of Selector to related elements
+	traverse []traverseStep
+	// index picks matches by their position
+	index *matchIndex
 	//Details is an optional field strictly for Link extractor type. It guides scraper to parse additional pages following the links according to the set of fields specified inside "details"
 	Details Scraper
+	// script transforms extracted values
+	script *script
+	// missing is a policy for missing values
+	missing *missingPolicy
 }
 
 //Scraper struct consolidates settings for scraping task.
@@ -189,6 +313,8 @@ type Task struct {
 	//Errors []error
 	//TaskQueue chan *Scraper
 	Robots map[string]*robotstxt.RobotsData
+	//hostLimiter applies robots.txt Crawl-delay to requests sent to the same host
+	hostLimiter *hostLimiter
 	//Results
 	Parsed bool
 	// storage using to write result into corresponding storage type
@@ -196,7 +322,7 @@ type Task struct {
 	//number of requests divided by request type "initial", "paginator", "details"
 	requestCount  map[string]uint32
 	responseCount uint32
-	mx            *sync.Mutex
+	mx            sync.Mutex
 
 	fetchChannel chan *fetchInfo
 	blockChannel chan *blockStruct
@@ -204,6 +330,43 @@ type Task struct {
 	ctx          context.Context
 	Cancel       context.CancelFunc
 	statePool    map[string]scrapeState
+	//extractSlots limits the number of concurrent extractions
+	extractSlots chan struct{}
+	//pageSlots limits the number of fetched pages waiting for extraction and storage of their blocks
+	pageSlots chan struct{}
+	//fetchStats summarizes latency and size of fetched pages per fetcher type
+	fetchStats *fetchStats
+	//stats collects page outcomes, field fill and durations for the job report
+	stats *jobStats
+	//recordScript transforms every record before it is stored
+	recordScript *script
+	//urlFilter gates links to paginated and details pages
+	urlFilter *urlFilter
+	//limiter enforces job limits
+	limiter *jobLimiter
+	//dedup keeps fingerprints of extracted pages if SkipNearDuplicates is on
+	dedup *dedup
+	//unchanged keeps states of pages of the previous run if SkipUnchanged is on
+	unchanged *unchangedPages
+	//snapshots are pages stored if Snapshots option is on
+	snapshots []Snapshot
+	//source replays archived responses if Source is specified or page snapshots if the task re-parses them
+	source pageSource
+	//warc writes fetched pages to WARC file if WARC option is on
+	warc *warcWriter
+	//loginMx serializes repeated logins, session counts them
+	loginMx sync.Mutex
+	session int
+	//logger adds request correlation ID to log lines of the task
+	logger *zap.Logger
+	//seedReports describe results of every seed URL of batch payload
+	seedReports []SeedReport
+	//seedVars are per-URL variables of seeds read from URL list
+	seedVars map[string]map[string]string
+	//failure is an error failing the job, f.e. a missing value of a field with "fail" policy
+	failure error
+	//gone keeps errors of pages answered with 410 Gone or 451 so they are not requested again
+	gone map[string]error
 }
 
 type taskWorker struct {
@@ -212,6 +375,13 @@ type taskWorker struct {
 	scraper         *Scraper
 	useBlockCounter bool
 	keys            map[int][]int
+	//firstPageNum is the number of the first page of the seed. Pages of batch payload seeds are numbered in a row.
+	firstPageNum int
+	//seed is the seed URL of batch payload the pages are scraped from. seedNum is its position among scraped seeds.
+	seed    string
+	seedNum int
+	//prefetched is the page submitted to fetch workers ahead. The page is fetched by scrape otherwise.
+	prefetched *pendingFetch
 }
 
 type blockStruct struct {
@@ -222,6 +392,20 @@ type blockStruct struct {
 	keys            *map[int][]int
 	wg              *sync.WaitGroup
 	scraper         *Scraper
+	//robots holds directives of the page containing the block if RobotsMeta is on
+	robots *robotsMeta
+	//baseURL is the base URL of the page containing the block. Relative URLs are resolved against it.
+	baseURL string
+	//seed is the seed URL of batch payload the block is scraped from
+	seed string
+	//page counts blocks of the page in process. The page slot is released when they are done
+	page *sync.WaitGroup
+	//pageURL is the URL of the page containing the block
+	pageURL string
+	//referer is the URL of the page containing the block. It is sent along with details requests
+	referer string
+	//meta describes the fetch of the page containing the block if FetchMetadata is on
+	meta *pageMeta
 }
 
 type fetchInfo struct {
@@ -229,6 +413,8 @@ type fetchInfo struct {
 	request fetch.Request
 	reqType string
 	err     chan<- error
+	//meta is filled with the fetch description before the result is sent
+	meta *pageMeta
 }
 
 type scrapeState struct {
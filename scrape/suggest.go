@@ -0,0 +1,299 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"golang.org/x/net/html"
+)
+
+// maxSuggestions is the maximum number of suggested selectors.
+const maxSuggestions = 10
+
+// SuggestRequest contains a web page and example values a user wants to extract from it.
+type SuggestRequest struct {
+	Request fetch.Request `json:"request"`
+	//Examples are one or two values visible on the page. F.e. names of the first two products in a list.
+	Examples []string `json:"examples"`
+}
+
+// Suggestion is a candidate CSS selector matching all example values.
+type Suggestion struct {
+	Selector string `json:"selector"`
+	//Type is an extractor type to be used with the selector: text, href, src or alt.
+	Type string `json:"type"`
+	//Matches is the number of elements selected on the page.
+	Matches int `json:"matches"`
+	//Specificity is CSS specificity of the selector (ids*100 + classes*10 + tags).
+	Specificity int `json:"specificity"`
+	//Stability estimates how likely the selector survives site updates in range (0, 1].
+	//Generated class names and ids decrease stability.
+	Stability float64 `json:"stability"`
+	//Samples are the first values selected by the selector.
+	Samples []string `json:"samples"`
+}
+
+var (
+	// cssIdentRe matches class names and ids usable in selectors without escaping
+	cssIdentRe = regexp.MustCompile(`^[A-Za-z_-][\w-]*$`)
+	// generatedRe matches class names and ids which look generated by frameworks or build tools
+	generatedRe = regexp.MustCompile(`\d{3,}|[A-Za-z]+-[A-Za-z0-9]{5,}$|__|^[a-z]{1,3}\d|^_`)
+	spaceRe     = regexp.MustCompile(`\s+`)
+	// tokenRe matches class names and ids in selectors
+	tokenRe = regexp.MustCompile(`[#.][\w-]+`)
+)
+
+// exampleAttrs are attributes compared with example values in addition to element text
+var exampleAttrs = []string{"href", "src", "alt"}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(spaceRe.ReplaceAllString(s, " ")))
+}
+
+// exampleMatch is an element containing example value
+type exampleMatch struct {
+	node *html.Node
+	//extractor type
+	typ string
+}
+
+// findExample returns the deepest elements whose text or attribute value is equal to example.
+func findExample(doc *goquery.Document, example string) []exampleMatch {
+	example = normalize(example)
+	matches := []exampleMatch{}
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		if normalize(s.Text()) == example {
+			matches = append(matches, exampleMatch{node: s.Get(0), typ: "text"})
+			return
+		}
+		for _, attr := range exampleAttrs {
+			if v, ok := s.Attr(attr); ok && normalize(v) == example {
+				matches = append(matches, exampleMatch{node: s.Get(0), typ: attr})
+				return
+			}
+		}
+	})
+	//drop ancestors of matched elements
+	deepest := []exampleMatch{}
+	for _, m := range matches {
+		isAncestor := false
+		for _, other := range matches {
+			if other.node != m.node && other.typ == m.typ && contains(m.node, other.node) {
+				isAncestor = true
+				break
+			}
+		}
+		if !isAncestor {
+			deepest = append(deepest, m)
+		}
+	}
+	return deepest
+}
+
+// contains checks if n is an ancestor of descendant
+func contains(n, descendant *html.Node) bool {
+	for p := descendant.Parent; p != nil; p = p.Parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// classes returns class names of n usable in selectors
+func classes(n *html.Node) []string {
+	cls := []string{}
+	for _, a := range n.Attr {
+		if a.Key == "class" {
+			for _, c := range strings.Fields(a.Val) {
+				if cssIdentRe.MatchString(c) {
+					cls = append(cls, c)
+				}
+			}
+		}
+	}
+	return cls
+}
+
+func nodeID(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key == "id" && cssIdentRe.MatchString(a.Val) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// compounds returns simple selectors describing n: tag, tag with every class, tag with all classes.
+func compounds(n *html.Node) []string {
+	c := []string{n.Data}
+	cls := classes(n)
+	for _, class := range cls {
+		c = append(c, n.Data+"."+class)
+	}
+	if len(cls) > 1 {
+		c = append(c, n.Data+"."+strings.Join(cls, "."))
+	}
+	return c
+}
+
+// candidates generates selectors for n using its own classes and classes or ids of up to 3 ancestors.
+func candidates(n *html.Node) []string {
+	own := compounds(n)
+	cands := append([]string{}, own...)
+	if id := nodeID(n); id != "" {
+		cands = append(cands, "#"+id)
+	}
+	depth := 0
+	for p := n.Parent; p != nil && p.Type == html.ElementNode && p.Data != "body" && depth < 3; p = p.Parent {
+		depth++
+		ancestors := []string{}
+		if id := nodeID(p); id != "" {
+			ancestors = append(ancestors, "#"+id)
+		}
+		for _, class := range classes(p) {
+			ancestors = append(ancestors, p.Data+"."+class)
+		}
+		for _, a := range ancestors {
+			for _, o := range own {
+				cands = append(cands, a+" "+o)
+			}
+		}
+	}
+	return cands
+}
+
+// specificity returns CSS specificity of selector built by candidates.
+func specificity(selector string) int {
+	s := 0
+	for _, compound := range strings.Fields(selector) {
+		s += 100 * strings.Count(compound, "#")
+		s += 10 * strings.Count(compound, ".")
+		if compound[0] != '#' && compound[0] != '.' {
+			s++
+		}
+	}
+	return s
+}
+
+// stability decreases for every generated-looking class name or id in selector.
+func stability(selector string) float64 {
+	st := 1.0
+	for _, token := range tokenRe.FindAllString(selector, -1) {
+		if generatedRe.MatchString(token[1:]) {
+			st /= 2
+		}
+		//ids are often unique per page and change between listings
+		if token[0] == '#' {
+			st *= 0.9
+		}
+	}
+	return st
+}
+
+// SuggestSelectors downloads a web page and returns candidate CSS selectors matching all example values
+// ranked by stability, specificity and the number of matching elements.
+func SuggestSelectors(req SuggestRequest) ([]Suggestion, error) {
+	if req.Request.URL == "" {
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	}
+	if len(req.Examples) == 0 {
+		return nil, errs.BadPayload{ErrText: "no example values provided"}
+	}
+	content, err := fetchContent(req.Request)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	return suggest(doc, req.Request.URL, req.Examples)
+}
+
+func suggest(doc *goquery.Document, url string, examples []string) ([]Suggestion, error) {
+	examplesMatches := [][]exampleMatch{}
+	for _, e := range examples {
+		m := findExample(doc, e)
+		if len(m) == 0 {
+			return nil, errs.StatusError{Code: 404, Err: fmt.Errorf("example %q not found on %s", e, url)}
+		}
+		examplesMatches = append(examplesMatches, m)
+	}
+	suggestions := []Suggestion{}
+	seen := map[string]bool{}
+	//candidates are generated from the first example and checked against the others
+	for _, m := range examplesMatches[0] {
+		for _, c := range candidates(m.node) {
+			if seen[c+m.typ] {
+				continue
+			}
+			seen[c+m.typ] = true
+			sel := doc.Find(c)
+			if !selectsAll(sel, examplesMatches, m.typ) {
+				continue
+			}
+			suggestions = append(suggestions, Suggestion{
+				Selector:    c,
+				Type:        m.typ,
+				Matches:     sel.Length(),
+				Specificity: specificity(c),
+				Stability:   stability(c),
+				Samples:     samples(sel, m.typ),
+			})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Stability != b.Stability {
+			return a.Stability > b.Stability
+		}
+		//tighter selectors select less unrelated elements
+		if a.Matches != b.Matches {
+			return a.Matches < b.Matches
+		}
+		return a.Specificity < b.Specificity
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions, nil
+}
+
+// selectsAll checks if sel contains an element matching every example with extractor type typ.
+func selectsAll(sel *goquery.Selection, examplesMatches [][]exampleMatch, typ string) bool {
+	for _, matches := range examplesMatches {
+		found := false
+		for _, m := range matches {
+			if m.typ == typ && sel.IsNodes(m.node) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// samples returns up to 3 values selected by sel
+func samples(sel *goquery.Selection, typ string) []string {
+	s := []string{}
+	sel.EachWithBreak(func(i int, el *goquery.Selection) bool {
+		if typ == "text" {
+			s = append(s, strings.TrimSpace(spaceRe.ReplaceAllString(el.Text(), " ")))
+		} else {
+			v, _ := el.Attr(typ)
+			s = append(s, v)
+		}
+		return len(s) < 3
+	})
+	return s
+}
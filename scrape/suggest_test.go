@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const suggestHTML = `<html><body>
+<div id="main">
+	<div class="product card">
+		<h3 class="title"><a href="/p/1">Red Chair</a></h3>
+		<span class="price css-1x2y3z4">10</span>
+	</div>
+	<div class="product card">
+		<h3 class="title"><a href="/p/2">Blue Table</a></h3>
+		<span class="price css-1x2y3z4">20</span>
+	</div>
+</div>
+<div class="footer"><a href="/about">About</a></div>
+</body></html>`
+
+func TestSuggest(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(suggestHTML))
+	assert.NoError(t, err)
+
+	s, err := suggest(doc, "http://example.com", []string{"Red Chair", " blue  table"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, s)
+	for _, suggestion := range s {
+		assert.Equal(t, "text", suggestion.Type)
+	}
+	//tighter selectors are ranked higher
+	assert.Equal(t, "h3.title a", s[0].Selector)
+	assert.Equal(t, 2, s[0].Matches)
+	assert.Equal(t, []string{"Red Chair", "Blue Table"}, s[0].Samples)
+
+	//generated class names are less stable
+	s, err = suggest(doc, "http://example.com", []string{"10"})
+	assert.NoError(t, err)
+	for _, suggestion := range s {
+		if strings.Contains(suggestion.Selector, "css-") {
+			assert.True(t, suggestion.Stability < s[0].Stability)
+		}
+	}
+
+	//attribute values
+	s, err = suggest(doc, "http://example.com", []string{"/p/1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "href", s[0].Type)
+
+	_, err = suggest(doc, "http://example.com", []string{"Green Sofa"})
+	assert.Error(t, err)
+}
+
+func TestSpecificity(t *testing.T) {
+	assert.Equal(t, 1, specificity("a"))
+	assert.Equal(t, 12, specificity("h3.title a"))
+	assert.Equal(t, 111, specificity("#main div.product"))
+}
@@ -0,0 +1,32 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// parseTextMatch compiles field textMatch regular expression. It returns nil if expr is empty.
+func parseTextMatch(field, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: fmt.Sprintf("field %s: invalid textMatch: %s", field, err.Error())}
+	}
+	return re, nil
+}
+
+// matchText keeps elements of sel whose trimmed text matches re.
+func matchText(sel *goquery.Selection, re *regexp.Regexp) *goquery.Selection {
+	if re == nil {
+		return sel
+	}
+	return sel.FilterFunction(func(i int, s *goquery.Selection) bool {
+		return re.MatchString(strings.TrimSpace(s.Text()))
+	})
+}
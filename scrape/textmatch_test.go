@@ -0,0 +1,37 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchText(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="section"><h3>Overview</h3><p>Compact</p></div>
+	<div class="section"><h3> Specifications </h3><ul><li>2 kg</li><li>Steel</li></ul></div>
+	<div class="section"><h3>Specifications of accessories</h3><ul><li>Case</li></ul></div>`))
+	assert.NoError(t, err)
+	texts := func(sel *goquery.Selection) []string {
+		return sel.Map(func(i int, s *goquery.Selection) string { return strings.TrimSpace(s.Text()) })
+	}
+
+	re, err := parseTextMatch("specs", "^Specifications$")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Specifications"}, texts(matchText(doc.Find("h3"), re)))
+	steps, err := parseTraverse([]string{"next(ul)", "children(li)"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2 kg", "Steel"}, texts(traverse(matchText(doc.Find("h3"), re), steps)))
+
+	//selector predicates
+	assert.Equal(t, []string{"2 kg", "Steel", "Case"}, texts(doc.Find("div:has(h3:contains('specifications')) li")))
+	assert.Equal(t, []string{"2 kg", "Steel"}, texts(doc.Find(`div:has(h3:matches(^\s*Specifications\s*$)) li`)))
+
+	re, err = parseTextMatch("specs", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, matchText(doc.Find("h3"), re).Length())
+
+	_, err = parseTextMatch("specs", "(")
+	assert.Error(t, err)
+}
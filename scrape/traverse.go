@@ -0,0 +1,100 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// traverseStepRe matches traversal steps like "parent", "closest(tr)" or "find(td.value)".
+var traverseStepRe = regexp.MustCompile(`^([a-zA-Z]+)\s*(?:\((.*)\))?$`)
+
+// traverseStep moves from elements matched by field selector to related elements.
+type traverseStep struct {
+	op  string
+	arg string
+}
+
+// traverseOps lists available steps and whether they require a selector argument.
+var traverseOps = map[string]bool{
+	"parent":   false,
+	"closest":  true,
+	"next":     false,
+	"prev":     false,
+	"children": false,
+	"find":     true,
+	"filter":   true,
+}
+
+// parseTraverse parses field traversal steps.
+func parseTraverse(steps []string) ([]traverseStep, error) {
+	parsed := []traverseStep{}
+	for _, s := range steps {
+		m := traverseStepRe.FindStringSubmatch(strings.TrimSpace(s))
+		if m == nil {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("invalid traverse step %q", s)}
+		}
+		op := strings.ToLower(m[1])
+		requiresArg, ok := traverseOps[op]
+		if !ok {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("unknown traverse step %q", s)}
+		}
+		arg := strings.TrimSpace(m[2])
+		if requiresArg && arg == "" {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("traverse step %q requires selector", s)}
+		}
+		parsed = append(parsed, traverseStep{op: op, arg: shadowSelector(arg)})
+	}
+	return parsed, nil
+}
+
+// traverse applies steps to sel one after another.
+// next and prev steps with selector move to the nearest following or preceding sibling matching it.
+func traverse(sel *goquery.Selection, steps []traverseStep) *goquery.Selection {
+	for _, step := range steps {
+		switch step.op {
+		case "parent":
+			sel = sel.Parent()
+			if step.arg != "" {
+				sel = sel.Filter(step.arg)
+			}
+		case "closest":
+			sel = sel.Closest(step.arg)
+		case "next":
+			if step.arg == "" {
+				sel = sel.Next()
+			} else {
+				sel = nearest(sel, step.arg, (*goquery.Selection).NextAllFiltered)
+			}
+		case "prev":
+			if step.arg == "" {
+				sel = sel.Prev()
+			} else {
+				sel = nearest(sel, step.arg, (*goquery.Selection).PrevAllFiltered)
+			}
+		case "children":
+			if step.arg == "" {
+				sel = sel.Children()
+			} else {
+				sel = sel.ChildrenFiltered(step.arg)
+			}
+		case "find":
+			sel = sel.Find(step.arg)
+		case "filter":
+			sel = sel.Filter(step.arg)
+		}
+	}
+	return sel
+}
+
+// nearest returns the first sibling matching selector for every element of sel.
+func nearest(sel *goquery.Selection, selector string, siblings func(*goquery.Selection, string) *goquery.Selection) *goquery.Selection {
+	result := sel.Slice(0, 0)
+	sel.Each(func(i int, s *goquery.Selection) {
+		result = result.AddSelection(siblings(s, selector).First())
+	})
+	return result
+}
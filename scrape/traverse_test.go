@@ -0,0 +1,36 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraverse(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<table class="specs">
+	<tr><th>Brand</th><td>Acme</td></tr>
+	<tr><th>SKU</th><td class="note">new</td><td class="value">A-100</td></tr>
+	<tr class="last"><th>Weight</th><td>2 kg</td></tr>
+	</table>`))
+	assert.NoError(t, err)
+	text := func(selector string, steps ...string) string {
+		parsed, err := parseTraverse(steps)
+		assert.NoError(t, err, steps)
+		return strings.Join(traverse(doc.Find(selector), parsed).Map(func(i int, s *goquery.Selection) string { return s.Text() }), ",")
+	}
+	assert.Equal(t, "new", text(`th:contains("SKU")`, "next"))
+	assert.Equal(t, "A-100", text(`th:contains("SKU")`, "next(.value)"))
+	assert.Equal(t, "A-100", text(`th:contains("SKU")`, "closest(tr)", "find(td.value)"))
+	assert.Equal(t, "SKU", text(".value", "prev(th)"))
+	assert.Equal(t, "Weight2 kg", text(`th:contains("Weight")`, "parent"))
+	assert.Equal(t, "Weight", text("th", "parent(.last)", "children(th)"))
+	assert.Equal(t, "Acme,new,2 kg", text("th", "next", "filter(td)"))
+	assert.Equal(t, "A-100", text(`th`, "next (.value)"))
+
+	for _, bad := range [][]string{{"up"}, {"closest"}, {"find()"}, {"next(a"}} {
+		_, err := parseTraverse(bad)
+		assert.Error(t, err, bad)
+	}
+}
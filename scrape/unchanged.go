@@ -0,0 +1,242 @@
+package scrape
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"go.uber.org/zap"
+)
+
+// errUnchanged is returned for pages skipped as they haven't changed since the previous run of the payload.
+var errUnchanged = errors.New("Page is unchanged since the previous run")
+
+// pageState describes a page extracted by a run of the payload.
+type pageState struct {
+	//Hash is SHA-256 of the page content
+	Hash         string `json:"hash"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	//Next is the URL of the next page followed from the page
+	Next string `json:"next,omitempty"`
+	//Records are extracted from the page blocks in their order
+	Records []map[string]interface{} `json:"records"`
+	//blocks collect records of the page extracted by the current run by block number
+	blocks map[int]map[string]interface{}
+}
+
+// unchangedPages keeps states of pages extracted by the previous run and collects states of pages of the current one.
+// Only initial and paginated pages are tracked. Details pages are not fetched for unchanged pages,
+// their fields are kept in the records of the previous run.
+type unchangedPages struct {
+	mx       sync.Mutex
+	previous map[string]*pageState
+	current  map[string]*pageState
+	skipped  int
+}
+
+// pagesKey returns the key of page states of results id.
+func pagesKey(id string) string {
+	return "pages-" + id
+}
+
+// loadPages reads states of pages extracted by the previous run of results uid.
+// Every page is extracted if there has been no previous run.
+func (task *Task) loadPages(uid string) {
+	task.unchanged = &unchangedPages{previous: map[string]*pageState{}, current: map[string]*pageState{}}
+	data, err := task.storage.Read(storage.Record{Type: storage.BINARY, Key: pagesKey(uid)})
+	if err != nil || len(data) == 0 {
+		return
+	}
+	if err := json.Unmarshal(data, &task.unchanged.previous); err != nil {
+		task.log().Warn("Cannot read states of pages of the previous run. " + err.Error())
+	}
+}
+
+// savePages stores states of pages extracted by the current run for the next one.
+func (task *Task) savePages(uid string) error {
+	u := task.unchanged
+	if u == nil {
+		return nil
+	}
+	u.mx.Lock()
+	for _, state := range u.current {
+		if state.blocks == nil {
+			continue
+		}
+		nums := make([]int, 0, len(state.blocks))
+		for n := range state.blocks {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+		state.Records = make([]map[string]interface{}, len(nums))
+		for i, n := range nums {
+			state.Records[i] = state.blocks[n]
+		}
+	}
+	data, err := json.Marshal(u.current)
+	u.mx.Unlock()
+	if err != nil {
+		return err
+	}
+	return task.storage.Write(storage.Record{
+		Type:  storage.BINARY,
+		Key:   pagesKey(uid),
+		Value: data,
+	})
+}
+
+// tracked reports whether pages of scraper are compared with the previous run.
+func (u *unchangedPages) tracked(scraper *Scraper) bool {
+	return u != nil && !scraper.IsPath && (scraper.reqType == "initial" || scraper.reqType == "paginator")
+}
+
+// conditional adds validators of the page extracted by the previous run to req so the server may answer
+// 304 Not Modified. Chrome requests are not conditional, their pages are compared by content.
+func (u *unchangedPages) conditional(req fetch.Request, reqType string) fetch.Request {
+	if u == nil || req.Type == "chrome" || (reqType != "initial" && reqType != "paginator") {
+		return req
+	}
+	u.mx.Lock()
+	prev := u.previous[req.URL]
+	u.mx.Unlock()
+	if prev == nil || (prev.ETag == "" && prev.LastModified == "") {
+		return req
+	}
+	headers := make(map[string]string, len(req.Headers)+2)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	if prev.ETag != "" {
+		headers["If-None-Match"] = prev.ETag
+	}
+	if prev.LastModified != "" {
+		headers["If-Modified-Since"] = prev.LastModified
+	}
+	req.Headers = headers
+	return req
+}
+
+// notModified returns the previous state of the page url if err reports it hasn't been modified.
+func (u *unchangedPages) notModified(url string, err error) *pageState {
+	if e, ok := err.(errs.FetchError); !ok || e.Code != http.StatusNotModified || u == nil {
+		return nil
+	}
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	return u.previous[url]
+}
+
+// hasher returns r hashing the page content as it is read along with the hash.
+func (u *unchangedPages) hasher(scraper *Scraper, r io.Reader) (io.Reader, hash.Hash) {
+	if !u.tracked(scraper) {
+		return r, nil
+	}
+	h := sha256.New()
+	return io.TeeReader(r, h), h
+}
+
+// sameContent returns the previous state of the page url if its content hash is equal to h.
+func (u *unchangedPages) sameContent(url string, h hash.Hash) *pageState {
+	if u == nil || h == nil {
+		return nil
+	}
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	if prev := u.previous[url]; prev != nil && prev.Hash == fmt.Sprintf("%x", h.Sum(nil)) {
+		return prev
+	}
+	return nil
+}
+
+// visit starts collecting the state of the page url being extracted.
+func (u *unchangedPages) visit(url string, h hash.Hash, header http.Header, next string) {
+	if u == nil || h == nil {
+		return
+	}
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	u.current[url] = &pageState{
+		Hash:         fmt.Sprintf("%x", h.Sum(nil)),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Next:         next,
+		blocks:       map[int]map[string]interface{}{},
+	}
+}
+
+// keep adds record extracted from block to the state of its page.
+func (u *unchangedPages) keep(block *blockStruct, record map[string]interface{}) {
+	if u == nil || block.pageURL == "" {
+		return
+	}
+	n, err := strconv.Atoi(block.key[strings.LastIndex(block.key, "-")+1:])
+	if err != nil {
+		return
+	}
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	if state := u.current[block.pageURL]; state != nil {
+		state.blocks[n] = record
+	}
+}
+
+// carry keeps the previous state of unchanged page url for the next run.
+func (u *unchangedPages) carry(url string, prev *pageState, next string) {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	state := *prev
+	state.Next = next
+	u.current[url] = &state
+	u.skipped++
+}
+
+// count returns the number of unchanged pages.
+func (u *unchangedPages) count() int {
+	if u == nil {
+		return 0
+	}
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	return u.skipped
+}
+
+// replayPage stores records of unchanged page url extracted by the previous run as records of the page of tw
+// and follows the next page. It returns errUnchanged so the page is counted as unchanged.
+func (task *Task) replayPage(tw *taskWorker, url string, prev *pageState, next string) error {
+	task.log().Info(errUnchanged.Error(), zap.String("URL", url))
+	task.unchanged.carry(url, prev, next)
+	task.followPage(tw, next)
+	task.mx.Lock()
+	defer task.mx.Unlock()
+	task.Parsed = true
+	pageNum := tw.currentPageNum
+	for i, record := range prev.Records {
+		blockNum := i
+		if tw.useBlockCounter {
+			blockNum = len(tw.keys[pageNum])
+		}
+		output, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s-%d-%d", tw.UID, pageNum, blockNum)
+		if err := task.storage.Write(storage.Record{Type: storage.INTERMEDIATE, Key: key, Value: output}); err != nil {
+			return fmt.Errorf("Failed to write %s. %s", key, err.Error())
+		}
+		tw.keys[pageNum] = append(tw.keys[pageNum], blockNum)
+		task.stats.record(record)
+	}
+	return errUnchanged
+}
@@ -0,0 +1,80 @@
+package scrape
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnchangedPages(t *testing.T) {
+	u := &unchangedPages{previous: map[string]*pageState{}, current: map[string]*pageState{}}
+	initial := &Scraper{reqType: "initial"}
+	url := "http://example.com/list"
+
+	//the first run extracts the page
+	r, h := u.hasher(initial, strings.NewReader("<html>list</html>"))
+	_, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Nil(t, u.sameContent(url, h))
+	header := http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Wed, 21 Oct 2015 07:28:00 GMT"}}
+	u.visit(url, h, header, "http://example.com/list?page=2")
+	u.keep(&blockStruct{key: "1-0-1", pageURL: url}, map[string]interface{}{"n": "b"})
+	u.keep(&blockStruct{key: "1-0-0", pageURL: url}, map[string]interface{}{"n": "a"})
+	//blocks of other pages are not kept
+	u.keep(&blockStruct{key: "1-0-2"}, map[string]interface{}{"n": "c"})
+	state := u.current[url]
+	assert.Len(t, state.blocks, 2)
+	assert.Equal(t, "a", state.blocks[0]["n"])
+	assert.Equal(t, `"v1"`, state.ETag)
+	state.Records = []map[string]interface{}{state.blocks[0], state.blocks[1]}
+
+	//the next run
+	u = &unchangedPages{previous: map[string]*pageState{url: state}, current: map[string]*pageState{}}
+	req := u.conditional(fetch.Request{URL: url, Headers: map[string]string{"Accept": "text/html"}}, "initial")
+	assert.Equal(t, `"v1"`, req.Headers["If-None-Match"])
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", req.Headers["If-Modified-Since"])
+	assert.Equal(t, "text/html", req.Headers["Accept"])
+	assert.Empty(t, u.conditional(fetch.Request{URL: url, Type: "chrome"}, "initial").Headers)
+	assert.Empty(t, u.conditional(fetch.Request{URL: url}, "details").Headers)
+	assert.Empty(t, u.conditional(fetch.Request{URL: "http://example.com/new"}, "initial").Headers)
+
+	assert.Equal(t, state, u.notModified(url, errs.FetchError{URL: url, Code: http.StatusNotModified}))
+	assert.Nil(t, u.notModified(url, errs.FetchError{URL: url, Code: http.StatusNotFound}))
+
+	r, h = u.hasher(initial, strings.NewReader("<html>list</html>"))
+	ioutil.ReadAll(r)
+	assert.Equal(t, state, u.sameContent(url, h))
+	r, h = u.hasher(initial, strings.NewReader("<html>changed list</html>"))
+	ioutil.ReadAll(r)
+	assert.Nil(t, u.sameContent(url, h))
+
+	u.carry(url, state, "")
+	assert.Equal(t, 1, u.count())
+	assert.Empty(t, u.current[url].Next)
+	assert.Len(t, u.current[url].Records, 2)
+
+	//details pages and disabled mode are not tracked
+	_, h = u.hasher(&Scraper{reqType: "details"}, strings.NewReader(""))
+	assert.Nil(t, h)
+	var off *unchangedPages
+	assert.False(t, off.tracked(initial))
+	assert.Nil(t, off.notModified(url, errs.FetchError{Code: http.StatusNotModified}))
+	assert.Equal(t, 0, off.count())
+}
+
+func TestJobStats_Unchanged(t *testing.T) {
+	s := newJobStats()
+	s.page(nil)
+	s.page(errUnchanged)
+	s.page(errUnchanged)
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	assert.Equal(t, 1, s.pagesOK)
+	assert.Equal(t, 2, s.unchanged)
+	assert.Empty(t, s.failed)
+}
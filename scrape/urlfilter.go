@@ -0,0 +1,109 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+)
+
+// errExcludedURL is reported for seed URLs excluded by URL patterns.
+var errExcludedURL = errors.New("URL is excluded by URL patterns")
+
+// urlFilter gates seed URLs, URLs of paginated and details pages and URLs they are redirected to
+// by payload's Include and Exclude patterns.
+type urlFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newURLFilter compiles include and exclude patterns. Patterns enclosed in slashes like "/page=\d+/"
+// are regular expressions searched anywhere in URL. Other patterns are globs matching the whole URL
+// where "*" matches any sequence of characters and "?" matches any single character.
+// It returns nil if there are no patterns.
+func newURLFilter(include, exclude []string) (*urlFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	f := &urlFilter{}
+	var err error
+	if f.include, err = compilePatterns("include", include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compilePatterns("exclude", exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func compilePatterns(name string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := globToRegexp(p)
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			expr = p[1 : len(p)-1]
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("%s pattern %q: %s", name, p, err.Error())}
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// globToRegexp converts glob pattern to anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// allowed reports whether u matches one of include patterns if there are any and none of exclude patterns.
+func (f *urlFilter) allowed(u string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(u) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRedirect returns an error if the page fetched as content has been redirected to a URL which is not allowed.
+func (f *urlFilter) checkRedirect(content io.ReadCloser) error {
+	final := fetch.ResponseHeader(content).Get(fetch.FinalURLHeader)
+	if final == "" || f.allowed(final) {
+		return nil
+	}
+	return errs.StatusError{
+		Code: http.StatusForbidden,
+		Err:  fmt.Errorf("Page is redirected to %s excluded by URL patterns", final),
+	}
+}
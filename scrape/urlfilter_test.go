@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLFilter(t *testing.T) {
+	f, err := newURLFilter(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, f)
+	assert.True(t, f.allowed("http://example.com/calendar/2019"))
+
+	f, err = newURLFilter(
+		[]string{"http://example.com/catalog/*", `/[?&]page=\d+$/`},
+		[]string{"*/calendar/*", "*sessionid=*", "*?sort=?"},
+	)
+	assert.NoError(t, err)
+	assert.True(t, f.allowed("http://example.com/catalog/books/1"))
+	assert.True(t, f.allowed("http://example.com/list?page=2"))
+	assert.False(t, f.allowed("http://example.com/about"))
+	assert.False(t, f.allowed("http://example.com/catalog/calendar/2019"))
+	assert.False(t, f.allowed("http://example.com/catalog/books?sessionid=42"))
+	assert.False(t, f.allowed("http://example.com/catalog/books?sort=a"))
+	assert.True(t, f.allowed("http://example.com/catalog/books?sort=az"))
+	assert.True(t, f.allowed("http://example.com/catalog/a.b"))
+
+	f, err = newURLFilter(nil, []string{"/calendar/"})
+	assert.NoError(t, err)
+	assert.False(t, f.allowed("http://example.com/calendar/2019"))
+	assert.True(t, f.allowed("http://example.com/catalog"))
+
+	_, err = newURLFilter([]string{"/[/"}, nil)
+	assert.IsType(t, errs.BadPayload{}, err)
+}
+
+func TestURLFilter_checkRedirect(t *testing.T) {
+	page := func(final string) io.ReadCloser {
+		h := http.Header{}
+		if final != "" {
+			h.Set(fetch.FinalURLHeader, final)
+		}
+		return fetch.WithResponseHeader(ioutil.NopCloser(strings.NewReader("")), h)
+	}
+	var f *urlFilter
+	assert.NoError(t, f.checkRedirect(page("http://example.com/login")))
+
+	f, err := newURLFilter([]string{"http://example.com/catalog/*"}, []string{"*/login*"})
+	assert.NoError(t, err)
+	assert.NoError(t, f.checkRedirect(page("")))
+	assert.NoError(t, f.checkRedirect(page("http://example.com/catalog/books")))
+	err = f.checkRedirect(page("http://example.com/login?next=/catalog/books"))
+	assert.Equal(t, http.StatusForbidden, err.(errs.StatusError).Code)
+	assert.Error(t, f.checkRedirect(page("http://example.com/about")))
+}
@@ -0,0 +1,375 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+)
+
+// maxURLListSize is the maximum size of URL list in bytes.
+const maxURLListSize = 32 << 20
+
+// URLList is a list of seed URLs of batch payload read from uploaded file, S3 object or remote URL.
+type URLList struct {
+	//Data is the content of the list. It is filled with the file uploaded along with the payload.
+	Data string `json:"data,omitempty"`
+	//Location is http(s) URL returning the list or S3 object written as "s3://bucket/key".
+	Location string `json:"location,omitempty"`
+	//Format is "txt" (a URL per line), "csv" (a header row followed by rows) or "json" (array of URLs or objects).
+	//It is detected by file extension, Content-Type of the response or content if omitted.
+	Format string `json:"format,omitempty"`
+	//URLColumn is the CSV column or JSON object key holding URLs. "url" column or the first column is used by default.
+	URLColumn string `json:"urlColumn,omitempty"`
+	//Vars map variable names to columns holding per-URL values, f.e. product IDs.
+	//Values are substituted for {{variables}} in the URL and are added to every record extracted from it.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// seed is a URL of the list along with its variables.
+type seed struct {
+	URL  string
+	Vars map[string]string
+}
+
+// varNames returns sorted names of per-URL variables.
+func (l *URLList) varNames() []string {
+	if l == nil {
+		return nil
+	}
+	names := make([]string, 0, len(l.Vars))
+	for name := range l.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// load reads the list from Data or Location.
+func (l *URLList) load() ([]seed, error) {
+	data, contentType := []byte(l.Data), ""
+	switch {
+	case l.Data != "":
+	case l.Location != "":
+		var err error
+		data, contentType, err = readLocation(l.Location)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load URL list %s. %s", l.Location, err.Error())
+		}
+	default:
+		return nil, errs.BadPayload{ErrText: "URL list has neither data nor location"}
+	}
+	format := strings.ToLower(l.Format)
+	if format == "" {
+		format = listFormat(l.Location, contentType, data)
+	}
+	var (
+		rows []map[string]string
+		cols []string
+		err  error
+	)
+	switch format {
+	case "txt", "text":
+		return l.seeds(textRows(data), []string{"url"})
+	case "csv":
+		rows, cols, err = csvRows(data)
+	case "json":
+		rows, cols, err = jsonRows(data)
+	default:
+		return nil, errs.BadPayload{ErrText: "unknown URL list format " + l.Format}
+	}
+	if err != nil {
+		return nil, errs.BadPayload{ErrText: "failed to parse URL list. " + err.Error()}
+	}
+	return l.seeds(rows, cols)
+}
+
+// seeds maps columns of rows to seed URLs and their variables. Rows without URL are skipped.
+func (l *URLList) seeds(rows []map[string]string, cols []string) ([]seed, error) {
+	urlColumn := l.URLColumn
+	if urlColumn == "" && len(cols) > 0 {
+		urlColumn = cols[0]
+		for _, c := range cols {
+			if strings.EqualFold(c, "url") {
+				urlColumn = c
+				break
+			}
+		}
+	}
+	seeds := []seed{}
+	for _, row := range rows {
+		u, ok := row[urlColumn]
+		if !ok && l.URLColumn != "" && len(cols) > 0 {
+			return nil, errs.BadPayload{ErrText: fmt.Sprintf("no %q column in URL list", l.URLColumn)}
+		}
+		s := seed{Vars: map[string]string{}}
+		for name, col := range l.Vars {
+			s.Vars[name] = row[col]
+		}
+		s.URL = strings.TrimSpace(expandURL(u, func(part string, escape func(string) string) string {
+			return varRe.ReplaceAllStringFunc(part, func(m string) string {
+				if v, ok := s.Vars[varRe.FindStringSubmatch(m)[1]]; ok {
+					return escape(v)
+				}
+				return m
+			})
+		}))
+		if s.URL != "" {
+			seeds = append(seeds, s)
+		}
+	}
+	return seeds, nil
+}
+
+// listFormat detects format of the list.
+func listFormat(location, contentType string, data []byte) string {
+	if u, err := url.Parse(location); err == nil && location != "" {
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".csv":
+			return "csv"
+		case ".json":
+			return "json"
+		case ".txt":
+			return "txt"
+		}
+	}
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return "csv"
+	case strings.Contains(contentType, "json"):
+		return "json"
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return "json"
+	}
+	return "txt"
+}
+
+// textRows returns a row for every non-empty line. Lines starting with # are comments.
+func textRows(data []byte) []map[string]string {
+	rows := []map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), maxURLListSize)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rows = append(rows, map[string]string{"url": line})
+	}
+	return rows
+}
+
+// csvRows reads CSV with a header row. Semicolon and tab separated files are detected by the header.
+func csvRows(data []byte) ([]map[string]string, []string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+	for _, sep := range []rune{',', ';', '\t'} {
+		if bytes.ContainsRune(header, sep) {
+			r.Comma = sep
+			break
+		}
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+	cols := records[0]
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(cols))
+		for i, c := range cols {
+			if i < len(rec) {
+				row[c] = strings.TrimSpace(rec[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, cols, nil
+}
+
+// jsonRows reads JSON array of URLs or objects. Values of objects are converted to strings.
+func jsonRows(data []byte) ([]map[string]string, []string, error) {
+	var items []interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, err
+	}
+	rows := make([]map[string]string, 0, len(items))
+	cols := []string{}
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			rows = append(rows, map[string]string{"url": v})
+			if len(cols) == 0 {
+				cols = append(cols, "url")
+			}
+		case map[string]interface{}:
+			row := make(map[string]string, len(v))
+			for k, val := range v {
+				if val != nil {
+					row[k] = fmt.Sprint(val)
+				}
+			}
+			rows = append(rows, row)
+			if len(cols) == 0 {
+				for k := range v {
+					cols = append(cols, k)
+				}
+				sort.Strings(cols)
+			}
+		}
+	}
+	return rows, cols, nil
+}
+
+// readLocation downloads the list from http(s) URL or S3 object.
+func readLocation(location string) ([]byte, string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, "", err
+	}
+	var req *http.Request
+	switch u.Scheme {
+	case "http", "https":
+		req, err = http.NewRequest("GET", location, nil)
+	case "s3":
+		req, err = newS3Request(u.Host, strings.TrimPrefix(u.Path, "/"))
+	default:
+		return nil, "", errs.BadPayload{ErrText: "URL list location should be http(s) URL or s3://bucket/key"}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("%s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxURLListSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxURLListSize {
+		return nil, "", fmt.Errorf("URL list exceeds %d bytes", maxURLListSize)
+	}
+	return data, strings.ToLower(resp.Header.Get("Content-Type")), nil
+}
+
+// newS3Request creates request getting S3 object. Objects are requested with path-style URL from S3_ENDPOINT,
+// f.e. DigitalOcean Spaces or MinIO, or from AWS virtual-hosted bucket endpoint if S3_ENDPOINT is empty.
+// Request is signed with S3_ACCESS_KEY and S3_SECRET_KEY. Anonymous request is sent for public objects if they are empty.
+func newS3Request(bucket, key string) (*http.Request, error) {
+	if bucket == "" || key == "" {
+		return nil, errs.BadPayload{ErrText: "S3 location should be s3://bucket/key"}
+	}
+	region := viper.GetString("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	var u string
+	if endpoint := strings.TrimRight(viper.GetString("S3_ENDPOINT"), "/"); endpoint != "" {
+		u = endpoint + "/" + bucket + "/" + s3EscapePath(key)
+	} else {
+		u = "https://" + bucket + ".s3." + region + ".amazonaws.com/" + s3EscapePath(key)
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	accessKey, secretKey := viper.GetString("S3_ACCESS_KEY"), viper.GetString("S3_SECRET_KEY")
+	if accessKey != "" && secretKey != "" {
+		signS3Request(req, accessKey, secretKey, region, time.Now())
+	}
+	return req, nil
+}
+
+// s3EscapePath escapes every segment of object key.
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = strings.Replace(url.PathEscape(s), "+", "%2B", -1)
+	}
+	return strings.Join(segments, "/")
+}
+
+// emptyPayloadHash is hex encoded SHA-256 of empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3Request signs GET request with AWS Signature Version 4. Host and all headers of the request are signed.
+func signS3Request(req *http.Request, accessKey, secretKey, region string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, s := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,147 @@
+package scrape
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/utils"
+)
+
+// varRe matches {{variable}} placeholders
+var varRe = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// secretVarPrefix starts names of variables holding secrets of the credentials vault, f.e. {{credentials.password}}.
+// They are only allowed in parts of the request sent to the site: form data, body, headers and login form fields.
+// URLs and const field values end up in results, reports and logs.
+const secretVarPrefix = "credentials."
+
+// secret variables handling in a part of the payload
+const (
+	//secretRefused parts may not refer to secrets
+	secretRefused = iota
+	//secretKept parts keep secret variables until they are fetched from the credentials domain. See Credentials.attach.
+	secretKept
+	//secretExpanded parts get secrets substituted at once
+	secretExpanded
+)
+
+// ExpandVars substitutes {{variables}} in request URL, seed URLs, form data, request body and headers,
+// login form field values and const field values with payload Vars,
+// so one payload can serve many searches or regions.
+// Values are escaped in URL and form data. See expandURL.
+// An error is returned if a variable is not defined.
+func (p Payload) ExpandVars() (Payload, error) {
+	return p.expandVars(nil)
+}
+
+// expandVars expands payload variables and secrets. Secrets are substituted in login form fields only,
+// request parts keep them to be expanded per fetch. See ExpandVars.
+func (p Payload) expandVars(secrets map[string]string) (Payload, error) {
+	missing := map[string]bool{}
+	misplaced := map[string]bool{}
+	expand := func(s string, escape func(string) string, secret int) string {
+		return varRe.ReplaceAllStringFunc(s, func(m string) string {
+			name := varRe.FindStringSubmatch(m)[1]
+			if !strings.HasPrefix(name, secretVarPrefix) {
+				v, ok := p.Vars[name]
+				if !ok {
+					missing[name] = true
+					return m
+				}
+				return escape(v)
+			}
+			if secret == secretRefused {
+				misplaced[name] = true
+				return m
+			}
+			v, ok := secrets[name]
+			if !ok {
+				missing[name] = true
+				return m
+			}
+			if secret == secretKept {
+				return m
+			}
+			return escape(v)
+		})
+	}
+	raw := func(s string) string { return s }
+	urlPart := func(s string, escape func(string) string) string {
+		return expand(s, escape, secretRefused)
+	}
+
+	p.Request.URL = expandURL(p.Request.URL, urlPart)
+	if len(p.URLs) > 0 {
+		urls := make([]Seed, len(p.URLs))
+		for i, s := range p.URLs {
+			s.URL = expandURL(s.URL, urlPart)
+			s.FormData = expand(s.FormData, url.QueryEscape, secretKept)
+			s.Body = expand(s.Body, raw, secretKept)
+			urls[i] = s
+		}
+		p.URLs = urls
+	}
+	p.Request.FormData = expand(p.Request.FormData, url.QueryEscape, secretKept)
+	p.Request.Body = expand(p.Request.Body, raw, secretKept)
+	if len(p.Request.Headers) > 0 {
+		headers := make(map[string]string, len(p.Request.Headers))
+		for k, v := range p.Request.Headers {
+			headers[k] = expand(v, raw, secretKept)
+		}
+		p.Request.Headers = headers
+	}
+	if p.Login != nil {
+		login := *p.Login
+		login.Form.Fields = make([]fetch.FormField, len(p.Login.Form.Fields))
+		for i, f := range p.Login.Form.Fields {
+			f.Value = expand(f.Value, raw, secretExpanded)
+			login.Form.Fields[i] = f
+		}
+		p.Login = &login
+	}
+	fields := make([]Field, len(p.Fields))
+	for i, f := range p.Fields {
+		if value, ok := f.Extractor.Params["value"].(string); ok && utils.ArrayContains(f.Extractor.Types, "const") {
+			params := make(map[string]interface{}, len(f.Extractor.Params))
+			for k, v := range f.Extractor.Params {
+				params[k] = v
+			}
+			params["value"] = expand(value, raw, secretRefused)
+			f.Extractor.Params = params
+		}
+		fields[i] = f
+	}
+	p.Fields = fields
+	if len(misplaced) > 0 {
+		return p, errs.BadPayload{ErrText: fmt.Sprintf("credentials variables are only allowed in form data, body, headers and login form: %s", sortedNames(misplaced))}
+	}
+	if len(missing) > 0 {
+		return p, errs.BadPayload{ErrText: fmt.Sprintf("undefined payload variables: %s", sortedNames(missing))}
+	}
+	p.Vars = nil
+	return p, nil
+}
+
+// expandURL expands variables of URL template s with expand, escaping values for the URL component they are in.
+// Values are escaped with url.QueryEscape after "?", so they can't add query parameters, and with url.PathEscape before it.
+func expandURL(s string, expand func(s string, escape func(string) string) string) string {
+	if i := strings.Index(s, "?"); i >= 0 {
+		return expand(s[:i], url.PathEscape) + expand(s[i:], url.QueryEscape)
+	}
+	return expand(s, url.PathEscape)
+}
+
+// sortedNames returns comma separated sorted names.
+func sortedNames(names map[string]bool) string {
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
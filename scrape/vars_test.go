@@ -0,0 +1,43 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandVars(t *testing.T) {
+	p := Payload{
+		Name: "search",
+		Request: fetch.Request{
+			URL:      "http://127.0.0.1:12345/{{category}}/search?q={{query}}&zip={{ zip }}",
+			FormData: "q={{query}}",
+		},
+		Fields: []Field{
+			{Name: "Region", Selector: "h1", Extractor: Extractor{
+				Types:  []string{"const"},
+				Params: map[string]interface{}{"value": "zip {{zip}}"},
+			}},
+			{Name: "Title", Selector: "h1", Extractor: Extractor{Types: []string{"text"}}},
+		},
+		Vars: map[string]string{"category": "tv & audio", "query": "gaming laptops", "zip": "10001&admin=1"},
+	}
+	expanded, err := p.ExpandVars()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:12345/tv%20&%20audio/search?q=gaming+laptops&zip=10001%26admin%3D1", expanded.Request.URL)
+	assert.Equal(t, "q=gaming+laptops", expanded.Request.FormData)
+	assert.Equal(t, "zip 10001&admin=1", expanded.Fields[0].Extractor.Params["value"])
+	assert.Nil(t, expanded.Vars)
+	//original payload is not changed
+	assert.Equal(t, "zip {{zip}}", p.Fields[0].Extractor.Params["value"])
+
+	p.Vars = map[string]string{"category": "tv", "query": "laptops"}
+	_, err = p.ExpandVars()
+	assert.EqualError(t, err, "undefined payload variables: zip")
+
+	//payload without variables
+	expanded, err = flatPayload.ExpandVars()
+	assert.NoError(t, err)
+	assert.Equal(t, flatPayload.Request, expanded.Request)
+}
@@ -0,0 +1,173 @@
+package scrape
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+)
+
+// warcVersion is the version of WARC format written.
+const warcVersion = "WARC/1.1"
+
+// warcField is a named field of WARC record header. Fields are written in the order they are added.
+type warcField struct {
+	name, value string
+}
+
+// warcWriter writes request and resource records of fetched pages to a WARC file.
+// Every record is compressed as a separate gzip member, so the file may be read by standard web-archive tools
+// and records may be accessed without decompressing the whole file.
+type warcWriter struct {
+	mx         sync.Mutex
+	w          io.Writer
+	closer     io.Closer
+	warcinfoID string
+	now        func() time.Time
+}
+
+// newWARCWriter starts WARC file named filename written to w with a warcinfo record.
+func newWARCWriter(w io.Writer, filename string) (*warcWriter, error) {
+	ww := &warcWriter{w: w, warcinfoID: warcRecordID(), now: time.Now}
+	info := "software: Dataflow Kit\r\n" +
+		"format: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+	err := ww.writeRecord([]warcField{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", ww.warcinfoID},
+		{"WARC-Date", ww.date()},
+		{"WARC-Filename", filename},
+		{"Content-Type", "application/warc-fields"},
+	}, []byte(info))
+	if err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// createWARC creates gzipped WARC file for results ID uid in RESULTS_DIR.
+func createWARC(uid string) (*warcWriter, string, error) {
+	resultPath := viper.GetString("RESULTS_DIR")
+	if _, err := os.Stat(resultPath); os.IsNotExist(err) {
+		os.Mkdir(resultPath, 0700)
+	}
+	filename := uid + "_" + time.Now().Format("2006-01-02_15:04") + ".warc.gz"
+	f, err := os.OpenFile(path.Join(resultPath, filename), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return nil, "", err
+	}
+	ww, err := newWARCWriter(f, filename)
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	ww.closer = f
+	return ww, f.Name(), nil
+}
+
+// Close closes WARC file.
+func (ww *warcWriter) Close() error {
+	if ww == nil || ww.closer == nil {
+		return nil
+	}
+	return ww.closer.Close()
+}
+
+// writeExchange writes a request record of req and a resource record with content fetched by it.
+// Fetch service passes content of successful responses only without their status line and most of headers,
+// so content is archived as a resource instead of a made up HTTP response. Its Content-Type is detected from content.
+func (ww *warcWriter) writeExchange(req fetch.Request, content []byte) error {
+	if ww == nil {
+		return nil
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return err
+	}
+	var request bytes.Buffer
+	fmt.Fprintf(&request, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.HTTPMethod(), u.RequestURI(), u.Host)
+	if body, contentType := req.RequestBody(); body != "" {
+		fmt.Fprintf(&request, "Content-Type: %s\r\nContent-Length: %d\r\n\r\n%s", contentType, len(body), body)
+	} else {
+		request.WriteString("\r\n")
+	}
+
+	ww.mx.Lock()
+	defer ww.mx.Unlock()
+	date := ww.date()
+	resourceID, requestID := warcRecordID(), warcRecordID()
+	err = ww.writeRecord([]warcField{
+		{"WARC-Type", "resource"},
+		{"WARC-Record-ID", resourceID},
+		{"WARC-Warcinfo-ID", ww.warcinfoID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", req.URL},
+		{"Content-Type", http.DetectContentType(content)},
+	}, content)
+	if err != nil {
+		return err
+	}
+	return ww.writeRecord([]warcField{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", requestID},
+		{"WARC-Warcinfo-ID", ww.warcinfoID},
+		{"WARC-Concurrent-To", resourceID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", req.URL},
+		{"Content-Type", "application/http;msgtype=request"},
+	}, request.Bytes())
+}
+
+// writeRecord writes WARC record with header fields and block as a separate gzip member.
+// WARC-Block-Digest and Content-Length fields are added.
+func (ww *warcWriter) writeRecord(fields []warcField, block []byte) error {
+	gw := gzip.NewWriter(ww.w)
+	var b bytes.Buffer
+	b.WriteString(warcVersion + "\r\n")
+	fields = append(fields,
+		warcField{"WARC-Block-Digest", warcDigest(block)},
+		warcField{"Content-Length", strconv.Itoa(len(block))},
+	)
+	for _, f := range fields {
+		b.WriteString(f.name + ": " + f.value + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.Write(block)
+	b.WriteString("\r\n\r\n")
+	if _, err := gw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func (ww *warcWriter) date() string {
+	return ww.now().UTC().Format(time.RFC3339)
+}
+
+// warcDigest returns base32 encoded SHA-1 digest of data used by WARC digest fields.
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// warcRecordID returns a random UUID URI identifying WARC record.
+func warcRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
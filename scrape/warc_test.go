@@ -0,0 +1,67 @@
+package scrape
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWARCWriter(t *testing.T) {
+	var buf bytes.Buffer
+	ww, err := newWARCWriter(&buf, "test.warc.gz")
+	assert.NoError(t, err)
+	ww.now = func() time.Time { return time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC) }
+	content := []byte("<html><body>Hello</body></html>")
+	err = ww.writeExchange(fetch.Request{URL: "http://example.com/books?page=2"}, content)
+	assert.NoError(t, err)
+	err = ww.writeExchange(fetch.Request{URL: "http://example.com/search", Method: "post", FormData: "q=go"}, content)
+	assert.NoError(t, err)
+	assert.NoError(t, ww.Close())
+	assert.Error(t, ww.writeExchange(fetch.Request{URL: "%"}, content))
+
+	//every record is a separate gzip member
+	members := 0
+	br := bytes.NewReader(buf.Bytes())
+	r, err := gzip.NewReader(br)
+	assert.NoError(t, err)
+	var all bytes.Buffer
+	for {
+		r.Multistream(false)
+		data, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		all.Write(data)
+		members++
+		if err := r.Reset(br); err != nil {
+			break
+		}
+	}
+	records := strings.Split(all.String(), "WARC/1.1\r\n")[1:]
+	assert.Equal(t, 5, members)
+	assert.Len(t, records, 5)
+	assert.Contains(t, records[0], "WARC-Type: warcinfo\r\n")
+	assert.Contains(t, records[0], "WARC-Filename: test.warc.gz\r\n")
+
+	resource := records[1]
+	assert.Contains(t, resource, "WARC-Type: resource\r\n")
+	assert.Contains(t, resource, "WARC-Target-URI: http://example.com/books?page=2\r\n")
+	assert.Contains(t, resource, "WARC-Date: 2019-01-02T03:04:05Z\r\n")
+	assert.Contains(t, resource, "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Contains(t, resource, "Content-Length: 31\r\n\r\n<html>")
+	assert.NotContains(t, resource, "HTTP/1.1 200 OK", "status line is not made up")
+	assert.True(t, strings.HasSuffix(resource, "</html>\r\n\r\n"))
+
+	request := records[2]
+	assert.Contains(t, request, "WARC-Type: request\r\n")
+	assert.Contains(t, request, "GET /books?page=2 HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	id := strings.SplitN(strings.SplitN(resource, "WARC-Record-ID: ", 2)[1], "\r\n", 2)[0]
+	assert.Contains(t, request, "WARC-Concurrent-To: "+id+"\r\n")
+
+	assert.Contains(t, records[4], "POST /search HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 4\r\n\r\nq=go")
+	assert.Contains(t, records[3], "WARC-Block-Digest: sha1:")
+}
@@ -0,0 +1,342 @@
+package scrape
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/slotix/dataflowkit/storage"
+	"github.com/slotix/dataflowkit/utils"
+	"github.com/spf13/viper"
+)
+
+// Watch periodically extracts a single value from a web page and keeps its time series,
+// f.e. price or stock status of a product. It is a lightweight alternative to scheduled payloads.
+type Watch struct {
+	Name    string        `json:"name"`
+	Request fetch.Request `json:"request"`
+	//Selector of the watched element. The first matching element is used.
+	Selector string `json:"selector"`
+	//Attr is the name of the attribute to extract. Text of the element is extracted if it is empty.
+	Attr string `json:"attr,omitempty"`
+	//Interval between checks in seconds.
+	Interval int `json:"interval"`
+}
+
+// WatchInfo describes a saved watch along with its latest value.
+type WatchInfo struct {
+	Watch
+	LastCheck time.Time `json:"lastCheck"`
+	LastValue string    `json:"lastValue,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// WatchPoint is a single check of a watch.
+type WatchPoint struct {
+	Time  time.Time `json:"time"`
+	Value string    `json:"value,omitempty"`
+	//Error is set if the value could not be extracted.
+	Error string `json:"error,omitempty"`
+}
+
+// WatchRequest refers to a saved watch.
+type WatchRequest struct {
+	Name string `json:"name"`
+	//Limit is the number of the latest points returned. All stored points are returned if it is 0.
+	Limit int `json:"limit,omitempty"`
+}
+
+// WatchSeries is a time series of watched value ordered by time.
+type WatchSeries struct {
+	WatchInfo
+	Points []WatchPoint `json:"points"`
+}
+
+// watchIndexKey is a key of the record listing all watches.
+const watchIndexKey = "watches"
+
+// watchesMx serializes watch index and series updates of the process. Replicas sharing the storage
+// update them holding the lease of watchIndexKey.
+var watchesMx sync.Mutex
+
+func watchKey(name string) string {
+	return "watch-" + hex.EncodeToString(utils.GenerateMD5([]byte(name)))
+}
+
+// Due reports whether watch should be checked at t.
+func (i WatchInfo) Due(t time.Time) bool {
+	return !t.Before(i.LastCheck.Add(time.Duration(i.Interval) * time.Second))
+}
+
+// Watches keeps watches and their time series in the storage.
+type Watches struct {
+	store storage.Store
+}
+
+// NewWatches opens watches in the storage specified by STORAGE_TYPE.
+// Watches should be closed after use.
+func NewWatches() *Watches {
+	return &Watches{store: storage.NewStore(viper.GetString("STORAGE_TYPE"))}
+}
+
+// Close closes storage connection.
+func (w *Watches) Close() {
+	w.store.Close()
+}
+
+func (w *Watches) index() (map[string]*WatchInfo, error) {
+	index := map[string]*WatchInfo{}
+	rec := storage.Record{Type: storage.BINARY, Key: watchIndexKey}
+	if !w.store.IsExists(rec) {
+		return index, nil
+	}
+	data, err := w.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// lock serializes index and series updates and returns the function releasing the lock.
+func (w *Watches) lock() (func(), error) {
+	watchesMx.Lock()
+	unlock, err := storage.Lock(w.store, watchIndexKey)
+	if err != nil {
+		watchesMx.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		watchesMx.Unlock()
+	}, nil
+}
+
+func (w *Watches) writeIndex(index map[string]*WatchInfo) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return w.store.Write(storage.Record{Type: storage.BINARY, Key: watchIndexKey, Value: data})
+}
+
+func errWatchNotFound(name string) error {
+	return errs.StatusError{
+		Code: http.StatusNotFound,
+		Err:  fmt.Errorf("watch %q not found", name),
+	}
+}
+
+// Save creates a new watch or replaces the one with the same name. Stored points are kept.
+func (w *Watches) Save(watch Watch) (*WatchInfo, error) {
+	switch {
+	case watch.Name == "":
+		return nil, errs.BadPayload{ErrText: "no watch name provided"}
+	case watch.Request.URL == "":
+		return nil, errs.BadPayload{ErrText: errs.ErrNoURL}
+	case watch.Selector == "":
+		return nil, errs.BadPayload{ErrText: errs.ErrNoSelectors}
+	case watch.Interval <= 0:
+		return nil, errs.BadPayload{ErrText: "watch interval should be positive"}
+	}
+	unlock, err := w.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	index, err := w.index()
+	if err != nil {
+		return nil, err
+	}
+	info, ok := index[watch.Name]
+	if !ok {
+		info = &WatchInfo{}
+		index[watch.Name] = info
+	}
+	info.Watch = watch
+	if err := w.writeIndex(index); err != nil {
+		return nil, err
+	}
+	res := *info
+	return &res, nil
+}
+
+// List returns all watches sorted by name.
+func (w *Watches) List() ([]WatchInfo, error) {
+	watchesMx.Lock()
+	index, err := w.index()
+	watchesMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	list := []WatchInfo{}
+	for _, info := range index {
+		list = append(list, *info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Delete removes the watch along with its time series.
+func (w *Watches) Delete(req WatchRequest) error {
+	unlock, err := w.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index, err := w.index()
+	if err != nil {
+		return err
+	}
+	if _, ok := index[req.Name]; !ok {
+		return errWatchNotFound(req.Name)
+	}
+	rec := storage.Record{Type: storage.BINARY, Key: watchKey(req.Name)}
+	if w.store.IsExists(rec) {
+		if err := w.store.Delete(rec); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	delete(index, req.Name)
+	return w.writeIndex(index)
+}
+
+// Series returns time series of the watch.
+func (w *Watches) Series(req WatchRequest) (*WatchSeries, error) {
+	watchesMx.Lock()
+	defer watchesMx.Unlock()
+	index, err := w.index()
+	if err != nil {
+		return nil, err
+	}
+	info, ok := index[req.Name]
+	if !ok {
+		return nil, errWatchNotFound(req.Name)
+	}
+	points, err := w.points(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Limit > 0 && req.Limit < len(points) {
+		points = points[len(points)-req.Limit:]
+	}
+	return &WatchSeries{WatchInfo: *info, Points: points}, nil
+}
+
+func (w *Watches) points(name string) ([]WatchPoint, error) {
+	points := []WatchPoint{}
+	rec := storage.Record{Type: storage.BINARY, Key: watchKey(name)}
+	if !w.store.IsExists(rec) {
+		return points, nil
+	}
+	data, err := w.store.Read(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// Check extracts the current value of the watch and appends it to the time series.
+// Only WATCH_MAX_POINTS latest points are kept.
+func (w *Watches) Check(name string) (*WatchPoint, error) {
+	watchesMx.Lock()
+	index, err := w.index()
+	watchesMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	info, ok := index[name]
+	if !ok {
+		return nil, errWatchNotFound(name)
+	}
+	point := WatchPoint{Time: time.Now().UTC()}
+	point.Value, err = info.Watch.extract()
+	if err != nil {
+		point.Error = err.Error()
+	}
+
+	unlock, err := w.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	//the watch may be changed or deleted while it is checked
+	if index, err = w.index(); err != nil {
+		return nil, err
+	}
+	if info, ok = index[name]; !ok {
+		return nil, errWatchNotFound(name)
+	}
+	points, err := w.points(name)
+	if err != nil {
+		return nil, err
+	}
+	points = append(points, point)
+	if max := viper.GetInt("WATCH_MAX_POINTS"); max > 0 && len(points) > max {
+		points = points[len(points)-max:]
+	}
+	data, err := json.Marshal(points)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.store.Write(storage.Record{Type: storage.BINARY, Key: watchKey(name), Value: data}); err != nil {
+		return nil, err
+	}
+	info.LastCheck = point.Time
+	info.LastError = point.Error
+	if point.Error == "" {
+		info.LastValue = point.Value
+	}
+	if err := w.writeIndex(index); err != nil {
+		return nil, err
+	}
+	return &point, nil
+}
+
+// extract fetches watched page and returns the value of the first element matching Selector.
+// Whitespace of the value is collapsed.
+func (watch Watch) extract() (string, error) {
+	req := watch.Request
+	if shadowCombinator.MatchString(watch.Selector) {
+		pierce := true
+		req.PierceShadow = &pierce
+	}
+	if checkRobotsTxt() {
+		if robots, err := fetch.RobotstxtData(req.URL); err == nil && !fetch.AllowedByRobots(req.URL, robots) {
+			return "", fmt.Errorf("%s is forbidden by robots.txt", req.URL)
+		}
+	}
+	content, err := fetchContent(req)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	doc, err := parseDocument(content)
+	if err != nil {
+		return "", err
+	}
+	sel := doc.Find(shadowSelector(watch.Selector)).First()
+	if sel.Length() == 0 {
+		return "", fmt.Errorf("no element matches %q", watch.Selector)
+	}
+	value := sel.Text()
+	if watch.Attr != "" {
+		var ok bool
+		if value, ok = sel.Attr(watch.Attr); !ok {
+			return "", fmt.Errorf("element %q has no attribute %q", watch.Selector, watch.Attr)
+		}
+	}
+	return strings.Join(strings.Fields(value), " "), nil
+}
@@ -0,0 +1,76 @@
+package scrape
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/fetch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatches(t *testing.T) {
+	os.RemoveAll("./diskv")
+	defer os.RemoveAll("./diskv")
+	viper.Set("WATCH_MAX_POINTS", 2)
+	defer viper.Set("WATCH_MAX_POINTS", 10000)
+	//nothing listens there so every check fails
+	viper.Set("DFK_FETCH", "127.0.0.1:1")
+	defer viper.Set("DFK_FETCH", "127.0.0.1:8000")
+
+	w := NewWatches()
+	defer w.Close()
+
+	watch := Watch{
+		Name:     "price",
+		Request:  fetch.Request{URL: "http://127.0.0.1:1/item"},
+		Selector: ".price",
+		Interval: 60,
+	}
+	for _, invalid := range []Watch{
+		{Request: watch.Request, Selector: ".price", Interval: 60},
+		{Name: "price", Selector: ".price", Interval: 60},
+		{Name: "price", Request: watch.Request, Interval: 60},
+		{Name: "price", Request: watch.Request, Selector: ".price"},
+	} {
+		_, err := w.Save(invalid)
+		assert.Error(t, err)
+	}
+	info, err := w.Save(watch)
+	assert.NoError(t, err)
+	assert.True(t, info.Due(time.Now()))
+
+	_, err = w.Check("missing")
+	assert.Error(t, err)
+	for i := 0; i < 3; i++ {
+		point, err := w.Check(watch.Name)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, point.Error)
+	}
+	series, err := w.Series(WatchRequest{Name: watch.Name})
+	assert.NoError(t, err)
+	assert.Len(t, series.Points, 2)
+	assert.NotEmpty(t, series.LastError)
+	assert.False(t, series.Due(time.Now()))
+	series, err = w.Series(WatchRequest{Name: watch.Name, Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, series.Points, 1)
+
+	//replacing the watch keeps its values
+	watch.Attr = "content"
+	_, err = w.Save(watch)
+	assert.NoError(t, err)
+	list, err := w.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "content", list[0].Attr)
+	series, err = w.Series(WatchRequest{Name: watch.Name})
+	assert.NoError(t, err)
+	assert.Len(t, series.Points, 2)
+
+	assert.NoError(t, w.Delete(WatchRequest{Name: watch.Name}))
+	assert.Error(t, w.Delete(WatchRequest{Name: watch.Name}))
+	_, err = w.Series(WatchRequest{Name: watch.Name})
+	assert.Error(t, err)
+}
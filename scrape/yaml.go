@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// IsYAML returns true if content type or file name refers to YAML.
+func IsYAML(contentTypeOrFile string) bool {
+	s := strings.ToLower(contentTypeOrFile)
+	switch filepath.Ext(s) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return strings.Contains(s, "yaml")
+}
+
+// YAMLToJSON converts YAML document to JSON.
+// Comments, multi-line strings, anchors and merge keys make complex payloads more maintainable in YAML.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(v))
+}
+
+// jsonValue converts maps with interface{} keys decoded from YAML to maps with string keys.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = jsonValue(val)
+		}
+		return t
+	}
+	return v
+}
+
+// UnmarshalPayload decodes JSON or YAML encoded payload.
+func UnmarshalPayload(data []byte, isYAML bool) (Payload, error) {
+	var p Payload
+	if isYAML {
+		var err error
+		data, err = YAMLToJSON(data)
+		if err != nil {
+			return p, err
+		}
+	}
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
@@ -0,0 +1,54 @@
+package scrape
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsYAML(t *testing.T) {
+	assert.True(t, IsYAML("payload.yaml"))
+	assert.True(t, IsYAML("payload.YML"))
+	assert.True(t, IsYAML("application/x-yaml"))
+	assert.False(t, IsYAML("payload.json"))
+	assert.False(t, IsYAML("application/json"))
+}
+
+func TestUnmarshalPayload(t *testing.T) {
+	data, err := ioutil.ReadFile("../examples/books.yaml")
+	assert.NoError(t, err)
+	yamlPayload, err := UnmarshalPayload(data, true)
+	assert.NoError(t, err)
+
+	data, err = ioutil.ReadFile("../examples/books.json")
+	assert.NoError(t, err)
+	jsonPayload, err := UnmarshalPayload(data, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, jsonPayload.Name, yamlPayload.Name)
+	assert.Equal(t, jsonPayload.Request, yamlPayload.Request)
+	assert.Equal(t, jsonPayload.Paginator, yamlPayload.Paginator)
+	assert.Len(t, yamlPayload.Fields, 3)
+	//anchors
+	assert.Equal(t, jsonPayload.Fields[1].Extractor, yamlPayload.Fields[1].Extractor)
+	//folded multi-line selector
+	assert.Equal(t, ".product_price .price_color", yamlPayload.Fields[2].Selector)
+
+	_, err = UnmarshalPayload([]byte("name: [unclosed"), true)
+	assert.Error(t, err)
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	data, err := YAMLToJSON([]byte("a:\n  1: b\n  c: [d, {e: f}]\n"))
+	assert.NoError(t, err)
+	v := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(data, &v))
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{
+			"1": "b",
+			"c": []interface{}{"d", map[string]interface{}{"e": "f"}},
+		},
+	}, v)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
 )
@@ -25,8 +26,15 @@ const (
 	deleteIntermediateMapsRowQuery = "DELETE FROM intermediatemaps WHERE payloadhash=?"
 	deleteCacheRowQuery            = "DELETE FROM cache WHERE key=?"
 	deleteCookiesRowQuery          = "DELETE FROM cookies WHERE key=?"
+	readBinaryQuery                = "SELECT value FROM binary WHERE key=?"
+	writeBinaryQuery               = "INSERT INTO binary (key, value) VALUES (?, ?) USING TTL %d"
+	deleteBinaryRowQuery           = "DELETE FROM binary WHERE key=?"
 	getTTLQuery                    = "SELECT TTL(%s) from %s"
 	isExistsQuery                  = "SELECT count(*) FROM %s WHERE payloadhash = %s"
+	insertLeaseQuery               = "INSERT INTO leases (name, holder, expires) VALUES (?, ?, ?) IF NOT EXISTS"
+	renewLeaseQuery                = "UPDATE leases SET expires=? WHERE name=? IF holder=?"
+	takeOverLeaseQuery             = "UPDATE leases SET holder=?, expires=? WHERE name=? IF expires<?"
+	releaseLeaseQuery              = "DELETE FROM leases WHERE name=? IF holder=?"
 )
 
 func newCassandra(host string) *cassandra {
@@ -46,6 +54,11 @@ func (c cassandra) Read(rec Record) (value []byte, err error) {
 	if rec.Type == INTERMEDIATE {
 		return c.readIntermediate(rec.Key)
 	}
+	//binary content is kept in blob column and can't be passed as a part of query string
+	if rec.Type == BINARY {
+		err = c.session.Query(readBinaryQuery, rec.Key).Scan(&value)
+		return value, err
+	}
 	var val string
 	query := fmt.Sprintf(readQuery, rec.Type, rec.Key)
 	err = c.session.Query(query).Scan(&val)
@@ -57,6 +70,10 @@ func (c cassandra) Write(rec Record) error {
 	if rec.Type == INTERMEDIATE {
 		return c.writeIntermediate(rec.Key, rec.Value, rec.ExpTime)
 	}
+	if rec.Type == BINARY {
+		q := fmt.Sprintf(writeBinaryQuery, rec.ExpTime)
+		return c.session.Query(q, rec.Key, rec.Value).Exec()
+	}
 	query := fmt.Sprintf(writeQuery, rec.Type, rec.Key, string(rec.Value), rec.ExpTime)
 	err := c.session.Query(query).Exec()
 	return err
@@ -193,6 +210,8 @@ func (c cassandra) Delete(rec Record) error {
 		// if err != nil {
 		// 	logger.Warningf("Failed delete cookies row: key %s. %s", keys[0], err.Error())
 		// }
+	case BINARY:
+		_ = c.session.Query(deleteBinaryRowQuery, rec.Key).Exec()
 	}
 	return nil
 }
@@ -220,9 +239,39 @@ func (c cassandra) DeleteAll() error {
 	// if err != nil {
 	// 	logger.Warningf("Failed truncate cookies table. %s", err.Error())
 	// }
+	query = fmt.Sprintf(truncateTableQuery, "binary")
+	_ = c.session.Query(query).Exec()
 	return nil
 }
 
 func (c cassandra) Close() {
 	c.session.Close()
 }
+
+// AcquireLease grants lease to holder. Leases are updated by lightweight transactions,
+// so concurrent replicas can't take a lease over at the same time.
+func (c cassandra) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+	queries := []*gocql.Query{
+		c.session.Query(insertLeaseQuery, name, holder, expires),
+		c.session.Query(renewLeaseQuery, expires, name, holder),
+		c.session.Query(takeOverLeaseQuery, holder, expires, name, now),
+	}
+	for _, q := range queries {
+		applied, err := q.MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			return false, err
+		}
+		if applied {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReleaseLease frees lease held by holder.
+func (c cassandra) ReleaseLease(name, holder string) error {
+	_, err := c.session.Query(releaseLeaseQuery, name, holder).MapScanCAS(map[string]interface{}{})
+	return err
+}
@@ -1,7 +1,11 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/peterbourgon/diskv"
@@ -106,3 +110,136 @@ func (d DiskvConn) DeleteAll() error {
 // Close storage connection
 func (d DiskvConn) Close() {
 }
+
+// List returns items matching f with sizes and modification times of their files.
+func (d DiskvConn) List(f Filter) ([]Entry, error) {
+	entries := []Entry{}
+	for _, key := range d.keys(f) {
+		fStat, err := os.Stat(d.diskv.BasePath + "/" + key)
+		if err != nil {
+			continue
+		}
+		if f.Match("", key, fStat.ModTime()) {
+			entries = append(entries, newEntry("", key, fStat.Size(), fStat.ModTime()))
+		}
+	}
+	return entries, nil
+}
+
+// DeleteMatching deletes items matching f. Diskv keeps no types, so f.Type is refused.
+func (d DiskvConn) DeleteMatching(f Filter) (int, error) {
+	if f.Type != "" {
+		return 0, ErrTypeFilterNotSupported
+	}
+	entries, err := d.List(f)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, e := range entries {
+		if err := d.diskv.Erase(e.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// keys returns keys starting with f.Prefix. They are collected before files are inspected or erased.
+func (d DiskvConn) keys(f Filter) []string {
+	keys := []string{}
+	for key := range d.diskv.KeysPrefix(f.Prefix, nil) {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// diskvLeaseLock serializes leases granted by the process. Processes sharing the base directory
+// are serialized with a lock file next to it.
+var diskvLeaseLock sync.Mutex
+
+// diskvLockTimeout is the age of the lock file after which it is considered left by a crashed process.
+const diskvLockTimeout = 10 * time.Second
+
+// AcquireLease grants lease to holder. Diskv leases are shared by replicas running on the same host only.
+func (d DiskvConn) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	unlock, err := d.lockLeases()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	key := leaseKey(name)
+	now := time.Now()
+	if d.diskv.Has(key) {
+		var l lease
+		data, err := d.diskv.Read(key)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &l); err != nil {
+			return false, err
+		}
+		if l.Holder != holder && now.Before(l.Expires) {
+			return false, nil
+		}
+	}
+	data, err := json.Marshal(lease{Holder: holder, Expires: now.Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+	return true, d.diskv.Write(key, data)
+}
+
+// ReleaseLease frees lease held by holder.
+func (d DiskvConn) ReleaseLease(name, holder string) error {
+	unlock, err := d.lockLeases()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	key := leaseKey(name)
+	if !d.diskv.Has(key) {
+		return nil
+	}
+	var l lease
+	data, err := d.diskv.Read(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	if l.Holder != holder {
+		return nil
+	}
+	return d.diskv.Erase(key)
+}
+
+// lockLeases takes the lease lock and returns the function releasing it.
+func (d DiskvConn) lockLeases() (func(), error) {
+	diskvLeaseLock.Lock()
+	path := filepath.Clean(d.diskv.BasePath) + ".lock"
+	for deadline := time.Now().Add(diskvLockTimeout); ; {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(path)
+				diskvLeaseLock.Unlock()
+			}, nil
+		}
+		if !os.IsExist(err) {
+			diskvLeaseLock.Unlock()
+			return nil, err
+		}
+		if fStat, err := os.Stat(path); err == nil && time.Since(fStat.ModTime()) > diskvLockTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			diskvLeaseLock.Unlock()
+			return nil, fmt.Errorf("Cannot lock leases. %s is held for too long", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLeaseNotSupported is returned by AcquireLease and ReleaseLease for stores which can't grant leases atomically.
+var ErrLeaseNotSupported = errors.New("leases are not supported by the storage")
+
+// Leaser is implemented by stores able to grant named leases to one holder at a time,
+// f.e. to elect a leader among service replicas sharing the storage.
+type Leaser interface {
+	//AcquireLease grants lease to holder for ttl. It succeeds if the lease is free, expired or held by holder already,
+	//so the holder renews the lease by acquiring it again.
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	//ReleaseLease frees lease held by holder. Leases held by others are kept.
+	ReleaseLease(name, holder string) error
+}
+
+// lease is a stored lease.
+type lease struct {
+	Holder  string    `json:"holder" bson:"holder"`
+	Expires time.Time `json:"expires" bson:"expires"`
+}
+
+func leaseKey(name string) string {
+	return "lease-" + name
+}
+
+// AcquireLease grants lease of s to holder for ttl.
+func AcquireLease(s Store, name, holder string, ttl time.Duration) (bool, error) {
+	l, ok := s.(Leaser)
+	if !ok {
+		return false, ErrLeaseNotSupported
+	}
+	return l.AcquireLease(name, holder, ttl)
+}
+
+// ReleaseLease frees lease of s held by holder.
+func ReleaseLease(s Store, name, holder string) error {
+	l, ok := s.(Leaser)
+	if !ok {
+		return ErrLeaseNotSupported
+	}
+	return l.ReleaseLease(name, holder)
+}
+
+// lockTTL is the TTL of the lease taken by Lock. A lock left by a crashed replica expires after it.
+const lockTTL = 30 * time.Second
+
+// lockTimeout is the time Lock waits for the lease held by another replica.
+const lockTimeout = 10 * time.Second
+
+// Lock takes lease name of s, so replicas sharing the storage don't update the same records at once.
+// It returns the function releasing the lease. Stores which can't grant leases are not locked.
+func Lock(s Store, name string) (func(), error) {
+	holder := newHolder()
+	for deadline := time.Now().Add(lockTimeout); ; {
+		ok, err := AcquireLease(s, name, holder, lockTTL)
+		if err == ErrLeaseNotSupported {
+			return func() {}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() {
+				if err := ReleaseLease(s, name, holder); err != nil {
+					logger.Error("Failed to release lock " + name + ". " + err.Error())
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Cannot lock %s. It is held for too long", name)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// newHolder returns a random lease holder.
+func newHolder() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskvLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskv")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	d := newDiskvConn(dir, 1024*1024)
+
+	ok, err := AcquireLease(d, "scheduler", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = AcquireLease(d, "scheduler", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok, "lease is held by another holder")
+	ok, err = AcquireLease(d, "scheduler", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok, "holder renews its lease")
+
+	assert.NoError(t, ReleaseLease(d, "scheduler", "b"))
+	ok, _ = AcquireLease(d, "scheduler", "b", time.Minute)
+	assert.False(t, ok, "lease is not released by another holder")
+	assert.NoError(t, ReleaseLease(d, "scheduler", "a"))
+	ok, _ = AcquireLease(d, "scheduler", "b", -time.Second)
+	assert.True(t, ok, "released lease is free")
+	ok, _ = AcquireLease(d, "scheduler", "a", time.Minute)
+	assert.True(t, ok, "expired lease is taken over")
+
+	_, err = os.Stat(dir + ".lock")
+	assert.True(t, os.IsNotExist(err), "lock file is removed")
+
+	_, err = AcquireLease(plainStore{}, "scheduler", "a", time.Minute)
+	assert.Equal(t, ErrLeaseNotSupported, err)
+}
+
+// plainStore can't grant leases.
+type plainStore struct {
+	Store
+}
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskv")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	d := newDiskvConn(dir, 1024*1024)
+
+	unlock, err := Lock(d, "index")
+	assert.NoError(t, err)
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := Lock(d, "index")
+		assert.NoError(t, err)
+		close(locked)
+		unlock()
+	}()
+	select {
+	case <-locked:
+		t.Fatal("lock is held by another holder")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("released lock is taken")
+	}
+
+	unlock, err = Lock(plainStore{}, "index")
+	assert.NoError(t, err)
+	unlock()
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrListNotSupported is returned by List and DeleteMatching for stores which can't enumerate their items.
+var ErrListNotSupported = errors.New("listing is not supported by the storage")
+
+// ErrTypeFilterNotSupported is returned by DeleteMatching of stores keeping all types together, f.e. Diskv.
+// They can't tell items of the type from others, so deleting by type would erase everything matching other criteria.
+var ErrTypeFilterNotSupported = errors.New("type filter is not supported by the storage")
+
+// Entry describes a stored item.
+type Entry struct {
+	Key string `json:"key"`
+	//Type is a storage type of the item. It is empty for stores keeping all types together, f.e. Diskv.
+	Type string `json:"type,omitempty"`
+	//Size is the size of the stored value in bytes
+	Size int64 `json:"size"`
+	//Modified is the time the item was last written. Age is the number of seconds passed since then.
+	Modified time.Time `json:"modified"`
+	Age      int64     `json:"age"`
+}
+
+// Filter selects stored items. Empty Filter matches all items.
+type Filter struct {
+	//Type restricts items to the storage type
+	Type string `json:"type,omitempty"`
+	//Prefix matches keys starting with it, f.e. "report-" or "snapshot-"
+	Prefix string `json:"prefix,omitempty"`
+	//Domain matches keys containing it as a host or a parent domain of a host, f.e. "example.com" matches
+	//"www.example.com" but not "myexample.com". Cookies and localStorage keys end with "|" and the host they belong to.
+	Domain string `json:"domain,omitempty"`
+	//OlderThan matches items written more than OlderThan seconds ago
+	OlderThan int64 `json:"olderThan,omitempty"`
+	//Limit caps the number of listed entries. Zero means no limit. It is ignored by DeleteMatching.
+	Limit int `json:"limit,omitempty"`
+}
+
+// Empty reports whether f matches all items.
+func (f Filter) Empty() bool {
+	return f.Type == "" && f.Prefix == "" && f.Domain == "" && f.OlderThan == 0
+}
+
+// Match reports whether an item of sType with key written at modified is selected by f.
+func (f Filter) Match(sType, key string, modified time.Time) bool {
+	if f.Type != "" && sType != "" && !strings.EqualFold(f.Type, sType) {
+		return false
+	}
+	if !strings.HasPrefix(key, f.Prefix) {
+		return false
+	}
+	if f.Domain != "" && !containsDomain(key, f.Domain) {
+		return false
+	}
+	if f.OlderThan > 0 && time.Since(modified) < time.Duration(f.OlderThan)*time.Second {
+		return false
+	}
+	return true
+}
+
+// containsDomain reports whether key contains domain on host boundaries: at the start of the key or of the host part
+// of it or after ".", and followed by the end of the key or of the host.
+func containsDomain(key, domain string) bool {
+	for i := strings.Index(key, domain); i >= 0; {
+		end := i + len(domain)
+		if (i == 0 || strings.IndexByte(".|/", key[i-1]) >= 0) && (end == len(key) || strings.IndexByte(":/|?", key[end]) >= 0) {
+			return true
+		}
+		next := strings.Index(key[i+1:], domain)
+		if next < 0 {
+			return false
+		}
+		i += next + 1
+	}
+	return false
+}
+
+// Lister is implemented by stores able to enumerate and bulk delete their items.
+type Lister interface {
+	//List returns stored items matching f
+	List(f Filter) ([]Entry, error)
+	//DeleteMatching deletes stored items matching f and returns their number
+	DeleteMatching(f Filter) (int, error)
+}
+
+// List returns items of s matching f sorted by key.
+func List(s Store, f Filter) ([]Entry, error) {
+	l, ok := s.(Lister)
+	if !ok {
+		return nil, ErrListNotSupported
+	}
+	entries, err := l.List(f)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Key == entries[j].Key {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+// DeleteMatching deletes items of s matching f. An empty Filter is refused, DeleteAll erases everything.
+func DeleteMatching(s Store, f Filter) (int, error) {
+	if f.Empty() {
+		return 0, errors.New("empty filter")
+	}
+	l, ok := s.(Lister)
+	if !ok {
+		return 0, ErrListNotSupported
+	}
+	return l.DeleteMatching(f)
+}
+
+func newEntry(sType, key string, size int64, modified time.Time) Entry {
+	return Entry{
+		Key:      key,
+		Type:     sType,
+		Size:     size,
+		Modified: modified.UTC(),
+		Age:      int64(time.Since(modified) / time.Second),
+	}
+}
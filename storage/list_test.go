@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	assert.True(t, Filter{}.Match(CACHE, "anykey", time.Now()))
+	assert.True(t, Filter{Type: "cookies"}.Match(COOKIES, "token|example.com", old))
+	assert.False(t, Filter{Type: CACHE}.Match(COOKIES, "token|example.com", old))
+	assert.True(t, Filter{Type: CACHE}.Match("", "token|example.com", old), "items of untyped stores are not filtered by type")
+	assert.True(t, Filter{Domain: "example.com"}.Match(COOKIES, "token|example.com", old))
+	assert.False(t, Filter{Domain: "example.org"}.Match(COOKIES, "token|example.com", old))
+	assert.True(t, Filter{Domain: "example.com"}.Match(COOKIES, "token|www.example.com", old))
+	assert.True(t, Filter{Domain: "example.com"}.Match(COOKIES, "localStorage-token|example.com:8080", old))
+	assert.True(t, Filter{Domain: "example.com"}.Match(COOKIES, "example.com", old))
+	assert.False(t, Filter{Domain: "example.com"}.Match(COOKIES, "token|myexample.com", old))
+	assert.False(t, Filter{Domain: "example.com"}.Match(COOKIES, "token|example.com.evil.org", old))
+	assert.True(t, Filter{Domain: "example.com"}.Match(COOKIES, "token|myexample.com|www.example.com", old), "later occurrences are checked")
+	assert.False(t, Filter{Prefix: "report-"}.Match(BINARY, "snapshot-1", old))
+	assert.True(t, Filter{OlderThan: 3600}.Match(BINARY, "snapshot-1", old))
+	assert.False(t, Filter{OlderThan: 3600}.Match(BINARY, "snapshot-1", time.Now()))
+	assert.True(t, Filter{}.Empty())
+	assert.True(t, Filter{Limit: 1}.Empty(), "limit doesn't select items")
+}
+
+func TestDiskvList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskv")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	d := newDiskvConn(dir, 1024*1024)
+	for key, value := range map[string]string{
+		"report-1":          "{}",
+		"report-2":          "{\"a\":1}",
+		"snapshot-1":        "<html></html>",
+		"token|example.com": "[]",
+		"token|example.org": "[]",
+	} {
+		assert.NoError(t, d.Write(Record{Key: key, Value: []byte(value)}))
+	}
+
+	entries, err := List(d, Filter{Prefix: "report-"})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "report-1", entries[0].Key)
+		assert.Equal(t, int64(2), entries[0].Size)
+		assert.Equal(t, "report-2", entries[1].Key)
+		assert.Equal(t, int64(7), entries[1].Size)
+	}
+
+	entries, err = List(d, Filter{Limit: 3})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	entries, err = List(d, Filter{OlderThan: 3600})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	_, err = DeleteMatching(d, Filter{})
+	assert.Error(t, err, "Expected empty filter error")
+
+	n, err := DeleteMatching(d, Filter{Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, d.IsExists(Record{Key: "token|example.com"}))
+	assert.True(t, d.IsExists(Record{Key: "token|example.org"}))
+
+	_, err = DeleteMatching(d, Filter{Type: CACHE})
+	assert.Equal(t, ErrTypeFilterNotSupported, err, "Diskv can't tell types apart")
+	entries, err = List(d, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4, "type-only delete leaves records of other types alone")
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -108,3 +111,96 @@ func (m mongodb) DeleteAll() error {
 func (m mongodb) Close() {
 	m.session.Close()
 }
+
+// mongoTypes are storage types kept in collections of the same name.
+var mongoTypes = []string{CACHE, COOKIES, INTERMEDIATE, BINARY}
+
+// List returns items matching f. Size is the size of the stored document.
+// Items are not timestamped, so Modified is the time the item was first written as recorded in its ObjectId.
+func (m mongodb) List(f Filter) ([]Entry, error) {
+	entries := []Entry{}
+	err := m.matching(f, func(sType string, e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// DeleteMatching deletes items matching f.
+func (m mongodb) DeleteMatching(f Filter) (int, error) {
+	deleted := 0
+	err := m.matching(f, func(sType string, e Entry) error {
+		if err := m.Delete(Record{Type: sType, Key: e.Key}); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// matching calls fn for every item matching f.
+func (m mongodb) matching(f Filter, fn func(sType string, e Entry) error) error {
+	query := bson.M{"uid": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(f.Prefix)}}
+	for _, sType := range mongoTypes {
+		if f.Type != "" && !strings.EqualFold(f.Type, sType) {
+			continue
+		}
+		var items []bson.M
+		if err := m.session.DB("dfk").C(sType).Find(query).All(&items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, _ := item["uid"].(string)
+			var modified time.Time
+			if id, ok := item["_id"].(bson.ObjectId); ok {
+				modified = id.Time()
+			}
+			if !f.Match(sType, key, modified) {
+				continue
+			}
+			doc, err := bson.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(sType, newEntry(sType, key, int64(len(doc)), modified)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// leasesCollection keeps leases by their names.
+const leasesCollection = "Leases"
+
+// AcquireLease grants lease to holder. The lease is updated only if it is held by holder or expired,
+// so concurrent replicas can't take it over at the same time.
+func (m mongodb) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	c := m.session.DB("dfk").C(leasesCollection)
+	now := time.Now()
+	l := lease{Holder: holder, Expires: now.Add(ttl)}
+	err := c.Update(
+		bson.M{"_id": name, "$or": []bson.M{{"holder": holder}, {"expires": bson.M{"$lt": now}}}},
+		bson.M{"$set": l})
+	if err == nil {
+		return true, nil
+	}
+	if err != mgo.ErrNotFound {
+		return false, err
+	}
+	err = c.Insert(bson.M{"_id": name, "holder": l.Holder, "expires": l.Expires})
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseLease frees lease held by holder.
+func (m mongodb) ReleaseLease(name, holder string) error {
+	err := m.session.DB("dfk").C(leasesCollection).Remove(bson.M{"_id": name, "holder": holder})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -12,6 +12,7 @@ const (
 	CACHE        = "Cache"
 	COOKIES      = "Cookies"
 	INTERMEDIATE = "Intermediate"
+	BINARY       = "Binary"
 )
 
 // Record struct keeps Key/Value and expiration time of specified type
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+// RequestIDHeader is HTTP header carrying correlation ID of a request between services.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of correlation IDs accepted from clients.
+const maxRequestIDLength = 64
+
+type requestIDKey struct{}
+
+// NewRequestID generates new correlation ID.
+func NewRequestID() string {
+	return ksuid.New().String()
+}
+
+// ContextWithRequestID returns a copy of ctx carrying correlation ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns correlation ID carried by ctx or empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDFromHTTP puts correlation ID received in X-Request-ID header to the context.
+// New ID is generated if the header is missing or invalid. It is used as go-kit ServerBefore option at the API edge.
+func RequestIDFromHTTP(ctx context.Context, r *http.Request) context.Context {
+	id := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+	return ContextWithRequestID(ctx, id)
+}
+
+// validRequestID reports whether id is safe to log and to pass to other services:
+// up to maxRequestIDLength letters, digits, dots, underscores and hyphens.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDToHTTP sets X-Request-ID response header. It is used as go-kit ServerAfter option.
+func RequestIDToHTTP(ctx context.Context, w http.ResponseWriter) context.Context {
+	if id := RequestIDFromContext(ctx); id != "" {
+		w.Header().Set(RequestIDHeader, id)
+	}
+	return ctx
+}
+
+// ErrorWithRequestID appends correlation ID to error message returned to a client.
+// Messages of errors passed from other services already containing the ID are not changed.
+func ErrorWithRequestID(msg, id string) string {
+	if id == "" || strings.Contains(msg, id) {
+		return msg
+	}
+	return msg + ". Request ID: " + id
+}
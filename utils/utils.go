@@ -52,6 +52,12 @@ func RelUrl(base, rel string) (string, error) {
 	return newUrl.String(), nil
 }
 
+// IsTrue reports whether optional flag b is set to true. Omitted flags are nil, so flags set to false
+// explicitly may be told apart from omitted ones, f.e. when payload settings are overridden.
+func IsTrue(b *bool) bool {
+	return b != nil && *b
+}
+
 //Random generates random int64 value
 func Random(min, max int64) int64 {
 	rand.Seed(time.Now().Unix())
@@ -9,8 +9,11 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -77,3 +80,32 @@ func TestRandInt(t *testing.T) {
 	m := s * time.Duration(rand) / 1000
 	fmt.Println(s, rand, m)
 }
+
+func TestRequestID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := RequestIDFromHTTP(context.Background(), r)
+	id := RequestIDFromContext(ctx)
+	assert.NotEmpty(t, id)
+
+	r.Header.Set(RequestIDHeader, "abc")
+	ctx = RequestIDFromHTTP(context.Background(), r)
+	assert.Equal(t, "abc", RequestIDFromContext(ctx))
+	w := httptest.NewRecorder()
+	RequestIDToHTTP(ctx, w)
+	assert.Equal(t, "abc", w.Header().Get(RequestIDHeader))
+
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+
+	//unsafe or too long IDs are replaced
+	for _, bad := range []string{"abc\ndef", "a b", "<script>", strings.Repeat("a", 65)} {
+		r.Header.Set(RequestIDHeader, bad)
+		id := RequestIDFromContext(RequestIDFromHTTP(context.Background(), r))
+		assert.NotEqual(t, bad, id)
+		assert.NotEmpty(t, id)
+	}
+	r.Header.Set(RequestIDHeader, "req-1.2_A")
+	assert.Equal(t, "req-1.2_A", RequestIDFromContext(RequestIDFromHTTP(context.Background(), r)))
+	assert.Equal(t, "failed. Request ID: abc", ErrorWithRequestID("failed", "abc"))
+	assert.Equal(t, "failed. Request ID: abc", ErrorWithRequestID("failed. Request ID: abc", "abc"))
+	assert.Equal(t, "failed", ErrorWithRequestID("failed", ""))
+}